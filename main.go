@@ -1,16 +1,35 @@
 package main
 
 import (
+	"flag"
+
 	"github.com/jfreed-dev/turingpi-terraform-provider/provider"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
 )
 
+// version is set at release time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// providerAddr is the registry address Terraform uses to match a debug
+// session to the provider in configurations
+const providerAddr = "registry.terraform.io/jfreed-dev/turingpi"
+
 func main() {
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "start the provider in debug mode for use with a debugger such as delve, "+
+		"printing the TF_REATTACH_PROVIDERS value to export before running Terraform")
+	flag.Parse()
+
+	provider.Version = version
+
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: func() *schema.Provider {
 			return provider.Provider()
 		},
+		Debug:        debug,
+		ProviderAddr: providerAddr,
 	})
+	provider.Shutdown()
 }
@@ -0,0 +1,411 @@
+// Package bmc provides a client for the Turing Pi 2 BMC API.
+package bmc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEndpoint is the BMC address used when Options sets none
+const DefaultEndpoint = "https://turingpi.local"
+
+// Nodes is the number of compute module slots on a Turing Pi 2 board
+const Nodes = 4
+
+// Request methods of mutating (opt=set) calls, for Options.RequestMethod
+const (
+	RequestMethodAuto = "auto" // POST from POSTAPIVersion on, GET before
+	RequestMethodGET  = "get"
+	RequestMethodPOST = "post"
+)
+
+// POSTAPIVersion is the first BMC API version that takes mutating calls as
+// POST bodies. Earlier firmware only accepts opt=set query parameters on GET,
+// which newer firmware deprecates.
+const POSTAPIVersion = "1.2"
+
+// Options configures a BMC client
+type Options struct {
+	Endpoint string // BMC URL (default DefaultEndpoint)
+	Username string // BMC user, used by Authenticate
+	Password string // BMC password, used by Authenticate
+	Token    string // Existing session token; Authenticate is not needed when set
+	Insecure bool   // Skip TLS certificate verification (self-signed BMC certificates)
+
+	// HTTPClient replaces the default HTTP client, e.g. to add tracing.
+	// Insecure is ignored when it is set.
+	HTTPClient *http.Client
+	// Timeout bounds each request (default 30s). Ignored when HTTPClient is set.
+	Timeout time.Duration
+	// RequestMethod selects how mutating calls are sent (default
+	// RequestMethodAuto, which reads the API version from About once)
+	RequestMethod string
+}
+
+// Client interface for BMC operations - allows mocking in tests
+type Client interface {
+	Authenticate(ctx context.Context) error
+	PowerStatus(ctx context.Context) (map[int]bool, error)
+	SetPower(ctx context.Context, node int, on bool) error
+	ReadUART(ctx context.Context, node int) (string, error)
+	About(ctx context.Context) (map[string]string, error)
+}
+
+// APIError is returned when the BMC answers with an HTTP error status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("BMC API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// RealClient implements Client over the BMC HTTP API
+type RealClient struct {
+	endpoint      string
+	username      string
+	password      string
+	requestMethod string
+	http          *http.Client
+
+	mu    sync.Mutex
+	token string
+	// post is whether mutating calls are POSTs, once detected
+	post *bool
+}
+
+// NewClient creates a BMC client. No request is made until a method is called.
+func NewClient(opts Options) *RealClient {
+	endpoint := strings.TrimSuffix(opts.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = 30 * time.Second
+		}
+		httpClient = &http.Client{Timeout: timeout}
+		if opts.Insecure {
+			httpClient.Transport = &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}
+		}
+	}
+
+	return &RealClient{
+		endpoint:      endpoint,
+		username:      opts.Username,
+		password:      opts.Password,
+		requestMethod: opts.RequestMethod,
+		token:         opts.Token,
+		http:          httpClient,
+	}
+}
+
+// Token returns the current session token
+func (c *RealClient) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Authenticate logs in with the configured username and password and keeps
+// the session token for later requests
+func (c *RealClient) Authenticate(ctx context.Context) error {
+	data, err := json.Marshal(map[string]string{"username": c.username, "password": c.password})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/api/bmc/authenticate", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	body, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode authentication response: %w", err)
+	}
+	if result["id"] == "" {
+		return fmt.Errorf("authentication response has no session token")
+	}
+
+	c.mu.Lock()
+	c.token = result["id"]
+	c.mu.Unlock()
+	return nil
+}
+
+// PowerStatus returns the power state of each node, keyed by node number (1-4)
+func (c *RealClient) PowerStatus(ctx context.Context) (map[int]bool, error) {
+	response, err := c.get(ctx, url.Values{"opt": {"get"}, "type": {"power"}})
+	if err != nil {
+		return nil, err
+	}
+
+	status := make(map[int]bool, Nodes)
+	for node := 1; node <= Nodes; node++ {
+		status[node] = false
+	}
+	for name, value := range parseFields(response) {
+		var node int
+		if _, err := fmt.Sscanf(name, "node%d", &node); err == nil && node >= 1 && node <= Nodes {
+			status[node] = parsePowerValue(value)
+		}
+	}
+	return status, nil
+}
+
+// SetPower turns a node (1-4) on or off
+func (c *RealClient) SetPower(ctx context.Context, node int, on bool) error {
+	if err := checkNode(node); err != nil {
+		return err
+	}
+	value := "0"
+	if on {
+		value = "1"
+	}
+	_, err := c.set(ctx, url.Values{"opt": {"set"}, "type": {"power"}, fmt.Sprintf("node%d", node): {value}})
+	return err
+}
+
+// ReadUART returns and clears the buffered UART output of a node (1-4)
+func (c *RealClient) ReadUART(ctx context.Context, node int) (string, error) {
+	if err := checkNode(node); err != nil {
+		return "", err
+	}
+	// API uses 0-indexed nodes
+	response, err := c.get(ctx, url.Values{"opt": {"get"}, "type": {"uart"}, "node": {fmt.Sprint(node - 1)}, "encoding": {"utf8"}})
+	if err != nil {
+		return "", err
+	}
+	fields := parseFields(response)
+	for _, key := range []string{"uart", "output", "data"} {
+		if v, ok := fields[key].(string); ok {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// About returns the BMC version information (api, version, buildroot, ...)
+func (c *RealClient) About(ctx context.Context) (map[string]string, error) {
+	response, err := c.get(ctx, url.Values{"opt": {"get"}, "type": {"about"}})
+	if err != nil {
+		return nil, err
+	}
+	about := make(map[string]string)
+	for k, v := range parseFields(response) {
+		about[k] = fmt.Sprint(v)
+	}
+	return about, nil
+}
+
+// get sends a GET request to /api/bmc and returns its "response" field
+func (c *RealClient) get(ctx context.Context, query url.Values) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/api/bmc?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.call(req, query)
+}
+
+// set sends a mutating call, as a POST on firmware that expects one, and
+// returns its "response" field
+func (c *RealClient) set(ctx context.Context, query url.Values) (json.RawMessage, error) {
+	req, err := NewSetRequest(ctx, c.endpoint, query, c.postMutations(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.call(req, query)
+}
+
+// postMutations reports whether mutating calls are sent as POSTs. In auto
+// mode the API version is read once; when About fails, GET is used and the
+// detection is retried on the next call.
+func (c *RealClient) postMutations(ctx context.Context) bool {
+	switch c.requestMethod {
+	case RequestMethodGET:
+		return false
+	case RequestMethodPOST:
+		return true
+	}
+	c.mu.Lock()
+	post := c.post
+	c.mu.Unlock()
+	if post != nil {
+		return *post
+	}
+
+	about, err := c.About(ctx)
+	if err != nil {
+		return false
+	}
+	detected := SupportsPOST(about["api"])
+	c.mu.Lock()
+	c.post = &detected
+	c.mu.Unlock()
+	return detected
+}
+
+// call sends an authorized request and returns its "response" field
+func (c *RealClient) call(req *http.Request, query url.Values) (json.RawMessage, error) {
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var envelope struct {
+		Response json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode BMC response to %s %s: %w", query.Get("opt"), query.Get("type"), err)
+	}
+	return envelope.Response, nil
+}
+
+// NewSetRequest builds a mutating /api/bmc call: the query parameters on a
+// GET, or with post the same parameters as a JSON object in a POST body.
+// Parameters without a value (e.g. local) are sent as empty strings.
+func NewSetRequest(ctx context.Context, endpoint string, query url.Values, post bool) (*http.Request, error) {
+	if !post {
+		return http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/api/bmc?"+query.Encode(), nil)
+	}
+	params := make(map[string]string, len(query))
+	for k := range query {
+		params[k] = query.Get(k)
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/api/bmc", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// SupportsPOST reports whether a BMC API version, as reported by About (e.g.
+// "1.1"), takes mutating calls as POST bodies. Unparsable versions do not.
+func SupportsPOST(apiVersion string) bool {
+	have, ok := parseAPIVersion(apiVersion)
+	if !ok {
+		return false
+	}
+	want, _ := parseAPIVersion(POSTAPIVersion)
+	if have[0] != want[0] {
+		return have[0] > want[0]
+	}
+	return have[1] >= want[1]
+}
+
+// parseAPIVersion parses the major and minor numbers of an API version
+func parseAPIVersion(v string) ([2]int, bool) {
+	var parsed [2]int
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	for i := 0; i < len(parts) && i < 2; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// do sends a request and returns the body of a successful response
+func (c *RealClient) do(req *http.Request) ([]byte, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
+// parseFields flattens a BMC response into key/value pairs. Firmware 2.x
+// answers [{"result": [{"key": value, ...}]}], firmware 1.x [["key", value], ...].
+func parseFields(response json.RawMessage) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	var v2 []map[string]interface{}
+	if err := json.Unmarshal(response, &v2); err == nil {
+		for _, item := range v2 {
+			if result, ok := item["result"].([]interface{}); ok {
+				for _, r := range result {
+					if m, ok := r.(map[string]interface{}); ok {
+						for k, v := range m {
+							fields[k] = v
+						}
+					}
+				}
+			}
+		}
+		return fields
+	}
+
+	var legacy [][]interface{}
+	if err := json.Unmarshal(response, &legacy); err == nil {
+		for _, item := range legacy {
+			if len(item) >= 2 {
+				if k, ok := item[0].(string); ok {
+					fields[k] = item[1]
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// parsePowerValue reads a power state reported as "1"/"0", a number or a bool
+func parsePowerValue(v interface{}) bool {
+	switch val := v.(type) {
+	case string:
+		return val == "1" || strings.EqualFold(val, "on") || strings.EqualFold(val, "true")
+	case float64:
+		return val == 1
+	case bool:
+		return val
+	}
+	return false
+}
+
+// checkNode validates a node number
+func checkNode(node int) error {
+	if node < 1 || node > Nodes {
+		return fmt.Errorf("invalid node %d: must be 1-%d", node, Nodes)
+	}
+	return nil
+}
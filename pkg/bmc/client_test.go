@@ -0,0 +1,207 @@
+package bmc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newFakeBMC serves canned /api/bmc responses keyed by the "type" parameter.
+// POST calls are recorded as "POST " and their JSON body as a query.
+func newFakeBMC(t *testing.T, responses map[string]string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bmc/authenticate" {
+			_, _ = w.Write([]byte(`{"id":"session-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer session-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		if r.Method == http.MethodPost {
+			var params map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			query = url.Values{}
+			for k, v := range params {
+				query.Set(k, v)
+			}
+			queries = append(queries, "POST "+query.Encode())
+		} else {
+			queries = append(queries, r.URL.RawQuery)
+		}
+		body, ok := responses[query.Get("type")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &queries
+}
+
+// Compile-time check that RealClient implements Client
+func TestRealClient_ImplementsInterface(t *testing.T) {
+	var _ Client = (*RealClient)(nil)
+}
+
+func TestNewClient_Defaults(t *testing.T) {
+	c := NewClient(Options{})
+	if c.endpoint != DefaultEndpoint {
+		t.Errorf("expected endpoint %s, got %s", DefaultEndpoint, c.endpoint)
+	}
+	if c.http == nil || c.http.Timeout == 0 {
+		t.Error("expected a default HTTP client with a timeout")
+	}
+
+	c = NewClient(Options{Endpoint: "https://10.10.88.70/", Token: "abc"})
+	if c.endpoint != "https://10.10.88.70" {
+		t.Errorf("expected trailing slash trimmed, got %s", c.endpoint)
+	}
+	if c.Token() != "abc" {
+		t.Errorf("expected token abc, got %s", c.Token())
+	}
+}
+
+func TestRealClient_PowerStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"v2", `{"response":[{"result":[{"node1":1,"node2":0,"node3":"1","node4":false}]}]}`},
+		{"legacy", `{"response":[["node1","1"],["node2","0"],["node3","1"],["node4","0"]]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newFakeBMC(t, map[string]string{"power": tt.response})
+			c := NewClient(Options{Endpoint: server.URL, Username: "root", Password: "turing"})
+			ctx := context.Background()
+
+			if err := c.Authenticate(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			status, err := c.PowerStatus(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := map[int]bool{1: true, 2: false, 3: true, 4: false}
+			for node, on := range want {
+				if status[node] != on {
+					t.Errorf("node %d: expected %v, got %v", node, on, status[node])
+				}
+			}
+		})
+	}
+}
+
+func TestRealClient_SetPower(t *testing.T) {
+	server, queries := newFakeBMC(t, map[string]string{"power": `{"response":[{"result":"ok"}]}`})
+	c := NewClient(Options{Endpoint: server.URL, Token: "session-token"})
+
+	if err := c.SetPower(context.Background(), 2, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The API version is looked up first; without it, GET is used
+	if len(*queries) != 2 || (*queries)[1] != "node2=1&opt=set&type=power" {
+		t.Errorf("unexpected queries %v", *queries)
+	}
+
+	if err := c.SetPower(context.Background(), 5, true); err == nil {
+		t.Error("expected error for invalid node")
+	}
+}
+
+func TestRealClient_SetPower_POST(t *testing.T) {
+	server, queries := newFakeBMC(t, map[string]string{
+		"about": `{"response":[{"result":[{"api":"1.2","version":"2.4.0"}]}]}`,
+		"power": `{"response":[{"result":"ok"}]}`,
+	})
+	c := NewClient(Options{Endpoint: server.URL, Token: "session-token"})
+
+	for _, on := range []bool{true, false} {
+		if err := c.SetPower(context.Background(), 3, on); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want := []string{"opt=get&type=about", "POST node3=1&opt=set&type=power", "POST node3=0&opt=set&type=power"}
+	if len(*queries) != len(want) {
+		t.Fatalf("expected the API version to be read once, got %v", *queries)
+	}
+	for i := range want {
+		if (*queries)[i] != want[i] {
+			t.Errorf("request %d: got %q, want %q", i, (*queries)[i], want[i])
+		}
+	}
+
+	// A pinned method skips the detection
+	server, queries = newFakeBMC(t, map[string]string{"power": `{"response":[{"result":"ok"}]}`})
+	c = NewClient(Options{Endpoint: server.URL, Token: "session-token", RequestMethod: RequestMethodGET})
+	if err := c.SetPower(context.Background(), 1, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*queries) != 1 || (*queries)[0] != "node1=1&opt=set&type=power" {
+		t.Errorf("unexpected queries %v", *queries)
+	}
+}
+
+func TestSupportsPOST(t *testing.T) {
+	for version, want := range map[string]bool{
+		"1.0": false, "1.1": false, "1.2": true, "1.10": true, "2.0": true, "v1.2.1": true, "": false, "unknown": false,
+	} {
+		if got := SupportsPOST(version); got != want {
+			t.Errorf("SupportsPOST(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestRealClient_ReadUART(t *testing.T) {
+	server, queries := newFakeBMC(t, map[string]string{"uart": `{"response":[{"result":[{"uart":"turing-1 login: "}]}]}`})
+	c := NewClient(Options{Endpoint: server.URL, Token: "session-token"})
+
+	output, err := c.ReadUART(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "turing-1 login: " {
+		t.Errorf("unexpected output %q", output)
+	}
+	// API uses 0-indexed nodes
+	if (*queries)[0] != "encoding=utf8&node=0&opt=get&type=uart" {
+		t.Errorf("unexpected query %s", (*queries)[0])
+	}
+}
+
+func TestRealClient_About(t *testing.T) {
+	server, _ := newFakeBMC(t, map[string]string{"about": `{"response":[{"result":[{"api":"1.1","version":"2.0.5"}]}]}`})
+	c := NewClient(Options{Endpoint: server.URL, Token: "session-token"})
+
+	about, err := c.About(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if about["version"] != "2.0.5" || about["api"] != "1.1" {
+		t.Errorf("unexpected about %v", about)
+	}
+}
+
+func TestRealClient_APIError(t *testing.T) {
+	server, _ := newFakeBMC(t, nil)
+	c := NewClient(Options{Endpoint: server.URL})
+
+	_, err := c.PowerStatus(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected APIError with status 401, got %v", err)
+	}
+}
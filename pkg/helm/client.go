@@ -3,13 +3,17 @@ package helm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 	helmclient "github.com/mittwald/go-helm-client"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Client interface for Helm operations - allows mocking in tests
@@ -38,8 +42,9 @@ type ChartSpec struct {
 
 // RealClient implements Client using mittwald/go-helm-client
 type RealClient struct {
-	client    helmclient.Client
-	namespace string
+	client     helmclient.Client
+	namespace  string
+	restConfig *rest.Config // Reads live objects for DiffRelease
 }
 
 // NewClient creates a new Helm client from a kubeconfig file path
@@ -73,10 +78,45 @@ func NewClientFromBytes(kubeconfig []byte, namespace string) (Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Helm client: %w", err)
 	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
 
 	return &RealClient{
-		client:    client,
-		namespace: namespace,
+		client:     client,
+		namespace:  namespace,
+		restConfig: restConfig,
+	}, nil
+}
+
+// NewClientFromRESTConfig creates a new Helm client from a REST config, e.g.
+// one with a kubeconfig context, TLS or rate limit settings applied
+func NewClientFromRESTConfig(restConfig *rest.Config, namespace string) (Client, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opt := &helmclient.RestConfClientOptions{
+		Options: &helmclient.Options{
+			Namespace:        namespace,
+			RepositoryCache:  "/tmp/.helmcache",
+			RepositoryConfig: "/tmp/.helmrepo",
+			Debug:            false,
+			Linting:          false,
+		},
+		RestConfig: restConfig,
+	}
+
+	client, err := helmclient.NewClientFromRestConf(opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Helm client: %w", err)
+	}
+
+	return &RealClient{
+		client:     client,
+		namespace:  namespace,
+		restConfig: restConfig,
 	}, nil
 }
 
@@ -209,28 +249,25 @@ func WaitForRelease(kubeconfigPath, name, namespace string, timeout time.Duratio
 
 // WaitForReleaseWithClient waits for a release using a provided client (for testing)
 func WaitForReleaseWithClient(client Client, name string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		rel, err := client.GetRelease(name)
 		if err != nil {
-			time.Sleep(5 * time.Second)
-			continue
+			return false, err
 		}
 
 		switch rel.Info.Status {
 		case release.StatusDeployed:
-			return nil
+			return true, nil
 		case release.StatusFailed:
-			return fmt.Errorf("release %s failed: %s", name, rel.Info.Description)
-		case release.StatusPendingInstall, release.StatusPendingUpgrade, release.StatusPendingRollback:
-			// Still in progress, keep waiting
+			return false, waitutil.Permanent(fmt.Errorf("release %s failed: %s", name, rel.Info.Description))
 		default:
-			// Unknown status, keep waiting
+			// Pending or unknown status, keep waiting
+			return false, nil
 		}
-
-		time.Sleep(5 * time.Second)
+	})
+	var timeoutErr *waitutil.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return fmt.Errorf("waiting for release %s: %w", name, err)
 	}
-
-	return fmt.Errorf("timeout waiting for release %s after %v", name, timeout)
+	return err
 }
@@ -0,0 +1,300 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// hookAnnotation marks Helm hooks, which run once and are not compared
+const hookAnnotation = "helm.sh/hook"
+
+// Differ is implemented by clients that can compare a chart with the live
+// objects of its release
+type Differ interface {
+	DiffRelease(ctx context.Context, spec *ChartSpec) (*ReleaseDiff, error)
+}
+
+// FieldDrift is a field whose live value differs from the rendered chart
+type FieldDrift struct {
+	Path    string // Field path, e.g. spec.replicas or spec.template.spec.containers[0].image
+	Desired string // Value rendered by the chart
+	Live    string // Value in the cluster; empty when the field is missing
+}
+
+// ResourceDrift lists the drifted fields of one object of a release
+type ResourceDrift struct {
+	Kind      string
+	Namespace string // Empty for cluster-scoped objects
+	Name      string
+	Missing   bool // The object does not exist in the cluster
+	Fields    []FieldDrift
+}
+
+// ReleaseDiff is the result of comparing a rendered chart with the live
+// objects of its release. Only fields set by the chart are compared, so
+// defaults filled in by the API server are not drift.
+type ReleaseDiff struct {
+	Release   string
+	Resources []ResourceDrift
+}
+
+// HasDrift reports whether any object is missing or changed
+func (r *ReleaseDiff) HasDrift() bool {
+	return r != nil && len(r.Resources) > 0
+}
+
+// Lines describes the drift one change per line, e.g.
+// "~ Deployment metallb-system/controller spec.replicas: 1 -> 3"
+func (r *ReleaseDiff) Lines() []string {
+	if r == nil {
+		return nil
+	}
+	var lines []string
+	for _, res := range r.Resources {
+		ref := res.Kind + " " + res.Name
+		if res.Namespace != "" {
+			ref = res.Kind + " " + res.Namespace + "/" + res.Name
+		}
+		if res.Missing {
+			lines = append(lines, "- "+ref+": missing")
+			continue
+		}
+		for _, f := range res.Fields {
+			lines = append(lines, fmt.Sprintf("~ %s %s: %s -> %s", ref, f.Path, f.Desired, displayLive(f.Live)))
+		}
+	}
+	return lines
+}
+
+func displayLive(v string) string {
+	if v == "" {
+		return "(unset)"
+	}
+	return v
+}
+
+// ObjectGetter returns the live object of a kind, or nil when it does not
+// exist. namespaced reports whether the kind is namespaced.
+type ObjectGetter func(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (obj *unstructured.Unstructured, namespaced bool, err error)
+
+// DiffRelease renders the chart of spec with its values and compares the
+// result with the live objects in the cluster, so changes made to release
+// objects outside Helm are found. Repositories must already be added.
+func (c *RealClient) DiffRelease(ctx context.Context, spec *ChartSpec) (*ReleaseDiff, error) {
+	if c.restConfig == nil {
+		return nil, fmt.Errorf("client has no cluster configuration")
+	}
+
+	manifest, err := c.client.TemplateChart(&helmclient.ChartSpec{
+		ReleaseName: spec.ReleaseName,
+		ChartName:   spec.ChartName,
+		Namespace:   spec.Namespace,
+		Version:     spec.Version,
+		ValuesYaml:  spec.ValuesYaml,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s: %w", spec.ChartName, err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(c.restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(c.restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	get := func(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, bool, error) {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, false, err
+		}
+		namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		var ri dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+		if namespaced {
+			ri = dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+		}
+		obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, namespaced, nil
+		}
+		return obj, namespaced, err
+	}
+
+	diff, err := DiffManifest(ctx, string(manifest), spec.Namespace, get)
+	if err != nil {
+		return nil, err
+	}
+	diff.Release = spec.ReleaseName
+	return diff, nil
+}
+
+// DiffManifest compares every object of a rendered manifest with its live
+// counterpart returned by get. Objects without a namespace are looked up in
+// namespace. Hooks are skipped.
+func DiffManifest(ctx context.Context, manifest, namespace string, get ObjectGetter) (*ReleaseDiff, error) {
+	diff := &ReleaseDiff{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode rendered manifest: %w", err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		desired := &unstructured.Unstructured{Object: raw}
+		if _, hook := desired.GetAnnotations()[hookAnnotation]; hook {
+			continue
+		}
+
+		ns := desired.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+		live, namespaced, err := get(ctx, desired.GroupVersionKind(), ns, desired.GetName())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s %s: %w", desired.GetKind(), desired.GetName(), err)
+		}
+		if !namespaced {
+			ns = ""
+		}
+
+		drift := ResourceDrift{Kind: desired.GetKind(), Namespace: ns, Name: desired.GetName()}
+		if live == nil {
+			drift.Missing = true
+			diff.Resources = append(diff.Resources, drift)
+			continue
+		}
+
+		// Secret data is compared, but never shown
+		secret := desired.GetKind() == "Secret"
+		delete(raw, "stringData")
+		for _, key := range []string{"apiVersion", "kind", "status"} {
+			delete(raw, key)
+		}
+		drift.Fields = diffFields("", raw, live.Object, secret)
+		if len(drift.Fields) > 0 {
+			diff.Resources = append(diff.Resources, drift)
+		}
+	}
+	return diff, nil
+}
+
+// diffFields returns the fields of desired whose value in live differs.
+// Fields only present in live are ignored.
+func diffFields(path string, desired, live interface{}, redact bool) []FieldDrift {
+	switch want := desired.(type) {
+	case map[string]interface{}:
+		got, _ := live.(map[string]interface{})
+		keys := make([]string, 0, len(want))
+		for k := range want {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var fields []FieldDrift
+		for _, k := range keys {
+			if path == "" && k == "metadata" {
+				fields = append(fields, diffMetadata(want[k], got[k])...)
+				continue
+			}
+			fields = append(fields, diffFields(joinPath(path, k), want[k], got[k], redact)...)
+		}
+		return fields
+	case []interface{}:
+		got, _ := live.([]interface{})
+		if len(got) != len(want) {
+			return []FieldDrift{{Path: path, Desired: fmt.Sprintf("%d items", len(want)), Live: fmt.Sprintf("%d items", len(got))}}
+		}
+		var fields []FieldDrift
+		for i := range want {
+			fields = append(fields, diffFields(fmt.Sprintf("%s[%d]", path, i), want[i], got[i], redact)...)
+		}
+		return fields
+	case nil:
+		return nil
+	}
+
+	if scalarEqual(desired, live) {
+		return nil
+	}
+	field := FieldDrift{Path: path, Desired: scalarString(desired), Live: scalarString(live)}
+	if redact {
+		field.Desired, field.Live = "(sensitive)", "(sensitive)"
+	}
+	return []FieldDrift{field}
+}
+
+// diffMetadata compares the labels and annotations set by the chart, and
+// skips the fields Helm and the API server manage
+func diffMetadata(desired, live interface{}) []FieldDrift {
+	want, _ := desired.(map[string]interface{})
+	got, _ := live.(map[string]interface{})
+	var fields []FieldDrift
+	for _, key := range []string{"labels", "annotations"} {
+		fields = append(fields, diffFields("metadata."+key, want[key], got[key], false)...)
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// scalarEqual compares scalars across YAML and JSON decoding, where numbers
+// may be int64 or float64, and resource quantities the API server
+// canonicalizes, e.g. "1000m" and "1"
+func scalarEqual(desired, live interface{}) bool {
+	if reflect.DeepEqual(desired, live) {
+		return true
+	}
+	if scalarString(desired) == scalarString(live) && live != nil {
+		return true
+	}
+	ws, wok := desired.(string)
+	gs, gok := live.(string)
+	if wok && gok {
+		wq, werr := resource.ParseQuantity(ws)
+		gq, gerr := resource.ParseQuantity(gs)
+		return werr == nil && gerr == nil && wq.Cmp(gq) == 0
+	}
+	return false
+}
+
+func scalarString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
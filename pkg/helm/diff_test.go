@@ -0,0 +1,190 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testManifest = `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app: demo
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: app
+          image: demo:1.0
+          resources:
+            limits:
+              cpu: 1000m
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app-credentials
+stringData:
+  password: from-chart
+data:
+  token: c2VjcmV0
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: app-reader
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: other
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: app-migrate
+  annotations:
+    helm.sh/hook: pre-upgrade
+`
+
+func testLiveObjects() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"apps/v1/Deployment apps/app": {
+			"metadata": map[string]interface{}{
+				"name":            "app",
+				"namespace":       "apps",
+				"resourceVersion": "123",
+				"labels":          map[string]interface{}{"app": "demo", "app.kubernetes.io/managed-by": "Helm"},
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64(3),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{
+							"name":            "app",
+							"image":           "demo:1.0",
+							"imagePullPolicy": "IfNotPresent",
+							"resources": map[string]interface{}{
+								"limits": map[string]interface{}{"cpu": "1"},
+							},
+						}},
+					},
+				},
+			},
+		},
+		"v1/Secret apps/app-credentials": {
+			"metadata": map[string]interface{}{"name": "app-credentials"},
+			"data":     map[string]interface{}{"token": "ZWRpdGVk", "password": "ZnJvbS1jaGFydA=="},
+		},
+		"v1/ConfigMap other/app-config": {
+			"metadata": map[string]interface{}{"name": "app-config"},
+		},
+	}
+}
+
+func testGetter(objects map[string]map[string]interface{}) ObjectGetter {
+	return func(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, bool, error) {
+		namespaced := gvk.Kind != "ClusterRole"
+		if !namespaced {
+			namespace = ""
+		}
+		key := gvk.GroupVersion().String() + "/" + gvk.Kind + " " + namespace + "/" + name
+		if obj, ok := objects[key]; ok {
+			return &unstructured.Unstructured{Object: obj}, namespaced, nil
+		}
+		return nil, namespaced, nil
+	}
+}
+
+func TestDiffManifest(t *testing.T) {
+	diff, err := DiffManifest(context.Background(), testManifest, "apps", testGetter(testLiveObjects()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !diff.HasDrift() {
+		t.Fatal("expected drift")
+	}
+
+	want := []string{
+		"~ Deployment apps/app spec.replicas: 1 -> 3",
+		"~ Secret apps/app-credentials data.token: (sensitive) -> (sensitive)",
+		"- ClusterRole app-reader: missing",
+	}
+	if got := diff.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected drift:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestDiffManifest_NoDrift(t *testing.T) {
+	objects := testLiveObjects()
+	deployment := objects["apps/v1/Deployment apps/app"]
+	deployment["spec"].(map[string]interface{})["replicas"] = int64(1)
+	objects["v1/Secret apps/app-credentials"]["data"] = map[string]interface{}{"token": "c2VjcmV0"}
+	objects["rbac.authorization.k8s.io/v1/ClusterRole /app-reader"] = map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "app-reader"},
+	}
+
+	diff, err := DiffManifest(context.Background(), testManifest, "apps", testGetter(objects))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff.HasDrift() {
+		t.Errorf("expected no drift, got:\n%s", strings.Join(diff.Lines(), "\n"))
+	}
+}
+
+func TestDiffManifest_Lists(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+    - port: 443
+`
+	objects := map[string]map[string]interface{}{
+		"v1/Service default/web": {
+			"spec": map[string]interface{}{
+				"ports": []interface{}{map[string]interface{}{"port": int64(8080)}},
+			},
+		},
+	}
+	diff, err := DiffManifest(context.Background(), manifest, "default", testGetter(objects))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := diff.Lines(); len(got) != 1 || got[0] != "~ Service default/web spec.ports: 2 items -> 1 items" {
+		t.Errorf("unexpected drift: %v", got)
+	}
+}
+
+func TestDiffManifest_GetterError(t *testing.T) {
+	get := func(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, bool, error) {
+		return nil, false, errors.New("no matches for kind")
+	}
+	_, err := DiffManifest(context.Background(), testManifest, "apps", get)
+	if err == nil || !strings.Contains(err.Error(), "failed to read Deployment app") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+}
+
+func TestReleaseDiff_Nil(t *testing.T) {
+	var diff *ReleaseDiff
+	if diff.HasDrift() || diff.Lines() != nil {
+		t.Error("expected a nil diff to have no drift")
+	}
+}
+
+// RealClient implements Differ
+var _ Differ = (*RealClient)(nil)
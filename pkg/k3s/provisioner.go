@@ -7,9 +7,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/ssh"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 )
 
 // NodeConfig holds SSH connection details for a K3s node
@@ -32,9 +34,14 @@ type ClusterConfig struct {
 	Workers      []NodeConfig
 }
 
+// downloadInstallScriptCmd fetches the K3s install script to /tmp
+const downloadInstallScriptCmd = "curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh && chmod +x /tmp/k3s-install.sh"
+
 // Provisioner handles K3s cluster installation via SSH
 type Provisioner struct {
 	clientFactory func() ssh.Client
+	// commands counts SSH commands run, for ProvisionReport
+	commands atomic.Int64
 }
 
 // NewProvisioner creates a new K3s provisioner
@@ -73,6 +80,7 @@ func (n *NodeConfig) getSSHConfig() *ssh.Config {
 
 // runCommand executes a command on a node via SSH
 func (p *Provisioner) runCommand(node NodeConfig, cmd string) (string, error) {
+	p.commands.Add(1)
 	client := p.clientFactory()
 	if err := client.Connect(node.Host, node.SSHPort, node.getSSHConfig()); err != nil {
 		return "", fmt.Errorf("SSH connection failed: %w", err)
@@ -88,29 +96,62 @@ func (p *Provisioner) runCommand(node NodeConfig, cmd string) (string, error) {
 
 // InstallServer installs K3s server on the control plane node
 func (p *Provisioner) InstallServer(ctx context.Context, node NodeConfig, cfg ClusterConfig, timeout time.Duration) error {
+	_, err := p.InstallServerWithReport(ctx, node, cfg, timeout)
+	return err
+}
+
+// InstallServerWithReport installs K3s server on the control plane node and
+// reports the time taken by each step. The report covers the steps that ran,
+// also when the install fails.
+func (p *Provisioner) InstallServerWithReport(ctx context.Context, node NodeConfig, cfg ClusterConfig, timeout time.Duration) (*ProvisionReport, error) {
+	run := NewRecorder(node.Host, RoleServer, p.commands.Load)
+	err := p.installServer(run, node, cfg, timeout)
+	if err == nil {
+		p.detectVersion(run, node)
+	}
+	return run.Finish(), err
+}
+
+func (p *Provisioner) installServer(run *Recorder, node NodeConfig, cfg ClusterConfig, timeout time.Duration) error {
 	// 1. Disable swap
-	if _, err := p.runCommand(node, "swapoff -a"); err != nil {
+	if err := run.Step(StepDisableSwap, func() error {
+		_, err := p.runCommand(node, "swapoff -a")
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to disable swap: %w", err)
 	}
 
 	// 2. Create K3s config directory
-	if _, err := p.runCommand(node, "mkdir -p /etc/rancher/k3s"); err != nil {
+	if err := run.Step(StepCreateConfigDir, func() error {
+		_, err := p.runCommand(node, "mkdir -p /etc/rancher/k3s")
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	// 3. Check if K3s is already installed
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'")
-	if strings.TrimSpace(output) == "installed" {
+	var installed bool
+	_ = run.Step(StepCheckInstalled, func() error {
+		installed, _ = p.CheckInstalled(node)
+		return nil
+	})
+	if installed {
 		// K3s already installed, just ensure it's running
-		if _, err := p.runCommand(node, "systemctl start k3s"); err != nil {
+		run.Report().AlreadyInstalled = true
+		if err := run.Step(StepStartExisting, func() error {
+			_, err := p.runCommand(node, ServiceCommand("start", "k3s"))
+			return err
+		}); err != nil {
 			return fmt.Errorf("failed to start existing K3s: %w", err)
 		}
-		return p.waitForReady(node, timeout)
+		return run.Step(StepWaitReady, func() error { return p.waitForReady(node, timeout) })
 	}
 
 	// 4. Download K3s install script
-	downloadCmd := "curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh && chmod +x /tmp/k3s-install.sh"
-	if _, err := p.runCommand(node, downloadCmd); err != nil {
+	if err := run.Step(StepDownloadScript, func() error {
+		_, err := p.runCommand(node, downloadInstallScriptCmd)
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to download K3s install script: %w", err)
 	}
 
@@ -124,27 +165,38 @@ func (p *Provisioner) InstallServer(ctx context.Context, node NodeConfig, cfg Cl
 	}
 
 	installCmd := fmt.Sprintf("%s /tmp/k3s-install.sh server", strings.Join(envVars, " "))
-	if _, err := p.runCommand(node, installCmd); err != nil {
+	if err := run.Step(StepInstall, func() error {
+		_, err := p.runCommand(node, installCmd)
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to install K3s server: %w", err)
 	}
 
 	// 6. Wait for K3s to be ready
-	return p.waitForReady(node, timeout)
+	return run.Step(StepWaitReady, func() error { return p.waitForReady(node, timeout) })
+}
+
+// detectVersion records the installed K3s version in the report. Failures
+// leave the version empty.
+func (p *Provisioner) detectVersion(run *Recorder, node NodeConfig) {
+	_ = run.Step(StepDetectVersion, func() error {
+		if output, err := p.GetVersion(node); err == nil {
+			run.Report().Version = ParseVersion(output)
+		}
+		return nil
+	})
 }
 
 // waitForReady waits for K3s to be ready on the control plane
 func (p *Provisioner) waitForReady(node NodeConfig, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		output, err := p.runCommand(node, "k3s kubectl get nodes 2>/dev/null")
-		if err == nil && strings.Contains(output, "Ready") {
-			return nil
-		}
-		time.Sleep(5 * time.Second)
+		return err == nil && strings.Contains(output, "Ready"), err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for K3s to be ready: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for K3s to be ready after %v", timeout)
+	return nil
 }
 
 // GetNodeToken retrieves the node token from the control plane
@@ -172,28 +224,61 @@ func (p *Provisioner) GetKubeconfig(node NodeConfig) (string, error) {
 
 // InstallAgent installs K3s agent on a worker node
 func (p *Provisioner) InstallAgent(ctx context.Context, node NodeConfig, serverURL, nodeToken, k3sVersion string, timeout time.Duration) error {
+	_, err := p.InstallAgentWithReport(ctx, node, serverURL, nodeToken, k3sVersion, timeout)
+	return err
+}
+
+// InstallAgentWithReport installs K3s agent on a worker node and reports the
+// time taken by each step. The report covers the steps that ran, also when
+// the install fails.
+func (p *Provisioner) InstallAgentWithReport(ctx context.Context, node NodeConfig, serverURL, nodeToken, k3sVersion string, timeout time.Duration) (*ProvisionReport, error) {
+	run := NewRecorder(node.Host, RoleAgent, p.commands.Load)
+	err := p.installAgent(run, node, serverURL, nodeToken, k3sVersion)
+	if err == nil {
+		p.detectVersion(run, node)
+	}
+	return run.Finish(), err
+}
+
+func (p *Provisioner) installAgent(run *Recorder, node NodeConfig, serverURL, nodeToken, k3sVersion string) error {
 	// 1. Disable swap
-	if _, err := p.runCommand(node, "swapoff -a"); err != nil {
+	if err := run.Step(StepDisableSwap, func() error {
+		_, err := p.runCommand(node, "swapoff -a")
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to disable swap: %w", err)
 	}
 
 	// 2. Create K3s config directory
-	if _, err := p.runCommand(node, "mkdir -p /etc/rancher/k3s"); err != nil {
+	if err := run.Step(StepCreateConfigDir, func() error {
+		_, err := p.runCommand(node, "mkdir -p /etc/rancher/k3s")
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	// 3. Check if K3s agent is already installed
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'")
-	if strings.TrimSpace(output) == "installed" {
+	var installed bool
+	_ = run.Step(StepCheckInstalled, func() error {
+		installed, _ = p.CheckInstalled(node)
+		return nil
+	})
+	if installed {
 		// K3s already installed, just ensure it's running
 		// Ignore error - might not be configured as agent yet
-		_, _ = p.runCommand(node, "systemctl start k3s-agent")
+		run.Report().AlreadyInstalled = true
+		_ = run.Step(StepStartExisting, func() error {
+			_, _ = p.runCommand(node, ServiceCommand("start", "k3s-agent"))
+			return nil
+		})
 		return nil
 	}
 
 	// 4. Download K3s install script
-	downloadCmd := "curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh && chmod +x /tmp/k3s-install.sh"
-	if _, err := p.runCommand(node, downloadCmd); err != nil {
+	if err := run.Step(StepDownloadScript, func() error {
+		_, err := p.runCommand(node, downloadInstallScriptCmd)
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to download K3s install script: %w", err)
 	}
 
@@ -206,7 +291,10 @@ func (p *Provisioner) InstallAgent(ctx context.Context, node NodeConfig, serverU
 	}
 
 	installCmd := fmt.Sprintf("%s /tmp/k3s-install.sh agent", strings.Join(envVars, " "))
-	if _, err := p.runCommand(node, installCmd); err != nil {
+	if err := run.Step(StepInstall, func() error {
+		_, err := p.runCommand(node, installCmd)
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to install K3s agent: %w", err)
 	}
 
@@ -215,25 +303,24 @@ func (p *Provisioner) InstallAgent(ctx context.Context, node NodeConfig, serverU
 
 // WaitForNodeReady waits for a specific node to be Ready in the cluster
 func (p *Provisioner) WaitForNodeReady(controlPlane NodeConfig, nodeHost string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	// Extract hostname from the node - typically the last octet or full hostname
-	// K3s uses the system hostname, so we need to check what hostname the node reports
-	for time.Now().Before(deadline) {
+	// K3s uses the system hostname, so the node is found by the IP in the wide output
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		// Get all nodes and check if our node's IP appears and is Ready
 		output, err := p.runCommand(controlPlane, "k3s kubectl get nodes -o wide 2>/dev/null")
-		if err == nil {
-			lines := strings.Split(output, "\n")
-			for _, line := range lines {
-				if strings.Contains(line, nodeHost) && strings.Contains(line, "Ready") {
-					return nil
-				}
+		if err != nil {
+			return false, err
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, nodeHost) && strings.Contains(line, "Ready") {
+				return true, nil
 			}
 		}
-		time.Sleep(5 * time.Second)
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for node %s to be Ready: %w", nodeHost, err)
 	}
-
-	return fmt.Errorf("timeout waiting for node %s to be Ready after %v", nodeHost, timeout)
+	return nil
 }
 
 // UninstallServer removes K3s server from a node
@@ -111,7 +111,7 @@ func TestProvisioner_InstallServer_AlreadyInstalled(t *testing.T) {
 			if strings.Contains(cmd, "test -f /usr/local/bin/k3s") {
 				return "installed", nil
 			}
-			if strings.Contains(cmd, "systemctl start k3s") {
+			if cmd == ServiceCommand("start", "k3s") {
 				return "", nil
 			}
 			if strings.Contains(cmd, "k3s kubectl get nodes") {
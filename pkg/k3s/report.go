@@ -0,0 +1,128 @@
+package k3s
+
+import (
+	"strings"
+	"time"
+)
+
+// Steps recorded in a ProvisionReport
+const (
+	StepDisableSwap     = "disable_swap"
+	StepCreateConfigDir = "create_config_dir"
+	StepCheckInstalled  = "check_installed"
+	StepStartExisting   = "start_existing"
+	StepDownloadScript  = "download_script"
+	StepInstall         = "install"
+	StepWaitReady       = "wait_ready"
+	StepDetectVersion   = "detect_version"
+)
+
+// Node roles of a ProvisionReport
+const (
+	RoleServer = "server"
+	RoleAgent  = "agent"
+)
+
+// StepTiming is the wall-clock duration of one provisioning step
+type StepTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// ProvisionReport describes one InstallServerWithReport or
+// InstallAgentWithReport run, so slow
+// applies can be traced to the step that took the time. A report is returned
+// even when the install fails, covering the steps that ran.
+type ProvisionReport struct {
+	Host string
+	Role string
+	// Steps in the order they ran
+	Steps []StepTiming
+	// CommandsRun counts SSH commands, including readiness polls
+	CommandsRun int
+	// AlreadyInstalled is set when K3s was found on the node and only started
+	AlreadyInstalled bool
+	// Version is the K3s version detected after install (e.g., v1.31.4+k3s1).
+	// Empty if it could not be detected.
+	Version  string
+	Duration time.Duration
+}
+
+// StepDuration returns the duration of the named step, or zero if it did not run
+func (r *ProvisionReport) StepDuration(name string) time.Duration {
+	var total time.Duration
+	for _, s := range r.Steps {
+		if s.Name == name {
+			total += s.Duration
+		}
+	}
+	return total
+}
+
+// Recorder times the steps of an install into a ProvisionReport. Each Begin
+// ends the previous step.
+type Recorder struct {
+	report    *ProvisionReport
+	start     time.Time
+	commands  func() int64
+	base      int64
+	step      string
+	stepStart time.Time
+}
+
+// NewRecorder starts the report of an install on host in role. commands
+// returns the number of SSH commands run so far, and the report counts the
+// ones run until Finish.
+func NewRecorder(host, role string, commands func() int64) *Recorder {
+	return &Recorder{
+		report:   &ProvisionReport{Host: host, Role: role},
+		start:    time.Now(),
+		commands: commands,
+		base:     commands(),
+	}
+}
+
+// Report returns the report being recorded, e.g. to set AlreadyInstalled
+func (r *Recorder) Report() *ProvisionReport {
+	return r.report
+}
+
+// Begin ends the running step and starts timing name
+func (r *Recorder) Begin(name string) {
+	r.End()
+	r.step, r.stepStart = name, time.Now()
+}
+
+// End ends the running step, if any
+func (r *Recorder) End() {
+	if r.step != "" {
+		r.report.Steps = append(r.report.Steps, StepTiming{Name: r.step, Duration: time.Since(r.stepStart)})
+		r.step = ""
+	}
+}
+
+// Step runs fn as the step name
+func (r *Recorder) Step(name string, fn func() error) error {
+	r.Begin(name)
+	defer r.End()
+	return fn()
+}
+
+// Finish ends the running step and fills in the totals
+func (r *Recorder) Finish() *ProvisionReport {
+	r.End()
+	r.report.CommandsRun = int(r.commands() - r.base)
+	r.report.Duration = time.Since(r.start)
+	return r.report
+}
+
+// ParseVersion extracts the version from `k3s --version` output, e.g.
+// "v1.31.4+k3s1" from "k3s version v1.31.4+k3s1 (a1b2c3d4)"
+func ParseVersion(output string) string {
+	for _, field := range strings.Fields(output) {
+		if len(field) > 1 && field[0] == 'v' && field[1] >= '0' && field[1] <= '9' {
+			return field
+		}
+	}
+	return ""
+}
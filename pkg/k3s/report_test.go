@@ -0,0 +1,127 @@
+package k3s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/ssh"
+)
+
+func TestProvisioner_InstallAgent_Report(t *testing.T) {
+	mock := &MockSSHClient{
+		RunCommandFunc: func(cmd string) (string, error) {
+			switch {
+			case strings.Contains(cmd, "test -f /usr/local/bin/k3s"):
+				return "not_installed", nil
+			case strings.Contains(cmd, "k3s --version"):
+				return "k3s version v1.31.4+k3s1 (a1b2c3d4)", nil
+			}
+			return "", nil
+		},
+	}
+	p := NewProvisionerWithClientFactory(func() ssh.Client { return mock })
+
+	node := NodeConfig{Host: "192.168.1.101", SSHUser: "root", SSHPort: 22}
+	report, err := p.InstallAgentWithReport(context.Background(), node, "https://192.168.1.100:6443", "token", "", 30*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Host != node.Host || report.Role != RoleAgent {
+		t.Errorf("unexpected report identity: %+v", report)
+	}
+	if report.AlreadyInstalled {
+		t.Error("expected AlreadyInstalled to be false")
+	}
+	if report.Version != "v1.31.4+k3s1" {
+		t.Errorf("expected version v1.31.4+k3s1, got %q", report.Version)
+	}
+	if report.CommandsRun != len(mock.RunCommandCalls) {
+		t.Errorf("expected %d commands, got %d", len(mock.RunCommandCalls), report.CommandsRun)
+	}
+
+	var names []string
+	for _, s := range report.Steps {
+		names = append(names, s.Name)
+	}
+	expected := []string{StepDisableSwap, StepCreateConfigDir, StepCheckInstalled, StepDownloadScript, StepInstall, StepDetectVersion}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected steps %v, got %v", expected, names)
+	}
+	if report.Duration < report.StepDuration(StepInstall) {
+		t.Error("expected total duration to cover the install step")
+	}
+}
+
+func TestProvisioner_InstallServer_ReportOnFailure(t *testing.T) {
+	mock := &MockSSHClient{
+		RunCommandFunc: func(cmd string) (string, error) {
+			if strings.Contains(cmd, "test -f /usr/local/bin/k3s") {
+				return "not_installed", nil
+			}
+			if strings.Contains(cmd, "curl") {
+				return "", errors.New("could not resolve host")
+			}
+			return "", nil
+		},
+	}
+	p := NewProvisionerWithClientFactory(func() ssh.Client { return mock })
+
+	node := NodeConfig{Host: "192.168.1.100", SSHUser: "root", SSHPort: 22}
+	report, err := p.InstallServerWithReport(context.Background(), node, ClusterConfig{Name: "test"}, 30*time.Second)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if report == nil {
+		t.Fatal("expected a report for the failed install")
+	}
+	last := report.Steps[len(report.Steps)-1]
+	if last.Name != StepDownloadScript {
+		t.Errorf("expected the report to end at the failed step, got %q", last.Name)
+	}
+	if report.Version != "" {
+		t.Errorf("expected no version for a failed install, got %q", report.Version)
+	}
+}
+
+func TestProvisioner_InstallServer_ReportAlreadyInstalled(t *testing.T) {
+	mock := &MockSSHClient{
+		RunCommandFunc: func(cmd string) (string, error) {
+			switch {
+			case strings.Contains(cmd, "test -f /usr/local/bin/k3s"):
+				return "installed", nil
+			case strings.Contains(cmd, "k3s kubectl get nodes"):
+				return "node1   Ready", nil
+			}
+			return "", nil
+		},
+	}
+	p := NewProvisionerWithClientFactory(func() ssh.Client { return mock })
+
+	node := NodeConfig{Host: "192.168.1.100", SSHUser: "root", SSHPort: 22}
+	report, err := p.InstallServerWithReport(context.Background(), node, ClusterConfig{Name: "test"}, 30*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.AlreadyInstalled || report.Role != RoleServer {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.StepDuration(StepInstall) != 0 {
+		t.Error("expected no install step for an existing installation")
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	if got := ParseVersion("k3s version v1.31.4+k3s1 (a1b2c3d4)\ngo version go1.22.9"); got != "v1.31.4+k3s1" {
+		t.Errorf("unexpected version %q", got)
+	}
+	if got := ParseVersion("version unknown"); got != "" {
+		t.Errorf("expected empty version, got %q", got)
+	}
+	if got := ParseVersion(""); got != "" {
+		t.Errorf("expected empty version, got %q", got)
+	}
+}
@@ -0,0 +1,14 @@
+package k3s
+
+import "fmt"
+
+// InitSystemCheck succeeds when the node was booted with systemd (sd_booted).
+// On images without systemd, such as Alpine, the install script sets K3s up
+// as an OpenRC service instead.
+const InitSystemCheck = "[ -d /run/systemd/system ]"
+
+// ServiceCommand returns the command to start or restart a K3s unit (k3s or
+// k3s-agent) with systemctl, or with rc-service on OpenRC nodes
+func ServiceCommand(action, unit string) string {
+	return fmt.Sprintf("if %s; then systemctl %s %s; else rc-service %s %s; fi", InitSystemCheck, action, unit, unit, action)
+}
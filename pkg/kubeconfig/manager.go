@@ -2,9 +2,11 @@
 package kubeconfig
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -72,19 +74,14 @@ func WaitForKubeAPI(kubeconfigPath string, timeout time.Duration) error {
 		return fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	deadline := time.Now().Add(timeout)
-	var lastErr error
-
-	for time.Now().Before(deadline) {
+	err = waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		_, err := client.Discovery().ServerVersion()
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for Kubernetes API: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for Kubernetes API after %v: %w", timeout, lastErr)
+	return nil
 }
 
 // WaitForKubeAPIWithConfig polls until Kubernetes API responds using a pre-loaded config
@@ -94,19 +91,14 @@ func WaitForKubeAPIWithConfig(config *rest.Config, timeout time.Duration) error
 		return fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	deadline := time.Now().Add(timeout)
-	var lastErr error
-
-	for time.Now().Before(deadline) {
+	err = waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		_, err := client.Discovery().ServerVersion()
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for Kubernetes API: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for Kubernetes API after %v: %w", timeout, lastErr)
+	return nil
 }
 
 // GetKubernetesVersion returns the server version from a kubeconfig
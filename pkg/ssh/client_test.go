@@ -186,6 +186,7 @@ func TestRunCommand_CommandFailed(t *testing.T) {
 func TestRealClient_ImplementsInterface(t *testing.T) {
 	// Compile-time check that RealClient implements Client
 	var _ Client = (*RealClient)(nil)
+	var _ Uploader = (*RealClient)(nil)
 }
 
 // Test MockClient implements interface
@@ -1,48 +1,46 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"time"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 )
 
 // WaitForSSH polls until SSH is available on a host
 // Returns nil when SSH connection succeeds, or error on timeout
 func WaitForSSH(host string, port int, config *Config, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	var lastErr error
-	for time.Now().Before(deadline) {
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		client := NewClient()
-		err := client.Connect(host, port, config)
-		if err == nil {
-			_ = client.Close()
-			return nil
+		if err := client.Connect(host, port, config); err != nil {
+			return false, err
 		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		_ = client.Close()
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for SSH on %s:%d: %w", host, port, err)
 	}
-
-	return fmt.Errorf("timeout waiting for SSH on %s:%d after %v: %w", host, port, timeout, lastErr)
+	return nil
 }
 
 // WaitForSSHWithClient polls until SSH is available using a custom client factory
 // Useful for testing with mock clients
 func WaitForSSHWithClient(host string, port int, config *Config, timeout time.Duration, clientFactory func() Client) error {
-	deadline := time.Now().Add(timeout)
-
-	var lastErr error
-	for time.Now().Before(deadline) {
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		client := clientFactory()
-		err := client.Connect(host, port, config)
-		if err == nil {
-			_ = client.Close()
-			return nil
+		if err := client.Connect(host, port, config); err != nil {
+			return false, err
 		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		_ = client.Close()
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for SSH on %s:%d: %w", host, port, err)
 	}
-
-	return fmt.Errorf("timeout waiting for SSH on %s:%d after %v: %w", host, port, timeout, lastErr)
+	return nil
 }
 
 // RunCommand executes a command over SSH and returns output
@@ -87,3 +85,30 @@ func CheckConnectivity(host string, port int, config *Config) bool {
 	_ = client.Close()
 	return true
 }
+
+// UploadFile copies a local file to host over SSH. See RealClient.Upload.
+func UploadFile(host string, port int, config *Config, localPath, remotePath string, opts UploadOptions) (*UploadResult, error) {
+	return UploadFileWithClient(host, port, config, localPath, remotePath, opts, NewClient())
+}
+
+// UploadFileWithClient copies a local file using a custom client, which must
+// implement Uploader
+func UploadFileWithClient(host string, port int, config *Config, localPath, remotePath string, opts UploadOptions, client Client) (*UploadResult, error) {
+	uploader, ok := client.(Uploader)
+	if !ok {
+		return nil, fmt.Errorf("SSH client does not support uploads")
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", localPath, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := client.Connect(host, port, config); err != nil {
+		return nil, fmt.Errorf("SSH connection failed: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	return uploader.Upload(f, remotePath, opts)
+}
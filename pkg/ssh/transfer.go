@@ -0,0 +1,191 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uploadChunkSize bounds the bytes sent between rate limiter waits, so a
+// limited transfer is smooth instead of bursting once per second
+const uploadChunkSize = 32 * 1024
+
+// UploadOptions controls a file upload
+type UploadOptions struct {
+	Mode os.FileMode // Permissions of the remote file (default 0644)
+	// Limiter caps the transfer rate. Share one limiter between concurrent
+	// uploads to cap their combined rate, e.g. on the shared BMC uplink.
+	// nil does not limit.
+	Limiter *RateLimiter
+	Verify  bool // Compare the SHA-256 of the remote file with the data sent
+}
+
+// UploadResult describes a completed upload
+type UploadResult struct {
+	Bytes    int64         // Bytes sent
+	SHA256   string        // Hex SHA-256 of the data sent
+	Duration time.Duration // Time spent sending and verifying
+}
+
+// Uploader is implemented by clients that can upload files
+type Uploader interface {
+	Upload(r io.Reader, remotePath string, opts UploadOptions) (*UploadResult, error)
+}
+
+// ChecksumError is returned when the uploaded file does not match the data
+// sent. The partial file is removed.
+type ChecksumError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: sent sha256 %s, remote file has %s", e.Path, e.Expected, e.Actual)
+}
+
+// RateLimiter paces byte transfers to a fixed rate. It is safe for
+// concurrent use; transfers sharing a limiter share its rate.
+type RateLimiter struct {
+	bytesPerSecond int64
+
+	mu   sync.Mutex
+	next time.Time
+	// Replaced in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewRateLimiter returns a limiter for bytesPerSecond, or nil (no limit) when
+// bytesPerSecond is 0 or less
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		bytesPerSecond: bytesPerSecond,
+		now:            time.Now,
+		sleep:          time.Sleep,
+	}
+}
+
+// WaitN blocks until n bytes may be sent
+func (l *RateLimiter) WaitN(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := l.now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.bytesPerSecond) * float64(time.Second)))
+	l.mu.Unlock()
+
+	if wait > 0 {
+		l.sleep(wait)
+	}
+}
+
+// limitedReader paces reads from r through a limiter
+type limitedReader struct {
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > uploadChunkSize {
+		p = p[:uploadChunkSize]
+	}
+	n, err := lr.r.Read(p)
+	lr.limiter.WaitN(n)
+	return n, err
+}
+
+// Upload streams r to remotePath over an SSH session. The data is written to
+// remotePath.part and moved into place once complete (and, with Verify, once
+// its SHA-256 matches), so an interrupted or corrupted transfer never leaves a
+// truncated file at remotePath. Only a POSIX shell with cat and sha256sum is
+// needed on the remote host, no SFTP subsystem.
+func (c *RealClient) Upload(r io.Reader, remotePath string, opts UploadOptions) (*UploadResult, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	start := time.Now()
+	partPath := remotePath + ".part"
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	if err := session.Start("cat > " + shellQuote(partPath)); err != nil {
+		return nil, fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	hash := sha256.New()
+	var src io.Reader = io.TeeReader(r, hash)
+	if opts.Limiter != nil {
+		src = &limitedReader{r: src, limiter: opts.Limiter}
+	}
+	written, copyErr := io.Copy(stdin, src)
+	_ = stdin.Close()
+	if waitErr := session.Wait(); waitErr != nil && copyErr == nil {
+		copyErr = fmt.Errorf("%w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if copyErr != nil {
+		_, _ = c.RunCommand("rm -f " + shellQuote(partPath))
+		return nil, fmt.Errorf("failed to upload %s: %w", remotePath, copyErr)
+	}
+
+	result := &UploadResult{Bytes: written, SHA256: hex.EncodeToString(hash.Sum(nil))}
+	if opts.Verify {
+		output, err := c.RunCommand("sha256sum " + shellQuote(partPath))
+		if err != nil {
+			_, _ = c.RunCommand("rm -f " + shellQuote(partPath))
+			return nil, fmt.Errorf("failed to checksum %s: %w", partPath, err)
+		}
+		if actual := parseSHA256Sum(output); actual != result.SHA256 {
+			_, _ = c.RunCommand("rm -f " + shellQuote(partPath))
+			return nil, &ChecksumError{Path: remotePath, Expected: result.SHA256, Actual: actual}
+		}
+	}
+
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	if _, err := c.RunCommand(fmt.Sprintf("chmod %o %s && mv -f %s %s", mode.Perm(), shellQuote(partPath), shellQuote(partPath), shellQuote(remotePath))); err != nil {
+		return nil, fmt.Errorf("failed to move %s into place: %w", remotePath, err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// parseSHA256Sum returns the checksum from sha256sum output ("<hex>  <path>")
+func parseSHA256Sum(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
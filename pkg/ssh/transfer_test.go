@@ -0,0 +1,236 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startExecServer runs an SSH server on localhost that executes commands with
+// the local shell. mangle, if set, rewrites the stdin of "cat" commands.
+func startExecServer(t *testing.T, mangle func([]byte) []byte) (host string, port int) {
+	t.Helper()
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum not available")
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveExecConn(conn, config, mangle)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func serveExecConn(conn net.Conn, config *ssh.ServerConfig, mangle func([]byte) []byte) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer func() { _ = channel.Close() }()
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				_ = req.Reply(true, nil)
+				length := binary.BigEndian.Uint32(req.Payload)
+				command := string(req.Payload[4 : 4+length])
+
+				var stdin io.Reader = channel
+				if mangle != nil && strings.HasPrefix(command, "cat > ") {
+					data, _ := io.ReadAll(channel)
+					stdin = bytes.NewReader(mangle(data))
+				}
+				cmd := exec.Command("sh", "-c", command)
+				cmd.Stdin = stdin
+				cmd.Stdout = channel
+				cmd.Stderr = channel.Stderr()
+				status := uint32(0)
+				if err := cmd.Run(); err != nil {
+					status = 1
+					var exitErr *exec.ExitError
+					if errors.As(err, &exitErr) {
+						status = uint32(exitErr.ExitCode())
+					}
+				}
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				return
+			}
+		}()
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	host, port := startExecServer(t, nil)
+	dir := t.TempDir()
+
+	data := bytes.Repeat([]byte("airgap bundle "), 10000)
+	local := filepath.Join(dir, "bundle.tar")
+	if err := os.WriteFile(local, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(dir, "remote", "bundle.tar")
+	if err := os.MkdirAll(filepath.Dir(remote), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{User: "root", Password: "secret", Timeout: 5 * time.Second}
+	result, err := UploadFile(host, port, config, local, remote, UploadOptions{Mode: 0600, Verify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if result.Bytes != int64(len(data)) || result.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	got, err := os.ReadFile(remote)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("remote file differs from the upload (%v)", err)
+	}
+	info, _ := os.Stat(remote)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+	if _, err := os.Stat(remote + ".part"); !os.IsNotExist(err) {
+		t.Error("expected the .part file to be moved into place")
+	}
+}
+
+func TestUploadFile_ChecksumMismatch(t *testing.T) {
+	host, port := startExecServer(t, func(data []byte) []byte {
+		corrupted := append([]byte(nil), data...)
+		corrupted[len(corrupted)/2] ^= 0xff
+		return corrupted
+	})
+	dir := t.TempDir()
+
+	local := filepath.Join(dir, "image.raw")
+	if err := os.WriteFile(local, []byte("talos image contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(dir, "image-remote.raw")
+
+	config := &Config{User: "root", Password: "secret", Timeout: 5 * time.Second}
+	_, err := UploadFile(host, port, config, local, remote, UploadOptions{Verify: true})
+
+	var checksumErr *ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("expected a ChecksumError, got %v", err)
+	}
+	for _, path := range []string{remote, remote + ".part"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed after a corrupted transfer", path)
+		}
+	}
+}
+
+func TestUploadFileWithClient_NotUploader(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(local, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := UploadFileWithClient("host", 22, &Config{}, local, "/tmp/file", UploadOptions{}, &MockClient{})
+	if err == nil || !strings.Contains(err.Error(), "does not support uploads") {
+		t.Errorf("expected an unsupported client error, got %v", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	if NewRateLimiter(0) != nil {
+		t.Error("expected no limiter for a rate of 0")
+	}
+
+	var mu sync.Mutex
+	now := time.Unix(0, 0)
+	var slept time.Duration
+	limiter := NewRateLimiter(1000)
+	limiter.now = func() time.Time { mu.Lock(); defer mu.Unlock(); return now }
+	limiter.sleep = func(d time.Duration) { mu.Lock(); defer mu.Unlock(); slept += d; now = now.Add(d) }
+
+	// 3000 bytes at 1000 B/s: the first 1000 go out at once, the rest are paced
+	for i := 0; i < 3; i++ {
+		limiter.WaitN(1000)
+	}
+	if slept != 2*time.Second {
+		t.Errorf("expected 2s of waiting, got %v", slept)
+	}
+
+	// An idle limiter does not bank unused time
+	now = now.Add(10 * time.Second)
+	slept = 0
+	limiter.WaitN(1000)
+	limiter.WaitN(1000)
+	if slept != time.Second {
+		t.Errorf("expected 1s of waiting after idling, got %v", slept)
+	}
+}
+
+func TestLimitedReader_Chunks(t *testing.T) {
+	limiter := NewRateLimiter(1 << 30)
+	limiter.sleep = func(time.Duration) {}
+
+	r := &limitedReader{r: bytes.NewReader(make([]byte, 100*1024)), limiter: limiter}
+	buf := make([]byte, 1024*1024)
+	n, _ := r.Read(buf)
+	if n != uploadChunkSize {
+		t.Errorf("expected reads capped at %d bytes, got %d", uploadChunkSize, n)
+	}
+}
+
+func TestParseSHA256Sum(t *testing.T) {
+	if got := parseSHA256Sum("ABCDEF  /tmp/file\n"); got != "abcdef" {
+		t.Errorf("expected abcdef, got %q", got)
+	}
+	if got := parseSHA256Sum(""); got != "" {
+		t.Errorf("expected empty checksum, got %q", got)
+	}
+}
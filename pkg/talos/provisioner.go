@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 	"gopkg.in/yaml.v3"
 )
 
@@ -294,45 +295,35 @@ func (p *Provisioner) ReadSecrets(path string) (string, error) {
 
 // WaitForHealth waits for the node to be healthy
 func (p *Provisioner) WaitForHealth(talosconfig, nodeIP string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		args := []string{
-			"health",
-			"--nodes", nodeIP,
-			"--wait-timeout", "10s",
-		}
-
+	args := []string{
+		"health",
+		"--nodes", nodeIP,
+		"--wait-timeout", "10s",
+	}
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		_, err := p.runTalosctlWithConfig(talosconfig, args...)
-		if err == nil {
-			return nil
-		}
-
-		time.Sleep(5 * time.Second)
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for node %s to be healthy: %w", nodeIP, err)
 	}
-
-	return fmt.Errorf("timeout waiting for node %s to be healthy after %v", nodeIP, timeout)
+	return nil
 }
 
 // WaitForAPIServer waits for the Kubernetes API server to be ready
 func (p *Provisioner) WaitForAPIServer(talosconfig, nodeIP string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		args := []string{
-			"service", "kube-apiserver",
-			"--nodes", nodeIP,
-		}
-
+	args := []string{
+		"service", "kube-apiserver",
+		"--nodes", nodeIP,
+	}
+	err := waitutil.Poll(context.Background(), 5*time.Second, timeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
 		output, err := p.runTalosctlWithConfig(talosconfig, args...)
-		if err == nil && strings.Contains(output, "Running") {
-			return nil
-		}
-
-		time.Sleep(5 * time.Second)
+		return err == nil && strings.Contains(output, "Running"), err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for API server on %s: %w", nodeIP, err)
 	}
-
-	return fmt.Errorf("timeout waiting for API server on %s after %v", nodeIP, timeout)
+	return nil
 }
 
 // Reset resets a node (wipes it)
@@ -457,11 +448,14 @@ func (p *Provisioner) ProvisionCluster(ctx context.Context, cfg ClusterConfig) (
 	if len(cfg.ControlPlanes) > 0 {
 		firstCP := cfg.ControlPlanes[0].Host
 
-		// Wait a bit for the node to be ready for bootstrap
-		time.Sleep(10 * time.Second)
-
-		if err := p.Bootstrap(talosconfigPath, firstCP); err != nil {
-			return nil, err
+		// The node only accepts the bootstrap once it runs the applied
+		// config. Bootstrap checks etcd first, so retrying it is safe.
+		err := waitutil.Poll(ctx, 5*time.Second, cfg.BootstrapTimeout, waitutil.DefaultJitter, func(context.Context) (bool, error) {
+			err := p.Bootstrap(talosconfigPath, firstCP)
+			return err == nil, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("bootstrapping %s: %w", firstCP, err)
 		}
 
 		// Wait for API server
@@ -0,0 +1,133 @@
+// Package waitutil polls a condition until it holds, a timeout passes or the
+// context is cancelled.
+package waitutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultJitter is the jitter of the provider's own waits: up to 20% of the
+// interval is added to each sleep
+const DefaultJitter = 0.2
+
+// ConditionFunc reports whether the awaited condition holds. A returned error
+// is treated as transient: polling continues and the last error is kept for
+// the TimeoutError. Wrap an error with Permanent to stop polling with it.
+type ConditionFunc func(ctx context.Context) (bool, error)
+
+// TimeoutError is returned by Poll when the timeout passes before the
+// condition holds
+type TimeoutError struct {
+	Timeout  time.Duration
+	Attempts int
+	LastErr  error // Last error of the condition, if any
+}
+
+func (e *TimeoutError) Error() string {
+	if e.LastErr != nil {
+		return fmt.Sprintf("timed out after %v: %v", e.Timeout, e.LastErr)
+	}
+	return fmt.Sprintf("timed out after %v", e.Timeout)
+}
+
+// Unwrap returns the last error of the condition
+func (e *TimeoutError) Unwrap() error {
+	return e.LastErr
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as final: Poll stops and returns err unwrapped
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// ProgressFunc is called while polling, with the number of attempts made so
+// far, the time since polling started and the last error of the condition
+type ProgressFunc func(attempt int, elapsed time.Duration, lastErr error)
+
+// Option configures Poll
+type Option func(*poller)
+
+// WithProgress calls fn after every n-th failed attempt, e.g. to log that a
+// long wait is still in progress
+func WithProgress(n int, fn ProgressFunc) Option {
+	return func(p *poller) {
+		p.progressEvery = n
+		p.progress = fn
+	}
+}
+
+type poller struct {
+	progressEvery int
+	progress      ProgressFunc
+}
+
+// Poll calls fn until it reports true, then returns nil. The first attempt is
+// made immediately, the following ones every interval plus a random jitter of
+// up to jitter*interval, so concurrent waits do not poll in lockstep. Sleeps
+// never run past the timeout.
+//
+// Poll returns a *TimeoutError when timeout passes, the error of the context
+// when ctx is done first, and the unwrapped error when fn returns Permanent.
+// fn receives a context that is cancelled at the timeout.
+func Poll(ctx context.Context, interval, timeout time.Duration, jitter float64, fn ConditionFunc, opts ...Option) error {
+	var p poller
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	start := time.Now()
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		done, err := fn(pollCtx)
+		if err != nil {
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				return permanent.err
+			}
+			lastErr = err
+		}
+		if done {
+			return nil
+		}
+
+		if p.progress != nil && p.progressEvery > 0 && attempt%p.progressEvery == 0 {
+			p.progress(attempt, time.Since(start), lastErr)
+		}
+
+		timer := time.NewTimer(withJitter(interval, jitter))
+		select {
+		case <-pollCtx.Done():
+			timer.Stop()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return &TimeoutError{Timeout: timeout, Attempts: attempt, LastErr: lastErr}
+		case <-timer.C:
+		}
+	}
+}
+
+// withJitter adds a random duration of up to jitter*interval to interval
+func withJitter(interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || interval <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Float64()*jitter*float64(interval))
+}
@@ -0,0 +1,114 @@
+package waitutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoll_Succeeds(t *testing.T) {
+	attempts := 0
+	err := Poll(context.Background(), time.Millisecond, time.Second, 0.5, func(context.Context) (bool, error) {
+		attempts++
+		if attempts < 3 {
+			return false, errors.New("not yet")
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestPoll_Timeout(t *testing.T) {
+	lastErr := errors.New("connection refused")
+	err := Poll(context.Background(), 10*time.Millisecond, 50*time.Millisecond, 0, func(context.Context) (bool, error) {
+		return false, lastErr
+	})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected *TimeoutError, got %v", err)
+	}
+	if timeoutErr.Attempts < 2 {
+		t.Errorf("expected several attempts, got %d", timeoutErr.Attempts)
+	}
+	if !errors.Is(err, lastErr) {
+		t.Errorf("expected the last error to be wrapped, got %v", err)
+	}
+	if err.Error() != "timed out after 50ms: connection refused" {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestPoll_TimeoutCapsSleep(t *testing.T) {
+	start := time.Now()
+	err := Poll(context.Background(), time.Minute, 20*time.Millisecond, 0, func(context.Context) (bool, error) {
+		return false, nil
+	})
+	if err == nil {
+		t.Fatal("expected timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the sleep to end at the timeout, took %v", elapsed)
+	}
+}
+
+func TestPoll_Permanent(t *testing.T) {
+	failed := errors.New("release failed")
+	attempts := 0
+	err := Poll(context.Background(), time.Millisecond, time.Second, 0, func(context.Context) (bool, error) {
+		attempts++
+		return false, Permanent(failed)
+	})
+	if err != failed {
+		t.Errorf("expected the permanent error unwrapped, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected polling to stop after 1 attempt, got %d", attempts)
+	}
+}
+
+func TestPoll_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Poll(ctx, time.Minute, time.Hour, 0, func(context.Context) (bool, error) {
+		cancel()
+		return false, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPoll_Progress(t *testing.T) {
+	var reported []int
+	attempts := 0
+	err := Poll(context.Background(), time.Millisecond, time.Second, 0, func(context.Context) (bool, error) {
+		attempts++
+		return attempts == 7, nil
+	}, WithProgress(3, func(attempt int, _ time.Duration, _ error) {
+		reported = append(reported, attempt)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reported) != 2 || reported[0] != 3 || reported[1] != 6 {
+		t.Errorf("expected progress after attempts 3 and 6, got %v", reported)
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := withJitter(time.Second, 0.2)
+		if d < time.Second || d > 1200*time.Millisecond {
+			t.Fatalf("jittered interval %v outside [1s, 1.2s]", d)
+		}
+	}
+	if d := withJitter(time.Second, 0); d != time.Second {
+		t.Errorf("expected no jitter, got %v", d)
+	}
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// imageRegistryMirrorSchema is the image_registry_mirror argument of the
+// cluster resources
+func imageRegistryMirrorSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Description: "Registry host (and optional path) the MetalLB and ingress-nginx addon images are pulled from instead of quay.io and registry.k8s.io, " +
+			"for air-gapped clusters (e.g., 'registry.lan:5000' or 'harbor.lan/mirror'). Images keep their repository path, e.g. quay.io/metallb/controller becomes <mirror>/metallb/controller.",
+		ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+			if strings.Contains(v.(string), "://") {
+				return nil, []error{fmt.Errorf("%s must be a registry host without a scheme, got %q", k, v)}
+			}
+			return nil, nil
+		},
+	}
+}
+
+// normalizeRegistryMirror trims the trailing slash of a mirror
+func normalizeRegistryMirror(mirror string) string {
+	return strings.TrimSuffix(strings.TrimSpace(mirror), "/")
+}
+
+// metallbImageValues returns MetalLB chart values pulling the controller,
+// speaker and FRR images from mirror
+func metallbImageValues(mirror string) map[string]interface{} {
+	mirror = normalizeRegistryMirror(mirror)
+	image := func(path string) map[string]interface{} {
+		return map[string]interface{}{"repository": mirror + "/" + path}
+	}
+	return map[string]interface{}{
+		"controller": map[string]interface{}{"image": image("metallb/controller")},
+		"speaker": map[string]interface{}{
+			"image": image("metallb/speaker"),
+			"frr":   map[string]interface{}{"image": image("frrouting/frr")},
+		},
+	}
+}
+
+// nginxIngressImageValues returns ingress-nginx chart values pulling the
+// controller and webhook certgen images from mirror. The upstream digests are
+// cleared, since re-pushed mirror images may not keep them.
+func nginxIngressImageValues(mirror string) map[string]interface{} {
+	mirror = normalizeRegistryMirror(mirror)
+	image := map[string]interface{}{
+		"registry":     mirror,
+		"digest":       "",
+		"digestChroot": "",
+	}
+	return map[string]interface{}{
+		"controller": map[string]interface{}{
+			"image": image,
+			"admissionWebhooks": map[string]interface{}{
+				"patch": map[string]interface{}{
+					"image": map[string]interface{}{"registry": mirror, "digest": ""},
+				},
+			},
+		},
+		"defaultBackend": map[string]interface{}{
+			"image": map[string]interface{}{"registry": mirror},
+		},
+	}
+}
+
+// certManagerImageValues returns cert-manager chart values pulling the
+// controller, webhook, cainjector, ACME solver and startup check images from
+// mirror
+func certManagerImageValues(mirror string) map[string]interface{} {
+	mirror = normalizeRegistryMirror(mirror)
+	image := func(name string) map[string]interface{} {
+		return map[string]interface{}{"repository": mirror + "/jetstack/cert-manager-" + name}
+	}
+	return map[string]interface{}{
+		"image":           image("controller"),
+		"webhook":         map[string]interface{}{"image": image("webhook")},
+		"cainjector":      map[string]interface{}{"image": image("cainjector")},
+		"acmesolver":      map[string]interface{}{"image": image("acmesolver")},
+		"startupapicheck": map[string]interface{}{"image": image("startupapicheck")},
+	}
+}
+
+// addonImageValues are the image values of the addon charts that
+// image_registry_mirror rewrites, by chart name
+var addonImageValues = map[string]func(mirror string) map[string]interface{}{
+	"cert-manager":  certManagerImageValues,
+	"metallb":       metallbImageValues,
+	"ingress-nginx": nginxIngressImageValues,
+}
+
+// chartBaseName returns the name of a chart reference, e.g. cert-manager for
+// jetstack/cert-manager or oci://quay.io/jetstack/charts/cert-manager:v1.16.1
+func chartBaseName(chart string) string {
+	name := chart[strings.LastIndex(chart, "/")+1:]
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// mirroredChartImageValues returns the values pulling the images of chart
+// from mirror. Charts without known image values are an error, since the
+// mirror would otherwise be ignored without notice.
+func mirroredChartImageValues(chart, mirror string) (map[string]interface{}, error) {
+	values, ok := addonImageValues[chartBaseName(chart)]
+	if !ok {
+		names := make([]string, 0, len(addonImageValues))
+		for name := range addonImageValues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("image_registry_mirror supports the %s charts, not %q; set the image values of the chart instead", strings.Join(names, ", "), chart)
+	}
+	return values(mirror), nil
+}
+
+// mergeHelmValues deep-merges src into dst, with src winning on conflicts
+func mergeHelmValues(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]interface{}); ok {
+			if dstMap, ok := dst[k].(map[string]interface{}); ok {
+				dst[k] = mergeHelmValues(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// renderHelmValues renders chart values as YAML for ChartSpec.ValuesYaml
+func renderHelmValues(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	out, err := yaml.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render chart values: %w", err)
+	}
+	return string(out), nil
+}
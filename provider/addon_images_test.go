@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNginxIngressValues(t *testing.T) {
+	rendered, err := renderHelmValues(nginxIngressValues("10.10.88.80", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var values struct {
+		Controller struct {
+			IngressClassResource struct {
+				Default bool `yaml:"default"`
+			} `yaml:"ingressClassResource"`
+			Service struct {
+				Type           string `yaml:"type"`
+				LoadBalancerIP string `yaml:"loadBalancerIP"`
+			} `yaml:"service"`
+			Image map[string]string `yaml:"image"`
+		} `yaml:"controller"`
+	}
+	if err := yaml.Unmarshal([]byte(rendered), &values); err != nil {
+		t.Fatalf("rendered values are not valid YAML: %v", err)
+	}
+	if !values.Controller.IngressClassResource.Default || values.Controller.Service.Type != "LoadBalancer" ||
+		values.Controller.Service.LoadBalancerIP != "10.10.88.80" {
+		t.Errorf("unexpected values:\n%s", rendered)
+	}
+	if values.Controller.Image != nil {
+		t.Errorf("expected no image overrides without a mirror, got %v", values.Controller.Image)
+	}
+}
+
+func TestNginxIngressValues_Mirror(t *testing.T) {
+	values := nginxIngressValues("", "registry.lan:5000/")
+	controller := values["controller"].(map[string]interface{})
+
+	if _, ok := controller["service"].(map[string]interface{})["loadBalancerIP"]; ok {
+		t.Error("expected no loadBalancerIP when unset")
+	}
+	if controller["ingressClassResource"] == nil {
+		t.Error("expected mirror overrides to be merged, not replace the controller values")
+	}
+	image := controller["image"].(map[string]interface{})
+	if image["registry"] != "registry.lan:5000" || image["digest"] != "" {
+		t.Errorf("unexpected controller image values: %v", image)
+	}
+	patch := controller["admissionWebhooks"].(map[string]interface{})["patch"].(map[string]interface{})
+	if patch["image"].(map[string]interface{})["registry"] != "registry.lan:5000" {
+		t.Errorf("expected webhook certgen image from the mirror, got %v", patch)
+	}
+}
+
+func TestMetallbImageValues(t *testing.T) {
+	rendered, err := renderHelmValues(metallbImageValues("harbor.lan/mirror"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"repository: harbor.lan/mirror/metallb/controller",
+		"repository: harbor.lan/mirror/metallb/speaker",
+		"repository: harbor.lan/mirror/frrouting/frr",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("expected %q in values:\n%s", want, rendered)
+		}
+	}
+}
+
+func TestMirroredChartImageValues(t *testing.T) {
+	for _, chart := range []string{"jetstack/cert-manager", "oci://quay.io/jetstack/charts/cert-manager:v1.16.1"} {
+		values, err := mirroredChartImageValues(chart, "registry.lan:5000/")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", chart, err)
+		}
+		rendered, err := renderHelmValues(values)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, name := range []string{"controller", "webhook", "cainjector", "acmesolver", "startupapicheck"} {
+			if want := "repository: registry.lan:5000/jetstack/cert-manager-" + name; !strings.Contains(rendered, want) {
+				t.Errorf("%s: expected %q in values:\n%s", chart, want, rendered)
+			}
+		}
+	}
+
+	if _, err := mirroredChartImageValues("bitnami/redis", "registry.lan:5000"); err == nil || !strings.Contains(err.Error(), "cert-manager, ingress-nginx, metallb") {
+		t.Errorf("expected an error naming the supported charts, got %v", err)
+	}
+}
+
+func TestImageRegistryMirrorSchema_Validate(t *testing.T) {
+	validate := imageRegistryMirrorSchema().ValidateFunc
+	if _, errs := validate("registry.lan:5000", "image_registry_mirror"); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if _, errs := validate("https://registry.lan", "image_registry_mirror"); len(errs) == 0 {
+		t.Error("expected error for a mirror with a scheme")
+	}
+}
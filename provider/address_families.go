@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// addressFamilies is the IPv4 and IPv6 part of a comma-separated list of
+// CIDRs or addresses; either may be empty
+type addressFamilies struct {
+	IPv4 string
+	IPv6 string
+}
+
+func (f addressFamilies) dualStack() bool {
+	return f.IPv4 != "" && f.IPv6 != ""
+}
+
+// describe names the families for error messages
+func (f addressFamilies) describe() string {
+	switch {
+	case f.dualStack():
+		return "dual-stack"
+	case f.IPv6 != "":
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// splitAddressFamilies parses a comma-separated list of at most one IPv4 and
+// one IPv6 value. parse returns the IP of one value, or nil if invalid.
+func splitAddressFamilies(value, what string, parse func(string) net.IP) (addressFamilies, error) {
+	var families addressFamilies
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		ip := parse(part)
+		if ip == nil {
+			return families, fmt.Errorf("%q is not a valid %s", part, what)
+		}
+		slot := &families.IPv6
+		if ip.To4() != nil {
+			slot = &families.IPv4
+		}
+		if *slot != "" {
+			return families, fmt.Errorf("%q has more than one %s of the same address family, use one IPv4 and one IPv6 %s for dual-stack", value, what, what)
+		}
+		*slot = part
+	}
+	return families, nil
+}
+
+func parseCIDRFamilies(value string) (addressFamilies, error) {
+	return splitAddressFamilies(value, "CIDR", func(s string) net.IP {
+		ip, _, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil
+		}
+		return ip
+	})
+}
+
+func parseIPFamilies(value string) (addressFamilies, error) {
+	return splitAddressFamilies(value, "IP address", net.ParseIP)
+}
+
+// validateCIDRFamilies checks the pod and service CIDRs of a cluster and
+// that both cover the same address families. It returns the pod families.
+func validateCIDRFamilies(podCIDRs, serviceCIDRs, podKey, serviceKey string) (addressFamilies, error) {
+	pods, err := parseCIDRFamilies(podCIDRs)
+	if err != nil {
+		return pods, fmt.Errorf("%s: %w", podKey, err)
+	}
+	services, err := parseCIDRFamilies(serviceCIDRs)
+	if err != nil {
+		return pods, fmt.Errorf("%s: %w", serviceKey, err)
+	}
+	if (pods.IPv4 == "") != (services.IPv4 == "") || (pods.IPv6 == "") != (services.IPv6 == "") {
+		return pods, fmt.Errorf("%s is %s but %s is %s: both must cover the same address families", podKey, pods.describe(), serviceKey, services.describe())
+	}
+	return pods, nil
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Values of the bmc_response_format provider option
+const (
+	responseFormatAuto   = "auto"
+	responseFormatLegacy = "legacy"
+	responseFormatV2     = "v2"
+)
+
+// bmcResponseFormat pins the response format parsers accept. In auto mode they
+// try the v2 format ([{"result": ...}]) first and fall back to the legacy
+// format ([[key, value], ...]). Set from the provider configuration.
+var bmcResponseFormat = responseFormatAuto
+
+// parseV2Responses reports whether parsers may accept v2 responses
+func parseV2Responses() bool {
+	return bmcResponseFormat != responseFormatLegacy
+}
+
+// parseLegacyResponses reports whether parsers may accept legacy responses
+func parseLegacyResponses() bool {
+	return bmcResponseFormat != responseFormatV2
+}
+
+// maxDecodeErrorBody caps how much of an undecodable response is quoted in errors
+const maxDecodeErrorBody = 512
+
+// bmcCompatibilityHint suggests a provider/firmware pairing for BMC daemon
+// versions below Below. The last entry has no bound.
+type bmcCompatibilityHint struct {
+	Below string
+	Hint  string
+}
+
+// bmcCompatibilityHints maps BMC daemon versions to the fix for responses the
+// provider cannot decode, ordered by version
+var bmcCompatibilityHints = []bmcCompatibilityHint{
+	{
+		Below: "2.0.0",
+		Hint: "BMC firmware 1.x returns legacy array responses, which newer resources do not understand. " +
+			"Upgrade the BMC to firmware 2.0.5 or later.",
+	},
+	{
+		Below: "2.0.5",
+		Hint: "BMC firmware 2.0.0 - 2.0.4 returns object responses whose flash and USB status formats changed again in 2.0.5. " +
+			"Upgrade the BMC to firmware 2.0.5 or later, and the provider to 1.3.10 or later.",
+	},
+	{
+		Below: "2.4.0",
+		Hint: "This firmware is supported, so the response is probably not from the BMC API. " +
+			"Check that endpoint points at the BMC and not at a proxy or login page.",
+	},
+	{
+		Hint: "This firmware is newer than the releases this provider is tested against (2.3.x). " +
+			"Upgrade the provider, or pin the BMC firmware with turingpi_bmc_firmware target_version.",
+	},
+}
+
+// bmcDecodeError is returned when a BMC response does not have the expected
+// shape, which usually means the provider and BMC firmware speak different
+// API versions. It carries the detected versions and a pairing hint.
+type bmcDecodeError struct {
+	Request    string
+	Version    string
+	APIVersion string
+	Body       string
+	Err        error
+}
+
+func (e *bmcDecodeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to decode BMC response to %s: %v", e.Request, e.Err)
+	if e.Version == "" {
+		b.WriteString("\nBMC firmware version could not be detected. " +
+			"Check that endpoint points at a Turing Pi BMC running firmware 2.0.5 or later.")
+	} else {
+		apiVersion := e.APIVersion
+		if apiVersion == "" {
+			apiVersion = "unknown"
+		}
+		fmt.Fprintf(&b, "\nBMC firmware %s (API %s). %s", e.Version, apiVersion, compatibilityHint(e.Version))
+	}
+	fmt.Fprintf(&b, "\nResponse: %s", e.Body)
+	return b.String()
+}
+
+func (e *bmcDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// compatibilityHint returns the pairing hint for a BMC daemon version
+func compatibilityHint(daemonVersion string) string {
+	v, err := version.NewVersion(strings.TrimPrefix(strings.TrimSpace(daemonVersion), "v"))
+	if err != nil {
+		return "The provider is tested against BMC firmware 2.0.5 - 2.3.x."
+	}
+	for _, h := range bmcCompatibilityHints {
+		if h.Below == "" || v.LessThan(version.Must(version.NewVersion(h.Below))) {
+			return h.Hint
+		}
+	}
+	return ""
+}
+
+// decodeBMCResponse decodes the JSON body of resp into out. On failure the
+// BMC version is looked up, so the error explains the likely mismatch instead
+// of only reporting the unmarshal error.
+func decodeBMCResponse(resp *http.Response, endpoint, token string, out interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		decodeErr := &bmcDecodeError{
+			Request: describeBMCRequest(resp.Request),
+			Body:    truncateBody(body),
+			Err:     err,
+		}
+		decodeErr.Version, decodeErr.APIVersion = detectBMCVersion(endpoint, token)
+		return decodeErr
+	}
+	return nil
+}
+
+// detectBMCVersion returns the daemon and API versions from the about
+// endpoint, or empty strings when the BMC does not report them
+func detectBMCVersion(endpoint, token string) (daemonVersion, apiVersion string) {
+	data, err := fetchBMCAbout(endpoint, token)
+	if err != nil {
+		return "", ""
+	}
+	about := parseAboutResponse(data)
+	return about["version"], about["api"]
+}
+
+// describeBMCRequest names a BMC request for errors, e.g. "get power"
+func describeBMCRequest(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return "request"
+	}
+	query := req.URL.Query()
+	if t := query.Get("type"); t != "" {
+		return strings.TrimSpace(query.Get("opt") + " " + t)
+	}
+	return req.URL.Path
+}
+
+func truncateBody(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) > maxDecodeErrorBody {
+		return s[:maxDecodeErrorBody] + "..."
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompatibilityHint(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.1.0", "firmware 1.x"},
+		{"2.0.3", "2.0.0 - 2.0.4"},
+		{"v2.0.4", "2.0.0 - 2.0.4"},
+		{"2.0.5", "supported"},
+		{"2.3.4", "supported"},
+		{"2.4.0", "newer than the releases"},
+		{"3.0.1", "newer than the releases"},
+		{"garbage", "tested against BMC firmware 2.0.5 - 2.3.x"},
+	}
+	for _, tt := range tests {
+		if got := compatibilityHint(tt.version); !strings.Contains(got, tt.want) {
+			t.Errorf("compatibilityHint(%q) = %q, want it to contain %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeBMCResponse_MismatchIncludesVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("type") {
+		case "about":
+			_, _ = w.Write([]byte(`{"response":[{"result":{"api":"1.0","version":"2.0.3"}}]}`))
+		case "flash":
+			// A response shape the provider does not expect
+			_, _ = w.Write([]byte(`{"Transferring":{"id":1},"Done":"yes"}`))
+		}
+	}))
+	defer server.Close()
+
+	_, err := getFlashStatus(server.URL, "test-token")
+	if err == nil {
+		t.Fatal("expected a decode error")
+	}
+
+	var decodeErr *bmcDecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a bmcDecodeError, got %T: %v", err, err)
+	}
+	if decodeErr.Version != "2.0.3" || decodeErr.APIVersion != "1.0" {
+		t.Errorf("expected detected versions 2.0.3 / 1.0, got %q / %q", decodeErr.Version, decodeErr.APIVersion)
+	}
+	msg := err.Error()
+	for _, want := range []string{"get flash", "BMC firmware 2.0.3 (API 1.0)", "the provider to 1.3.10", `"Done":"yes"`} {
+		if !strings.Contains(strings.ToLower(msg), strings.ToLower(want)) {
+			t.Errorf("expected error to contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestDecodeBMCResponse_NotABMC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>" + strings.Repeat("login ", 200) + "</html>"))
+	}))
+	defer server.Close()
+
+	_, err := getPowerStatus(server.URL, "test-token")
+	if err == nil {
+		t.Fatal("expected a decode error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "could not be detected") {
+		t.Errorf("expected an undetected version hint, got:\n%s", msg)
+	}
+	if !strings.HasSuffix(msg, "...") {
+		t.Errorf("expected the response body to be truncated, got:\n%s", msg)
+	}
+}
+
+// useResponseFormat pins bmc_response_format for one test
+func useResponseFormat(t *testing.T, format string) {
+	t.Helper()
+	previous := bmcResponseFormat
+	bmcResponseFormat = format
+	t.Cleanup(func() { bmcResponseFormat = previous })
+}
+
+func TestBMCResponseFormat_Power(t *testing.T) {
+	legacy := &powerStatusResponse{Response: []byte(`[["node1", "1"], ["node2", "0"]]`)}
+	v2 := &powerStatusResponse{Response: []byte(`[{"result": [{"node1": 1, "node2": 0}]}]`)}
+
+	tests := []struct {
+		format     string
+		wantLegacy bool
+		wantV2     bool
+	}{
+		{responseFormatAuto, true, true},
+		{responseFormatLegacy, true, false},
+		{responseFormatV2, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			useResponseFormat(t, tt.format)
+			if got := parsePowerStatus(legacy)["node1"]; got != tt.wantLegacy {
+				t.Errorf("legacy response: node1 = %v, want %v", got, tt.wantLegacy)
+			}
+			if got := parsePowerStatus(v2)["node1"]; got != tt.wantV2 {
+				t.Errorf("v2 response: node1 = %v, want %v", got, tt.wantV2)
+			}
+		})
+	}
+}
+
+func TestBMCResponseFormat_USB(t *testing.T) {
+	legacy := &usbStatusResponse{Response: []byte(`[["mode", "Device"], ["node", 2], ["route", "BMC"]]`)}
+
+	useResponseFormat(t, responseFormatV2)
+	if mode, node, route := parseUSBStatus(legacy); mode != "host" || node != 1 || route != "usb-a" {
+		t.Errorf("expected defaults when legacy responses are not accepted, got %s/%d/%s", mode, node, route)
+	}
+
+	bmcResponseFormat = responseFormatLegacy
+	if mode, node, route := parseUSBStatus(legacy); mode != "device" || node != 3 || route != "bmc" {
+		t.Errorf("expected the legacy response to be parsed, got %s/%d/%s", mode, node, route)
+	}
+}
+
+func TestBMCResponseFormat_FlashTransfer(t *testing.T) {
+	status := &flashStatusResponse{Transferring: []byte(`[1024, 4096]`)}
+
+	useResponseFormat(t, responseFormatV2)
+	if inProgress, written, total := status.isTransferring(); !inProgress || written != 0 || total != 0 {
+		t.Errorf("expected no progress from a legacy response in v2 mode, got %v %d/%d", inProgress, written, total)
+	}
+
+	bmcResponseFormat = responseFormatAuto
+	if _, written, total := status.isTransferring(); written != 1024 || total != 4096 {
+		t.Errorf("expected legacy progress in auto mode, got %d/%d", written, total)
+	}
+}
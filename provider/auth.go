@@ -18,7 +18,11 @@ func authenticate(endpoint, username, password string) (string, error) {
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("authentication failed with status: %d", resp.StatusCode)
+		err := fmt.Errorf("authentication failed with status: %d", resp.StatusCode)
+		if resp.StatusCode == 401 || resp.StatusCode == 403 {
+			err = categorize(errorCategoryAuth, err)
+		}
+		return "", err
 	}
 
 	var result map[string]string
@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
+)
+
+// Restart handling settings. The BMC refuses connections for a minute or two
+// after a reboot, a daemon reload or a firmware upgrade; the upgrade only
+// reboots once the new image is written, minutes after it was started.
+// Replaced in tests to keep them fast.
+var (
+	bmcRestartWindow        = 15 * time.Minute
+	bmcRestartWait          = 3 * time.Minute
+	bmcRestartRetryInterval = 5 * time.Second
+)
+
+// bmcRestartTypes are the opt=set types after which the BMC restarts
+var bmcRestartTypes = map[string]bool{
+	"reboot":   true,
+	"reload":   true,
+	"firmware": true,
+}
+
+// bmcRestart remembers when this provider last restarted the BMC, so that
+// requests of other resources that fail while it is down wait for it to come
+// back instead of failing the apply
+type bmcRestart struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+// mark records a restart started now
+func (r *bmcRestart) mark() {
+	r.mu.Lock()
+	r.at = time.Now()
+	r.mu.Unlock()
+}
+
+// recent reports whether a restart started within bmcRestartWindow
+func (r *bmcRestart) recent() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.at.IsZero() && time.Since(r.at) < bmcRestartWindow
+}
+
+// since reports whether a restart started after t
+func (r *bmcRestart) since(t time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.at.After(t)
+}
+
+// isBMCRestartRequest reports whether req reboots the BMC or its daemon
+func isBMCRestartRequest(req *http.Request) bool {
+	query := req.URL.Query()
+	return strings.HasSuffix(req.URL.Path, "/api/bmc") && query.Get("opt") == "set" && bmcRestartTypes[query.Get("type")]
+}
+
+// isBMCRestartError reports whether err is what a request sees while the BMC
+// is down: the connection is refused, reset or cut off, or the host is gone
+func isBMCRestartError(err error) bool {
+	return isBMCUnreachableError(err) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isBMCUnreachableError reports whether err shows that a request never
+// reached the BMC: the connection was refused or the host is gone
+func isBMCUnreachableError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+// isBMCMutation reports whether req changes state on the BMC: an opt=set
+// GET, or any request with a method other than GET and HEAD
+func isBMCMutation(req *http.Request) bool {
+	return isGETMutation(req) || (req.Method != http.MethodGet && req.Method != http.MethodHead)
+}
+
+// canResend reports whether req, which failed with err, can be sent again.
+// A mutation that was cut off after it was sent, e.g. with io.EOF or
+// ECONNRESET, may already have been applied, so it is only resent when the
+// connection never reached the BMC.
+func canResend(req *http.Request, err error) bool {
+	if isBMCMutation(req) {
+		return isBMCUnreachableError(err)
+	}
+	return isBMCRestartError(err)
+}
+
+// retry resends req, which failed with err, every bmcRestartRetryInterval
+// while the BMC restarts, for up to bmcRestartWait. Requests whose body
+// cannot be sent again are not retried, nor are mutations that may have
+// reached the BMC (see canResend). With a session, a request rejected after
+// the restart is sent again with a new token, since the BMC forgets its
+// sessions when it reboots.
+func (r *bmcRestart) retry(base http.RoundTripper, req *http.Request, session *bmcSession, err error) (*http.Response, error) {
+	if !r.recent() || !canResend(req, err) {
+		return nil, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	var resp *http.Response
+	attempt := 0
+	waitErr := poll(ctx, bmcRestartRetryInterval, bmcRestartWait, "the BMC to come back after a restart", func(context.Context) (bool, error) {
+		attempt++
+		metrics.countRetry(retryKindBMCRestart)
+		log.Printf("[WARN] BMC unreachable after a restart, retrying %s %s (attempt %d): %v",
+			req.Method, req.URL.Redacted(), attempt, err)
+
+		var sendErr error
+		resp, sendErr = resend(base, req, session)
+		if sendErr == nil && resp.StatusCode == http.StatusUnauthorized && session != nil {
+			_ = resp.Body.Close()
+			if refreshErr := session.refresh(); refreshErr != nil {
+				return false, waitutil.Permanent(fmt.Errorf("BMC rejected the session after a restart: %w", refreshErr))
+			}
+			log.Printf("[INFO] BMC session renewed after a restart")
+			resp, sendErr = resend(base, req, session)
+		}
+		if sendErr == nil {
+			return true, nil
+		}
+		err = sendErr
+		if !canResend(req, err) {
+			return false, waitutil.Permanent(err)
+		}
+		return false, err
+	})
+
+	var timeoutErr *waitutil.TimeoutError
+	switch {
+	case waitErr == nil:
+		return resp, nil
+	case errors.As(waitErr, &timeoutErr):
+		return nil, fmt.Errorf("%w (the BMC did not come back within %s of a restart)", err, bmcRestartWait)
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("%w (gave up waiting for the BMC: %v)", err, ctx.Err())
+	default:
+		return nil, waitErr
+	}
+}
+
+// resend sends a copy of req with a fresh body and the current session token
+func resend(base http.RoundTripper, req *http.Request, session *bmcSession) (*http.Response, error) {
+	again := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		again.Body = &countingBody{ReadCloser: body, n: &metrics.uploadBytes}
+	}
+	if session != nil {
+		session.authorize(again)
+	}
+	return base.RoundTrip(again)
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// downTransport refuses the first down connections, or fails them with err
+// when set, then answers 200 with the request body
+type downTransport struct {
+	down   int
+	err    error
+	calls  int
+	bodies []string
+}
+
+func (t *downTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	if t.calls <= t.down {
+		if t.err != nil {
+			return nil, t.err
+		}
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	}
+	var body string
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+	}
+	t.bodies = append(t.bodies, body)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+}
+
+func fastBMCRestart(t *testing.T) {
+	t.Helper()
+	wait, interval := bmcRestartWait, bmcRestartRetryInterval
+	bmcRestartWait, bmcRestartRetryInterval = 50*time.Millisecond, time.Millisecond
+	t.Cleanup(func() { bmcRestartWait, bmcRestartRetryInterval = wait, interval })
+}
+
+func TestBMCTransport_WaitsForRestart(t *testing.T) {
+	fastBMCRestart(t)
+	base := &downTransport{}
+	client := &http.Client{Transport: &bmcTransport{base: base, restart: &bmcRestart{}}}
+
+	resp, err := client.Get("http://bmc/api/bmc?opt=set&type=reboot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// The BMC is down for the next two connections
+	base.down = base.calls + 2
+	resp, err = client.Post("http://bmc/api/bmc", "application/json", strings.NewReader(`{"opt":"set","type":"power"}`))
+	if err != nil {
+		t.Fatalf("expected the request to wait for the restart, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if base.calls != 4 {
+		t.Errorf("expected two retries, got %d calls", base.calls)
+	}
+	if got := base.bodies[len(base.bodies)-1]; got != `{"opt":"set","type":"power"}` {
+		t.Errorf("expected the body to be sent again, got %q", got)
+	}
+}
+
+func TestBMCTransport_RestartErrors(t *testing.T) {
+	fastBMCRestart(t)
+
+	// Without a restart, connection errors fail at once
+	base := &downTransport{down: 1}
+	client := &http.Client{Transport: &bmcTransport{base: base, restart: &bmcRestart{}}}
+	if _, err := client.Get("http://bmc/api/bmc?opt=get&type=about"); err == nil || base.calls != 1 {
+		t.Errorf("expected no retry without a restart, got %v after %d calls", err, base.calls)
+	}
+
+	// A BMC that stays down is given up on after bmcRestartWait
+	restart := &bmcRestart{}
+	restart.mark()
+	base = &downTransport{down: 1 << 30}
+	client = &http.Client{Transport: &bmcTransport{base: base, restart: restart}}
+	start := time.Now()
+	_, err := client.Get("http://bmc/api/bmc?opt=get&type=about")
+	if err == nil || !strings.Contains(err.Error(), "did not come back") {
+		t.Errorf("expected the wait to be bounded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected to give up after bmcRestartWait, took %s", time.Since(start))
+	}
+
+	// Restart requests themselves are not sent twice
+	base = &downTransport{down: 1}
+	client = &http.Client{Transport: &bmcTransport{base: base, restart: restart}}
+	if _, err := client.Get("http://bmc/api/bmc?opt=set&type=reboot"); err == nil || base.calls != 1 {
+		t.Errorf("expected the reboot not to be retried, got %v after %d calls", err, base.calls)
+	}
+}
+
+func TestBMCTransport_CutOffMutations(t *testing.T) {
+	fastBMCRestart(t)
+	restart := &bmcRestart{}
+	restart.mark()
+
+	// A cut off read is sent again
+	base := &downTransport{down: 1, err: io.EOF}
+	client := &http.Client{Transport: &bmcTransport{base: base, restart: restart}}
+	resp, err := client.Get("http://bmc/api/bmc?opt=get&type=about")
+	if err != nil {
+		t.Fatalf("expected the read to be sent again, got %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// A cut off mutation may have been applied, so it is not
+	for _, cutOff := range []error{io.EOF, syscall.ECONNRESET} {
+		base = &downTransport{down: 1, err: cutOff}
+		client = &http.Client{Transport: &bmcTransport{base: base, restart: restart}}
+		if _, err := client.Get("http://bmc/api/bmc?opt=set&type=power&node1=1"); err == nil || base.calls != 1 {
+			t.Errorf("%v: expected the mutation not to be resent, got %v after %d calls", cutOff, err, base.calls)
+		}
+	}
+
+	// A mutation cut off while waiting for the restart stops the wait
+	base = &downTransport{down: 1 << 30}
+	flaky := &cutOffAfter{downTransport: base, after: 2}
+	client = &http.Client{Transport: &bmcTransport{base: flaky, restart: restart}}
+	if _, err := client.Post("http://bmc/api/bmc", "application/json", strings.NewReader(`{"opt":"set","type":"power"}`)); err == nil || base.calls != 2 {
+		t.Errorf("expected the wait to stop at the cut off mutation, got %v after %d calls", err, base.calls)
+	}
+}
+
+// cutOffAfter fails the connections of downTransport with io.EOF from the
+// after-th call on
+type cutOffAfter struct {
+	*downTransport
+	after int
+}
+
+func (t *cutOffAfter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.calls+1 >= t.after {
+		t.err = io.EOF
+	}
+	return t.downTransport.RoundTrip(req)
+}
+
+func TestIsBMCRestartRequest(t *testing.T) {
+	for query, want := range map[string]bool{
+		"opt=set&type=reboot":         true,
+		"opt=set&type=reload":         true,
+		"opt=set&type=firmware&local": true,
+		"opt=get&type=firmware":       false,
+		"opt=set&type=power&node1=1":  false,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "http://bmc/api/bmc?"+query, nil)
+		if got := isBMCRestartRequest(req); got != want {
+			t.Errorf("%s: expected %v, got %v", query, want, got)
+		}
+	}
+}
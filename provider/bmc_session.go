@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultReauthInterval is how often the BMC session is renewed. The BMC
+// expires tokens after a few hours, which a long firmware upgrade plus four
+// node flashes can outlive.
+const defaultReauthInterval = "30m"
+
+// bmcSession holds the current BMC token and renews it in the background.
+// Resources keep sending the token they were configured with; bmcTransport
+// swaps in the current one, so no call site needs to know about renewals.
+type bmcSession struct {
+	endpoint string
+	username string
+	password string
+
+	mu    sync.RWMutex
+	token string
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// activeBMCSessions are the sessions of this provider process, whose renewal
+// is stopped by Shutdown
+var (
+	activeBMCSessionsMu sync.Mutex
+	activeBMCSessions   []*bmcSession
+)
+
+// stopBMCSessions stops the background renewal of the sessions of this
+// provider process
+func stopBMCSessions() {
+	activeBMCSessionsMu.Lock()
+	defer activeBMCSessionsMu.Unlock()
+	for _, s := range activeBMCSessions {
+		s.Stop()
+	}
+	activeBMCSessions = nil
+}
+
+func newBMCSession(endpoint, username, password, token string) *bmcSession {
+	return &bmcSession{
+		endpoint: endpoint,
+		username: username,
+		password: password,
+		token:    token,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Token returns the current session token
+func (s *bmcSession) Token() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token
+}
+
+// refresh authenticates again and stores the new token. On failure the
+// previous token is kept, since it may still be valid.
+func (s *bmcSession) refresh() error {
+	token, err := authenticate(s.endpoint, s.username, s.password)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return nil
+	}
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	return nil
+}
+
+// startKeepalive renews the token every interval until Stop is called, at
+// the latest by Shutdown. A zero interval disables renewal.
+func (s *bmcSession) startKeepalive(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	activeBMCSessionsMu.Lock()
+	activeBMCSessions = append(activeBMCSessions, s)
+	activeBMCSessionsMu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.refresh(); err != nil {
+					log.Printf("[WARN] BMC session renewal failed, keeping the current token: %v", err)
+					continue
+				}
+				log.Printf("[DEBUG] BMC session renewed")
+			}
+		}
+	}()
+}
+
+// Stop ends background renewal
+func (s *bmcSession) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// authorize replaces the bearer token of req with the current session token
+func (s *bmcSession) authorize(req *http.Request) {
+	if strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+		req.Header.Set("Authorization", "Bearer "+s.Token())
+	}
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeAuthBMC issues token-1, token-2, ... on each authentication and
+// records the bearer token of every other request
+func newFakeAuthBMC(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var issued int
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/api/bmc/authenticate" {
+			issued++
+			_, _ = fmt.Fprintf(w, `{"id":"token-%d"}`, issued)
+			return
+		}
+		seen = append(seen, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestBMCSession_Refresh(t *testing.T) {
+	server, _ := newFakeAuthBMC(t)
+
+	s := newBMCSession(server.URL, "root", "turing", "token-0")
+	if err := s.refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.Token(); got != "token-1" {
+		t.Errorf("expected token-1, got %q", got)
+	}
+}
+
+func TestBMCSession_RefreshFailureKeepsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	s := newBMCSession(server.URL, "root", "wrong", "token-0")
+	if err := s.refresh(); err == nil {
+		t.Error("expected error for failed authentication")
+	}
+	if got := s.Token(); got != "token-0" {
+		t.Errorf("expected previous token to be kept, got %q", got)
+	}
+}
+
+func TestBMCSession_Keepalive(t *testing.T) {
+	server, _ := newFakeAuthBMC(t)
+
+	s := newBMCSession(server.URL, "root", "turing", "token-0")
+	s.startKeepalive(10 * time.Millisecond)
+	defer s.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Token() == "token-0" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the token to be renewed in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBMCTransport_UsesSessionToken(t *testing.T) {
+	server, seen := newFakeAuthBMC(t)
+
+	s := newBMCSession(server.URL, "root", "turing", "token-0")
+	client := &http.Client{Transport: &bmcTransport{session: s}}
+	oldClient := HTTPClient
+	HTTPClient = client
+	defer func() { HTTPClient = oldClient }()
+
+	if err := s.refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Resources keep sending the token they were configured with
+	req, _ := http.NewRequest("GET", server.URL+"/api/bmc?opt=get&type=power", nil)
+	req.Header.Set("Authorization", "Bearer token-0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	got := seen()
+	if len(got) != 1 || got[0] != "Bearer token-1" {
+		t.Errorf("expected the renewed token to be sent, got %v", got)
+	}
+	if req.Header.Get("Authorization") != "Bearer token-0" {
+		t.Error("caller's request should not be modified")
+	}
+}
+
+func TestStopBMCSessions(t *testing.T) {
+	server, _ := newFakeAuthBMC(t)
+
+	s := newBMCSession(server.URL, "root", "turing", "token-0")
+	s.startKeepalive(time.Hour)
+	stopBMCSessions()
+
+	select {
+	case <-s.stop:
+	default:
+		t.Error("expected Shutdown to stop the session renewal")
+	}
+	if len(activeBMCSessions) != 0 {
+		t.Errorf("expected no active sessions, got %d", len(activeBMCSessions))
+	}
+}
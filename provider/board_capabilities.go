@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Install disks of compute modules. RK1 modules can install to an NVMe drive
+// in their slot, which is larger and faster than the eMMC.
+const (
+	emmcInstallDisk = "/dev/mmcblk0"
+	nvmeInstallDisk = "/dev/nvme0n1"
+)
+
+// boardCapabilities describes the board and compute modules reported by the
+// BMC, so resources can pick hardware-aware defaults
+type boardCapabilities struct {
+	Revision string
+	SoC      string
+	// Modules maps slot number (1-4) to the module name configured on the BMC.
+	// Empty when the firmware does not report node info.
+	Modules map[int]string
+}
+
+// Model returns the board model derived from the revision, e.g. "Turing Pi 2.5".
+// Empty when the firmware does not report a revision.
+func (c *boardCapabilities) Model() string {
+	switch {
+	case c.Revision == "":
+		return ""
+	case c.IsTuringPi25():
+		return "Turing Pi 2.5"
+	default:
+		return "Turing Pi 2"
+	}
+}
+
+// IsTuringPi25 reports whether the board is a Turing Pi 2.5
+func (c *boardCapabilities) IsTuringPi25() bool {
+	return strings.Contains(c.Revision, "2.5")
+}
+
+// IsRK1 reports whether the module in slot is a Turing RK1
+func (c *boardCapabilities) IsRK1(slot int) bool {
+	return isRK1Module(c.Modules[slot])
+}
+
+// isRK1Module reports whether a BMC module name refers to a Turing RK1
+func isRK1Module(name string) bool {
+	return strings.Contains(strings.ToUpper(name), "RK1")
+}
+
+// detectBoardCapabilities queries the BMC for the board revision and the
+// module in each slot. Module names come from the node_info endpoint, which
+// older firmware does not provide; its absence is not an error.
+func detectBoardCapabilities(endpoint, token string) (*boardCapabilities, error) {
+	aboutData, err := fetchBMCAbout(endpoint, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch BMC about info: %w", err)
+	}
+	caps := &boardCapabilities{Modules: make(map[int]string)}
+	caps.Revision, caps.SoC = parseBoardInfo(parseAboutResponse(aboutData))
+
+	if data, err := fetchBMCNodeInfo(endpoint, token); err == nil {
+		for slot, fields := range parseNodeInfoResponse(data) {
+			caps.Modules[slot] = fields["module_name"]
+		}
+	}
+	return caps, nil
+}
+
+// diskNamePattern matches block device names listed by talosctl
+var diskNamePattern = regexp.MustCompile(`^(/dev/)?(mmcblk\d+|nvme\d+n\d+|sd[a-z]+)$`)
+
+// parseTalosDisks extracts device paths from `talosctl get disks` (ID column)
+// or the older `talosctl disks` (DEV column) table output
+func parseTalosDisks(output string) []string {
+	var disks []string
+	for _, line := range strings.Split(output, "\n") {
+		for _, field := range strings.Fields(line) {
+			if m := diskNamePattern.FindStringSubmatch(field); m != nil {
+				disks = append(disks, "/dev/"+m[2])
+				break
+			}
+		}
+	}
+	return disks
+}
+
+// selectRK1InstallDisk prefers NVMe over eMMC for RK1 modules
+func selectRK1InstallDisk(disks []string) string {
+	for _, disk := range disks {
+		if disk == nvmeInstallDisk {
+			return nvmeInstallDisk
+		}
+	}
+	return emmcInstallDisk
+}
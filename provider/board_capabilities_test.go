@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDetectBoardCapabilities(t *testing.T) {
+	server := newFakeInventoryBMC(t, true)
+
+	caps, err := detectBoardCapabilities(server.URL, "test-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if caps.Model() != "Turing Pi 2.5" || !caps.IsTuringPi25() {
+		t.Errorf("expected Turing Pi 2.5, got %q", caps.Model())
+	}
+	if !caps.IsRK1(1) || !caps.IsRK1(2) || caps.IsRK1(3) || caps.IsRK1(4) {
+		t.Errorf("unexpected RK1 detection for modules %v", caps.Modules)
+	}
+}
+
+func TestDetectBoardCapabilities_WithoutNodeInfo(t *testing.T) {
+	server := newFakeInventoryBMC(t, false)
+
+	caps, err := detectBoardCapabilities(server.URL, "test-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(caps.Modules) != 0 || caps.IsRK1(1) {
+		t.Errorf("expected no modules without node_info, got %v", caps.Modules)
+	}
+}
+
+func TestBoardCapabilities_Model(t *testing.T) {
+	tests := []struct {
+		revision string
+		expected string
+	}{
+		{"", ""},
+		{"2.4", "Turing Pi 2"},
+		{"2.5.2", "Turing Pi 2.5"},
+	}
+
+	for _, tt := range tests {
+		caps := &boardCapabilities{Revision: tt.revision}
+		if got := caps.Model(); got != tt.expected {
+			t.Errorf("revision %q: expected %q, got %q", tt.revision, tt.expected, got)
+		}
+	}
+}
+
+func TestParseTalosDisks(t *testing.T) {
+	getDisks := `NODE          NAMESPACE   TYPE   ID        VERSION   SIZE     READ ONLY   TRANSPORT   ROTATIONAL   WWID   MODEL          SERIAL
+10.10.88.73   runtime     Disk   mmcblk0   1         31 GB    false       mmc                               BJTD4R         0x1234
+10.10.88.73   runtime     Disk   nvme0n1   1         512 GB   false       nvme                       eui.1  Samsung 980    S1234
+`
+	if got := parseTalosDisks(getDisks); !reflect.DeepEqual(got, []string{"/dev/mmcblk0", "/dev/nvme0n1"}) {
+		t.Errorf("unexpected disks from get disks: %v", got)
+	}
+
+	legacy := `DEV            MODEL   SERIAL   TYPE   UUID   WWID   MODALIAS   NAME   SIZE    BUS_PATH
+/dev/mmcblk0   -       -        SD     -      -      -          BJTD4R 31 GB   /platform/fe2e0000.mmc
+`
+	if got := parseTalosDisks(legacy); !reflect.DeepEqual(got, []string{"/dev/mmcblk0"}) {
+		t.Errorf("unexpected disks from legacy disks: %v", got)
+	}
+}
+
+func TestSelectRK1InstallDisk(t *testing.T) {
+	if got := selectRK1InstallDisk([]string{"/dev/mmcblk0", "/dev/nvme0n1"}); got != "/dev/nvme0n1" {
+		t.Errorf("expected NVMe when present, got %s", got)
+	}
+	if got := selectRK1InstallDisk([]string{"/dev/mmcblk0"}); got != "/dev/mmcblk0" {
+		t.Errorf("expected eMMC without NVMe, got %s", got)
+	}
+}
+
+func TestGenerateNodePatchYAML_InstallDisk(t *testing.T) {
+	patch, err := generateNodePatchYAML("turing-w-1", false, false, "/dev/nvme0n1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(patch, "disk: /dev/nvme0n1") {
+		t.Errorf("expected install disk in patch, got:\n%s", patch)
+	}
+
+	patch, _ = generateNodePatchYAML("turing-w-1", false, false, "")
+	if strings.Contains(patch, "install") {
+		t.Errorf("expected no install section without a disk, got:\n%s", patch)
+	}
+}
+
+func TestResolveTalosInstallDisks(t *testing.T) {
+	server := newFakeInventoryBMC(t, true)
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		return exec.Command("echo", "10.10.88.73 runtime Disk nvme0n1 1 512GB")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	raw := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73", "slot": 1}},
+		"worker": []interface{}{
+			map[string]interface{}{"host": "10.10.88.74", "slot": 2, "install_disk": "/dev/sda"},
+			map[string]interface{}{"host": "10.10.88.75", "slot": 3},
+			map[string]interface{}{"host": "10.10.88.76"},
+		},
+	}
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, raw)
+	cfg := extractTalosClusterConfig(d)
+
+	resolveTalosInstallDisks(context.Background(), d, config, provisioner, &cfg)
+
+	if got := cfg.ControlPlanes[0].InstallDisk; got != "/dev/nvme0n1" {
+		t.Errorf("expected NVMe for RK1 in slot 1, got %q", got)
+	}
+	if got := cfg.Workers[0].InstallDisk; got != "/dev/sda" {
+		t.Errorf("expected explicit node install_disk to be kept, got %q", got)
+	}
+	if got := cfg.Workers[1].InstallDisk; got != "" {
+		t.Errorf("expected cluster default for CM4 in slot 3, got %q", got)
+	}
+	if got := cfg.Workers[2].InstallDisk; got != "" {
+		t.Errorf("expected cluster default for node without slot, got %q", got)
+	}
+}
+
+func TestResourceNodeStatus_DetectsModule(t *testing.T) {
+	server := newFakeInventoryBMC(t, true)
+
+	d := resourceNode().TestResourceData()
+	_ = d.Set("node", 3)
+	d.SetId("node-3")
+
+	if err := resourceNodeStatus(d, &ProviderConfig{Token: "test-token", Endpoint: server.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.Get("module").(string); got != "CM4" {
+		t.Errorf("expected module CM4, got %q", got)
+	}
+	if got := d.Get("board_model").(string); got != "Turing Pi 2.5" {
+		t.Errorf("expected board model Turing Pi 2.5, got %q", got)
+	}
+}
+
+func TestResolveTalosInstallDisks_ClusterInstallDiskSet(t *testing.T) {
+	server := newFakeInventoryBMC(t, true)
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		return exec.Command("echo", "10.10.88.73 runtime Disk nvme0n1 1 512GB")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"install_disk":     "/dev/sda",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73", "slot": 1}},
+	})
+	cfg := extractTalosClusterConfig(d)
+
+	resolveTalosInstallDisks(context.Background(), d, config, provisioner, &cfg)
+
+	if got := cfg.ControlPlanes[0].InstallDisk; got != "" {
+		t.Errorf("expected configured cluster install_disk to win, got %q", got)
+	}
+}
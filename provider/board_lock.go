@@ -0,0 +1,289 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	defaultBoardLockPath = "/tmp/terraform-provider-turingpi.lock"
+	defaultBoardLockTTL  = "2m"
+)
+
+// activeBoardLocks are the locks of this provider process, released by
+// Shutdown
+var (
+	activeBoardLocksMu sync.Mutex
+	activeBoardLocks   []*boardLock
+)
+
+// releaseBoardLocks releases the board locks of this provider process
+func releaseBoardLocks() {
+	activeBoardLocksMu.Lock()
+	defer activeBoardLocksMu.Unlock()
+	for _, l := range activeBoardLocks {
+		if err := l.release(); err != nil {
+			log.Printf("[WARN] Failed to release the board lock %s on %s: %v", l.path, l.target.Host, err)
+		}
+	}
+	activeBoardLocks = nil
+}
+
+// boardLockScript takes the lock file %[1]s for owner %[2]s unless another
+// owner renewed it less than %[3]d seconds ago. The file holds the owner and
+// the time of the last renewal. It is created with noclobber, so of two runs
+// creating it at once only one succeeds, and the holder renews it by
+// replacing it atomically with mv. An expired lock is taken over under the
+// directory $f.d, so that only one run at a time removes and recreates it;
+// a directory left by a run that died during the takeover is removed after a
+// minute.
+const boardLockScript = `f=%[1]s; me=%[2]s; now=$(date +%%s)
+create() { (set -C; echo "$me" "$now" > "$f") 2>/dev/null; }
+held() { echo "held $owner $((now - ${ts:-0}))"; exit 0; }
+if create; then echo acquired; exit 0; fi
+read owner ts < "$f"
+if [ "$owner" = "$me" ]; then
+  echo "$me" "$now" > "$f.$$" && mv "$f.$$" "$f" && echo acquired; exit 0
+fi
+[ $((now - ${ts:-0})) -lt %[3]d ] && held
+[ -n "$(find "$f.d" -maxdepth 0 -mmin +1 2>/dev/null)" ] && rmdir "$f.d"
+mkdir "$f.d" 2>/dev/null || held
+read owner ts < "$f"
+if [ -n "$owner" ] && [ $((now - ${ts:-0})) -lt %[3]d ]; then rmdir "$f.d"; held; fi
+rm -f "$f"
+if create; then rmdir "$f.d"; echo acquired; exit 0; fi
+rmdir "$f.d"; read owner ts < "$f"; held`
+
+// boardLockReleaseScript removes the lock file if owner still holds it
+const boardLockReleaseScript = `f=%[1]s; [ -f "$f" ] && read owner ts < "$f" && [ "$owner" = %[2]s ] && rm -f "$f"; true`
+
+// bmcSSHSchema returns the SSH arguments of blocks that run commands on the
+// BMC
+func bmcSSHSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"host": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "SSH host of the BMC (default: the host of the provider endpoint)",
+		},
+		"ssh_user": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "root",
+			Description: "SSH username on the BMC",
+		},
+		"ssh_key": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Description: "SSH private key content",
+		},
+		"ssh_password": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Description: "SSH password (ssh_key is preferred)",
+		},
+		"ssh_port": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     22,
+			Description: "SSH port number",
+		},
+	}
+}
+
+// bmcSSHTarget returns the BMC SSH target of a block with bmcSSHSchema. The
+// host defaults to the host of the provider endpoint.
+func bmcSSHTarget(data map[string]interface{}, endpoint string) (NodeConfig, error) {
+	target := extractNodeConfig(data)
+	if target.Host == "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Hostname() == "" {
+			return target, fmt.Errorf("cannot derive BMC host from endpoint %q, set host", endpoint)
+		}
+		target.Host = u.Hostname()
+	}
+	return target, validateNodeSSH(target)
+}
+
+func boardLockSchema() *schema.Resource {
+	s := bmcSSHSchema()
+	s["path"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     defaultBoardLockPath,
+		Description: "Lock file on the BMC (default: " + defaultBoardLockPath + ")",
+	}
+	s["ttl"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     defaultBoardLockTTL,
+		Description: "How long the lock of a run that stopped renewing it, e.g. because it crashed, blocks other runs (default: " + defaultBoardLockTTL + ")",
+	}
+	return &schema.Resource{Schema: s}
+}
+
+// boardLock is a lock file on the BMC, taken before the first change a run
+// makes to the board and renewed while the provider runs, so two
+// workstations applying to the same board at once notice each other
+type boardLock struct {
+	target NodeConfig
+	path   string
+	ttl    time.Duration
+	owner  string
+	// clientFactory creates the SSH clients that run the lock scripts
+	clientFactory func() SSHClient
+
+	mu         sync.Mutex
+	held       bool
+	verifiedAt time.Time
+	stop       chan struct{}
+}
+
+// extractBoardLock returns the board_lock block, or nil if not set. The host
+// defaults to the host of the provider endpoint.
+func extractBoardLock(d *schema.ResourceData, endpoint, requestID string) (*boardLock, error) {
+	list := d.Get("board_lock").([]interface{})
+	if len(list) == 0 || list[0] == nil {
+		return nil, nil
+	}
+	data := list[0].(map[string]interface{})
+
+	ttl, err := time.ParseDuration(data["ttl"].(string))
+	if err != nil || ttl < 10*time.Second {
+		return nil, fmt.Errorf("board_lock: invalid ttl %q: must be a duration of at least 10s", data["ttl"].(string))
+	}
+	target, err := bmcSSHTarget(data, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("board_lock: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), requestID)
+	lock := &boardLock{
+		target: target,
+		path:   data["path"].(string),
+		ttl:    ttl,
+		owner:  strings.Join(strings.Fields(owner), "_"),
+
+		clientFactory: NewSSHClient,
+	}
+	activeBoardLocksMu.Lock()
+	activeBoardLocks = append(activeBoardLocks, lock)
+	activeBoardLocksMu.Unlock()
+	return lock, nil
+}
+
+// ensure takes or verifies the lock before a change to the board. The lock
+// is verified over SSH at most once per renewal interval.
+func (l *boardLock) ensure(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held && time.Since(l.verifiedAt) < l.ttl/3 {
+		return nil
+	}
+	if err := l.take(); err != nil {
+		return err
+	}
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+		go l.renew(l.stop)
+	}
+	return nil
+}
+
+// take runs the lock script. Called with mu held.
+func (l *boardLock) take() error {
+	output, err := l.run(fmt.Sprintf(boardLockScript, shellQuote(l.path), shellQuote(l.owner), int(l.ttl.Seconds())))
+	if err != nil {
+		return fmt.Errorf("failed to take the board lock %s on %s: %w", l.path, l.target.Host, err)
+	}
+	fields := strings.Fields(output)
+	switch {
+	case len(fields) > 0 && fields[len(fields)-1] == "acquired":
+		if !l.held {
+			log.Printf("[INFO] Took the board lock %s on %s as %s", l.path, l.target.Host, l.owner)
+		}
+		l.held = true
+		l.verifiedAt = time.Now()
+		return nil
+	case len(fields) == 3 && fields[0] == "held":
+		l.held = false
+		age, _ := strconv.Atoi(fields[2])
+		return categorize(errorCategoryBoardLocked, fmt.Errorf(
+			"another apply appears to be in progress on this BMC: %s holds the lock %s on %s, renewed %ds ago. "+
+				"The lock expires %s after its last renewal", fields[1], l.path, l.target.Host, age, l.ttl))
+	}
+	return fmt.Errorf("unexpected output of the board lock script on %s: %q", l.target.Host, strings.TrimSpace(output))
+}
+
+// renew keeps the lock fresh until stop is closed
+func (l *boardLock) renew(stop chan struct{}) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			if l.held {
+				if err := l.take(); err != nil {
+					log.Printf("[WARN] Board lock renewal failed: %v", err)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// release stops renewal and removes the lock file if this run holds it
+func (l *boardLock) release() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	if !l.held {
+		return nil
+	}
+	l.held = false
+	_, err := l.run(fmt.Sprintf(boardLockReleaseScript, shellQuote(l.path), shellQuote(l.owner)))
+	return err
+}
+
+func (l *boardLock) run(script string) (string, error) {
+	client := l.clientFactory()
+	if err := client.Connect(l.target.Host, l.target.SSHPort, l.target.getSSHConfig()); err != nil {
+		return "", fmt.Errorf("SSH connection to %s failed: %w", l.target.Host, err)
+	}
+	defer func() { _ = client.Close() }()
+	return client.RunCommand(script)
+}
+
+// isMutatingBMCRequest reports whether req changes the board: set requests
+// and uploads. Logins and reads do not.
+func isMutatingBMCRequest(req *http.Request) bool {
+	if req.URL.Query().Get("opt") == "set" {
+		return true
+	}
+	return req.Method == http.MethodPost && !strings.HasSuffix(req.URL.Path, "/authenticate")
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// localShellClient runs board lock scripts with the local shell, as if it
+// were the BMC
+func localShellClient() SSHClient {
+	return &MockSSHClient{RunCommandFunc: func(cmd string) (string, error) {
+		out, err := exec.Command("sh", "-c", cmd).CombinedOutput()
+		return string(out), err
+	}}
+}
+
+func testBoardLock(t *testing.T, path, requestID string) *boardLock {
+	t.Helper()
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"board_lock": []interface{}{map[string]interface{}{
+			"ssh_password": "turing",
+			"path":         path,
+		}},
+	})
+	lock, err := extractBoardLock(d, "https://10.10.88.70", requestID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock.clientFactory = localShellClient
+	t.Cleanup(func() { _ = lock.release() })
+	return lock
+}
+
+func TestBoardLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "turingpi.lock")
+	ours := testBoardLock(t, path, "run-a")
+	theirs := testBoardLock(t, path, "run-b")
+
+	if ours.target.Host != "10.10.88.70" || ours.target.SSHUser != "root" {
+		t.Errorf("unexpected target: %+v", ours.target)
+	}
+	if err := ours.ensure(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Taking it again is a no-op for the holder
+	ours.verifiedAt = ours.verifiedAt.Add(-ours.ttl)
+	if err := ours.ensure(context.Background()); err != nil {
+		t.Fatalf("unexpected error renewing: %v", err)
+	}
+
+	err := theirs.ensure(context.Background())
+	var categorized *categorizedError
+	if err == nil || !strings.Contains(err.Error(), "another apply appears to be in progress on this BMC") ||
+		!errors.As(err, &categorized) || categorized.Category != errorCategoryBoardLocked {
+		t.Fatalf("expected a lock conflict, got %v", err)
+	}
+	if !strings.Contains(err.Error(), ours.owner) {
+		t.Errorf("expected the holder in the error, got %v", err)
+	}
+
+	// Another run may take over once the holder releases it
+	if err := ours.release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file to be removed, got %v", err)
+	}
+	if err := theirs.ensure(context.Background()); err != nil {
+		t.Errorf("unexpected error after release: %v", err)
+	}
+}
+
+func TestBoardLock_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "turingpi.lock")
+	// Renewed long ago by a run that crashed
+	if err := os.WriteFile(path, []byte("other:1:run-x 1000\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBoardLock(t, path, "run-a").ensure(context.Background()); err != nil {
+		t.Errorf("expected a stale lock to be taken over, got %v", err)
+	}
+
+	// A run that died during a takeover left its directory behind
+	if err := os.WriteFile(path, []byte("other:1:run-x 1000\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path+".d", 0700); err != nil {
+		t.Fatal(err)
+	}
+	longAgo := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".d", longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBoardLock(t, path, "run-b").ensure(context.Background()); err != nil {
+		t.Errorf("expected a left over takeover to be cleaned up, got %v", err)
+	}
+}
+
+func TestBoardLock_Race(t *testing.T) {
+	for _, stale := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "turingpi.lock")
+		if stale {
+			if err := os.WriteFile(path, []byte("other:1:run-x 1000\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var locks []*boardLock
+		for i := 0; i < 8; i++ {
+			locks = append(locks, testBoardLock(t, path, fmt.Sprintf("run-%d", i)))
+		}
+
+		var wg sync.WaitGroup
+		var acquired atomic.Int32
+		for _, lock := range locks {
+			wg.Add(1)
+			go func(lock *boardLock) {
+				defer wg.Done()
+				if lock.ensure(context.Background()) == nil {
+					acquired.Add(1)
+				}
+			}(lock)
+		}
+		wg.Wait()
+		if acquired.Load() != 1 {
+			t.Errorf("stale=%v: expected exactly one run to take the lock, %d did", stale, acquired.Load())
+		}
+	}
+}
+
+func TestBMCTransport_BoardLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "turingpi.lock")
+	if err := os.WriteFile(path, []byte("other:1:run-x 99999999999\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &bmcTransport{lock: testBoardLock(t, path, "run-a")}}
+
+	for _, tt := range []struct {
+		method, uri string
+		blocked     bool
+	}{
+		{http.MethodGet, "/api/bmc?opt=get&type=power", false},
+		{http.MethodPost, "/api/bmc/authenticate", false},
+		{http.MethodGet, "/api/bmc?opt=set&type=power&node1=1", true},
+		{http.MethodPost, "/api/bmc/upload/42", true},
+	} {
+		req, _ := http.NewRequest(tt.method, server.URL+tt.uri, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		if blocked := err != nil; blocked != tt.blocked {
+			t.Errorf("%s %s: expected blocked=%v, got %v", tt.method, tt.uri, tt.blocked, err)
+		}
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected only the reads and the login to reach the BMC, got %v", requests)
+	}
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// lookPath locates external binaries on the Terraform host
+// Replaced in tests to simulate missing binaries
+var lookPath = exec.LookPath
+
+// externalBinary describes a program that some features still run on the
+// machine executing Terraform. BMC, K3s (SSH), Helm and Kubernetes operations
+// are native Go and need no binaries.
+type externalBinary struct {
+	Name    string
+	Feature string
+	Install string
+}
+
+var (
+	talosctlBinary = externalBinary{
+		Name:    "talosctl",
+		Feature: "turingpi_talos_cluster (create, refresh, update and destroy), turingpi_talos_cert_rotation and turingpi_talos_cluster_health",
+		Install: "https://www.talos.dev/latest/talos-guides/install/talosctl/",
+	}
+	rpibootBinary = externalBinary{
+		Name:    "rpiboot",
+		Feature: "turingpi_eeprom",
+		Install: "https://github.com/raspberrypi/usbboot",
+	}
+)
+
+// checkBinary verifies an external binary is available and returns an error
+// diagnostic explaining what is missing and how to fix it if not.
+// path overrides the binary name when the user configured an explicit path.
+func checkBinary(bin externalBinary, path string) (string, diag.Diagnostics) {
+	if path == "" {
+		path = bin.Name
+	}
+	resolved, err := lookPath(path)
+	if err != nil {
+		return "", diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Required binary %q not found", path),
+			Detail: fmt.Sprintf("%s runs %s on the machine executing Terraform, but it was not found: %v\n\n"+
+				"Install it from %s and make sure it is in PATH. Remote runners such as Terraform Cloud "+
+				"do not provide it; use a self-hosted agent or local execution mode for these resources.",
+				bin.Feature, bin.Name, err, bin.Install),
+		}}
+	}
+	return resolved, nil
+}
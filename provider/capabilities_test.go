@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckBinary(t *testing.T) {
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+
+	lookPath = func(file string) (string, error) {
+		if file == "talosctl" {
+			return "/usr/local/bin/talosctl", nil
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+
+	path, diags := checkBinary(talosctlBinary, "")
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if path != "/usr/local/bin/talosctl" {
+		t.Errorf("expected resolved path, got %q", path)
+	}
+
+	_, diags = checkBinary(rpibootBinary, "/opt/usbboot/rpiboot")
+	if !diags.HasError() {
+		t.Fatal("expected error for missing binary")
+	}
+	if !strings.Contains(diags[0].Summary, "/opt/usbboot/rpiboot") {
+		t.Errorf("expected summary to name the configured path, got %q", diags[0].Summary)
+	}
+	if !strings.Contains(diags[0].Detail, "turingpi_eeprom") || !strings.Contains(diags[0].Detail, "Terraform Cloud") {
+		t.Errorf("expected detail to explain the affected feature, got %q", diags[0].Detail)
+	}
+}
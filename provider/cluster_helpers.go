@@ -1,48 +1,46 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 // WaitForSSH polls until SSH is available on a host
 // Returns nil when SSH connection succeeds, or error on timeout
 func WaitForSSH(host string, port int, config *SSHConfig, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	var lastErr error
-	for time.Now().Before(deadline) {
+	err := poll(context.Background(), pollInterval, timeout, "SSH", func(context.Context) (bool, error) {
 		client := NewSSHClient()
-		err := client.Connect(host, port, config)
-		if err == nil {
-			_ = client.Close()
-			return nil
+		if err := client.Connect(host, port, config); err != nil {
+			return false, err
 		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		_ = client.Close()
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for SSH on %s:%d: %w", host, port, err)
 	}
-
-	return fmt.Errorf("timeout waiting for SSH on %s:%d after %v: %w", host, port, timeout, lastErr)
+	return nil
 }
 
 // WaitForSSHWithClient polls until SSH is available using a custom client factory
 // Useful for testing with mock clients
 func WaitForSSHWithClient(host string, port int, config *SSHConfig, timeout time.Duration, clientFactory func() SSHClient) error {
-	deadline := time.Now().Add(timeout)
-
-	var lastErr error
-	for time.Now().Before(deadline) {
+	err := poll(context.Background(), pollInterval, timeout, "SSH", func(context.Context) (bool, error) {
 		client := clientFactory()
-		err := client.Connect(host, port, config)
-		if err == nil {
-			_ = client.Close()
-			return nil
+		if err := client.Connect(host, port, config); err != nil {
+			return false, err
 		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		_ = client.Close()
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for SSH on %s:%d: %w", host, port, err)
 	}
-
-	return fmt.Errorf("timeout waiting for SSH on %s:%d after %v: %w", host, port, timeout, lastErr)
+	return nil
 }
 
 // RunSSHCommand executes a command over SSH and returns output
@@ -87,3 +85,76 @@ func CheckSSHConnectivity(host string, port int, config *SSHConfig) bool {
 	_ = client.Close()
 	return true
 }
+
+// storeSensitiveOutputs reports whether sensitive cluster outputs (kubeconfig,
+// tokens, secrets) should be persisted in Terraform state. State written before
+// the store_sensitive_outputs attribute existed is treated as opted in.
+func storeSensitiveOutputs(d *schema.ResourceData) bool {
+	//nolint:staticcheck // SA1019: GetOkExists is needed to tell an explicit false from an unset value
+	v, ok := d.GetOkExists("store_sensitive_outputs")
+	if !ok {
+		return true
+	}
+	return v.(bool)
+}
+
+// suppressDefaultFill suppresses the diff of an attribute with a default on
+// resources whose state was written before the attribute existed, so upgrading
+// the provider does not plan a change, or a replacement when it is ForceNew.
+// The missing value must mean the same as def to the code reading it.
+func suppressDefaultFill(def string) schema.SchemaDiffSuppressFunc {
+	return func(_, old, new string, d *schema.ResourceData) bool {
+		return d.Id() != "" && old == "" && new == def
+	}
+}
+
+// setSensitiveOutput sets a sensitive computed attribute, storing an empty
+// string instead when store_sensitive_outputs is disabled
+func setSensitiveOutput(d *schema.ResourceData, key, value string) error {
+	if !storeSensitiveOutputs(d) {
+		value = ""
+	}
+	return d.Set(key, value)
+}
+
+// readSensitiveOutput returns a sensitive attribute from state, falling back to
+// the contents of the file referenced by pathKey when it was not persisted
+func readSensitiveOutput(d *schema.ResourceData, key, pathKey string) string {
+	if v := d.Get(key).(string); v != "" {
+		return v
+	}
+	if path := d.Get(pathKey).(string); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// setKubeconfigCredentials sets host, cluster_ca_certificate,
+// client_certificate and client_key from the kubeconfig. The certificates and
+// key honour store_sensitive_outputs.
+func setKubeconfigCredentials(d *schema.ResourceData, kubeconfig string) error {
+	creds := &kubeconfigCredentials{}
+	if kubeconfig != "" {
+		var err error
+		if creds, err = parseKubeconfigCredentials(kubeconfig); err != nil {
+			return err
+		}
+	}
+
+	if err := d.Set("host", creds.Host); err != nil {
+		return fmt.Errorf("failed to set host: %w", err)
+	}
+	values := map[string]string{
+		"cluster_ca_certificate": creds.ClusterCACertificate,
+		"client_certificate":     creds.ClientCertificate,
+		"client_key":             creds.ClientKey,
+	}
+	for key, value := range values {
+		if err := setSensitiveOutput(d, key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -1,11 +1,18 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
 // MockSSHClient implements SSHClient for testing
@@ -313,3 +320,197 @@ func TestRealSSHClient_ImplementsInterface(t *testing.T) {
 func TestMockSSHClient_ImplementsInterface(t *testing.T) {
 	var _ SSHClient = (*MockSSHClient)(nil)
 }
+
+func TestSetSensitiveOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		store    bool
+		expected string
+	}{
+		{"stored", true, "apiVersion: v1"},
+		{"not stored", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+				"name":                    "test",
+				"store_sensitive_outputs": tt.store,
+			})
+			if err := setSensitiveOutput(d, "kubeconfig", "apiVersion: v1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := d.Get("kubeconfig").(string); got != tt.expected {
+				t.Errorf("expected kubeconfig %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// certKubeconfig is a K3s-style kubeconfig with embedded client certificates.
+// The data fields are base64 of "ca-pem", "cert-pem" and "key-pem".
+const certKubeconfig = `apiVersion: v1
+kind: Config
+current-context: default
+clusters:
+- cluster:
+    server: https://10.10.88.73:6443
+    certificate-authority-data: Y2EtcGVt
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+users:
+- name: default
+  user:
+    client-certificate-data: Y2VydC1wZW0=
+    client-key-data: a2V5LXBlbQ==
+`
+
+func TestSetKubeconfigCredentials(t *testing.T) {
+	tests := []struct {
+		name  string
+		store bool
+		want  map[string]string
+	}{
+		{"stored", true, map[string]string{
+			"cluster_ca_certificate": "ca-pem",
+			"client_certificate":     "cert-pem",
+			"client_key":             "key-pem",
+		}},
+		{"not stored", false, map[string]string{
+			"cluster_ca_certificate": "",
+			"client_certificate":     "",
+			"client_key":             "",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+				"name":                    "test",
+				"store_sensitive_outputs": tt.store,
+			})
+			if err := setKubeconfigCredentials(d, certKubeconfig); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, want := range tt.want {
+				if got := d.Get(key).(string); got != want {
+					t.Errorf("expected %s %q, got %q", key, want, got)
+				}
+			}
+			// The server URL is not secret and is always set
+			if got := d.Get("host").(string); got != "https://10.10.88.73:6443" {
+				t.Errorf("expected host https://10.10.88.73:6443, got %q", got)
+			}
+		})
+	}
+}
+
+func TestSetKubeconfigCredentials_Invalid(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{"name": "test"})
+	if err := setKubeconfigCredentials(d, "current-context: missing\n"); err == nil {
+		t.Error("expected error for a kubeconfig without the current context")
+	}
+	if err := setKubeconfigCredentials(d, ""); err != nil {
+		t.Errorf("expected an empty kubeconfig to clear the credentials, got %v", err)
+	}
+}
+
+func TestStoreSensitiveOutputs_UnsetDefaultsToTrue(t *testing.T) {
+	d := resourceK3sCluster().Data(nil)
+	if !storeSensitiveOutputs(d) {
+		t.Error("expected sensitive outputs to be stored when attribute is unset")
+	}
+}
+
+func TestReadSensitiveOutput_FallsBackToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte("from-file"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":                    "test",
+		"kubeconfig_path":         path,
+		"store_sensitive_outputs": false,
+	})
+	if got := readSensitiveOutput(d, "kubeconfig", "kubeconfig_path"); got != "from-file" {
+		t.Errorf("expected file contents, got %q", got)
+	}
+
+	if err := d.Set("kubeconfig", "from-state"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readSensitiveOutput(d, "kubeconfig", "kubeconfig_path"); got != "from-state" {
+		t.Errorf("expected state value, got %q", got)
+	}
+}
+
+func TestSSHErrors(t *testing.T) {
+	exitErr := &ExitError{Command: "false", Code: 1, Stderr: "boom"}
+	if exitErr.Error() != "command exited with status 1: boom" {
+		t.Errorf("unexpected ExitError message: %s", exitErr.Error())
+	}
+	wrapped := fmt.Errorf("install failed: %w", exitErr)
+	var gotExit *ExitError
+	if !errors.As(wrapped, &gotExit) || gotExit.Code != 1 || isSSHConnectionError(wrapped) {
+		t.Error("wrapped ExitError should report exit code 1 and not be a connection error")
+	}
+
+	dialErr := fmt.Errorf("SSH connection failed: %w", &DialError{Addr: "10.10.88.73:22", Err: fmt.Errorf("connection refused")})
+	if !isSSHDialError(dialErr) || errors.As(dialErr, &gotExit) {
+		t.Error("wrapped DialError should be a dial error without exit code")
+	}
+
+	lostErr := fmt.Errorf("install failed: %w", &SessionLostError{Addr: "10.10.88.73:22", Err: io.EOF})
+	if isSSHDialError(lostErr) || !isSSHConnectionError(lostErr) || !errors.Is(lostErr, io.EOF) {
+		t.Error("wrapped SessionLostError should be a connection error, but not a dial error")
+	}
+	if !strings.Contains(dialErr.Error(), "failed to connect to 10.10.88.73:22: connection refused") {
+		t.Errorf("unexpected DialError message: %s", dialErr.Error())
+	}
+}
+
+// legacyStateDiff plans config against the state of a created resource with
+// the given attributes removed, as in state written before they existed
+func legacyStateDiff(t *testing.T, r *schema.Resource, config map[string]interface{}, removed ...string) *terraform.InstanceDiff {
+	t.Helper()
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.SetId("legacy")
+	state := data.State()
+	for _, key := range removed {
+		delete(state.Attributes, key)
+	}
+
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diff
+}
+
+func TestSuppressDefaultFill(t *testing.T) {
+	suppress := suppressDefaultFill("full")
+	existing := schema.TestResourceDataRaw(t, map[string]*schema.Schema{}, map[string]interface{}{})
+	existing.SetId("existing")
+
+	if !suppress("mode", "", "full", existing) {
+		t.Error("expected the default filled into legacy state to be suppressed")
+	}
+	if suppress("mode", "", "agents_only", existing) || suppress("mode", "agents_only", "full", existing) {
+		t.Error("expected real changes to be kept")
+	}
+	if suppress("mode", "", "full", schema.TestResourceDataRaw(t, map[string]*schema.Schema{}, map[string]interface{}{})) {
+		t.Error("expected the default to be planned on create")
+	}
+}
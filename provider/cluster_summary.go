@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// clusterSummarySchema is the summary attribute shared by the cluster resources
+func clusterSummarySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+		Description: "JSON-encoded cluster descriptor for tools reading `terraform show -json`: " +
+			"distribution, name, mode, status, API endpoint, node counts, versions and add-ons",
+	}
+}
+
+// clusterSummary is the JSON document of the summary attribute. Fields are
+// only ever added, so consumers can rely on the existing ones.
+type clusterSummary struct {
+	Distribution      string                         `json:"distribution"`
+	Name              string                         `json:"name"`
+	Mode              string                         `json:"mode"`
+	Status            string                         `json:"status"`
+	APIEndpoint       string                         `json:"api_endpoint"`
+	NodeCount         int                            `json:"node_count"`
+	ControlPlaneCount int                            `json:"control_plane_count"`
+	WorkerCount       int                            `json:"worker_count"`
+	Versions          map[string]string              `json:"versions"`
+	Addons            map[string]clusterAddonSummary `json:"addons"`
+}
+
+// clusterAddonSummary describes an add-on; an empty version is the latest chart
+type clusterAddonSummary struct {
+	Enabled bool   `json:"enabled"`
+	Version string `json:"version"`
+}
+
+// withClusterSummary wraps a create, read or update function so the summary
+// is refreshed from state after it ran, whatever path it returned through
+func withClusterSummary(fn func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics, summarize func(*schema.ResourceData) clusterSummary) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		diags := fn(ctx, d, meta)
+		if d.Id() == "" {
+			return diags
+		}
+		if err := setClusterSummary(d, summarize(d)); err != nil {
+			return append(diags, diagFromErr(err)...)
+		}
+		return diags
+	}
+}
+
+func setClusterSummary(d *schema.ResourceData, summary clusterSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	return d.Set("summary", string(data))
+}
+
+// clusterAddons summarizes the metallb and ingress blocks
+func clusterAddons(d *schema.ResourceData) map[string]clusterAddonSummary {
+	addons := map[string]clusterAddonSummary{}
+	for _, name := range []string{"metallb", "ingress"} {
+		addon := clusterAddonSummary{}
+		if v, ok := d.GetOk(name); ok {
+			if list := v.([]interface{}); len(list) > 0 && list[0] != nil {
+				block := list[0].(map[string]interface{})
+				addon.Enabled = block["enabled"].(bool)
+				addon.Version = block["version"].(string)
+			}
+		}
+		addons[name] = addon
+	}
+	return addons
+}
+
+// k3sClusterSummary describes a turingpi_k3s_cluster from its state
+func k3sClusterSummary(d *schema.ResourceData) clusterSummary {
+	mode := d.Get("mode").(string)
+	controlPlanes := 1
+	if mode == k3sModeAgentsOnly {
+		controlPlanes = 0
+	}
+	workers := len(d.Get("worker").([]interface{}))
+
+	// Prefer the configured version, then the one detected during install
+	k3sVersion := d.Get("k3s_version").(string)
+	if k3sVersion == "" {
+		for _, e := range d.Get("provision_report").([]interface{}) {
+			if m, ok := e.(map[string]interface{}); ok && m["k3s_version"].(string) != "" {
+				k3sVersion = m["k3s_version"].(string)
+				break
+			}
+		}
+	}
+	kubernetesVersion, _, _ := strings.Cut(k3sVersion, "+")
+
+	return clusterSummary{
+		Distribution:      "k3s",
+		Name:              d.Get("name").(string),
+		Mode:              mode,
+		Status:            d.Get("cluster_status").(string),
+		APIEndpoint:       d.Get("api_endpoint").(string),
+		NodeCount:         controlPlanes + workers,
+		ControlPlaneCount: controlPlanes,
+		WorkerCount:       workers,
+		Versions: map[string]string{
+			"k3s":        k3sVersion,
+			"kubernetes": kubernetesVersion,
+		},
+		Addons: clusterAddons(d),
+	}
+}
+
+// talosClusterSummary describes a turingpi_talos_cluster from its state
+func talosClusterSummary(d *schema.ResourceData) clusterSummary {
+	controlPlanes := len(d.Get("control_plane").([]interface{}))
+	workers := len(d.Get("worker").([]interface{}))
+
+	return clusterSummary{
+		Distribution:      "talos",
+		Name:              d.Get("name").(string),
+		Mode:              d.Get("mode").(string),
+		Status:            d.Get("cluster_status").(string),
+		APIEndpoint:       d.Get("api_endpoint").(string),
+		NodeCount:         controlPlanes + workers,
+		ControlPlaneCount: controlPlanes,
+		WorkerCount:       workers,
+		Versions: map[string]string{
+			"talos":      d.Get("talos_version").(string),
+			"kubernetes": d.Get("kubernetes_version").(string),
+		},
+		Addons: clusterAddons(d),
+	}
+}
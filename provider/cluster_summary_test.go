@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestK3sClusterSummary(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name": "homelab",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+		}},
+		"worker": []interface{}{
+			map[string]interface{}{"host": "10.10.88.74", "ssh_user": "root", "ssh_password": "secret"},
+			map[string]interface{}{"host": "10.10.88.75", "ssh_user": "root", "ssh_password": "secret"},
+		},
+		"metallb": []interface{}{map[string]interface{}{"enabled": true, "ip_range": "10.10.88.80-10.10.88.89", "version": "0.14.9"}},
+	})
+	_ = d.Set("cluster_status", "ready")
+	_ = d.Set("api_endpoint", "https://10.10.88.73:6443")
+	_ = d.Set("provision_report", []interface{}{map[string]interface{}{"host": "10.10.88.73", "k3s_version": "v1.31.4+k3s1"}})
+
+	summary := k3sClusterSummary(d)
+	expected := clusterSummary{
+		Distribution:      "k3s",
+		Name:              "homelab",
+		Mode:              k3sModeFull,
+		Status:            "ready",
+		APIEndpoint:       "https://10.10.88.73:6443",
+		NodeCount:         3,
+		ControlPlaneCount: 1,
+		WorkerCount:       2,
+		Versions:          map[string]string{"k3s": "v1.31.4+k3s1", "kubernetes": "v1.31.4"},
+		Addons: map[string]clusterAddonSummary{
+			"metallb": {Enabled: true, Version: "0.14.9"},
+			"ingress": {},
+		},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected %+v, got %+v", expected, summary)
+	}
+}
+
+func TestTalosClusterSummary(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":               "talos",
+		"cluster_endpoint":   "https://10.10.88.73:6443",
+		"talos_version":      "v1.9.1",
+		"kubernetes_version": "v1.32.0",
+		"control_plane":      []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"worker":             []interface{}{map[string]interface{}{"host": "10.10.88.74"}},
+	})
+
+	summary := talosClusterSummary(d)
+	if summary.Distribution != "talos" || summary.NodeCount != 2 || summary.ControlPlaneCount != 1 || summary.WorkerCount != 1 {
+		t.Errorf("unexpected node counts: %+v", summary)
+	}
+	if summary.Versions["talos"] != "v1.9.1" || summary.Versions["kubernetes"] != "v1.32.0" {
+		t.Errorf("unexpected versions: %v", summary.Versions)
+	}
+}
+
+func TestWithClusterSummary(t *testing.T) {
+	read := func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		_ = d.Set("cluster_status", "degraded")
+		return nil
+	}
+	fn := withClusterSummary(read, k3sClusterSummary)
+
+	d := resourceK3sCluster().TestResourceData()
+	_ = d.Set("name", "homelab")
+	if diags := fn(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Get("summary").(string) != "" {
+		t.Error("expected no summary for a resource without ID")
+	}
+
+	d.SetId("homelab")
+	if diags := fn(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(d.Get("summary").(string)), &decoded); err != nil {
+		t.Fatalf("summary is not JSON: %v", err)
+	}
+	if decoded["status"] != "degraded" || decoded["name"] != "homelab" || decoded["distribution"] != "k3s" {
+		t.Errorf("expected the summary to reflect state after the wrapped function, got %v", decoded)
+	}
+	for _, key := range []string{"node_count", "control_plane_count", "worker_count", "versions", "addons", "api_endpoint", "mode"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("expected %s in summary", key)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// consoleCapturePollInterval is how often the BMC UART buffer is drained
+// while capturing. Replaced in tests to keep them fast.
+var consoleCapturePollInterval = 2 * time.Second
+
+// consoleCaptureMaxSeen caps the output kept in memory for boot pattern
+// matching; the log file itself is not capped
+const consoleCaptureMaxSeen = 1 << 20
+
+// consoleCapture appends the UART output of a node to a local file while it
+// is being flashed and booted. Reading UART clears the BMC buffer, so while a
+// capture runs, boot checks must match against it instead of reading UART.
+type consoleCapture struct {
+	endpoint string
+	token    string
+	node     int
+	file     *os.File
+
+	mu   sync.Mutex
+	seen string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startConsoleCapture opens path for appending and starts draining the UART
+// of node into it
+func startConsoleCapture(endpoint, token string, node int, path string) (*consoleCapture, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open console log %s: %w", path, err)
+	}
+	if _, err := fmt.Fprintf(file, "=== node %d console capture started %s ===\n", node, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write console log %s: %w", path, err)
+	}
+
+	c := &consoleCapture{
+		endpoint: endpoint,
+		token:    token,
+		node:     node,
+		file:     file,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+func (c *consoleCapture) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(consoleCapturePollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.poll()
+		select {
+		case <-c.stop:
+			// Drain whatever arrived since the last poll
+			c.poll()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the UART buffer once. Read errors are expected while the BMC is
+// busy flashing, so they are logged and the capture continues.
+func (c *consoleCapture) poll() {
+	output, err := readUART(c.endpoint, c.token, c.node, "utf8")
+	if err != nil {
+		log.Printf("[DEBUG] Console capture of node %d: UART read failed: %v", c.node, err)
+		return
+	}
+	if output == "" {
+		return
+	}
+	if _, err := c.file.WriteString(output); err != nil {
+		log.Printf("[WARN] Console capture of node %d: failed to write log: %v", c.node, err)
+	}
+
+	c.mu.Lock()
+	c.seen += output
+	if len(c.seen) > consoleCaptureMaxSeen {
+		c.seen = c.seen[len(c.seen)-consoleCaptureMaxSeen:]
+	}
+	c.mu.Unlock()
+}
+
+// WaitFor reports whether pattern appears in the captured output before the
+// timeout expires
+func (c *consoleCapture) WaitFor(pattern string, timeout time.Duration) bool {
+	err := poll(context.Background(), consoleCapturePollInterval, timeout, "console output "+strconv.Quote(pattern), func(context.Context) (bool, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return strings.Contains(c.seen, pattern), nil
+	})
+	return err == nil
+}
+
+// Stop drains the UART one last time and closes the log file
+func (c *consoleCapture) Stop() error {
+	close(c.stop)
+	<-c.done
+	if _, err := fmt.Fprintf(c.file, "\n=== node %d console capture stopped %s ===\n", c.node, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		_ = c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeUARTServer returns each chunk once per UART read, then empty output
+func newFakeUARTServer(t *testing.T, chunks ...string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		output := ""
+		if len(chunks) > 0 {
+			output, chunks = chunks[0], chunks[1:]
+		}
+		_, _ = fmt.Fprintf(w, `{"response":[["uart",%q]]}`, output)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConsoleCapture(t *testing.T) {
+	oldInterval := consoleCapturePollInterval
+	consoleCapturePollInterval = 5 * time.Millisecond
+	defer func() { consoleCapturePollInterval = oldInterval }()
+
+	server := newFakeUARTServer(t, "U-Boot 2024.01\n", "Starting kernel ...\n", "node1 login: ")
+	path := filepath.Join(t.TempDir(), "node1.log")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	capture, err := startConsoleCapture(server.URL, "test-token", 1, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !capture.WaitFor("login:", time.Second) {
+		t.Error("expected boot pattern to be found in captured output")
+	}
+	if err := capture.Stop(); err != nil {
+		t.Fatalf("unexpected error stopping capture: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !strings.HasPrefix(content, "previous run\n") {
+		t.Error("expected console log to be appended to")
+	}
+	if !strings.Contains(content, "U-Boot 2024.01\nStarting kernel ...\nnode1 login: ") {
+		t.Errorf("expected UART output in console log, got %q", content)
+	}
+	if !strings.Contains(content, "console capture started") || !strings.Contains(content, "console capture stopped") {
+		t.Errorf("expected capture markers in console log, got %q", content)
+	}
+}
+
+func TestConsoleCapture_WaitForTimeout(t *testing.T) {
+	oldInterval := consoleCapturePollInterval
+	consoleCapturePollInterval = 5 * time.Millisecond
+	defer func() { consoleCapturePollInterval = oldInterval }()
+
+	server := newFakeUARTServer(t, "Kernel panic - not syncing\n")
+	capture, err := startConsoleCapture(server.URL, "test-token", 2, filepath.Join(t.TempDir(), "node2.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = capture.Stop() }()
+
+	if capture.WaitFor("login:", 50*time.Millisecond) {
+		t.Error("expected boot pattern not to be found")
+	}
+}
+
+func TestStartConsoleCapture_InvalidPath(t *testing.T) {
+	if _, err := startConsoleCapture("http://127.0.0.1:0", "test-token", 1, filepath.Join(t.TempDir(), "missing", "node1.log")); err == nil {
+		t.Error("expected error for a console log in a missing directory")
+	}
+}
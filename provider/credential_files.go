@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// defaultFilePermission is the mode of kubeconfig, talosconfig and secrets files
+const defaultFilePermission = "0600"
+
+// filePermissionSchema returns the file_permission argument of resources that
+// write credential files
+func filePermissionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Default:  defaultFilePermission,
+		Description: "Octal mode of the credential files written to the *_path arguments (e.g., 0600 or 0640). " +
+			"Files are never readable by others, and only the owner can write them.",
+		ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+			if _, err := parseFilePermission(v.(string)); err != nil {
+				return nil, []error{fmt.Errorf("%s: %w", k, err)}
+			}
+			return nil, nil
+		},
+	}
+}
+
+// parseFilePermission parses an octal file mode, rejecting modes that let
+// anyone but the owner write the file, or anyone outside the group read it
+func parseFilePermission(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not an octal file mode such as 0600", s)
+	}
+	if mode&0400 == 0 {
+		return 0, fmt.Errorf("mode %s must let the owner read the file", s)
+	}
+	if mode&0137 != 0 {
+		return 0, fmt.Errorf("mode %s is too permissive: credential files may only be group-readable, never executable, writable by the group or accessible to others", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// filePermission returns the parsed file_permission of a resource
+func filePermission(d *schema.ResourceData) os.FileMode {
+	if mode, err := parseFilePermission(d.Get("file_permission").(string)); err == nil {
+		return mode
+	}
+	return 0600
+}
+
+// writeCredentialFile atomically writes a kubeconfig, talosconfig or secrets
+// file: content goes to a temp file in the same directory, which is renamed
+// over path once complete, so an interrupted apply never leaves a truncated
+// file. Paths rejected by checkCredentialPath are not written.
+func writeCredentialFile(path string, content []byte, perm os.FileMode) error {
+	if err := checkCredentialPath(path); err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file in %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set mode of %s: %w", tmpPath, err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", tmpPath, path, err)
+	}
+	committed = true
+	return nil
+}
+
+// checkCredentialPath refuses credential file paths that are symbolic links
+// or other non-regular files, and directories that other users could use to
+// swap the file
+func checkCredentialPath(path string) error {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write %s: it is a symbolic link", path)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("refusing to write %s: it is not a regular file", path)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check directory %s: %w", dir, err)
+	}
+	if !dirInfo.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return checkDirOwnership(dir, dirInfo)
+}
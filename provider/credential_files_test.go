@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseFilePermission(t *testing.T) {
+	for _, valid := range []string{"0600", "0400", "0640", "600"} {
+		if _, err := parseFilePermission(valid); err != nil {
+			t.Errorf("expected %s to be valid: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"0644", "0660", "0700", "0200", "0606", "rw-------", "01600", ""} {
+		if _, err := parseFilePermission(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestWriteCredentialFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kubeconfig")
+
+	if err := writeCredentialFile(path, []byte("first"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeCredentialFile(path, []byte("second"), 0640); err != nil {
+		t.Fatalf("unexpected error on overwrite: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Errorf("expected file to be replaced, got %q, %v", data, err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+			t.Errorf("expected mode 0640, got %o", info.Mode().Perm())
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected no temp files to be left behind, got %v", entries)
+	}
+}
+
+func TestWriteCredentialFile_RefusesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "kubeconfig")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	err := writeCredentialFile(link, []byte("secret"), 0600)
+	if err == nil || !strings.Contains(err.Error(), "symbolic link") {
+		t.Errorf("expected symlink to be refused, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "keep" {
+		t.Errorf("expected symlink target to be untouched, got %q", data)
+	}
+}
+
+func TestWriteCredentialFile_RefusesSharedDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory modes are not checked on Windows")
+	}
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeCredentialFile(filepath.Join(dir, "kubeconfig"), []byte("secret"), 0600)
+	if err == nil || !strings.Contains(err.Error(), "writable by all users") {
+		t.Errorf("expected world-writable directory to be refused, got %v", err)
+	}
+
+	// The sticky bit (as on /tmp) keeps others from replacing the file
+	if err := os.Chmod(dir, 0777|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCredentialFile(filepath.Join(dir, "kubeconfig"), []byte("secret"), 0600); err != nil {
+		t.Errorf("expected sticky directory to be accepted: %v", err)
+	}
+}
+
+func TestWriteCredentialFile_MissingDirectory(t *testing.T) {
+	err := writeCredentialFile(filepath.Join(t.TempDir(), "missing", "kubeconfig"), []byte("secret"), 0600)
+	if err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkDirOwnership refuses directories owned by another user (other than
+// root), and world-writable directories without the sticky bit, where the
+// credential file could be swapped before it is read
+func checkDirOwnership(dir string, info os.FileInfo) error {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if uid := uint32(os.Getuid()); stat.Uid != uid && stat.Uid != 0 {
+			return fmt.Errorf("refusing to write to %s: the directory is owned by uid %d, not the current user (uid %d) or root", dir, stat.Uid, uid)
+		}
+	}
+	if info.Mode().Perm()&0002 != 0 && info.Mode()&os.ModeSticky == 0 {
+		return fmt.Errorf("refusing to write to %s: the directory is writable by all users", dir)
+	}
+	return nil
+}
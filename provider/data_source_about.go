@@ -38,6 +38,27 @@ func dataSourceAbout() *schema.Resource {
 				Computed:    true,
 				Description: "Timestamp when the BMC firmware was built.",
 			},
+			"board_revision": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Turing Pi board revision (e.g., '2.4', '2.5.2'). Empty if not reported by the BMC firmware.",
+			},
+			"bmc_soc": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "BMC hardware model / SoC. Empty if not reported by the BMC firmware.",
+			},
+			"board_model": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Board model derived from board_revision (Turing Pi 2 or Turing Pi 2.5). Empty if not reported by the BMC firmware.",
+			},
+			"raw": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Every key of the about response, including keys this provider does not model yet (e.g., 'hostname', 'mac'). Numbers and booleans are converted to strings.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
 		},
 	}
 }
@@ -49,7 +70,7 @@ func dataSourceAboutRead(ctx context.Context, d *schema.ResourceData, meta inter
 	// Reuse the existing fetchBMCAbout function from data_source_info.go
 	aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to fetch BMC about info: %w", err))
+		return diagFromErr(fmt.Errorf("failed to fetch BMC about info: %w", err))
 	}
 
 	// Parse the response using the shared function that handles both formats
@@ -57,35 +78,42 @@ func dataSourceAboutRead(ctx context.Context, d *schema.ResourceData, meta inter
 
 	if v, ok := aboutMap["api"]; ok {
 		if err := d.Set("api_version", v); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set api_version: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set api_version: %w", err))
 		}
 	}
 	if v, ok := aboutMap["version"]; ok {
 		if err := d.Set("daemon_version", v); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set daemon_version: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set daemon_version: %w", err))
 		}
 	}
 	if v, ok := aboutMap["buildroot"]; ok {
 		if err := d.Set("buildroot_version", v); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set buildroot_version: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set buildroot_version: %w", err))
 		}
 	}
 	if v, ok := aboutMap["firmware"]; ok {
 		if err := d.Set("firmware_version", v); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set firmware_version: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set firmware_version: %w", err))
 		}
 	}
 	// Handle both "buildtime" (legacy) and "build_version" (new) field names
 	if v, ok := aboutMap["buildtime"]; ok {
 		if err := d.Set("build_time", v); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set build_time: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set build_time: %w", err))
 		}
 	} else if v, ok := aboutMap["build_version"]; ok {
 		if err := d.Set("build_time", v); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set build_time: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set build_time: %w", err))
 		}
 	}
 
+	if err := setBoardData(d, aboutMap); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("raw", aboutMap); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set raw: %w", err))
+	}
+
 	d.SetId("turingpi-about")
 
 	return diags
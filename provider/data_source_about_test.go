@@ -26,6 +26,8 @@ func TestDataSourceAbout_Schema(t *testing.T) {
 		"buildroot_version",
 		"firmware_version",
 		"build_time",
+		"board_revision",
+		"bmc_soc",
 	}
 
 	for _, field := range expectedFields {
@@ -47,6 +49,8 @@ func TestDataSourceAbout_SchemaTypes(t *testing.T) {
 		{"buildroot_version", schema.TypeString},
 		{"firmware_version", schema.TypeString},
 		{"build_time", schema.TypeString},
+		{"board_revision", schema.TypeString},
+		{"bmc_soc", schema.TypeString},
 	}
 
 	for _, tt := range tests {
@@ -250,3 +254,128 @@ func TestDataSourceAboutRead_InvalidResponseFormat(t *testing.T) {
 		t.Errorf("expected api_version '', got '%s'", v)
 	}
 }
+
+func TestDataSourceAboutRead_BoardInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"response": []map[string]interface{}{
+				{"result": map[string]interface{}{
+					"api":            "1.1",
+					"version":        "2.3.4",
+					"board_revision": "2.5.2",
+					"soc":            "Allwinner T113-S3",
+				}},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	rd := dataSourceAbout().TestResourceData()
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := dataSourceAboutRead(context.Background(), rd, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if v := rd.Get("board_revision").(string); v != "2.5.2" {
+		t.Errorf("expected board_revision '2.5.2', got '%s'", v)
+	}
+	if v := rd.Get("bmc_soc").(string); v != "Allwinner T113-S3" {
+		t.Errorf("expected bmc_soc 'Allwinner T113-S3', got '%s'", v)
+	}
+}
+
+func TestDataSourceAboutRead_Raw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"response": []map[string]interface{}{
+				{"result": map[string]interface{}{
+					"api":      "1.1",
+					"version":  "2.3.4",
+					"hostname": "turingpi",
+					"mac":      "02:00:00:12:34:56",
+					"uptime":   3600,
+					"ssh":      true,
+					"unset":    nil,
+				}},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	rd := dataSourceAbout().TestResourceData()
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := dataSourceAboutRead(context.Background(), rd, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	raw := rd.Get("raw").(map[string]interface{})
+	expected := map[string]string{
+		"api":      "1.1",
+		"version":  "2.3.4",
+		"hostname": "turingpi",
+		"mac":      "02:00:00:12:34:56",
+		"uptime":   "3600",
+		"ssh":      "true",
+	}
+	for key, want := range expected {
+		if raw[key] != want {
+			t.Errorf("expected raw[%q] = %q, got %v", key, want, raw[key])
+		}
+	}
+	if _, ok := raw["unset"]; ok {
+		t.Error("expected null values to be skipped")
+	}
+}
+
+func TestAboutValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+		ok   bool
+	}{
+		{"2.3.4", "2.3.4", true},
+		{float64(42), "42", true},
+		{1.5, "1.5", true},
+		{false, "false", true},
+		{map[string]interface{}{"a": "b"}, `{"a":"b"}`, true},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := aboutValue(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("aboutValue(%v) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseBoardInfo(t *testing.T) {
+	tests := []struct {
+		name             string
+		about            map[string]string
+		expectedRevision string
+		expectedSoC      string
+	}{
+		{"empty", map[string]string{}, "", ""},
+		{"primary keys", map[string]string{"board_revision": "2.4", "bmc_soc": "T113"}, "2.4", "T113"},
+		{"alternate keys", map[string]string{"hw_version": " 2.5.1 ", "hardware": "T113-S3"}, "2.5.1", "T113-S3"},
+		{"primary key wins", map[string]string{"board_revision": "2.5", "hw_version": "2.4"}, "2.5", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, soc := parseBoardInfo(tt.about)
+			if revision != tt.expectedRevision {
+				t.Errorf("expected revision %q, got %q", tt.expectedRevision, revision)
+			}
+			if soc != tt.expectedSoC {
+				t.Errorf("expected soc %q, got %q", tt.expectedSoC, soc)
+			}
+		})
+	}
+}
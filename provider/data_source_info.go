@@ -6,11 +6,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
+// firmwareUpdateCheckTimeout bounds the release lookup of turingpi_info, so
+// boards without internet access are not held up for long
+const firmwareUpdateCheckTimeout = 10 * time.Second
+
 // BMC API response structures
 // Use json.RawMessage to support both legacy and new BMC firmware formats
 type bmcAboutResponse struct {
@@ -71,6 +78,37 @@ func dataSourceInfo() *schema.Resource {
 				Computed:    true,
 				Description: "BMC build timestamp",
 			},
+			"board_revision": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Turing Pi board revision (e.g., 2.4, 2.5.2), if reported by the BMC",
+			},
+			"bmc_soc": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "BMC hardware model / SoC, if reported by the BMC",
+			},
+			"board_model": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Board model derived from board_revision (Turing Pi 2 or Turing Pi 2.5), if reported by the BMC",
+			},
+			"check_for_updates": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Look up the newest BMC firmware release on GitHub to set latest_version and update_available. Off by default, so reads make no requests beyond the BMC.",
+			},
+			"latest_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Newest BMC firmware release on GitHub (e.g., v2.1.0). Empty when check_for_updates is false or the release lookup fails.",
+			},
+			"update_available": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether latest_version is newer than the firmware running on the BMC. False when check_for_updates is false.",
+			},
 
 			// Network information from /api/bmc?opt=get&type=info
 			"network_interfaces": {
@@ -149,31 +187,34 @@ func dataSourceInfoRead(ctx context.Context, d *schema.ResourceData, meta interf
 	// Fetch version/about information
 	aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to fetch BMC about info: %w", err))
+		return diagFromErr(fmt.Errorf("failed to fetch BMC about info: %w", err))
 	}
 
 	if err := setAboutData(d, aboutData); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
+	}
+	if d.Get("check_for_updates").(bool) {
+		diags = append(diags, setFirmwareUpdateData(ctx, d, parseAboutResponse(aboutData))...)
 	}
 
 	// Fetch network and storage information
 	infoData, err := fetchBMCInfo(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to fetch BMC info: %w", err))
+		return diagFromErr(fmt.Errorf("failed to fetch BMC info: %w", err))
 	}
 
 	if err := setInfoData(d, infoData); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
 	}
 
 	// Fetch power status
 	powerData, err := fetchBMCPower(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to fetch BMC power status: %w", err))
+		return diagFromErr(fmt.Errorf("failed to fetch BMC power status: %w", err))
 	}
 
 	if err := setPowerData(d, powerData); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
 	}
 
 	// Set a stable ID for the data source
@@ -231,8 +272,8 @@ func fetchBMCInfo(endpoint, token string) (*bmcInfoResponse, error) {
 	}
 
 	var result bmcInfoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -259,8 +300,8 @@ func fetchBMCPower(endpoint, token string) (*bmcPowerResponse, error) {
 	}
 
 	var result bmcPowerResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -300,6 +341,78 @@ func setAboutData(d *schema.ResourceData, data *bmcAboutResponse) error {
 		}
 	}
 
+	return setBoardData(d, aboutMap)
+}
+
+// setFirmwareUpdateData sets latest_version and update_available from the
+// BMC firmware releases. A failed lookup is a warning, so the data source
+// still works without internet access.
+func setFirmwareUpdateData(ctx context.Context, d *schema.ResourceData, aboutMap map[string]string) diag.Diagnostics {
+	current := aboutMap["version"]
+	if current == "" {
+		current = aboutMap["firmware"]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, firmwareUpdateCheckTimeout)
+	defer cancel()
+	latest, err := resolveLatestBMCFirmware(ctx)
+	if err != nil {
+		latest = ""
+	}
+	if setErr := d.Set("latest_version", latest); setErr != nil {
+		return diagFromErr(fmt.Errorf("failed to set latest_version: %w", setErr))
+	}
+	if setErr := d.Set("update_available", firmwareUpdateAvailable(current, latest)); setErr != nil {
+		return diagFromErr(fmt.Errorf("failed to set update_available: %w", setErr))
+	}
+
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Could not check for BMC firmware updates",
+			Detail:   err.Error(),
+		}}
+	}
+	return nil
+}
+
+// Key names used by different BMC firmware releases for board hardware details
+var (
+	boardRevisionKeys = []string{"board_revision", "board_version", "hw_version", "hardware_version"}
+	bmcSoCKeys        = []string{"bmc_soc", "soc", "hardware", "model", "cpu"}
+)
+
+// parseBoardInfo extracts the board revision and BMC SoC from about data.
+// Either value is empty when the firmware does not report it.
+func parseBoardInfo(aboutMap map[string]string) (revision, soc string) {
+	for _, key := range boardRevisionKeys {
+		if v := strings.TrimSpace(aboutMap[key]); v != "" {
+			revision = v
+			break
+		}
+	}
+	for _, key := range bmcSoCKeys {
+		if v := strings.TrimSpace(aboutMap[key]); v != "" {
+			soc = v
+			break
+		}
+	}
+	return revision, soc
+}
+
+// setBoardData sets board_revision, bmc_soc and board_model from about data
+func setBoardData(d *schema.ResourceData, aboutMap map[string]string) error {
+	revision, soc := parseBoardInfo(aboutMap)
+	if err := d.Set("board_revision", revision); err != nil {
+		return fmt.Errorf("failed to set board_revision: %w", err)
+	}
+	if err := d.Set("bmc_soc", soc); err != nil {
+		return fmt.Errorf("failed to set bmc_soc: %w", err)
+	}
+	caps := &boardCapabilities{Revision: revision}
+	if err := d.Set("board_model", caps.Model()); err != nil {
+		return fmt.Errorf("failed to set board_model: %w", err)
+	}
 	return nil
 }
 
@@ -310,11 +423,11 @@ func parseAboutResponse(data *bmcAboutResponse) map[string]string {
 
 	// Try parsing as new format first: [{"result": {key: value, ...}}]
 	var newFormat []map[string]interface{}
-	if err := json.Unmarshal(data.Response, &newFormat); err == nil {
+	if err := json.Unmarshal(data.Response, &newFormat); err == nil && parseV2Responses() {
 		for _, item := range newFormat {
 			if result, ok := item["result"].(map[string]interface{}); ok {
 				for key, value := range result {
-					if strVal, ok := value.(string); ok {
+					if strVal, ok := aboutValue(value); ok {
 						aboutMap[key] = strVal
 					}
 				}
@@ -327,11 +440,11 @@ func parseAboutResponse(data *bmcAboutResponse) map[string]string {
 
 	// Fall back to legacy format: [[key, value], [key, value], ...]
 	var legacyFormat [][]interface{}
-	if err := json.Unmarshal(data.Response, &legacyFormat); err == nil {
+	if err := json.Unmarshal(data.Response, &legacyFormat); err == nil && parseLegacyResponses() {
 		for _, item := range legacyFormat {
 			if len(item) >= 2 {
 				key, keyOk := item[0].(string)
-				value, valueOk := item[1].(string)
+				value, valueOk := aboutValue(item[1])
 				if keyOk && valueOk {
 					aboutMap[key] = value
 				}
@@ -342,6 +455,27 @@ func parseAboutResponse(data *bmcAboutResponse) map[string]string {
 	return aboutMap
 }
 
+// aboutValue converts an about response value to a string. Numbers and
+// booleans are formatted, objects and arrays are kept as JSON, null is skipped.
+func aboutValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
+
 func setInfoData(d *schema.ResourceData, data *bmcInfoResponse) error {
 	networks, storages := parseInfoResponse(data)
 
@@ -392,7 +526,7 @@ func parseInfoResponse(data *bmcInfoResponse) ([]networkInterface, []storageDevi
 
 	// Try parsing as new format first: [{"result": {"ip": [...], "storage": [...]}}]
 	var newFormat []map[string]interface{}
-	if err := json.Unmarshal(data.Response, &newFormat); err == nil {
+	if err := json.Unmarshal(data.Response, &newFormat); err == nil && parseV2Responses() {
 		for _, item := range newFormat {
 			if result, ok := item["result"].(map[string]interface{}); ok {
 				// Parse IP/network interfaces
@@ -433,7 +567,7 @@ func parseInfoResponse(data *bmcInfoResponse) ([]networkInterface, []storageDevi
 		Network []networkInterface `json:"network"`
 		Storage []storageDevice    `json:"storage"`
 	}
-	if err := json.Unmarshal(data.Response, &legacyFormat); err == nil {
+	if err := json.Unmarshal(data.Response, &legacyFormat); err == nil && parseLegacyResponses() {
 		networks = legacyFormat.Network
 		storages = legacyFormat.Storage
 	}
@@ -486,7 +620,7 @@ func parsePowerResponseForInfo(data *bmcPowerResponse) map[string]interface{} {
 
 	// Try parsing as new format first: [{"result": [{"node1": "1", ...}]}]
 	var newFormat []map[string]interface{}
-	if err := json.Unmarshal(data.Response, &newFormat); err == nil {
+	if err := json.Unmarshal(data.Response, &newFormat); err == nil && parseV2Responses() {
 		for _, item := range newFormat {
 			// Check for "result" array in the response
 			if result, ok := item["result"].([]interface{}); ok {
@@ -504,7 +638,7 @@ func parsePowerResponseForInfo(data *bmcPowerResponse) map[string]interface{} {
 
 	// Fall back to legacy format: [[nodeName, status], [nodeName, status], ...]
 	var legacyFormat [][]interface{}
-	if err := json.Unmarshal(data.Response, &legacyFormat); err == nil {
+	if err := json.Unmarshal(data.Response, &legacyFormat); err == nil && parseLegacyResponses() {
 		for _, item := range legacyFormat {
 			if len(item) >= 2 {
 				nodeName, nameOk := item[0].(string)
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -26,6 +27,8 @@ func TestDataSourceInfo_Schema(t *testing.T) {
 		"buildroot_version",
 		"firmware_version",
 		"build_time",
+		"board_revision",
+		"bmc_soc",
 		"network_interfaces",
 		"storage_devices",
 		"nodes",
@@ -50,6 +53,8 @@ func TestDataSourceInfo_SchemaTypes(t *testing.T) {
 		{"buildroot_version", schema.TypeString},
 		{"firmware_version", schema.TypeString},
 		{"build_time", schema.TypeString},
+		{"board_revision", schema.TypeString},
+		{"bmc_soc", schema.TypeString},
 		{"network_interfaces", schema.TypeList},
 		{"storage_devices", schema.TypeList},
 		{"nodes", schema.TypeMap},
@@ -68,6 +73,9 @@ func TestDataSourceInfo_AllFieldsComputed(t *testing.T) {
 	d := dataSourceInfo()
 
 	for name, s := range d.Schema {
+		if name == "check_for_updates" {
+			continue
+		}
 		if !s.Computed {
 			t.Errorf("field %s should be computed", name)
 		}
@@ -180,6 +188,11 @@ func TestDataSourceInfoRead_Success(t *testing.T) {
 		Endpoint: server.URL,
 	}
 
+	newFakeReleaseServer(t, &bmcFirmwareReleasesURL, http.StatusOK, testBMCFirmwareReleases)
+	if err := rd.Set("check_for_updates", true); err != nil {
+		t.Fatal(err)
+	}
+
 	diags := dataSourceInfoRead(context.Background(), rd, config)
 	if diags.HasError() {
 		t.Fatalf("unexpected error: %v", diags)
@@ -203,6 +216,12 @@ func TestDataSourceInfoRead_Success(t *testing.T) {
 	if v := rd.Get("firmware_version").(string); v != "1.1.0" {
 		t.Errorf("expected firmware_version '1.1.0', got '%s'", v)
 	}
+	if v := rd.Get("latest_version").(string); v != "v2.1.0" {
+		t.Errorf("expected latest_version 'v2.1.0', got '%s'", v)
+	}
+	if !rd.Get("update_available").(bool) {
+		t.Error("expected update_available for daemon version 2.0.5")
+	}
 
 	// Verify network interfaces
 	networkInterfaces := rd.Get("network_interfaces").([]interface{})
@@ -424,6 +443,7 @@ func TestSetAboutData(t *testing.T) {
 		{"buildroot", "2023.02"},
 		{"firmware", "1.1.0"},
 		{"buildtime", "2024-01-15T10:30:00Z"},
+		{"hw_version", "2.4"},
 	}
 	jsonData, _ := json.Marshal(responseData)
 	aboutData := &bmcAboutResponse{
@@ -450,6 +470,12 @@ func TestSetAboutData(t *testing.T) {
 	if v := rd.Get("build_time").(string); v != "2024-01-15T10:30:00Z" {
 		t.Errorf("expected build_time '2024-01-15T10:30:00Z', got '%s'", v)
 	}
+	if v := rd.Get("board_revision").(string); v != "2.4" {
+		t.Errorf("expected board_revision '2.4', got '%s'", v)
+	}
+	if v := rd.Get("bmc_soc").(string); v != "" {
+		t.Errorf("expected empty bmc_soc, got '%s'", v)
+	}
 }
 
 func TestSetPowerData_BoolValues(t *testing.T) {
@@ -507,3 +533,89 @@ func TestSetPowerData_NumericValues(t *testing.T) {
 		t.Errorf("expected node2 to be false")
 	}
 }
+
+const testBMCFirmwareReleases = `[
+	{"tag_name":"v2.2.0-rc1","prerelease":true},
+	{"tag_name":"v2.1.0","prerelease":false},
+	{"tag_name":"v2.0.5","prerelease":false}
+]`
+
+func TestDataSourceInfoRead_UpdateCheckFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("type") {
+		case "about":
+			_, _ = w.Write([]byte(`{"response":[["version","2.0.5"]]}`))
+		case "info":
+			_, _ = w.Write([]byte(`{"response":{"network":[],"storage":[]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"response":[]}`))
+		}
+	}))
+	defer server.Close()
+	newFakeReleaseServer(t, &bmcFirmwareReleasesURL, http.StatusForbidden, `{"message":"API rate limit exceeded"}`)
+
+	rd := dataSourceInfo().TestResourceData()
+	if err := rd.Set("check_for_updates", true); err != nil {
+		t.Fatal(err)
+	}
+	diags := dataSourceInfoRead(context.Background(), rd, &ProviderConfig{Token: "test-token", Endpoint: server.URL})
+	if diags.HasError() {
+		t.Fatalf("expected a failed update check not to fail the read: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Errorf("expected one warning, got %v", diags)
+	}
+	if rd.Get("latest_version").(string) != "" || rd.Get("update_available").(bool) {
+		t.Error("expected no update information when the lookup fails")
+	}
+}
+
+func TestDataSourceInfoRead_UpdateCheckOffByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("type") {
+		case "about":
+			_, _ = w.Write([]byte(`{"response":[["version","2.0.5"]]}`))
+		case "info":
+			_, _ = w.Write([]byte(`{"response":{"network":[],"storage":[]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"response":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	releases := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no release lookup without check_for_updates")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer releases.Close()
+	orig := bmcFirmwareReleasesURL
+	bmcFirmwareReleasesURL = releases.URL
+	defer func() { bmcFirmwareReleasesURL = orig }()
+
+	rd := dataSourceInfo().TestResourceData()
+	diags := dataSourceInfoRead(context.Background(), rd, &ProviderConfig{Token: "test-token", Endpoint: server.URL})
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if rd.Get("latest_version").(string) != "" || rd.Get("update_available").(bool) {
+		t.Error("expected no update information without check_for_updates")
+	}
+}
+
+func TestFirmwareUpdateAvailable(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"2.0.5", "v2.1.0", true},
+		{"v2.1.0", "v2.1.0", false},
+		{"2.2.0", "v2.1.0", false},
+		{"", "v2.1.0", false},
+		{"2.0.5", "", false},
+	}
+	for _, tt := range tests {
+		if got := firmwareUpdateAvailable(tt.current, tt.latest); got != tt.want {
+			t.Errorf("firmwareUpdateAvailable(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// boardSerialKeys are key names used by different BMC firmware releases for the board serial number
+var boardSerialKeys = []string{"serial", "serial_number", "board_serial", "sn"}
+
+type bmcNodeInfoResponse struct {
+	Response json.RawMessage `json:"response"`
+}
+
+// inventorySlot describes one compute module slot
+type inventorySlot struct {
+	Node    int    `json:"node"`
+	Powered bool   `json:"powered"`
+	Name    string `json:"name"`
+	Module  string `json:"module"`
+	Host    string `json:"host"`
+}
+
+// inventory is the structured board inventory rendered to JSON
+type inventory struct {
+	Endpoint        string             `json:"endpoint"`
+	Serial          string             `json:"serial"`
+	BoardRevision   string             `json:"board_revision"`
+	BMCSoC          string             `json:"bmc_soc"`
+	FirmwareVersion string             `json:"firmware_version"`
+	APIVersion      string             `json:"api_version"`
+	BMCInterfaces   []networkInterface `json:"bmc_interfaces"`
+	Slots           []inventorySlot    `json:"slots"`
+}
+
+func dataSourceInventory() *schema.Resource {
+	return &schema.Resource{
+		Description: "Assembles a board inventory (serial, firmware, BMC network interfaces and per-slot module details) " +
+			"into structured attributes plus rendered JSON and CSV, for feeding asset databases from Terraform.",
+		ReadContext: dataSourceInventoryRead,
+		Schema: map[string]*schema.Schema{
+			"node_hosts": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "IP address or hostname of each node OS (node1-node4 -> host), included in the inventory. The BMC does not know node IPs.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			// Computed attributes
+			"serial": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Board serial number. Empty if not reported by the BMC firmware.",
+			},
+			"board_revision": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Turing Pi board revision, if reported by the BMC",
+			},
+			"bmc_soc": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "BMC hardware model / SoC, if reported by the BMC",
+			},
+			"firmware_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "BMC firmware version",
+			},
+			"api_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "BMC API version",
+			},
+			"bmc_interfaces": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "BMC network interfaces",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"device": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Network interface device name",
+						},
+						"ip": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "IP address",
+						},
+						"mac": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "MAC address",
+						},
+					},
+				},
+			},
+			"slots": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Compute module slots 1-4",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Slot number (1-4)",
+						},
+						"powered": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the slot is powered on",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node name configured on the BMC. Empty if not reported.",
+						},
+						"module": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Compute module name as configured on the BMC (e.g., RK1, CM4). Empty if not reported.",
+						},
+						"host": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node OS host from node_hosts",
+						},
+					},
+				},
+			},
+			"json": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The whole inventory rendered as JSON",
+			},
+			"csv": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "One CSV row per slot, with a header row and the board serial and firmware version repeated on each row",
+			},
+		},
+	}
+}
+
+func dataSourceInventoryRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to fetch BMC about info: %w", err))
+	}
+	aboutMap := parseAboutResponse(aboutData)
+
+	infoData, err := fetchBMCInfo(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to fetch BMC info: %w", err))
+	}
+	interfaces, _ := parseInfoResponse(infoData)
+
+	powerData, err := fetchBMCPower(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to fetch BMC power status: %w", err))
+	}
+
+	// Node names and module types are only reported by newer firmware
+	var nodeInfo map[int]map[string]string
+	if data, err := fetchBMCNodeInfo(config.Endpoint, config.Token); err == nil {
+		nodeInfo = parseNodeInfoResponse(data)
+	}
+
+	inv := buildInventory(config.Endpoint, aboutMap, interfaces, parsePowerResponseForInfo(powerData), nodeInfo,
+		expandStringMap(d.Get("node_hosts").(map[string]interface{})))
+
+	if err := setInventoryData(d, inv); err != nil {
+		return diagFromErr(err)
+	}
+
+	d.SetId("turingpi-inventory")
+	return nil
+}
+
+// buildInventory assembles the inventory from parsed BMC responses
+func buildInventory(endpoint string, aboutMap map[string]string, interfaces []networkInterface,
+	power map[string]interface{}, nodeInfo map[int]map[string]string, hosts map[string]string) *inventory {
+	revision, soc := parseBoardInfo(aboutMap)
+	inv := &inventory{
+		Endpoint:        endpoint,
+		BoardRevision:   revision,
+		BMCSoC:          soc,
+		FirmwareVersion: aboutMap["firmware"],
+		APIVersion:      aboutMap["api"],
+		BMCInterfaces:   interfaces,
+	}
+	if inv.FirmwareVersion == "" {
+		inv.FirmwareVersion = aboutMap["version"]
+	}
+	for _, key := range boardSerialKeys {
+		if v := strings.TrimSpace(aboutMap[key]); v != "" {
+			inv.Serial = v
+			break
+		}
+	}
+	if inv.BMCInterfaces == nil {
+		inv.BMCInterfaces = []networkInterface{}
+	}
+
+	for i := 1; i <= 4; i++ {
+		name := fmt.Sprintf("node%d", i)
+		powered, _ := power[name].(bool)
+		inv.Slots = append(inv.Slots, inventorySlot{
+			Node:    i,
+			Powered: powered,
+			Name:    nodeInfo[i]["name"],
+			Module:  nodeInfo[i]["module_name"],
+			Host:    hosts[name],
+		})
+	}
+
+	return inv
+}
+
+// setInventoryData sets the structured and rendered inventory attributes
+func setInventoryData(d *schema.ResourceData, inv *inventory) error {
+	values := map[string]interface{}{
+		"serial":           inv.Serial,
+		"board_revision":   inv.BoardRevision,
+		"bmc_soc":          inv.BMCSoC,
+		"firmware_version": inv.FirmwareVersion,
+		"api_version":      inv.APIVersion,
+	}
+
+	interfaces := make([]interface{}, 0, len(inv.BMCInterfaces))
+	for _, iface := range inv.BMCInterfaces {
+		interfaces = append(interfaces, map[string]interface{}{
+			"device": iface.Device,
+			"ip":     iface.IP,
+			"mac":    iface.MAC,
+		})
+	}
+	values["bmc_interfaces"] = interfaces
+
+	slots := make([]interface{}, 0, len(inv.Slots))
+	for _, slot := range inv.Slots {
+		slots = append(slots, map[string]interface{}{
+			"node":    slot.Node,
+			"powered": slot.Powered,
+			"name":    slot.Name,
+			"module":  slot.Module,
+			"host":    slot.Host,
+		})
+	}
+	values["slots"] = slots
+
+	rendered, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("failed to render inventory JSON: %w", err)
+	}
+	values["json"] = string(rendered)
+
+	csvData, err := renderInventoryCSV(inv)
+	if err != nil {
+		return fmt.Errorf("failed to render inventory CSV: %w", err)
+	}
+	values["csv"] = csvData
+
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// renderInventoryCSV renders one row per slot with board-level columns repeated
+func renderInventoryCSV(inv *inventory) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{{"serial", "board_revision", "firmware_version", "node", "powered", "name", "module", "host"}}
+	for _, slot := range inv.Slots {
+		rows = append(rows, []string{
+			inv.Serial, inv.BoardRevision, inv.FirmwareVersion,
+			strconv.Itoa(slot.Node), strconv.FormatBool(slot.Powered), slot.Name, slot.Module, slot.Host,
+		})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func fetchBMCNodeInfo(endpoint, token string) (*bmcNodeInfoResponse, error) {
+	url := fmt.Sprintf("%s/api/bmc?opt=get&type=node_info", endpoint)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result bmcNodeInfoResponse
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// parseNodeInfoResponse extracts per-node string fields (name, module_name, ...)
+// keyed by node number. The result is an array in slot order:
+// [{"result": [{"name": "...", "module_name": "..."}, ...]}]
+func parseNodeInfoResponse(data *bmcNodeInfoResponse) map[int]map[string]string {
+	nodes := make(map[int]map[string]string)
+
+	var newFormat []map[string]interface{}
+	if err := json.Unmarshal(data.Response, &newFormat); err != nil {
+		return nodes
+	}
+	for _, item := range newFormat {
+		result, ok := item["result"].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, r := range result {
+			nodeMap, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fields := make(map[string]string)
+			for k, v := range nodeMap {
+				if s, ok := v.(string); ok {
+					fields[k] = s
+				}
+			}
+			nodes[i+1] = fields
+		}
+	}
+
+	return nodes
+}
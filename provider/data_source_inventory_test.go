@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// newFakeInventoryBMC serves about, info, power and (optionally) node_info responses
+func newFakeInventoryBMC(t *testing.T, withNodeInfo bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var response interface{}
+		switch r.URL.Query().Get("type") {
+		case "about":
+			response = [][]interface{}{
+				{"api", "1.1"},
+				{"version", "2.3.4"},
+				{"firmware", "2.3.4"},
+				{"serial", "TP2-0042"},
+				{"board_revision", "2.5.2"},
+			}
+		case "info":
+			response = map[string]interface{}{
+				"network": []map[string]string{
+					{"device": "eth0", "ip": "10.10.88.70", "mac": "00:11:22:33:44:55"},
+				},
+			}
+		case "power":
+			response = [][]interface{}{
+				{"node1", float64(1)},
+				{"node2", float64(1)},
+				{"node3", float64(0)},
+				{"node4", float64(0)},
+			}
+		case "node_info":
+			if !withNodeInfo {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			response = []map[string]interface{}{{
+				"result": []map[string]interface{}{
+					{"name": "cp", "module_name": "RK1"},
+					{"name": "worker-1", "module_name": "RK1"},
+					{"name": "", "module_name": "CM4"},
+					{"name": "", "module_name": ""},
+				},
+			}}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"response": response})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDataSourceInventory(t *testing.T) {
+	d := dataSourceInventory()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourceInventoryRead(t *testing.T) {
+	server := newFakeInventoryBMC(t, true)
+
+	d := schema.TestResourceDataRaw(t, dataSourceInventory().Schema, map[string]interface{}{
+		"node_hosts": map[string]interface{}{"node1": "10.10.88.73", "node2": "10.10.88.74"},
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	if diags := dataSourceInventoryRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if v := d.Get("serial").(string); v != "TP2-0042" {
+		t.Errorf("expected serial TP2-0042, got %q", v)
+	}
+	if v := d.Get("slots.1.module").(string); v != "RK1" {
+		t.Errorf("expected slot 2 module RK1, got %q", v)
+	}
+	if v := d.Get("slots.0.host").(string); v != "10.10.88.73" {
+		t.Errorf("expected slot 1 host, got %q", v)
+	}
+
+	var inv inventory
+	if err := json.Unmarshal([]byte(d.Get("json").(string)), &inv); err != nil {
+		t.Fatalf("invalid inventory JSON: %v", err)
+	}
+	if inv.FirmwareVersion != "2.3.4" || len(inv.Slots) != 4 || !inv.Slots[1].Powered || inv.Slots[2].Powered {
+		t.Errorf("unexpected inventory: %+v", inv)
+	}
+	if len(inv.BMCInterfaces) != 1 || inv.BMCInterfaces[0].IP != "10.10.88.70" {
+		t.Errorf("unexpected BMC interfaces: %+v", inv.BMCInterfaces)
+	}
+
+	lines := strings.Split(strings.TrimSpace(d.Get("csv").(string)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header and 4 rows, got %d lines", len(lines))
+	}
+	if lines[1] != "TP2-0042,2.5.2,2.3.4,1,true,cp,RK1,10.10.88.73" {
+		t.Errorf("unexpected first CSV row: %q", lines[1])
+	}
+}
+
+func TestDataSourceInventoryRead_WithoutNodeInfo(t *testing.T) {
+	server := newFakeInventoryBMC(t, false)
+
+	d := schema.TestResourceDataRaw(t, dataSourceInventory().Schema, map[string]interface{}{})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	if diags := dataSourceInventoryRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error on firmware without node_info: %v", diags)
+	}
+	if slots := d.Get("slots").([]interface{}); len(slots) != 4 {
+		t.Errorf("expected 4 slots, got %d", len(slots))
+	}
+	if v := d.Get("slots.0.module").(string); v != "" {
+		t.Errorf("expected empty module without node_info, got %q", v)
+	}
+}
@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceK3sClusterStatus() *schema.Resource {
+	return &schema.Resource{
+		Description: "Reports the node list, K3s version and component health of an existing K3s cluster over SSH, " +
+			"without managing it. Useful for monitoring-only workspaces.",
+		ReadContext: dataSourceK3sClusterStatusRead,
+		Schema: map[string]*schema.Schema{
+			"control_plane": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MaxItems:    1,
+				Description: "SSH connection details for the control plane node",
+				Elem:        k3sNodeSchema(),
+			},
+			// Computed attributes
+			"k3s_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "K3s version installed on the control plane",
+			},
+			"nodes": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Nodes registered in the cluster",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node name",
+						},
+						"ready": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the node's Ready condition is True",
+						},
+						"roles": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Node roles (e.g., control-plane, master)",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Kubelet version",
+						},
+						"internal_ip": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node internal IP address",
+						},
+					},
+				},
+			},
+			"node_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Total number of nodes",
+			},
+			"ready_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of Ready nodes",
+			},
+			"components": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "API server readiness checks mapped to \"ok\" or the failure reason",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"healthy": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "True when all nodes are Ready and all readiness checks pass",
+			},
+		},
+	}
+}
+
+func dataSourceK3sClusterStatusRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	cpList := d.Get("control_plane").([]interface{})
+	if len(cpList) == 0 {
+		return diag.Errorf("control_plane is required")
+	}
+	controlPlane := extractNodeConfig(cpList[0].(map[string]interface{}))
+	if err := validateNodeSSH(controlPlane); err != nil {
+		return diagFromErr(err)
+	}
+
+	return readK3sClusterStatus(d, NewK3sProvisioner(), controlPlane)
+}
+
+// readK3sClusterStatus queries the cluster through the given provisioner and sets the data source attributes
+func readK3sClusterStatus(d *schema.ResourceData, provisioner *K3sProvisioner, controlPlane NodeConfig) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	installed, err := provisioner.CheckK3sInstalled(controlPlane)
+	if err != nil || !installed {
+		return diag.Errorf("K3s is not installed on %s", controlPlane.Host)
+	}
+
+	version, err := provisioner.GetK3sVersion(controlPlane)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	// "k3s version v1.31.4+k3s1 (xxx)" -> "v1.31.4+k3s1"
+	for _, part := range strings.Fields(version) {
+		if len(part) > 1 && part[0] == 'v' && part[1] >= '0' && part[1] <= '9' {
+			version = part
+			break
+		}
+	}
+	if err := d.Set("k3s_version", version); err != nil {
+		return diagFromErr(err)
+	}
+
+	nodes, err := provisioner.GetNodeStatuses(controlPlane)
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	readyCount := 0
+	nodeList := make([]interface{}, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Ready {
+			readyCount++
+		}
+		nodeList = append(nodeList, map[string]interface{}{
+			"name":        n.Name,
+			"ready":       n.Ready,
+			"roles":       n.Roles,
+			"version":     n.Version,
+			"internal_ip": n.InternalIP,
+		})
+	}
+	if err := d.Set("nodes", nodeList); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("node_count", len(nodes)); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("ready_count", readyCount); err != nil {
+		return diagFromErr(err)
+	}
+
+	components, healthErr := provisioner.GetComponentHealth(controlPlane)
+	if healthErr != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Could not read component health",
+			Detail:   healthErr.Error(),
+		})
+	}
+	if err := d.Set("components", components); err != nil {
+		return diagFromErr(err)
+	}
+
+	healthy := healthErr == nil && len(nodes) > 0 && readyCount == len(nodes)
+	for _, status := range components {
+		if status != "ok" {
+			healthy = false
+		}
+	}
+	if err := d.Set("healthy", healthy); err != nil {
+		return diagFromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("k3s-cluster-status-%s", controlPlane.Host))
+	return diags
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const testNodeListJSON = `{
+  "items": [
+    {
+      "metadata": {
+        "name": "node1",
+        "labels": {
+          "node-role.kubernetes.io/master": "true",
+          "node-role.kubernetes.io/control-plane": "true"
+        }
+      },
+      "status": {
+        "conditions": [{"type": "Ready", "status": "True"}],
+        "addresses": [{"type": "InternalIP", "address": "10.10.88.73"}],
+        "nodeInfo": {"kubeletVersion": "v1.31.4+k3s1"}
+      }
+    },
+    {
+      "metadata": {"name": "node2", "labels": {}},
+      "status": {
+        "conditions": [{"type": "Ready", "status": "False"}],
+        "addresses": [{"type": "InternalIP", "address": "10.10.88.74"}],
+        "nodeInfo": {"kubeletVersion": "v1.31.4+k3s1"}
+      }
+    }
+  ]
+}`
+
+func TestDataSourceK3sClusterStatus(t *testing.T) {
+	d := dataSourceK3sClusterStatus()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourceK3sClusterStatus_SchemaTypes(t *testing.T) {
+	d := dataSourceK3sClusterStatus()
+
+	tests := []struct {
+		field    string
+		expected schema.ValueType
+	}{
+		{"control_plane", schema.TypeList},
+		{"k3s_version", schema.TypeString},
+		{"nodes", schema.TypeList},
+		{"node_count", schema.TypeInt},
+		{"ready_count", schema.TypeInt},
+		{"components", schema.TypeMap},
+		{"healthy", schema.TypeBool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			s, ok := d.Schema[tt.field]
+			if !ok {
+				t.Fatalf("schema missing '%s' field", tt.field)
+			}
+			if s.Type != tt.expected {
+				t.Errorf("expected %s to be type %v, got %v", tt.field, tt.expected, s.Type)
+			}
+		})
+	}
+}
+
+func TestParseNodeStatuses(t *testing.T) {
+	nodes, err := parseNodeStatuses(testNodeListJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(nodes))
+	}
+
+	cp := nodes[0]
+	if cp.Name != "node1" || !cp.Ready || cp.InternalIP != "10.10.88.73" || cp.Version != "v1.31.4+k3s1" {
+		t.Errorf("unexpected control plane status: %+v", cp)
+	}
+	if strings.Join(cp.Roles, ",") != "control-plane,master" {
+		t.Errorf("expected roles control-plane,master, got %v", cp.Roles)
+	}
+	if nodes[1].Ready {
+		t.Error("expected node2 to be not ready")
+	}
+	if len(nodes[1].Roles) != 0 {
+		t.Errorf("expected no roles for node2, got %v", nodes[1].Roles)
+	}
+
+	if _, err := parseNodeStatuses("not json"); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestParseReadyz(t *testing.T) {
+	output := `[+]ping ok
+[+]etcd ok
+[-]informer-sync failed: reason withheld
+readyz check failed`
+
+	health := parseReadyz(output)
+	if len(health) != 3 {
+		t.Fatalf("expected 3 checks, got %d: %v", len(health), health)
+	}
+	if health["etcd"] != "ok" {
+		t.Errorf("expected etcd ok, got %q", health["etcd"])
+	}
+	if health["informer-sync"] != "failed: reason withheld" {
+		t.Errorf("unexpected informer-sync status: %q", health["informer-sync"])
+	}
+}
+
+func TestReadK3sClusterStatus(t *testing.T) {
+	readyz := "[+]ping ok\n[+]etcd ok\nreadyz check passed"
+
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				switch {
+				case strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s"):
+					return "installed\n", nil
+				case strings.HasPrefix(cmd, "k3s --version"):
+					return "k3s version v1.31.4+k3s1 (a1b2c3d4)\n", nil
+				case strings.HasPrefix(cmd, "k3s kubectl get nodes -o json"):
+					return testNodeListJSON, nil
+				case strings.Contains(cmd, "/readyz?verbose"):
+					return readyz, nil
+				}
+				return "", fmt.Errorf("unexpected command: %s", cmd)
+			},
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceK3sClusterStatus().Schema, map[string]interface{}{})
+	node := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHKey: []byte("fake-key"), SSHPort: 22}
+
+	diags := readK3sClusterStatus(d, NewK3sProvisionerWithClientFactory(mockFactory), node)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if d.Id() != "k3s-cluster-status-10.10.88.73" {
+		t.Errorf("unexpected ID: %s", d.Id())
+	}
+	if v := d.Get("k3s_version").(string); v != "v1.31.4+k3s1" {
+		t.Errorf("expected k3s_version 'v1.31.4+k3s1', got %q", v)
+	}
+	if v := d.Get("node_count").(int); v != 2 {
+		t.Errorf("expected node_count 2, got %d", v)
+	}
+	if v := d.Get("ready_count").(int); v != 1 {
+		t.Errorf("expected ready_count 1, got %d", v)
+	}
+	if v := d.Get("components.etcd").(string); v != "ok" {
+		t.Errorf("expected etcd component 'ok', got %q", v)
+	}
+	if v := d.Get("nodes.1.internal_ip").(string); v != "10.10.88.74" {
+		t.Errorf("expected node2 internal_ip '10.10.88.74', got %q", v)
+	}
+	// node2 is NotReady so the cluster is not healthy
+	if d.Get("healthy").(bool) {
+		t.Error("expected healthy to be false with a NotReady node")
+	}
+}
+
+func TestReadK3sClusterStatus_NotInstalled(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				return "not_installed", nil
+			},
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceK3sClusterStatus().Schema, map[string]interface{}{})
+	node := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHKey: []byte("fake-key"), SSHPort: 22}
+
+	diags := readK3sClusterStatus(d, NewK3sProvisionerWithClientFactory(mockFactory), node)
+	if !diags.HasError() {
+		t.Fatal("expected error when K3s is not installed")
+	}
+}
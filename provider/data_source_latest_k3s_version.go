@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceLatestK3sVersion() *schema.Resource {
+	return &schema.Resource{
+		Description: "Resolves the current K3s version of a release channel from the K3s channel server, " +
+			"so clusters can track a channel without hard-coding a version.",
+		ReadContext: dataSourceLatestK3sVersionRead,
+		Schema: map[string]*schema.Schema{
+			"channel": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "stable",
+				Description: "Release channel: stable, latest, testing, or a minor version channel such as v1.30.",
+			},
+			// Computed attributes
+			"version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Resolved K3s version (e.g., v1.31.4+k3s1), usable as k3s_version.",
+			},
+		},
+	}
+}
+
+func dataSourceLatestK3sVersionRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	channel := d.Get("channel").(string)
+
+	v, err := resolveK3sChannel(ctx, channel)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to resolve K3s channel: %w", err))
+	}
+
+	if err := d.Set("version", v); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set version: %w", err))
+	}
+
+	d.SetId(fmt.Sprintf("k3s-%s", channel))
+	return nil
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// newFakeReleaseServer serves body for every request and points url at the server
+func newFakeReleaseServer(t *testing.T, url *string, status int, body string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	orig := *url
+	*url = server.URL
+	t.Cleanup(func() { *url = orig })
+}
+
+const testK3sChannels = `{"data":[
+	{"id":"stable","name":"stable","latest":"v1.31.4+k3s1"},
+	{"id":"latest","name":"latest","latest":"v1.32.0+k3s1"},
+	{"id":"v1.30","name":"v1.30","latest":"v1.30.8+k3s1"}
+]}`
+
+func TestDataSourceLatestK3sVersion(t *testing.T) {
+	d := dataSourceLatestK3sVersion()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourceLatestK3sVersionRead(t *testing.T) {
+	newFakeReleaseServer(t, &k3sChannelsURL, http.StatusOK, testK3sChannels)
+
+	tests := map[string]string{
+		"stable": "v1.31.4+k3s1",
+		"latest": "v1.32.0+k3s1",
+		"v1.30":  "v1.30.8+k3s1",
+	}
+	for channel, expected := range tests {
+		d := schema.TestResourceDataRaw(t, dataSourceLatestK3sVersion().Schema, map[string]interface{}{"channel": channel})
+		if diags := dataSourceLatestK3sVersionRead(context.Background(), d, nil); diags.HasError() {
+			t.Fatalf("channel %s: unexpected error: %v", channel, diags)
+		}
+		if v := d.Get("version").(string); v != expected {
+			t.Errorf("channel %s: expected %q, got %q", channel, expected, v)
+		}
+	}
+}
+
+func TestDataSourceLatestK3sVersionRead_UnknownChannel(t *testing.T) {
+	newFakeReleaseServer(t, &k3sChannelsURL, http.StatusOK, testK3sChannels)
+
+	d := schema.TestResourceDataRaw(t, dataSourceLatestK3sVersion().Schema, map[string]interface{}{"channel": "v1.20"})
+	diags := dataSourceLatestK3sVersionRead(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "available: stable, latest, v1.30") {
+		t.Errorf("expected unknown channel error listing channels, got %v", diags)
+	}
+}
+
+func TestDataSourceLatestK3sVersionRead_HTTPError(t *testing.T) {
+	newFakeReleaseServer(t, &k3sChannelsURL, http.StatusServiceUnavailable, "unavailable")
+
+	d := schema.TestResourceDataRaw(t, dataSourceLatestK3sVersion().Schema, map[string]interface{}{})
+	diags := dataSourceLatestK3sVersionRead(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "HTTP 503") {
+		t.Errorf("expected HTTP error, got %v", diags)
+	}
+}
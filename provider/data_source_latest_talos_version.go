@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceLatestTalosVersion() *schema.Resource {
+	return &schema.Resource{
+		Description: "Resolves the newest Talos Linux release of a channel from GitHub releases, " +
+			"so clusters can track a channel without hard-coding a version.",
+		ReadContext: dataSourceLatestTalosVersionRead,
+		Schema: map[string]*schema.Schema{
+			"channel": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "stable",
+				Description: "Release channel: stable (newest release), latest (including alpha and beta releases), or a minor version such as v1.7 (newest patch release).",
+			},
+			// Computed attributes
+			"version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Resolved Talos version (e.g., v1.8.3), usable as talos_version.",
+			},
+		},
+	}
+}
+
+func dataSourceLatestTalosVersionRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	channel := d.Get("channel").(string)
+
+	v, err := resolveTalosChannel(ctx, channel)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to resolve Talos channel: %w", err))
+	}
+
+	if err := d.Set("version", v); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set version: %w", err))
+	}
+
+	d.SetId(fmt.Sprintf("talos-%s", channel))
+	return nil
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const testTalosReleases = `[
+	{"tag_name":"v1.9.0-beta.0","prerelease":true},
+	{"tag_name":"v1.8.3","prerelease":false},
+	{"tag_name":"v1.7.7","prerelease":false},
+	{"tag_name":"v1.8.10","prerelease":false,"draft":true},
+	{"tag_name":"v1.7.10","prerelease":false}
+]`
+
+func TestDataSourceLatestTalosVersion(t *testing.T) {
+	d := dataSourceLatestTalosVersion()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourceLatestTalosVersionRead(t *testing.T) {
+	newFakeReleaseServer(t, &talosReleasesURL, http.StatusOK, testTalosReleases)
+
+	d := schema.TestResourceDataRaw(t, dataSourceLatestTalosVersion().Schema, map[string]interface{}{})
+	if diags := dataSourceLatestTalosVersionRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if v := d.Get("version").(string); v != "v1.8.3" {
+		t.Errorf("expected v1.8.3, got %q", v)
+	}
+	if d.Id() != "talos-stable" {
+		t.Errorf("expected ID talos-stable, got %q", d.Id())
+	}
+}
+
+func TestSelectTalosRelease(t *testing.T) {
+	var releases []githubRelease
+	if err := json.Unmarshal([]byte(testTalosReleases), &releases); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channel  string
+		expected string
+		wantErr  bool
+	}{
+		{channel: "stable", expected: "v1.8.3"},
+		{channel: "latest", expected: "v1.9.0-beta.0"},
+		{channel: "v1.7", expected: "v1.7.10"},
+		{channel: "v1.6", wantErr: true},
+		{channel: "v1.7.7", wantErr: true},
+		{channel: "beta", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			got, err := selectTalosRelease(releases, tt.channel)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 // powerStatusResponse represents the response from GET /api/bmc?opt=get&type=power
@@ -22,6 +25,21 @@ func dataSourcePower() *schema.Resource {
 		Description: "Retrieves the current power status of all nodes on the Turing Pi BMC.",
 		ReadContext: dataSourcePowerRead,
 		Schema: map[string]*schema.Schema{
+			"require_all_on": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Fail the read when any node is powered off. Use as a guardrail before cluster provisioning.",
+			},
+			"require_nodes": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Node numbers (1-4) that must be powered on. The read fails when any of them is off.",
+				Elem: &schema.Schema{
+					Type:             schema.TypeInt,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
+				},
+			},
 			"node1": {
 				Type:        schema.TypeBool,
 				Computed:    true,
@@ -71,7 +89,7 @@ func dataSourcePowerRead(ctx context.Context, d *schema.ResourceData, meta inter
 	// Fetch power status
 	status, err := getPowerStatus(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to read power status: %w", err))
+		return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
 	}
 
 	// Parse the response
@@ -79,21 +97,21 @@ func dataSourcePowerRead(ctx context.Context, d *schema.ResourceData, meta inter
 
 	// Set individual node values
 	if err := d.Set("node1", nodeStatus["node1"]); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set node1: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set node1: %w", err))
 	}
 	if err := d.Set("node2", nodeStatus["node2"]); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set node2: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set node2: %w", err))
 	}
 	if err := d.Set("node3", nodeStatus["node3"]); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set node3: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set node3: %w", err))
 	}
 	if err := d.Set("node4", nodeStatus["node4"]); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set node4: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set node4: %w", err))
 	}
 
 	// Set nodes map
 	if err := d.Set("nodes", nodeStatus); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set nodes: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set nodes: %w", err))
 	}
 
 	// Calculate counts
@@ -108,18 +126,52 @@ func dataSourcePowerRead(ctx context.Context, d *schema.ResourceData, meta inter
 	}
 
 	if err := d.Set("powered_on_count", poweredOn); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set powered_on_count: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set powered_on_count: %w", err))
 	}
 	if err := d.Set("powered_off_count", poweredOff); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set powered_off_count: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set powered_off_count: %w", err))
 	}
 
 	// Set a stable ID for the data source
 	d.SetId("turingpi-power-status")
 
+	if off := requiredNodesOff(d, nodeStatus); len(off) > 0 {
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Required nodes are powered off",
+			Detail: fmt.Sprintf("Powered off: %s. Power them on (e.g. with turingpi_power) before provisioning.",
+				strings.Join(off, ", ")),
+		})
+	}
+
 	return diags
 }
 
+// requiredNodesOff returns the nodes required by require_all_on or
+// require_nodes that are powered off, in node order
+func requiredNodesOff(d *schema.ResourceData, nodeStatus map[string]bool) []string {
+	required := make(map[string]bool)
+	if d.Get("require_all_on").(bool) {
+		for i := 1; i <= 4; i++ {
+			required[fmt.Sprintf("node%d", i)] = true
+		}
+	}
+	if v, ok := d.GetOk("require_nodes"); ok {
+		for _, n := range v.(*schema.Set).List() {
+			required[fmt.Sprintf("node%d", n.(int))] = true
+		}
+	}
+
+	var off []string
+	for name := range required {
+		if !nodeStatus[name] {
+			off = append(off, name)
+		}
+	}
+	sort.Strings(off)
+	return off
+}
+
 // getPowerStatus fetches current power status from BMC
 func getPowerStatus(endpoint, token string) (*powerStatusResponse, error) {
 	url := fmt.Sprintf("%s/api/bmc?opt=get&type=power", endpoint)
@@ -142,8 +194,8 @@ func getPowerStatus(endpoint, token string) (*powerStatusResponse, error) {
 	}
 
 	var result powerStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -162,7 +214,7 @@ func parsePowerStatus(status *powerStatusResponse) map[string]bool {
 
 	// Try parsing as new format first: [{"result": [{"node1": "1", ...}]}]
 	var newFormat []map[string]interface{}
-	if err := json.Unmarshal(status.Response, &newFormat); err == nil {
+	if err := json.Unmarshal(status.Response, &newFormat); err == nil && parseV2Responses() {
 		for _, item := range newFormat {
 			// Check for "result" array in the response
 			if result, ok := item["result"].([]interface{}); ok {
@@ -180,7 +232,7 @@ func parsePowerStatus(status *powerStatusResponse) map[string]bool {
 
 	// Fall back to legacy format: [[nodeName, status], [nodeName, status], ...]
 	var legacyFormat [][]interface{}
-	if err := json.Unmarshal(status.Response, &legacyFormat); err == nil {
+	if err := json.Unmarshal(status.Response, &legacyFormat); err == nil && parseLegacyResponses() {
 		for _, item := range legacyFormat {
 			if len(item) >= 2 {
 				nodeName, nameOk := item[0].(string)
@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// nodePowerMetrics is the power draw of one slot, as reported by node_info
+type nodePowerMetrics struct {
+	Volts float64
+	Amps  float64
+	Watts float64
+}
+
+// powerMetricField is a node_info field of a metric and the factor
+// converting it to volts, amps or watts
+type powerMetricField struct {
+	Name   string
+	Factor float64
+}
+
+// powerMetricFields lists the node_info fields of each metric in order of
+// preference. Firmware reports either base units or milli-units.
+var powerMetricFields = map[string][]powerMetricField{
+	"volts": {{"voltage", 1}, {"voltage_v", 1}, {"voltage_mv", 0.001}},
+	"amps":  {{"current", 1}, {"current_a", 1}, {"current_ma", 0.001}},
+	"watts": {{"power", 1}, {"power_w", 1}, {"power_mw", 0.001}},
+}
+
+func dataSourcePowerMetrics() *schema.Resource {
+	return &schema.Resource{
+		Description: "Retrieves the per-slot voltage, current and power draw reported by newer BMC firmware, " +
+			"for capacity planning and checks that a module is actually running.",
+		ReadContext: dataSourcePowerMetricsRead,
+		Schema: map[string]*schema.Schema{
+			"require_metrics": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Fail the read when the BMC firmware does not report power metrics for any slot.",
+			},
+			"idle_threshold_watts": {
+				Type:        schema.TypeFloat,
+				Optional:    true,
+				Default:     0.5,
+				Description: "Power draw in watts at or below which a powered-on slot is not considered active.",
+			},
+			// Computed attributes
+			"slots": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Power metrics of each slot, in node order",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Node number (1-4)",
+						},
+						"powered": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the node is powered on",
+						},
+						"available": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the BMC reported metrics for the slot",
+						},
+						"volts": {
+							Type:        schema.TypeFloat,
+							Computed:    true,
+							Description: "Supply voltage in volts",
+						},
+						"amps": {
+							Type:        schema.TypeFloat,
+							Computed:    true,
+							Description: "Current draw in amps",
+						},
+						"watts": {
+							Type:        schema.TypeFloat,
+							Computed:    true,
+							Description: "Power draw in watts, computed from volts and amps when not reported",
+						},
+						"active": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the node is powered on and draws more than idle_threshold_watts",
+						},
+					},
+				},
+			},
+			"watts": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Power draw in watts of the slots with metrics (node1-node4 -> watts)",
+				Elem:        &schema.Schema{Type: schema.TypeFloat},
+			},
+			"total_watts": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Sum of the power draw of all slots with metrics",
+			},
+			"metrics_available": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the BMC firmware reported power metrics for any slot",
+			},
+		},
+	}
+}
+
+func dataSourcePowerMetricsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	status, err := getPowerStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
+	}
+
+	// Older firmware has no node_info endpoint, so its slots have no metrics
+	var metrics map[int]nodePowerMetrics
+	if data, err := fetchBMCNodeInfo(config.Endpoint, config.Token); err == nil {
+		metrics = parseNodePowerMetrics(data)
+	}
+
+	if len(metrics) == 0 && d.Get("require_metrics").(bool) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "BMC does not report power metrics",
+			Detail:   "The BMC firmware reported no per-slot voltage or current. Upgrade the BMC firmware, or set require_metrics = false.",
+		}}
+	}
+
+	if err := setPowerMetrics(d, parsePowerStatus(status), metrics); err != nil {
+		return diagFromErr(err)
+	}
+
+	d.SetId("turingpi-power-metrics")
+	return nil
+}
+
+// setPowerMetrics sets the computed attributes from the power state and the
+// metrics of each slot
+func setPowerMetrics(d *schema.ResourceData, power map[string]bool, metrics map[int]nodePowerMetrics) error {
+	threshold := d.Get("idle_threshold_watts").(float64)
+
+	slots := make([]interface{}, 0, 4)
+	watts := make(map[string]interface{})
+	total := 0.0
+	for node := 1; node <= 4; node++ {
+		name := fmt.Sprintf("node%d", node)
+		m, available := metrics[node]
+		powered := power[name]
+		slots = append(slots, map[string]interface{}{
+			"node":      node,
+			"powered":   powered,
+			"available": available,
+			"volts":     m.Volts,
+			"amps":      m.Amps,
+			"watts":     m.Watts,
+			"active":    powered && available && m.Watts > threshold,
+		})
+		if available {
+			watts[name] = m.Watts
+			total += m.Watts
+		}
+	}
+
+	if err := d.Set("slots", slots); err != nil {
+		return fmt.Errorf("failed to set slots: %w", err)
+	}
+	if err := d.Set("watts", watts); err != nil {
+		return fmt.Errorf("failed to set watts: %w", err)
+	}
+	if err := d.Set("total_watts", roundMetric(total)); err != nil {
+		return fmt.Errorf("failed to set total_watts: %w", err)
+	}
+	if err := d.Set("metrics_available", len(metrics) > 0); err != nil {
+		return fmt.Errorf("failed to set metrics_available: %w", err)
+	}
+	return nil
+}
+
+// parseNodePowerMetrics extracts the voltage, current and power of each slot
+// from a node_info response, keyed by node number. Slots without any metric
+// are left out.
+func parseNodePowerMetrics(data *bmcNodeInfoResponse) map[int]nodePowerMetrics {
+	metrics := make(map[int]nodePowerMetrics)
+
+	var newFormat []map[string]interface{}
+	if err := json.Unmarshal(data.Response, &newFormat); err != nil {
+		return metrics
+	}
+	for _, item := range newFormat {
+		result, ok := item["result"].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, r := range result {
+			nodeMap, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			volts, hasVolts := powerMetricValue(nodeMap, "volts")
+			amps, hasAmps := powerMetricValue(nodeMap, "amps")
+			watts, hasWatts := powerMetricValue(nodeMap, "watts")
+			if !hasVolts && !hasAmps && !hasWatts {
+				continue
+			}
+			if !hasWatts {
+				watts = volts * amps
+			}
+			metrics[i+1] = nodePowerMetrics{
+				Volts: roundMetric(volts),
+				Amps:  roundMetric(amps),
+				Watts: roundMetric(watts),
+			}
+		}
+	}
+
+	return metrics
+}
+
+// powerMetricValue returns a metric of a node_info entry in base units,
+// accepting numbers and numeric strings
+func powerMetricValue(nodeMap map[string]interface{}, metric string) (float64, bool) {
+	for _, field := range powerMetricFields[metric] {
+		switch v := nodeMap[field.Name].(type) {
+		case float64:
+			return v * field.Factor, true
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f * field.Factor, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// roundMetric rounds a metric to milli-units, hiding float noise from unit
+// conversion and multiplication
+func roundMetric(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// newFakePowerMetricsBMC serves power and node_info responses, the latter
+// only when nodeInfo is set
+func newFakePowerMetricsBMC(t *testing.T, nodeInfo []map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var response interface{}
+		switch r.URL.Query().Get("type") {
+		case "power":
+			response = []map[string]interface{}{{
+				"result": []map[string]interface{}{{"node1": "1", "node2": "1", "node3": "1", "node4": "0"}},
+			}}
+		case "node_info":
+			if nodeInfo == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			response = []map[string]interface{}{{"result": nodeInfo}}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"response": response})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDataSourcePowerMetrics(t *testing.T) {
+	d := dataSourcePowerMetrics()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourcePowerMetricsRead(t *testing.T) {
+	server := newFakePowerMetricsBMC(t, []map[string]interface{}{
+		{"name": "cp", "voltage": 12.1, "current": 0.8},
+		{"name": "worker-1", "voltage_mv": "12000", "current_ma": "25", "power_mw": "300"},
+		{"name": "worker-2", "voltage": "12.0", "current": "1.25", "power": 15.2},
+		{"name": ""},
+	})
+
+	d := schema.TestResourceDataRaw(t, dataSourcePowerMetrics().Schema, map[string]interface{}{})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	if diags := dataSourcePowerMetricsRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if v := d.Get("slots.0.watts").(float64); v != 9.68 {
+		t.Errorf("expected node1 watts computed from volts and amps (9.68), got %v", v)
+	}
+	if !d.Get("slots.0.active").(bool) {
+		t.Error("expected node1 to be active")
+	}
+	if v := d.Get("slots.1.amps").(float64); v != 0.025 {
+		t.Errorf("expected node2 current converted from mA, got %v", v)
+	}
+	if d.Get("slots.1.active").(bool) {
+		t.Error("expected node2 drawing 0.3 W to be idle")
+	}
+	if v := d.Get("slots.2.watts").(float64); v != 15.2 {
+		t.Errorf("expected reported node3 watts to be used, got %v", v)
+	}
+	if d.Get("slots.3.available").(bool) || d.Get("slots.3.powered").(bool) {
+		t.Error("expected node4 to be off without metrics")
+	}
+	if v := d.Get("total_watts").(float64); v != 25.18 {
+		t.Errorf("expected total_watts 25.18, got %v", v)
+	}
+	if watts := d.Get("watts").(map[string]interface{}); len(watts) != 3 {
+		t.Errorf("expected watts for 3 slots, got %v", watts)
+	}
+	if !d.Get("metrics_available").(bool) {
+		t.Error("expected metrics_available")
+	}
+}
+
+func TestDataSourcePowerMetricsRead_Unsupported(t *testing.T) {
+	server := newFakePowerMetricsBMC(t, nil)
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	d := schema.TestResourceDataRaw(t, dataSourcePowerMetrics().Schema, map[string]interface{}{})
+	if diags := dataSourcePowerMetricsRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error on firmware without metrics: %v", diags)
+	}
+	if d.Get("metrics_available").(bool) || d.Get("slots.0.available").(bool) {
+		t.Error("expected no metrics")
+	}
+	if !d.Get("slots.0.powered").(bool) {
+		t.Error("expected power state without metrics")
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourcePowerMetrics().Schema, map[string]interface{}{"require_metrics": true})
+	if diags := dataSourcePowerMetricsRead(context.Background(), d, config); !diags.HasError() {
+		t.Error("expected error with require_metrics")
+	}
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourcePowerProfile() *schema.Resource {
+	return &schema.Resource{
+		Description: "Reports which power profile the board is currently in, by matching node power states " +
+			"against the built-in profiles (all-on, all-off) and any given profile blocks.",
+		ReadContext: dataSourcePowerProfileRead,
+		Schema: map[string]*schema.Schema{
+			"profile": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Custom power profiles to match against, checked in order before the built-in profiles.",
+				Elem:        powerProfileSchema(),
+			},
+			// Computed attributes
+			"active": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Name of the first matching profile, or empty if the board matches none",
+			},
+			"matching": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Names of all profiles matching the current power state",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"nodes": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Power status of all nodes as a map (node1-node4 -> bool)",
+				Elem: &schema.Schema{
+					Type: schema.TypeBool,
+				},
+			},
+		},
+	}
+}
+
+func dataSourcePowerProfileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	blocks := d.Get("profile").([]interface{})
+	profiles, err := expandPowerProfiles(blocks)
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	status, err := getPowerStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
+	}
+	nodeStatus := parsePowerStatus(status)
+
+	// Custom profiles first, in the order given, then built-ins
+	var order []string
+	for _, b := range blocks {
+		order = append(order, b.(map[string]interface{})["name"].(string))
+	}
+	order = append(order, "all-on", "all-off")
+
+	matching := []string{}
+	for _, name := range order {
+		if powerProfileMatches(profiles[name], nodeStatus) {
+			matching = append(matching, name)
+		}
+	}
+
+	active := ""
+	if len(matching) > 0 {
+		active = matching[0]
+	}
+
+	if err := d.Set("active", active); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set active: %w", err))
+	}
+	if err := d.Set("matching", matching); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set matching: %w", err))
+	}
+	if err := d.Set("nodes", nodeStatus); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set nodes: %w", err))
+	}
+
+	d.SetId("turingpi-power-profile")
+
+	return nil
+}
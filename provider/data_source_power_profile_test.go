@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDataSourcePowerProfile(t *testing.T) {
+	d := dataSourcePowerProfile()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourcePowerProfileRead(t *testing.T) {
+	server := newFakePowerBMC(t, map[string]int{"node1": 1, "node2": 1, "node3": 1, "node4": 0})
+
+	d := schema.TestResourceDataRaw(t, dataSourcePowerProfile().Schema, map[string]interface{}{
+		"profile": []interface{}{
+			map[string]interface{}{
+				"name":  "storage-only",
+				"nodes": map[string]interface{}{"node4": true},
+			},
+			map[string]interface{}{
+				"name":  "compute-only",
+				"nodes": map[string]interface{}{"node1": true, "node2": true, "node3": true, "node4": false},
+			},
+			map[string]interface{}{
+				"name":  "node1-up",
+				"nodes": map[string]interface{}{"node1": true},
+			},
+		},
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := dataSourcePowerProfileRead(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if v := d.Get("active").(string); v != "compute-only" {
+		t.Errorf("expected active 'compute-only', got %q", v)
+	}
+	matching := d.Get("matching").([]interface{})
+	if len(matching) != 2 || matching[1] != "node1-up" {
+		t.Errorf("unexpected matching profiles: %v", matching)
+	}
+}
+
+func TestDataSourcePowerProfileRead_NoMatch(t *testing.T) {
+	server := newFakePowerBMC(t, map[string]int{"node1": 1, "node2": 0, "node3": 0, "node4": 0})
+
+	d := schema.TestResourceDataRaw(t, dataSourcePowerProfile().Schema, map[string]interface{}{})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := dataSourcePowerProfileRead(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if v := d.Get("active").(string); v != "" {
+		t.Errorf("expected no active profile, got %q", v)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -66,6 +67,9 @@ func TestDataSourcePower_AllFieldsComputed(t *testing.T) {
 	d := dataSourcePower()
 
 	for name, s := range d.Schema {
+		if name == "require_all_on" || name == "require_nodes" {
+			continue
+		}
 		if !s.Computed {
 			t.Errorf("field %s should be computed", name)
 		}
@@ -476,3 +480,52 @@ func TestParsePowerStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestDataSourcePowerRead_RequiredNodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"response": [][]interface{}{
+				{"node1", float64(1)},
+				{"node2", float64(0)},
+				{"node3", float64(1)},
+				{"node4", float64(0)},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr string
+	}{
+		{"no requirement", map[string]interface{}{}, ""},
+		{"required nodes on", map[string]interface{}{"require_nodes": []interface{}{1, 3}}, ""},
+		{"required node off", map[string]interface{}{"require_nodes": []interface{}{1, 2}}, "Powered off: node2."},
+		{"all on", map[string]interface{}{"require_all_on": true}, "Powered off: node2, node4."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := schema.TestResourceDataRaw(t, dataSourcePower().Schema, tt.raw)
+
+			diags := dataSourcePowerRead(context.Background(), rd, config)
+			if tt.wantErr == "" {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				return
+			}
+			if !diags.HasError() {
+				t.Fatal("expected error for powered off nodes")
+			}
+			if !strings.Contains(diags[0].Detail, tt.wantErr) {
+				t.Errorf("expected detail containing %q, got %q", tt.wantErr, diags[0].Detail)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -72,23 +71,23 @@ func dataSourceSDCardRead(ctx context.Context, d *schema.ResourceData, meta inte
 
 	sdcard, err := fetchSDCardInfo(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to fetch SD card info: %w", err))
+		return diagFromErr(fmt.Errorf("failed to fetch SD card info: %w", err))
 	}
 
 	if len(sdcard.Response) == 0 {
-		return diag.FromErr(fmt.Errorf("no SD card information returned from API"))
+		return diagFromErr(fmt.Errorf("no SD card information returned from API"))
 	}
 
 	info := sdcard.Response[0]
 
 	if err := d.Set("total_bytes", info.Total); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set total_bytes: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set total_bytes: %w", err))
 	}
 	if err := d.Set("used_bytes", info.Use); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set used_bytes: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set used_bytes: %w", err))
 	}
 	if err := d.Set("free_bytes", info.Free); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set free_bytes: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set free_bytes: %w", err))
 	}
 
 	// Calculate GB values (bytes / 1024^3)
@@ -98,13 +97,13 @@ func dataSourceSDCardRead(ctx context.Context, d *schema.ResourceData, meta inte
 	freeGB := float64(info.Free) / bytesPerGB
 
 	if err := d.Set("total_gb", totalGB); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set total_gb: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set total_gb: %w", err))
 	}
 	if err := d.Set("used_gb", usedGB); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set used_gb: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set used_gb: %w", err))
 	}
 	if err := d.Set("free_gb", freeGB); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set free_gb: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set free_gb: %w", err))
 	}
 
 	// Calculate percentage used
@@ -113,7 +112,7 @@ func dataSourceSDCardRead(ctx context.Context, d *schema.ResourceData, meta inte
 		usedPercent = (float64(info.Use) / float64(info.Total)) * 100
 	}
 	if err := d.Set("used_percent", usedPercent); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set used_percent: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set used_percent: %w", err))
 	}
 
 	d.SetId("turingpi-sdcard")
@@ -142,8 +141,8 @@ func fetchSDCardInfo(endpoint, token string) (*sdcardResponse, error) {
 	}
 
 	var result sdcardResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/crypto/ssh"
+)
+
+func dataSourceSSHHostKeys() *schema.Resource {
+	return &schema.Resource{
+		Description: "Scans nodes for their SSH host keys, so the keys can be pinned with ssh_host_keys on " +
+			"turingpi_k3s_cluster nodes or written to a known_hosts file.",
+		ReadContext: dataSourceSSHHostKeysRead,
+		Schema: map[string]*schema.Schema{
+			"hosts": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "IP addresses or hostnames of the nodes to scan",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"port": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          22,
+				Description:      "SSH port of the nodes",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsPortNumber),
+			},
+			"timeout": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     10,
+				Description: "Connection timeout in seconds for each node",
+			},
+			// Computed attributes
+			"keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Host keys found, one per node and key type",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"host": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node the key was read from",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Key type (e.g., ssh-ed25519)",
+						},
+						"public_key": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Public key in authorized_keys format",
+						},
+						"fingerprint": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "SHA256 fingerprint, as shown by ssh-keygen -l",
+						},
+					},
+				},
+			},
+			"host_keys": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Host keys of each node in authorized_keys format, one per line",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"known_hosts": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "All host keys as OpenSSH known_hosts lines",
+			},
+		},
+	}
+}
+
+func dataSourceSSHHostKeysRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	port := d.Get("port").(int)
+	timeout := time.Duration(d.Get("timeout").(int)) * time.Second
+
+	var hosts []string
+	for _, h := range d.Get("hosts").([]interface{}) {
+		if s, ok := h.(string); ok && s != "" {
+			hosts = append(hosts, s)
+		}
+	}
+
+	scanned := make(map[string][]ssh.PublicKey, len(hosts))
+	for _, host := range hosts {
+		keys, err := scanSSHHostKeys(host, port, timeout)
+		if err != nil {
+			return diagFromErr(fmt.Errorf("failed to scan SSH host keys of %s: %w", host, err))
+		}
+		scanned[host] = keys
+	}
+
+	return setSSHHostKeys(d, hosts, port, scanned)
+}
+
+// setSSHHostKeys sets the computed attributes from the scanned keys of each host
+func setSSHHostKeys(d *schema.ResourceData, hosts []string, port int, scanned map[string][]ssh.PublicKey) diag.Diagnostics {
+	var keyList []interface{}
+	var knownHosts strings.Builder
+	hostKeys := make(map[string]interface{}, len(hosts))
+	for _, host := range hosts {
+		var lines []string
+		for _, key := range scanned[host] {
+			keyList = append(keyList, map[string]interface{}{
+				"host":        host,
+				"type":        key.Type(),
+				"public_key":  formatHostKey(key),
+				"fingerprint": ssh.FingerprintSHA256(key),
+			})
+			lines = append(lines, formatHostKey(key))
+			knownHosts.WriteString(knownHostsLine(host, port, key) + "\n")
+		}
+		hostKeys[host] = strings.Join(lines, "\n")
+	}
+
+	if err := d.Set("keys", keyList); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("host_keys", hostKeys); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("known_hosts", knownHosts.String()); err != nil {
+		return diagFromErr(err)
+	}
+
+	sorted := append([]string(nil), hosts...)
+	sort.Strings(sorted)
+	d.SetId(fmt.Sprintf("%s:%d", strings.Join(sorted, ","), port))
+	return nil
+}
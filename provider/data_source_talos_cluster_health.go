@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceTalosClusterHealth() *schema.Resource {
+	return &schema.Resource{
+		Description: "Reports etcd members, per-node service health and the Kubernetes version of an existing Talos cluster " +
+			"using talosctl, without managing it. Requires talosctl in PATH.",
+		ReadContext: dataSourceTalosClusterHealthRead,
+		Schema: map[string]*schema.Schema{
+			"talosconfig": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Talosconfig content used to authenticate to the cluster",
+			},
+			"node": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "IP address of the control plane node to query for etcd members and Kubernetes version",
+			},
+			"nodes": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "IP addresses of nodes to report service health for (defaults to node)",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			// Computed attributes
+			"kubernetes_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Kubernetes version run by the kubelet on node",
+			},
+			"etcd_members": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Members of the etcd cluster",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "etcd member ID",
+						},
+						"hostname": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Member hostname",
+						},
+						"peer_urls": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Peer URLs",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+						"client_urls": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Client URLs",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+						"learner": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the member is a non-voting learner",
+						},
+					},
+				},
+			},
+			"services": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Talos services on each queried node",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node the service runs on",
+						},
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Service ID (e.g., etcd, kubelet, apid)",
+						},
+						"state": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Service state (e.g., Running, Finished)",
+						},
+						"health": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Health check result (OK, Fail, or ? when the service has no health check)",
+						},
+					},
+				},
+			},
+			"healthy": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "True when etcd has members and no service is failing or stopped",
+			},
+		},
+	}
+}
+
+func dataSourceTalosClusterHealthRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if _, diags := checkBinary(talosctlBinary, ""); diags.HasError() {
+		return diags
+	}
+
+	provisioner, err := NewTalosProvisioner()
+	if err != nil {
+		return diagFromErr(err)
+	}
+	defer func() { _ = provisioner.Cleanup() }()
+
+	return readTalosClusterHealth(d, provisioner)
+}
+
+// readTalosClusterHealth queries the cluster through the given provisioner and sets the data source attributes
+func readTalosClusterHealth(d *schema.ResourceData, provisioner *TalosProvisioner) diag.Diagnostics {
+	node := d.Get("node").(string)
+	nodes := []string{node}
+	if v, ok := d.GetOk("nodes"); ok {
+		nodes = nil
+		for _, n := range v.([]interface{}) {
+			nodes = append(nodes, n.(string))
+		}
+	}
+
+	talosconfigPath := filepath.Join(provisioner.WorkDir(), "talosconfig")
+	if err := os.WriteFile(talosconfigPath, []byte(d.Get("talosconfig").(string)), 0600); err != nil {
+		return diagFromErr(fmt.Errorf("failed to write talosconfig: %w", err))
+	}
+
+	members, err := provisioner.GetEtcdMembers(talosconfigPath, node)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	memberList := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		memberList = append(memberList, map[string]interface{}{
+			"id":          m.ID,
+			"hostname":    m.Hostname,
+			"peer_urls":   m.PeerURLs,
+			"client_urls": m.ClientURLs,
+			"learner":     m.Learner,
+		})
+	}
+	if err := d.Set("etcd_members", memberList); err != nil {
+		return diagFromErr(err)
+	}
+
+	services, err := provisioner.GetServices(talosconfigPath, nodes)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	healthy := len(members) > 0
+	serviceList := make([]interface{}, 0, len(services))
+	for _, s := range services {
+		if s.Health == "Fail" || (s.State != "Running" && s.State != "Finished") {
+			healthy = false
+		}
+		serviceList = append(serviceList, map[string]interface{}{
+			"node":   s.Node,
+			"id":     s.ID,
+			"state":  s.State,
+			"health": s.Health,
+		})
+	}
+	if err := d.Set("services", serviceList); err != nil {
+		return diagFromErr(err)
+	}
+
+	version, err := provisioner.GetKubernetesVersion(talosconfigPath, node)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("kubernetes_version", version); err != nil {
+		return diagFromErr(err)
+	}
+
+	if err := d.Set("healthy", healthy); err != nil {
+		return diagFromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("talos-cluster-health-%s", node))
+	return nil
+}
@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const testEtcdMembersOutput = `NODE          ID                 HOSTNAME       PEER URLS                  CLIENT URLS                LEARNER
+10.10.88.73   2cb1cc5cc4d2f8ec   turing-cp-1    https://10.10.88.73:2380   https://10.10.88.73:2379   false
+10.10.88.73   8f1a3e7c9b2d4a60   turing-cp-2    https://10.10.88.74:2380   https://10.10.88.74:2379   true
+`
+
+const testTalosServicesOutput = `NODE          SERVICE      STATE      HEALTH   LAST CHANGE    LAST EVENT
+10.10.88.73   apid         Running    OK       1h3m11s ago    Health check successful
+10.10.88.73   etcd         Running    OK       1h2m50s ago    Health check successful
+10.10.88.73   machined     Running    ?        1h3m20s ago    Service started as goroutine
+10.10.88.75   kubelet      Running    Fail     2m1s ago       Health check failed: connection refused
+`
+
+const testKubeletSpecOutput = `node: 10.10.88.73
+metadata:
+    namespace: k8s
+    type: KubeletSpecs.kubernetes.talos.dev
+    id: kubelet
+spec:
+    image: ghcr.io/siderolabs/kubelet:v1.31.4
+`
+
+func TestDataSourceTalosClusterHealth(t *testing.T) {
+	d := dataSourceTalosClusterHealth()
+	if err := d.InternalValidate(nil, false); err != nil {
+		t.Fatalf("data source internal validation failed: %s", err)
+	}
+}
+
+func TestDataSourceTalosClusterHealth_SchemaTypes(t *testing.T) {
+	d := dataSourceTalosClusterHealth()
+
+	tests := []struct {
+		field    string
+		expected schema.ValueType
+	}{
+		{"talosconfig", schema.TypeString},
+		{"node", schema.TypeString},
+		{"nodes", schema.TypeList},
+		{"kubernetes_version", schema.TypeString},
+		{"etcd_members", schema.TypeList},
+		{"services", schema.TypeList},
+		{"healthy", schema.TypeBool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			s, ok := d.Schema[tt.field]
+			if !ok {
+				t.Fatalf("schema missing '%s' field", tt.field)
+			}
+			if s.Type != tt.expected {
+				t.Errorf("expected %s to be type %v, got %v", tt.field, tt.expected, s.Type)
+			}
+		})
+	}
+
+	if !d.Schema["talosconfig"].Sensitive {
+		t.Error("talosconfig should be sensitive")
+	}
+}
+
+func TestParseEtcdMembers(t *testing.T) {
+	members := parseEtcdMembers(testEtcdMembersOutput)
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(members))
+	}
+	if members[0].ID != "2cb1cc5cc4d2f8ec" || members[0].Hostname != "turing-cp-1" || members[0].Learner {
+		t.Errorf("unexpected first member: %+v", members[0])
+	}
+	if members[1].PeerURLs[0] != "https://10.10.88.74:2380" || !members[1].Learner {
+		t.Errorf("unexpected second member: %+v", members[1])
+	}
+}
+
+func TestParseTalosServices(t *testing.T) {
+	services := parseTalosServices(testTalosServicesOutput)
+	if len(services) != 4 {
+		t.Fatalf("expected 4 services, got %d", len(services))
+	}
+	last := services[3]
+	if last.Node != "10.10.88.75" || last.ID != "kubelet" || last.State != "Running" || last.Health != "Fail" {
+		t.Errorf("unexpected service: %+v", last)
+	}
+}
+
+func TestParseKubeletVersion(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{testKubeletSpecOutput, "v1.31.4"},
+		{"spec:\n    image: registry.local:5000/kubelet:v1.30.2@sha256:abcd\n", "v1.30.2"},
+		{"spec:\n    image: registry.local:5000/kubelet\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseKubeletVersion(tt.output); got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestReadTalosClusterHealth(t *testing.T) {
+	var calls []string
+	mockExec := func(name string, args ...string) *exec.Cmd {
+		joined := strings.Join(args, " ")
+		calls = append(calls, joined)
+		switch {
+		case strings.Contains(joined, "etcd members"):
+			return exec.Command("printf", "%s", testEtcdMembersOutput)
+		case strings.Contains(joined, "service"):
+			return exec.Command("printf", "%s", testTalosServicesOutput)
+		case strings.Contains(joined, "kubeletspec"):
+			return exec.Command("printf", "%s", testKubeletSpecOutput)
+		}
+		return exec.Command("false")
+	}
+	provisioner := NewTalosProvisionerWithExec(mockExec)
+	defer func() { _ = provisioner.Cleanup() }()
+
+	d := schema.TestResourceDataRaw(t, dataSourceTalosClusterHealth().Schema, map[string]interface{}{
+		"talosconfig": "context: turing",
+		"node":        "10.10.88.73",
+		"nodes":       []interface{}{"10.10.88.73", "10.10.88.75"},
+	})
+
+	diags := readTalosClusterHealth(d, provisioner)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if d.Id() != "talos-cluster-health-10.10.88.73" {
+		t.Errorf("unexpected ID: %s", d.Id())
+	}
+	if v := d.Get("kubernetes_version").(string); v != "v1.31.4" {
+		t.Errorf("expected kubernetes_version 'v1.31.4', got %q", v)
+	}
+	if v := d.Get("etcd_members.#").(int); v != 2 {
+		t.Errorf("expected 2 etcd members, got %d", v)
+	}
+	if v := d.Get("services.#").(int); v != 4 {
+		t.Errorf("expected 4 services, got %d", v)
+	}
+	// kubelet on 10.10.88.75 is failing its health check
+	if d.Get("healthy").(bool) {
+		t.Error("expected healthy to be false with a failing service")
+	}
+
+	found := false
+	for _, c := range calls {
+		if strings.Contains(c, "service --nodes 10.10.88.73,10.10.88.75") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected services to be queried on all nodes, calls: %v", calls)
+	}
+}
+
+func TestReadTalosClusterHealth_EtcdError(t *testing.T) {
+	mockExec := func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo 'connection refused' >&2; exit 1")
+	}
+	provisioner := NewTalosProvisionerWithExec(mockExec)
+	defer func() { _ = provisioner.Cleanup() }()
+
+	d := schema.TestResourceDataRaw(t, dataSourceTalosClusterHealth().Schema, map[string]interface{}{
+		"talosconfig": "context: turing",
+		"node":        "10.10.88.73",
+	})
+
+	diags := readTalosClusterHealth(d, provisioner)
+	if !diags.HasError() {
+		t.Fatal("expected error when talosctl fails")
+	}
+}
@@ -2,7 +2,6 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -59,15 +58,15 @@ func dataSourceUARTRead(ctx context.Context, d *schema.ResourceData, meta interf
 
 	output, err := readUART(config.Endpoint, config.Token, node, encoding)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to read UART: %w", err))
+		return diagFromErr(fmt.Errorf("failed to read UART: %w", err))
 	}
 
 	if err := d.Set("output", output); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set output: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set output: %w", err))
 	}
 
 	if err := d.Set("has_output", len(output) > 0); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set has_output: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set has_output: %w", err))
 	}
 
 	d.SetId(fmt.Sprintf("uart-node-%d", node))
@@ -99,8 +98,8 @@ func readUART(endpoint, token string, node int, encoding string) (string, error)
 	}
 
 	var result uartReadResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return "", err
 	}
 
 	return extractUARTOutput(result), nil
@@ -10,7 +10,7 @@ import (
 
 func dataSourceUSB() *schema.Resource {
 	return &schema.Resource{
-		Description: "Retrieves the current USB routing configuration from the Turing Pi BMC.",
+		Description: "Retrieves the current USB routing configuration from the Turing Pi BMC, with the power state of the routed node.",
 		ReadContext: dataSourceUSBRead,
 		Schema: map[string]*schema.Schema{
 			"mode": {
@@ -28,6 +28,11 @@ func dataSourceUSB() *schema.Resource {
 				Computed:    true,
 				Description: "Current USB routing destination: 'usb-a' (external connector) or 'bmc' (BMC chip)",
 			},
+			"node_powered": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the node USB is routed to is powered on. USB routed to a powered-off node is usually a misconfiguration.",
+			},
 		},
 	}
 }
@@ -39,20 +44,28 @@ func dataSourceUSBRead(ctx context.Context, d *schema.ResourceData, meta interfa
 	// Fetch current USB status using the function from resource_usb.go
 	status, err := getUSBStatus(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to read USB status: %w", err))
+		return diagFromErr(fmt.Errorf("failed to read USB status: %w", err))
 	}
 
 	// Parse the response using the function from resource_usb.go
 	mode, node, route := parseUSBStatus(status)
 
 	if err := d.Set("mode", mode); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set mode: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set mode: %w", err))
 	}
 	if err := d.Set("node", node); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set node: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set node: %w", err))
 	}
 	if err := d.Set("route", route); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set route: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set route: %w", err))
+	}
+
+	powerStatus, err := getPowerStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
+	}
+	if err := d.Set("node_powered", parsePowerStatus(powerStatus)[fmt.Sprintf("node%d", node)]); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set node_powered: %w", err))
 	}
 
 	// Set a stable ID for the data source
@@ -24,6 +24,7 @@ func TestDataSourceUSB_Schema(t *testing.T) {
 		"mode",
 		"node",
 		"route",
+		"node_powered",
 	}
 
 	for _, field := range expectedFields {
@@ -43,6 +44,7 @@ func TestDataSourceUSB_SchemaTypes(t *testing.T) {
 		{"mode", schema.TypeString},
 		{"node", schema.TypeInt},
 		{"route", schema.TypeString},
+		{"node_powered", schema.TypeBool},
 	}
 
 	for _, tt := range tests {
@@ -254,3 +256,50 @@ func TestDataSourceUSBRead_AuthHeader(t *testing.T) {
 		t.Errorf("expected Authorization 'Bearer my-secret-token', got '%s'", capturedAuth)
 	}
 }
+
+func TestDataSourceUSBRead_RoutedNodePoweredOff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("type") {
+		case "usb":
+			_, _ = w.Write([]byte(`{"response":[["mode","Device"],["node",1],["route","BMC"]]}`))
+		case "power":
+			_, _ = w.Write([]byte(`{"response":[{"result":[{"node1":1,"node2":0,"node3":1,"node4":1}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	rd := dataSourceUSB().TestResourceData()
+	diags := dataSourceUSBRead(context.Background(), rd, &ProviderConfig{Token: "test-token", Endpoint: server.URL})
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if v := rd.Get("node_powered").(bool); v {
+		t.Error("expected the routed node to be powered off")
+	}
+}
+
+func TestDataSourceUSBRead_RoutedNodePowered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("type") {
+		case "usb":
+			_, _ = w.Write([]byte(`{"response":[["mode","Host"],["node",2],["route","USB-A"]]}`))
+		case "power":
+			_, _ = w.Write([]byte(`{"response":[{"result":[{"node1":0,"node2":0,"node3":1,"node4":0}]}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	rd := dataSourceUSB().TestResourceData()
+	diags := dataSourceUSBRead(context.Background(), rd, &ProviderConfig{Token: "test-token", Endpoint: server.URL})
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if v := rd.Get("node_powered").(bool); !v {
+		t.Error("expected node 3 to be powered")
+	}
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultMetadata returns the provider default_metadata, or nil when the
+// provider is not configured (e.g. in unit tests)
+func defaultMetadata(meta interface{}) map[string]string {
+	config, ok := meta.(*ProviderConfig)
+	if !ok {
+		return nil
+	}
+	return config.DefaultMetadata
+}
+
+// validateDefaultMetadata checks that default_metadata is made of valid
+// Kubernetes labels, since it becomes the node labels of cluster resources
+func validateDefaultMetadata(v interface{}, k string) ([]string, []error) {
+	var errs []error
+	for key, value := range v.(map[string]interface{}) {
+		if msgs := k8svalidation.IsQualifiedName(key); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("%s: key %q is not a valid label name: %s", k, key, strings.Join(msgs, "; ")))
+		}
+		if msgs := k8svalidation.IsValidLabelValue(value.(string)); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("%s: value %q of %s is not a valid label value: %s", k, value, key, strings.Join(msgs, "; ")))
+		}
+	}
+	return nil, errs
+}
+
+// mergeDefaultMetadata returns defaults overlaid with values. Keys set on the
+// resource win over the provider defaults.
+func mergeDefaultMetadata(defaults, values map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(values))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
+
+// customizeDiffDefaultTriggers plans triggers as the configured triggers merged
+// with the provider default_metadata. triggers must be Optional and Computed,
+// and is read from the raw config so removed keys do not linger in state.
+func customizeDiffDefaultTriggers(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	configured := map[string]string{}
+	raw := d.GetRawConfig()
+	if raw.IsNull() {
+		// No raw config, e.g. when the SDK re-plans during apply: the planned
+		// triggers are already merged
+		configured = expandStringMap(d.Get("triggers").(map[string]interface{}))
+	} else {
+		triggers := raw.GetAttr("triggers")
+		if !triggers.IsWhollyKnown() {
+			return d.SetNewComputed("triggers")
+		}
+		if !triggers.IsNull() {
+			for k, v := range triggers.AsValueMap() {
+				if !v.IsNull() {
+					configured[k] = v.AsString()
+				}
+			}
+		}
+	}
+
+	merged := mergeDefaultMetadata(defaultMetadata(meta), configured)
+	value := make(map[string]interface{}, len(merged))
+	for k, v := range merged {
+		value[k] = v
+	}
+	return d.SetNew("triggers", value)
+}
+
+// triggersChanged reports whether a trigger set on the resource changed.
+// Keys merged in from default_metadata are not compared, so editing the
+// fleet-wide metadata does not re-run the action of every resource.
+func triggersChanged(d *schema.ResourceData) bool {
+	if !d.HasChange("triggers") {
+		return false
+	}
+	raw := d.GetRawConfig()
+	if raw.IsNull() {
+		return true
+	}
+	configured := map[string]string{}
+	if triggers := raw.GetAttr("triggers"); !triggers.IsNull() {
+		for k, v := range triggers.AsValueMap() {
+			if !v.IsNull() {
+				configured[k] = v.AsString()
+			}
+		}
+	}
+	old, _ := d.GetChange("triggers")
+	return configuredTriggersChanged(old.(map[string]interface{}), configured)
+}
+
+// configuredTriggersChanged reports whether a configured trigger differs from
+// the prior triggers. Removing a trigger does not count, as it cannot be told
+// from a key removed from default_metadata.
+func configuredTriggersChanged(prior map[string]interface{}, configured map[string]string) bool {
+	for k, v := range configured {
+		if p, ok := prior[k].(string); !ok || p != v {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeLabelFlags returns K3s installer flags for labels, sorted by key so the
+// install command is stable. Labels are quoted for the install command line.
+func nodeLabelFlags(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	flags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		flags = append(flags, shellQuote(fmt.Sprintf("--node-label=%s=%s", k, labels[k])))
+	}
+	return flags
+}
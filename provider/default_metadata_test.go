@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeDefaultMetadata(t *testing.T) {
+	merged := mergeDefaultMetadata(
+		map[string]string{"site": "lab", "owner": "platform"},
+		map[string]string{"owner": "storage", "version": "2.3.4"},
+	)
+	expected := map[string]string{"site": "lab", "owner": "storage", "version": "2.3.4"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %v, got %v", expected, merged)
+	}
+
+	if merged := mergeDefaultMetadata(nil, nil); len(merged) != 0 {
+		t.Errorf("expected empty map, got %v", merged)
+	}
+}
+
+func TestNodeLabelFlags(t *testing.T) {
+	flags := nodeLabelFlags(map[string]string{"site": "lab", "rack": "r1"})
+	expected := []string{"--node-label=rack=r1", "--node-label=site=lab"}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("expected %v, got %v", expected, flags)
+	}
+	if flags := nodeLabelFlags(nil); len(flags) != 0 {
+		t.Errorf("expected no flags, got %v", flags)
+	}
+}
+
+func TestNodeLabelFlags_Quoted(t *testing.T) {
+	flags := nodeLabelFlags(map[string]string{"owner": "a b;reboot"})
+	if len(flags) != 1 || flags[0] != `'--node-label=owner=a b;reboot'` {
+		t.Errorf("expected the label to be quoted, got %v", flags)
+	}
+}
+
+func TestValidateDefaultMetadata(t *testing.T) {
+	valid := map[string]interface{}{"site": "lab", "example.com/rack": "r1", "owner": ""}
+	if _, errs := validateDefaultMetadata(valid, "default_metadata"); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	for _, invalid := range []map[string]interface{}{
+		{"owner": "platform team"},
+		{"owner; reboot": "x"},
+		{"site": "$(id)"},
+	} {
+		if _, errs := validateDefaultMetadata(invalid, "default_metadata"); len(errs) == 0 {
+			t.Errorf("expected %v to be rejected", invalid)
+		}
+	}
+}
+
+func TestConfiguredTriggersChanged(t *testing.T) {
+	prior := map[string]interface{}{"version": "2.3.4", "site": "lab"}
+	tests := []struct {
+		name       string
+		configured map[string]string
+		want       bool
+	}{
+		{"only default_metadata changed", map[string]string{"version": "2.3.4"}, false},
+		{"trigger changed", map[string]string{"version": "2.3.5"}, true},
+		{"trigger added", map[string]string{"version": "2.3.4", "run": "1"}, true},
+		{"trigger overrides metadata", map[string]string{"version": "2.3.4", "site": "dc"}, true},
+	}
+	for _, tt := range tests {
+		if got := configuredTriggersChanged(prior, tt.configured); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCustomizeDiffDefaultTriggers(t *testing.T) {
+	config := &ProviderConfig{DefaultMetadata: map[string]string{"site": "lab", "owner": "platform"}}
+
+	resources := map[string]map[string]interface{}{
+		"turingpi_bmc_firmware":   {"firmware_file": "/tmp/fw.swu"},
+		"turingpi_uart":           {"node": 1, "command": "ls\n"},
+		"turingpi_usb_boot":       {"node": 1},
+		"turingpi_clear_usb_boot": {"node": 1},
+		"turingpi_node_to_msd":    {"node": 1},
+	}
+	for name, raw := range resources {
+		t.Run(name, func(t *testing.T) {
+			r := Provider().ResourcesMap[name]
+			raw["triggers"] = map[string]interface{}{"owner": "storage"}
+
+			diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(raw), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, want := range map[string]string{"triggers.site": "lab", "triggers.owner": "storage"} {
+				attr, ok := diff.Attributes[key]
+				if !ok || attr.New != want {
+					t.Errorf("expected %s = %q, got %+v", key, want, attr)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomizeDiffDefaultTriggers_NoDefaults(t *testing.T) {
+	r := resourceUSBBoot()
+	raw := map[string]interface{}{"node": 2, "triggers": map[string]interface{}{"run": "1"}}
+
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(raw), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff.Attributes["triggers.run"] == nil || diff.Attributes["triggers.run"].New != "1" {
+		t.Errorf("expected configured trigger to be kept, got %+v", diff.Attributes)
+	}
+	if diff.Attributes["triggers.%"] == nil || diff.Attributes["triggers.%"].New != "1" {
+		t.Errorf("expected one trigger, got %+v", diff.Attributes["triggers.%"])
+	}
+}
+
+func TestK3sProvisioner_NodeLabels(t *testing.T) {
+	var installCmd string
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s ") {
+					return "not_installed", nil
+				}
+				if strings.Contains(cmd, "/tmp/k3s-install.sh agent") {
+					installCmd = cmd
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	provisioner.NodeLabels = map[string]string{"site": "lab", "rack": "r1"}
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(installCmd, "/tmp/k3s-install.sh agent --node-label=rack=r1 --node-label=site=lab") {
+		t.Errorf("expected node labels in install command, got %q", installCmd)
+	}
+}
+
+func TestGenerateMachineSettingsPatchYAML_NodeLabels(t *testing.T) {
+	patch, err := generateMachineSettingsPatchYAML(TalosClusterConfig{
+		NodeLabels: map[string]string{"site": "lab"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Machine struct {
+			NodeLabels map[string]string `yaml:"nodeLabels"`
+		} `yaml:"machine"`
+	}
+	if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+		t.Fatalf("invalid patch YAML: %v\n%s", err, patch)
+	}
+	if parsed.Machine.NodeLabels["site"] != "lab" {
+		t.Errorf("expected site node label, got:\n%s", patch)
+	}
+}
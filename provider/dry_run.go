@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// base64Payload matches the encoded file contents of the write commands, which
+// may hold tokens and registry credentials
+var base64Payload = regexp.MustCompile(`echo '[A-Za-z0-9+/=]{16,}' \| base64 -d`)
+
+// redactCommand replaces the base64 file payloads and the secrets in a
+// command line
+func redactCommand(line string, secrets []string) string {
+	line = base64Payload.ReplaceAllString(line, "echo '<redacted>' | base64 -d")
+	for _, s := range secrets {
+		line = strings.ReplaceAll(line, s, "<redacted>")
+	}
+	return line
+}
+
+// dryRunRecorder collects the commands a provisioner would run when the
+// provider is configured with dry_run. Provisioners with a recorder run
+// nothing; checks get canned answers and waits return at once.
+type dryRunRecorder struct {
+	mu       sync.Mutex
+	lines    []string
+	commands int
+	secrets  []string
+	aliases  map[string]string
+	// assumeInstalled answers the "is K3s installed" checks. Create assumes
+	// fresh nodes, Delete assumes installed ones.
+	assumeInstalled bool
+}
+
+// newDryRunRecorder returns a recorder when the provider runs with dry_run,
+// or nil. secrets are replaced in the recorded commands.
+func newDryRunRecorder(meta interface{}, secrets ...string) *dryRunRecorder {
+	config, ok := meta.(*ProviderConfig)
+	if !ok || !config.DryRun {
+		return nil
+	}
+	r := &dryRunRecorder{aliases: make(map[string]string)}
+	for _, s := range secrets {
+		if s != "" {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	return r
+}
+
+// alias shows path as name in the script, e.g. a temporary work directory
+func (r *dryRunRecorder) alias(path, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aliases[path] = name
+}
+
+// command records a command line
+func (r *dryRunRecorder) command(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	line = redactCommand(line, r.secrets)
+	for path, name := range r.aliases {
+		line = strings.ReplaceAll(line, path, name)
+	}
+	log.Printf("[INFO] dry_run: %s", line)
+	r.lines = append(r.lines, line)
+	r.commands++
+}
+
+// comment records a step that is not a command, e.g. a wait
+func (r *dryRunRecorder) comment(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, "# "+fmt.Sprintf(format, args...))
+}
+
+// ssh records cmd as an ssh invocation and returns its canned output
+func (r *dryRunRecorder) ssh(node NodeConfig, cmd string) string {
+	port := node.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	r.command(fmt.Sprintf("ssh -p %d %s@%s %s", port, node.SSHUser, node.Host, shellQuote(cmd)))
+	return r.sshOutput(cmd)
+}
+
+// sshOutput answers the "test -f path && echo 'yes' || echo 'not_yes'" checks
+// according to assumeInstalled; other commands have no output
+func (r *dryRunRecorder) sshOutput(cmd string) string {
+	if !strings.HasPrefix(cmd, "test -f ") {
+		return ""
+	}
+	_, answers, ok := strings.Cut(cmd, "&& echo '")
+	if !ok {
+		return ""
+	}
+	yes, _, _ := strings.Cut(answers, "'")
+	if r.assumeInstalled {
+		return yes
+	}
+	return "not_" + yes
+}
+
+// script renders the recorded commands as a shell script
+func (r *dryRunRecorder) script(title string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# %s (dry_run: %d commands, none were run)\n", title, r.commands)
+	for _, line := range r.lines {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// diagnostics returns the error that ends a dry run, with the script as
+// detail. It is an error so Terraform does not record the change as applied.
+func (r *dryRunRecorder) diagnostics(ctx context.Context, title string) diag.Diagnostics {
+	script := r.script(title)
+	tflog.Info(ctx, "dry_run script", map[string]interface{}{"script": script})
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("dry_run: %s was not applied", title),
+		Detail:   "The provider is configured with dry_run, so no commands were run. These are the commands it would run:\n\n" + script,
+	}}
+}
+
+// newDryRunTalosProvisioner returns a provisioner that records talosctl
+// commands instead of running them, so talosctl does not need to be installed
+func newDryRunTalosProvisioner(rec *dryRunRecorder) (*TalosProvisioner, error) {
+	workDir, err := os.MkdirTemp("", "talos-provisioner-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	rec.alias(workDir, "$WORKDIR")
+	rec.command(`WORKDIR="$(mktemp -d)"`)
+	return &TalosProvisioner{
+		talosctlPath: "talosctl",
+		workDir:      workDir,
+		DryRun:       rec,
+	}, nil
+}
+
+// newTalosClusterProvisioner returns the dry run provisioner when rec is set,
+// and the talosctl one otherwise
+func newTalosClusterProvisioner(rec *dryRunRecorder) (*TalosProvisioner, error) {
+	if rec != nil {
+		return newDryRunTalosProvisioner(rec)
+	}
+	return NewTalosProvisioner()
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
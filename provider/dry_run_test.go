@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+var dryRunConfig = &ProviderConfig{Endpoint: "https://turingpi.local", DryRun: true}
+
+func TestNewDryRunRecorder_OnlyWithDryRun(t *testing.T) {
+	if newDryRunRecorder(nil) != nil {
+		t.Error("expected no recorder without provider config")
+	}
+	if newDryRunRecorder(&ProviderConfig{}) != nil {
+		t.Error("expected no recorder when dry_run is unset")
+	}
+	if newDryRunRecorder(dryRunConfig) == nil {
+		t.Error("expected a recorder when dry_run is set")
+	}
+}
+
+func TestDryRunRecorder_RedactsSecrets(t *testing.T) {
+	rec := newDryRunRecorder(dryRunConfig, "s3cret-token", "")
+	rec.command("K3S_TOKEN=s3cret-token /tmp/k3s-install.sh server")
+	rec.command("echo 'dG9rZW46IHMzY3JldC10b2tlbgo=' | base64 -d > /etc/rancher/k3s/config.yaml")
+
+	script := rec.script("test")
+	if strings.Contains(script, "s3cret-token") || strings.Contains(script, "dG9rZW46") {
+		t.Errorf("expected secrets to be redacted:\n%s", script)
+	}
+	if !strings.Contains(script, "K3S_TOKEN=<redacted>") || !strings.Contains(script, "echo '<redacted>' | base64 -d") {
+		t.Errorf("expected redaction markers:\n%s", script)
+	}
+	if !strings.Contains(script, "dry_run: 2 commands") {
+		t.Errorf("expected the command count:\n%s", script)
+	}
+}
+
+func TestDryRunRecorder_SSHOutput(t *testing.T) {
+	rec := newDryRunRecorder(dryRunConfig)
+	check := "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'"
+
+	if got := rec.sshOutput(check); got != "not_installed" {
+		t.Errorf("expected fresh nodes by default, got %q", got)
+	}
+	rec.assumeInstalled = true
+	if got := rec.sshOutput(check); got != "installed" {
+		t.Errorf("expected installed nodes, got %q", got)
+	}
+	if got := rec.sshOutput("swapoff -a"); got != "" {
+		t.Errorf("expected no output, got %q", got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"swapoff":      "swapoff",
+		"swapoff -a":   "'swapoff -a'",
+		"echo 'a'":     `'echo '\''a'\'''`,
+		"":             "''",
+		"10.10.88.73":  "10.10.88.73",
+		"@/tmp/p.yaml": "@/tmp/p.yaml",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func k3sDryRunData(t *testing.T) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":          "test",
+		"cluster_token": "s3cret-token",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host":     "10.10.88.73",
+			"ssh_user": "root",
+			"ssh_key":  "key",
+		}},
+		"worker": []interface{}{map[string]interface{}{
+			"host":     "10.10.88.74",
+			"ssh_user": "root",
+			"ssh_key":  "key",
+		}},
+	})
+}
+
+func TestResourceK3sClusterCreate_DryRun(t *testing.T) {
+	d := k3sDryRunData(t)
+
+	diags := resourceK3sClusterCreate(context.Background(), d, dryRunConfig)
+	if !diags.HasError() {
+		t.Fatal("expected the dry run to end with an error")
+	}
+	if d.Id() != "" {
+		t.Errorf("expected no ID after a dry run, got %q", d.Id())
+	}
+
+	script := diags[0].Detail
+	for _, want := range []string{
+		"ssh -p 22 root@10.10.88.73 'swapoff -a'",
+		"/tmp/k3s-install.sh server",
+		"ssh -p 22 root@10.10.88.74",
+		"K3S_URL=https://10.10.88.73:6443 K3S_TOKEN=<node-token>",
+		"# wait up to",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected script to contain %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "s3cret-token") {
+		t.Errorf("expected the cluster token to be redacted:\n%s", script)
+	}
+}
+
+func TestResourceK3sClusterDelete_DryRun(t *testing.T) {
+	d := k3sDryRunData(t)
+	d.SetId("test")
+
+	diags := resourceK3sClusterDelete(context.Background(), d, dryRunConfig)
+	if !diags.HasError() {
+		t.Fatal("expected the dry run to end with an error")
+	}
+	if d.Id() != "test" {
+		t.Error("expected the cluster to stay in state")
+	}
+	script := diags[len(diags)-1].Detail
+	for _, want := range []string{"/usr/local/bin/k3s-agent-uninstall.sh", "/usr/local/bin/k3s-uninstall.sh"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected script to contain %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestResourceK3sClusterUpdate_DryRun(t *testing.T) {
+	d := k3sDryRunData(t)
+	d.SetId("test")
+
+	diags := resourceK3sClusterUpdate(context.Background(), d, dryRunConfig)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "dry_run") {
+		t.Fatalf("expected dry_run error, got %v", diags)
+	}
+}
+
+func TestResourceTalosClusterCreate_DryRun(t *testing.T) {
+	// talosctl is not needed for a dry run
+	oldLookPath := lookPath
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	defer func() { lookPath = oldLookPath }()
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"worker":           []interface{}{map[string]interface{}{"host": "10.10.88.74"}},
+	})
+
+	diags := resourceTalosClusterCreate(context.Background(), d, dryRunConfig)
+	if !diags.HasError() {
+		t.Fatal("expected the dry run to end with an error")
+	}
+	if d.Id() != "" {
+		t.Errorf("expected no ID after a dry run, got %q", d.Id())
+	}
+
+	script := diags[0].Detail
+	for _, want := range []string{
+		`WORKDIR="$(mktemp -d)"`,
+		"talosctl gen secrets -o $WORKDIR/secrets.yaml",
+		"talosctl apply-config --nodes 10.10.88.73 --file $WORKDIR/controlplane-1.yaml --insecure",
+		"talosctl --talosconfig $WORKDIR/configs/talosconfig bootstrap --nodes 10.10.88.73",
+		"talosctl apply-config --nodes 10.10.88.74 --file $WORKDIR/worker-1.yaml --insecure",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected script to contain %q:\n%s", want, script)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// endpointProbeTimeout bounds the reachability check of the BMC endpoint
+const endpointProbeTimeout = 10 * time.Second
+
+// maxEndpointRedirects caps how many redirects the reachability check follows
+const maxEndpointRedirects = 3
+
+// normalizeEndpoint turns the endpoint setting into the base URL of the BMC
+// API: a bare host or IP gets https://, the default port of the scheme is
+// dropped, and a trailing slash or path is removed, since the API is always
+// served from the root.
+func normalizeEndpoint(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("endpoint is empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", raw, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("invalid endpoint %q: scheme must be http or https", raw)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid endpoint %q: missing host", raw)
+	}
+	if u.Path != "" && u.Path != "/" {
+		log.Printf("[WARN] Ignoring path %q of endpoint %q; the BMC API is served from the root", u.Path, raw)
+	}
+
+	host := u.Host
+	if port := u.Port(); (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		host = u.Hostname()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+	}
+	return scheme + "://" + host, nil
+}
+
+// endpointBase returns the scheme and host of u as an endpoint
+func endpointBase(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// probeEndpoint checks that the BMC API answers at endpoint before the
+// provider authenticates, and returns the endpoint to use. Redirects to
+// another scheme or port of the same host, such as http:// to https://, are
+// followed and the final endpoint is returned, since the authentication POST
+// would not survive them. Redirects to another host, or from https:// to
+// http://, are errors: the credentials would follow them. Failures name the
+// step that failed: DNS lookup, TCP connect or TLS handshake.
+func probeEndpoint(endpoint string) (string, error) {
+	client := &http.Client{
+		Timeout:   endpointProbeTimeout,
+		Transport: HTTPClient.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for i := 0; ; i++ {
+		resp, err := client.Get(endpoint + "/api/bmc?opt=get&type=about")
+		if err != nil {
+			return "", explainProbeError(endpoint, err)
+		}
+		_ = resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			// Any other answer, including 401, means the BMC is reachable;
+			// credentials are checked by authentication
+			return endpoint, nil
+		}
+		if i == maxEndpointRedirects {
+			return "", fmt.Errorf("BMC endpoint %s redirects more than %d times; set endpoint to the final URL", endpoint, maxEndpointRedirects)
+		}
+
+		target, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return "", fmt.Errorf("BMC endpoint %s redirects to invalid location %q: %w", endpoint, location, err)
+		}
+		current := resp.Request.URL
+		if !strings.EqualFold(target.Hostname(), current.Hostname()) {
+			return "", fmt.Errorf("BMC endpoint %s redirects to another host, %s; credentials are only sent to the configured host. Set endpoint to the BMC's own address", endpoint, target.Redacted())
+		}
+		if current.Scheme == "https" && target.Scheme != "https" {
+			return "", fmt.Errorf("BMC endpoint %s redirects from https to %s; credentials are not sent unencrypted. Set endpoint to the BMC's https address", endpoint, target.Redacted())
+		}
+		next := endpointBase(target)
+		if next == endpoint {
+			// A redirect within the same host, e.g. to a login page, is not
+			// the API; leave it to authentication to report
+			return endpoint, nil
+		}
+		log.Printf("[WARN] BMC endpoint %s redirects to %s; using %s. Set endpoint = %q to skip the redirect.", endpoint, target.Redacted(), next, next)
+		endpoint = next
+	}
+}
+
+// explainProbeError describes which step of reaching the BMC failed
+func explainProbeError(endpoint string, err error) error {
+	u, _ := url.Parse(endpoint)
+	host := endpoint
+	if u != nil {
+		host = u.Host
+	}
+
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("cannot reach the BMC at %s: DNS lookup of %q failed: %w. Check the host name, or use the BMC's IP address", endpoint, dnsErr.Name, err)
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return fmt.Errorf("cannot reach the BMC at %s: TLS certificate verification failed: %w. The BMC ships a self-signed certificate; set insecure = true or trust its certificate", endpoint, err)
+	case errors.As(err, &recordErr), strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return fmt.Errorf("cannot reach the BMC at %s: TLS handshake failed, %s does not speak HTTPS: %w. Use an http:// endpoint or the BMC's HTTPS port", endpoint, host, err)
+	case strings.Contains(err.Error(), "tls:"):
+		return fmt.Errorf("cannot reach the BMC at %s: TLS handshake failed: %w", endpoint, err)
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return fmt.Errorf("cannot reach the BMC at %s: TCP connection to %s failed: %w. Check that the BMC is powered and on this network, and the port", endpoint, host, err)
+	}
+	return fmt.Errorf("cannot reach the BMC at %s: %w", endpoint, err)
+}
+
+// logBMCVersion logs the firmware version of the BMC after authentication,
+// so debug logs show which firmware a run talked to, and returns the
+// firmware and API versions. Failures only log, and return empty versions.
+func logBMCVersion(endpoint, token string) (firmware, apiVersion string) {
+	about, err := fetchBMCAbout(endpoint, token)
+	if err != nil {
+		log.Printf("[WARN] Could not read the BMC firmware version from %s: %v", endpoint, err)
+		return "", ""
+	}
+	if firmware = extractFirmwareVersion(about); firmware != "" {
+		log.Printf("[INFO] BMC at %s runs firmware %s", endpoint, firmware)
+	}
+	return firmware, parseAboutResponse(about)["api"]
+}
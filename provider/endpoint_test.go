@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"172.16.0.125":               "https://172.16.0.125",
+		" turingpi.local ":           "https://turingpi.local",
+		"https://turingpi.local/":    "https://turingpi.local",
+		"https://turingpi.local:443": "https://turingpi.local",
+		"http://bmc:80":              "http://bmc",
+		"HTTP://bmc:8080/api/":       "http://bmc:8080",
+		"https://[fd00::70]:443":     "https://[fd00::70]",
+		"https://[fd00::70]:8443":    "https://[fd00::70]:8443",
+	}
+	for raw, want := range tests {
+		got, err := normalizeEndpoint(raw)
+		if err != nil {
+			t.Errorf("normalizeEndpoint(%q): unexpected error: %v", raw, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("normalizeEndpoint(%q) = %q, want %q", raw, got, want)
+		}
+	}
+
+	for _, raw := range []string{"", "ftp://bmc", "https://"} {
+		if _, err := normalizeEndpoint(raw); err == nil {
+			t.Errorf("normalizeEndpoint(%q): expected an error", raw)
+		}
+	}
+}
+
+func TestProbeEndpoint_FollowsRedirect(t *testing.T) {
+	origClient := HTTPClient
+	t.Cleanup(func() { HTTPClient = origClient })
+	HTTPClient = &http.Client{}
+
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer bmc.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, bmc.URL+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}))
+	defer redirect.Close()
+
+	got, err := probeEndpoint(redirect.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != bmc.URL {
+		t.Errorf("expected the redirect target %s, got %s", bmc.URL, got)
+	}
+}
+
+func TestProbeEndpoint_RefusesRedirects(t *testing.T) {
+	origClient := HTTPClient
+	t.Cleanup(func() { HTTPClient = origClient })
+	HTTPClient = &http.Client{}
+
+	tests := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{"another host", "http://attacker.invalid/api/bmc", "redirects to another host"},
+		{"https to http", "http://127.0.0.1/api/bmc", "redirects from https"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, tt.location, http.StatusFound)
+			}))
+			defer server.Close()
+			HTTPClient = server.Client()
+
+			_, err := probeEndpoint(server.URL)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestProbeEndpoint_Failures(t *testing.T) {
+	origClient := HTTPClient
+	t.Cleanup(func() { HTTPClient = origClient })
+	HTTPClient = &http.Client{}
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plainServer.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{"DNS", "https://turingpi.invalid", "DNS lookup of \"turingpi.invalid\" failed"},
+		{"TCP", closedURL, "TCP connection to"},
+		{"self-signed certificate", tlsServer.URL, "TLS certificate verification failed"},
+		{"plain HTTP", strings.Replace(plainServer.URL, "http://", "https://", 1), "does not speak HTTPS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := probeEndpoint(tt.endpoint)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
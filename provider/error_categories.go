@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// Error categories reported in the detail of error diagnostics, so wrapper
+// tooling can decide whether to retry, re-authenticate or alert
+const (
+	errorCategoryAuth           = "AuthError"
+	errorCategoryFirmwareCompat = "FirmwareCompatError"
+	errorCategoryNodeBusy       = "NodeBusy"
+	errorCategoryTimeout        = "Timeout"
+	errorCategorySSHUnreachable = "SSHUnreachable"
+	errorCategoryBoardLocked    = "BoardLocked"
+)
+
+// errorCategoryPrefix starts the first line of the detail of a categorized
+// diagnostic, e.g. "turingpi_error_category=Timeout"
+const errorCategoryPrefix = "turingpi_error_category="
+
+// categorizedError tags an error with its category
+type categorizedError struct {
+	Category string
+	Err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.Err
+}
+
+// categorize tags err with a category. Returns nil for a nil error.
+func categorize(category string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categorizedError{Category: category, Err: err}
+}
+
+// categoryErrorMessages are error fragments of categorized failures that
+// reach us as plain strings, e.g. wrapped by talosctl or Helm
+var categoryErrorMessages = []struct {
+	Fragment string
+	Category string
+}{
+	{"authentication failed", errorCategoryAuth},
+	{"status 401", errorCategoryAuth},
+	{"status 403", errorCategoryAuth},
+	{"transfer is still in progress", errorCategoryNodeBusy},
+	{"timeout waiting", errorCategoryTimeout},
+	{"timed out", errorCategoryTimeout},
+}
+
+// errorCategoryOf returns the category of err: the one it was tagged with,
+// or one inferred from its type or message. Returns "" when uncategorized.
+func errorCategoryOf(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var categorized *categorizedError
+	if errors.As(err, &categorized) {
+		return categorized.Category
+	}
+	var decodeErr *bmcDecodeError
+	if errors.As(err, &decodeErr) {
+		return errorCategoryFirmwareCompat
+	}
+	if isSSHConnectionError(err) {
+		return errorCategorySSHUnreachable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errorCategoryTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errorCategoryTimeout
+	}
+
+	msg := err.Error()
+	for _, m := range categoryErrorMessages {
+		if strings.Contains(msg, m.Fragment) {
+			return m.Category
+		}
+	}
+	return ""
+}
+
+// diagFromErr converts err into an error diagnostic like diag.FromErr, with
+// its category in the detail when it has one
+func diagFromErr(err error) diag.Diagnostics {
+	if err == nil {
+		return nil
+	}
+	d := diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  err.Error(),
+	}
+	if category := errorCategoryOf(err); category != "" {
+		d.Detail = errorCategoryPrefix + category
+	}
+	return diag.Diagnostics{d}
+}
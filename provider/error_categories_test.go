@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorCategoryOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"tagged", fmt.Errorf("flash failed: %w", categorize(errorCategoryNodeBusy, errors.New("busy"))), errorCategoryNodeBusy},
+		{"decode error", fmt.Errorf("read: %w", &bmcDecodeError{Request: "type=power", Err: errors.New("bad")}), errorCategoryFirmwareCompat},
+		{"ssh dial", fmt.Errorf("connect: %w", &DialError{Addr: "10.10.88.73:22", Err: errors.New("no route to host")}), errorCategorySSHUnreachable},
+		{"deadline", fmt.Errorf("wait: %w", context.DeadlineExceeded), errorCategoryTimeout},
+		{"timeout message", errors.New("timeout waiting for MetalLB to be ready"), errorCategoryTimeout},
+		{"unauthorized message", errors.New("API returned status 401: unauthorized"), errorCategoryAuth},
+		{"uncategorized", errors.New("invalid node 5"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCategoryOf(tt.err); got != tt.want {
+				t.Errorf("expected category %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiagFromErr(t *testing.T) {
+	if diags := diagFromErr(nil); diags != nil {
+		t.Errorf("expected no diagnostics for nil, got %v", diags)
+	}
+
+	diags := diagFromErr(fmt.Errorf("wait: %w", context.DeadlineExceeded))
+	if len(diags) != 1 || !diags.HasError() {
+		t.Fatalf("expected one error diagnostic, got %v", diags)
+	}
+	if diags[0].Summary != "wait: context deadline exceeded" {
+		t.Errorf("unexpected summary %q", diags[0].Summary)
+	}
+	if diags[0].Detail != "turingpi_error_category=Timeout" {
+		t.Errorf("unexpected detail %q", diags[0].Detail)
+	}
+
+	if diags := diagFromErr(errors.New("invalid node 5")); diags[0].Detail != "" {
+		t.Errorf("expected no detail for an uncategorized error, got %q", diags[0].Detail)
+	}
+}
+
+func TestAuthenticate_AuthErrorCategory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := authenticate(server.URL, "root", "wrong")
+	if got := errorCategoryOf(err); got != errorCategoryAuth {
+		t.Errorf("expected %s, got %q (%v)", errorCategoryAuth, got, err)
+	}
+}
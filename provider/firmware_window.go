@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // window_timezone works on hosts without a zoneinfo database
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// windowNow is the clock of maintenance window checks. Replaced in tests.
+var windowNow = time.Now
+
+// windowHoursPattern matches allowed_hours, e.g. 01:00-05:30
+var windowHoursPattern = regexp.MustCompile(`^([01]\d|2[0-3]):([0-5]\d)-([01]\d|2[0-3]):([0-5]\d)$`)
+
+// windowDayNames are the allowed_days values, in the order they are shown
+var windowDayNames = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// windowDays maps the allowed_days values to weekdays
+var windowDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// maintenanceWindow is when a firmware flash, and the BMC reboot that
+// follows, may start. A window whose end is before its start runs past
+// midnight and belongs to the day it starts on.
+type maintenanceWindow struct {
+	start, end int // Minutes since midnight; equal for the whole day
+	days       map[time.Weekday]bool
+	loc        *time.Location
+}
+
+// validateWindowTimezone is the ValidateFunc of window_timezone
+func validateWindowTimezone(v interface{}, k string) ([]string, []error) {
+	if _, err := time.LoadLocation(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: unknown time zone %q", k, v.(string))}
+	}
+	return nil, nil
+}
+
+// extractMaintenanceWindow returns the configured window, or nil when any
+// time is allowed
+func extractMaintenanceWindow(d *schema.ResourceData) (*maintenanceWindow, error) {
+	hours := d.Get("allowed_hours").(string)
+	daySet := d.Get("allowed_days").(*schema.Set)
+	if hours == "" && daySet.Len() == 0 {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(d.Get("window_timezone").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid window_timezone: %w", err)
+	}
+	w := &maintenanceWindow{days: make(map[time.Weekday]bool), loc: loc}
+
+	if hours != "" {
+		m := windowHoursPattern.FindStringSubmatch(hours)
+		if m == nil {
+			return nil, fmt.Errorf("invalid allowed_hours %q: must be HH:MM-HH:MM", hours)
+		}
+		w.start = windowMinutes(m[1], m[2])
+		w.end = windowMinutes(m[3], m[4])
+	}
+
+	for _, day := range daySet.List() {
+		w.days[windowDays[day.(string)]] = true
+	}
+	if len(w.days) == 0 {
+		for _, weekday := range windowDays {
+			w.days[weekday] = true
+		}
+	}
+	return w, nil
+}
+
+func windowMinutes(hour, minute string) int {
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+	return h*60 + m
+}
+
+// contains reports whether the window is open at t
+func (w *maintenanceWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+
+	switch {
+	case w.start == w.end:
+		return w.days[t.Weekday()]
+	case w.start < w.end:
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	default:
+		// Past midnight: the evening part belongs to today, the morning part
+		// to the day before
+		if minute >= w.start {
+			return w.days[t.Weekday()]
+		}
+		return minute < w.end && w.days[t.AddDate(0, 0, -1).Weekday()]
+	}
+}
+
+// next returns when the window next opens after t
+func (w *maintenanceWindow) next(t time.Time) time.Time {
+	t = t.In(w.loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.loc)
+	for i := 0; i <= 7; i++ {
+		day := midnight.AddDate(0, 0, i)
+		open := day.Add(time.Duration(w.start) * time.Minute)
+		if w.days[day.Weekday()] && open.After(t) {
+			return open
+		}
+	}
+	// Unreachable with at least one allowed day
+	return t
+}
+
+// String describes the window for error messages
+func (w *maintenanceWindow) String() string {
+	days := make([]string, 0, len(w.days))
+	for _, name := range windowDayNames {
+		if w.days[windowDays[name]] {
+			days = append(days, name)
+		}
+	}
+	hours := "any time"
+	if w.start != w.end {
+		hours = fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+	}
+	return fmt.Sprintf("%s on %s (%s)", hours, strings.Join(days, ","), w.loc)
+}
+
+// enforceMaintenanceWindow returns nil inside the configured maintenance
+// window. Outside it, it fails, or with wait_for_window waits for the window
+// to open. Only the start of the upgrade is checked.
+func enforceMaintenanceWindow(ctx context.Context, d *schema.ResourceData) error {
+	w, err := extractMaintenanceWindow(d)
+	if err != nil || w == nil {
+		return err
+	}
+
+	now := windowNow()
+	if w.contains(now) {
+		return nil
+	}
+	next := w.next(now)
+	if !d.Get("wait_for_window").(bool) {
+		return fmt.Errorf("firmware upgrade is outside the maintenance window %s; the window next opens at %s. Set wait_for_window = true to wait for it", w, next.Format(time.RFC3339))
+	}
+
+	tflog.Info(ctx, "Waiting for the maintenance window to open", map[string]interface{}{
+		"window": w.String(),
+		"opens":  next.Format(time.RFC3339),
+	})
+	if err := sleepContext(ctx, next.Sub(now)); err != nil {
+		return fmt.Errorf("interrupted waiting for the maintenance window %s: %w", w, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func testMaintenanceWindow(t *testing.T, raw map[string]interface{}) *maintenanceWindow {
+	t.Helper()
+	raw["firmware_file"] = "/tmp/bmc.swu"
+	d := schema.TestResourceDataRaw(t, resourceBMCFirmware().Schema, raw)
+	w, err := extractMaintenanceWindow(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return w
+}
+
+func TestMaintenanceWindow_Contains(t *testing.T) {
+	// 2026-10-14 is a Wednesday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		raw    map[string]interface{}
+		t      time.Time
+		inside bool
+	}{
+		{"inside hours", map[string]interface{}{"allowed_hours": "01:00-05:00"}, at(14, 3, 0), true},
+		{"end is exclusive", map[string]interface{}{"allowed_hours": "01:00-05:00"}, at(14, 5, 0), false},
+		{"midday", map[string]interface{}{"allowed_hours": "01:00-05:00"}, at(14, 12, 0), false},
+		{"allowed day", map[string]interface{}{"allowed_days": []interface{}{"wed"}}, at(14, 12, 0), true},
+		{"other day", map[string]interface{}{"allowed_days": []interface{}{"sat", "sun"}}, at(14, 12, 0), false},
+		{"past midnight, evening", map[string]interface{}{"allowed_hours": "22:00-02:00", "allowed_days": []interface{}{"wed"}}, at(14, 23, 0), true},
+		{"past midnight, next morning", map[string]interface{}{"allowed_hours": "22:00-02:00", "allowed_days": []interface{}{"wed"}}, at(15, 1, 0), true},
+		{"past midnight, morning of start day", map[string]interface{}{"allowed_hours": "22:00-02:00", "allowed_days": []interface{}{"wed"}}, at(14, 1, 0), false},
+		{"time zone", map[string]interface{}{"allowed_hours": "01:00-05:00", "window_timezone": "America/New_York"}, at(14, 7, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testMaintenanceWindow(t, tt.raw)
+			if got := w.contains(tt.t); got != tt.inside {
+				t.Errorf("contains(%s) = %v, want %v (window %s)", tt.t, got, tt.inside, w)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindow_Next(t *testing.T) {
+	w := testMaintenanceWindow(t, map[string]interface{}{
+		"allowed_hours": "01:00-05:00",
+		"allowed_days":  []interface{}{"sat"},
+	})
+
+	// Wednesday midday opens on Saturday at 01:00
+	got := w.next(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 10, 17, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestMaintenanceWindow_Unset(t *testing.T) {
+	if w := testMaintenanceWindow(t, map[string]interface{}{}); w != nil {
+		t.Errorf("expected no window, got %s", w)
+	}
+}
+
+func TestEnforceMaintenanceWindow(t *testing.T) {
+	orig := windowNow
+	t.Cleanup(func() { windowNow = orig })
+	windowNow = func() time.Time { return time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) }
+
+	d := schema.TestResourceDataRaw(t, resourceBMCFirmware().Schema, map[string]interface{}{
+		"firmware_file": "/tmp/bmc.swu",
+		"allowed_hours": "01:00-05:00",
+	})
+	err := enforceMaintenanceWindow(context.Background(), d)
+	if err == nil || !strings.Contains(err.Error(), "next opens at 2026-10-15T01:00:00Z") {
+		t.Errorf("expected an outside-window error, got %v", err)
+	}
+
+	// Waiting is interrupted with the context
+	if err := d.Set("wait_for_window", true); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = enforceMaintenanceWindow(ctx, d)
+	if err == nil || !strings.Contains(err.Error(), "interrupted waiting for the maintenance window") {
+		t.Errorf("expected an interrupted wait, got %v", err)
+	}
+
+	// Inside the window the upgrade proceeds
+	windowNow = func() time.Time { return time.Date(2026, 10, 14, 2, 0, 0, 0, time.UTC) }
+	if err := enforceMaintenanceWindow(context.Background(), d); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestResourceBMCFirmware_WindowValidation(t *testing.T) {
+	s := resourceBMCFirmware().Schema
+	if _, errs := s["allowed_hours"].ValidateFunc("1:00-5:00", "allowed_hours"); len(errs) == 0 {
+		t.Error("expected allowed_hours without leading zeros to be rejected")
+	}
+	if _, errs := s["window_timezone"].ValidateFunc("Mars/Olympus_Mons", "window_timezone"); len(errs) == 0 {
+		t.Error("expected an unknown time zone to be rejected")
+	}
+}
@@ -0,0 +1,258 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// fakeFlashBMC serves the flash sequence and fails flashes of the nodes in
+// fail (1-indexed). It records the nodes flashed, in order.
+type fakeFlashBMC struct {
+	mu      sync.Mutex
+	fail    map[int]bool
+	current int
+	flashed []int
+	msd     []string
+}
+
+func (f *fakeFlashBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/bmc/upload/"):
+		_, _ = w.Write([]byte(`{}`))
+	case q.Get("type") == "power":
+		_, _ = w.Write([]byte(`{}`))
+	case q.Get("type") == "node_to_msd":
+		f.msd = append(f.msd, q.Get("node"))
+		_, _ = w.Write([]byte(`{}`))
+	case q.Get("opt") == "set" && q.Get("type") == "flash":
+		_, _ = fmt.Sscanf(q.Get("node"), "%d", &f.current)
+		f.current++
+		f.flashed = append(f.flashed, f.current)
+		_, _ = w.Write([]byte(`{"handle":1}`))
+	case q.Get("opt") == "get" && q.Get("type") == "flash":
+		if f.fail[f.current] {
+			_, _ = w.Write([]byte(`{"Error":"write failed"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Done":[]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeFlashBMC) nodes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.flashed...)
+}
+
+func setupFlashNodesTest(t *testing.T, fail ...int) (*fakeFlashBMC, *ProviderConfig, string) {
+	t.Helper()
+
+	oldDelay, oldPoll := flashPowerOffDelay, flashPollInterval
+	flashPowerOffDelay, flashPollInterval = 0, time.Millisecond
+	t.Cleanup(func() { flashPowerOffDelay, flashPollInterval = oldDelay, oldPoll })
+
+	bmc := &fakeFlashBMC{fail: make(map[int]bool)}
+	for _, n := range fail {
+		bmc.fail[n] = true
+	}
+	server := httptest.NewServer(bmc)
+	t.Cleanup(server.Close)
+
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("firmware"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return bmc, &ProviderConfig{Endpoint: server.URL, Token: "test-token"}, image
+}
+
+func TestResourceFlashCreate_Nodes(t *testing.T) {
+	bmc, config, image := setupFlashNodesTest(t)
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{2, 4, 1},
+		"firmware_file": image,
+	})
+	if diags := resourceFlashCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if got := fmt.Sprint(bmc.nodes()); got != "[2 4 1]" {
+		t.Errorf("expected nodes flashed in order [2 4 1], got %s", got)
+	}
+	if d.Id() != "flash-nodes-2-4-1" {
+		t.Errorf("unexpected ID %q", d.Id())
+	}
+	status := d.Get("node_status").(map[string]interface{})
+	for _, key := range []string{"node1", "node2", "node4"} {
+		if status[key] != flashStatusFlashed {
+			t.Errorf("expected %s flashed, got %v", key, status[key])
+		}
+	}
+	if d.Get("node_progress.node4").(int) != 100 {
+		t.Errorf("expected node4 progress 100, got %v", d.Get("node_progress.node4"))
+	}
+}
+
+func TestResourceFlashCreate_NodesPartialFailure(t *testing.T) {
+	bmc, config, image := setupFlashNodesTest(t, 2)
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 2, 3},
+		"firmware_file": image,
+	})
+	diags := resourceFlashCreate(context.Background(), d, config)
+	if diags.HasError() || len(diags) != 1 || !strings.Contains(diags[0].Detail, "node 2: flash failed: write failed") {
+		t.Fatalf("expected a warning about node 2 that does not taint the resource, got %v", diags)
+	}
+
+	if got := fmt.Sprint(bmc.nodes()); got != "[1 2 3]" {
+		t.Errorf("expected every node to be attempted, got %s", got)
+	}
+	if d.Id() == "" {
+		t.Fatal("expected ID to be set so the per-node status is saved")
+	}
+	if d.Get("node_status.node2") != flashStatusFailed {
+		t.Errorf("expected node2 failed, got %v", d.Get("node_status.node2"))
+	}
+	if d.Get("node_status.node3") != flashStatusFlashed {
+		t.Errorf("expected node3 flashed, got %v", d.Get("node_status.node3"))
+	}
+	if diags[0].Summary != "Node 2 was not flashed" {
+		t.Errorf("expected the failed node in the summary, got %q", diags[0].Summary)
+	}
+
+	// Refreshes keep reporting it until it is flashed
+	diags = resourceFlashRead(context.Background(), d, config)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "Node 2 was not flashed" {
+		t.Errorf("expected a warning about node 2 on refresh, got %v", diags)
+	}
+
+	// The next run retries only the failed node
+	bmc.fail = nil
+	bmc.flashed = nil
+	if diags := resourceFlashUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := fmt.Sprint(bmc.nodes()); got != "[2]" {
+		t.Errorf("expected only node 2 to be retried, got %s", got)
+	}
+	if d.Get("node_status.node2") != flashStatusFlashed {
+		t.Errorf("expected node2 flashed, got %v", d.Get("node_status.node2"))
+	}
+	if diags := resourceFlashRead(context.Background(), d, config); len(diags) != 0 {
+		t.Errorf("expected no warning once every node is flashed, got %v", diags)
+	}
+}
+
+func TestNotFlashedSummary(t *testing.T) {
+	if got := notFlashedSummary([]int{2, 4}); got != "Nodes 2, 4 were not flashed" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestResourceFlashUpdate_DropsRemovedNodes(t *testing.T) {
+	bmc, config, image := setupFlashNodesTest(t)
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 3},
+		"firmware_file": image,
+	})
+	d.SetId("flash-nodes-1-2")
+	_ = d.Set("node_status", map[string]interface{}{"node1": flashStatusFlashed, "node2": flashStatusFlashed})
+	_ = d.Set("node_progress", map[string]interface{}{"node1": 100, "node2": 100})
+
+	if diags := resourceFlashUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := fmt.Sprint(bmc.nodes()); got != "[3]" {
+		t.Errorf("expected only node 3 to be flashed, got %s", got)
+	}
+	status := d.Get("node_status").(map[string]interface{})
+	if _, ok := status["node2"]; ok {
+		t.Error("expected node2 to be dropped from node_status")
+	}
+	if status["node3"] != flashStatusFlashed {
+		t.Errorf("expected node3 flashed, got %v", status["node3"])
+	}
+	if d.Id() != "flash-nodes-1-3" {
+		t.Errorf("expected ID flash-nodes-1-3, got %q", d.Id())
+	}
+}
+
+func TestResourceFlashUpdate_AddedNodeFailureUpdatesID(t *testing.T) {
+	_, config, image := setupFlashNodesTest(t, 3)
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 2, 3},
+		"firmware_file": image,
+	})
+	d.SetId("flash-nodes-1-2")
+	_ = d.Set("node_status", map[string]interface{}{"node1": flashStatusFlashed, "node2": flashStatusFlashed})
+	_ = d.Set("node_progress", map[string]interface{}{"node1": 100, "node2": 100})
+
+	if diags := resourceFlashUpdate(context.Background(), d, config); !diags.HasError() {
+		t.Fatal("expected the failed node 3 to be reported")
+	}
+	if d.Id() != "flash-nodes-1-2-3" {
+		t.Errorf("expected ID flash-nodes-1-2-3, got %q", d.Id())
+	}
+	if status := d.Get("node_status").(map[string]interface{}); status["node3"] != flashStatusFailed {
+		t.Errorf("expected node3 failed, got %v", status["node3"])
+	}
+}
+
+func TestResourceFlashCreate_SingleNodeFailureLeavesNoID(t *testing.T) {
+	_, config, image := setupFlashNodesTest(t, 1)
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"node":          1,
+		"firmware_file": image,
+	})
+	if diags := resourceFlashCreate(context.Background(), d, config); !diags.HasError() {
+		t.Fatal("expected error")
+	}
+	if d.Id() != "" {
+		t.Errorf("expected no ID after a failed single-node flash, got %q", d.Id())
+	}
+}
+
+func TestResourceFlashCreate_NodesAllFailed(t *testing.T) {
+	_, config, image := setupFlashNodesTest(t, 1, 2)
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 2},
+		"firmware_file": image,
+	})
+	if diags := resourceFlashCreate(context.Background(), d, config); !diags.HasError() {
+		t.Fatalf("expected an error when no node was flashed, got %v", diags)
+	}
+}
+
+func TestFlashTargets_DuplicateNodes(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 2, 1},
+		"firmware_file": "/tmp/image.img",
+	})
+	_, err := flashTargets(d)
+	if err == nil || !strings.Contains(err.Error(), "node 1 is listed more than once") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
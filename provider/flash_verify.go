@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// flashVerifyDeviceWait is how long readback waits for the storage of a node
+// in MSD mode to appear on the BMC
+var flashVerifyDeviceWait = 2 * time.Minute
+
+// flashReadbackScript waits for the block device of the node, the first
+// /dev/sd? unless a device is given, and prints the SHA256 of its first size
+// bytes
+const flashReadbackScript = `want=%[1]s; dev=
+i=0
+while [ $i -lt %[3]d ]; do
+  for d in ${want:-/dev/sd?}; do [ -b "$d" ] && [ -z "$dev" ] && dev=$d; done
+  [ -n "$dev" ] && break
+  i=$((i+1)); sleep 1
+done
+[ -n "$dev" ] || { echo "the node storage did not appear on the BMC" >&2; exit 1; }
+head -c %[2]d "$dev" | sha256sum`
+
+var sha256Output = regexp.MustCompile(`(?m)^([0-9a-f]{64})\b`)
+
+// flashVerify is the verify block of turingpi_flash
+type flashVerify struct {
+	target NodeConfig
+	device string
+}
+
+func flashVerifySchema() *schema.Resource {
+	s := bmcSSHSchema()
+	s["device"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "Block device of the node storage on the BMC in MSD mode (default: the first /dev/sd? device)",
+	}
+	return &schema.Resource{Schema: s}
+}
+
+// expandFlashVerify returns the verify block, or nil when it is not set
+func expandFlashVerify(v []interface{}, endpoint string) (*flashVerify, error) {
+	if len(v) == 0 || v[0] == nil {
+		return nil, nil
+	}
+	data := v[0].(map[string]interface{})
+	target, err := bmcSSHTarget(data, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	return &flashVerify{target: target, device: data["device"].(string)}, nil
+}
+
+// imageSHA256 returns the SHA256 and size of firmwarePath with patches
+// applied, i.e. of the bytes written to the node
+func imageSHA256(firmwarePath string, patches []imagePatch) (string, int64, error) {
+	file, err := os.Open(firmwarePath)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open firmware file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	h := sha256.New()
+	size, err := io.Copy(h, newPatchedReader(file, patches))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read firmware file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// verifyFlash reads back what was flashed to node and compares its SHA256
+// with the image. The node is put in MSD mode, so its storage shows up as a
+// USB disk on the BMC, hashed there over SSH, and powered off again, as a
+// flash leaves it. Returns the checksum when it matches.
+func verifyFlash(ctx context.Context, config *ProviderConfig, v *flashVerify, node int, firmwarePath string, patches []imagePatch) (string, error) {
+	want, size, err := imageSHA256(firmwarePath, patches)
+	if err != nil {
+		return "", err
+	}
+
+	// API uses 0-indexed nodes
+	if err := nodeToMSD(config.Endpoint, config.Token, node-1); err != nil {
+		return "", fmt.Errorf("failed to put node %d in MSD mode for readback: %w", node, err)
+	}
+	defer func() { _ = setNodePower(config.Endpoint, config.Token, node, false) }()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("readback of node %d interrupted: %w", node, ctx.Err())
+	}
+
+	fmt.Printf("Reading back %d bytes from node %d...\n", size, node)
+	client := config.newSSHClient()
+	if err := client.Connect(v.target.Host, v.target.SSHPort, v.target.getSSHConfig()); err != nil {
+		return "", fmt.Errorf("SSH connection to %s failed: %w", v.target.Host, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	output, err := client.RunCommand(fmt.Sprintf(flashReadbackScript, shellQuote(v.device), size, int(flashVerifyDeviceWait.Seconds())))
+	if err != nil {
+		return "", fmt.Errorf("readback of node %d failed: %w: %s", node, err, strings.TrimSpace(output))
+	}
+	m := sha256Output.FindStringSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("readback of node %d returned no checksum: %s", node, strings.TrimSpace(output))
+	}
+	if got := m[1]; got != want {
+		return "", fmt.Errorf("readback checksum mismatch on node %d: wrote %s, read back %s. The flash is corrupt and is retried on the next apply", node, want, got)
+	}
+	fmt.Printf("Node %d verified: %s\n", node, want)
+	return want, nil
+}
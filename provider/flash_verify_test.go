@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestImageSHA256(t *testing.T) {
+	_, _, image := setupFlashNodesTest(t)
+	sum, size, err := imageSHA256(image, []imagePatch{{offset: 0, data: []byte("F")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte("Firmware"))
+	if sum != hex.EncodeToString(want[:]) || size != 8 {
+		t.Errorf("expected the checksum of the patched image, got %s (%d bytes)", sum, size)
+	}
+}
+
+func TestResourceFlashCreate_Verify(t *testing.T) {
+	bmc, config, image := setupFlashNodesTest(t)
+	data, _ := os.ReadFile(image)
+	good := sha256.Sum256(data)
+
+	var scripts []string
+	readback := map[int]string{1: hex.EncodeToString(good[:]), 2: strings.Repeat("0", 64)}
+	config.sshClientFactory = func() SSHClient {
+		return &MockSSHClient{RunCommandFunc: func(cmd string) (string, error) {
+			scripts = append(scripts, cmd)
+			node := len(scripts)
+			return readback[node] + "  -\n", nil
+		}}
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 2},
+		"firmware_file": image,
+		"verify":        []interface{}{map[string]interface{}{"ssh_password": "turing", "device": "/dev/sda"}},
+	})
+	diags := resourceFlashCreate(context.Background(), d, config)
+	if len(diags) != 1 || !strings.Contains(diags[0].Detail, "readback checksum mismatch on node 2") {
+		t.Fatalf("expected a mismatch on node 2, got %v", diags)
+	}
+
+	if got := fmt.Sprint(bmc.msd); got != "[0 1]" {
+		t.Errorf("expected both nodes put in MSD mode (0-indexed), got %s", got)
+	}
+	if !strings.Contains(scripts[0], "want=/dev/sda") || !strings.Contains(scripts[0], "head -c 8 ") {
+		t.Errorf("unexpected readback script:\n%s", scripts[0])
+	}
+	if d.Get("node_status.node1") != flashStatusFlashed || d.Get("node_status.node2") != flashStatusFailed {
+		t.Errorf("expected node2 to fail verification, got %v", d.Get("node_status"))
+	}
+	if d.Get("node_checksum.node1") != hex.EncodeToString(good[:]) || d.Get("node_checksum.node2") != "" {
+		t.Errorf("unexpected checksums %v", d.Get("node_checksum"))
+	}
+	if d.Get("verified").(bool) {
+		t.Error("expected verified to be false with a mismatch")
+	}
+
+	// The next apply flashes and reads back only node 2
+	readback[3] = hex.EncodeToString(good[:])
+	if diags := resourceFlashUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if !d.Get("verified").(bool) {
+		t.Error("expected verified once every node matched")
+	}
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
+)
+
+// Terraform runs a provider process per provider configuration, so a fleet
+// with one aliased provider per BMC spans several processes. Slots are lock
+// files in a directory shared by all of them. A holder touches its lock file
+// while it works; a lock that has not been touched for fleetLockStale is left
+// over from a crashed run and is taken over.
+// Replaced in tests to keep them fast and isolated.
+var (
+	fleetLockDir       = filepath.Join(os.TempDir(), "terraform-provider-turingpi-fleet")
+	fleetLockHeartbeat = 10 * time.Second
+	fleetLockStale     = time.Minute
+	fleetPollInterval  = time.Second
+)
+
+// fleetWaitTimeout bounds the wait for a slot. Slots are held for whole
+// uploads and flashes, so it is far longer than any one of them.
+const fleetWaitTimeout = 24 * time.Hour
+
+// fleetPool caps the number of concurrent firmware uploads and node flashes
+// across all boards. A nil pool or a size of 0 does not limit anything.
+type fleetPool struct {
+	size int
+	dir  string
+}
+
+func newFleetPool(size int) *fleetPool {
+	if size <= 0 {
+		return nil
+	}
+	return &fleetPool{size: size, dir: fleetLockDir}
+}
+
+// run waits for a free slot, runs fn and releases the slot. operation and
+// endpoint are written to the lock file and logged while waiting.
+func (p *fleetPool) run(ctx context.Context, operation, endpoint string, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+
+	release, err := p.acquire(ctx, fmt.Sprintf("%s %s (pid %d)", operation, endpoint, os.Getpid()))
+	if err != nil {
+		return fmt.Errorf("waiting for a fleet_parallelism slot for %s: %w", operation, err)
+	}
+	defer release()
+	return fn()
+}
+
+// acquire blocks until a slot lock file is created, ctx is done or
+// fleetWaitTimeout passes
+func (p *fleetPool) acquire(ctx context.Context, owner string) (func(), error) {
+	if err := os.MkdirAll(p.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	var release func()
+	logged := false
+	err := poll(ctx, fleetPollInterval, fleetWaitTimeout, "a fleet_parallelism slot", func(context.Context) (bool, error) {
+		for i := 0; i < p.size; i++ {
+			path := filepath.Join(p.dir, fmt.Sprintf("slot-%d.lock", i))
+			ok, err := tryLock(path, owner)
+			if err != nil {
+				return false, waitutil.Permanent(err)
+			}
+			if ok {
+				release = holdLock(path)
+				return true, nil
+			}
+		}
+
+		if !logged {
+			tflog.Info(ctx, "All fleet_parallelism slots are busy, waiting", map[string]interface{}{
+				"slots": p.size,
+				"owner": owner,
+			})
+			logged = true
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// tryLock creates the lock file at path, taking over a stale one
+func tryLock(path, owner string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err == nil {
+		_, _ = f.WriteString(owner + "\n")
+		return true, f.Close()
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return false, fmt.Errorf("failed to create lock file: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		// Released between the create and the stat; try again on the next pass
+		return false, nil
+	}
+	if time.Since(info.ModTime()) > fleetLockStale {
+		_ = os.Remove(path)
+	}
+	return false, nil
+}
+
+// holdLock touches path every fleetLockHeartbeat until the returned release
+// function is called, which removes it
+func holdLock(path string) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(fleetLockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				_ = os.Chtimes(path, now, now)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+		_ = os.Remove(path)
+	}
+}
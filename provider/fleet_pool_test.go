@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useTestFleetLocks points the pool at a temporary directory with fast timings
+func useTestFleetLocks(t *testing.T) string {
+	t.Helper()
+	dir, heartbeat, stale, poll := fleetLockDir, fleetLockHeartbeat, fleetLockStale, fleetPollInterval
+	fleetLockDir = t.TempDir()
+	fleetLockHeartbeat = 10 * time.Millisecond
+	fleetLockStale = time.Second
+	fleetPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() {
+		fleetLockDir, fleetLockHeartbeat, fleetLockStale, fleetPollInterval = dir, heartbeat, stale, poll
+	})
+	return fleetLockDir
+}
+
+func TestNewFleetPool_Unlimited(t *testing.T) {
+	if newFleetPool(0) != nil {
+		t.Error("expected no pool for fleet_parallelism = 0")
+	}
+
+	var p *fleetPool
+	called := false
+	if err := p.run(context.Background(), "flash", "https://turingpi.local", func() error {
+		called = true
+		return nil
+	}); err != nil || !called {
+		t.Errorf("expected a nil pool to run fn directly, got called=%v err=%v", called, err)
+	}
+}
+
+func TestFleetPool_CapsConcurrency(t *testing.T) {
+	dir := useTestFleetLocks(t)
+
+	// Two pools stand in for two provider processes sharing the lock directory
+	pools := []*fleetPool{newFleetPool(2), newFleetPool(2)}
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(p *fleetPool) {
+			defer wg.Done()
+			err := p.run(context.Background(), "flash", "https://bmc", func() error {
+				n := running.Add(1)
+				for {
+					old := peak.Load()
+					if n <= old || peak.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(30 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(pools[i%2])
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("expected at most 2 operations at once, peak was %d", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected all slots to be released, found %d lock files", len(entries))
+	}
+}
+
+func TestFleetPool_TakesOverStaleLock(t *testing.T) {
+	dir := useTestFleetLocks(t)
+
+	stale := filepath.Join(dir, "slot-0.lock")
+	if err := os.WriteFile(stale, []byte("crashed run\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := newFleetPool(1).run(ctx, "flash", "https://bmc", func() error { return nil }); err != nil {
+		t.Errorf("expected the stale lock to be taken over, got %v", err)
+	}
+}
+
+func TestFleetPool_WaitHonoursContext(t *testing.T) {
+	useTestFleetLocks(t)
+	p := newFleetPool(1)
+
+	release, err := p.acquire(context.Background(), "holder")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = p.run(ctx, "BMC firmware upload", "https://bmc", func() error {
+		t.Error("fn must not run without a slot")
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error when the context ends while waiting")
+	}
+}
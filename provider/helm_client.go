@@ -2,13 +2,17 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 	helmclient "github.com/mittwald/go-helm-client"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // HelmClient interface for Helm operations - allows mocking in tests
@@ -41,23 +45,50 @@ type RealHelmClient struct {
 	namespace string
 }
 
+// HelmClientOptions tunes how the Helm client connects to the cluster.
+// The zero value uses the kubeconfig as-is with client-go default rate limits.
+type HelmClientOptions struct {
+	KubeContext           string  // Kubeconfig context to use (empty = current context)
+	APIServer             string  // Overrides the API server URL from the kubeconfig
+	QPS                   float32 // Client-side request rate limit (0 = client-go default)
+	Burst                 int     // Client-side request burst limit (0 = client-go default)
+	InsecureSkipTLSVerify bool    // Skip API server certificate verification
+}
+
 // NewHelmClient creates a new Helm client from a kubeconfig file path
 func NewHelmClient(kubeconfigPath, namespace string) (HelmClient, error) {
+	return NewHelmClientWithOptions(kubeconfigPath, namespace, HelmClientOptions{})
+}
+
+// NewHelmClientWithOptions creates a new Helm client from a kubeconfig file path
+// with connection options
+func NewHelmClientWithOptions(kubeconfigPath, namespace string, opts HelmClientOptions) (HelmClient, error) {
 	kubeconfig, err := os.ReadFile(kubeconfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
 	}
 
-	return NewHelmClientFromBytes(kubeconfig, namespace)
+	return NewHelmClientFromBytesWithOptions(kubeconfig, namespace, opts)
 }
 
 // NewHelmClientFromBytes creates a new Helm client from kubeconfig bytes
 func NewHelmClientFromBytes(kubeconfig []byte, namespace string) (HelmClient, error) {
+	return NewHelmClientFromBytesWithOptions(kubeconfig, namespace, HelmClientOptions{})
+}
+
+// NewHelmClientFromBytesWithOptions creates a new Helm client from kubeconfig
+// bytes with connection options
+func NewHelmClientFromBytesWithOptions(kubeconfig []byte, namespace string, opts HelmClientOptions) (HelmClient, error) {
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	opt := &helmclient.KubeConfClientOptions{
+	restConfig, err := helmRESTConfig(kubeconfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := &helmclient.RestConfClientOptions{
 		Options: &helmclient.Options{
 			Namespace:        namespace,
 			RepositoryCache:  "/tmp/.helmcache",
@@ -65,10 +96,10 @@ func NewHelmClientFromBytes(kubeconfig []byte, namespace string) (HelmClient, er
 			Debug:            false,
 			Linting:          false,
 		},
-		KubeConfig: kubeconfig,
+		RestConfig: restConfig,
 	}
 
-	client, err := helmclient.NewClientFromKubeConf(opt)
+	client, err := helmclient.NewClientFromRestConf(opt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Helm client: %w", err)
 	}
@@ -79,6 +110,43 @@ func NewHelmClientFromBytes(kubeconfig []byte, namespace string) (HelmClient, er
 	}, nil
 }
 
+// helmRESTConfig builds the REST config for the Helm client from raw
+// kubeconfig bytes, applying context, API server, TLS and rate limit options
+func helmRESTConfig(kubeconfig []byte, opts HelmClientOptions) (*rest.Config, error) {
+	rawConfig, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.KubeContext}
+	if opts.KubeContext != "" {
+		if _, ok := rawConfig.Contexts[opts.KubeContext]; !ok {
+			return nil, fmt.Errorf("context %q not found in kubeconfig", opts.KubeContext)
+		}
+	}
+	if opts.APIServer != "" {
+		overrides.ClusterInfo.Server = opts.APIServer
+	}
+	if opts.InsecureSkipTLSVerify {
+		// Also clears the kubeconfig CA, which client-go rejects alongside insecure
+		overrides.ClusterInfo.InsecureSkipTLSVerify = true
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, opts.KubeContext, overrides, nil).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Kubernetes client config: %w", err)
+	}
+
+	if opts.QPS > 0 {
+		restConfig.QPS = opts.QPS
+	}
+	if opts.Burst > 0 {
+		restConfig.Burst = opts.Burst
+	}
+
+	return restConfig, nil
+}
+
 // AddRepository adds or updates a Helm chart repository
 func (c *RealHelmClient) AddRepository(name, url string) error {
 	chartRepo := repo.Entry{
@@ -120,7 +188,12 @@ func (c *RealHelmClient) InstallOrUpgradeChart(ctx context.Context, spec *ChartS
 		CleanupOnFail:   spec.Atomic, // Clean up on failure if atomic
 	}
 
-	rel, err := c.client.InstallOrUpgradeChart(ctx, &chartSpec, nil)
+	var rel *release.Release
+	err := retryTransient(ctx, "install chart "+spec.ChartName, func() error {
+		var err error
+		rel, err = c.client.InstallOrUpgradeChart(ctx, &chartSpec, nil)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to install/upgrade chart %s: %w", spec.ChartName, err)
 	}
@@ -208,28 +281,25 @@ func WaitForHelmRelease(kubeconfigPath, name, namespace string, timeout time.Dur
 
 // WaitForHelmReleaseWithClient waits for a release using a provided client (for testing)
 func WaitForHelmReleaseWithClient(client HelmClient, name string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
+	err := poll(context.Background(), pollInterval, timeout, "Helm release "+name, func(context.Context) (bool, error) {
 		rel, err := client.GetRelease(name)
 		if err != nil {
-			time.Sleep(5 * time.Second)
-			continue
+			return false, err
 		}
 
 		switch rel.Info.Status {
 		case release.StatusDeployed:
-			return nil
+			return true, nil
 		case release.StatusFailed:
-			return fmt.Errorf("release %s failed: %s", name, rel.Info.Description)
-		case release.StatusPendingInstall, release.StatusPendingUpgrade, release.StatusPendingRollback:
-			// Still in progress, keep waiting
+			return false, waitutil.Permanent(fmt.Errorf("release %s failed: %s", name, rel.Info.Description))
 		default:
-			// Unknown status, keep waiting
+			// Pending or unknown status, keep waiting
+			return false, nil
 		}
-
-		time.Sleep(5 * time.Second)
+	})
+	var timeoutErr *waitutil.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return fmt.Errorf("waiting for release %s: %w", name, err)
 	}
-
-	return fmt.Errorf("timeout waiting for release %s after %v", name, timeout)
+	return err
 }
@@ -520,3 +520,78 @@ speaker:
 		t.Error("ValuesYaml not passed correctly")
 	}
 }
+
+const testHelmKubeconfig = `apiVersion: v1
+kind: Config
+current-context: home
+clusters:
+- name: home
+  cluster:
+    server: https://10.10.88.73:6443
+    certificate-authority-data: ZmFrZS1jYQ==
+- name: lab
+  cluster:
+    server: https://10.10.89.73:6443
+contexts:
+- name: home
+  context:
+    cluster: home
+    user: admin
+- name: lab
+  context:
+    cluster: lab
+    user: admin
+users:
+- name: admin
+  user:
+    token: fake-token
+`
+
+func TestHelmRESTConfig_Defaults(t *testing.T) {
+	cfg, err := helmRESTConfig([]byte(testHelmKubeconfig), HelmClientOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Host != "https://10.10.88.73:6443" {
+		t.Errorf("expected current context server, got %q", cfg.Host)
+	}
+	if string(cfg.CAData) != "fake-ca" || cfg.Insecure {
+		t.Errorf("expected kubeconfig TLS settings to be kept, got CAData=%q insecure=%v", cfg.CAData, cfg.Insecure)
+	}
+}
+
+func TestHelmRESTConfig_Options(t *testing.T) {
+	cfg, err := helmRESTConfig([]byte(testHelmKubeconfig), HelmClientOptions{
+		KubeContext:           "home",
+		APIServer:             "https://192.168.1.50:6443",
+		QPS:                   50,
+		Burst:                 100,
+		InsecureSkipTLSVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Host != "https://192.168.1.50:6443" {
+		t.Errorf("expected API server override, got %q", cfg.Host)
+	}
+	if !cfg.Insecure || len(cfg.CAData) != 0 {
+		t.Errorf("expected insecure without CA data, got CAData=%q insecure=%v", cfg.CAData, cfg.Insecure)
+	}
+	if cfg.QPS != 50 || cfg.Burst != 100 {
+		t.Errorf("expected QPS 50 and burst 100, got %v and %d", cfg.QPS, cfg.Burst)
+	}
+}
+
+func TestHelmRESTConfig_Context(t *testing.T) {
+	cfg, err := helmRESTConfig([]byte(testHelmKubeconfig), HelmClientOptions{KubeContext: "lab"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Host != "https://10.10.89.73:6443" {
+		t.Errorf("expected lab context server, got %q", cfg.Host)
+	}
+
+	if _, err := helmRESTConfig([]byte(testHelmKubeconfig), HelmClientOptions{KubeContext: "missing"}); err == nil {
+		t.Error("expected error for unknown context")
+	}
+}
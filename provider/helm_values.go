@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// normalizeHelmValuesYAML parses a values document and renders it with sorted
+// keys, so formatting, key order and comments do not count as changes. An
+// empty document normalizes to "".
+func normalizeHelmValuesYAML(doc string) (string, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &values); err != nil {
+		return "", fmt.Errorf("failed to parse values: %w", err)
+	}
+	return normalizeHelmValues(values)
+}
+
+// normalizeHelmValues renders values with sorted keys. Values pass through
+// JSON first so numbers compare the same whether they were parsed from the
+// configuration or returned by Helm with a release.
+func normalizeHelmValues(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize values: %w", err)
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return "", fmt.Errorf("failed to normalize values: %w", err)
+	}
+	return renderHelmValues(generic)
+}
+
+// helmValuesHash returns the SHA-256 of a normalized values document
+func helmValuesHash(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// diffHelmValues compares two normalized values documents key by key and
+// returns one line per changed leaf, e.g. "~ controller.replicaCount: 1 -> 2",
+// "+ controller.service.type: \"LoadBalancer\"" or "- rbac.create: true".
+// Lists are compared as a whole.
+func diffHelmValues(oldDoc, newDoc string) ([]string, error) {
+	oldLeaves, err := helmValuesLeaves(oldDoc)
+	if err != nil {
+		return nil, err
+	}
+	newLeaves, err := helmValuesLeaves(newDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(oldLeaves)+len(newLeaves))
+	for k := range oldLeaves {
+		keys = append(keys, k)
+	}
+	for k := range newLeaves {
+		if _, ok := oldLeaves[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, k := range keys {
+		oldValue, inOld := oldLeaves[k]
+		newValue, inNew := newLeaves[k]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", k, newValue))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("- %s: %s", k, oldValue))
+		case oldValue != newValue:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", k, oldValue, newValue))
+		}
+	}
+	return changes, nil
+}
+
+// helmValuesLeaves flattens a values document to dotted keys and their
+// values rendered as compact JSON
+func helmValuesLeaves(doc string) (map[string]string, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values: %w", err)
+	}
+	leaves := make(map[string]string)
+	if err := flattenHelmValues("", values, leaves); err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+func flattenHelmValues(prefix string, values map[string]interface{}, leaves map[string]string) error {
+	for k, v := range values {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			if err := flattenHelmValues(key, nested, leaves); err != nil {
+				return err
+			}
+			continue
+		}
+		rendered, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to render value of %s: %w", key, err)
+		}
+		leaves[key] = string(rendered)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeHelmValuesYAML(t *testing.T) {
+	a, err := normalizeHelmValuesYAML("# replicas\ncontroller:\n  replicaCount: 2\n  image: {tag: v1}\nrbac:\n    create: true\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := normalizeHelmValuesYAML("rbac: {create: true}\ncontroller:\n  image:\n    tag: v1\n  replicaCount: 2\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a != b {
+		t.Errorf("equivalent documents normalized differently:\n%s\n%s", a, b)
+	}
+	want := "controller:\n    image:\n        tag: v1\n    replicaCount: 2\nrbac:\n    create: true\n"
+	if a != want {
+		t.Errorf("normalized = %q, want %q", a, want)
+	}
+
+	empty, err := normalizeHelmValuesYAML("  \n# nothing\n")
+	if err != nil || empty != "" {
+		t.Errorf("empty document = %q, %v; want \"\", nil", empty, err)
+	}
+
+	if _, err := normalizeHelmValuesYAML("- a\n- b\n"); err == nil {
+		t.Error("expected an error for a document that is not a map")
+	}
+}
+
+func TestNormalizeHelmValues_MatchesYAML(t *testing.T) {
+	// Helm returns release values decoded from JSON, so numbers are float64
+	fromRelease, err := normalizeHelmValues(map[string]interface{}{
+		"controller": map[string]interface{}{"replicaCount": float64(2)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fromConfig, err := normalizeHelmValuesYAML("controller:\n  replicaCount: 2\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fromRelease != fromConfig {
+		t.Errorf("release values %q do not match configured values %q", fromRelease, fromConfig)
+	}
+}
+
+func TestHelmValuesHash(t *testing.T) {
+	if helmValuesHash("a: 1\n") == helmValuesHash("a: 2\n") {
+		t.Error("different documents have the same hash")
+	}
+	if got := helmValuesHash(""); len(got) != 64 {
+		t.Errorf("hash = %q, want 64 hex characters", got)
+	}
+}
+
+func TestDiffHelmValues(t *testing.T) {
+	oldDoc := "controller:\n  replicaCount: 1\n  args: [a]\nrbac:\n  create: true\n"
+	newDoc := "controller:\n  replicaCount: 2\n  args: [a, b]\n  service:\n    type: LoadBalancer\n"
+
+	changes, err := diffHelmValues(oldDoc, newDoc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		`~ controller.args: ["a"] -> ["a","b"]`,
+		"~ controller.replicaCount: 1 -> 2",
+		`+ controller.service.type: "LoadBalancer"`,
+		"- rbac.create: true",
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %q, want %q", changes, want)
+	}
+
+	if changes, _ := diffHelmValues(oldDoc, oldDoc); len(changes) != 0 {
+		t.Errorf("expected no changes, got %q", changes)
+	}
+}
@@ -1,11 +1,15 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 )
 
 // Note: Uses HTTPClient from provider.go for TLS configuration
@@ -35,34 +39,38 @@ func flashNode(node int, firmware string) {
 func checkBootStatus(endpoint string, node int, timeout int, token string, pattern string) (bool, error) {
 	url := fmt.Sprintf("%s/api/bmc?opt=get&type=uart&node=%d", endpoint, node)
 
-	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
-
-	for time.Now().Before(deadline) {
-		req, err := http.NewRequest("GET", url, nil)
+	err := poll(context.Background(), pollInterval, time.Duration(timeout)*time.Second, "boot pattern", func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			return false, fmt.Errorf("failed to create UART request: %v", err)
+			return false, waitutil.Permanent(fmt.Errorf("failed to create UART request: %v", err))
 		}
 
 		req.Header.Set("Authorization", "Bearer "+token)
 		resp, err := HTTPClient.Do(req)
 		if err != nil {
-			return false, fmt.Errorf("UART request failed: %v", err)
+			if ctx.Err() != nil {
+				return false, err
+			}
+			return false, waitutil.Permanent(fmt.Errorf("UART request failed: %v", err))
 		}
 
 		defer func() { _ = resp.Body.Close() }()
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return false, fmt.Errorf("failed to read UART response: %v", err)
+			return false, waitutil.Permanent(fmt.Errorf("failed to read UART response: %v", err))
 		}
 
 		// Check for configured boot pattern in UART output
-		if strings.Contains(string(body), pattern) {
-			fmt.Printf("Node %d booted successfully: pattern %q detected.\n", node, pattern)
-			return true, nil
-		}
-
-		time.Sleep(5 * time.Second)
+		return strings.Contains(string(body), pattern), nil
+	})
+	var timeoutErr *waitutil.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return false, fmt.Errorf("timeout reached: node %d did not boot successfully (pattern %q not found)", node, pattern)
+	}
+	if err != nil {
+		return false, err
 	}
 
-	return false, fmt.Errorf("timeout reached: node %d did not boot successfully (pattern %q not found)", node, pattern)
+	fmt.Printf("Node %d booted successfully: pattern %q detected.\n", node, pattern)
+	return true, nil
 }
@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/bmc"
+)
+
+// Version is the provider version reported in the User-Agent header.
+// Set by main from the release build flags.
+var Version = "dev"
+
+// requestIDHeader carries the per-run request ID on every BMC call
+const requestIDHeader = "X-Request-ID"
+
+// bmcTransport adds the User-Agent and request ID headers to BMC requests and
+// logs each call, so BMC-side logs can be correlated with Terraform runs.
+// With a session, bearer tokens are replaced by the current session token;
+// without one (auth = "none"), empty bearer tokens are dropped. With a board
+// lock, requests that change the board are only sent while this run holds it.
+// Requests and body bytes are counted in the provider metrics. With
+// postMutations, opt=set GETs are sent as the POSTs newer firmware expects.
+// With restart, requests failing while a reboot, reload or firmware upgrade
+// sent through this transport restarts the BMC wait for it to come back.
+type bmcTransport struct {
+	base          http.RoundTripper
+	userAgent     string
+	requestID     string
+	session       *bmcSession
+	lock          *boardLock
+	postMutations bool
+	restart       *bmcRestart
+}
+
+func (t *bmcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.lock != nil && isMutatingBMCRequest(req) {
+		if err := t.lock.ensure(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	req.Header.Set(requestIDHeader, t.requestID)
+	if t.session != nil {
+		t.session.authorize(req)
+	}
+	if strings.TrimSpace(req.Header.Get("Authorization")) == "Bearer" {
+		req.Header.Del("Authorization")
+	}
+
+	log.Printf("[DEBUG] BMC request: %s %s (user_agent=%q request_id=%s)", req.Method, req.URL.Redacted(), t.userAgent, t.requestID)
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countingBody{ReadCloser: req.Body, n: &metrics.uploadBytes}
+	}
+	// Metrics keep the type and opt labels of the query
+	sent := req
+	if t.postMutations && isGETMutation(req) {
+		post, err := postMutation(req)
+		if err != nil {
+			return nil, err
+		}
+		sent = post
+	}
+	resp, err := base.RoundTrip(sent)
+	restarts := isBMCRestartRequest(req)
+	if err != nil && t.restart != nil && !restarts {
+		resp, err = t.restart.retry(base, sent, t.session, err)
+	}
+	if err != nil {
+		metrics.countBMCRequest(req, 0)
+		log.Printf("[DEBUG] BMC request failed: %s %s (request_id=%s): %v", sent.Method, req.URL.Redacted(), t.requestID, err)
+		return nil, err
+	}
+	metrics.countBMCRequest(req, resp.StatusCode)
+	if restarts && t.restart != nil && resp.StatusCode == http.StatusOK {
+		t.restart.mark()
+	}
+	log.Printf("[DEBUG] BMC response: %s %s -> %d (request_id=%s)", sent.Method, req.URL.Redacted(), resp.StatusCode, t.requestID)
+	return resp, nil
+}
+
+// usePOSTMutations returns whether opt=set calls are sent as POSTs, for the
+// bmc_request_method setting and the API version the BMC reports
+func usePOSTMutations(method, apiVersion string) bool {
+	switch method {
+	case bmc.RequestMethodGET:
+		return false
+	case bmc.RequestMethodPOST:
+		return true
+	}
+	if bmc.SupportsPOST(apiVersion) {
+		log.Printf("[INFO] BMC API %s: mutating calls are sent as POST requests", apiVersion)
+		return true
+	}
+	return false
+}
+
+// isGETMutation reports whether req is a legacy opt=set GET to /api/bmc
+func isGETMutation(req *http.Request) bool {
+	return req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/api/bmc") && req.URL.Query().Get("opt") == "set"
+}
+
+// postMutation returns the POST of req's query parameters, with its headers
+func postMutation(req *http.Request) (*http.Request, error) {
+	endpoint := *req.URL
+	endpoint.RawQuery = ""
+	post, err := bmc.NewSetRequest(req.Context(), strings.TrimSuffix(endpoint.String(), "/api/bmc"), req.URL.Query(), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create POST request: %w", err)
+	}
+	contentType := post.Header.Get("Content-Type")
+	post.Header = req.Header.Clone()
+	post.Header.Set("Content-Type", contentType)
+	return post, nil
+}
+
+// buildUserAgent returns terraform-provider-turingpi/<version>, followed by
+// the user_agent_suffix provider setting if set
+func buildUserAgent(suffix string) string {
+	ua := "terraform-provider-turingpi/" + Version
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		ua += " " + suffix
+	}
+	return ua
+}
+
+// newRequestID returns a random ID identifying one provider run
+func newRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate request ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
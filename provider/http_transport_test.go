@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestBMCTransport_SetsHeaders(t *testing.T) {
+	var gotUA, gotID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		gotID = r.Header.Get(requestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &bmcTransport{
+		userAgent: "terraform-provider-turingpi/1.2.3",
+		requestID: "abc123",
+	}}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/bmc?opt=get&type=about", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if gotUA != "terraform-provider-turingpi/1.2.3" {
+		t.Errorf("expected User-Agent header, got %q", gotUA)
+	}
+	if gotID != "abc123" {
+		t.Errorf("expected request ID header, got %q", gotID)
+	}
+	if req.Header.Get(requestIDHeader) != "" {
+		t.Error("caller's request should not be modified")
+	}
+}
+
+func TestBuildUserAgent(t *testing.T) {
+	oldVersion := Version
+	Version = "1.2.3"
+	defer func() { Version = oldVersion }()
+
+	if got := buildUserAgent(""); got != "terraform-provider-turingpi/1.2.3" {
+		t.Errorf("unexpected User-Agent %q", got)
+	}
+	if got := buildUserAgent(" ci-pipeline/42 "); got != "terraform-provider-turingpi/1.2.3 ci-pipeline/42" {
+		t.Errorf("unexpected User-Agent with suffix %q", got)
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, err := newRequestID()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := newRequestID()
+	if !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(a) {
+		t.Errorf("unexpected request ID format %q", a)
+	}
+	if a == b {
+		t.Error("expected unique request IDs")
+	}
+}
+
+func TestBMCTransport_PostMutations(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, fmt.Sprintf("%s %s?%s %s %s", r.Method, r.URL.Path, r.URL.RawQuery, body, r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &bmcTransport{postMutations: true}}
+
+	for _, query := range []string{"opt=set&type=power&node1=1", "opt=get&type=power"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/bmc?"+query, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	want := []string{
+		`POST /api/bmc? {"node1":"1","opt":"set","type":"power"} Bearer token`,
+		"GET /api/bmc?opt=get&type=power  Bearer token",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected requests %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUsePOSTMutations(t *testing.T) {
+	tests := []struct {
+		method, api string
+		want        bool
+	}{
+		{"auto", "1.1", false},
+		{"auto", "1.2", true},
+		{"auto", "", false},
+		{"get", "1.2", false},
+		{"post", "", true},
+	}
+	for _, tt := range tests {
+		if got := usePOSTMutations(tt.method, tt.api); got != tt.want {
+			t.Errorf("usePOSTMutations(%q, %q) = %v, want %v", tt.method, tt.api, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package provider
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// kernelArgsNodePlaceholder in kernel_args is replaced with the slot number,
+// so a config server can tell the nodes of one image apart
+const kernelArgsNodePlaceholder = "{node}"
+
+// kernelCmdlineFiles are the boot files holding the kernel command line,
+// looked up on every FAT partition of an image in this order
+var kernelCmdlineFiles = []string{
+	"cmdline.txt",                 // Raspberry Pi firmware (CM4)
+	"extlinux/extlinux.conf",      // U-Boot distro boot (RK1, Jetson)
+	"boot/extlinux/extlinux.conf", // U-Boot distro boot with /boot on the FAT partition
+	"armbianEnv.txt",              // Armbian
+	"boot/armbianEnv.txt",
+}
+
+// errNoKernelCmdline is returned for images without a supported boot file
+var errNoKernelCmdline = errors.New("no kernel command line file found on a FAT partition of the image " +
+	"(supported: " + strings.Join(kernelCmdlineFiles, ", ") + "); Talos images keep it on the XFS BOOT partition, " +
+	"so add extra kernel arguments with an Image Factory schematic instead")
+
+// imagePatch replaces the bytes of an image at offset with data
+type imagePatch struct {
+	offset int64
+	data   []byte
+}
+
+// kernelArgsForNode returns args with the node placeholder replaced
+func kernelArgsForNode(args []string, node int) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = strings.ReplaceAll(arg, kernelArgsNodePlaceholder, strconv.Itoa(node))
+	}
+	return out
+}
+
+// kernelArgsPatches returns the patches that append args to the kernel
+// command line of a raw disk image. The boot file is rewritten in the
+// clusters it already has, which leaves room for several hundred bytes of
+// arguments on any real boot partition.
+func kernelArgsPatches(image io.ReaderAt, args []string) (path string, patches []imagePatch, err error) {
+	partitions, err := readPartitionTable(image)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, start := range partitions {
+		vol, err := openFATVolume(image, start)
+		if err != nil {
+			continue
+		}
+		for _, name := range kernelCmdlineFiles {
+			file, err := vol.lookup(name)
+			if err != nil {
+				continue
+			}
+			content, err := vol.readFile(file)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			updated := appendKernelArgs(name, string(content), args)
+			patches, err := vol.rewriteFile(file, []byte(updated))
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to update %s: %w", name, err)
+			}
+			return name, patches, nil
+		}
+	}
+	return "", nil, errNoKernelCmdline
+}
+
+// appendKernelArgs appends args to the kernel command line in the content of
+// the boot file name
+func appendKernelArgs(name, content string, args []string) string {
+	extra := strings.Join(args, " ")
+	lines := strings.Split(strings.TrimRight(content, "\r\n"), "\n")
+	switch {
+	case strings.HasSuffix(name, "cmdline.txt"):
+		// A single line; the firmware ignores anything after the first newline
+		lines[0] = strings.TrimRight(lines[0], " \r") + " " + extra
+	case strings.HasSuffix(name, "extlinux.conf"):
+		for i, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) > 0 && strings.EqualFold(fields[0], "append") {
+				lines[i] = strings.TrimRight(line, " \r") + " " + extra
+			}
+		}
+	default:
+		found := false
+		for i, line := range lines {
+			if strings.HasPrefix(line, "extraargs=") {
+				lines[i] = strings.TrimRight(line, " \r") + " " + extra
+				found = true
+			}
+		}
+		if !found {
+			lines = append(lines, "extraargs="+extra)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// readPartitionTable returns the byte offsets of the partitions of an MBR or
+// GPT disk image
+func readPartitionTable(image io.ReaderAt) ([]int64, error) {
+	mbr := make([]byte, 512)
+	if _, err := image.ReadAt(mbr, 0); err != nil {
+		return nil, fmt.Errorf("failed to read partition table: %w", err)
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xAA {
+		return nil, fmt.Errorf("no partition table found; kernel_args requires an uncompressed disk image")
+	}
+
+	var starts []int64
+	for i := 0; i < 4; i++ {
+		entry := mbr[446+16*i : 446+16*(i+1)]
+		if entry[4] == 0xEE {
+			return readGPT(image)
+		}
+		if entry[4] != 0 {
+			starts = append(starts, int64(binary.LittleEndian.Uint32(entry[8:]))*512)
+		}
+	}
+	return starts, nil
+}
+
+func readGPT(image io.ReaderAt) ([]int64, error) {
+	header := make([]byte, 92)
+	if _, err := image.ReadAt(header, 512); err != nil {
+		return nil, fmt.Errorf("failed to read GPT header: %w", err)
+	}
+	if string(header[:8]) != "EFI PART" {
+		return nil, fmt.Errorf("invalid GPT header")
+	}
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	count := binary.LittleEndian.Uint32(header[80:])
+	size := binary.LittleEndian.Uint32(header[84:])
+	if size < 48 || count > 1024 {
+		return nil, fmt.Errorf("invalid GPT partition entries")
+	}
+
+	entries := make([]byte, int(count)*int(size))
+	if _, err := image.ReadAt(entries, entriesLBA*512); err != nil {
+		return nil, fmt.Errorf("failed to read GPT partition entries: %w", err)
+	}
+	var starts []int64
+	for i := 0; i < int(count); i++ {
+		entry := entries[i*int(size):]
+		if isZero(entry[:16]) {
+			continue
+		}
+		starts = append(starts, int64(binary.LittleEndian.Uint64(entry[32:]))*512)
+	}
+	return starts, nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fatVolume reads a FAT16 or FAT32 file system inside an image
+type fatVolume struct {
+	image       io.ReaderAt
+	fatOffset   int64 // Image offset of the first FAT
+	rootOffset  int64 // Image offset of the FAT16 root directory
+	rootSize    int64
+	dataOffset  int64 // Image offset of cluster 2
+	clusterSize int64
+	rootCluster uint32 // FAT32 root directory cluster, 0 on FAT16
+	fat32       bool
+}
+
+// fatFile is a file found in a FAT directory
+type fatFile struct {
+	entryOffset  int64 // Image offset of the short directory entry
+	firstCluster uint32
+	size         uint32
+	dir          bool
+}
+
+func openFATVolume(image io.ReaderAt, start int64) (*fatVolume, error) {
+	bs := make([]byte, 512)
+	if _, err := image.ReadAt(bs, start); err != nil {
+		return nil, err
+	}
+	if bs[510] != 0x55 || bs[511] != 0xAA {
+		return nil, fmt.Errorf("no FAT boot sector")
+	}
+	bytesPerSector := int64(binary.LittleEndian.Uint16(bs[11:]))
+	sectorsPerCluster := int64(bs[13])
+	reserved := int64(binary.LittleEndian.Uint16(bs[14:]))
+	numFATs := int64(bs[16])
+	rootEntries := int64(binary.LittleEndian.Uint16(bs[17:]))
+	totalSectors := int64(binary.LittleEndian.Uint16(bs[19:]))
+	fatSize := int64(binary.LittleEndian.Uint16(bs[22:]))
+	if totalSectors == 0 {
+		totalSectors = int64(binary.LittleEndian.Uint32(bs[32:]))
+	}
+	if fatSize == 0 {
+		fatSize = int64(binary.LittleEndian.Uint32(bs[36:]))
+	}
+	switch bytesPerSector {
+	case 512, 1024, 2048, 4096:
+	default:
+		return nil, fmt.Errorf("not a FAT file system")
+	}
+	if sectorsPerCluster == 0 || sectorsPerCluster&(sectorsPerCluster-1) != 0 || numFATs == 0 || fatSize == 0 {
+		return nil, fmt.Errorf("not a FAT file system")
+	}
+
+	rootSectors := (rootEntries*32 + bytesPerSector - 1) / bytesPerSector
+	dataSector := reserved + numFATs*fatSize + rootSectors
+	clusters := (totalSectors - dataSector) / sectorsPerCluster
+	if clusters < 4085 {
+		return nil, fmt.Errorf("FAT12 is not supported")
+	}
+
+	vol := &fatVolume{
+		image:       image,
+		fatOffset:   start + reserved*bytesPerSector,
+		rootOffset:  start + (reserved+numFATs*fatSize)*bytesPerSector,
+		rootSize:    rootEntries * 32,
+		dataOffset:  start + dataSector*bytesPerSector,
+		clusterSize: sectorsPerCluster * bytesPerSector,
+		fat32:       clusters >= 65525,
+	}
+	if vol.fat32 {
+		vol.rootCluster = binary.LittleEndian.Uint32(bs[44:])
+	}
+	return vol, nil
+}
+
+// chain returns the clusters of a file starting at first
+func (v *fatVolume) chain(first uint32) ([]uint32, error) {
+	var clusters []uint32
+	entry := make([]byte, 4)
+	for c := first; ; {
+		if c < 2 {
+			return nil, fmt.Errorf("invalid cluster %d", c)
+		}
+		clusters = append(clusters, c)
+		if len(clusters) > 1<<20 {
+			return nil, fmt.Errorf("cluster chain too long")
+		}
+		var next uint32
+		if v.fat32 {
+			if _, err := v.image.ReadAt(entry, v.fatOffset+int64(c)*4); err != nil {
+				return nil, err
+			}
+			next = binary.LittleEndian.Uint32(entry) & 0x0FFFFFFF
+			if next >= 0x0FFFFFF8 {
+				return clusters, nil
+			}
+		} else {
+			if _, err := v.image.ReadAt(entry[:2], v.fatOffset+int64(c)*2); err != nil {
+				return nil, err
+			}
+			next = uint32(binary.LittleEndian.Uint16(entry))
+			if next >= 0xFFF8 {
+				return clusters, nil
+			}
+		}
+		c = next
+	}
+}
+
+func (v *fatVolume) clusterOffset(c uint32) int64 {
+	return v.dataOffset + int64(c-2)*v.clusterSize
+}
+
+// dirRegions returns the image offsets and sizes holding a directory
+func (v *fatVolume) dirRegions(dir *fatFile) ([][2]int64, error) {
+	first := v.rootCluster
+	if dir != nil {
+		first = dir.firstCluster
+	} else if !v.fat32 {
+		return [][2]int64{{v.rootOffset, v.rootSize}}, nil
+	}
+	clusters, err := v.chain(first)
+	if err != nil {
+		return nil, err
+	}
+	regions := make([][2]int64, len(clusters))
+	for i, c := range clusters {
+		regions[i] = [2]int64{v.clusterOffset(c), v.clusterSize}
+	}
+	return regions, nil
+}
+
+// lookup finds a file by its slash-separated path, ignoring case
+func (v *fatVolume) lookup(path string) (*fatFile, error) {
+	var dir *fatFile
+	for _, name := range strings.Split(path, "/") {
+		file, err := v.find(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		dir = file
+	}
+	if dir.dir {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	return dir, nil
+}
+
+// find returns the entry called name in dir, or the root directory when dir
+// is nil. Long file names are matched as well as 8.3 names.
+func (v *fatVolume) find(dir *fatFile, name string) (*fatFile, error) {
+	regions, err := v.dirRegions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var long []uint16
+	for _, region := range regions {
+		buf := make([]byte, region[1])
+		if _, err := v.image.ReadAt(buf, region[0]); err != nil {
+			return nil, err
+		}
+		for off := 0; off+32 <= len(buf); off += 32 {
+			entry := buf[off : off+32]
+			switch {
+			case entry[0] == 0x00:
+				return nil, fmt.Errorf("%s not found", name)
+			case entry[0] == 0xE5:
+				long = nil
+				continue
+			case entry[11] == 0x0F:
+				// Long name entries precede their short entry, last part first
+				part := make([]uint16, 0, 13)
+				for _, i := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+					part = append(part, binary.LittleEndian.Uint16(entry[i:]))
+				}
+				if entry[0]&0x40 != 0 {
+					long = nil
+				}
+				long = append(part, long...)
+				continue
+			case entry[11]&0x08 != 0:
+				long = nil
+				continue
+			}
+
+			entryName := shortEntryName(entry)
+			if long != nil {
+				entryName = longEntryName(long)
+			}
+			long = nil
+			if !strings.EqualFold(entryName, name) {
+				continue
+			}
+			return &fatFile{
+				entryOffset:  region[0] + int64(off),
+				firstCluster: uint32(binary.LittleEndian.Uint16(entry[20:]))<<16 | uint32(binary.LittleEndian.Uint16(entry[26:])),
+				size:         binary.LittleEndian.Uint32(entry[28:]),
+				dir:          entry[11]&0x10 != 0,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+func shortEntryName(entry []byte) string {
+	base := strings.TrimRight(string(entry[:8]), " ")
+	ext := strings.TrimRight(string(entry[8:11]), " ")
+	if ext == "" {
+		return base
+	}
+	return base + "." + ext
+}
+
+func longEntryName(chars []uint16) string {
+	for i, c := range chars {
+		if c == 0 || c == 0xFFFF {
+			chars = chars[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(chars))
+}
+
+func (v *fatVolume) readFile(file *fatFile) ([]byte, error) {
+	if file.size == 0 {
+		return nil, nil
+	}
+	clusters, err := v.chain(file.firstCluster)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, 0, len(clusters)*int(v.clusterSize))
+	buf := make([]byte, v.clusterSize)
+	for _, c := range clusters {
+		if _, err := v.image.ReadAt(buf, v.clusterOffset(c)); err != nil {
+			return nil, err
+		}
+		content = append(content, buf...)
+	}
+	if int64(len(content)) < int64(file.size) {
+		return nil, fmt.Errorf("file is larger than its clusters")
+	}
+	return content[:file.size], nil
+}
+
+// rewriteFile returns the patches that replace the content of file. The file
+// keeps its clusters, so the new content must fit in them.
+func (v *fatVolume) rewriteFile(file *fatFile, content []byte) ([]imagePatch, error) {
+	if file.size == 0 {
+		return nil, fmt.Errorf("empty files are not supported")
+	}
+	clusters, err := v.chain(file.firstCluster)
+	if err != nil {
+		return nil, err
+	}
+	if capacity := int64(len(clusters)) * v.clusterSize; int64(len(content)) > capacity {
+		return nil, fmt.Errorf("%d bytes do not fit in the %d bytes allocated to the file", len(content), capacity)
+	}
+
+	var patches []imagePatch
+	for i, c := range clusters {
+		data := make([]byte, v.clusterSize)
+		if start := int64(i) * v.clusterSize; start < int64(len(content)) {
+			copy(data, content[start:])
+		}
+		patches = append(patches, imagePatch{offset: v.clusterOffset(c), data: data})
+	}
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(content)))
+	return append(patches, imagePatch{offset: file.entryOffset + 28, data: size}), nil
+}
+
+// patchedReader applies patches to an image while it is read sequentially
+type patchedReader struct {
+	r       io.Reader
+	offset  int64
+	patches []imagePatch
+}
+
+func newPatchedReader(r io.Reader, patches []imagePatch) io.Reader {
+	if len(patches) == 0 {
+		return r
+	}
+	return &patchedReader{r: r, patches: patches}
+}
+
+func (p *patchedReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	start, end := p.offset, p.offset+int64(n)
+	for _, patch := range p.patches {
+		patchEnd := patch.offset + int64(len(patch.data))
+		if patchEnd <= start || patch.offset >= end {
+			continue
+		}
+		from := max(patch.offset, start)
+		to := min(patchEnd, end)
+		copy(buf[from-start:to-start], patch.data[from-patch.offset:to-patch.offset])
+	}
+	p.offset = end
+	return n, err
+}
@@ -0,0 +1,256 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+// Layout of the FAT16 test volume: 512-byte sectors and clusters, two FATs
+// of 17 sectors and a 512-entry root directory
+const (
+	testFATStart    = 8 * 512 // Partition offset in the image
+	testFATSectors  = 4267
+	testFATSize     = 17
+	testFATDataSec  = 1 + 2*testFATSize + 32
+	testFATClusters = testFATSectors - testFATDataSec
+)
+
+// testFATImage builds a disk image with an MBR and one FAT16 partition
+type testFATImage struct {
+	buf         []byte
+	nextCluster uint32
+	rootUsed    int
+	dirUsed     map[uint32]int
+}
+
+func newTestFATImage(gpt bool) *testFATImage {
+	img := &testFATImage{
+		buf:         make([]byte, testFATStart+testFATSectors*512),
+		nextCluster: 2,
+		dirUsed:     map[uint32]int{},
+	}
+	mbr := img.buf[:512]
+	mbr[510], mbr[511] = 0x55, 0xAA
+	if gpt {
+		mbr[446+4] = 0xEE
+		header := img.buf[512:]
+		copy(header, "EFI PART")
+		binary.LittleEndian.PutUint64(header[72:], 2)
+		binary.LittleEndian.PutUint32(header[80:], 4)
+		binary.LittleEndian.PutUint32(header[84:], 128)
+		entry := img.buf[1024:]
+		entry[0] = 0xAF // Any non-zero type GUID
+		binary.LittleEndian.PutUint64(entry[32:], testFATStart/512)
+	} else {
+		mbr[446+4] = 0x0E
+		binary.LittleEndian.PutUint32(mbr[446+8:], testFATStart/512)
+	}
+
+	bs := img.buf[testFATStart:]
+	binary.LittleEndian.PutUint16(bs[11:], 512)
+	bs[13] = 1
+	binary.LittleEndian.PutUint16(bs[14:], 1)
+	bs[16] = 2
+	binary.LittleEndian.PutUint16(bs[17:], 512)
+	binary.LittleEndian.PutUint16(bs[19:], testFATSectors)
+	binary.LittleEndian.PutUint16(bs[22:], testFATSize)
+	bs[510], bs[511] = 0x55, 0xAA
+	return img
+}
+
+func (img *testFATImage) clusterAt(c uint32) []byte {
+	off := testFATStart + (testFATDataSec+int(c)-2)*512
+	return img.buf[off : off+512]
+}
+
+// allocate chains n new clusters in the FAT
+func (img *testFATImage) allocate(n int) uint32 {
+	first := img.nextCluster
+	fat := img.buf[testFATStart+512:]
+	for i := 0; i < n; i++ {
+		c := img.nextCluster
+		next := uint16(c + 1)
+		if i == n-1 {
+			next = 0xFFFF
+		}
+		binary.LittleEndian.PutUint16(fat[c*2:], next)
+		img.nextCluster++
+	}
+	return first
+}
+
+// addEntry writes a directory entry, with long name entries when name is not
+// a valid 8.3 name, into the root directory or directory cluster parent
+func (img *testFATImage) addEntry(parent uint32, name, short string, attr byte, first uint32, size int) {
+	var entries [][]byte
+	if !strings.EqualFold(shortEntryName([]byte(short)), name) {
+		chars := utf16.Encode([]rune(name))
+		chars = append(chars, 0)
+		for len(chars)%13 != 0 {
+			chars = append(chars, 0xFFFF)
+		}
+		parts := len(chars) / 13
+		for seq := parts; seq >= 1; seq-- {
+			entry := make([]byte, 32)
+			entry[0] = byte(seq)
+			if seq == parts {
+				entry[0] |= 0x40
+			}
+			entry[11] = 0x0F
+			for i, off := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+				binary.LittleEndian.PutUint16(entry[off:], chars[(seq-1)*13+i])
+			}
+			entries = append(entries, entry)
+		}
+	}
+	entry := make([]byte, 32)
+	copy(entry, short)
+	entry[11] = attr
+	binary.LittleEndian.PutUint16(entry[20:], uint16(first>>16))
+	binary.LittleEndian.PutUint16(entry[26:], uint16(first))
+	binary.LittleEndian.PutUint32(entry[28:], uint32(size))
+	entries = append(entries, entry)
+
+	for _, e := range entries {
+		if parent == 0 {
+			off := testFATStart + (1+2*testFATSize)*512 + img.rootUsed
+			copy(img.buf[off:], e)
+			img.rootUsed += 32
+		} else {
+			copy(img.clusterAt(parent)[img.dirUsed[parent]:], e)
+			img.dirUsed[parent] += 32
+		}
+	}
+}
+
+func (img *testFATImage) addDir(parent uint32, name, short string) uint32 {
+	c := img.allocate(1)
+	img.addEntry(parent, name, short, 0x10, c, 0)
+	return c
+}
+
+func (img *testFATImage) addFile(parent uint32, name, short, content string, clusters int) {
+	c := img.allocate(clusters)
+	for i := 0; i < clusters; i++ {
+		if start := i * 512; start < len(content) {
+			copy(img.clusterAt(c+uint32(i)), content[start:])
+		}
+	}
+	img.addEntry(parent, name, short, 0x20, c, len(content))
+}
+
+// patchedImage flashes the image through patchedReader, one byte at a time
+func patchedImage(t *testing.T, image []byte, patches []imagePatch) []byte {
+	t.Helper()
+	out, err := io.ReadAll(newPatchedReader(iotest.OneByteReader(bytes.NewReader(image)), patches))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// readImageFile reads path from the FAT partition of an image
+func readImageFile(t *testing.T, image []byte, path string) string {
+	t.Helper()
+	vol, err := openFATVolume(bytes.NewReader(image), testFATStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := vol.lookup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := vol.readFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestKernelArgsPatches_Cmdline(t *testing.T) {
+	img := newTestFATImage(false)
+	img.addFile(0, "config.txt", "CONFIG  TXT", "arm_64bit=1\n", 1)
+	img.addFile(0, "cmdline.txt", "CMDLINE TXT", "console=serial0,115200 root=PARTUUID=abc rootwait\n", 1)
+
+	args := kernelArgsForNode([]string{"talos.config=http://10.10.88.5/config?node={node}", "console=ttyS2,115200"}, 3)
+	path, patches, err := kernelArgsPatches(bytes.NewReader(img.buf), args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "cmdline.txt" {
+		t.Errorf("expected cmdline.txt, got %s", path)
+	}
+
+	out := patchedImage(t, img.buf, patches)
+	want := "console=serial0,115200 root=PARTUUID=abc rootwait talos.config=http://10.10.88.5/config?node=3 console=ttyS2,115200\n"
+	if got := readImageFile(t, out, "cmdline.txt"); got != want {
+		t.Errorf("unexpected cmdline:\n%q\nwant:\n%q", got, want)
+	}
+	if got := readImageFile(t, out, "config.txt"); got != "arm_64bit=1\n" {
+		t.Errorf("expected other files unchanged, got %q", got)
+	}
+	if len(out) != len(img.buf) {
+		t.Errorf("expected the image size to be kept, got %d bytes", len(out))
+	}
+}
+
+func TestKernelArgsPatches_ExtlinuxGPT(t *testing.T) {
+	img := newTestFATImage(true)
+	dir := img.addDir(0, "extlinux", "EXTLINUX   ")
+	conf := "default l0\nlabel l0\n  kernel /Image\n  append root=LABEL=rootfs rw\nlabel l1\n  APPEND root=LABEL=recovery\n"
+	// Spans two clusters, so the rewrite follows the chain
+	conf += "# " + strings.Repeat("x", 600) + "\n"
+	img.addFile(dir, "extlinux.conf", "EXTLIN~1CON", conf, 2)
+
+	_, patches, err := kernelArgsPatches(bytes.NewReader(img.buf), []string{"net.ifnames=0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := readImageFile(t, patchedImage(t, img.buf, patches), "extlinux/extlinux.conf")
+	for _, line := range []string{"  append root=LABEL=rootfs rw net.ifnames=0\n", "  APPEND root=LABEL=recovery net.ifnames=0\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("expected %q in:\n%s", line, got)
+		}
+	}
+	if !strings.HasSuffix(got, strings.Repeat("x", 600)+"\n") {
+		t.Error("expected the rest of the file to be kept")
+	}
+}
+
+func TestKernelArgsPatches_Errors(t *testing.T) {
+	img := newTestFATImage(false)
+	img.addFile(0, "config.txt", "CONFIG  TXT", "arm_64bit=1\n", 1)
+	if _, _, err := kernelArgsPatches(bytes.NewReader(img.buf), []string{"quiet"}); !errors.Is(err, errNoKernelCmdline) {
+		t.Errorf("expected errNoKernelCmdline, got %v", err)
+	}
+
+	img.addFile(0, "cmdline.txt", "CMDLINE TXT", "root=/dev/mmcblk0p2\n", 1)
+	_, _, err := kernelArgsPatches(bytes.NewReader(img.buf), []string{strings.Repeat("a", 600)})
+	if err == nil || !strings.Contains(err.Error(), "do not fit") {
+		t.Errorf("expected a size error, got %v", err)
+	}
+
+	if _, _, err := kernelArgsPatches(bytes.NewReader([]byte(strings.Repeat("\xfd7zXZ", 200))), []string{"quiet"}); err == nil || !strings.Contains(err.Error(), "uncompressed") {
+		t.Errorf("expected an uncompressed image error, got %v", err)
+	}
+}
+
+func TestAppendKernelArgs_Armbian(t *testing.T) {
+	tests := []struct {
+		content, want string
+	}{
+		{"verbosity=1\nextraargs=cma=128M\n", "verbosity=1\nextraargs=cma=128M quiet\n"},
+		{"verbosity=1\n", "verbosity=1\nextraargs=quiet\n"},
+	}
+	for _, tt := range tests {
+		if got := appendKernelArgs("armbianEnv.txt", tt.content, []string{"quiet"}); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
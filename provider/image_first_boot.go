@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cloud-init NoCloud files on the boot partition of Ubuntu images for the
+// CM4 and RK1, read by cloud-init on first boot
+const (
+	firstBootUserData      = "user-data"
+	firstBootNetworkConfig = "network-config"
+)
+
+// errNoCloudInitSeed is returned for images without NoCloud files
+var errNoCloudInitSeed = errors.New("no cloud-init " + firstBootUserData + " file found on a FAT partition of the image; " +
+	"first_boot requires an image with a NoCloud seed on its boot partition, such as the Ubuntu images for the CM4 and RK1")
+
+// firstBootConfig is the first_boot block of turingpi_flash
+type firstBootConfig struct {
+	Hostname          string
+	SSHAuthorizedKeys []string
+	// NetworkConfig is a netplan (network config version 2) document
+	NetworkConfig string
+}
+
+// forNode returns the config with the node placeholder replaced
+func (c firstBootConfig) forNode(node int) firstBootConfig {
+	slot := strconv.Itoa(node)
+	c.Hostname = strings.ReplaceAll(c.Hostname, kernelArgsNodePlaceholder, slot)
+	c.NetworkConfig = strings.ReplaceAll(c.NetworkConfig, kernelArgsNodePlaceholder, slot)
+	return c
+}
+
+// files returns the NoCloud files to write, by name
+func (c firstBootConfig) files() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if c.Hostname != "" || len(c.SSHAuthorizedKeys) > 0 {
+		userData := struct {
+			Hostname          string   `yaml:"hostname,omitempty"`
+			PreserveHostname  bool     `yaml:"preserve_hostname"`
+			SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys,omitempty"`
+		}{c.Hostname, false, c.SSHAuthorizedKeys}
+		out, err := yaml.Marshal(userData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", firstBootUserData, err)
+		}
+		files[firstBootUserData] = append([]byte("#cloud-config\n"), out...)
+	}
+	if c.NetworkConfig != "" {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(c.NetworkConfig), &doc); err != nil {
+			return nil, fmt.Errorf("network_config is not valid YAML: %w", err)
+		}
+		files[firstBootNetworkConfig] = []byte(strings.TrimRight(c.NetworkConfig, "\n") + "\n")
+	}
+	return files, nil
+}
+
+// firstBootPatches returns the patches that replace the NoCloud files of a
+// raw disk image with files. Like kernel_args, each file is rewritten in the
+// clusters it already has.
+func firstBootPatches(image io.ReaderAt, files map[string][]byte) ([]imagePatch, error) {
+	partitions, err := readPartitionTable(image)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, start := range partitions {
+		vol, err := openFATVolume(image, start)
+		if err != nil {
+			continue
+		}
+		if _, err := vol.lookup(firstBootUserData); err != nil {
+			continue
+		}
+		var patches []imagePatch
+		for _, name := range names {
+			file, err := vol.lookup(name)
+			if err != nil {
+				return nil, fmt.Errorf("no %s file on the boot partition of the image", name)
+			}
+			filePatches, err := vol.rewriteFile(file, files[name])
+			if err != nil {
+				return nil, fmt.Errorf("failed to update %s: %w", name, err)
+			}
+			patches = append(patches, filePatches...)
+		}
+		return patches, nil
+	}
+	return nil, errNoCloudInitSeed
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestSeedImage returns an image with the NoCloud files of an Ubuntu
+// preinstalled server image
+func newTestSeedImage() *testFATImage {
+	img := newTestFATImage(false)
+	img.addFile(0, "cmdline.txt", "CMDLINE TXT", "root=LABEL=writable rootwait ds=nocloud;s=file:///boot/firmware\n", 1)
+	img.addFile(0, "meta-data", "META-D~1   ", "instance-id: ubuntu\n", 1)
+	img.addFile(0, "user-data", "USER-D~1   ", "#cloud-config\nchpasswd:\n  expire: true\n", 2)
+	img.addFile(0, "network-config", "NETWOR~1   ", "network:\n  version: 2\n", 1)
+	return img
+}
+
+func TestFirstBootPatches(t *testing.T) {
+	img := newTestSeedImage()
+	cfg := firstBootConfig{
+		Hostname:          "turing-{node}",
+		SSHAuthorizedKeys: []string{"ssh-ed25519 AAAAC3Nza admin@host"},
+		NetworkConfig:     "network:\n  version: 2\n  ethernets:\n    eth0:\n      addresses: [10.10.88.7{node}/24]\n",
+	}
+	files, err := cfg.forNode(3).files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	patches, err := firstBootPatches(bytes.NewReader(img.buf), files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := patchedImage(t, img.buf, patches)
+	userData := readImageFile(t, out, "user-data")
+	for _, want := range []string{"#cloud-config\n", "hostname: turing-3\n", "preserve_hostname: false\n", "- ssh-ed25519 AAAAC3Nza admin@host\n"} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("expected %q in user-data:\n%s", want, userData)
+		}
+	}
+	if got := readImageFile(t, out, "network-config"); !strings.Contains(got, "addresses: [10.10.88.73/24]") {
+		t.Errorf("unexpected network-config:\n%s", got)
+	}
+	if got := readImageFile(t, out, "meta-data"); got != "instance-id: ubuntu\n" {
+		t.Errorf("expected meta-data unchanged, got %q", got)
+	}
+}
+
+func TestFirstBootPatches_Errors(t *testing.T) {
+	img := newTestFATImage(false)
+	img.addFile(0, "cmdline.txt", "CMDLINE TXT", "root=/dev/mmcblk0p2\n", 1)
+	files := map[string][]byte{firstBootUserData: []byte("#cloud-config\n")}
+	if _, err := firstBootPatches(bytes.NewReader(img.buf), files); !errors.Is(err, errNoCloudInitSeed) {
+		t.Errorf("expected errNoCloudInitSeed, got %v", err)
+	}
+
+	img = newTestFATImage(false)
+	img.addFile(0, "user-data", "USER-D~1   ", "#cloud-config\n", 1)
+	files[firstBootNetworkConfig] = []byte("network:\n  version: 2\n")
+	if _, err := firstBootPatches(bytes.NewReader(img.buf), files); err == nil || !strings.Contains(err.Error(), "no network-config") {
+		t.Errorf("expected a missing network-config error, got %v", err)
+	}
+
+	if _, err := (firstBootConfig{NetworkConfig: "network: [\n"}).files(); err == nil || !strings.Contains(err.Error(), "not valid YAML") {
+		t.Errorf("expected a YAML error, got %v", err)
+	}
+}
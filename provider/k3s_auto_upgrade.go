@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// defaultSystemUpgradeControllerVersion is the system-upgrade-controller
+// release deployed when auto_upgrade sets no controller_version
+const defaultSystemUpgradeControllerVersion = "v0.14.2"
+
+// systemUpgradeControllerURL is the release download URL of the
+// system-upgrade-controller manifests, formatted with the version and file
+// Replaced in tests to point at a local server
+var systemUpgradeControllerURL = "https://github.com/rancher/system-upgrade-controller/releases/download/%s/%s"
+
+// Names of the system-upgrade-controller objects and the upgrade Plans
+const (
+	systemUpgradeNamespace = "system-upgrade"
+	systemUpgradePlanCRD   = "plans.upgrade.cattle.io"
+	k3sServerPlanName      = "k3s-server"
+	k3sAgentPlanName       = "k3s-agent"
+)
+
+// k3sAutoUpgrade is the auto_upgrade block of a K3s cluster
+type k3sAutoUpgrade struct {
+	Channel           string
+	Concurrency       int
+	ControllerVersion string
+}
+
+func autoUpgradeSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Deploy system-upgrade-controller and the upgrade Plans. Setting this to false on an existing cluster deletes the Plans and leaves the controller in place.",
+			},
+			"channel": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "stable",
+				Description: "K3s release channel the Plans follow: stable, latest, testing, a minor version channel such as v1.31, or a full channel URL",
+			},
+			"concurrency": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				Description:  "Number of agent nodes upgraded at the same time. Servers are always upgraded one at a time.",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"controller_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     defaultSystemUpgradeControllerVersion,
+				Description: "system-upgrade-controller release to deploy",
+			},
+		},
+	}
+}
+
+// extractK3sAutoUpgrade returns the auto_upgrade settings, or nil when the
+// block is absent or disabled
+func extractK3sAutoUpgrade(d *schema.ResourceData) *k3sAutoUpgrade {
+	v, ok := d.GetOk("auto_upgrade")
+	if !ok {
+		return nil
+	}
+	list := v.([]interface{})
+	if len(list) == 0 || list[0] == nil {
+		return nil
+	}
+	block := list[0].(map[string]interface{})
+	if !block["enabled"].(bool) {
+		return nil
+	}
+	return &k3sAutoUpgrade{
+		Channel:           block["channel"].(string),
+		Concurrency:       block["concurrency"].(int),
+		ControllerVersion: block["controller_version"].(string),
+	}
+}
+
+// k3sChannelURL returns the channel server URL of a channel name. Full URLs
+// are returned unchanged.
+func k3sChannelURL(channel string) string {
+	if strings.Contains(channel, "://") {
+		return channel
+	}
+	return strings.TrimSuffix(k3sChannelsURL, "/") + "/" + channel
+}
+
+// renderK3sUpgradePlans renders the server and agent Plans. Servers are
+// upgraded one at a time and cordoned; agents wait for the server Plan to
+// finish and are drained before the upgrade.
+func renderK3sUpgradePlans(cfg *k3sAutoUpgrade) string {
+	channel := k3sChannelURL(cfg.Channel)
+	return fmt.Sprintf(`apiVersion: upgrade.cattle.io/v1
+kind: Plan
+metadata:
+  name: %[1]s
+  namespace: %[3]s
+spec:
+  concurrency: 1
+  cordon: true
+  nodeSelector:
+    matchExpressions:
+    - key: node-role.kubernetes.io/control-plane
+      operator: In
+      values: ["true"]
+  serviceAccountName: system-upgrade
+  upgrade:
+    image: rancher/k3s-upgrade
+  channel: %[4]q
+---
+apiVersion: upgrade.cattle.io/v1
+kind: Plan
+metadata:
+  name: %[2]s
+  namespace: %[3]s
+spec:
+  concurrency: %[5]d
+  nodeSelector:
+    matchExpressions:
+    - key: node-role.kubernetes.io/control-plane
+      operator: DoesNotExist
+  prepare:
+    image: rancher/k3s-upgrade
+    args: ["prepare", %[1]q]
+  drain:
+    force: true
+    skipWaitForDeleteTimeout: 60
+  serviceAccountName: system-upgrade
+  upgrade:
+    image: rancher/k3s-upgrade
+  channel: %[4]q
+`, k3sServerPlanName, k3sAgentPlanName, systemUpgradeNamespace, channel, cfg.Concurrency)
+}
+
+// fetchSystemUpgradeControllerManifest downloads a manifest of a
+// system-upgrade-controller release (crd.yaml or system-upgrade-controller.yaml)
+func fetchSystemUpgradeControllerManifest(ctx context.Context, version, file string) (string, error) {
+	url := fmt.Sprintf(systemUpgradeControllerURL, version, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := releaseHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// deployK3sAutoUpgrade installs system-upgrade-controller and applies the
+// upgrade Plans. The Plan CRD must be established before the Plans are
+// applied, so it is polled until the API server serves it.
+func deployK3sAutoUpgrade(ctx context.Context, kubeconfig []byte, cfg *k3sAutoUpgrade, timeout time.Duration) error {
+	client, err := NewK8sClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	for _, file := range []string{"crd.yaml", "system-upgrade-controller.yaml"} {
+		manifest, err := fetchSystemUpgradeControllerManifest(ctx, cfg.ControllerVersion, file)
+		if err != nil {
+			return fmt.Errorf("failed to download system-upgrade-controller %s: %w", cfg.ControllerVersion, err)
+		}
+		if err := client.ApplyManifest(ctx, manifest); err != nil {
+			return fmt.Errorf("failed to apply system-upgrade-controller %s: %w", file, err)
+		}
+	}
+
+	err = poll(ctx, 2*time.Second, timeout, "the "+systemUpgradePlanCRD+" CRD", func(ctx context.Context) (bool, error) {
+		return client.CRDExists(ctx, systemUpgradePlanCRD)
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for the %s CRD: %w", systemUpgradePlanCRD, err)
+	}
+
+	// A new client discovers the Plan kind, which the first one has cached as missing
+	plansClient, err := NewK8sClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = plansClient.Close() }()
+	return applyK3sUpgradePlans(ctx, plansClient, cfg)
+}
+
+// applyK3sUpgradePlans applies the server and agent Plans
+func applyK3sUpgradePlans(ctx context.Context, client *K8sClient, cfg *k3sAutoUpgrade) error {
+	if err := client.ApplyManifest(ctx, renderK3sUpgradePlans(cfg)); err != nil {
+		return fmt.Errorf("failed to apply upgrade Plans: %w", err)
+	}
+	return nil
+}
+
+// deleteK3sUpgradePlans deletes the server and agent Plans, stopping further
+// upgrades. The controller is left running.
+func deleteK3sUpgradePlans(ctx context.Context, kubeconfig []byte) error {
+	client, err := NewK8sClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	// The channel does not matter for deletion
+	if err := client.DeleteManifest(ctx, renderK3sUpgradePlans(&k3sAutoUpgrade{Channel: "stable", Concurrency: 1})); err != nil {
+		return fmt.Errorf("failed to delete upgrade Plans: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestExtractK3sAutoUpgrade(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":         "test",
+		"auto_upgrade": []interface{}{map[string]interface{}{"channel": "v1.31"}},
+	})
+	upgrade := extractK3sAutoUpgrade(d)
+	if upgrade == nil {
+		t.Fatal("expected auto_upgrade settings")
+	}
+	if upgrade.Channel != "v1.31" || upgrade.Concurrency != 1 || upgrade.ControllerVersion != defaultSystemUpgradeControllerVersion {
+		t.Errorf("unexpected settings %+v", upgrade)
+	}
+
+	d = schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":         "test",
+		"auto_upgrade": []interface{}{map[string]interface{}{"enabled": false}},
+	})
+	if extractK3sAutoUpgrade(d) != nil {
+		t.Error("expected disabled auto_upgrade to be ignored")
+	}
+
+	d = schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{"name": "test"})
+	if extractK3sAutoUpgrade(d) != nil {
+		t.Error("expected no settings without auto_upgrade")
+	}
+}
+
+func TestRenderK3sUpgradePlans(t *testing.T) {
+	plans := renderK3sUpgradePlans(&k3sAutoUpgrade{Channel: "stable", Concurrency: 2})
+
+	objects, err := decodeManifest(plans)
+	if err != nil {
+		t.Fatalf("plans do not decode: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 Plans, got %d", len(objects))
+	}
+	for _, obj := range objects {
+		if obj.GetKind() != "Plan" || obj.GetNamespace() != systemUpgradeNamespace {
+			t.Errorf("unexpected object %s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		}
+		channel, _, _ := unstructured.NestedString(obj.Object, "spec", "channel")
+		if channel != "https://update.k3s.io/v1-release/channels/stable" {
+			t.Errorf("unexpected channel %q in %s", channel, obj.GetName())
+		}
+	}
+
+	if concurrency, _, _ := unstructured.NestedFieldNoCopy(objects[1].Object, "spec", "concurrency"); concurrency != float64(2) {
+		t.Errorf("expected agent concurrency 2, got %v", concurrency)
+	}
+	prepare, _, _ := unstructured.NestedStringSlice(objects[1].Object, "spec", "prepare", "args")
+	if len(prepare) != 2 || prepare[1] != k3sServerPlanName {
+		t.Errorf("expected agents to wait for the server Plan, got %v", prepare)
+	}
+}
+
+func TestK3sChannelURL(t *testing.T) {
+	if got := k3sChannelURL("latest"); got != "https://update.k3s.io/v1-release/channels/latest" {
+		t.Errorf("unexpected channel URL %q", got)
+	}
+	custom := "https://channels.lan/v1-release/channels/stable"
+	if got := k3sChannelURL(custom); got != custom {
+		t.Errorf("expected full URL to be kept, got %q", got)
+	}
+}
+
+func TestFetchSystemUpgradeControllerManifest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v0.14.2/crd.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("kind: CustomResourceDefinition\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	orig := systemUpgradeControllerURL
+	systemUpgradeControllerURL = server.URL + "/%s/%s"
+	t.Cleanup(func() { systemUpgradeControllerURL = orig })
+
+	manifest, err := fetchSystemUpgradeControllerManifest(context.Background(), "v0.14.2", "crd.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(manifest, "CustomResourceDefinition") {
+		t.Errorf("unexpected manifest %q", manifest)
+	}
+
+	if _, err := fetchSystemUpgradeControllerManifest(context.Background(), "v0.0.0", "crd.yaml"); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("expected HTTP 404 error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// bmcPowerCycleOffTime is how long a slot stays off when an unreachable
+// worker is power-cycled. Replaced in tests to keep them fast.
+var bmcPowerCycleOffTime = 5 * time.Second
+
+func bmcManagedNodesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeMap,
+		Optional: true,
+		Description: "Map of worker host to the Turing Pi slot (1-4) it runs in, on the board of this provider's BMC. " +
+			"A worker that cannot be reached over SSH when it is joined during an update, including by auto_repair, " +
+			"is power-cycled through the BMC and joined again once it answers SSH.",
+		Elem:             &schema.Schema{Type: schema.TypeInt},
+		ValidateDiagFunc: validation.ToDiagFunc(validateBMCManagedNodes),
+	}
+}
+
+func validateBMCManagedNodes(v interface{}, k string) ([]string, []error) {
+	var errs []error
+	for host, slot := range v.(map[string]interface{}) {
+		if n, ok := slot.(int); !ok || n < 1 || n > 4 {
+			errs = append(errs, fmt.Errorf("%s: slot of %s must be between 1 and 4, got %v", k, host, slot))
+		}
+	}
+	return nil, errs
+}
+
+// bmcManagedSlot returns the slot bmc_managed_nodes maps worker to
+func bmcManagedSlot(d *schema.ResourceData, worker NodeConfig) (int, bool) {
+	slot, ok := d.Get("bmc_managed_nodes").(map[string]interface{})[worker.Host].(int)
+	return slot, ok
+}
+
+// joinWithPowerCycle runs join for worker. When it fails because the worker
+// does not answer SSH at the network level and bmc_managed_nodes maps it to a
+// slot, the slot is powered off and on through the BMC and join is run once
+// more after the worker answers SSH again, within timeout. A hung node is the
+// usual cause on a Turing Pi, and nothing but the BMC can bring it back.
+func joinWithPowerCycle(ctx context.Context, d *schema.ResourceData, meta interface{}, p *K3sProvisioner, worker NodeConfig, timeout time.Duration, join func() error) error {
+	err := join()
+	if err == nil || !isSSHUnreachableError(err) {
+		return err
+	}
+	slot, ok := bmcManagedSlot(d, worker)
+	config, isConfig := meta.(*ProviderConfig)
+	if !ok || !isConfig || config.DryRun {
+		return err
+	}
+
+	tflog.Warn(ctx, "Worker unreachable, power-cycling its slot through the BMC", map[string]interface{}{
+		"host":  worker.Host,
+		"slot":  slot,
+		"error": err.Error(),
+	})
+	if cycleErr := powerCycleSlot(ctx, config, slot); cycleErr != nil {
+		return fmt.Errorf("%w (power cycle of slot %d failed: %v)", err, slot, cycleErr)
+	}
+	waitErr := poll(ctx, pollInterval, timeout, "SSH on "+worker.Host, func(context.Context) (bool, error) {
+		client := p.clientFactory()
+		if err := client.Connect(worker.Host, worker.SSHPort, worker.getSSHConfig()); err != nil {
+			return false, err
+		}
+		_ = client.Close()
+		return true, nil
+	})
+	if waitErr != nil {
+		return fmt.Errorf("worker %s did not come back after a power cycle of slot %d: %w", worker.Host, slot, waitErr)
+	}
+	tflog.Info(ctx, "Worker is back after a power cycle, joining it again", map[string]interface{}{
+		"host": worker.Host,
+		"slot": slot,
+	})
+	return join()
+}
+
+// powerCycleSlot powers slot off and on through the BMC
+func powerCycleSlot(ctx context.Context, config *ProviderConfig, slot int) error {
+	if err := setNodePower(config.Endpoint, config.Token, slot, false); err != nil {
+		return fmt.Errorf("failed to power off: %w", err)
+	}
+	if err := sleepContext(ctx, bmcPowerCycleOffTime); err != nil {
+		return err
+	}
+	if err := setNodePower(config.Endpoint, config.Token, slot, true); err != nil {
+		return fmt.Errorf("failed to power on: %w", err)
+	}
+	return nil
+}
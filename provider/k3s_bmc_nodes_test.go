@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestJoinWithPowerCycle(t *testing.T) {
+	oldOff := bmcPowerCycleOffTime
+	bmcPowerCycleOffTime = 0
+	t.Cleanup(func() { bmcPowerCycleOffTime = oldOff })
+
+	var power []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		power = append(power, r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	config := &ProviderConfig{Endpoint: server.URL, Token: "test-token"}
+
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":              "test",
+		"control_plane":     []interface{}{map[string]interface{}{"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret"}},
+		"bmc_managed_nodes": map[string]interface{}{"10.10.88.75": 3},
+	})
+	p := NewK3sProvisionerWithClientFactory(func() SSHClient { return &MockSSHClient{} })
+	unreachable := &DialError{Addr: "10.10.88.75:22", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.EHOSTUNREACH}}
+
+	// A hung worker in a managed slot is power-cycled and joined again
+	calls := 0
+	err := joinWithPowerCycle(context.Background(), d, config, p, NodeConfig{Host: "10.10.88.75", SSHPort: 22}, time.Minute, func() error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("install failed: %w", unreachable)
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("expected the join to be retried after a power cycle, got %v after %d calls", err, calls)
+	}
+	if got := strings.Join(power, " "); got != "opt=set&type=power&node3=0 opt=set&type=power&node3=1" {
+		t.Errorf("expected slot 3 to be powered off and on, got %s", got)
+	}
+
+	// Unmapped workers and other failures are returned as they are
+	power, calls = nil, 0
+	err = joinWithPowerCycle(context.Background(), d, config, p, NodeConfig{Host: "10.10.88.76", SSHPort: 22}, time.Minute, func() error {
+		calls++
+		return unreachable
+	})
+	if !errors.Is(err, unreachable) || calls != 1 || len(power) != 0 {
+		t.Errorf("expected no power cycle for an unmapped worker, got %v after %d calls, power %v", err, calls, power)
+	}
+	err = joinWithPowerCycle(context.Background(), d, config, p, NodeConfig{Host: "10.10.88.75", SSHPort: 22}, time.Minute, func() error {
+		return errors.New("k3s-agent failed to start")
+	})
+	if err == nil || len(power) != 0 {
+		t.Errorf("expected no power cycle for a reachable worker, got %v, power %v", err, power)
+	}
+
+	// A worker that rejects the credentials or host key is up and left alone
+	err = joinWithPowerCycle(context.Background(), d, config, p, NodeConfig{Host: "10.10.88.75", SSHPort: 22}, time.Minute, func() error {
+		return &DialError{Addr: "10.10.88.75:22", Err: errors.New("ssh: handshake failed: ssh: unable to authenticate")}
+	})
+	if err == nil || len(power) != 0 {
+		t.Errorf("expected no power cycle on an authentication failure, got %v, power %v", err, power)
+	}
+}
+
+func TestIsSSHUnreachableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&DialError{Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{&DialError{Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.EHOSTUNREACH}}, true},
+		{&DialError{Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}}, true},
+		{&DialError{Err: errors.New("ssh: handshake failed: ssh: unable to authenticate")}, false},
+		{&DialError{Err: errors.New("ssh: handshake failed: host key mismatch")}, false},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, false},
+	}
+	for _, tt := range tests {
+		if got := isSSHUnreachableError(tt.err); got != tt.want {
+			t.Errorf("isSSHUnreachableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestValidateBMCManagedNodes(t *testing.T) {
+	if _, errs := validateBMCManagedNodes(map[string]interface{}{"10.10.88.74": 2}, "bmc_managed_nodes"); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if _, errs := validateBMCManagedNodes(map[string]interface{}{"10.10.88.74": 5}, "bmc_managed_nodes"); len(errs) != 1 {
+		t.Errorf("expected slot 5 to be rejected, got %v", errs)
+	}
+}
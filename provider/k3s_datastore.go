@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// k3sDatastoreDir holds the TLS files of the external datastore on the control plane
+const k3sDatastoreDir = "/etc/rancher/k3s/datastore"
+
+// k3sDatastoreSchemes are the datastore endpoint schemes K3s supports. etcd
+// endpoints are given as http(s) URLs.
+var k3sDatastoreSchemes = []string{"mysql://", "postgres://", "https://", "http://"}
+
+// k3sDatastoreConflictKeys are server_config keys that select another
+// datastore or join an HA cluster, which cannot be combined with an external
+// datastore set through datastore_endpoint
+var k3sDatastoreConflictKeys = []string{"cluster-init", "datastore-endpoint", "server"}
+
+// k3sDatastore is an external datastore K3s keeps cluster state in, instead
+// of SQLite on the control plane's SD card or eMMC
+type k3sDatastore struct {
+	Endpoint   string
+	CACert     string
+	ClientCert string
+	ClientKey  string
+}
+
+// extractK3sDatastore reads the datastore_* arguments. get is the Get method
+// of schema.ResourceData or schema.ResourceDiff.
+func extractK3sDatastore(get func(string) interface{}) k3sDatastore {
+	return k3sDatastore{
+		Endpoint:   get("datastore_endpoint").(string),
+		CACert:     get("datastore_ca_cert").(string),
+		ClientCert: get("datastore_client_cert").(string),
+		ClientKey:  get("datastore_client_key").(string),
+	}
+}
+
+// files returns the TLS files to write to the control plane, keyed by the
+// config.yaml key that references them
+func (ds k3sDatastore) files() map[string]string {
+	files := make(map[string]string)
+	if ds.CACert != "" {
+		files["datastore-cafile"] = ds.CACert
+	}
+	if ds.ClientCert != "" {
+		files["datastore-certfile"] = ds.ClientCert
+	}
+	if ds.ClientKey != "" {
+		files["datastore-keyfile"] = ds.ClientKey
+	}
+	return files
+}
+
+// configValues returns the config.yaml keys of the datastore
+func (ds k3sDatastore) configValues() map[string]string {
+	if ds.Endpoint == "" {
+		return nil
+	}
+	values := map[string]string{"datastore-endpoint": ds.Endpoint}
+	for key := range ds.files() {
+		values[key] = k3sDatastoreFilePath(key)
+	}
+	return values
+}
+
+// k3sDatastoreFilePath returns where the file of a datastore-*file key is written
+func k3sDatastoreFilePath(key string) string {
+	name := map[string]string{
+		"datastore-cafile":   "ca.crt",
+		"datastore-certfile": "client.crt",
+		"datastore-keyfile":  "client.key",
+	}[key]
+	return path.Join(k3sDatastoreDir, name)
+}
+
+// validateK3sDatastore checks that an external datastore is complete and not
+// combined with embedded etcd, an HA join or agents_only mode
+func validateK3sDatastore(ds k3sDatastore, mode string, serverConfig map[string]string) error {
+	if ds.Endpoint == "" {
+		if len(ds.files()) > 0 {
+			return fmt.Errorf("datastore_ca_cert, datastore_client_cert and datastore_client_key require datastore_endpoint")
+		}
+		return nil
+	}
+
+	if mode == k3sModeAgentsOnly {
+		return fmt.Errorf("datastore_endpoint is not supported in %s mode, the server is managed externally", k3sModeAgentsOnly)
+	}
+	for _, key := range k3sDatastoreConflictKeys {
+		if _, ok := serverConfig[key]; ok {
+			return fmt.Errorf("server_config key %q cannot be combined with datastore_endpoint: an external datastore replaces embedded etcd and SQLite", key)
+		}
+	}
+	if (ds.ClientCert == "") != (ds.ClientKey == "") {
+		return fmt.Errorf("datastore_client_cert and datastore_client_key must be set together")
+	}
+	return nil
+}
+
+// validateK3sDatastoreEndpoint is the ValidateFunc of datastore_endpoint.
+// The value is not echoed, as it usually contains a password.
+func validateK3sDatastoreEndpoint(v interface{}, k string) ([]string, []error) {
+	endpoint := v.(string)
+	for _, scheme := range k3sDatastoreSchemes {
+		if strings.HasPrefix(endpoint, scheme) {
+			return nil, nil
+		}
+	}
+	return nil, []error{fmt.Errorf("%s must start with one of %s", k, strings.Join(k3sDatastoreSchemes, ", "))}
+}
+
+// writeDatastoreFiles writes the datastore TLS files to the control plane
+func (p *K3sProvisioner) writeDatastoreFiles(node NodeConfig, ds k3sDatastore) error {
+	files := ds.files()
+	if len(files) == 0 {
+		return nil
+	}
+	if _, err := p.runCommand(node, fmt.Sprintf("mkdir -p %s && chmod 700 %s", k3sDatastoreDir, k3sDatastoreDir)); err != nil {
+		return fmt.Errorf("failed to create %s: %w", k3sDatastoreDir, err)
+	}
+	for key, content := range files {
+		target := k3sDatastoreFilePath(key)
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		cmd := fmt.Sprintf("echo '%s' | base64 -d > %s && chmod 600 %s", encoded, target, target)
+		if _, err := p.runCommand(node, cmd); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+	}
+	return nil
+}
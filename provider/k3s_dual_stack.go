@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// k3sIPv6SysctlPath holds the kernel parameters IPv6 pod networking needs
+const k3sIPv6SysctlPath = "/etc/sysctl.d/90-k3s-ipv6.conf"
+
+// k3sIPv6Sysctls enables forwarding for pod traffic. accept_ra=2 keeps
+// router advertisements, and so SLAAC addresses and the default route, on
+// hosts that forward.
+var k3sIPv6Sysctls = []string{
+	"net.ipv4.ip_forward=1",
+	"net.ipv6.conf.all.forwarding=1",
+	"net.ipv6.conf.default.forwarding=1",
+	"net.ipv6.conf.all.accept_ra=2",
+	"net.ipv6.conf.default.accept_ra=2",
+}
+
+// validateClusterCIDR is the ValidateFunc of pod_cidr and service_cidr: an
+// IPv4 or IPv6 CIDR, or both comma-separated for dual-stack
+func validateClusterCIDR(v interface{}, k string) ([]string, []error) {
+	if _, err := parseCIDRFamilies(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+// validateNodeIPs is the ValidateFunc of node_ip and node_external_ip: an
+// IPv4 or IPv6 address, or both comma-separated for dual-stack
+func validateNodeIPs(v interface{}, k string) ([]string, []error) {
+	if _, err := parseIPFamilies(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+// validateK3sDualStack checks that pod_cidr and service_cidr cover the same
+// address families, and that on a dual-stack cluster the node_ip of each
+// node carries both, as K3s requires
+func validateK3sDualStack(cfg ClusterConfig) error {
+	pods, err := validateCIDRFamilies(cfg.PodCIDR, cfg.ServiceCIDR, "pod_cidr", "service_cidr")
+	if err != nil {
+		return err
+	}
+	if !pods.dualStack() {
+		return nil
+	}
+	for _, node := range append([]NodeConfig{cfg.ControlPlane}, cfg.Workers...) {
+		if node.Host == "" || node.NodeIP == "" {
+			continue
+		}
+		if ips, err := parseIPFamilies(node.NodeIP); err == nil && !ips.dualStack() {
+			return fmt.Errorf("node %s: node_ip must list an IPv4 and an IPv6 address (e.g. \"10.10.88.73,fd00::73\") on a dual-stack cluster", node.Host)
+		}
+	}
+	return nil
+}
+
+// clusterUsesIPv6 reports whether the pod network has an IPv6 CIDR
+func clusterUsesIPv6(cfg ClusterConfig) bool {
+	pods, err := parseCIDRFamilies(cfg.PodCIDR)
+	return err == nil && pods.IPv6 != ""
+}
+
+// primaryNodeIP returns the first address of a comma-separated node_ip,
+// the one Kubernetes reports as the node InternalIP
+func primaryNodeIP(nodeIP string) string {
+	first, _, _ := strings.Cut(nodeIP, ",")
+	return strings.TrimSpace(first)
+}
+
+// renderK3sIPv6Sysctls renders the sysctl.d file for IPv6 pod networking
+func renderK3sIPv6Sysctls() string {
+	return "# Managed by terraform-provider-turingpi: IPv6 pod networking\n" + strings.Join(k3sIPv6Sysctls, "\n") + "\n"
+}
+
+// applyIPv6Sysctls enables IPv6 forwarding on a node before K3s is
+// installed. Does nothing unless the cluster uses IPv6.
+func (p *K3sProvisioner) applyIPv6Sysctls(node NodeConfig) error {
+	if !p.IPv6 {
+		return nil
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(renderK3sIPv6Sysctls()))
+	cmd := fmt.Sprintf("mkdir -p /etc/sysctl.d && echo '%s' | base64 -d > %s && chmod 644 %s && sysctl -p %s",
+		encoded, k3sIPv6SysctlPath, k3sIPv6SysctlPath, k3sIPv6SysctlPath)
+	if _, err := p.runCommand(node, cmd); err != nil {
+		return fmt.Errorf("failed to enable IPv6 forwarding on %s: %w", node.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateClusterCIDR(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"10.244.0.0/16", true},
+		{"fd00:10:244::/56", true},
+		{"10.244.0.0/16,fd00:10:244::/56", true},
+		{"fd00:10:244::/56, 10.244.0.0/16", true},
+		{"10.244.0.0", false},
+		{"10.244.0.0/16,10.245.0.0/16", false},
+		{"fd00:1::/64,fd00:2::/64", false},
+		{"10.244.0.0/16,", false},
+	}
+	for _, tt := range tests {
+		_, errs := validateClusterCIDR(tt.value, "pod_cidr")
+		if (len(errs) == 0) != tt.valid {
+			t.Errorf("%q: expected valid=%v, got %v", tt.value, tt.valid, errs)
+		}
+	}
+}
+
+func TestValidateNodeIPs(t *testing.T) {
+	for _, value := range []string{"10.10.88.73", "fd00::73", "10.10.88.73,fd00::73"} {
+		if _, errs := validateNodeIPs(value, "node_ip"); len(errs) > 0 {
+			t.Errorf("%q: unexpected errors %v", value, errs)
+		}
+	}
+	for _, value := range []string{"node1", "10.10.88.73,10.10.88.74", "10.10.88.0/24"} {
+		if _, errs := validateNodeIPs(value, "node_ip"); len(errs) == 0 {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestValidateK3sDualStack(t *testing.T) {
+	dual := ClusterConfig{
+		PodCIDR:      "10.244.0.0/16,fd00:10:244::/56",
+		ServiceCIDR:  "10.96.0.0/12,fd00:10:96::/112",
+		ControlPlane: NodeConfig{Host: "10.10.88.73", NodeIP: "10.10.88.73,fd00::73"},
+		Workers:      []NodeConfig{{Host: "10.10.88.74"}},
+	}
+	if err := validateK3sDualStack(dual); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	mismatched := dual
+	mismatched.ServiceCIDR = "10.96.0.0/12"
+	if err := validateK3sDualStack(mismatched); err == nil || !strings.Contains(err.Error(), "pod_cidr is dual-stack but service_cidr is IPv4") {
+		t.Errorf("expected a family mismatch error, got %v", err)
+	}
+
+	singleNodeIP := dual
+	singleNodeIP.Workers = []NodeConfig{{Host: "10.10.88.74", NodeIP: "10.10.88.74"}}
+	if err := validateK3sDualStack(singleNodeIP); err == nil || !strings.Contains(err.Error(), "node 10.10.88.74: node_ip must list an IPv4 and an IPv6 address") {
+		t.Errorf("expected a node_ip error, got %v", err)
+	}
+
+	ipv4 := ClusterConfig{PodCIDR: "10.244.0.0/16", ServiceCIDR: "10.96.0.0/12", ControlPlane: NodeConfig{Host: "10.10.88.73", NodeIP: "10.10.88.73"}}
+	if err := validateK3sDualStack(ipv4); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if clusterUsesIPv6(ipv4) || !clusterUsesIPv6(dual) {
+		t.Error("expected only the dual-stack cluster to use IPv6")
+	}
+}
+
+func TestRenderServerConfig_DualStack(t *testing.T) {
+	got := RenderServerConfig(ClusterConfig{PodCIDR: "10.244.0.0/16,fd00:10:244::/56", ServiceCIDR: "10.96.0.0/12,fd00:10:96::/112"})
+	want := "cluster-cidr: \"10.244.0.0/16,fd00:10:244::/56\"\nservice-cidr: \"10.96.0.0/12,fd00:10:96::/112\"\n"
+	if got != want {
+		t.Errorf("unexpected config:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestK3sProvisioner_IPv6Sysctls(t *testing.T) {
+	for _, ipv6 := range []bool{true, false} {
+		var commands []string
+		provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+			return &MockSSHClient{
+				RunCommandFunc: func(cmd string) (string, error) {
+					commands = append(commands, cmd)
+					if strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s ") {
+						return "not_installed", nil
+					}
+					return "", nil
+				},
+			}
+		})
+		provisioner.IPv6 = ipv6
+		node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22, NodeIP: "10.10.88.74,fd00::74"}
+		if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		sysctl := commandIndex(commands, "sysctl -p "+k3sIPv6SysctlPath)
+		install := commandIndex(commands, "/tmp/k3s-install.sh agent")
+		if !ipv6 {
+			if sysctl != -1 {
+				t.Error("expected no IPv6 sysctls on an IPv4 cluster")
+			}
+			continue
+		}
+		if sysctl == -1 || sysctl > install {
+			t.Errorf("expected the IPv6 sysctls before the install, got:\n%s", strings.Join(commands, "\n"))
+		}
+		if !strings.Contains(commands[install], "--node-ip=10.10.88.74,fd00::74") {
+			t.Errorf("expected both node addresses in --node-ip, got %s", commands[install])
+		}
+	}
+}
+
+func TestK3sNodeReady_DualStackNodeIP(t *testing.T) {
+	statuses := []K3sNodeStatus{{Name: "turing-2", InternalIP: "10.10.88.74", Ready: true}}
+	if !k3sNodeReady(statuses, NodeConfig{Host: "turing-2.local", NodeIP: "10.10.88.74,fd00::74"}) {
+		t.Error("expected the node to match its first node_ip address")
+	}
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Accelerator vendors detected on nodes with enable_gpu
+const (
+	acceleratorNVIDIA   = "nvidia"
+	acceleratorRockchip = "rockchip"
+)
+
+// acceleratorLabel is the node label K3s registers with the detected vendor
+const acceleratorLabel = "turingpi.io/accelerator"
+
+// detectAcceleratorScript prints the accelerator vendor of the node: nvidia
+// for Jetson modules (or a discrete NVIDIA GPU), rockchip for RK1 modules
+const detectAcceleratorScript = `if [ -f /etc/nv_tegra_release ] || command -v nvidia-smi >/dev/null 2>&1; then
+  echo nvidia
+elif grep -qi rockchip /proc/device-tree/compatible 2>/dev/null; then
+  echo rockchip
+fi`
+
+// installNVIDIARuntimeScript installs the NVIDIA container toolkit. K3s
+// detects nvidia-container-runtime when it starts and adds an "nvidia"
+// runtime to its containerd config, so the toolkit must be in place before
+// K3s is installed or restarted.
+const installNVIDIARuntimeScript = `set -e
+if command -v nvidia-container-runtime >/dev/null 2>&1; then exit 0; fi
+if ! command -v apt-get >/dev/null 2>&1; then
+  echo "nvidia-container-toolkit must be installed manually on this distribution" >&2; exit 1
+fi
+if ! apt-cache show nvidia-container-toolkit >/dev/null 2>&1; then
+  curl -fsSL https://nvidia.github.io/libnvidia-container/gpgkey | gpg --dearmor --yes -o /usr/share/keyrings/nvidia-container-toolkit-keyring.gpg
+  curl -fsSL https://nvidia.github.io/libnvidia-container/stable/deb/nvidia-container-toolkit.list | \
+    sed 's#deb https://#deb [signed-by=/usr/share/keyrings/nvidia-container-toolkit-keyring.gpg] https://#g' > /etc/apt/sources.list.d/nvidia-container-toolkit.list
+  apt-get update -q
+fi
+DEBIAN_FRONTEND=noninteractive apt-get install -y -q nvidia-container-toolkit`
+
+// prepareRockchipScript loads the RK1 NPU driver and checks that the NPU or
+// GPU device nodes exist. Rockchip needs no containerd runtime; pods use the
+// device nodes directly.
+const prepareRockchipScript = `modprobe rknpu 2>/dev/null || true
+ls /dev/rknpu* /dev/dri/renderD* >/dev/null 2>&1 || { echo "no Rockchip NPU or GPU device nodes found" >&2; exit 1; }`
+
+// nvidiaRuntimeClassManifest lets pods select the nvidia containerd runtime
+// with runtimeClassName: nvidia
+const nvidiaRuntimeClassManifest = `apiVersion: node.k8s.io/v1
+kind: RuntimeClass
+metadata:
+  name: nvidia
+handler: nvidia
+`
+
+// prepareAccelerator detects the accelerator of a node with enable_gpu and
+// installs its container runtime bits. The vendor is recorded in
+// Accelerators and returned for the node label.
+func (p *K3sProvisioner) prepareAccelerator(node NodeConfig) (string, error) {
+	output, err := p.runCommand(node, detectAcceleratorScript)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect accelerator on %s: %w", node.Host, err)
+	}
+	if p.DryRun != nil {
+		p.DryRun.comment("install the NVIDIA container runtime or prepare the Rockchip NPU on %s, depending on the detected vendor", node.Host)
+		return "<detected>", nil
+	}
+
+	vendor := strings.TrimSpace(output)
+	switch vendor {
+	case acceleratorNVIDIA:
+		if _, err := p.runCommand(node, installNVIDIARuntimeScript); err != nil {
+			return "", fmt.Errorf("failed to install NVIDIA container runtime on %s: %w", node.Host, err)
+		}
+	case acceleratorRockchip:
+		if _, err := p.runCommand(node, prepareRockchipScript); err != nil {
+			return "", fmt.Errorf("failed to prepare Rockchip accelerator on %s: %w", node.Host, err)
+		}
+	default:
+		return "", fmt.Errorf("enable_gpu is set but no NVIDIA or Rockchip accelerator was found on %s", node.Host)
+	}
+
+	if p.Accelerators == nil {
+		p.Accelerators = make(map[string]string)
+	}
+	p.Accelerators[node.Host] = vendor
+	return vendor, nil
+}
+
+// acceleratorFlags prepares the accelerator of a node with enable_gpu and
+// returns the node label flag for the K3s installer
+func (p *K3sProvisioner) acceleratorFlags(node NodeConfig) ([]string, error) {
+	if !node.EnableGPU {
+		return nil, nil
+	}
+	vendor, err := p.prepareAccelerator(node)
+	if err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("--node-label=%s=%s", acceleratorLabel, vendor)}, nil
+}
+
+// HasNVIDIA reports whether an NVIDIA accelerator was prepared on any node
+func (p *K3sProvisioner) HasNVIDIA() bool {
+	for _, vendor := range p.Accelerators {
+		if vendor == acceleratorNVIDIA {
+			return true
+		}
+	}
+	return false
+}
+
+// applyNVIDIARuntimeClass creates the nvidia RuntimeClass in the cluster
+func applyNVIDIARuntimeClass(ctx context.Context, kubeconfig []byte) error {
+	client, err := NewK8sClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if err := client.ApplyManifest(ctx, nvidiaRuntimeClassManifest); err != nil {
+		return fmt.Errorf("failed to create nvidia RuntimeClass: %w", err)
+	}
+	return nil
+}
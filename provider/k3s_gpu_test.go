@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGPUMockProvisioner returns a provisioner whose nodes report vendor from
+// the accelerator detection script, recording every command
+func newGPUMockProvisioner(vendor string, commands *[]string) *K3sProvisioner {
+	return NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				*commands = append(*commands, cmd)
+				switch {
+				case cmd == detectAcceleratorScript:
+					return vendor + "\n", nil
+				case strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s "):
+					return "not_installed", nil
+				}
+				return "", nil
+			},
+		}
+	})
+}
+
+func TestK3sProvisioner_InstallK3sAgent_EnableGPU(t *testing.T) {
+	var commands []string
+	provisioner := newGPUMockProvisioner(acceleratorNVIDIA, &commands)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22, EnableGPU: true}
+
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runtimeIdx, installIdx := -1, -1
+	for i, cmd := range commands {
+		if cmd == installNVIDIARuntimeScript {
+			runtimeIdx = i
+		}
+		if strings.Contains(cmd, "/tmp/k3s-install.sh agent") {
+			installIdx = i
+			if !strings.Contains(cmd, "--node-label=turingpi.io/accelerator=nvidia") {
+				t.Errorf("expected accelerator node label, got %q", cmd)
+			}
+		}
+	}
+	if runtimeIdx == -1 || installIdx == -1 || runtimeIdx > installIdx {
+		t.Errorf("expected the NVIDIA runtime to be installed before K3s, got commands %v", commands)
+	}
+	if !provisioner.HasNVIDIA() {
+		t.Error("expected HasNVIDIA to be true")
+	}
+}
+
+func TestK3sProvisioner_PrepareAccelerator(t *testing.T) {
+	tests := []struct {
+		vendor    string
+		expectErr bool
+		script    string
+	}{
+		{vendor: acceleratorNVIDIA, script: installNVIDIARuntimeScript},
+		{vendor: acceleratorRockchip, script: prepareRockchipScript},
+		{vendor: "", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendor, func(t *testing.T) {
+			var commands []string
+			provisioner := newGPUMockProvisioner(tt.vendor, &commands)
+			node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22, EnableGPU: true}
+
+			vendor, err := provisioner.prepareAccelerator(node)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error when no accelerator is found")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if vendor != tt.vendor || provisioner.Accelerators[node.Host] != tt.vendor {
+				t.Errorf("expected vendor %q, got %q", tt.vendor, vendor)
+			}
+			if commands[len(commands)-1] != tt.script {
+				t.Errorf("expected %s preparation script to run, got %v", tt.vendor, commands)
+			}
+		})
+	}
+}
+
+func TestK3sProvisioner_AcceleratorFlags_Disabled(t *testing.T) {
+	var commands []string
+	provisioner := newGPUMockProvisioner(acceleratorNVIDIA, &commands)
+
+	flags, err := provisioner.acceleratorFlags(NodeConfig{Host: "10.10.88.74"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(flags) != 0 || len(commands) != 0 {
+		t.Errorf("expected no flags or commands without enable_gpu, got %v and %v", flags, commands)
+	}
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// k3sHardeningCIS applies the settings of the K3s CIS hardening guide
+// (https://docs.k3s.io/security/hardening-guide)
+const k3sHardeningCIS = "cis"
+
+// Files written by the CIS hardening profile
+const (
+	k3sHardeningSysctlPath   = "/etc/sysctl.d/90-kubelet.conf"
+	k3sHardeningDropInPath   = "/etc/rancher/k3s/config.yaml.d/90-cis-hardening.yaml"
+	k3sHardeningPSAPath      = "/var/lib/rancher/k3s/server/psa.yaml"
+	k3sHardeningAuditPath    = "/var/lib/rancher/k3s/server/audit.yaml"
+	k3sHardeningAuditLogPath = "/var/lib/rancher/k3s/server/logs/audit.log"
+)
+
+// k3sCISSysctls are the kernel parameters the kubelet expects with
+// protect-kernel-defaults. It refuses to start when they differ.
+var k3sCISSysctls = map[string]string{
+	"vm.panic_on_oom":      "0",
+	"vm.overcommit_memory": "1",
+	"kernel.panic":         "10",
+	"kernel.panic_on_oops": "1",
+}
+
+// k3sCISPSAConfig enforces the restricted Pod Security Standard in every
+// namespace except kube-system, where the bundled components run, and the
+// namespaces of the MetalLB, NGINX Ingress and system-upgrade-controller
+// addons, whose pods need host networking or host access
+const k3sCISPSAConfig = `apiVersion: apiserver.config.k8s.io/v1
+kind: AdmissionConfiguration
+plugins:
+- name: PodSecurity
+  configuration:
+    apiVersion: pod-security.admission.config.k8s.io/v1
+    kind: PodSecurityConfiguration
+    defaults:
+      enforce: "restricted"
+      enforce-version: "latest"
+      audit: "restricted"
+      audit-version: "latest"
+      warn: "restricted"
+      warn-version: "latest"
+    exemptions:
+      usernames: []
+      runtimeClasses: []
+      namespaces: [kube-system, cis-operator-system, metallb-system, ingress-nginx, system-upgrade]
+`
+
+// k3sCISAuditPolicy logs request metadata for every request
+const k3sCISAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: Metadata
+`
+
+// renderK3sCISSysctls renders the sysctl.d file of the CIS profile with
+// sorted keys
+func renderK3sCISSysctls() string {
+	keys := make([]string, 0, len(k3sCISSysctls))
+	for k := range k3sCISSysctls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, k3sCISSysctls[k])
+	}
+	return b.String()
+}
+
+// renderK3sCISConfig renders the config.yaml.d drop-in of the CIS profile.
+// K3s merges it into config.yaml, so server_config keys stay in config.yaml
+// and its checksum. Agents only get the kubelet settings.
+func renderK3sCISConfig(server bool) string {
+	var b strings.Builder
+	b.WriteString("protect-kernel-defaults: true\n")
+	if !server {
+		b.WriteString("kubelet-arg:\n")
+		b.WriteString("- \"streaming-connection-idle-timeout=5m\"\n")
+		return b.String()
+	}
+	b.WriteString("secrets-encryption: true\n")
+	b.WriteString("kube-apiserver-arg:\n")
+	for _, arg := range []string{
+		"admission-control-config-file=" + k3sHardeningPSAPath,
+		"audit-policy-file=" + k3sHardeningAuditPath,
+		"audit-log-path=" + k3sHardeningAuditLogPath,
+		"audit-log-maxage=30",
+		"audit-log-maxbackup=10",
+		"audit-log-maxsize=100",
+	} {
+		fmt.Fprintf(&b, "- %q\n", arg)
+	}
+	b.WriteString("kube-controller-manager-arg:\n")
+	b.WriteString("- \"terminated-pod-gc-threshold=10\"\n")
+	b.WriteString("kubelet-arg:\n")
+	b.WriteString("- \"streaming-connection-idle-timeout=5m\"\n")
+	return b.String()
+}
+
+// hardeningFile is a file written by a hardening profile
+type hardeningFile struct {
+	path    string
+	content string
+	mode    string
+}
+
+// applyHardening prepares a node for the hardening profile before K3s is
+// installed: the kernel parameters are set, and on servers the Pod Security
+// admission config and audit policy are written. The K3s settings go into a
+// config.yaml.d drop-in. Does nothing without a profile.
+func (p *K3sProvisioner) applyHardening(node NodeConfig, server bool) error {
+	if p.Hardening != k3sHardeningCIS {
+		return nil
+	}
+
+	files := []hardeningFile{
+		{k3sHardeningSysctlPath, renderK3sCISSysctls(), "644"},
+		{k3sHardeningDropInPath, renderK3sCISConfig(server), "600"},
+	}
+	if server {
+		files = append(files,
+			hardeningFile{k3sHardeningPSAPath, k3sCISPSAConfig, "600"},
+			hardeningFile{k3sHardeningAuditPath, k3sCISAuditPolicy, "600"},
+		)
+	}
+
+	for _, f := range files {
+		encoded := base64.StdEncoding.EncodeToString([]byte(f.content))
+		dir := f.path[:strings.LastIndex(f.path, "/")]
+		cmd := fmt.Sprintf("mkdir -p %s && echo '%s' | base64 -d > %s && chmod %s %s", dir, encoded, f.path, f.mode, f.path)
+		if _, err := p.runCommand(node, cmd); err != nil {
+			return fmt.Errorf("failed to write %s on %s: %w", f.path, node.Host, err)
+		}
+	}
+	if server {
+		if _, err := p.runCommand(node, "mkdir -p /var/lib/rancher/k3s/server/logs && chmod 700 /var/lib/rancher/k3s/server/logs"); err != nil {
+			return fmt.Errorf("failed to create audit log directory on %s: %w", node.Host, err)
+		}
+	}
+
+	if _, err := p.runCommand(node, "sysctl -p "+k3sHardeningSysctlPath); err != nil {
+		return fmt.Errorf("failed to apply kernel parameters on %s: %w", node.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderK3sCISSysctls(t *testing.T) {
+	want := "kernel.panic=10\nkernel.panic_on_oops=1\nvm.overcommit_memory=1\nvm.panic_on_oom=0\n"
+	if got := renderK3sCISSysctls(); got != want {
+		t.Errorf("sysctls = %q, want %q", got, want)
+	}
+}
+
+func TestRenderK3sCISConfig(t *testing.T) {
+	server := renderK3sCISConfig(true)
+	for _, want := range []string{
+		"protect-kernel-defaults: true",
+		"secrets-encryption: true",
+		`- "admission-control-config-file=` + k3sHardeningPSAPath + `"`,
+		`- "audit-policy-file=` + k3sHardeningAuditPath + `"`,
+		`- "audit-log-path=` + k3sHardeningAuditLogPath + `"`,
+		`- "terminated-pod-gc-threshold=10"`,
+	} {
+		if !strings.Contains(server, want) {
+			t.Errorf("server config missing %q:\n%s", want, server)
+		}
+	}
+
+	agent := renderK3sCISConfig(false)
+	if !strings.Contains(agent, "protect-kernel-defaults: true") {
+		t.Errorf("agent config missing protect-kernel-defaults:\n%s", agent)
+	}
+	if strings.Contains(agent, "kube-apiserver-arg") || strings.Contains(agent, "secrets-encryption") {
+		t.Errorf("agent config should only have kubelet settings:\n%s", agent)
+	}
+}
+
+// hardeningCommands installs K3s with the CIS profile and returns the
+// commands run
+func hardeningCommands(t *testing.T, hardening string, server bool) []string {
+	t.Helper()
+	var commands []string
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				switch {
+				case strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s "):
+					return "not_installed", nil
+				case strings.HasPrefix(cmd, "k3s kubectl get nodes"):
+					return "node1 Ready", nil
+				}
+				return "", nil
+			},
+		}
+	})
+	provisioner.Hardening = hardening
+	node := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPort: 22}
+
+	var err error
+	if server {
+		_, err = provisioner.InstallK3sServer(context.Background(), node, ClusterConfig{}, time.Second)
+	} else {
+		_, err = provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return commands
+}
+
+// commandIndex returns the index of the first command containing substr, or -1
+func commandIndex(commands []string, substr string) int {
+	for i, cmd := range commands {
+		if strings.Contains(cmd, substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestK3sProvisioner_CISHardening_Server(t *testing.T) {
+	commands := hardeningCommands(t, k3sHardeningCIS, true)
+
+	install := commandIndex(commands, "/tmp/k3s-install.sh server")
+	for _, substr := range []string{
+		"> " + k3sHardeningSysctlPath,
+		"> " + k3sHardeningDropInPath,
+		"> " + k3sHardeningPSAPath,
+		"> " + k3sHardeningAuditPath,
+		"sysctl -p " + k3sHardeningSysctlPath,
+	} {
+		if i := commandIndex(commands, substr); i == -1 || i > install {
+			t.Errorf("expected %q before the install, got commands %v", substr, commands)
+		}
+	}
+
+	dropIn := base64.StdEncoding.EncodeToString([]byte(renderK3sCISConfig(true)))
+	if commandIndex(commands, dropIn) == -1 {
+		t.Error("expected the server drop-in to be written")
+	}
+}
+
+func TestK3sProvisioner_CISHardening_Agent(t *testing.T) {
+	commands := hardeningCommands(t, k3sHardeningCIS, false)
+
+	install := commandIndex(commands, "/tmp/k3s-install.sh agent")
+	if i := commandIndex(commands, "sysctl -p "+k3sHardeningSysctlPath); i == -1 || i > install {
+		t.Errorf("expected kernel parameters applied before the install, got commands %v", commands)
+	}
+	if commandIndex(commands, base64.StdEncoding.EncodeToString([]byte(renderK3sCISConfig(false)))) == -1 {
+		t.Error("expected the agent drop-in to be written")
+	}
+	if commandIndex(commands, k3sHardeningPSAPath) != -1 || commandIndex(commands, k3sHardeningAuditPath) != -1 {
+		t.Error("agents should not get the API server files")
+	}
+}
+
+func TestK3sProvisioner_NoHardening(t *testing.T) {
+	commands := hardeningCommands(t, "", true)
+	if i := commandIndex(commands, "sysctl"); i != -1 {
+		t.Errorf("expected no hardening commands, got %q", commands[i])
+	}
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// k3sDefaultAPIPort is the port of the Kubernetes API and, unless
+// supervisor-port is set, of the K3s supervisor
+const k3sDefaultAPIPort = 6443
+
+func k3sHealthProbesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Description: "Health checks of the K3s servers for external load balancers (HAProxy, keepalived): " +
+			"the Kubernetes API /healthz and the supervisor /ping",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "Probe name: apiserver or supervisor",
+				},
+				"protocol": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "Probe protocol, always https",
+				},
+				"port": {
+					Type:        schema.TypeInt,
+					Computed:    true,
+					Description: "Port to check on each server",
+				},
+				"path": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "HTTP path to request",
+				},
+				"url": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "Full probe URL on the first server",
+				},
+			},
+		},
+	}
+}
+
+// k3sHealthProbe is one entry of health_probes
+type k3sHealthProbe struct {
+	Name string
+	Port int
+	Path string
+}
+
+// k3sHealthProbes returns the apiserver and supervisor probes of a cluster.
+// The API port comes from the endpoint; K3s serves the supervisor on the same
+// port unless supervisor-port is set in server_config.
+func k3sHealthProbes(apiEndpoint string, serverConfig map[string]string) []k3sHealthProbe {
+	apiPort := k3sDefaultAPIPort
+	if u, err := url.Parse(apiEndpoint); err == nil {
+		if port, err := strconv.Atoi(u.Port()); err == nil {
+			apiPort = port
+		}
+	}
+	supervisorPort := apiPort
+	if port, err := strconv.Atoi(serverConfig["supervisor-port"]); err == nil {
+		supervisorPort = port
+	}
+	return []k3sHealthProbe{
+		{Name: "apiserver", Port: apiPort, Path: "/healthz"},
+		{Name: "supervisor", Port: supervisorPort, Path: "/ping"},
+	}
+}
+
+// k3sServerIPs returns the addresses of the K3s servers: the control plane's
+// node_ip, or its host, or with agents_only the host of server_url
+func k3sServerIPs(cfg ClusterConfig, apiEndpoint string) []string {
+	if cfg.ControlPlane.Host != "" {
+		if ip := primaryNodeIP(cfg.ControlPlane.NodeIP); ip != "" {
+			return []string{ip}
+		}
+		return []string{cfg.ControlPlane.Host}
+	}
+	if u, err := url.Parse(apiEndpoint); err == nil && u.Hostname() != "" {
+		return []string{u.Hostname()}
+	}
+	return nil
+}
+
+// setK3sHealthProbes sets health_probes and server_ips from api_endpoint and
+// the control plane
+func setK3sHealthProbes(d *schema.ResourceData) error {
+	apiEndpoint := d.Get("api_endpoint").(string)
+	cfg := extractClusterConfig(d)
+	ips := k3sServerIPs(cfg, apiEndpoint)
+
+	var probes []interface{}
+	if apiEndpoint != "" {
+		for _, probe := range k3sHealthProbes(apiEndpoint, cfg.ServerConfig) {
+			probeURL := ""
+			if len(ips) > 0 {
+				probeURL = fmt.Sprintf("https://%s%s", net.JoinHostPort(ips[0], strconv.Itoa(probe.Port)), probe.Path)
+			}
+			probes = append(probes, map[string]interface{}{
+				"name":     probe.Name,
+				"protocol": "https",
+				"port":     probe.Port,
+				"path":     probe.Path,
+				"url":      probeURL,
+			})
+		}
+	}
+	if err := d.Set("health_probes", probes); err != nil {
+		return fmt.Errorf("failed to set health_probes: %w", err)
+	}
+	if err := d.Set("server_ips", ips); err != nil {
+		return fmt.Errorf("failed to set server_ips: %w", err)
+	}
+	return nil
+}
+
+// withK3sHealthProbes sets health_probes and server_ips after fn
+func withK3sHealthProbes(fn func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		diags := fn(ctx, d, meta)
+		if d.Id() == "" {
+			return diags
+		}
+		if err := setK3sHealthProbes(d); err != nil {
+			return append(diags, diagFromErr(err)...)
+		}
+		return diags
+	}
+}
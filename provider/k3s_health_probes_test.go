@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestK3sHealthProbes(t *testing.T) {
+	probes := k3sHealthProbes("https://10.10.88.73:6443", nil)
+	if len(probes) != 2 || probes[0] != (k3sHealthProbe{"apiserver", 6443, "/healthz"}) || probes[1] != (k3sHealthProbe{"supervisor", 6443, "/ping"}) {
+		t.Errorf("unexpected default probes: %+v", probes)
+	}
+
+	probes = k3sHealthProbes("https://k3s.example.com", map[string]string{"supervisor-port": "9345"})
+	if probes[0].Port != 6443 || probes[1].Port != 9345 {
+		t.Errorf("expected the default API port and supervisor-port, got %+v", probes)
+	}
+}
+
+func TestSetK3sHealthProbes(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host":     "turing-1.local",
+			"node_ip":  "10.10.88.73,fd00::73",
+			"ssh_user": "root",
+		}},
+	})
+	_ = d.Set("api_endpoint", "https://turing-1.local:6443")
+	if err := setK3sHealthProbes(d); err != nil {
+		t.Fatal(err)
+	}
+	if ips := d.Get("server_ips").([]interface{}); len(ips) != 1 || ips[0] != "10.10.88.73" {
+		t.Errorf("expected the primary node_ip, got %v", ips)
+	}
+	if got := d.Get("health_probes.0.url"); got != "https://10.10.88.73:6443/healthz" {
+		t.Errorf("unexpected apiserver probe url %v", got)
+	}
+	if got := d.Get("health_probes.1.path"); got != "/ping" {
+		t.Errorf("unexpected supervisor probe path %v", got)
+	}
+
+	d = schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":          "test",
+		"mode":          k3sModeAgentsOnly,
+		"server_url":    "https://10.10.88.10:6443",
+		"cluster_token": "token",
+	})
+	_ = d.Set("api_endpoint", "https://10.10.88.10:6443")
+	if err := setK3sHealthProbes(d); err != nil {
+		t.Fatal(err)
+	}
+	if ips := d.Get("server_ips").([]interface{}); len(ips) != 1 || ips[0] != "10.10.88.10" {
+		t.Errorf("expected the server_url host in agents_only mode, got %v", ips)
+	}
+}
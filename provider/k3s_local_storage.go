@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// defaultStorageClassAnnotation marks the StorageClass used by
+// PersistentVolumeClaims that do not name one
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// k3sLocalStorage is the local_storage block of turingpi_k3s_cluster
+type k3sLocalStorage struct {
+	// Path is rendered as default-local-storage-path in config.yaml
+	Path string
+	// DefaultClass is the StorageClass to mark as the default
+	DefaultClass string
+}
+
+func k3sLocalStorageSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Local-path provisioner and default StorageClass settings, e.g. to keep volumes off the SD card",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"path": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Description: "Directory the local-path provisioner creates volumes in on every node (e.g., /mnt/nvme/k3s-storage), " +
+						"rendered as default-local-storage-path in config.yaml. Changing it requires allow_restart; existing volumes stay where they are.",
+				},
+				"default_class": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Description: "StorageClass marked as the cluster default (e.g., longhorn). Every other class loses the default annotation. " +
+						"A class that does not exist yet is marked on a later apply.",
+				},
+			},
+		},
+	}
+}
+
+// extractK3sLocalStorage returns the local_storage block, or nil when it is
+// not set
+func extractK3sLocalStorage(get func(string) interface{}) *k3sLocalStorage {
+	v := get("local_storage").([]interface{})
+	if len(v) == 0 || v[0] == nil {
+		return nil
+	}
+	m := v[0].(map[string]interface{})
+	return &k3sLocalStorage{
+		Path:         m["path"].(string),
+		DefaultClass: m["default_class"].(string),
+	}
+}
+
+// k3sLocalStoragePath returns the configured local-path data directory, or
+// "" for the K3s default
+func k3sLocalStoragePath(get func(string) interface{}) string {
+	if ls := extractK3sLocalStorage(get); ls != nil {
+		return ls.Path
+	}
+	return ""
+}
+
+// k3sDesiredDefaultClass returns the StorageClass local_storage marks as
+// default, or "" when the default is left to K3s
+func k3sDesiredDefaultClass(get func(string) interface{}) string {
+	if ls := extractK3sLocalStorage(get); ls != nil {
+		return ls.DefaultClass
+	}
+	return ""
+}
+
+// DefaultStorageClasses returns the names of the StorageClasses annotated as
+// default, sorted. More than one means PVCs without a class are ambiguous.
+func (c *K8sClient) DefaultStorageClasses(ctx context.Context) ([]string, error) {
+	classes, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	var names []string
+	for _, class := range classes.Items {
+		if class.Annotations[defaultStorageClassAnnotation] == "true" {
+			names = append(names, class.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetDefaultStorageClass annotates name as the default StorageClass and
+// removes the default from every other class. found is false when name does
+// not exist; the other classes are still updated.
+func (c *K8sClient) SetDefaultStorageClass(ctx context.Context, name string) (found bool, err error) {
+	classes, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	for _, class := range classes.Items {
+		isDefault := class.Annotations[defaultStorageClassAnnotation] == "true"
+		want := class.Name == name
+		found = found || want
+		if isDefault == want {
+			continue
+		}
+		patch, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{defaultStorageClassAnnotation: fmt.Sprintf("%t", want)},
+			},
+		})
+		if _, err := c.clientset.StorageV1().StorageClasses().Patch(ctx, class.Name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: k8sFieldManager}); err != nil {
+			return found, fmt.Errorf("failed to update storage class %s: %w", class.Name, err)
+		}
+	}
+	return found, nil
+}
+
+// applyK3sDefaultStorageClass marks the default_class of local_storage as
+// the default and sets default_storage_class to the result. A missing class
+// is a warning, so it can be installed after the cluster.
+func applyK3sDefaultStorageClass(ctx context.Context, d *schema.ResourceData, kubeconfig []byte) diag.Diagnostics {
+	name := k3sDesiredDefaultClass(d.Get)
+	if name == "" {
+		return nil
+	}
+	client, err := NewK8sClient(kubeconfig)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to create Kubernetes client: %w", err))
+	}
+	defer func() { _ = client.Close() }()
+
+	found, err := client.SetDefaultStorageClass(ctx, name)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	var diags diag.Diagnostics
+	if !found {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Default storage class not found",
+			Detail: fmt.Sprintf("Storage class %q does not exist yet, so no class is the default. "+
+				"It is marked as the default on the next apply after it is installed.", name),
+		})
+	}
+	tflog.Info(ctx, "Set the default storage class", map[string]interface{}{
+		"class": name,
+		"found": found,
+	})
+
+	current, err := client.DefaultStorageClasses(ctx)
+	if err != nil {
+		return append(diags, diagFromErr(err)...)
+	}
+	return append(diags, diagFromErr(d.Set("default_storage_class", strings.Join(current, ",")))...)
+}
+
+// refreshK3sDefaultStorageClass sets default_storage_class from the cluster,
+// so an annotation reset by a K3s restart or upgrade is planned again
+func refreshK3sDefaultStorageClass(ctx context.Context, d *schema.ResourceData, kubeconfig []byte) error {
+	client, err := NewK8sClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	current, err := client.DefaultStorageClasses(ctx)
+	if err != nil {
+		return err
+	}
+	return d.Set("default_storage_class", strings.Join(current, ","))
+}
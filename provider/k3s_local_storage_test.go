@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func testStorageClass(name string, isDefault bool) *storagev1.StorageClass {
+	class := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if isDefault {
+		class.Annotations = map[string]string{defaultStorageClassAnnotation: "true"}
+	}
+	return class
+}
+
+func TestRenderServerConfig_LocalStoragePath(t *testing.T) {
+	got := RenderServerConfig(ClusterConfig{LocalStoragePath: "/mnt/nvme/k3s-storage"})
+	if want := "default-local-storage-path: \"/mnt/nvme/k3s-storage\"\n"; got != want {
+		t.Errorf("unexpected config:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestK8sClient_SetDefaultStorageClass(t *testing.T) {
+	ctx := context.Background()
+	client := &K8sClient{clientset: kubefake.NewClientset(
+		testStorageClass("local-path", true),
+		testStorageClass("longhorn", false),
+		testStorageClass("nfs", false),
+	)}
+
+	found, err := client.SetDefaultStorageClass(ctx, "longhorn")
+	if err != nil || !found {
+		t.Fatalf("expected longhorn to be found, got %v, %v", found, err)
+	}
+	if current, _ := client.DefaultStorageClasses(ctx); !reflect.DeepEqual(current, []string{"longhorn"}) {
+		t.Errorf("expected only longhorn to be default, got %v", current)
+	}
+	localPath, _ := client.clientset.StorageV1().StorageClasses().Get(ctx, "local-path", metav1.GetOptions{})
+	if localPath.Annotations[defaultStorageClassAnnotation] != "false" {
+		t.Errorf("expected local-path to lose the default, got %v", localPath.Annotations)
+	}
+
+	found, err = client.SetDefaultStorageClass(ctx, "ceph")
+	if err != nil || found {
+		t.Fatalf("expected ceph not to be found, got %v, %v", found, err)
+	}
+	if current, _ := client.DefaultStorageClasses(ctx); len(current) != 0 {
+		t.Errorf("expected no default class, got %v", current)
+	}
+}
+
+func TestResourceK3sClusterCustomizeDiff_DefaultStorageClass(t *testing.T) {
+	r := resourceK3sCluster()
+	config := func(class string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": "test",
+			"control_plane": []interface{}{map[string]interface{}{
+				"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+			}},
+			"local_storage": []interface{}{map[string]interface{}{"default_class": class}},
+		}
+	}
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config("longhorn")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prior, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prior.SetId("test")
+	// A K3s restart marked local-path as the default again
+	_ = prior.Set("default_storage_class", "local-path")
+
+	diff, err := r.Diff(context.Background(), prior.State(), terraform.NewResourceConfigRaw(config("longhorn")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr := diff.Attributes["default_storage_class"]; attr == nil || attr.New != "longhorn" {
+		t.Errorf("expected default_storage_class to be planned back to longhorn, got %+v", attr)
+	}
+
+	_ = prior.Set("default_storage_class", "longhorn")
+	diff, err = r.Diff(context.Background(), prior.State(), terraform.NewResourceConfigRaw(config("longhorn")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != nil && diff.Attributes["default_storage_class"] != nil {
+		t.Errorf("expected no change without drift, got %+v", diff.Attributes["default_storage_class"])
+	}
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// k3sMigrationExternalModule hands the cluster over to the
+// terraform-turingpi-modules k3s-cluster module: the resource becomes
+// read-only and destroying it leaves the cluster running
+const k3sMigrationExternalModule = "external-module"
+
+// k3sMigrating reports whether migration_mode hands the cluster over to the
+// external module
+func k3sMigrating(get func(string) interface{}) bool {
+	return get("migration_mode").(string) == k3sMigrationExternalModule
+}
+
+// k3sMigrationChanges returns the arguments a plan changes besides
+// migration_mode. Computed-only attributes are not arguments, and neither are
+// changes the plan suppresses or defaults filled into state written before
+// the argument existed.
+func k3sMigrationChanges(d *schema.ResourceDiff) []string {
+	var changed []string
+	for key, s := range resourceK3sCluster().Schema {
+		if key == "migration_mode" || (s.Computed && !s.Optional) {
+			continue
+		}
+		if d.HasChange(key) && len(d.GetChangedKeysPrefix(key)) > 0 && !defaultFilled(d, key, s) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// defaultFilled reports whether the plan only sets key to its schema default
+// because the prior state has no value for it
+func defaultFilled(d *schema.ResourceDiff, key string, s *schema.Schema) bool {
+	if s.Default == nil {
+		return false
+	}
+	old, new := d.GetChange(key)
+	if new != s.Default {
+		return false
+	}
+	if raw := d.GetRawState(); !raw.IsNull() && raw.Type().HasAttribute(key) {
+		return raw.GetAttr(key).IsNull()
+	}
+	// Without the raw state, a missing value reads as the zero value
+	return old == reflect.Zero(reflect.TypeOf(s.Default)).Interface()
+}
+
+// customizeK3sMigrationDiff rejects plans that would change or create a
+// cluster in external-module migration mode
+func customizeK3sMigrationDiff(d *schema.ResourceDiff) error {
+	if d.Id() == "" {
+		return fmt.Errorf("migration_mode = %q only applies to existing clusters: import the cluster, or remove migration_mode to create one", k3sMigrationExternalModule)
+	}
+	if changed := k3sMigrationChanges(d); len(changed) > 0 {
+		return fmt.Errorf("migration_mode = %q makes turingpi_k3s_cluster read-only, but the plan changes %s: "+
+			"revert these changes, or make them with the k3s-cluster module after adopting the cluster", k3sMigrationExternalModule, strings.Join(changed, ", "))
+	}
+	if d.HasChange("migration_mode") {
+		return d.SetNewComputed("module_inputs")
+	}
+	return nil
+}
+
+// withK3sModuleInputs sets module_inputs after fn, or clears it when the
+// cluster is not being migrated
+func withK3sModuleInputs(fn func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		diags := fn(ctx, d, meta)
+		if d.Id() == "" {
+			return diags
+		}
+		var inputs map[string]string
+		if k3sMigrating(d.Get) {
+			inputs = k3sModuleInputs(d)
+		}
+		if err := d.Set("module_inputs", inputs); err != nil {
+			return append(diags, diagFromErr(err)...)
+		}
+		return diags
+	}
+}
+
+// k3sModuleInputs returns the values the k3s-cluster module needs to adopt
+// the cluster without reinstalling it. Tokens and the kubeconfig honour
+// store_sensitive_outputs; the kubeconfig falls back to kubeconfig_path.
+func k3sModuleInputs(d *schema.ResourceData) map[string]string {
+	cfg := extractClusterConfig(d)
+	workers := make([]string, 0, len(cfg.Workers))
+	for _, w := range cfg.Workers {
+		workers = append(workers, w.Host)
+	}
+	inputs := map[string]string{
+		"cluster_name":       cfg.Name,
+		"k3s_version":        cfg.K3sVersion,
+		"api_endpoint":       d.Get("api_endpoint").(string),
+		"control_plane_host": cfg.ControlPlane.Host,
+		"worker_hosts":       strings.Join(workers, ","),
+		"pod_cidr":           cfg.PodCIDR,
+		"service_cidr":       cfg.ServiceCIDR,
+	}
+	if storeSensitiveOutputs(d) {
+		inputs["cluster_token"] = cfg.ClusterToken
+		inputs["node_token"] = d.Get("node_token").(string)
+		inputs["kubeconfig"] = readSensitiveOutput(d, "kubeconfig", "kubeconfig_path")
+	}
+	return inputs
+}
+
+// deleteK3sMigrated removes a cluster handed over to the external module
+// from state, leaving K3s installed on the nodes
+func deleteK3sMigrated(d *schema.ResourceData) diag.Diagnostics {
+	name := d.Get("name").(string)
+	d.SetId("")
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "K3s cluster left running",
+		Detail: fmt.Sprintf("turingpi_k3s_cluster %q has migration_mode = %q, so it was removed from state without uninstalling K3s. "+
+			"Manage the cluster with the k3s-cluster module from now on.", name, k3sMigrationExternalModule),
+	}}
+}
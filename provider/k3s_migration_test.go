@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func k3sMigrationConfig(extra map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+		}},
+		"worker": []interface{}{map[string]interface{}{
+			"host": "10.10.88.74", "ssh_user": "root", "ssh_password": "secret",
+		}},
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+	return config
+}
+
+// k3sMigrationPlan plans config against the state of a cluster created
+// without migration_mode, with the given attributes removed from state as in
+// state written before they existed
+func k3sMigrationPlan(t *testing.T, config map[string]interface{}, removed ...string) (*terraform.InstanceDiff, error) {
+	t.Helper()
+	r := resourceK3sCluster()
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(k3sMigrationConfig(nil)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetId("k3s-test")
+	prior := state.State()
+	for _, key := range removed {
+		delete(prior.Attributes, key)
+	}
+	return r.Diff(context.Background(), prior, terraform.NewResourceConfigRaw(config), nil)
+}
+
+func TestResourceK3sClusterCustomizeDiff_Migration(t *testing.T) {
+	diff, err := k3sMigrationPlan(t, k3sMigrationConfig(map[string]interface{}{"migration_mode": "external-module"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff == nil || diff.RequiresNew() || diff.Attributes["module_inputs.%"] == nil {
+		t.Errorf("expected an in-place update planning module_inputs, got %+v", diff)
+	}
+
+	// Other changes, including ones that would replace the cluster, are refused
+	_, err = k3sMigrationPlan(t, k3sMigrationConfig(map[string]interface{}{
+		"migration_mode": "external-module",
+		"name":           "renamed",
+		"k3s_version":    "v1.32.0+k3s1",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "the plan changes k3s_version, name") {
+		t.Errorf("expected the changed arguments to be refused, got %v", err)
+	}
+
+	// New clusters cannot start in migration mode
+	r := resourceK3sCluster()
+	_, err = r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(k3sMigrationConfig(map[string]interface{}{"migration_mode": "external-module"})), nil)
+	if err == nil || !strings.Contains(err.Error(), "only applies to existing clusters") {
+		t.Errorf("expected create to be refused, got %v", err)
+	}
+}
+
+func TestResourceK3sClusterCustomizeDiff_MigrationLegacyState(t *testing.T) {
+	// Arguments added after the cluster was created are missing from its state
+	// and filled with their defaults by the plan, which changes nothing
+	_, err := k3sMigrationPlan(t, k3sMigrationConfig(map[string]interface{}{"migration_mode": "external-module"}),
+		"mode", "file_permission", "store_sensitive_outputs", "kubeconfig_auth", "collect_failure_logs",
+		"control_plane.0.enable_gpu", "worker.0.enable_gpu")
+	if err != nil {
+		t.Errorf("expected defaults filled into legacy state to be allowed, got %v", err)
+	}
+	// A node block that relied on the former ssh_port default of 22
+	config := k3sMigrationConfig(map[string]interface{}{"migration_mode": "external-module"})
+	r := resourceK3sCluster()
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(k3sMigrationConfig(map[string]interface{}{
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret", "ssh_port": 22,
+		}},
+	})), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetId("k3s-test")
+	if _, err := r.Diff(context.Background(), state.State(), terraform.NewResourceConfigRaw(config), nil); err != nil {
+		t.Errorf("expected the former ssh_port default to be allowed, got %v", err)
+	}
+
+	// An explicit value is still a change
+	_, err = k3sMigrationPlan(t, k3sMigrationConfig(map[string]interface{}{
+		"migration_mode":  "external-module",
+		"kubeconfig_auth": k3sKubeconfigAuthServiceAccount,
+	}), "kubeconfig_auth")
+	if err == nil || !strings.Contains(err.Error(), "the plan changes kubeconfig_auth") {
+		t.Errorf("expected a non-default value to be refused, got %v", err)
+	}
+}
+
+func TestK3sModuleInputs(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, k3sMigrationConfig(map[string]interface{}{
+		"migration_mode": "external-module",
+		"cluster_token":  "cluster-secret",
+		"k3s_version":    "v1.31.4+k3s1",
+	}))
+	d.SetId("k3s-test")
+	_ = d.Set("node_token", "K10::node-secret")
+	_ = d.Set("kubeconfig", "apiVersion: v1\n")
+	_ = d.Set("api_endpoint", "https://10.10.88.73:6443")
+
+	inputs := k3sModuleInputs(d)
+	want := map[string]string{
+		"cluster_name":       "test",
+		"k3s_version":        "v1.31.4+k3s1",
+		"api_endpoint":       "https://10.10.88.73:6443",
+		"control_plane_host": "10.10.88.73",
+		"worker_hosts":       "10.10.88.74",
+		"cluster_token":      "cluster-secret",
+		"node_token":         "K10::node-secret",
+		"kubeconfig":         "apiVersion: v1\n",
+	}
+	for k, v := range want {
+		if inputs[k] != v {
+			t.Errorf("%s = %q, want %q", k, inputs[k], v)
+		}
+	}
+
+	_ = d.Set("store_sensitive_outputs", false)
+	for _, key := range []string{"cluster_token", "node_token", "kubeconfig"} {
+		if _, ok := k3sModuleInputs(d)[key]; ok {
+			t.Errorf("expected %s to be left out when store_sensitive_outputs is false", key)
+		}
+	}
+}
+
+func TestResourceK3sClusterDelete_Migration(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, k3sMigrationConfig(map[string]interface{}{"migration_mode": "external-module"}))
+	d.SetId("k3s-test")
+
+	// No SSH connection is attempted: the nodes are not reachable in tests
+	diags := resourceK3sClusterDelete(context.Background(), d, nil)
+	if diags.HasError() || len(diags) != 1 || diags[0].Summary != "K3s cluster left running" {
+		t.Errorf("expected a single warning, got %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected the cluster to be removed from state, got ID %q", d.Id())
+	}
+}
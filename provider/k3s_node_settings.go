@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// interfaceNamePattern matches Linux network interface names: at most 15
+// characters (IFNAMSIZ - 1), without whitespace, slashes or colons
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,15}$`)
+
+// validateInterfaceName is the ValidateFunc of flannel_iface
+func validateInterfaceName(v interface{}, k string) ([]string, []error) {
+	name := v.(string)
+	if !interfaceNamePattern.MatchString(name) || name == "." || name == ".." {
+		return nil, []error{fmt.Errorf("%s: %q is not a network interface name (e.g., eth0, end0)", k, name)}
+	}
+	return nil, nil
+}
+
+// k3sNodeFlagsChanged reports whether the installer flags of a node differ
+// between two versions of its block: the network flags and, on workers, the
+// role, kubelet_args and agent_flags
+func k3sNodeFlagsChanged(old, new NodeConfig) bool {
+	flags := func(node NodeConfig) string {
+		return strings.Join(append(k3sNodeFlags(node), k3sWorkerRoleFlags(node)...), " ")
+	}
+	return flags(old) != flags(new)
+}
+
+// k3sChangedNodes returns the nodes of blocks already in state whose
+// installer flags changed. Appended blocks are new nodes and are skipped.
+func k3sChangedNodes(old, new []interface{}, defaults map[string]interface{}, extract func(data, defaults map[string]interface{}) NodeConfig) []NodeConfig {
+	var changed []NodeConfig
+	for i := 0; i < len(old) && i < len(new); i++ {
+		oldData, _ := old[i].(map[string]interface{})
+		newData, _ := new[i].(map[string]interface{})
+		if oldData == nil || newData == nil {
+			continue
+		}
+		node := extract(newData, defaults)
+		if k3sNodeFlagsChanged(extract(oldData, defaults), node) {
+			changed = append(changed, node)
+		}
+	}
+	return changed
+}
+
+// updateK3sNodeSettings re-runs the install script on existing nodes whose
+// flannel_iface, node_ip, node_external_ip or, on workers, role,
+// kubelet_args or agent_flags changed, so the K3s service is rewritten with
+// the new flags and restarted. Like other changes that restart K3s, it
+// requires allow_restart. Role changes are also applied to the node objects,
+// which keep the labels and taints they registered with.
+func updateK3sNodeSettings(ctx context.Context, d *schema.ResourceData, meta interface{}, agentsOnly bool, logs *provisionLogs) diag.Diagnostics {
+	defaults := extractSSHDefaults(d)
+	var servers []NodeConfig
+	if !agentsOnly {
+		old, new := d.GetChange("control_plane")
+		servers = k3sChangedNodes(old.([]interface{}), new.([]interface{}), defaults, extractNodeConfigWithDefaults)
+	}
+	old, new := d.GetChange("worker")
+	workers := k3sChangedNodes(old.([]interface{}), new.([]interface{}), defaults, extractWorkerConfig)
+	if len(servers) == 0 && len(workers) == 0 {
+		return nil
+	}
+
+	var hosts []string
+	for _, node := range append(servers, workers...) {
+		hosts = append(hosts, node.Host)
+	}
+	if !d.Get("allow_restart").(bool) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "K3s node settings change requires a restart",
+			Detail: fmt.Sprintf("The K3s flags of %s changed. "+
+				"Set allow_restart = true to re-run the K3s installer on these nodes and restart K3s.", strings.Join(hosts, ", ")),
+		}}
+	}
+
+	cfg := extractClusterConfig(d)
+	provisioner := NewK3sProvisioner()
+	provisioner.CollectJournalOnFailure = d.Get("collect_failure_logs").(bool)
+	provisioner.NodeLabels = defaultMetadata(meta)
+	provisioner.Hardening = d.Get("hardening").(string)
+	provisioner.SELinux = k3sSELinuxMode(d)
+	provisioner.IPv6 = clusterUsesIPv6(cfg)
+	provisioner.Logs = logs
+	provisioner.Reinstall = true
+	timeout := time.Duration(d.Get("install_timeout").(int)) * time.Second
+	registries, err := RenderRegistriesConfig(d.Get("docker_config_json").(string))
+	if err != nil {
+		return diagFromErr(err)
+	}
+	provisioner.RegistriesConfig = registries
+
+	for _, server := range servers {
+		tflog.Info(ctx, "Re-running the K3s server install to apply node settings", map[string]interface{}{"host": server.Host})
+		report, err := provisioner.InstallK3sServer(ctx, server, cfg, timeout)
+		logProvisionReport(ctx, report)
+		if err != nil {
+			return diagFromErr(fmt.Errorf("failed to apply node settings on %s: %w", server.Host, err))
+		}
+	}
+	if len(workers) == 0 {
+		return nil
+	}
+
+	// agents_only clusters join with the configured server URL and token
+	serverURL, nodeToken := d.Get("server_url").(string), cfg.ClusterToken
+	var client *K8sClient
+	if !agentsOnly {
+		if nodeToken, err = provisioner.GetNodeToken(cfg.ControlPlane); err != nil {
+			return diagFromErr(err)
+		}
+		serverURL = fmt.Sprintf("https://%s:6443", cfg.ControlPlane.Host)
+
+		// Roles are set on the node objects through the cluster API
+		kubeconfig := readSensitiveOutput(d, "kubeconfig", "kubeconfig_path")
+		if kubeconfig == "" {
+			return diag.Errorf("worker role changes need the kubeconfig, which is neither in state nor at kubeconfig_path")
+		}
+		if client, err = NewK8sClient([]byte(kubeconfig)); err != nil {
+			return diagFromErr(err)
+		}
+		defer client.Close()
+	}
+	for _, worker := range workers {
+		tflog.Info(ctx, "Re-running the K3s agent install to apply node settings", map[string]interface{}{"host": worker.Host})
+		report, err := provisioner.InstallK3sAgent(ctx, worker, serverURL, nodeToken, cfg.K3sVersion, timeout)
+		logProvisionReport(ctx, report)
+		if err != nil {
+			return diagFromErr(fmt.Errorf("failed to apply node settings on %s: %w", worker.Host, err))
+		}
+		if agentsOnly {
+			err = provisioner.WaitForAgentActive(worker, timeout)
+		} else {
+			err = provisioner.WaitForNodeReady(cfg.ControlPlane, worker.Host, timeout)
+		}
+		if err != nil {
+			return diagFromErr(err)
+		}
+		// agents_only clusters refuse role changes in CustomizeDiff
+		if client != nil {
+			role := worker.Role
+			if err := client.UpdateNode(ctx, func(n *corev1.Node) { applyK3sWorkerRole(n, role) }, primaryNodeIP(worker.NodeIP), worker.Host); err != nil {
+				return diagFromErr(fmt.Errorf("failed to set the role of worker %s: %w", worker.Host, err))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateInterfaceName(t *testing.T) {
+	for _, name := range []string{"eth0", "end0", "enp1s0", "wlan0", "br-lan", "eth0.100"} {
+		if _, errs := validateInterfaceName(name, "flannel_iface"); len(errs) != 0 {
+			t.Errorf("expected %q to be valid, got %v", name, errs)
+		}
+	}
+	for _, name := range []string{"", "eth0; reboot", "eth 0", "a/b", "eth0:1", "..", "averyveryverylongname"} {
+		if _, errs := validateInterfaceName(name, "flannel_iface"); len(errs) == 0 {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestK3sNodeFlags_Quoted(t *testing.T) {
+	flags := k3sNodeFlags(NodeConfig{NodeIP: "10.10.88.73,fd00::73", NodeExternalIP: "192.168.1.50 "})
+	expected := []string{"--node-ip=10.10.88.73,fd00::73", "'--node-external-ip=192.168.1.50 '"}
+	if strings.Join(flags, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, flags)
+	}
+}
+
+func TestK3sChangedNodes(t *testing.T) {
+	old := []interface{}{
+		map[string]interface{}{"host": "10.10.88.74", "flannel_iface": "end0"},
+		map[string]interface{}{"host": "10.10.88.75", "node_ip": "10.10.88.75"},
+	}
+	new := []interface{}{
+		map[string]interface{}{"host": "10.10.88.74", "flannel_iface": "end0", "ssh_user": "admin"},
+		map[string]interface{}{"host": "10.10.88.75", "node_ip": "10.10.88.85"},
+		map[string]interface{}{"host": "10.10.88.76", "node_ip": "10.10.88.76"},
+	}
+
+	changed := k3sChangedNodes(old, new, nil, extractNodeConfigWithDefaults)
+	if len(changed) != 1 || changed[0].Host != "10.10.88.75" || changed[0].NodeIP != "10.10.88.85" {
+		t.Errorf("expected only the node with a changed node_ip, got %+v", changed)
+	}
+
+	old[0].(map[string]interface{})["kubelet_args"] = map[string]interface{}{"max-pods": "30"}
+	new[0].(map[string]interface{})["kubelet_args"] = map[string]interface{}{"max-pods": "60"}
+	if changed := k3sChangedNodes(old, new, nil, extractWorkerConfig); len(changed) != 2 {
+		t.Errorf("expected the kubelet_args change to be found, got %+v", changed)
+	}
+}
+
+// Test that a reinstall re-runs the installer on an installed node without downloading K3s
+func TestK3sProvisioner_InstallK3sAgent_Reinstall(t *testing.T) {
+	var installCmd string
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s ") {
+					return "installed", nil
+				}
+				if strings.Contains(cmd, "/tmp/k3s-install.sh agent") {
+					installCmd = cmd
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22, FlannelIface: "end1"}
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "v1.31.4+k3s1", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if installCmd != "" {
+		t.Fatalf("expected an installed agent to be left as is, got %q", installCmd)
+	}
+
+	provisioner.Reinstall = true
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "v1.31.4+k3s1", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(installCmd, k3sSkipDownloadEnv) || strings.Contains(installCmd, "INSTALL_K3S_VERSION") {
+		t.Errorf("expected the installer to keep the installed binary, got %q", installCmd)
+	}
+	if !strings.HasSuffix(installCmd, "agent --flannel-iface=end1") {
+		t.Errorf("expected the new flags in the install command, got %q", installCmd)
+	}
+}
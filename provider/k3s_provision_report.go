@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/k3s"
+)
+
+// Steps recorded in a ProvisionReport. The ones the provider shares with
+// pkg/k3s use its names.
+const (
+	k3sStepDisableSwap        = k3s.StepDisableSwap
+	k3sStepWriteConfig        = "write_config"
+	k3sStepApplyHardening     = "apply_hardening"
+	k3sStepPrepareSecurity    = "prepare_security"
+	k3sStepPrepareNetwork     = "prepare_network"
+	k3sStepPrepareAccelerator = "prepare_accelerator"
+	k3sStepCheckInstalled     = k3s.StepCheckInstalled
+	k3sStepStartExisting      = k3s.StepStartExisting
+	k3sStepDownloadScript     = k3s.StepDownloadScript
+	k3sStepInstall            = k3s.StepInstall
+	k3sStepWaitReady          = k3s.StepWaitReady
+	k3sStepDetectVersion      = k3s.StepDetectVersion
+)
+
+// ProvisionReport describes one K3s server or agent install; see
+// k3s.ProvisionReport
+type ProvisionReport = k3s.ProvisionReport
+
+// StepTiming is the wall-clock duration of one provisioning step
+type StepTiming = k3s.StepTiming
+
+// startReport starts recording the report of an install on host
+func (p *K3sProvisioner) startReport(host, role string) *k3s.Recorder {
+	return k3s.NewRecorder(host, role, p.commands.Load)
+}
+
+// detectVersion records the installed K3s version. Failures leave it empty.
+func (p *K3sProvisioner) detectVersion(run *k3s.Recorder, node NodeConfig) {
+	run.Begin(k3sStepDetectVersion)
+	if output, err := p.GetK3sVersion(node); err == nil {
+		run.Report().Version = k3s.ParseVersion(output)
+	}
+}
+
+func provisionReportSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Node host",
+			},
+			"role": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "K3s role installed on the node: server or agent",
+			},
+			"duration_seconds": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Total install time in seconds",
+			},
+			"step_seconds": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Time in seconds spent in each install step (e.g., download_script, install, wait_ready)",
+				Elem:        &schema.Schema{Type: schema.TypeFloat},
+			},
+			"commands_run": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of SSH commands run, including readiness polls",
+			},
+			"already_installed": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether K3s was already installed and only started",
+			},
+			"k3s_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "K3s version detected after install",
+			},
+		},
+	}
+}
+
+// roundSeconds converts d to seconds with millisecond precision
+func roundSeconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
+
+// flattenProvisionReport renders a report as a provision_report element
+func flattenProvisionReport(r *ProvisionReport) map[string]interface{} {
+	steps := make(map[string]interface{}, len(r.Steps))
+	for _, s := range r.Steps {
+		steps[s.Name] = roundSeconds(r.StepDuration(s.Name))
+	}
+	return map[string]interface{}{
+		"host":              r.Host,
+		"role":              r.Role,
+		"duration_seconds":  roundSeconds(r.Duration),
+		"step_seconds":      steps,
+		"commands_run":      r.CommandsRun,
+		"already_installed": r.AlreadyInstalled,
+		"k3s_version":       r.Version,
+	}
+}
+
+// logProvisionReport logs the step timings of an install
+func logProvisionReport(ctx context.Context, r *ProvisionReport) {
+	fields := map[string]interface{}{
+		"host":         r.Host,
+		"role":         r.Role,
+		"duration":     r.Duration.Round(time.Millisecond).String(),
+		"commands_run": r.CommandsRun,
+	}
+	for _, s := range r.Steps {
+		fields["step_"+s.Name] = s.Duration.Round(time.Millisecond).String()
+	}
+	tflog.Info(ctx, "K3s install timings", fields)
+}
+
+// setProvisionReports records the reports of this apply in provision_report,
+// replacing earlier entries for the same hosts
+func setProvisionReports(d *schema.ResourceData, reports []*ProvisionReport) error {
+	replaced := make(map[string]bool, len(reports))
+	for _, r := range reports {
+		replaced[r.Host] = true
+	}
+
+	var entries []interface{}
+	for _, e := range d.Get("provision_report").([]interface{}) {
+		if m, ok := e.(map[string]interface{}); ok && !replaced[m["host"].(string)] {
+			entries = append(entries, m)
+		}
+	}
+	for _, r := range reports {
+		entries = append(entries, flattenProvisionReport(r))
+	}
+	return d.Set("provision_report", entries)
+}
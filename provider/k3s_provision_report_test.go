@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestK3sProvisioner_InstallK3sAgent_Report(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				switch {
+				case strings.Contains(cmd, "test -f /usr/local/bin/k3s"):
+					return "not_installed", nil
+				case strings.HasPrefix(cmd, "k3s --version"):
+					return "k3s version v1.31.4+k3s1 (a1b2c3d4)\ngo version go1.22.9", nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHKey: []byte("fake-key"), SSHPort: 22}
+	report, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Host != "10.10.88.74" || report.Role != "agent" {
+		t.Errorf("unexpected host/role: %s/%s", report.Host, report.Role)
+	}
+	if report.Version != "v1.31.4+k3s1" {
+		t.Errorf("expected detected version v1.31.4+k3s1, got %q", report.Version)
+	}
+	if report.AlreadyInstalled {
+		t.Error("expected a fresh install")
+	}
+	if report.CommandsRun == 0 {
+		t.Error("expected commands to be counted")
+	}
+
+	var names []string
+	for _, s := range report.Steps {
+		names = append(names, s.Name)
+	}
+	want := []string{k3sStepDisableSwap, k3sStepWriteConfig, k3sStepCheckInstalled, k3sStepDownloadScript, k3sStepInstall, k3sStepDetectVersion}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected steps %v, want %v", names, want)
+	}
+}
+
+func TestK3sProvisioner_InstallK3sAgent_ReportOnFailure(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				switch {
+				case strings.Contains(cmd, "test -f /usr/local/bin/k3s"):
+					return "not_installed", nil
+				case strings.Contains(cmd, "get.k3s.io"):
+					return "", errors.New("could not resolve host")
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHKey: []byte("fake-key"), SSHPort: 22}
+	report, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second)
+	if err == nil {
+		t.Fatal("expected download failure")
+	}
+	if report == nil {
+		t.Fatal("expected a report for the failed install")
+	}
+	last := report.Steps[len(report.Steps)-1]
+	if last.Name != k3sStepDownloadScript {
+		t.Errorf("expected the report to end at the failed step, got %s", last.Name)
+	}
+	if report.Version != "" {
+		t.Errorf("expected no version after a failed install, got %q", report.Version)
+	}
+}
+
+func TestK3sProvisioner_InstallK3sAgent_ReportAlreadyInstalled(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.Contains(cmd, "test -f /usr/local/bin/k3s") {
+					return "installed", nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHKey: []byte("fake-key"), SSHPort: 22}
+	report, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.AlreadyInstalled {
+		t.Error("expected AlreadyInstalled")
+	}
+	for _, s := range report.Steps {
+		if s.Name == k3sStepInstall {
+			t.Error("expected the install step to be skipped")
+		}
+	}
+}
+
+func TestSetProvisionReports(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{})
+
+	initial := []*ProvisionReport{
+		{Host: "10.10.88.73", Role: "server", Duration: 90 * time.Second, CommandsRun: 12, Version: "v1.31.4+k3s1",
+			Steps: []StepTiming{{Name: k3sStepInstall, Duration: 60 * time.Second}, {Name: k3sStepWaitReady, Duration: 1500 * time.Millisecond}}},
+		{Host: "10.10.88.74", Role: "agent", Duration: 30 * time.Second},
+	}
+	if err := setProvisionReports(d, initial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A re-install of one worker replaces only its entry
+	if err := setProvisionReports(d, []*ProvisionReport{{Host: "10.10.88.74", Role: "agent", Duration: 45 * time.Second}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := d.Get("provision_report").([]interface{})
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(entries))
+	}
+	server := entries[0].(map[string]interface{})
+	if server["host"] != "10.10.88.73" || server["k3s_version"] != "v1.31.4+k3s1" || server["commands_run"] != 12 {
+		t.Errorf("unexpected server report: %v", server)
+	}
+	steps := server["step_seconds"].(map[string]interface{})
+	if steps[k3sStepInstall] != 60.0 || steps[k3sStepWaitReady] != 1.5 {
+		t.Errorf("unexpected step timings: %v", steps)
+	}
+	if agent := entries[1].(map[string]interface{}); agent["duration_seconds"] != 45.0 {
+		t.Errorf("expected the agent report to be replaced, got %v", agent)
+	}
+}
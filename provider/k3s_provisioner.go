@@ -3,12 +3,30 @@ package provider
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/k3s"
 )
 
+// installOutputTailLines is the number of installer and journal lines attached to install errors
+const installOutputTailLines = 40
+
+// k3sConfigPath is the K3s server configuration file managed by the provider
+const k3sConfigPath = "/etc/rancher/k3s/config.yaml"
+
+// k3sSkipDownloadEnv makes the install script keep the installed K3s binary
+// when it is re-run to change node flags
+const k3sSkipDownloadEnv = "INSTALL_K3S_SKIP_DOWNLOAD=true"
+
 // NodeConfig holds SSH connection details for a K3s node
 type NodeConfig struct {
 	Host        string
@@ -16,6 +34,22 @@ type NodeConfig struct {
 	SSHKey      []byte
 	SSHPassword string
 	SSHPort     int
+	// FlannelIface, NodeIP and NodeExternalIP pin K3s to a network interface
+	// when the node has more than one (e.g., switch network plus a USB uplink)
+	FlannelIface   string
+	NodeIP         string
+	NodeExternalIP string
+	// EnableGPU installs the container runtime bits of the node's NVIDIA or
+	// Rockchip accelerator before K3s is installed
+	EnableGPU bool
+	// SSHHostKeys pins the node's SSH host keys (authorized_keys format);
+	// empty accepts any host key
+	SSHHostKeys []string
+	// Role (worker, storage or edge), KubeletArgs and AgentFlags are the
+	// per-worker agent settings; see k3sWorkerRoleFlags
+	Role        string
+	KubeletArgs map[string]string
+	AgentFlags  []string
 }
 
 // ClusterConfig holds the K3s cluster configuration
@@ -27,25 +61,128 @@ type ClusterConfig struct {
 	ServiceCIDR  string
 	ControlPlane NodeConfig
 	Workers      []NodeConfig
+	// ServerConfig holds additional K3s server config.yaml keys (e.g., "disable": "traefik")
+	ServerConfig map[string]string
+	// Datastore is the external datastore of the control plane; the zero
+	// value keeps the default SQLite datastore
+	Datastore k3sDatastore
+	// LocalStoragePath is the local-path provisioner data directory, empty
+	// for the K3s default
+	LocalStoragePath string
 }
 
 // K3sProvisioner handles K3s cluster installation via SSH
 type K3sProvisioner struct {
 	clientFactory func() SSHClient
+	// CollectJournalOnFailure fetches the k3s service journal when the install script fails
+	CollectJournalOnFailure bool
+	// RegistriesConfig is registries.yaml content written to each node before
+	// K3s is installed, so private images pull on first boot. Empty to skip.
+	RegistriesConfig string
+	// Accelerators maps the host of each node with enable_gpu to the
+	// detected accelerator vendor (nvidia or rockchip)
+	Accelerators map[string]string
+	// DryRun records commands instead of running them; nil runs them
+	DryRun *dryRunRecorder
+	// Logs records each command and its output per node; nil records nothing
+	Logs *provisionLogs
+	// NodeLabels are applied to every node installed, from the provider
+	// default_metadata
+	NodeLabels map[string]string
+	// Hardening is the hardening profile applied to each node before K3s is
+	// installed ("cis"), or empty for none
+	Hardening string
+	// IPv6 enables IPv6 forwarding on each node before K3s is installed, for
+	// clusters with an IPv6 or dual-stack pod_cidr
+	IPv6 bool
+	// SELinux is the selinux mode of the cluster: how nodes with SELinux or
+	// AppArmor are prepared for the installer. Empty skips the detection.
+	SELinux string
+	// Reinstall re-runs the install script on nodes that already have K3s,
+	// keeping the installed binary, so changed node flags are written to the
+	// service and it is restarted
+	Reinstall bool
+	// commands counts SSH commands run, for ProvisionReport
+	commands atomic.Int64
 }
 
 // NewK3sProvisioner creates a new K3s provisioner
 func NewK3sProvisioner() *K3sProvisioner {
 	return &K3sProvisioner{
-		clientFactory: NewSSHClient,
+		clientFactory:           NewSSHClient,
+		CollectJournalOnFailure: true,
 	}
 }
 
 // NewK3sProvisionerWithClientFactory creates a provisioner with custom client factory (for testing)
 func NewK3sProvisionerWithClientFactory(factory func() SSHClient) *K3sProvisioner {
 	return &K3sProvisioner{
-		clientFactory: factory,
+		clientFactory:           factory,
+		CollectJournalOnFailure: true,
+	}
+}
+
+// InstallError is returned when the K3s install script fails.
+// It carries the tail of the installer output and, if collected, the service journal.
+type InstallError struct {
+	Host    string
+	Unit    string
+	Output  string
+	Journal string
+	Err     error
+}
+
+func (e *InstallError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "install script failed on %s: %v", e.Host, e.Err)
+	if e.Output != "" {
+		fmt.Fprintf(&b, "\n\nInstaller output (last %d lines):\n%s", installOutputTailLines, e.Output)
+	}
+	if e.Journal != "" {
+		fmt.Fprintf(&b, "\n\n%s service log:\n%s", e.Unit, e.Journal)
+	}
+	return b.String()
+}
+
+func (e *InstallError) Unwrap() error {
+	return e.Err
+}
+
+// tailLines returns the last n lines of s with surrounding whitespace trimmed
+func tailLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// runInstaller runs the K3s install script, logs its output, and on failure
+// returns an InstallError with the output tail and the unit's journal
+func (p *K3sProvisioner) runInstaller(ctx context.Context, node NodeConfig, installCmd, unit string) error {
+	output, err := p.runCommand(node, installCmd)
+	if output != "" {
+		tflog.Debug(ctx, "K3s installer output", map[string]interface{}{
+			"host":   node.Host,
+			"output": output,
+		})
+	}
+	if err == nil {
+		return nil
+	}
+
+	installErr := &InstallError{
+		Host:   node.Host,
+		Unit:   unit,
+		Output: tailLines(output, installOutputTailLines),
+		Err:    err,
 	}
+	if p.CollectJournalOnFailure {
+		if journal, jerr := p.runCommand(node, serviceLogCommand(unit, installOutputTailLines)); jerr == nil {
+			installErr.Journal = strings.TrimSpace(journal)
+		}
+	}
+	return installErr
 }
 
 // GenerateClusterToken generates a random cluster token
@@ -61,87 +198,285 @@ func GenerateClusterToken() string {
 // getSSHConfig creates SSHConfig from NodeConfig
 func (n *NodeConfig) getSSHConfig() *SSHConfig {
 	return &SSHConfig{
-		User:       n.SSHUser,
-		PrivateKey: n.SSHKey,
-		Password:   n.SSHPassword,
-		Timeout:    30 * time.Second,
+		User:         n.SSHUser,
+		PrivateKey:   n.SSHKey,
+		Password:     n.SSHPassword,
+		Timeout:      30 * time.Second,
+		HostKeyCheck: len(n.SSHHostKeys) > 0,
+		HostKeys:     n.SSHHostKeys,
 	}
 }
 
-// runCommand executes a command on a node via SSH
+// Retry settings for SSH connection failures in runCommand.
+// Replaced in tests to keep them fast.
+var (
+	sshDialRetryAttempts = 3
+	sshDialRetryDelay    = 5 * time.Second
+)
+
+// runCommand executes a command on a node via SSH. Connection failures are
+// retried; a command that ran and failed is returned immediately.
 func (p *K3sProvisioner) runCommand(node NodeConfig, cmd string) (string, error) {
+	var err error
+	for attempt := 1; attempt <= sshDialRetryAttempts; attempt++ {
+		if attempt > 1 {
+			metrics.countRetry(retryKindSSHDial)
+			time.Sleep(sshDialRetryDelay)
+		}
+
+		var output string
+		output, err = p.runCommandOnce(node, cmd)
+		if err == nil || !isSSHDialError(err) {
+			return output, err
+		}
+	}
+	return "", err
+}
+
+// runCommandOnce connects, runs a single command and disconnects
+func (p *K3sProvisioner) runCommandOnce(node NodeConfig, cmd string) (string, error) {
+	p.commands.Add(1)
+	if p.DryRun != nil {
+		return p.DryRun.ssh(node, cmd), nil
+	}
 	client := p.clientFactory()
 	if err := client.Connect(node.Host, node.SSHPort, node.getSSHConfig()); err != nil {
+		p.Logs.record(node.Host, cmd, "", err)
 		return "", fmt.Errorf("SSH connection failed: %w", err)
 	}
 	defer func() { _ = client.Close() }()
 
 	output, err := client.RunCommand(cmd)
+	p.Logs.record(node.Host, cmd, output, err)
 	if err != nil {
 		return output, fmt.Errorf("command failed: %w", err)
 	}
 	return output, nil
 }
 
-// InstallK3sServer installs K3s server on the control plane node
-func (p *K3sProvisioner) InstallK3sServer(ctx context.Context, node NodeConfig, cfg ClusterConfig, timeout time.Duration) error {
+// RenderServerConfig renders the K3s server config.yaml for a cluster.
+// Keys are sorted so the output, and its checksum, is stable.
+func RenderServerConfig(cfg ClusterConfig) string {
+	values := make(map[string]string, len(cfg.ServerConfig)+2)
+	for k, v := range cfg.ServerConfig {
+		values[k] = v
+	}
+	for k, v := range cfg.Datastore.configValues() {
+		values[k] = v
+	}
+	if cfg.PodCIDR != "" {
+		values["cluster-cidr"] = cfg.PodCIDR
+	}
+	if cfg.ServiceCIDR != "" {
+		values["service-cidr"] = cfg.ServiceCIDR
+	}
+	if cfg.LocalStoragePath != "" {
+		values["default-local-storage-path"] = cfg.LocalStoragePath
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		// JSON string quoting is valid YAML and avoids escaping issues
+		quoted, _ := json.Marshal(values[k])
+		fmt.Fprintf(&b, "%s: %s\n", k, quoted)
+	}
+	return b.String()
+}
+
+// ConfigChecksum returns the SHA-256 checksum of rendered config content
+func ConfigChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeServerConfig writes the rendered config.yaml to a node
+func (p *K3sProvisioner) writeServerConfig(node NodeConfig, cfg ClusterConfig) error {
+	if err := p.writeDatastoreFiles(node, cfg.Datastore); err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(RenderServerConfig(cfg)))
+	cmd := fmt.Sprintf("echo '%s' | base64 -d > %s && chmod 600 %s", encoded, k3sConfigPath, k3sConfigPath)
+	if _, err := p.runCommand(node, cmd); err != nil {
+		return fmt.Errorf("failed to write %s: %w", k3sConfigPath, err)
+	}
+	return nil
+}
+
+// GetServerConfigChecksum returns the checksum of config.yaml on a node,
+// or an empty string if the file does not exist
+func (p *K3sProvisioner) GetServerConfigChecksum(node NodeConfig) (string, error) {
+	output, err := p.runCommand(node, fmt.Sprintf("test -f %s && sha256sum %s | cut -d' ' -f1 || true", k3sConfigPath, k3sConfigPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", k3sConfigPath, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ApplyServerConfig rewrites config.yaml on the control plane and restarts K3s
+func (p *K3sProvisioner) ApplyServerConfig(ctx context.Context, node NodeConfig, cfg ClusterConfig, timeout time.Duration) error {
+	if err := p.writeServerConfig(node, cfg); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Restarting K3s to apply configuration", map[string]interface{}{
+		"host": node.Host,
+	})
+	if _, err := p.runCommand(node, serviceCommand("restart", "k3s")); err != nil {
+		return fmt.Errorf("failed to restart K3s: %w", err)
+	}
+
+	return p.waitForK3sReady(node, timeout)
+}
+
+// InstallK3sServer installs K3s server on the control plane node. The report
+// covers the steps that ran, also when the install fails.
+func (p *K3sProvisioner) InstallK3sServer(ctx context.Context, node NodeConfig, cfg ClusterConfig, timeout time.Duration) (report *ProvisionReport, err error) {
+	run := p.startReport(node.Host, k3s.RoleServer)
+	defer func() {
+		if err == nil {
+			p.detectVersion(run, node)
+		}
+		report = run.Finish()
+	}()
+
 	// 1. Disable swap
+	run.Begin(k3sStepDisableSwap)
 	if _, err := p.runCommand(node, "swapoff -a"); err != nil {
-		return fmt.Errorf("failed to disable swap: %w", err)
+		return nil, fmt.Errorf("failed to disable swap: %w", err)
 	}
 
-	// 2. Create K3s config directory
+	// 2. Create K3s config directory and write config.yaml
+	run.Begin(k3sStepWriteConfig)
 	if _, err := p.runCommand(node, "mkdir -p /etc/rancher/k3s"); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := p.writeServerConfig(node, cfg); err != nil {
+		return nil, err
+	}
+	if p.RegistriesConfig != "" {
+		if err := p.writeRegistriesConfig(node, p.RegistriesConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Hardening != "" {
+		run.Begin(k3sStepApplyHardening)
+		if err := p.applyHardening(node, true); err != nil {
+			return nil, err
+		}
+	}
+	if p.SELinux != "" {
+		run.Begin(k3sStepPrepareSecurity)
+	}
+	securityEnv, securityFlags, err := p.prepareSecurityModules(node)
+	if err != nil {
+		return nil, err
+	}
+	if p.IPv6 {
+		run.Begin(k3sStepPrepareNetwork)
+		if err := p.applyIPv6Sysctls(node); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.EnableGPU {
+		run.Begin(k3sStepPrepareAccelerator)
+	}
+	gpuFlags, err := p.acceleratorFlags(node)
+	if err != nil {
+		return nil, err
 	}
 
 	// 3. Check if K3s is already installed
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'")
-	if strings.TrimSpace(output) == "installed" {
-		// K3s already installed, just ensure it's running
-		if _, err := p.runCommand(node, "systemctl start k3s"); err != nil {
-			return fmt.Errorf("failed to start existing K3s: %w", err)
+	run.Begin(k3sStepCheckInstalled)
+	installed, err := p.CheckK3sInstalled(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check K3s installation: %w", err)
+	}
+	if installed && !p.Reinstall {
+		// K3s already installed, just ensure it's running. A restart picks up
+		// a container runtime installed for enable_gpu.
+		run.Report().AlreadyInstalled = true
+		run.Begin(k3sStepStartExisting)
+		start := serviceCommand("start", "k3s")
+		if node.EnableGPU {
+			start = serviceCommand("restart", "k3s")
+		}
+		if _, err := p.runCommand(node, start); err != nil {
+			return nil, fmt.Errorf("failed to start existing K3s: %w", err)
 		}
-		return p.waitForK3sReady(node, timeout)
+		run.Begin(k3sStepWaitReady)
+		return nil, p.waitForK3sReady(node, timeout)
 	}
 
 	// 4. Download K3s install script
-	downloadCmd := "curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh && chmod +x /tmp/k3s-install.sh"
-	if _, err := p.runCommand(node, downloadCmd); err != nil {
-		return fmt.Errorf("failed to download K3s install script: %w", err)
+	run.Begin(k3sStepDownloadScript)
+	if _, err := p.runCommand(node, k3sDownloadScriptCmd); err != nil {
+		return nil, fmt.Errorf("failed to download K3s install script: %w", err)
 	}
 
 	// 5. Build install command with environment variables
+	run.Begin(k3sStepInstall)
 	var envVars []string
-	if cfg.K3sVersion != "" {
+	if installed {
+		envVars = append(envVars, k3sSkipDownloadEnv)
+	} else if cfg.K3sVersion != "" {
 		envVars = append(envVars, fmt.Sprintf("INSTALL_K3S_VERSION=%s", cfg.K3sVersion))
 	}
 	if cfg.ClusterToken != "" {
 		envVars = append(envVars, fmt.Sprintf("K3S_TOKEN=%s", cfg.ClusterToken))
 	}
-
-	installCmd := fmt.Sprintf("%s /tmp/k3s-install.sh server", strings.Join(envVars, " "))
-	if _, err := p.runCommand(node, installCmd); err != nil {
-		return fmt.Errorf("failed to install K3s server: %w", err)
+	envVars = append(envVars, securityEnv...)
+
+	flags := append(k3sNodeFlags(node), gpuFlags...)
+	flags = append(flags, securityFlags...)
+	flags = append(flags, nodeLabelFlags(p.NodeLabels)...)
+	installCmd := strings.TrimSpace(fmt.Sprintf("%s /tmp/k3s-install.sh server %s", strings.Join(envVars, " "), strings.Join(flags, " ")))
+	if err := p.runInstaller(ctx, node, installCmd, "k3s"); err != nil {
+		return nil, fmt.Errorf("failed to install K3s server: %w", err)
 	}
 
 	// 6. Wait for K3s to be ready
-	return p.waitForK3sReady(node, timeout)
+	run.Begin(k3sStepWaitReady)
+	return nil, p.waitForK3sReady(node, timeout)
+}
+
+// k3sNodeFlags returns the per-node network flags passed to the K3s
+// installer, quoted for the install command line
+func k3sNodeFlags(node NodeConfig) []string {
+	var flags []string
+	if node.FlannelIface != "" {
+		flags = append(flags, shellQuote(fmt.Sprintf("--flannel-iface=%s", node.FlannelIface)))
+	}
+	if node.NodeIP != "" {
+		flags = append(flags, shellQuote(fmt.Sprintf("--node-ip=%s", node.NodeIP)))
+	}
+	if node.NodeExternalIP != "" {
+		flags = append(flags, shellQuote(fmt.Sprintf("--node-external-ip=%s", node.NodeExternalIP)))
+	}
+	return flags
 }
 
 // waitForK3sReady waits for K3s to be ready on the control plane
 func (p *K3sProvisioner) waitForK3sReady(node NodeConfig, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
+	if p.DryRun != nil {
+		p.DryRun.comment("wait up to %v for K3s on %s to report Ready", timeout, node.Host)
+		return nil
+	}
+	err := poll(context.Background(), pollInterval, timeout, "K3s", func(context.Context) (bool, error) {
 		output, err := p.runCommand(node, "k3s kubectl get nodes 2>/dev/null")
-		if err == nil && strings.Contains(output, "Ready") {
-			return nil
-		}
-		time.Sleep(5 * time.Second)
+		return err == nil && strings.Contains(output, "Ready"), err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for K3s to be ready: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for K3s to be ready after %v", timeout)
+	return nil
 }
 
 // GetNodeToken retrieves the node token from the control plane
@@ -150,6 +485,9 @@ func (p *K3sProvisioner) GetNodeToken(node NodeConfig) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get node token: %w", err)
 	}
+	if p.DryRun != nil {
+		return "<node-token>", nil
+	}
 	return strings.TrimSpace(output), nil
 }
 
@@ -167,77 +505,168 @@ func (p *K3sProvisioner) GetKubeconfig(node NodeConfig) (string, error) {
 	return kubeconfig, nil
 }
 
-// InstallK3sAgent installs K3s agent on a worker node
-func (p *K3sProvisioner) InstallK3sAgent(ctx context.Context, node NodeConfig, serverURL, nodeToken, k3sVersion string, timeout time.Duration) error {
+// InstallK3sAgent installs K3s agent on a worker node. The report covers the
+// steps that ran, also when the install fails.
+func (p *K3sProvisioner) InstallK3sAgent(ctx context.Context, node NodeConfig, serverURL, nodeToken, k3sVersion string, timeout time.Duration) (report *ProvisionReport, err error) {
+	run := p.startReport(node.Host, k3s.RoleAgent)
+	defer func() {
+		if err == nil {
+			p.detectVersion(run, node)
+		}
+		report = run.Finish()
+	}()
+
 	// 1. Disable swap
+	run.Begin(k3sStepDisableSwap)
 	if _, err := p.runCommand(node, "swapoff -a"); err != nil {
-		return fmt.Errorf("failed to disable swap: %w", err)
+		return nil, fmt.Errorf("failed to disable swap: %w", err)
 	}
 
-	// 2. Create K3s config directory
+	// 2. Create K3s config directory and write registry credentials
+	run.Begin(k3sStepWriteConfig)
 	if _, err := p.runCommand(node, "mkdir -p /etc/rancher/k3s"); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if p.RegistriesConfig != "" {
+		if err := p.writeRegistriesConfig(node, p.RegistriesConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Hardening != "" {
+		run.Begin(k3sStepApplyHardening)
+		if err := p.applyHardening(node, false); err != nil {
+			return nil, err
+		}
+	}
+	if p.SELinux != "" {
+		run.Begin(k3sStepPrepareSecurity)
+	}
+	securityEnv, securityFlags, err := p.prepareSecurityModules(node)
+	if err != nil {
+		return nil, err
+	}
+	if p.IPv6 {
+		run.Begin(k3sStepPrepareNetwork)
+		if err := p.applyIPv6Sysctls(node); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.EnableGPU {
+		run.Begin(k3sStepPrepareAccelerator)
+	}
+	gpuFlags, err := p.acceleratorFlags(node)
+	if err != nil {
+		return nil, err
 	}
 
 	// 3. Check if K3s agent is already installed
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'")
-	if strings.TrimSpace(output) == "installed" {
+	run.Begin(k3sStepCheckInstalled)
+	installed, err := p.CheckK3sInstalled(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check K3s installation: %w", err)
+	}
+	if installed && !p.Reinstall {
 		// K3s already installed, just ensure it's running
 		// Ignore error - might not be configured as agent yet
-		_, _ = p.runCommand(node, "systemctl start k3s-agent")
-		return nil
+		run.Report().AlreadyInstalled = true
+		run.Begin(k3sStepStartExisting)
+		start := serviceCommand("start", "k3s-agent")
+		if node.EnableGPU {
+			start = serviceCommand("restart", "k3s-agent")
+		}
+		_, _ = p.runCommand(node, start)
+		return nil, nil
 	}
 
 	// 4. Download K3s install script
-	downloadCmd := "curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh && chmod +x /tmp/k3s-install.sh"
-	if _, err := p.runCommand(node, downloadCmd); err != nil {
-		return fmt.Errorf("failed to download K3s install script: %w", err)
+	run.Begin(k3sStepDownloadScript)
+	if _, err := p.runCommand(node, k3sDownloadScriptCmd); err != nil {
+		return nil, fmt.Errorf("failed to download K3s install script: %w", err)
 	}
 
 	// 5. Build install command with environment variables
+	run.Begin(k3sStepInstall)
 	var envVars []string
 	envVars = append(envVars, fmt.Sprintf("K3S_URL=%s", serverURL))
 	envVars = append(envVars, fmt.Sprintf("K3S_TOKEN=%s", nodeToken))
-	if k3sVersion != "" {
+	if installed {
+		envVars = append(envVars, k3sSkipDownloadEnv)
+	} else if k3sVersion != "" {
 		envVars = append(envVars, fmt.Sprintf("INSTALL_K3S_VERSION=%s", k3sVersion))
 	}
-
-	installCmd := fmt.Sprintf("%s /tmp/k3s-install.sh agent", strings.Join(envVars, " "))
-	if _, err := p.runCommand(node, installCmd); err != nil {
-		return fmt.Errorf("failed to install K3s agent: %w", err)
+	envVars = append(envVars, securityEnv...)
+
+	flags := append(k3sNodeFlags(node), gpuFlags...)
+	flags = append(flags, securityFlags...)
+	flags = append(flags, nodeLabelFlags(p.NodeLabels)...)
+	flags = append(flags, k3sWorkerRoleFlags(node)...)
+	installCmd := strings.TrimSpace(fmt.Sprintf("%s /tmp/k3s-install.sh agent %s", strings.Join(envVars, " "), strings.Join(flags, " ")))
+	if err := p.runInstaller(ctx, node, installCmd, "k3s-agent"); err != nil {
+		return nil, fmt.Errorf("failed to install K3s agent: %w", err)
 	}
 
-	return nil
+	return nil, nil
 }
 
 // WaitForNodeReady waits for a specific node to be Ready in the cluster
 func (p *K3sProvisioner) WaitForNodeReady(controlPlane NodeConfig, nodeHost string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	// Extract hostname from the node - typically the last octet or full hostname
-	// K3s uses the system hostname, so we need to check what hostname the node reports
-	for time.Now().Before(deadline) {
+	if p.DryRun != nil {
+		p.DryRun.comment("wait up to %v for node %s to be Ready", timeout, nodeHost)
+		return nil
+	}
+	// K3s uses the system hostname, so the node is found by the IP in the wide output
+	err := poll(context.Background(), pollInterval, timeout, "node "+nodeHost, func(context.Context) (bool, error) {
 		// Get all nodes and check if our node's IP appears and is Ready
 		output, err := p.runCommand(controlPlane, "k3s kubectl get nodes -o wide 2>/dev/null")
-		if err == nil {
-			lines := strings.Split(output, "\n")
-			for _, line := range lines {
-				if strings.Contains(line, nodeHost) && strings.Contains(line, "Ready") {
-					return nil
-				}
+		if err != nil {
+			return false, err
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, nodeHost) && strings.Contains(line, "Ready") {
+				return true, nil
 			}
 		}
-		time.Sleep(5 * time.Second)
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for node %s to be Ready: %w", nodeHost, err)
 	}
+	return nil
+}
+
+// AgentActive reports whether the k3s-agent service is running on a node
+func (p *K3sProvisioner) AgentActive(node NodeConfig) bool {
+	// The status check exits non-zero for any state other than running
+	_, err := p.runCommand(node, serviceActiveCommand("k3s-agent"))
+	return err == nil
+}
 
-	return fmt.Errorf("timeout waiting for node %s to be Ready after %v", nodeHost, timeout)
+// WaitForAgentActive waits for the k3s-agent service to be running on a node.
+// Used when the server is managed externally and cannot be queried for node readiness.
+func (p *K3sProvisioner) WaitForAgentActive(node NodeConfig, timeout time.Duration) error {
+	if p.DryRun != nil {
+		p.DryRun.comment("wait up to %v for k3s-agent on %s to be active", timeout, node.Host)
+		return nil
+	}
+	err := poll(context.Background(), pollInterval, timeout, "k3s-agent", func(context.Context) (bool, error) {
+		return p.AgentActive(node), nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for k3s-agent on %s to be active: %w", node.Host, err)
+	}
+	return nil
 }
 
 // UninstallK3sServer removes K3s server from a node
 func (p *K3sProvisioner) UninstallK3sServer(node NodeConfig) error {
 	// Check if uninstall script exists
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s-uninstall.sh && echo 'exists' || echo 'not_exists'")
-	if strings.TrimSpace(output) != "exists" {
+	installed, err := p.fileExists(node, "/usr/local/bin/k3s-uninstall.sh")
+	if err != nil {
+		return fmt.Errorf("failed to check K3s server installation: %w", err)
+	}
+	if !installed {
 		return nil // K3s not installed
 	}
 
@@ -250,8 +679,11 @@ func (p *K3sProvisioner) UninstallK3sServer(node NodeConfig) error {
 // UninstallK3sAgent removes K3s agent from a node
 func (p *K3sProvisioner) UninstallK3sAgent(node NodeConfig) error {
 	// Check if uninstall script exists
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s-agent-uninstall.sh && echo 'exists' || echo 'not_exists'")
-	if strings.TrimSpace(output) != "exists" {
+	installed, err := p.fileExists(node, "/usr/local/bin/k3s-agent-uninstall.sh")
+	if err != nil {
+		return fmt.Errorf("failed to check K3s agent installation: %w", err)
+	}
+	if !installed {
 		return nil // K3s agent not installed
 	}
 
@@ -261,12 +693,26 @@ func (p *K3sProvisioner) UninstallK3sAgent(node NodeConfig) error {
 	return nil
 }
 
-// CheckK3sInstalled checks if K3s is installed on a node
+// CheckK3sInstalled checks if K3s is installed on a node.
+// Returns an error if the node cannot be reached, rather than reporting K3s as missing.
 func (p *K3sProvisioner) CheckK3sInstalled(node NodeConfig) (bool, error) {
-	output, _ := p.runCommand(node, "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'")
+	output, err := p.runCommand(node, "test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'")
+	if err != nil && isSSHConnectionError(err) {
+		return false, err
+	}
 	return strings.TrimSpace(output) == "installed", nil
 }
 
+// fileExists reports whether path exists on a node. Only connection failures
+// are returned as errors.
+func (p *K3sProvisioner) fileExists(node NodeConfig, path string) (bool, error) {
+	output, err := p.runCommand(node, fmt.Sprintf("test -f %s && echo 'exists' || echo 'not_exists'", path))
+	if err != nil && isSSHConnectionError(err) {
+		return false, err
+	}
+	return strings.TrimSpace(output) == "exists", nil
+}
+
 // GetK3sVersion returns the installed K3s version on a node
 func (p *K3sProvisioner) GetK3sVersion(node NodeConfig) (string, error) {
 	output, err := p.runCommand(node, "k3s --version 2>/dev/null | head -1")
@@ -286,3 +732,113 @@ func (p *K3sProvisioner) GetClusterNodes(controlPlane NodeConfig) ([]string, err
 	nodes := strings.Fields(strings.Trim(output, "'"))
 	return nodes, nil
 }
+
+// K3sNodeStatus describes a node as reported by the Kubernetes API
+type K3sNodeStatus struct {
+	Name       string
+	Ready      bool
+	Roles      []string
+	Version    string
+	InternalIP string
+}
+
+// k3sNodeList is the subset of `kubectl get nodes -o json` used for status reporting
+type k3sNodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+			Addresses []struct {
+				Type    string `json:"type"`
+				Address string `json:"address"`
+			} `json:"addresses"`
+			NodeInfo struct {
+				KubeletVersion string `json:"kubeletVersion"`
+			} `json:"nodeInfo"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// GetNodeStatuses returns readiness, roles and versions of all cluster nodes
+func (p *K3sProvisioner) GetNodeStatuses(controlPlane NodeConfig) ([]K3sNodeStatus, error) {
+	output, err := p.runCommand(controlPlane, "k3s kubectl get nodes -o json 2>/dev/null")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster nodes: %w", err)
+	}
+	return parseNodeStatuses(output)
+}
+
+// parseNodeStatuses parses `kubectl get nodes -o json` output
+func parseNodeStatuses(output string) ([]K3sNodeStatus, error) {
+	var list k3sNodeList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse node list: %w", err)
+	}
+
+	nodes := make([]K3sNodeStatus, 0, len(list.Items))
+	for _, item := range list.Items {
+		node := K3sNodeStatus{
+			Name:    item.Metadata.Name,
+			Version: item.Status.NodeInfo.KubeletVersion,
+		}
+		for _, c := range item.Status.Conditions {
+			if c.Type == "Ready" {
+				node.Ready = c.Status == "True"
+			}
+		}
+		for _, a := range item.Status.Addresses {
+			if a.Type == "InternalIP" {
+				node.InternalIP = a.Address
+			}
+		}
+		for label := range item.Metadata.Labels {
+			if role, ok := strings.CutPrefix(label, "node-role.kubernetes.io/"); ok && role != "" {
+				node.Roles = append(node.Roles, role)
+			}
+		}
+		sort.Strings(node.Roles)
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// GetComponentHealth returns the API server readiness checks (e.g., etcd, informer-sync)
+// mapped to "ok" or the reported failure
+func (p *K3sProvisioner) GetComponentHealth(controlPlane NodeConfig) (map[string]string, error) {
+	output, err := p.runCommand(controlPlane, "k3s kubectl get --raw='/readyz?verbose' 2>&1")
+	// readyz returns a non-zero exit status when any check fails, but the output is still useful
+	health := parseReadyz(output)
+	if len(health) == 0 && err != nil {
+		return nil, fmt.Errorf("failed to get component health: %w", err)
+	}
+	return health, nil
+}
+
+// parseReadyz parses verbose readyz output lines such as "[+]etcd ok" and "[-]etcd failed: reason"
+func parseReadyz(output string) map[string]string {
+	health := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		var ok bool
+		switch {
+		case strings.HasPrefix(line, "[+]"):
+			ok = true
+		case strings.HasPrefix(line, "[-]"):
+		default:
+			continue
+		}
+		name, rest, _ := strings.Cut(line[3:], " ")
+		if ok {
+			health[name] = "ok"
+		} else {
+			health[name] = strings.TrimSpace(rest)
+		}
+	}
+	return health
+}
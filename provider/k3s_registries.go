@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// k3sRegistriesPath is the containerd registry configuration read by K3s at startup
+const k3sRegistriesPath = "/etc/rancher/k3s/registries.yaml"
+
+// dockerConfig is the subset of ~/.docker/config.json used for registry credentials
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+}
+
+// dockerRegistryHost converts a Docker config auths key (which may be a URL,
+// e.g. https://index.docker.io/v1/) to the registry host K3s expects
+func dockerRegistryHost(key string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return host
+}
+
+// RenderRegistriesConfig converts a Docker config.json into K3s registries.yaml
+// content with one auth entry per registry. Returns an empty string for empty input.
+func RenderRegistriesConfig(dockerConfigJSON string) (string, error) {
+	if strings.TrimSpace(dockerConfigJSON) == "" {
+		return "", nil
+	}
+
+	var parsed dockerConfig
+	if err := json.Unmarshal([]byte(dockerConfigJSON), &parsed); err != nil {
+		return "", fmt.Errorf("invalid docker_config_json: %w", err)
+	}
+	if len(parsed.Auths) == 0 {
+		return "", fmt.Errorf("invalid docker_config_json: no registries in auths")
+	}
+
+	entries := make(map[string]map[string]string, len(parsed.Auths))
+	for key, entry := range parsed.Auths {
+		auth := make(map[string]string)
+		switch {
+		case entry.Username != "" || entry.Password != "":
+			auth["username"] = entry.Username
+			auth["password"] = entry.Password
+		case entry.Auth != "":
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return "", fmt.Errorf("invalid docker_config_json: auth for %s is not base64: %w", key, err)
+			}
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return "", fmt.Errorf("invalid docker_config_json: auth for %s is not username:password", key)
+			}
+			auth["username"] = username
+			auth["password"] = password
+		}
+		if entry.IdentityToken != "" {
+			auth["identity_token"] = entry.IdentityToken
+		}
+		if len(auth) == 0 {
+			return "", fmt.Errorf("invalid docker_config_json: no credentials for %s", key)
+		}
+		entries[dockerRegistryHost(key)] = auth
+	}
+
+	hosts := make([]string, 0, len(entries))
+	for host := range entries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var b strings.Builder
+	b.WriteString("configs:\n")
+	for _, host := range hosts {
+		quotedHost, _ := json.Marshal(host)
+		fmt.Fprintf(&b, "  %s:\n    auth:\n", quotedHost)
+
+		keys := make([]string, 0, len(entries[host]))
+		for k := range entries[host] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			// JSON string quoting is valid YAML and avoids escaping issues
+			quoted, _ := json.Marshal(entries[host][k])
+			fmt.Fprintf(&b, "      %s: %s\n", k, quoted)
+		}
+	}
+	return b.String(), nil
+}
+
+// RegistriesChecksum returns the checksum of rendered registries.yaml content,
+// or an empty string when no registry credentials are managed
+func RegistriesChecksum(content string) string {
+	if content == "" {
+		return ""
+	}
+	return ConfigChecksum(content)
+}
+
+// writeRegistriesConfig writes registries.yaml to a node, or removes it when
+// content is empty
+func (p *K3sProvisioner) writeRegistriesConfig(node NodeConfig, content string) error {
+	cmd := fmt.Sprintf("rm -f %s", k3sRegistriesPath)
+	if content != "" {
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		cmd = fmt.Sprintf("mkdir -p /etc/rancher/k3s && echo '%s' | base64 -d > %s && chmod 600 %s", encoded, k3sRegistriesPath, k3sRegistriesPath)
+	}
+	if _, err := p.runCommand(node, cmd); err != nil {
+		return fmt.Errorf("failed to write %s on %s: %w", k3sRegistriesPath, node.Host, err)
+	}
+	return nil
+}
+
+// GetRegistriesChecksum returns the checksum of registries.yaml on a node,
+// or an empty string if the file does not exist
+func (p *K3sProvisioner) GetRegistriesChecksum(node NodeConfig) (string, error) {
+	output, err := p.runCommand(node, fmt.Sprintf("test -f %s && sha256sum %s | cut -d' ' -f1 || true", k3sRegistriesPath, k3sRegistriesPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", k3sRegistriesPath, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ApplyRegistriesConfig rewrites registries.yaml on a node and restarts the
+// K3s unit (k3s or k3s-agent) so containerd picks up the credentials
+func (p *K3sProvisioner) ApplyRegistriesConfig(ctx context.Context, node NodeConfig, content, unit string, timeout time.Duration) error {
+	if err := p.writeRegistriesConfig(node, content); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Restarting K3s to apply registry credentials", map[string]interface{}{
+		"host": node.Host,
+		"unit": unit,
+	})
+	if _, err := p.runCommand(node, serviceCommand("restart", unit)); err != nil {
+		return fmt.Errorf("failed to restart %s on %s: %w", unit, node.Host, err)
+	}
+
+	if unit == "k3s-agent" {
+		return p.WaitForAgentActive(node, timeout)
+	}
+	return p.waitForK3sReady(node, timeout)
+}
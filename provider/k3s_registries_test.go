@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderRegistriesConfig(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("octocat:ghp_secret"))
+	input := `{"auths": {
+		"ghcr.io": {"auth": "` + auth + `"},
+		"https://index.docker.io/v1/": {"username": "dockeruser", "password": "pa\"ss"},
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com": {"username": "AWS", "password": "ecr-token"}
+	}}`
+
+	got, err := RenderRegistriesConfig(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `configs:
+  "123456789012.dkr.ecr.us-east-1.amazonaws.com":
+    auth:
+      password: "ecr-token"
+      username: "AWS"
+  "docker.io":
+    auth:
+      password: "pa\"ss"
+      username: "dockeruser"
+  "ghcr.io":
+    auth:
+      password: "ghp_secret"
+      username: "octocat"
+`
+	if got != expected {
+		t.Errorf("unexpected registries.yaml:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestRenderRegistriesConfig_Empty(t *testing.T) {
+	got, err := RenderRegistriesConfig("")
+	if err != nil || got != "" {
+		t.Errorf("expected empty result for empty input, got %q, %v", got, err)
+	}
+	if RegistriesChecksum(got) != "" {
+		t.Error("expected empty checksum when registries are not managed")
+	}
+}
+
+func TestRenderRegistriesConfig_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":         "{",
+		"no auths":         `{"auths": {}}`,
+		"bad base64":       `{"auths": {"ghcr.io": {"auth": "!!!"}}}`,
+		"no separator":     `{"auths": {"ghcr.io": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("token")) + `"}}}`,
+		"no credentials":   `{"auths": {"ghcr.io": {}}}`,
+		"credential store": `{"credsStore": "desktop"}`,
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := RenderRegistriesConfig(input); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// Test that registries.yaml is written before the agent is installed
+func TestK3sProvisioner_InstallK3sAgent_WritesRegistries(t *testing.T) {
+	var commands []string
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				if strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s ") {
+					return "not_installed", nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	provisioner.RegistriesConfig = "configs:\n"
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writeIdx, installIdx := -1, -1
+	for i, cmd := range commands {
+		if strings.Contains(cmd, "> "+k3sRegistriesPath) && strings.Contains(cmd, base64.StdEncoding.EncodeToString([]byte("configs:\n"))) {
+			writeIdx = i
+		}
+		if strings.Contains(cmd, "/tmp/k3s-install.sh agent") {
+			installIdx = i
+		}
+	}
+	if writeIdx == -1 || installIdx == -1 || writeIdx > installIdx {
+		t.Errorf("expected registries.yaml to be written before install, got commands %v", commands)
+	}
+}
+
+func TestK3sProvisioner_ApplyRegistriesConfig_Remove(t *testing.T) {
+	var commands []string
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+
+	if err := provisioner.ApplyRegistriesConfig(context.Background(), node, "", "k3s-agent", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(commands) < 2 || commands[0] != "rm -f "+k3sRegistriesPath || commands[1] != serviceCommand("restart", "k3s-agent") {
+		t.Errorf("expected file removal and agent restart, got %v", commands)
+	}
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// k3sNodeReady reports whether statuses contain node as a Ready cluster node,
+// matched by internal IP or name
+func k3sNodeReady(statuses []K3sNodeStatus, node NodeConfig) bool {
+	for _, s := range statuses {
+		if s.InternalIP == node.Host || s.Name == node.Host ||
+			(node.NodeIP != "" && s.InternalIP == primaryNodeIP(node.NodeIP)) {
+			return s.Ready
+		}
+	}
+	return false
+}
+
+// RepairServer restarts K3s on the control plane when it does not report
+// itself as a Ready node. It returns whether K3s was restarted.
+func (p *K3sProvisioner) RepairServer(controlPlane NodeConfig, timeout time.Duration) (bool, error) {
+	if statuses, err := p.GetNodeStatuses(controlPlane); err == nil && k3sNodeReady(statuses, controlPlane) {
+		return false, nil
+	}
+	if _, err := p.runCommand(controlPlane, serviceCommand("restart", "k3s")); err != nil {
+		return false, fmt.Errorf("failed to restart K3s on %s: %w", controlPlane.Host, err)
+	}
+	return true, p.waitForK3sReady(controlPlane, timeout)
+}
+
+// MissingWorkers returns the workers that are not Ready nodes of the cluster
+func (p *K3sProvisioner) MissingWorkers(controlPlane NodeConfig, workers []NodeConfig) ([]NodeConfig, error) {
+	statuses, err := p.GetNodeStatuses(controlPlane)
+	if err != nil {
+		return nil, err
+	}
+	var missing []NodeConfig
+	for _, worker := range workers {
+		if !k3sNodeReady(statuses, worker) {
+			missing = append(missing, worker)
+		}
+	}
+	return missing, nil
+}
+
+// repairK3sCluster brings a degraded cluster back: K3s is restarted on a
+// control plane that is not Ready, and the agent install is re-run on workers
+// that are missing from the cluster (or, for agents_only clusters, whose agent
+// is not running). The install starts K3s on workers that still have it.
+func repairK3sCluster(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	cfg := extractClusterConfig(d)
+	agentsOnly := d.Get("mode").(string) == k3sModeAgentsOnly
+	provisioner := NewK3sProvisioner()
+	provisioner.CollectJournalOnFailure = d.Get("collect_failure_logs").(bool)
+	provisioner.NodeLabels = defaultMetadata(meta)
+	provisioner.Hardening = d.Get("hardening").(string)
+	provisioner.SELinux = k3sSELinuxMode(d)
+	provisioner.IPv6 = clusterUsesIPv6(cfg)
+	timeout := time.Duration(d.Get("install_timeout").(int)) * time.Second
+	registries, err := RenderRegistriesConfig(d.Get("docker_config_json").(string))
+	if err != nil {
+		return diagFromErr(err)
+	}
+	provisioner.RegistriesConfig = registries
+
+	var repaired []string
+	var missing []NodeConfig
+	serverURL, nodeToken := d.Get("server_url").(string), cfg.ClusterToken
+	if agentsOnly {
+		for _, worker := range cfg.Workers {
+			if !provisioner.AgentActive(worker) {
+				missing = append(missing, worker)
+			}
+		}
+	} else {
+		restarted, err := provisioner.RepairServer(cfg.ControlPlane, timeout)
+		if err != nil {
+			return diagFromErr(fmt.Errorf("auto_repair: %w", err))
+		}
+		if restarted {
+			repaired = append(repaired, fmt.Sprintf("restarted K3s on control plane %s", cfg.ControlPlane.Host))
+		}
+		if missing, err = provisioner.MissingWorkers(cfg.ControlPlane, cfg.Workers); err != nil {
+			return diagFromErr(fmt.Errorf("auto_repair: %w", err))
+		}
+		if len(missing) > 0 {
+			if nodeToken, err = provisioner.GetNodeToken(cfg.ControlPlane); err != nil {
+				return diagFromErr(fmt.Errorf("auto_repair: %w", err))
+			}
+		}
+		serverURL = fmt.Sprintf("https://%s:6443", cfg.ControlPlane.Host)
+	}
+
+	for _, worker := range missing {
+		tflog.Info(ctx, "auto_repair: re-running K3s agent install", map[string]interface{}{"host": worker.Host})
+		var report *ProvisionReport
+		err := joinWithPowerCycle(ctx, d, meta, provisioner, worker, timeout, func() error {
+			var err error
+			report, err = provisioner.InstallK3sAgent(ctx, worker, serverURL, nodeToken, cfg.K3sVersion, timeout)
+			logProvisionReport(ctx, report)
+			return err
+		})
+		if err != nil {
+			return diagFromErr(fmt.Errorf("auto_repair: worker %s: %w", worker.Host, err))
+		}
+		if err := setProvisionReports(d, []*ProvisionReport{report}); err != nil {
+			return diagFromErr(err)
+		}
+		if agentsOnly {
+			err = provisioner.WaitForAgentActive(worker, timeout)
+		} else {
+			err = provisioner.WaitForNodeReady(cfg.ControlPlane, worker.Host, timeout)
+		}
+		if err != nil {
+			return diagFromErr(fmt.Errorf("auto_repair: worker %s: %w", worker.Host, err))
+		}
+		repaired = append(repaired, fmt.Sprintf("re-ran the agent install on worker %s", worker.Host))
+	}
+
+	if len(repaired) == 0 {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Degraded K3s cluster repaired",
+		Detail:   "auto_repair brought the cluster back:\n- " + strings.Join(repaired, "\n- "),
+	}}
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestK3sNodeReady(t *testing.T) {
+	statuses, err := parseNodeStatuses(testNodeListJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		node NodeConfig
+		want bool
+	}{
+		{"ready by IP", NodeConfig{Host: "10.10.88.73"}, true},
+		{"ready by name", NodeConfig{Host: "node1"}, true},
+		{"not ready", NodeConfig{Host: "10.10.88.74"}, false},
+		{"ready by node_ip", NodeConfig{Host: "192.168.1.73", NodeIP: "10.10.88.73"}, true},
+		{"missing", NodeConfig{Host: "10.10.88.75"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k3sNodeReady(statuses, tt.node); got != tt.want {
+				t.Errorf("k3sNodeReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestK3sProvisioner_MissingWorkers(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				return testNodeListJSON, nil
+			},
+		}
+	}
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+
+	controlPlane := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPort: 22}
+	workers := []NodeConfig{
+		{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22},
+		{Host: "10.10.88.75", SSHUser: "root", SSHPort: 22},
+	}
+	missing, err := provisioner.MissingWorkers(controlPlane, workers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(missing) != 2 || missing[0].Host != "10.10.88.74" || missing[1].Host != "10.10.88.75" {
+		t.Errorf("expected the not-ready and the unregistered worker, got %+v", missing)
+	}
+}
+
+func TestK3sProvisioner_RepairServer(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		wantRestart bool
+	}{
+		{"ready control plane is left alone", "10.10.88.73", false},
+		{"not ready control plane is restarted", "10.10.88.74", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restarted := false
+			mockFactory := func() SSHClient {
+				return &MockSSHClient{
+					RunCommandFunc: func(cmd string) (string, error) {
+						switch {
+						case strings.HasPrefix(cmd, "k3s kubectl get nodes -o json"):
+							return testNodeListJSON, nil
+						case strings.Contains(cmd, "restart k3s"):
+							restarted = true
+							return "", nil
+						case strings.HasPrefix(cmd, "k3s kubectl get nodes"):
+							return "node2   Ready    <none>   1m   v1.31.4+k3s1", nil
+						}
+						return "", nil
+					},
+				}
+			}
+			provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+
+			got, err := provisioner.RepairServer(NodeConfig{Host: tt.host, SSHUser: "root", SSHPort: 22}, time.Second)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.wantRestart || restarted != tt.wantRestart {
+				t.Errorf("expected restart %v, got %v (command run: %v)", tt.wantRestart, got, restarted)
+			}
+		})
+	}
+}
+
+func TestResourceK3sClusterCustomizeDiff_AutoRepair(t *testing.T) {
+	raw := map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+		}},
+	}
+
+	for _, autoRepair := range []bool{false, true} {
+		r := resourceK3sCluster()
+		config := map[string]interface{}{"auto_repair": autoRepair}
+		for k, v := range raw {
+			config[k] = v
+		}
+
+		// Prior state of a created cluster that refresh found degraded
+		create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, err := schema.InternalMap(r.Schema).Data(nil, create)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state.SetId("k3s-test")
+		_ = state.Set("cluster_status", "degraded")
+
+		diff, err := r.Diff(context.Background(), state.State(), terraform.NewResourceConfigRaw(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		planned := diff != nil && diff.Attributes["cluster_status"] != nil && diff.Attributes["cluster_status"].NewComputed
+		if planned != autoRepair {
+			t.Errorf("auto_repair = %v: expected repair planned %v, got diff %+v", autoRepair, autoRepair, diff)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// SELinux modes of turingpi_k3s_cluster
+const (
+	// k3sSELinuxAuto installs container-selinux on enforcing hosts, so the
+	// K3s installer can add the k3s-selinux policy, and starts K3s with
+	// --selinux. On permissive hosts the policy is skipped.
+	k3sSELinuxAuto = "auto"
+	// k3sSELinuxWarn never installs the policy: the installer only warns
+	// when it is missing. For air-gapped hosts with the policy preinstalled.
+	k3sSELinuxWarn = "warn"
+)
+
+// k3sSELinuxMode returns the selinux mode of a cluster, auto when unset
+func k3sSELinuxMode(d *schema.ResourceData) string {
+	if mode := d.Get("selinux").(string); mode != "" {
+		return mode
+	}
+	return k3sSELinuxAuto
+}
+
+// k3sDetectSecurityCmd reports the SELinux mode, whether AppArmor is enabled
+// and its parser installed, and the package manager of a node
+const k3sDetectSecurityCmd = `echo "selinux=$(getenforce 2>/dev/null)"
+echo "apparmor=$(cat /sys/module/apparmor/parameters/enabled 2>/dev/null)"
+echo "apparmor_parser=$(command -v apparmor_parser || ls /sbin/apparmor_parser 2>/dev/null)"
+for m in dnf yum apt-get; do
+  if command -v $m >/dev/null 2>&1; then echo "package_manager=$m"; break; fi
+done`
+
+// k3sHostSecurity is the security module state of a node
+type k3sHostSecurity struct {
+	// SELinux is enforcing, permissive or disabled; empty without SELinux
+	SELinux        string
+	AppArmor       bool
+	AppArmorParser bool
+	// PackageManager is dnf, yum or apt-get; empty when none was found
+	PackageManager string
+}
+
+// parseHostSecurity parses the output of k3sDetectSecurityCmd
+func parseHostSecurity(output string) k3sHostSecurity {
+	var host k3sHostSecurity
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "selinux":
+			host.SELinux = strings.ToLower(value)
+		case "apparmor":
+			host.AppArmor = value == "Y"
+		case "apparmor_parser":
+			host.AppArmorParser = value != ""
+		case "package_manager":
+			host.PackageManager = value
+		}
+	}
+	return host
+}
+
+// k3sSELinuxSettings returns the installer environment and K3s flags for a
+// host. Hosts without SELinux need neither.
+func k3sSELinuxSettings(mode string, host k3sHostSecurity) (env, flags []string) {
+	skipPolicy := []string{"INSTALL_K3S_SKIP_SELINUX_RPM=true", "INSTALL_K3S_SELINUX_WARN=true"}
+	switch {
+	case mode == k3sSELinuxWarn:
+		return skipPolicy, nil
+	case host.SELinux == "enforcing":
+		return nil, []string{"--selinux"}
+	case host.SELinux != "":
+		return skipPolicy, nil
+	}
+	return nil, nil
+}
+
+// prepareSecurityModules makes a node ready for the K3s installer under
+// SELinux and AppArmor, and returns the installer environment and K3s flags
+// that go with it. Does nothing unless SELinux is set.
+//
+// An enforcing host gets container-selinux, which the k3s-selinux policy
+// depends on but the installer does not install; without it the install
+// fails halfway through. An AppArmor host without apparmor_parser gets the
+// apparmor package, which containerd needs to load its profile.
+func (p *K3sProvisioner) prepareSecurityModules(node NodeConfig) ([]string, []string, error) {
+	if p.SELinux == "" {
+		return nil, nil, nil
+	}
+	output, err := p.runCommand(node, k3sDetectSecurityCmd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to detect SELinux and AppArmor on %s: %w", node.Host, err)
+	}
+	host := parseHostSecurity(output)
+
+	if host.AppArmor && !host.AppArmorParser {
+		if host.PackageManager != "apt-get" {
+			return nil, nil, fmt.Errorf("AppArmor is enabled on %s but apparmor_parser is missing: install the apparmor package", node.Host)
+		}
+		if _, err := p.runCommand(node, "DEBIAN_FRONTEND=noninteractive apt-get install -y -q apparmor"); err != nil {
+			return nil, nil, fmt.Errorf("failed to install apparmor on %s: %w", node.Host, err)
+		}
+	}
+
+	if p.SELinux == k3sSELinuxAuto && host.SELinux == "enforcing" {
+		if host.PackageManager != "dnf" && host.PackageManager != "yum" {
+			return nil, nil, fmt.Errorf("SELinux is enforcing on %s but neither dnf nor yum was found to install container-selinux: "+
+				"install container-selinux and k3s-selinux, then set selinux = %q", node.Host, k3sSELinuxWarn)
+		}
+		if _, err := p.runCommand(node, host.PackageManager+" install -y container-selinux"); err != nil {
+			return nil, nil, fmt.Errorf("failed to install container-selinux on %s: %w (set selinux = %q if the node cannot reach its package repositories and has the policy installed)", node.Host, err, k3sSELinuxWarn)
+		}
+	}
+
+	env, flags := k3sSELinuxSettings(p.SELinux, host)
+	return env, flags, nil
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHostSecurity(t *testing.T) {
+	host := parseHostSecurity("selinux=Enforcing\napparmor=\napparmor_parser=\npackage_manager=dnf\n")
+	if host.SELinux != "enforcing" || host.AppArmor || host.PackageManager != "dnf" {
+		t.Errorf("unexpected Rocky host: %+v", host)
+	}
+
+	host = parseHostSecurity("selinux=\napparmor=Y\napparmor_parser=/usr/sbin/apparmor_parser\npackage_manager=apt-get\n")
+	if host.SELinux != "" || !host.AppArmor || !host.AppArmorParser || host.PackageManager != "apt-get" {
+		t.Errorf("unexpected Ubuntu host: %+v", host)
+	}
+}
+
+func TestK3sSELinuxSettings(t *testing.T) {
+	tests := []struct {
+		mode, selinux string
+		env, flags    string
+	}{
+		{k3sSELinuxAuto, "", "", ""},
+		{k3sSELinuxAuto, "enforcing", "", "--selinux"},
+		{k3sSELinuxAuto, "permissive", "INSTALL_K3S_SKIP_SELINUX_RPM=true INSTALL_K3S_SELINUX_WARN=true", ""},
+		{k3sSELinuxAuto, "disabled", "INSTALL_K3S_SKIP_SELINUX_RPM=true INSTALL_K3S_SELINUX_WARN=true", ""},
+		{k3sSELinuxWarn, "enforcing", "INSTALL_K3S_SKIP_SELINUX_RPM=true INSTALL_K3S_SELINUX_WARN=true", ""},
+	}
+	for _, tt := range tests {
+		env, flags := k3sSELinuxSettings(tt.mode, k3sHostSecurity{SELinux: tt.selinux})
+		if strings.Join(env, " ") != tt.env || strings.Join(flags, " ") != tt.flags {
+			t.Errorf("%s/%q: got env %v, flags %v", tt.mode, tt.selinux, env, flags)
+		}
+	}
+}
+
+// selinuxCommands installs a K3s agent on a host whose security modules
+// report detected, and returns the commands run
+func selinuxCommands(t *testing.T, mode, detected string) ([]string, error) {
+	t.Helper()
+	var commands []string
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				switch {
+				case cmd == k3sDetectSecurityCmd:
+					return detected, nil
+				case strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s "):
+					return "not_installed", nil
+				}
+				return "", nil
+			},
+		}
+	})
+	provisioner.SELinux = mode
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+	_, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second)
+	return commands, err
+}
+
+func TestK3sProvisioner_SELinuxEnforcing(t *testing.T) {
+	commands, err := selinuxCommands(t, k3sSELinuxAuto, "selinux=Enforcing\npackage_manager=dnf\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	install := commandIndex(commands, "/tmp/k3s-install.sh agent")
+	if i := commandIndex(commands, "dnf install -y container-selinux"); i == -1 || i > install {
+		t.Errorf("expected container-selinux installed before K3s, got commands %v", commands)
+	}
+	if !strings.Contains(commands[install], "--selinux") || strings.Contains(commands[install], "INSTALL_K3S_SKIP_SELINUX_RPM") {
+		t.Errorf("expected the policy to be installed and --selinux passed, got %q", commands[install])
+	}
+
+	_, err = selinuxCommands(t, k3sSELinuxAuto, "selinux=Enforcing\n")
+	if err == nil || !strings.Contains(err.Error(), "neither dnf nor yum") {
+		t.Errorf("expected an error without a package manager, got %v", err)
+	}
+}
+
+func TestK3sProvisioner_SELinuxWarn(t *testing.T) {
+	commands, err := selinuxCommands(t, k3sSELinuxWarn, "selinux=Enforcing\npackage_manager=dnf\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if commandIndex(commands, "container-selinux") != -1 {
+		t.Error("expected no policy packages in warn mode")
+	}
+	install := commands[commandIndex(commands, "/tmp/k3s-install.sh agent")]
+	if !strings.Contains(install, "INSTALL_K3S_SKIP_SELINUX_RPM=true INSTALL_K3S_SELINUX_WARN=true") {
+		t.Errorf("expected the installer to skip the policy, got %q", install)
+	}
+}
+
+func TestK3sProvisioner_AppArmorParser(t *testing.T) {
+	commands, err := selinuxCommands(t, k3sSELinuxAuto, "apparmor=Y\napparmor_parser=\npackage_manager=apt-get\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i := commandIndex(commands, "apt-get install -y -q apparmor"); i == -1 || i > commandIndex(commands, "/tmp/k3s-install.sh agent") {
+		t.Errorf("expected apparmor installed before K3s, got commands %v", commands)
+	}
+
+	commands, _ = selinuxCommands(t, "", "apparmor=Y\n")
+	if commandIndex(commands, k3sDetectSecurityCmd) != -1 {
+		t.Error("expected no detection without an SELinux mode")
+	}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/k3s"
+)
+
+// K3s runs as a systemd unit on most images. On images without systemd, such
+// as Alpine, the install script sets it up as an OpenRC service instead,
+// logging to /var/log/<unit>.log. The commands below detect the init system
+// on the node, so each works on both.
+
+// k3sDownloadScriptCmd fetches the install script with curl, or with the
+// BusyBox wget of minimal images that ship without curl
+const k3sDownloadScriptCmd = "(curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh || wget -qO /tmp/k3s-install.sh https://get.k3s.io) && chmod +x /tmp/k3s-install.sh"
+
+// initSystemCheck succeeds when the node was booted with systemd (sd_booted)
+const initSystemCheck = k3s.InitSystemCheck
+
+// serviceCommand returns the command to start or restart a K3s unit
+// (k3s or k3s-agent)
+func serviceCommand(action, unit string) string {
+	return k3s.ServiceCommand(action, unit)
+}
+
+// serviceActiveCommand returns a command that exits zero only while the unit
+// is running
+func serviceActiveCommand(unit string) string {
+	return fmt.Sprintf("if %s; then systemctl is-active --quiet %s; else rc-service %s status >/dev/null 2>&1; fi", initSystemCheck, unit, unit)
+}
+
+// serviceLogCommand returns a command that prints the last lines lines of the
+// unit's log
+func serviceLogCommand(unit string, lines int) string {
+	return fmt.Sprintf("if %s; then journalctl -u %s --no-pager -n %d; else tail -n %d /var/log/%s.log; fi 2>/dev/null", initSystemCheck, unit, lines, lines, unit)
+}
@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Values of kubeconfig_auth
+const (
+	k3sKubeconfigAuthCertificate    = "client_certificate"
+	k3sKubeconfigAuthServiceAccount = "service_account"
+)
+
+// defaultK3sServiceAccount is the ServiceAccount the kubeconfig authenticates
+// as with kubeconfig_auth = service_account
+const defaultK3sServiceAccount = "turingpi-admin"
+
+// k3sServiceAccountNamePattern matches valid kubeconfig_service_account names
+var k3sServiceAccountNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// k3sServiceAccountNamespace holds the kubeconfig ServiceAccount and its token
+const k3sServiceAccountNamespace = "kube-system"
+
+// k3sTokenSecretName is the Secret holding the long-lived token of account
+func k3sTokenSecretName(account string) string {
+	return account + "-token"
+}
+
+// k3sKubeconfigAccount returns the ServiceAccount the exported kubeconfig
+// authenticates as, or "" when it uses the K3s client certificate
+func k3sKubeconfigAccount(d *schema.ResourceData) string {
+	return k3sAccountFor(d.Get("kubeconfig_auth"), d.Get("kubeconfig_service_account"))
+}
+
+// k3sAccountFor returns the ServiceAccount of a kubeconfig_auth and
+// kubeconfig_service_account pair, or "" for client certificates. State
+// written before kubeconfig_auth existed has an empty value, which is the
+// client certificate.
+func k3sAccountFor(auth, name interface{}) string {
+	if auth.(string) != k3sKubeconfigAuthServiceAccount {
+		return ""
+	}
+	return name.(string)
+}
+
+// k3sKubeconfigAuthChanged reports whether the kubeconfig credentials must be
+// issued, switched or reissued: the ServiceAccount changed, or its token
+// Secret was found deleted
+func k3sKubeconfigAuthChanged(getChange func(string) (interface{}, interface{})) bool {
+	oldAuth, newAuth := getChange("kubeconfig_auth")
+	oldName, newName := getChange("kubeconfig_service_account")
+	account := k3sAccountFor(newAuth, newName)
+	if k3sAccountFor(oldAuth, oldName) != account {
+		return true
+	}
+	_, tokenSecret := getChange("kubeconfig_token_secret")
+	return account != "" && tokenSecret.(string) == ""
+}
+
+// serviceAccountKubeconfig rewrites the admin kubeconfig of K3s so its
+// current context authenticates as account with token instead of the
+// cluster admin client certificate
+func serviceAccountKubeconfig(adminKubeconfig, account, token string) (string, error) {
+	config, err := clientcmd.Load([]byte(adminKubeconfig))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	ctx := config.Contexts[config.CurrentContext]
+	if ctx == nil {
+		return "", fmt.Errorf("no current context in kubeconfig")
+	}
+
+	config.AuthInfos = map[string]*clientcmdapi.AuthInfo{account: {Token: token}}
+	ctx.AuthInfo = account
+	out, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return string(out), nil
+}
+
+// issueK3sKubeconfig returns the kubeconfig to export. With
+// kubeconfig_auth = service_account, the ServiceAccount, its cluster-admin
+// binding and token Secret are created when missing, and the kubeconfig
+// carries the token. Otherwise the admin kubeconfig is returned unchanged.
+func issueK3sKubeconfig(ctx context.Context, d *schema.ResourceData, adminKubeconfig string, timeout time.Duration) (string, error) {
+	account := k3sKubeconfigAccount(d)
+	if account == "" {
+		return adminKubeconfig, nil
+	}
+
+	client, err := NewK8sClient([]byte(adminKubeconfig))
+	if err != nil {
+		return "", err
+	}
+	secret := k3sTokenSecretName(account)
+	if err := client.EnsureAdminServiceAccount(ctx, k3sServiceAccountNamespace, account, secret); err != nil {
+		return "", err
+	}
+
+	// The token controller fills in the Secret shortly after it is created
+	var token string
+	err = poll(ctx, 2*time.Second, timeout, "the token of ServiceAccount "+account, func(ctx context.Context) (bool, error) {
+		var tokenErr error
+		token, tokenErr = client.ServiceAccountToken(ctx, k3sServiceAccountNamespace, secret)
+		return token != "", tokenErr
+	})
+	if err != nil {
+		return "", err
+	}
+	tflog.Info(ctx, "Issued kubeconfig ServiceAccount token", map[string]interface{}{
+		"service_account": k3sServiceAccountNamespace + "/" + account,
+	})
+	return serviceAccountKubeconfig(adminKubeconfig, account, token)
+}
+
+// revokeK3sServiceAccount deletes a kubeconfig ServiceAccount with its
+// binding and token Secret, which invalidates kubeconfigs holding the token
+func revokeK3sServiceAccount(ctx context.Context, adminKubeconfig, account string) error {
+	client, err := NewK8sClient([]byte(adminKubeconfig))
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteAdminServiceAccount(ctx, k3sServiceAccountNamespace, account, k3sTokenSecretName(account)); err != nil {
+		return err
+	}
+	tflog.Info(ctx, "Revoked kubeconfig ServiceAccount", map[string]interface{}{
+		"service_account": k3sServiceAccountNamespace + "/" + account,
+	})
+	return nil
+}
+
+// setK3sKubeconfig sets kubeconfig and the credentials parsed from it:
+// host, cluster_ca_certificate, client_certificate, client_key, token and
+// kubeconfig_token_secret. The sensitive ones honour store_sensitive_outputs.
+func setK3sKubeconfig(d *schema.ResourceData, kubeconfig string) error {
+	if err := setSensitiveOutput(d, "kubeconfig", kubeconfig); err != nil {
+		return err
+	}
+	if err := setKubeconfigCredentials(d, kubeconfig); err != nil {
+		return err
+	}
+
+	token, tokenSecret := "", ""
+	if account := k3sKubeconfigAccount(d); account != "" && kubeconfig != "" {
+		creds, err := parseKubeconfigCredentials(kubeconfig)
+		if err != nil {
+			return err
+		}
+		token = creds.Token
+		tokenSecret = k3sServiceAccountNamespace + "/" + k3sTokenSecretName(account)
+	}
+	if err := setSensitiveOutput(d, "token", token); err != nil {
+		return err
+	}
+	return d.Set("kubeconfig_token_secret", tokenSecret)
+}
+
+// refreshK3sKubeconfig refreshes the kubeconfig outputs during Read and
+// returns the kubeconfig, or "" when the cluster could not be asked. A
+// deleted token Secret clears kubeconfig_token_secret, so the next plan
+// issues a new token.
+func refreshK3sKubeconfig(ctx context.Context, d *schema.ResourceData, adminKubeconfig string) (string, diag.Diagnostics) {
+	account := k3sKubeconfigAccount(d)
+	if account == "" {
+		return adminKubeconfig, diagFromErr(setK3sKubeconfig(d, adminKubeconfig))
+	}
+
+	client, err := NewK8sClient([]byte(adminKubeconfig))
+	if err != nil {
+		return "", diagFromErr(err)
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	token, err := client.ServiceAccountToken(lookupCtx, k3sServiceAccountNamespace, k3sTokenSecretName(account))
+	switch {
+	case apierrors.IsNotFound(err) || (err == nil && token == ""):
+		tflog.Warn(ctx, "Kubeconfig ServiceAccount token was revoked; the next apply issues a new one", map[string]interface{}{
+			"service_account": k3sServiceAccountNamespace + "/" + account,
+		})
+		return "", diagFromErr(d.Set("kubeconfig_token_secret", ""))
+	case err != nil:
+		// Keep the known kubeconfig while the API server is unreachable
+		return "", diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Could not refresh the kubeconfig ServiceAccount token",
+			Detail:   err.Error(),
+		}}
+	}
+
+	kubeconfig, err := serviceAccountKubeconfig(adminKubeconfig, account, token)
+	if err != nil {
+		return "", diagFromErr(err)
+	}
+	return kubeconfig, diagFromErr(setK3sKubeconfig(d, kubeconfig))
+}
+
+// updateK3sKubeconfigAuth switches the exported kubeconfig between the client
+// certificate and a ServiceAccount token, or reissues a revoked token. A
+// ServiceAccount that is no longer used is deleted, revoking its token.
+func updateK3sKubeconfigAuth(ctx context.Context, d *schema.ResourceData, logs *provisionLogs) diag.Diagnostics {
+	cfg := extractClusterConfig(d)
+	provisioner := NewK3sProvisioner()
+	provisioner.Logs = logs
+	adminKubeconfig, err := provisioner.GetKubeconfig(cfg.ControlPlane)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to get kubeconfig: %w", err))
+	}
+
+	oldAuth, _ := d.GetChange("kubeconfig_auth")
+	oldName, _ := d.GetChange("kubeconfig_service_account")
+	if oldAccount := k3sAccountFor(oldAuth, oldName); oldAccount != "" && oldAccount != k3sKubeconfigAccount(d) {
+		if err := revokeK3sServiceAccount(ctx, adminKubeconfig, oldAccount); err != nil {
+			return diagFromErr(err)
+		}
+	}
+
+	timeout := time.Duration(d.Get("install_timeout").(int)) * time.Second
+	kubeconfig, err := issueK3sKubeconfig(ctx, d, adminKubeconfig, timeout)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to issue kubeconfig ServiceAccount token: %w", err))
+	}
+	if err := setK3sKubeconfig(d, kubeconfig); err != nil {
+		return diagFromErr(err)
+	}
+	if kubeconfigPath := d.Get("kubeconfig_path").(string); kubeconfigPath != "" {
+		if err := writeCredentialFile(kubeconfigPath, []byte(kubeconfig), filePermission(d)); err != nil {
+			return diagFromErr(fmt.Errorf("failed to write kubeconfig to %s: %w", kubeconfigPath, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceAccountKubeconfig(t *testing.T) {
+	kubeconfig, err := serviceAccountKubeconfig(certKubeconfig, "turingpi-admin", "sa-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(kubeconfig, "client-key-data") || strings.Contains(kubeconfig, "client-certificate-data") {
+		t.Errorf("expected the client certificate to be removed:\n%s", kubeconfig)
+	}
+
+	creds, err := parseKubeconfigCredentials(kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "sa-token" || creds.Host != "https://10.10.88.73:6443" || creds.ClusterCACertificate != "ca-pem" {
+		t.Errorf("unexpected credentials: %+v", creds)
+	}
+}
+
+func TestK8sClient_AdminServiceAccount(t *testing.T) {
+	ctx := context.Background()
+	clientset := kubefake.NewClientset()
+	client := &K8sClient{clientset: clientset}
+
+	if err := client.EnsureAdminServiceAccount(ctx, "kube-system", "turingpi-admin", "turingpi-admin-token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Existing objects are kept
+	if err := client.EnsureAdminServiceAccount(ctx, "kube-system", "turingpi-admin", "turingpi-admin-token"); err != nil {
+		t.Fatalf("unexpected error on second call: %v", err)
+	}
+
+	binding, err := clientset.RbacV1().ClusterRoleBindings().Get(ctx, "turingpi-admin", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binding.RoleRef.Name != "cluster-admin" || binding.Subjects[0].Namespace != "kube-system" {
+		t.Errorf("unexpected binding: %+v", binding)
+	}
+
+	// The token is empty until the token controller fills it in
+	if token, err := client.ServiceAccountToken(ctx, "kube-system", "turingpi-admin-token"); err != nil || token != "" {
+		t.Errorf("expected no token yet, got %q, %v", token, err)
+	}
+	secret, _ := clientset.CoreV1().Secrets("kube-system").Get(ctx, "turingpi-admin-token", metav1.GetOptions{})
+	if secret.Type != corev1.SecretTypeServiceAccountToken || secret.Annotations[corev1.ServiceAccountNameKey] != "turingpi-admin" {
+		t.Errorf("unexpected secret: %+v", secret)
+	}
+	secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("sa-token")}
+	if _, err := clientset.CoreV1().Secrets("kube-system").Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := client.ServiceAccountToken(ctx, "kube-system", "turingpi-admin-token"); err != nil || token != "sa-token" {
+		t.Errorf("expected the token, got %q, %v", token, err)
+	}
+
+	if err := client.DeleteAdminServiceAccount(ctx, "kube-system", "turingpi-admin", "turingpi-admin-token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.ServiceAccountToken(ctx, "kube-system", "turingpi-admin-token"); err == nil {
+		t.Error("expected the token Secret to be deleted")
+	}
+	// Deleting again is a no-op
+	if err := client.DeleteAdminServiceAccount(ctx, "kube-system", "turingpi-admin", "turingpi-admin-token"); err != nil {
+		t.Errorf("unexpected error deleting missing objects: %v", err)
+	}
+}
+
+func TestSetK3sKubeconfig(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":            "test",
+		"kubeconfig_auth": k3sKubeconfigAuthServiceAccount,
+	})
+	kubeconfig, err := serviceAccountKubeconfig(certKubeconfig, defaultK3sServiceAccount, "sa-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setK3sKubeconfig(d, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"token":                   "sa-token",
+		"kubeconfig_token_secret": "kube-system/turingpi-admin-token",
+		"client_certificate":      "",
+		"client_key":              "",
+		"cluster_ca_certificate":  "ca-pem",
+	}
+	for key, value := range want {
+		if got := d.Get(key).(string); got != value {
+			t.Errorf("expected %s %q, got %q", key, value, got)
+		}
+	}
+}
+
+func TestK3sKubeconfigAuthChanged(t *testing.T) {
+	type values struct{ auth, name, secret string }
+	tests := []struct {
+		name     string
+		old, new values
+		want     bool
+	}{
+		{"state before kubeconfig_auth", values{"", "", ""}, values{"client_certificate", "turingpi-admin", ""}, false},
+		{"switch to service account", values{"client_certificate", "turingpi-admin", ""}, values{"service_account", "turingpi-admin", ""}, true},
+		{"switch back", values{"service_account", "turingpi-admin", "kube-system/turingpi-admin-token"}, values{"client_certificate", "turingpi-admin", "kube-system/turingpi-admin-token"}, true},
+		{"rename", values{"service_account", "turingpi-admin", "kube-system/turingpi-admin-token"}, values{"service_account", "ops", "kube-system/turingpi-admin-token"}, true},
+		{"unchanged", values{"service_account", "turingpi-admin", "kube-system/turingpi-admin-token"}, values{"service_account", "turingpi-admin", "kube-system/turingpi-admin-token"}, false},
+		{"revoked", values{"service_account", "turingpi-admin", ""}, values{"service_account", "turingpi-admin", ""}, true},
+		{"name change with certificates", values{"client_certificate", "turingpi-admin", ""}, values{"client_certificate", "ops", ""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getChange := func(key string) (interface{}, interface{}) {
+				switch key {
+				case "kubeconfig_auth":
+					return tt.old.auth, tt.new.auth
+				case "kubeconfig_service_account":
+					return tt.old.name, tt.new.name
+				}
+				return tt.old.secret, tt.new.secret
+			}
+			if got := k3sKubeconfigAuthChanged(getChange); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
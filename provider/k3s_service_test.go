@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceCommand(t *testing.T) {
+	got := serviceCommand("restart", "k3s-agent")
+	want := "if [ -d /run/systemd/system ]; then systemctl restart k3s-agent; else rc-service k3s-agent restart; fi"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestServiceActiveCommand(t *testing.T) {
+	got := serviceActiveCommand("k3s-agent")
+	for _, want := range []string{"systemctl is-active --quiet k3s-agent", "rc-service k3s-agent status"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestServiceLogCommand(t *testing.T) {
+	got := serviceLogCommand("k3s", 50)
+	for _, want := range []string{"journalctl -u k3s --no-pager -n 50", "tail -n 50 /var/log/k3s.log"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestK3sProvisioner_StartExistingDetectsInitSystem(t *testing.T) {
+	var commands []string
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				switch {
+				case strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s "):
+					return "installed", nil
+				case strings.HasPrefix(cmd, "k3s kubectl get nodes"):
+					return "node1 Ready", nil
+				}
+				return "", nil
+			},
+		}
+	})
+
+	if _, err := provisioner.InstallK3sServer(context.Background(), NodeConfig{Host: "10.10.88.73", SSHPort: 22}, ClusterConfig{}, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := serviceCommand("start", "k3s")
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd, "systemctl") {
+			t.Errorf("expected no bare systemctl command, got %q", cmd)
+		}
+		if cmd == start {
+			return
+		}
+	}
+	t.Errorf("expected %q, got %v", start, commands)
+}
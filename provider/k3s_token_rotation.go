@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// setTokenEnvCommand returns a command that sets K3S_TOKEN in the environment
+// file the install script wrote for unit: under /etc/systemd/system with
+// systemd, under /etc/rancher/k3s with OpenRC. It fails when neither exists.
+func setTokenEnvCommand(unit, token string) string {
+	line := shellQuote("K3S_TOKEN='" + token + "'")
+	return fmt.Sprintf(`found=
+for f in /etc/systemd/system/%[1]s.service.env /etc/rancher/k3s/%[1]s.env; do
+  [ -f "$f" ] || continue
+  found=$f
+  grep -v '^K3S_TOKEN=' "$f" > "$f.tmp"; echo %[2]s >> "$f.tmp"; cat "$f.tmp" > "$f"; rm -f "$f.tmp"
+done
+[ -n "$found" ] || { echo "no environment file of %[1]s found" >&2; exit 1; }`, unit, line)
+}
+
+// RotateServerToken replaces the server token of the cluster, which nodes
+// join with, by newToken in the datastore with k3s token rotate. K3s keeps
+// running with the old token until SetServerToken restarts it.
+func (p *K3sProvisioner) RotateServerToken(controlPlane NodeConfig, oldToken, newToken string) error {
+	p.Logs.redact(newToken)
+	rotate := fmt.Sprintf("k3s token rotate --token %s --new-token %s", shellQuote(oldToken), shellQuote(newToken))
+	if _, err := p.runCommand(controlPlane, rotate); err != nil {
+		return fmt.Errorf("failed to rotate the token on %s (k3s token rotate needs K3s v1.28 or later): %w", controlPlane.Host, err)
+	}
+	return nil
+}
+
+// SetServerToken updates the K3s environment file of the control plane to a
+// token rotated with RotateServerToken and restarts K3s. Agents keep their
+// connection until they restart, so they must be switched to the new node
+// token after this.
+func (p *K3sProvisioner) SetServerToken(ctx context.Context, controlPlane NodeConfig, token string, timeout time.Duration) error {
+	p.Logs.redact(token)
+	if _, err := p.runCommand(controlPlane, setTokenEnvCommand("k3s", token)); err != nil {
+		return fmt.Errorf("failed to store the new token on %s: %w", controlPlane.Host, err)
+	}
+
+	tflog.Info(ctx, "Restarting K3s to use the rotated token", map[string]interface{}{
+		"host": controlPlane.Host,
+	})
+	if _, err := p.runCommand(controlPlane, serviceCommand("restart", "k3s")); err != nil {
+		return fmt.Errorf("failed to restart K3s on %s: %w", controlPlane.Host, err)
+	}
+	return p.waitForK3sReady(controlPlane, timeout)
+}
+
+// SetAgentToken switches the agent on worker to nodeToken and restarts it
+func (p *K3sProvisioner) SetAgentToken(ctx context.Context, worker NodeConfig, nodeToken string) error {
+	p.Logs.redact(nodeToken)
+	if _, err := p.runCommand(worker, setTokenEnvCommand("k3s-agent", nodeToken)); err != nil {
+		return fmt.Errorf("failed to store the new token on %s: %w", worker.Host, err)
+	}
+
+	tflog.Info(ctx, "Restarting the K3s agent to use the rotated token", map[string]interface{}{
+		"host": worker.Host,
+	})
+	if _, err := p.runCommand(worker, serviceCommand("restart", "k3s-agent")); err != nil {
+		return fmt.Errorf("failed to restart k3s-agent on %s: %w", worker.Host, err)
+	}
+	return nil
+}
+
+// rotateK3sCredentials replaces cluster_token with a new random token on the
+// control plane, then moves every worker to the node token derived from it.
+// When the rotation itself fails, d is marked partial so state is left as it
+// was. Once the datastore holds the new token, cluster_token is stored and
+// later failures keep it, so state matches the server, and the workers can be
+// fixed by changing rotate_credentials again or with auto_repair.
+func rotateK3sCredentials(ctx context.Context, d *schema.ResourceData, provisioner *K3sProvisioner) diag.Diagnostics {
+	cfg := extractClusterConfig(d)
+	timeout := time.Duration(d.Get("install_timeout").(int)) * time.Second
+
+	// Imported clusters have no cluster_token in state; the full node token
+	// authenticates the rotation as well
+	oldToken := cfg.ClusterToken
+	if oldToken == "" {
+		var err error
+		if oldToken, err = provisioner.GetNodeToken(cfg.ControlPlane); err != nil {
+			d.Partial(true)
+			return diagFromErr(err)
+		}
+		provisioner.Logs.redact(oldToken)
+	}
+	newToken := GenerateClusterToken()
+	if err := provisioner.RotateServerToken(cfg.ControlPlane, oldToken, newToken); err != nil {
+		d.Partial(true)
+		return diagFromErr(err)
+	}
+	if err := d.Set("cluster_token", newToken); err != nil {
+		return diagFromErr(err)
+	}
+	if err := provisioner.SetServerToken(ctx, cfg.ControlPlane, newToken, timeout); err != nil {
+		return diagFromErr(err)
+	}
+
+	nodeToken, err := provisioner.GetNodeToken(cfg.ControlPlane)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	if err := setSensitiveOutput(d, "node_token", nodeToken); err != nil {
+		return diagFromErr(err)
+	}
+
+	for _, worker := range cfg.Workers {
+		if err := provisioner.SetAgentToken(ctx, worker, nodeToken); err != nil {
+			return diagFromErr(err)
+		}
+		if err := provisioner.WaitForNodeReady(cfg.ControlPlane, worker.Host, timeout); err != nil {
+			return diagFromErr(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestSetTokenEnvCommand(t *testing.T) {
+	root := t.TempDir()
+	envFile := filepath.Join(root, "etc/systemd/system/k3s-agent.service.env")
+	if err := os.MkdirAll(filepath.Dir(envFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envFile, []byte("K3S_URL='https://10.10.88.73:6443'\nK3S_TOKEN='K10old::server:old'\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(unit string) error {
+		cmd := strings.ReplaceAll(setTokenEnvCommand(unit, "K10new::server:new"), "/etc/", root+"/etc/")
+		return exec.Command("sh", "-c", cmd).Run()
+	}
+	if err := run("k3s-agent"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "K3S_URL='https://10.10.88.73:6443'\nK3S_TOKEN='K10new::server:new'\n"; string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+	if info, _ := os.Stat(envFile); info.Mode().Perm() != 0600 {
+		t.Errorf("expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+
+	if err := run("k3s"); err == nil {
+		t.Error("expected an error without an environment file")
+	}
+}
+
+func TestK3sProvisioner_RotateServerToken(t *testing.T) {
+	var commands []string
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{RunCommandFunc: func(cmd string) (string, error) {
+			commands = append(commands, cmd)
+			if strings.HasPrefix(cmd, "k3s kubectl get nodes") {
+				return "node1   Ready   control-plane,master", nil
+			}
+			return "", nil
+		}}
+	})
+	logs, err := newProvisionLogs(t.TempDir(), "old-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provisioner.Logs = logs
+
+	controlPlane := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPort: 22}
+	if err := provisioner.RotateServerToken(controlPlane, "old-token", "new-token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := provisioner.SetServerToken(context.Background(), controlPlane, "new-token", pollInterval); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(commands) < 3 {
+		t.Fatalf("expected rotate, env update and restart, got %q", commands)
+	}
+	if commands[0] != "k3s token rotate --token old-token --new-token new-token" {
+		t.Errorf("unexpected rotate command %q", commands[0])
+	}
+	if !strings.Contains(commands[1], "/etc/systemd/system/k3s.service.env") || !strings.Contains(commands[1], "new-token") {
+		t.Errorf("expected the K3s environment file to get the new token, got %q", commands[1])
+	}
+	if commands[2] != serviceCommand("restart", "k3s") {
+		t.Errorf("expected a K3s restart, got %q", commands[2])
+	}
+
+	data, err := os.ReadFile(logs.path(controlPlane.Host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"old-token", "new-token"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("log leaks %q:\n%s", leaked, data)
+		}
+	}
+}
+
+func TestK3sProvisioner_SetAgentToken(t *testing.T) {
+	var commands []string
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{RunCommandFunc: func(cmd string) (string, error) {
+			commands = append(commands, cmd)
+			return "", nil
+		}}
+	})
+
+	worker := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+	if err := provisioner.SetAgentToken(context.Background(), worker, "K10abc::server:new-token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(commands) != 2 || !strings.Contains(commands[0], "k3s-agent.service.env") || commands[1] != serviceCommand("restart", "k3s-agent") {
+		t.Errorf("expected the agent environment file to be updated and k3s-agent restarted, got %q", commands)
+	}
+}
+
+func TestRotateK3sCredentials_StateAfterFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		failOn    string
+		wantToken bool
+	}{
+		{"failed rotation keeps the old token", "k3s token rotate", false},
+		{"failed worker keeps the rotated token", "k3s-agent.service.env", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+				return &MockSSHClient{RunCommandFunc: func(cmd string) (string, error) {
+					switch {
+					case strings.Contains(cmd, tt.failOn):
+						return "", &ExitError{Command: cmd, Code: 1}
+					case strings.HasPrefix(cmd, "k3s kubectl get nodes"):
+						return "node1   Ready   control-plane,master", nil
+					case strings.HasPrefix(cmd, "cat /var/lib/rancher/k3s/server/node-token"):
+						return "K10abc::server:rotated", nil
+					}
+					return "", nil
+				}}
+			})
+			d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+				"name":            "test",
+				"install_timeout": 1,
+				"control_plane": []interface{}{map[string]interface{}{
+					"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+				}},
+				"worker": []interface{}{map[string]interface{}{
+					"host": "10.10.88.74", "ssh_user": "root", "ssh_password": "secret",
+				}},
+			})
+			d.SetId("k3s-test")
+
+			if diags := rotateK3sCredentials(context.Background(), d, provisioner); !diags.HasError() {
+				t.Fatal("expected an error")
+			}
+			token := d.State().Attributes["cluster_token"]
+			if got := token != ""; got != tt.wantToken {
+				t.Errorf("expected rotated cluster_token in state %v, got %q", tt.wantToken, token)
+			}
+		})
+	}
+}
+
+func TestResourceK3sClusterCustomizeDiff_RotateCredentials(t *testing.T) {
+	r := resourceK3sCluster()
+	config := map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+		}},
+	}
+
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetId("k3s-test")
+	_ = state.Set("cluster_token", "old-token")
+	_ = state.Set("node_token", "K10abc::server:old-token")
+
+	config["rotate_credentials"] = "2026-10"
+	diff, err := r.Diff(context.Background(), state.State(), terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"cluster_token", "node_token"} {
+		if diff == nil || diff.Attributes[key] == nil || !diff.Attributes[key].NewComputed {
+			t.Errorf("expected %s to be planned for rotation, got diff %+v", key, diff)
+		}
+	}
+}
+
+func TestValidateK3sMode_RotateCredentialsAgentsOnly(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":               "test",
+		"mode":               k3sModeAgentsOnly,
+		"server_url":         "https://10.10.88.73:6443",
+		"cluster_token":      "K10abc::server:token",
+		"rotate_credentials": "1",
+		"worker": []interface{}{map[string]interface{}{
+			"host": "10.10.88.74", "ssh_user": "root", "ssh_password": "secret",
+		}},
+	})
+	err := validateK3sMode(d, extractClusterConfig(d))
+	if err == nil || !strings.Contains(err.Error(), "rotate_credentials") {
+		t.Errorf("expected rotate_credentials to be rejected in agents_only mode, got %v", err)
+	}
+}
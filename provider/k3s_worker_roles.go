@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Worker roles of turingpi_k3s_cluster worker blocks
+const (
+	k3sWorkerRoleWorker  = "worker"
+	k3sWorkerRoleStorage = "storage"
+	k3sWorkerRoleEdge    = "edge"
+)
+
+// k3sRoleLabel is the node label and taint key set from the worker role
+const k3sRoleLabel = "turingpi.io/role"
+
+// k3sWorkerRoleTaints maps roles to the taint effect that keeps general
+// workloads off their nodes. Pods opt in with a toleration for
+// turingpi.io/role=<role>. Workers are not tainted.
+var k3sWorkerRoleTaints = map[string]string{
+	k3sWorkerRoleStorage: "NoSchedule",
+	k3sWorkerRoleEdge:    "NoSchedule",
+}
+
+// k3sWorkerSchema is the node schema of worker blocks: the shared node
+// settings plus the role shorthand and extra agent flags
+func k3sWorkerSchema() *schema.Resource {
+	r := k3sNodeSchema()
+	r.Schema["role"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      k3sWorkerRoleWorker,
+		ValidateFunc: validation.StringInSlice([]string{k3sWorkerRoleWorker, k3sWorkerRoleStorage, k3sWorkerRoleEdge}, false),
+		Description: "Role of the node: worker, storage or edge. The node is labeled turingpi.io/role=<role>; storage and edge nodes are also tainted " +
+			"turingpi.io/role=<role>:NoSchedule so only pods tolerating the taint run there. Changing it on an existing worker relabels the node through the Kubernetes API and re-runs the agent install (requires allow_restart); it cannot change in agents_only mode.",
+	}
+	r.Schema["kubelet_args"] = &schema.Schema{
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "Kubelet settings of the node (e.g., max-pods = \"30\"). Passed to K3s as --kubelet-arg=<key>=<value>. Changing it on an existing worker re-runs the agent install and restarts the agent (requires allow_restart).",
+	}
+	r.Schema["agent_flags"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "Extra flags passed to the K3s agent install (e.g., [\"--node-taint=dedicated=gpu:NoSchedule\"]). Changing it on an existing worker re-runs the agent install and restarts the agent (requires allow_restart).",
+	}
+	return r
+}
+
+// extractWorkerConfig extracts a worker block: the shared node settings
+// plus its role, kubelet_args and agent_flags
+func extractWorkerConfig(data, defaults map[string]interface{}) NodeConfig {
+	node := extractNodeConfigWithDefaults(data, defaults)
+	if v, ok := data["role"].(string); ok {
+		node.Role = v
+	}
+	if v, ok := data["kubelet_args"].(map[string]interface{}); ok && len(v) > 0 {
+		node.KubeletArgs = expandStringMap(v)
+	}
+	if v, ok := data["agent_flags"].([]interface{}); ok {
+		for _, flag := range v {
+			if s, ok := flag.(string); ok && s != "" {
+				node.AgentFlags = append(node.AgentFlags, s)
+			}
+		}
+	}
+	return node
+}
+
+// k3sWorkerRoleFlags returns the K3s agent flags of the node role, kubelet
+// args and extra agent flags, in that order. User-supplied values are quoted
+// for the install command line.
+func k3sWorkerRoleFlags(node NodeConfig) []string {
+	var flags []string
+	if node.Role != "" {
+		flags = append(flags, fmt.Sprintf("--node-label=%s=%s", k3sRoleLabel, node.Role))
+		if effect, ok := k3sWorkerRoleTaints[node.Role]; ok {
+			flags = append(flags, fmt.Sprintf("--node-taint=%s=%s:%s", k3sRoleLabel, node.Role, effect))
+		}
+	}
+
+	keys := make([]string, 0, len(node.KubeletArgs))
+	for k := range node.KubeletArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flags = append(flags, shellQuote(fmt.Sprintf("--kubelet-arg=%s=%s", k, node.KubeletArgs[k])))
+	}
+	for _, flag := range node.AgentFlags {
+		flags = append(flags, shellQuote(flag))
+	}
+	return flags
+}
+
+// applyK3sWorkerRole sets the role label of node and replaces its role
+// taint with the one of role, if any. K3s applies --node-label and
+// --node-taint only when a node registers, so role changes of existing
+// workers are made through the Kubernetes API.
+func applyK3sWorkerRole(node *corev1.Node, role string) {
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	node.Labels[k3sRoleLabel] = role
+
+	var taints []corev1.Taint
+	for _, taint := range node.Spec.Taints {
+		if taint.Key != k3sRoleLabel {
+			taints = append(taints, taint)
+		}
+	}
+	if effect, ok := k3sWorkerRoleTaints[role]; ok {
+		taints = append(taints, corev1.Taint{Key: k3sRoleLabel, Value: role, Effect: corev1.TaintEffect(effect)})
+	}
+	node.Spec.Taints = taints
+}
+
+// k3sWorkerRoleChanges returns the hosts of workers already in state whose
+// role changed
+func k3sWorkerRoleChanges(old, new []interface{}) []string {
+	var hosts []string
+	for i := 0; i < len(old) && i < len(new); i++ {
+		oldData, _ := old[i].(map[string]interface{})
+		newData, _ := new[i].(map[string]interface{})
+		if oldData != nil && newData != nil && oldData["role"] != newData["role"] {
+			hosts = append(hosts, fmt.Sprint(newData["host"]))
+		}
+	}
+	return hosts
+}
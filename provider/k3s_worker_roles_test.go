@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestK3sWorkerRoleFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		node NodeConfig
+		want []string
+	}{
+		{"none", NodeConfig{}, nil},
+		{"worker", NodeConfig{Role: k3sWorkerRoleWorker}, []string{"--node-label=turingpi.io/role=worker"}},
+		{"storage", NodeConfig{Role: k3sWorkerRoleStorage}, []string{
+			"--node-label=turingpi.io/role=storage",
+			"--node-taint=turingpi.io/role=storage:NoSchedule",
+		}},
+		{"edge with kubelet args and flags", NodeConfig{
+			Role:        k3sWorkerRoleEdge,
+			KubeletArgs: map[string]string{"max-pods": "30", "eviction-hard": "memory.available<100Mi"},
+			AgentFlags:  []string{"--node-name=edge-1"},
+		}, []string{
+			"--node-label=turingpi.io/role=edge",
+			"--node-taint=turingpi.io/role=edge:NoSchedule",
+			"'--kubelet-arg=eviction-hard=memory.available<100Mi'",
+			"--kubelet-arg=max-pods=30",
+			"--node-name=edge-1",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k3sWorkerRoleFlags(tt.node); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractWorkerConfig(t *testing.T) {
+	node := extractWorkerConfig(map[string]interface{}{
+		"host":         "10.10.88.75",
+		"ssh_user":     "root",
+		"role":         "storage",
+		"kubelet_args": map[string]interface{}{"max-pods": "30"},
+		"agent_flags":  []interface{}{"--node-name=storage-1", ""},
+	}, nil)
+
+	if node.Host != "10.10.88.75" || node.SSHUser != "root" {
+		t.Errorf("expected node settings to be extracted, got %+v", node)
+	}
+	if node.Role != k3sWorkerRoleStorage {
+		t.Errorf("expected role storage, got %q", node.Role)
+	}
+	if node.KubeletArgs["max-pods"] != "30" {
+		t.Errorf("expected kubelet args, got %v", node.KubeletArgs)
+	}
+	if !reflect.DeepEqual(node.AgentFlags, []string{"--node-name=storage-1"}) {
+		t.Errorf("expected empty flags to be dropped, got %v", node.AgentFlags)
+	}
+}
+
+func TestK3sProvisioner_InstallK3sAgent_Role(t *testing.T) {
+	var commands []string
+	provisioner := newGPUMockProvisioner("", &commands)
+	node := NodeConfig{Host: "10.10.88.75", SSHUser: "root", SSHPort: 22, Role: k3sWorkerRoleStorage}
+
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, cmd := range commands {
+		if strings.Contains(cmd, "/tmp/k3s-install.sh agent") {
+			if !strings.Contains(cmd, "--node-taint=turingpi.io/role=storage:NoSchedule") {
+				t.Errorf("expected storage taint, got %q", cmd)
+			}
+			return
+		}
+	}
+	t.Errorf("expected agent install command, got %v", commands)
+}
+
+func TestApplyK3sWorkerRole(t *testing.T) {
+	node := &corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		{Key: k3sRoleLabel, Value: k3sWorkerRoleStorage, Effect: corev1.TaintEffectNoSchedule},
+	}}}
+
+	applyK3sWorkerRole(node, k3sWorkerRoleEdge)
+	if node.Labels[k3sRoleLabel] != k3sWorkerRoleEdge {
+		t.Errorf("expected the edge label, got %v", node.Labels)
+	}
+	want := []corev1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		{Key: k3sRoleLabel, Value: k3sWorkerRoleEdge, Effect: corev1.TaintEffectNoSchedule},
+	}
+	if !reflect.DeepEqual(node.Spec.Taints, want) {
+		t.Errorf("expected taints %v, got %v", want, node.Spec.Taints)
+	}
+
+	applyK3sWorkerRole(node, k3sWorkerRoleWorker)
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Key != "dedicated" {
+		t.Errorf("expected only the unrelated taint on a worker, got %v", node.Spec.Taints)
+	}
+}
+
+func TestK3sWorkerRoleChanges(t *testing.T) {
+	old := []interface{}{
+		map[string]interface{}{"host": "10.10.88.74", "role": "worker"},
+		map[string]interface{}{"host": "10.10.88.75", "role": "worker"},
+	}
+	new := []interface{}{
+		map[string]interface{}{"host": "10.10.88.74", "role": "worker"},
+		map[string]interface{}{"host": "10.10.88.75", "role": "storage"},
+		map[string]interface{}{"host": "10.10.88.76", "role": "edge"},
+	}
+	if hosts := k3sWorkerRoleChanges(old, new); !reflect.DeepEqual(hosts, []string{"10.10.88.75"}) {
+		t.Errorf("expected only the existing worker with a new role, got %v", hosts)
+	}
+}
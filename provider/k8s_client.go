@@ -2,123 +2,365 @@ package provider
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
+	"io"
 	"strings"
+	"time"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
 )
 
-// K8sClient provides Kubernetes operations using kubectl
+// k8sFieldManager is the server-side apply field manager used for manifests
+const k8sFieldManager = "terraform-provider-turingpi"
+
+// crdResource is the GroupVersionResource of CustomResourceDefinitions
+var crdResource = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// K8sClient provides Kubernetes operations using client-go, so no kubectl
+// binary is needed on the machine running Terraform
 type K8sClient struct {
-	kubeconfig     []byte
-	kubeconfigPath string
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
 }
 
 // NewK8sClient creates a new Kubernetes client from kubeconfig bytes
 func NewK8sClient(kubeconfig []byte) (*K8sClient, error) {
-	// Write kubeconfig to a temp file
-	tmpFile, err := os.CreateTemp("", "kubeconfig-k8s-*")
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp kubeconfig file: %w", err)
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
 	}
 
-	if err := os.WriteFile(tmpFile.Name(), kubeconfig, 0600); err != nil {
-		_ = os.Remove(tmpFile.Name())
-		return nil, fmt.Errorf("failed to write kubeconfig: %w", err)
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes clientset: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
 	}
 
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
 	return &K8sClient{
-		kubeconfig:     kubeconfig,
-		kubeconfigPath: tmpFile.Name(),
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		mapper:    mapper,
 	}, nil
 }
 
-// Close cleans up temporary files
+// Close releases client resources
 func (c *K8sClient) Close() error {
-	if c.kubeconfigPath != "" {
-		return os.Remove(c.kubeconfigPath)
+	return nil
+}
+
+// ApplyManifest server-side applies every document in a YAML manifest,
+// retrying transient API server errors
+func (c *K8sClient) ApplyManifest(ctx context.Context, manifest string) error {
+	objects, err := decodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		err := retryTransient(ctx, fmt.Sprintf("apply %s %s", obj.GetKind(), obj.GetName()), func() error {
+			resource, err := c.resourceFor(obj)
+			if err != nil {
+				return err
+			}
+			if _, err := resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{FieldManager: k8sFieldManager, Force: true}); err != nil {
+				return fmt.Errorf("failed to apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 	}
+
 	return nil
 }
 
-// RunKubectl executes a kubectl command and returns the output
-func (c *K8sClient) RunKubectl(args ...string) (string, error) {
-	cmdArgs := append([]string{"--kubeconfig", c.kubeconfigPath}, args...)
-	cmd := exec.Command("kubectl", cmdArgs...)
+// DeleteManifest deletes resources from a YAML manifest, ignoring ones that do not exist
+func (c *K8sClient) DeleteManifest(ctx context.Context, manifest string) error {
+	objects, err := decodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		resource, err := c.resourceFor(obj)
+		if err != nil {
+			return err
+		}
+		if err := resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// CRDExists checks if a CustomResourceDefinition is installed
+func (c *K8sClient) CRDExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.dynamic.Resource(crdResource).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return stdout.String(), fmt.Errorf("kubectl %s failed: %s: %w", strings.Join(args, " "), stderr.String(), err)
+// NamespaceExists checks if a namespace exists
+func (c *K8sClient) NamespaceExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	return stdout.String(), nil
+// PodPhase returns the phase of the first pod matching a label selector, or "" if none match
+func (c *K8sClient) PodPhase(ctx context.Context, namespace, selector string) (string, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", nil
+	}
+	return string(pods.Items[0].Status.Phase), nil
 }
 
-// ApplyManifest applies a YAML manifest to the cluster
-func (c *K8sClient) ApplyManifest(manifest string) error {
-	// Create a temp file for the manifest
-	tmpFile, err := os.CreateTemp("", "manifest-*.yaml")
+// ServiceIPs returns the cluster IP of a Service and its first load balancer
+// address, which is empty until the load balancer assigns one
+func (c *K8sClient) ServiceIPs(ctx context.Context, namespace, name string) (clusterIP, loadBalancerIP string, err error) {
+	svc, err := c.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create temp manifest file: %w", err)
+		return "", "", err
 	}
-	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return svc.Spec.ClusterIP, ingress.IP, nil
+		}
+		if ingress.Hostname != "" {
+			return svc.Spec.ClusterIP, ingress.Hostname, nil
+		}
+	}
+	return svc.Spec.ClusterIP, "", nil
+}
 
-	if err := os.WriteFile(tmpFile.Name(), []byte(manifest), 0644); err != nil {
-		return fmt.Errorf("failed to write manifest: %w", err)
+// EnsureAdminServiceAccount creates a ServiceAccount bound to the
+// cluster-admin ClusterRole, and a Secret holding a long-lived token for it.
+// Objects that already exist are kept, so their token stays valid.
+func (c *K8sClient) EnsureAdminServiceAccount(ctx context.Context, namespace, name, secretName string) error {
+	account := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	if _, err := c.clientset.CoreV1().ServiceAccounts(namespace).Create(ctx, account, metav1.CreateOptions{FieldManager: k8sFieldManager}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create ServiceAccount %s/%s: %w", namespace, name, err)
 	}
 
-	_, err = c.RunKubectl("apply", "-f", tmpFile.Name())
-	return err
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}},
+	}
+	if _, err := c.clientset.RbacV1().ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{FieldManager: k8sFieldManager}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create ClusterRoleBinding %s: %w", name, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secretName,
+			Namespace:   namespace,
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: name},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	if _, err := c.clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{FieldManager: k8sFieldManager}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create Secret %s/%s: %w", namespace, secretName, err)
+	}
+	return nil
 }
 
-// DeleteManifest deletes resources from a YAML manifest
-func (c *K8sClient) DeleteManifest(manifest string) error {
-	// Create a temp file for the manifest
-	tmpFile, err := os.CreateTemp("", "manifest-*.yaml")
+// ServiceAccountToken returns the token of a service account token Secret,
+// or "" until the token controller has filled it in
+func (c *K8sClient) ServiceAccountToken(ctx context.Context, namespace, secretName string) (string, error) {
+	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create temp manifest file: %w", err)
+		return "", err
 	}
-	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	return string(secret.Data[corev1.ServiceAccountTokenKey]), nil
+}
 
-	if err := os.WriteFile(tmpFile.Name(), []byte(manifest), 0644); err != nil {
-		return fmt.Errorf("failed to write manifest: %w", err)
+// DeleteAdminServiceAccount deletes the objects created by
+// EnsureAdminServiceAccount, which revokes the token. Missing objects are
+// ignored.
+func (c *K8sClient) DeleteAdminServiceAccount(ctx context.Context, namespace, name, secretName string) error {
+	if err := c.clientset.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Secret %s/%s: %w", namespace, secretName, err)
+	}
+	if err := c.clientset.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ClusterRoleBinding %s: %w", name, err)
 	}
+	if err := c.clientset.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ServiceAccount %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
 
-	_, err = c.RunKubectl("delete", "-f", tmpFile.Name(), "--ignore-not-found")
-	return err
+// UpdateNode applies mutate to the node whose name or an InternalIP matches
+// one of addresses and writes it back, retrying on update conflicts
+func (c *K8sClient) UpdateNode(ctx context.Context, mutate func(*corev1.Node), addresses ...string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list nodes: %w", err)
+		}
+		for i := range nodes.Items {
+			node := &nodes.Items[i]
+			if !nodeMatches(node, addresses) {
+				continue
+			}
+			mutate(node)
+			if _, err := c.clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{FieldManager: k8sFieldManager}); err != nil {
+				return err
+			}
+			return nil
+		}
+		return fmt.Errorf("no node with address %s found", strings.Join(addresses, " or "))
+	})
+}
+
+// nodeMatches reports whether the name or an InternalIP of node is one of addresses
+func nodeMatches(node *corev1.Node, addresses []string) bool {
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		if node.Name == address {
+			return true
+		}
+		for _, a := range node.Status.Addresses {
+			if a.Type == corev1.NodeInternalIP && a.Address == address {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// WaitForResource waits for a resource to reach a condition
-func (c *K8sClient) WaitForResource(resourceType, name, namespace, condition string, timeout string) error {
-	args := []string{"wait", resourceType, name, "--for", condition, "--timeout", timeout}
-	if namespace != "" {
-		args = append(args, "-n", namespace)
+// drainPollInterval is how often DrainNode retries blocked evictions and checks for remaining pods
+var drainPollInterval = 5 * time.Second
+
+// DrainNode cordons a node and evicts its pods, skipping DaemonSet-managed and
+// mirror pods. Evictions blocked by a PodDisruptionBudget are retried until
+// timeout passes or ctx is done.
+func (c *K8sClient) DrainNode(ctx context.Context, name string, timeout time.Duration) error {
+	cordon := []byte(`{"spec":{"unschedulable":true}}`)
+	if _, err := c.clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, cordon, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %w", name, err)
 	}
 
-	_, err := c.RunKubectl(args...)
+	remaining := 0
+	err := poll(ctx, drainPollInterval, timeout, "drain of node "+name, func(ctx context.Context) (bool, error) {
+		pods, err := c.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + name})
+		if err != nil {
+			return false, waitutil.Permanent(fmt.Errorf("failed to list pods on node %s: %w", name, err))
+		}
+
+		remaining = 0
+		for _, pod := range pods.Items {
+			if _, mirror := pod.Annotations["kubernetes.io/config.mirror"]; mirror {
+				continue
+			}
+			if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+				continue
+			}
+			if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+				continue
+			}
+			remaining++
+
+			eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+			err := c.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+			if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsTooManyRequests(err) {
+				return false, waitutil.Permanent(fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err))
+			}
+		}
+		return remaining == 0, nil
+	})
+	var timeoutErr *waitutil.TimeoutError
+	if errors.As(err, &timeoutErr) || (err != nil && ctx.Err() != nil) {
+		return fmt.Errorf("timeout draining node %s: %d pods remaining", name, remaining)
+	}
 	return err
 }
 
-// GetResource gets a resource and returns the raw output
-func (c *K8sClient) GetResource(resourceType, name, namespace string) (string, error) {
-	args := []string{"get", resourceType, name, "-o", "yaml"}
-	if namespace != "" {
-		args = append(args, "-n", namespace)
+// resourceFor maps an object to its dynamic resource client
+func (c *K8sClient) resourceFor(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find resource for %s: %w", gvk, err)
 	}
 
-	return c.RunKubectl(args...)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		return c.dynamic.Resource(mapping.Resource).Namespace(namespace), nil
+	}
+	return c.dynamic.Resource(mapping.Resource), nil
 }
 
-// ResourceExists checks if a resource exists
-func (c *K8sClient) ResourceExists(resourceType, name, namespace string) bool {
-	args := []string{"get", resourceType, name}
-	if namespace != "" {
-		args = append(args, "-n", namespace)
+// decodeManifest splits a multi-document YAML manifest into objects
+func decodeManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader([]byte(manifest)), 4096)
+
+	var objects []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		// Skip empty documents (e.g., a leading "---")
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objects = append(objects, obj)
 	}
 
-	_, err := c.RunKubectl(args...)
-	return err == nil
+	return objects, nil
 }
@@ -0,0 +1,363 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var ipAddressPoolGVK = schema.GroupVersionKind{Group: "metallb.io", Version: "v1beta1", Kind: "IPAddressPool"}
+
+func newTestK8sClient(objects ...runtime.Object) (*K8sClient, *dynamicfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(ipAddressPoolGVK, meta.RESTScopeNamespace)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdResource: "CustomResourceDefinitionList",
+	}, objects...)
+
+	return &K8sClient{
+		clientset: kubefake.NewClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "controller-abc",
+				Namespace: "metallb-system",
+				Labels:    map[string]string{"app.kubernetes.io/component": "controller"},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}),
+		dynamic: dynamicClient,
+		mapper:  mapper,
+	}, dynamicClient
+}
+
+func TestNewK8sClient_InvalidKubeconfig(t *testing.T) {
+	if _, err := NewK8sClient([]byte("not a kubeconfig")); err == nil {
+		t.Error("expected error for invalid kubeconfig")
+	}
+}
+
+func TestDecodeManifest(t *testing.T) {
+	manifest := `---
+apiVersion: metallb.io/v1beta1
+kind: IPAddressPool
+metadata:
+  name: default-pool
+---
+apiVersion: metallb.io/v1beta1
+kind: L2Advertisement
+metadata:
+  name: default-l2
+`
+	objects, err := decodeManifest(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objects))
+	}
+	if objects[1].GetKind() != "L2Advertisement" || objects[1].GetName() != "default-l2" {
+		t.Errorf("unexpected second object: %s/%s", objects[1].GetKind(), objects[1].GetName())
+	}
+}
+
+func TestK8sClient_ApplyManifest(t *testing.T) {
+	client, dynamicClient := newTestK8sClient()
+
+	var patched []string
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		patched = append(patched, patch.GetNamespace()+"/"+patch.GetName()+"/"+string(patch.GetPatchType()))
+		return true, &unstructured.Unstructured{}, nil
+	})
+
+	err := client.ApplyManifest(context.Background(), `apiVersion: metallb.io/v1beta1
+kind: IPAddressPool
+metadata:
+  name: default-pool
+  namespace: metallb-system
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patched) != 1 || patched[0] != "metallb-system/default-pool/application/apply-patch+yaml" {
+		t.Errorf("expected one server-side apply, got %v", patched)
+	}
+}
+
+func TestK8sClient_ApplyManifest_RetriesTransientErrors(t *testing.T) {
+	fastTransientRetry(t)
+	client, dynamicClient := newTestK8sClient()
+
+	attempts := 0
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, apierrors.NewServiceUnavailable("etcd leader election in progress")
+		}
+		return true, &unstructured.Unstructured{}, nil
+	})
+
+	err := client.ApplyManifest(context.Background(), `apiVersion: metallb.io/v1beta1
+kind: IPAddressPool
+metadata:
+  name: default-pool
+  namespace: metallb-system
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 apply attempts, got %d", attempts)
+	}
+}
+
+func TestK8sClient_ApplyManifest_UnknownKind(t *testing.T) {
+	client, _ := newTestK8sClient()
+
+	err := client.ApplyManifest(context.Background(), `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+`)
+	if err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestK8sClient_CRDExists(t *testing.T) {
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName("ipaddresspools.metallb.io")
+
+	client, _ := newTestK8sClient(crd)
+
+	exists, err := client.CRDExists(context.Background(), "ipaddresspools.metallb.io")
+	if err != nil || !exists {
+		t.Errorf("expected CRD to exist, got %v, %v", exists, err)
+	}
+	exists, err = client.CRDExists(context.Background(), "l2advertisements.metallb.io")
+	if err != nil || exists {
+		t.Errorf("expected CRD to be missing, got %v, %v", exists, err)
+	}
+}
+
+func TestK8sClient_PodPhase(t *testing.T) {
+	client, _ := newTestK8sClient()
+
+	phase, err := client.PodPhase(context.Background(), "metallb-system", "app.kubernetes.io/component=controller")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phase != "Running" {
+		t.Errorf("expected Running, got %q", phase)
+	}
+
+	phase, err = client.PodPhase(context.Background(), "metallb-system", "app.kubernetes.io/component=speaker")
+	if err != nil || phase != "" {
+		t.Errorf("expected no matching pod, got %q, %v", phase, err)
+	}
+}
+
+func TestK8sClient_UpdateNode(t *testing.T) {
+	clientset := kubefake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}, Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.10.88.74"},
+		}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}},
+	)
+	client := &K8sClient{clientset: clientset}
+	ctx := context.Background()
+
+	label := func(n *corev1.Node) { n.Labels = map[string]string{"updated": "true"} }
+	if err := client.UpdateNode(ctx, label, "", "10.10.88.74"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node, err := clientset.CoreV1().Nodes().Get(ctx, "worker-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node: %v", err)
+	}
+	if node.Labels["updated"] != "true" {
+		t.Errorf("expected the node matched by InternalIP to be updated, got %v", node.Labels)
+	}
+
+	if err := client.UpdateNode(ctx, label, "10.10.88.99"); err == nil {
+		t.Error("expected an error for an unknown address")
+	}
+}
+
+func TestK8sClient_DrainNode(t *testing.T) {
+	isController := true
+	clientset := kubefake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "worker-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "speaker",
+				Namespace: "metallb-system",
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "DaemonSet", Name: "speaker", Controller: &isController},
+				},
+			},
+			Spec:   corev1.PodSpec{NodeName: "worker-1"},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "static",
+				Namespace:   "kube-system",
+				Annotations: map[string]string{"kubernetes.io/config.mirror": "abc"},
+			},
+			Spec:   corev1.PodSpec{NodeName: "worker-1"},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+
+	var evicted []string
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		evicted = append(evicted, eviction.Namespace+"/"+eviction.Name)
+		podsResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+		return true, nil, clientset.Tracker().Delete(podsResource, eviction.Namespace, eviction.Name)
+	})
+
+	orig := drainPollInterval
+	drainPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { drainPollInterval = orig })
+
+	client := &K8sClient{clientset: clientset}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.DrainNode(ctx, "worker-1", 5*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(ctx, "worker-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node: %v", err)
+	}
+	if !node.Spec.Unschedulable {
+		t.Error("expected node to be cordoned")
+	}
+	if len(evicted) != 1 || evicted[0] != "default/app" {
+		t.Errorf("expected only default/app to be evicted, got %v", evicted)
+	}
+}
+
+func newServiceTestK8sClient(services ...runtime.Object) *K8sClient {
+	return &K8sClient{clientset: kubefake.NewClientset(services...)}
+}
+
+func testService(namespace, name, clusterIP string, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.ServiceSpec{ClusterIP: clusterIP},
+		Status:     corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+	}
+}
+
+func TestK8sClient_ServiceIPs(t *testing.T) {
+	client := newServiceTestK8sClient(
+		testService("kube-system", "kube-dns", "10.43.0.10"),
+		testService("kube-system", "traefik", "10.43.12.7", corev1.LoadBalancerIngress{IP: "10.10.88.80"}),
+		testService("default", "hostname-lb", "10.43.1.1", corev1.LoadBalancerIngress{Hostname: "lb.example.com"}),
+	)
+
+	tests := []struct {
+		namespace, name string
+		clusterIP, lbIP string
+	}{
+		{"kube-system", "kube-dns", "10.43.0.10", ""},
+		{"kube-system", "traefik", "10.43.12.7", "10.10.88.80"},
+		{"default", "hostname-lb", "10.43.1.1", "lb.example.com"},
+	}
+	for _, tt := range tests {
+		clusterIP, lbIP, err := client.ServiceIPs(context.Background(), tt.namespace, tt.name)
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", tt.namespace, tt.name, err)
+		}
+		if clusterIP != tt.clusterIP || lbIP != tt.lbIP {
+			t.Errorf("%s/%s: got (%q, %q), want (%q, %q)", tt.namespace, tt.name, clusterIP, lbIP, tt.clusterIP, tt.lbIP)
+		}
+	}
+
+	if _, _, err := client.ServiceIPs(context.Background(), "kube-system", "missing"); err == nil {
+		t.Error("expected error for missing service")
+	}
+}
+
+func TestK8sClient_WaitForK3sServiceIPs(t *testing.T) {
+	oldInterval := serviceIPPollInterval
+	serviceIPPollInterval = 10 * time.Millisecond
+	defer func() { serviceIPPollInterval = oldInterval }()
+
+	ingress := &k3sServiceRef{Namespace: "kube-system", Name: "traefik"}
+
+	t.Run("ready", func(t *testing.T) {
+		client := newServiceTestK8sClient(
+			testService("kube-system", "kube-dns", "10.43.0.10"),
+			testService("kube-system", "traefik", "10.43.12.7", corev1.LoadBalancerIngress{IP: "10.10.88.80"}),
+		)
+		dnsIP, ingressIP, err := client.waitForK3sServiceIPs(context.Background(), ingress, time.Second)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dnsIP != "10.43.0.10" || ingressIP != "10.10.88.80" {
+			t.Errorf("got (%q, %q)", dnsIP, ingressIP)
+		}
+	})
+
+	t.Run("no ingress controller", func(t *testing.T) {
+		client := newServiceTestK8sClient(testService("kube-system", "kube-dns", "10.43.0.10"))
+		dnsIP, ingressIP, err := client.waitForK3sServiceIPs(context.Background(), nil, time.Second)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dnsIP != "10.43.0.10" || ingressIP != "" {
+			t.Errorf("got (%q, %q)", dnsIP, ingressIP)
+		}
+	})
+
+	t.Run("load balancer pending", func(t *testing.T) {
+		client := newServiceTestK8sClient(
+			testService("kube-system", "kube-dns", "10.43.0.10"),
+			testService("kube-system", "traefik", "10.43.12.7"),
+		)
+		dnsIP, _, err := client.waitForK3sServiceIPs(context.Background(), ingress, 50*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "not assigned") {
+			t.Fatalf("expected load balancer timeout, got %v", err)
+		}
+		if dnsIP != "10.43.0.10" {
+			t.Errorf("expected DNS IP to be returned on timeout, got %q", dnsIP)
+		}
+	})
+
+	t.Run("cluster IP pending", func(t *testing.T) {
+		client := newServiceTestK8sClient(testService("kube-system", "kube-dns", ""))
+		_, _, err := client.waitForK3sServiceIPs(context.Background(), nil, 50*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "kube-system/kube-dns not assigned") {
+			t.Fatalf("expected cluster IP timeout, got %v", err)
+		}
+	})
+}
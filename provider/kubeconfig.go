@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -59,6 +60,43 @@ func ExtractClusterEndpoint(path string) (string, error) {
 	return cluster.Server, nil
 }
 
+// kubeconfigCredentials holds the server URL, the PEM-encoded certificates
+// and key, and the bearer token of the current context of a kubeconfig
+type kubeconfigCredentials struct {
+	Host                 string
+	ClusterCACertificate string
+	ClientCertificate    string
+	ClientKey            string
+	Token                string
+}
+
+// parseKubeconfigCredentials extracts the cluster CA and client credentials of
+// the current context, so other providers can be configured without decoding
+// the kubeconfig. Values referenced by file path are not resolved.
+func parseKubeconfigCredentials(kubeconfig string) (*kubeconfigCredentials, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	ctx := config.Contexts[config.CurrentContext]
+	if ctx == nil {
+		return nil, fmt.Errorf("no current context in kubeconfig")
+	}
+
+	creds := &kubeconfigCredentials{}
+	if cluster := config.Clusters[ctx.Cluster]; cluster != nil {
+		creds.Host = cluster.Server
+		creds.ClusterCACertificate = string(cluster.CertificateAuthorityData)
+	}
+	if user := config.AuthInfos[ctx.AuthInfo]; user != nil {
+		creds.ClientCertificate = string(user.ClientCertificateData)
+		creds.ClientKey = string(user.ClientKeyData)
+		creds.Token = user.Token
+	}
+	return creds, nil
+}
+
 // WaitForKubeAPI polls until Kubernetes API responds
 func WaitForKubeAPI(kubeconfigPath string, timeout time.Duration) error {
 	config, err := LoadKubeconfig(kubeconfigPath)
@@ -71,19 +109,14 @@ func WaitForKubeAPI(kubeconfigPath string, timeout time.Duration) error {
 		return fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	deadline := time.Now().Add(timeout)
-	var lastErr error
-
-	for time.Now().Before(deadline) {
+	err = poll(context.Background(), pollInterval, timeout, "Kubernetes API", func(context.Context) (bool, error) {
 		_, err := client.Discovery().ServerVersion()
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for Kubernetes API: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for Kubernetes API after %v: %w", timeout, lastErr)
+	return nil
 }
 
 // WaitForKubeAPIWithConfig polls until Kubernetes API responds using a pre-loaded config
@@ -93,19 +126,14 @@ func WaitForKubeAPIWithConfig(config *rest.Config, timeout time.Duration) error
 		return fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	deadline := time.Now().Add(timeout)
-	var lastErr error
-
-	for time.Now().Before(deadline) {
+	err = poll(context.Background(), pollInterval, timeout, "Kubernetes API", func(context.Context) (bool, error) {
 		_, err := client.Discovery().ServerVersion()
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-		time.Sleep(5 * time.Second)
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for Kubernetes API: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for Kubernetes API after %v: %w", timeout, lastErr)
+	return nil
 }
 
 // GetKubernetesVersion returns the server version from a kubeconfig
@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Retry kinds counted in turingpi_retries_total
+const (
+	retryKindSSHDial       = "ssh_dial"
+	retryKindKubernetesAPI = "kubernetes_api"
+	retryKindBMCRestart    = "bmc_restart"
+)
+
+// providerMetrics counts what one provider process did, so operators of
+// long fleet applies can see where time and failures concentrate. Counters
+// are always kept; metrics_listen and metrics_summary expose them.
+type providerMetrics struct {
+	mu          sync.Mutex
+	bmcRequests map[string]int64 // type, opt and status code
+	retries     map[string]int64
+	operations  map[string]*operationStats // resource and operation
+
+	uploadBytes atomic.Int64
+	sshCommands atomic.Int64
+}
+
+type operationStats struct {
+	calls    int64
+	failures int64
+	seconds  float64
+}
+
+func newProviderMetrics() *providerMetrics {
+	return &providerMetrics{
+		bmcRequests: map[string]int64{},
+		retries:     map[string]int64{},
+		operations:  map[string]*operationStats{},
+	}
+}
+
+// metrics are the counters of this provider process
+var metrics = newProviderMetrics()
+
+// metricsServer serves metrics on metrics_listen; nil when not set
+var (
+	metricsServerMu sync.Mutex
+	metricsServer   *http.Server
+	metricsSummary  bool
+)
+
+// bmcRequestType returns the type and opt labels of a BMC request: the type
+// query parameter of /api/bmc calls, otherwise the path segment after
+// /api/bmc/ (e.g. authenticate or upload)
+func bmcRequestType(req *http.Request) (string, string) {
+	query := req.URL.Query()
+	if t := query.Get("type"); t != "" {
+		return t, query.Get("opt")
+	}
+	rest := strings.TrimPrefix(req.URL.Path, "/api/bmc")
+	rest = strings.TrimPrefix(rest, "/")
+	if segment, _, _ := strings.Cut(rest, "/"); segment != "" {
+		return segment, ""
+	}
+	return "other", ""
+}
+
+// countBMCRequest counts a BMC request by type and status code; code 0 is
+// a request that got no response
+func (m *providerMetrics) countBMCRequest(req *http.Request, code int) {
+	kind, opt := bmcRequestType(req)
+	status := "error"
+	if code != 0 {
+		status = fmt.Sprint(code)
+	}
+	m.mu.Lock()
+	m.bmcRequests[fmt.Sprintf(`type=%q,opt=%q,code=%q`, kind, opt, status)]++
+	m.mu.Unlock()
+}
+
+func (m *providerMetrics) countRetry(kind string) {
+	m.mu.Lock()
+	m.retries[kind]++
+	m.mu.Unlock()
+}
+
+// observeOperation records the wall time of one resource or data source
+// operation
+func (m *providerMetrics) observeOperation(resource, operation string, elapsed time.Duration, failed bool) {
+	key := fmt.Sprintf(`resource=%q,operation=%q`, resource, operation)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.operations[key]
+	if stats == nil {
+		stats = &operationStats{}
+		m.operations[key] = stats
+	}
+	stats.calls++
+	stats.seconds += elapsed.Seconds()
+	if failed {
+		stats.failures++
+	}
+}
+
+// countingBody counts the request body bytes sent to the BMC
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// render returns the metrics in the Prometheus text exposition format
+func (m *providerMetrics) render() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	writeFamily := func(name, help, kind string, samples map[string]string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		labels := make([]string, 0, len(samples))
+		for l := range samples {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			if l == "" {
+				fmt.Fprintf(&b, "%s %s\n", name, samples[l])
+			} else {
+				fmt.Fprintf(&b, "%s{%s} %s\n", name, l, samples[l])
+			}
+		}
+	}
+
+	requests := map[string]string{}
+	for l, v := range m.bmcRequests {
+		requests[l] = fmt.Sprint(v)
+	}
+	writeFamily("turingpi_bmc_requests_total", "BMC API requests by type, opt and status code (error when no response was received).", "counter", requests)
+	writeFamily("turingpi_bmc_upload_bytes_total", "Request body bytes sent to the BMC, mostly firmware and image uploads.", "counter",
+		map[string]string{"": fmt.Sprint(m.uploadBytes.Load())})
+
+	retries := map[string]string{}
+	for kind, v := range m.retries {
+		retries[fmt.Sprintf("kind=%q", kind)] = fmt.Sprint(v)
+	}
+	writeFamily("turingpi_retries_total", "Retried operations by kind.", "counter", retries)
+	writeFamily("turingpi_ssh_commands_total", "Commands run over SSH on nodes and the BMC.", "counter",
+		map[string]string{"": fmt.Sprint(m.sshCommands.Load())})
+
+	calls, failures, seconds := map[string]string{}, map[string]string{}, map[string]string{}
+	for l, s := range m.operations {
+		calls[l] = fmt.Sprint(s.calls)
+		failures[l] = fmt.Sprint(s.failures)
+		seconds[l] = fmt.Sprintf("%.3f", s.seconds)
+	}
+	writeFamily("turingpi_operations_total", "Resource and data source operations.", "counter", calls)
+	writeFamily("turingpi_operation_failures_total", "Resource and data source operations that returned an error.", "counter", failures)
+	writeFamily("turingpi_operation_seconds_total", "Wall time spent in resource and data source operations.", "counter", seconds)
+	return b.String()
+}
+
+// startMetrics applies the metrics_listen and metrics_summary settings. The
+// endpoint is served once per process; a busy address, e.g. from another
+// provider process, is logged and skipped.
+func startMetrics(listen string, summary bool) {
+	metricsServerMu.Lock()
+	defer metricsServerMu.Unlock()
+	metricsSummary = metricsSummary || summary
+	if listen == "" || metricsServer != nil {
+		return
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		log.Printf("[WARN] Provider metrics are not served: %v", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = io.WriteString(w, metrics.render())
+	})
+	metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[WARN] Provider metrics endpoint stopped: %v", err)
+		}
+	}(metricsServer)
+	log.Printf("[INFO] Serving provider metrics on http://%s/metrics", listener.Addr())
+}
+
+// stopMetrics logs the summary if requested and stops the metrics endpoint
+func stopMetrics() {
+	metricsServerMu.Lock()
+	defer metricsServerMu.Unlock()
+	if metricsSummary {
+		log.Printf("[INFO] Provider metrics:\n%s", metrics.render())
+	}
+	if metricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = metricsServer.Shutdown(ctx)
+		metricsServer = nil
+	}
+}
+
+// instrumentOperations wraps the operations of resources (or data sources,
+// with prefix "data.") so their wall time and failures are counted
+func instrumentOperations(resources map[string]*schema.Resource, prefix string) {
+	for name, r := range resources {
+		name = prefix + name
+		r.CreateContext = timedContext(name, "create", r.CreateContext)
+		r.ReadContext = timedContext(name, "read", r.ReadContext)
+		r.UpdateContext = timedContext(name, "update", r.UpdateContext)
+		r.DeleteContext = timedContext(name, "delete", r.DeleteContext)
+		//nolint:staticcheck // SA1019: turingpi_node still uses the non-context functions
+		r.Create = timed(name, "create", r.Create)
+		//nolint:staticcheck // SA1019: see above
+		r.Read = timed(name, "read", r.Read)
+		//nolint:staticcheck // SA1019: see above
+		r.Update = timed(name, "update", r.Update)
+		//nolint:staticcheck // SA1019: see above
+		r.Delete = timed(name, "delete", r.Delete)
+	}
+}
+
+func timedContext(resource, operation string, fn func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	if fn == nil {
+		return nil
+	}
+	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		start := time.Now()
+		diags := fn(ctx, d, meta)
+		metrics.observeOperation(resource, operation, time.Since(start), diags.HasError())
+		return diags
+	}
+}
+
+func timed(resource, operation string, fn func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+	if fn == nil {
+		return nil
+	}
+	return func(d *schema.ResourceData, meta interface{}) error {
+		start := time.Now()
+		err := fn(d, meta)
+		metrics.observeOperation(resource, operation, time.Since(start), err != nil)
+		return err
+	}
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// withTestMetrics replaces the provider metrics for one test
+func withTestMetrics(t *testing.T) *providerMetrics {
+	t.Helper()
+	old := metrics
+	metrics = newProviderMetrics()
+	t.Cleanup(func() { metrics = old })
+	return metrics
+}
+
+func TestBMCRequestType(t *testing.T) {
+	tests := []struct {
+		url, kind, opt string
+	}{
+		{"https://bmc/api/bmc?opt=get&type=power", "power", "get"},
+		{"https://bmc/api/bmc?opt=set&type=firmware&length=10", "firmware", "set"},
+		{"https://bmc/api/bmc/authenticate", "authenticate", ""},
+		{"https://bmc/api/bmc/upload/42", "upload", ""},
+		{"https://bmc/", "other", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		if kind, opt := bmcRequestType(req); kind != tt.kind || opt != tt.opt {
+			t.Errorf("%s: expected %s/%s, got %s/%s", tt.url, tt.kind, tt.opt, kind, opt)
+		}
+	}
+}
+
+func TestBMCTransport_Metrics(t *testing.T) {
+	m := withTestMetrics(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") == "flash" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &bmcTransport{userAgent: "test", requestID: "abc"}}
+
+	for _, query := range []string{"opt=get&type=power", "opt=get&type=power", "opt=set&type=flash"} {
+		resp, err := client.Get(server.URL + "/api/bmc?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	resp, err := client.Post(server.URL+"/api/bmc/upload/7", "application/octet-stream", strings.NewReader(strings.Repeat("x", 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	out := m.render()
+	for _, want := range []string{
+		`turingpi_bmc_requests_total{type="power",opt="get",code="200"} 2`,
+		`turingpi_bmc_requests_total{type="flash",opt="set",code="400"} 1`,
+		`turingpi_bmc_requests_total{type="upload",opt="",code="200"} 1`,
+		"turingpi_bmc_upload_bytes_total 1000",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestInstrumentOperations(t *testing.T) {
+	m := withTestMetrics(t)
+	resources := map[string]*schema.Resource{
+		"turingpi_test": {
+			CreateContext: func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			},
+			ReadContext: func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+				return diag.Errorf("unreachable")
+			},
+		},
+		"turingpi_legacy": {
+			Read: func(*schema.ResourceData, interface{}) error { return errors.New("failed") },
+		},
+	}
+	instrumentOperations(resources, "")
+
+	r := resources["turingpi_test"]
+	_ = r.CreateContext(context.Background(), nil, nil)
+	_ = r.ReadContext(context.Background(), nil, nil)
+	//nolint:staticcheck // SA1019: testing the non-context function path
+	_ = resources["turingpi_legacy"].Read(nil, nil)
+	if r.UpdateContext != nil || r.DeleteContext != nil {
+		t.Error("expected unset operations to stay unset")
+	}
+
+	out := m.render()
+	for _, want := range []string{
+		`turingpi_operations_total{resource="turingpi_test",operation="create"} 1`,
+		`turingpi_operation_failures_total{resource="turingpi_test",operation="create"} 0`,
+		`turingpi_operation_failures_total{resource="turingpi_test",operation="read"} 1`,
+		`turingpi_operation_failures_total{resource="turingpi_legacy",operation="read"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `turingpi_operation_seconds_total{resource="turingpi_test",operation="create"} 0.000`) {
+		t.Errorf("expected the create wall time to be recorded:\n%s", out)
+	}
+}
+
+func TestProviderMetrics_Render(t *testing.T) {
+	m := newProviderMetrics()
+	m.countRetry(retryKindSSHDial)
+	m.countRetry(retryKindSSHDial)
+	m.sshCommands.Add(3)
+
+	out := m.render()
+	for _, want := range []string{
+		"# TYPE turingpi_retries_total counter\n",
+		`turingpi_retries_total{kind="ssh_dial"} 2`,
+		"turingpi_ssh_commands_total 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestProvider_OperationsInstrumented(t *testing.T) {
+	m := withTestMetrics(t)
+	p := Provider()
+	read := p.DataSourcesMap["turingpi_latest_k3s_version"].ReadContext
+	if read == nil {
+		t.Fatal("expected a read function")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := p.DataSourcesMap["turingpi_latest_k3s_version"].TestResourceData()
+	_ = read(ctx, d, nil)
+	if !strings.Contains(m.render(), `resource="data.turingpi_latest_k3s_version",operation="read"`) {
+		t.Errorf("expected data source reads to be counted:\n%s", m.render())
+	}
+}
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
+)
+
+// pollInterval is the interval of the provider's wait loops
+const pollInterval = 5 * time.Second
+
+// pollLogEvery is the number of failed attempts between progress log lines
+const pollLogEvery = 6
+
+// poll waits with waitutil.Poll, logging at debug level every pollLogEvery
+// attempts that it is still waiting for what. The jitter spreads concurrent
+// waits, e.g. of nodes provisioned in parallel, so they do not poll the BMC or
+// API in lockstep.
+func poll(ctx context.Context, interval, timeout time.Duration, what string, fn waitutil.ConditionFunc) error {
+	return waitutil.Poll(ctx, interval, timeout, waitutil.DefaultJitter, fn, waitutil.WithProgress(pollLogEvery, func(attempt int, elapsed time.Duration, lastErr error) {
+		fields := map[string]interface{}{
+			"attempt": attempt,
+			"elapsed": elapsed.Round(time.Second).String(),
+			"timeout": timeout.String(),
+		}
+		if lastErr != nil {
+			fields["last_error"] = lastErr.Error()
+		}
+		tflog.Debug(ctx, "Still waiting for "+what, fields)
+	}))
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
+)
+
+func TestPoll_TimeoutIsCategorized(t *testing.T) {
+	err := poll(context.Background(), time.Millisecond, 20*time.Millisecond, "test", func(context.Context) (bool, error) {
+		return false, errors.New("connection refused")
+	})
+
+	var timeoutErr *waitutil.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected *waitutil.TimeoutError, got %v", err)
+	}
+	if category := errorCategoryOf(err); category != errorCategoryTimeout {
+		t.Errorf("expected category %q, got %q", errorCategoryTimeout, category)
+	}
+}
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// powerVerifyTimeout is how long a power cycle waits for the BMC to report
+// each new state
+const powerVerifyTimeout = 30 * time.Second
+
+// powerVerifyInterval is the power status polling interval of a power cycle
+// Replaced in tests
+var powerVerifyInterval = time.Second
+
+// cyclePower powers the node off (gracefully, if configured), waits
+// cycle_delay_seconds and powers it back on, verifying each state. Unlike a
+// reset, the module loses power for the whole delay.
+func cyclePower(ctx context.Context, config *ProviderConfig, d *schema.ResourceData, node int) error {
+	delay := time.Duration(d.Get("cycle_delay_seconds").(int)) * time.Second
+
+	if err := powerOffGracefully(ctx, config, d, node); err != nil {
+		return fmt.Errorf("power cycle: %w", err)
+	}
+	if err := waitForNodePower(ctx, config.Endpoint, config.Token, node, false, powerVerifyTimeout); err != nil {
+		return fmt.Errorf("power cycle: %w", err)
+	}
+
+	tflog.Info(ctx, "Node powered off, waiting before powering on", map[string]interface{}{
+		"node":  node,
+		"delay": delay.String(),
+	})
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("power cycle interrupted with node %d powered off: %w", node, ctx.Err())
+	case <-time.After(delay):
+	}
+
+	if err := setNodePower(config.Endpoint, config.Token, node, true); err != nil {
+		return fmt.Errorf("power cycle: failed to power on node %d: %w", node, err)
+	}
+	if err := waitForNodePower(ctx, config.Endpoint, config.Token, node, true, powerVerifyTimeout); err != nil {
+		return fmt.Errorf("power cycle: %w", err)
+	}
+	return nil
+}
+
+// waitForNodePower polls the BMC until node reports the wanted power state
+func waitForNodePower(ctx context.Context, endpoint, token string, node int, on bool, timeout time.Duration) error {
+	want := "off"
+	if on {
+		want = "on"
+	}
+	nodeName := fmt.Sprintf("node%d", node)
+	err := poll(ctx, powerVerifyInterval, timeout, nodeName+" power "+want, func(context.Context) (bool, error) {
+		status, err := getPowerStatus(endpoint, token)
+		return err == nil && parsePowerStatus(status)[nodeName] == on, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for node %d to power %s: %w", node, want, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestResourcePowerCreate_Cycle(t *testing.T) {
+	bmc := &fakePowerBMC{state: map[string]int{"node1": 1}}
+	server := httptest.NewServer(bmc)
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":                1,
+		"state":               "cycle",
+		"cycle_delay_seconds": 0,
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	if diags := resourcePowerCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if want := []string{"node1=0", "node1=1"}; !reflect.DeepEqual(bmc.writes, want) {
+		t.Errorf("expected writes %v, got %v", want, bmc.writes)
+	}
+	if !d.Get("current_state").(bool) {
+		t.Error("expected node to be on after the cycle")
+	}
+}
+
+func TestWaitForNodePower_Timeout(t *testing.T) {
+	orig := powerVerifyInterval
+	powerVerifyInterval = 10 * time.Millisecond
+	t.Cleanup(func() { powerVerifyInterval = orig })
+
+	// The BMC accepts the request but the node never reports on
+	server := httptest.NewServer(&fakePowerBMC{state: map[string]int{}})
+	defer server.Close()
+
+	err := waitForNodePower(context.Background(), server.URL, "test-token", 2, true, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "waiting for node 2 to power on: timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestResourcePowerUpdate_CycleDelayOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("opt") == "set" {
+			t.Errorf("expected no power change, got %s", r.URL.String())
+		}
+		_, _ = w.Write([]byte(`{"response":[["node1",1]]}`))
+	}))
+	defer server.Close()
+
+	r := resourcePower()
+	state := r.Data(nil)
+	state.SetId("power-node-1")
+	_ = state.Set("node", 1)
+	_ = state.Set("state", "cycle")
+	_ = state.Set("cycle_delay_seconds", 5)
+
+	diff, err := r.Diff(context.Background(), state.State(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"node":                1,
+		"state":               "cycle",
+		"cycle_delay_seconds": 10,
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state.State(), diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+	if diags := resourcePowerUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+}
@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// gracefulPollInterval is how often the node heartbeat is probed while waiting for shutdown
+var gracefulPollInterval = 5 * time.Second
+
+// gracefulSettleDelay is how long to wait after the node stops accepting SSH
+// connections before power is cut. sshd is stopped early in the shutdown, so
+// this leaves the OS time to unmount filesystems and halt.
+var gracefulSettleDelay = 15 * time.Second
+
+// errGracefulTimeout is returned when the drain or the shutdown did not finish
+// within the timeout, the only failure force_on_timeout cuts power on
+var errGracefulTimeout = errors.New("graceful shutdown timed out")
+
+// heartbeatProbe reports whether a node still accepts TCP connections on address
+var heartbeatProbe = func(address string) bool {
+	conn, err := net.DialTimeout("tcp", address, 3*time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// gracefulShutdownConfig holds the graceful block of turingpi_power
+type gracefulShutdownConfig struct {
+	Method         string
+	Timeout        time.Duration
+	ForceOnTimeout bool
+	Node           NodeConfig
+	Kubeconfig     []byte
+	NodeName       string
+}
+
+func gracefulShutdownSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"method": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Shutdown method: 'ssh' runs shutdown -h now on the node, 'kubectl' drains the node through the Kubernetes API first (and also shuts down over SSH if host is set).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"ssh", "kubectl"}, false)),
+			},
+			"timeout": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          120,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(10)),
+				Description:      "Seconds to wait for the drain and for the node to stop responding before cutting power.",
+			},
+			"force_on_timeout": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Cut power anyway when the timeout is reached. When false, the apply fails and the node stays on. Other failures, such as an SSH connection error or a failed drain, always fail the apply and leave the node on.",
+			},
+			"host": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "IP address or hostname of the node OS. Required for the ssh method.",
+			},
+			"ssh_user": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "SSH username",
+			},
+			"ssh_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH private key content",
+			},
+			"ssh_password": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH password (ssh_key is preferred)",
+			},
+			"ssh_port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     22,
+				Description: "SSH port number",
+			},
+			"kubeconfig": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Kubeconfig content. Required for the kubectl method.",
+			},
+			"node_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Kubernetes node name to drain. Required for the kubectl method.",
+			},
+		},
+	}
+}
+
+// extractGracefulShutdownConfig returns the graceful block, or nil if not set
+func extractGracefulShutdownConfig(d *schema.ResourceData) (*gracefulShutdownConfig, error) {
+	v, ok := d.GetOk("graceful")
+	if !ok {
+		return nil, nil
+	}
+	list := v.([]interface{})
+	if len(list) == 0 || list[0] == nil {
+		return nil, nil
+	}
+	data := list[0].(map[string]interface{})
+
+	cfg := &gracefulShutdownConfig{
+		Method:         data["method"].(string),
+		Timeout:        time.Duration(data["timeout"].(int)) * time.Second,
+		ForceOnTimeout: data["force_on_timeout"].(bool),
+		Node:           extractNodeConfig(data),
+		Kubeconfig:     []byte(data["kubeconfig"].(string)),
+		NodeName:       data["node_name"].(string),
+	}
+
+	switch cfg.Method {
+	case "ssh":
+		if cfg.Node.Host == "" {
+			return nil, fmt.Errorf("graceful: host is required for the ssh method")
+		}
+	case "kubectl":
+		if len(cfg.Kubeconfig) == 0 || cfg.NodeName == "" {
+			return nil, fmt.Errorf("graceful: kubeconfig and node_name are required for the kubectl method")
+		}
+	}
+	if cfg.Node.Host != "" {
+		if err := validateNodeSSH(cfg.Node); err != nil {
+			return nil, fmt.Errorf("graceful: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// gracefulShutdown drains and/or shuts down the node OS, then waits for it to stop
+// responding. The caller cuts power afterwards.
+func gracefulShutdown(parent context.Context, cfg *gracefulShutdownConfig, clientFactory func() SSHClient) error {
+	ctx, cancel := context.WithTimeout(parent, cfg.Timeout)
+	defer cancel()
+
+	if cfg.Method == "kubectl" {
+		tflog.Info(ctx, "Draining Kubernetes node before power-off", map[string]interface{}{"node_name": cfg.NodeName})
+		client, err := NewK8sClient(cfg.Kubeconfig)
+		if err != nil {
+			return fmt.Errorf("failed to create Kubernetes client: %w", err)
+		}
+		if err := client.DrainNode(ctx, cfg.NodeName, cfg.Timeout); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w: draining node %s: %v", errGracefulTimeout, cfg.NodeName, err)
+			}
+			return err
+		}
+	}
+
+	if cfg.Node.Host == "" {
+		return nil
+	}
+
+	tflog.Info(ctx, "Shutting down node OS over SSH", map[string]interface{}{"host": cfg.Node.Host})
+	client := clientFactory()
+	if err := client.Connect(cfg.Node.Host, cfg.Node.SSHPort, cfg.Node.getSSHConfig()); err != nil {
+		return fmt.Errorf("SSH connection failed: %w", err)
+	}
+	// The session usually drops as the node goes down, so the command error is not meaningful
+	_, _ = client.RunCommand("shutdown -h now")
+	_ = client.Close()
+
+	address := net.JoinHostPort(cfg.Node.Host, strconv.Itoa(cfg.Node.SSHPort))
+	err := poll(ctx, gracefulPollInterval, cfg.Timeout, "node "+cfg.Node.Host+" to stop responding", func(context.Context) (bool, error) {
+		return !heartbeatProbe(address), nil
+	})
+	if err != nil {
+		if parent.Err() != nil {
+			return parent.Err()
+		}
+		return fmt.Errorf("%w: node %s still responding after %s", errGracefulTimeout, cfg.Node.Host, cfg.Timeout)
+	}
+
+	// A closed SSH port only means sshd has stopped, the OS is still halting.
+	// Nothing reports the halt itself, so this is a fixed delay.
+	tflog.Debug(ctx, "Node stopped responding, waiting for the OS to halt", map[string]interface{}{
+		"host":  cfg.Node.Host,
+		"delay": gracefulSettleDelay.String(),
+	})
+	return sleepContext(parent, gracefulSettleDelay)
+}
+
+// powerOffGracefully runs the graceful shutdown, if configured and the node is on, then cuts power
+func powerOffGracefully(ctx context.Context, config *ProviderConfig, d *schema.ResourceData, node int) error {
+	graceful, err := extractGracefulShutdownConfig(d)
+	if err != nil {
+		return err
+	}
+	return powerOffAfterShutdown(ctx, config, graceful, node)
+}
+
+// powerOffAfterShutdown shuts the node down gracefully, unless graceful is nil
+// or the node is off, then cuts power. Power is only cut after a failed
+// shutdown if it timed out and force_on_timeout is set.
+func powerOffAfterShutdown(ctx context.Context, config *ProviderConfig, graceful *gracefulShutdownConfig, node int) error {
+	if graceful != nil {
+		status, err := getPowerStatus(config.Endpoint, config.Token)
+		if err != nil {
+			return fmt.Errorf("failed to read power status: %w", err)
+		}
+		if parsePowerStatus(status)[fmt.Sprintf("node%d", node)] {
+			if err := gracefulShutdown(ctx, graceful, config.newSSHClient); err != nil {
+				if !graceful.ForceOnTimeout || !errors.Is(err, errGracefulTimeout) {
+					return fmt.Errorf("graceful shutdown failed, node left powered on: %w", err)
+				}
+				tflog.Warn(ctx, "Graceful shutdown failed, cutting power", map[string]interface{}{"node": node, "error": err.Error()})
+			}
+		}
+	}
+
+	return setNodePower(config.Endpoint, config.Token, node, false)
+}
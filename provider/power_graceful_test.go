@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// mockGracefulShutdown replaces the heartbeat probe for a test and returns an
+// SSH client factory recording each command. The probe reports the node alive
+// for aliveProbes calls, or forever if negative.
+func mockGracefulShutdown(t *testing.T, aliveProbes int) (func() SSHClient, *[]string) {
+	t.Helper()
+	var commands []string
+
+	origProbe, origInterval, origSettle := heartbeatProbe, gracefulPollInterval, gracefulSettleDelay
+	factory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				return "", nil
+			},
+		}
+	}
+	probes := 0
+	heartbeatProbe = func(address string) bool {
+		probes++
+		return aliveProbes < 0 || probes <= aliveProbes
+	}
+	gracefulPollInterval = 10 * time.Millisecond
+	gracefulSettleDelay = 0
+
+	t.Cleanup(func() {
+		heartbeatProbe, gracefulPollInterval, gracefulSettleDelay = origProbe, origInterval, origSettle
+	})
+	return factory, &commands
+}
+
+func sshGracefulBlock() map[string]interface{} {
+	return map[string]interface{}{
+		"method":   "ssh",
+		"timeout":  30,
+		"host":     "10.10.88.73",
+		"ssh_user": "root",
+		"ssh_key":  "fake-key",
+	}
+}
+
+func TestExtractGracefulShutdownConfig_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		block map[string]interface{}
+	}{
+		{"ssh without host", map[string]interface{}{"method": "ssh", "ssh_user": "root", "ssh_key": "k"}},
+		{"ssh without credentials", map[string]interface{}{"method": "ssh", "host": "10.10.88.73", "ssh_user": "root"}},
+		{"kubectl without node_name", map[string]interface{}{"method": "kubectl", "kubeconfig": "apiVersion: v1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+				"node":     1,
+				"state":    "off",
+				"graceful": []interface{}{tt.block},
+			})
+			if _, err := extractGracefulShutdownConfig(d); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestGracefulShutdown_SSH(t *testing.T) {
+	factory, commands := mockGracefulShutdown(t, 2)
+
+	cfg := &gracefulShutdownConfig{
+		Method:  "ssh",
+		Timeout: 5 * time.Second,
+		Node:    NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHKey: []byte("k"), SSHPort: 22},
+	}
+	if err := gracefulShutdown(context.Background(), cfg, factory); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*commands) != 1 || (*commands)[0] != "shutdown -h now" {
+		t.Errorf("expected shutdown command, got %v", *commands)
+	}
+}
+
+func TestGracefulShutdown_Timeout(t *testing.T) {
+	factory, _ := mockGracefulShutdown(t, -1)
+
+	cfg := &gracefulShutdownConfig{
+		Method:  "ssh",
+		Timeout: 50 * time.Millisecond,
+		Node:    NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHKey: []byte("k"), SSHPort: 22},
+	}
+	err := gracefulShutdown(context.Background(), cfg, factory)
+	if !errors.Is(err, errGracefulTimeout) || !strings.Contains(err.Error(), "still responding") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestPowerOffAfterShutdown_ForceOnTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		connectErr error
+		wantOn     bool
+	}{
+		{"timeout cuts power", nil, false},
+		{"connection failure leaves the node on", errors.New("connection refused"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockGracefulShutdown(t, -1)
+			factory := func() SSHClient {
+				return &MockSSHClient{ConnectFunc: func(string, int, *SSHConfig) error { return tt.connectErr }}
+			}
+			bmc := &fakePowerBMC{state: map[string]int{"node1": 1}}
+			server := httptest.NewServer(bmc)
+			defer server.Close()
+
+			cfg := &gracefulShutdownConfig{
+				Method:         "ssh",
+				Timeout:        50 * time.Millisecond,
+				ForceOnTimeout: true,
+				Node:           NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHKey: []byte("k"), SSHPort: 22},
+			}
+			err := powerOffAfterShutdown(context.Background(), &ProviderConfig{Token: "test-token", Endpoint: server.URL, sshClientFactory: factory}, cfg, 1)
+			if (err != nil) != tt.wantOn {
+				t.Errorf("expected error %v, got %v", tt.wantOn, err)
+			}
+			if on := bmc.state["node1"] == 1; on != tt.wantOn {
+				t.Errorf("expected node1 on %v, got %v", tt.wantOn, on)
+			}
+		})
+	}
+}
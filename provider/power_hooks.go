@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// powerHookConfig holds the on_power_change block
+type powerHookConfig struct {
+	PreCommand  string
+	PostCommand string
+	Target      NodeConfig
+}
+
+func powerHooksSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"pre_command": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Shell command run before the power transition. A non-zero exit aborts the transition.",
+			},
+			"post_command": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Shell command run after the power transition. A non-zero exit fails the apply, but the transition is not undone.",
+			},
+			"target": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "bmc",
+				Description:      "Where commands run over SSH: 'bmc' (the BMC itself) or 'host' (the machine given by host).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"bmc", "host"}, false)),
+			},
+			"host": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "IP address or hostname to run commands on. Required for the host target; defaults to the provider endpoint host for the bmc target.",
+			},
+			"ssh_user": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "root",
+				Description: "SSH username",
+			},
+			"ssh_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH private key content",
+			},
+			"ssh_password": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH password (ssh_key is preferred)",
+			},
+			"ssh_port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     22,
+				Description: "SSH port number",
+			},
+		},
+	}
+}
+
+// extractPowerHookConfig returns the on_power_change block, or nil if not set.
+// For the bmc target, the host defaults to the host of the provider endpoint.
+func extractPowerHookConfig(d *schema.ResourceData, endpoint string) (*powerHookConfig, error) {
+	v, ok := d.GetOk("on_power_change")
+	if !ok {
+		return nil, nil
+	}
+	list := v.([]interface{})
+	if len(list) == 0 || list[0] == nil {
+		return nil, nil
+	}
+	data := list[0].(map[string]interface{})
+
+	cfg := &powerHookConfig{
+		PreCommand:  data["pre_command"].(string),
+		PostCommand: data["post_command"].(string),
+		Target:      extractNodeConfig(data),
+	}
+	if cfg.PreCommand == "" && cfg.PostCommand == "" {
+		return nil, nil
+	}
+
+	if cfg.Target.Host == "" {
+		if data["target"].(string) == "host" {
+			return nil, fmt.Errorf("on_power_change: host is required for the host target")
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("on_power_change: cannot derive BMC host from endpoint %q, set host", endpoint)
+		}
+		cfg.Target.Host = u.Hostname()
+	}
+	if err := validateNodeSSH(cfg.Target); err != nil {
+		return nil, fmt.Errorf("on_power_change: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// runPowerHook runs a hook command over SSH with the transition exported as
+// TURINGPI_NODE, TURINGPI_POWER_FROM and TURINGPI_POWER_TO, logging its output
+func runPowerHook(ctx context.Context, clientFactory func() SSHClient, target NodeConfig, phase, command string, node int, from, to string) error {
+	client := clientFactory()
+	if err := client.Connect(target.Host, target.SSHPort, target.getSSHConfig()); err != nil {
+		return fmt.Errorf("%s hook: SSH connection to %s failed: %w", phase, target.Host, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	script := fmt.Sprintf("export TURINGPI_NODE=%d TURINGPI_POWER_FROM=%s TURINGPI_POWER_TO=%s; %s", node, from, to, command)
+	output, err := client.RunCommand(script)
+	tflog.Info(ctx, "Power change hook output", map[string]interface{}{
+		"phase":  phase,
+		"node":   node,
+		"host":   target.Host,
+		"output": strings.TrimSpace(output),
+	})
+	if err != nil {
+		return fmt.Errorf("%s hook failed on %s: %w", phase, target.Host, err)
+	}
+	return nil
+}
+
+// withPowerHooks runs transition between the pre and post hooks of the
+// on_power_change block. Hooks are skipped when the node is already in the
+// requested state; a reset or cycle always counts as a transition.
+func withPowerHooks(ctx context.Context, config *ProviderConfig, d *schema.ResourceData, node int, to string, transition func() error) error {
+	hooks, err := extractPowerHookConfig(d, config.Endpoint)
+	if err != nil {
+		return err
+	}
+	if hooks == nil {
+		return transition()
+	}
+
+	status, err := getPowerStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return fmt.Errorf("failed to read power status: %w", err)
+	}
+	from := "off"
+	if parsePowerStatus(status)[fmt.Sprintf("node%d", node)] {
+		from = "on"
+	}
+	if from == to {
+		return transition()
+	}
+
+	if hooks.PreCommand != "" {
+		if err := runPowerHook(ctx, config.newSSHClient, hooks.Target, "pre", hooks.PreCommand, node, from, to); err != nil {
+			return fmt.Errorf("power transition aborted: %w", err)
+		}
+	}
+
+	if err := transition(); err != nil {
+		return err
+	}
+
+	if hooks.PostCommand != "" {
+		if err := runPowerHook(ctx, config.newSSHClient, hooks.Target, "post", hooks.PostCommand, node, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// mockPowerHooks returns an SSH client factory recording host and command of
+// each hook run. Commands containing "fail" return an error.
+func mockPowerHooks() (func() SSHClient, *[]string) {
+	var runs []string
+	factory := func() SSHClient {
+		var host string
+		return &MockSSHClient{
+			ConnectFunc: func(h string, port int, config *SSHConfig) error {
+				host = h
+				return nil
+			},
+			RunCommandFunc: func(cmd string) (string, error) {
+				runs = append(runs, host+": "+cmd)
+				if strings.Contains(cmd, "fail") {
+					return "hook output", errors.New("Process exited with status 1")
+				}
+				return "hook output", nil
+			},
+		}
+	}
+	return factory, &runs
+}
+
+func TestExtractPowerHookConfig(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":  1,
+		"state": "on",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"pre_command":  "echo pre",
+			"ssh_password": "turing",
+		}},
+	})
+
+	cfg, err := extractPowerHookConfig(d, "https://10.10.88.70")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Target.Host != "10.10.88.70" || cfg.Target.SSHUser != "root" || cfg.Target.SSHPort != 22 {
+		t.Errorf("expected BMC target from endpoint, got %+v", cfg.Target)
+	}
+
+	d = schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":  1,
+		"state": "on",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"pre_command":  "echo pre",
+			"target":       "host",
+			"ssh_password": "secret",
+		}},
+	})
+	if _, err := extractPowerHookConfig(d, "https://10.10.88.70"); err == nil {
+		t.Error("expected error for host target without host")
+	}
+}
+
+func TestWithPowerHooks_RunsAroundTransition(t *testing.T) {
+	factory, runs := mockPowerHooks()
+	server := newFakePowerBMC(t, map[string]int{"node1": 0, "node2": 0, "node3": 0, "node4": 0})
+
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":  2,
+		"state": "on",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"pre_command":  "logger pre",
+			"post_command": "logger post",
+			"target":       "host",
+			"host":         "10.10.88.10",
+			"ssh_user":     "ops",
+			"ssh_key":      "fake-key",
+		}},
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL, sshClientFactory: factory}
+
+	transitioned := false
+	err := withPowerHooks(context.Background(), config, d, 2, "on", func() error {
+		if len(*runs) != 1 {
+			t.Errorf("expected pre hook before transition, got %v", *runs)
+		}
+		transitioned = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !transitioned {
+		t.Fatal("expected transition to run")
+	}
+
+	expected := []string{
+		"10.10.88.10: export TURINGPI_NODE=2 TURINGPI_POWER_FROM=off TURINGPI_POWER_TO=on; logger pre",
+		"10.10.88.10: export TURINGPI_NODE=2 TURINGPI_POWER_FROM=off TURINGPI_POWER_TO=on; logger post",
+	}
+	if strings.Join(*runs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected hook runs:\n%s", strings.Join(*runs, "\n"))
+	}
+}
+
+func TestWithPowerHooks_PreFailureAbortsTransition(t *testing.T) {
+	factory, _ := mockPowerHooks()
+	server := newFakePowerBMC(t, map[string]int{"node1": 1, "node2": 0, "node3": 0, "node4": 0})
+
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":  1,
+		"state": "off",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"pre_command":  "systemctl stop app || fail",
+			"ssh_password": "turing",
+		}},
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL, sshClientFactory: factory}
+
+	err := withPowerHooks(context.Background(), config, d, 1, "off", func() error {
+		t.Error("transition should not run after a failed pre hook")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Errorf("expected aborted transition, got %v", err)
+	}
+}
+
+func TestWithPowerHooks_SkipsWithoutTransition(t *testing.T) {
+	factory, runs := mockPowerHooks()
+	server := newFakePowerBMC(t, map[string]int{"node1": 1, "node2": 0, "node3": 0, "node4": 0})
+
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":  1,
+		"state": "on",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"pre_command":  "logger pre",
+			"ssh_password": "turing",
+		}},
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL, sshClientFactory: factory}
+
+	if err := withPowerHooks(context.Background(), config, d, 1, "on", func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*runs) != 0 {
+		t.Errorf("expected no hooks when already on, got %v", *runs)
+	}
+
+	// A reset is always a transition
+	if err := withPowerHooks(context.Background(), config, d, 1, "reset", func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*runs) != 1 {
+		t.Errorf("expected pre hook for reset, got %v", *runs)
+	}
+}
+
+func TestResourcePowerDelete_RunsHooks(t *testing.T) {
+	factory, runs := mockPowerHooks()
+	bmc := &fakePowerBMC{state: map[string]int{"node1": 0, "node2": 0, "node3": 1, "node4": 0}}
+	server := httptest.NewServer(bmc)
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":  3,
+		"state": "on",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"post_command": "logger powered off",
+			"ssh_password": "turing",
+		}},
+	})
+	d.SetId("power-node-3")
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL, sshClientFactory: factory}
+
+	if diags := resourcePowerDelete(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if bmc.state["node3"] != 0 {
+		t.Error("expected node 3 to be powered off")
+	}
+	if len(*runs) != 1 || !strings.Contains((*runs)[0], "TURINGPI_POWER_TO=off; logger powered off") {
+		t.Errorf("expected post hook on destroy, got %v", *runs)
+	}
+}
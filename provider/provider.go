@@ -1,44 +1,88 @@
 package provider
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/bmc"
 )
 
 const defaultEndpoint = "https://turingpi.local"
 
+// Values of the auth provider setting
+const (
+	authPassword = "password"
+	authNone     = "none"
+)
+
 // HTTPClient is the shared HTTP client for all API requests
 var HTTPClient = &http.Client{}
 
 // ProviderConfig holds the configuration for the provider
 type ProviderConfig struct {
-	Token    string
+	Token    string // Empty with auth = "none"
 	Endpoint string
+	// DryRun makes cluster resources record provisioning commands instead of running them
+	DryRun bool
+	// DefaultMetadata is merged into the triggers of firmware and node
+	// resources and into the node labels of cluster resources
+	DefaultMetadata map[string]string
+
+	// session renews Token in the background; requests always carry the latest token
+	session *bmcSession
+	// fleet caps concurrent uploads and flashes across boards; nil when unlimited
+	fleet *fleetPool
+	// firmware is the BMC firmware version checked against required_firmware
+	firmware *bmcFirmware
+	// sshClientFactory creates the SSH clients of power hooks, graceful
+	// shutdowns, flash readback and rootfs expansion; NewSSHClient when nil
+	sshClientFactory func() SSHClient
+}
+
+// newSSHClient returns an SSH client from the configured factory
+func (c *ProviderConfig) newSSHClient() SSHClient {
+	if c.sshClientFactory != nil {
+		return c.sshClientFactory()
+	}
+	return NewSSHClient()
 }
 
 func Provider() *schema.Provider {
-	return &schema.Provider{
+	p := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"username": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_USERNAME", nil),
-				Description: "The username for BMC authentication",
+				Description: "The username for BMC authentication. Required unless auth is 'none'.",
 			},
 			"password": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				Sensitive:   true,
 				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_PASSWORD", nil),
-				Description: "The password for BMC authentication",
+				Description: "The password for BMC authentication. Required unless auth is 'none'.",
+			},
+			"auth": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("TURINGPI_AUTH", authPassword),
+				Description:      "How to authenticate with the BMC: 'password' (default) logs in with username and password, 'none' skips login and sends no bearer token, for BMCs with authentication disabled on an isolated network.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{authPassword, authNone}, false)),
 			},
 			"endpoint": {
 				Type:        schema.TypeString,
 				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_ENDPOINT", defaultEndpoint),
-				Description: "The BMC API endpoint URL (e.g., https://turingpi.local or https://192.168.1.100)",
+				Description: "The BMC API endpoint URL (e.g., https://turingpi.local or https://192.168.1.100). A bare host or IP gets https://, and redirects, such as http:// to https://, are followed.",
 			},
 			"insecure": {
 				Type:        schema.TypeBool,
@@ -46,57 +90,243 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_INSECURE", false),
 				Description: "Skip TLS certificate verification (useful for self-signed or expired certificates)",
 			},
+			"user_agent_suffix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_USER_AGENT_SUFFIX", ""),
+				Description: "Text appended to the User-Agent header (terraform-provider-turingpi/<version>) of BMC requests",
+			},
+			"request_id": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_REQUEST_ID", ""),
+				Description: "Value of the X-Request-ID header sent on every BMC request. Defaults to a random ID generated for each provider run.",
+			},
+			"reauth_interval": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_REAUTH_INTERVAL", defaultReauthInterval),
+				Description: "How often to re-authenticate with the BMC in the background, so long applies do not outlive the session (e.g., '30m'). '0' disables renewal.",
+			},
+			"fleet_parallelism": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_FLEET_PARALLELISM", 0),
+				Description: "Maximum number of firmware uploads and node flashes running at once across all boards managed from this machine, including boards of other provider configurations. 0 (default) means no limit.",
+			},
+			"dry_run": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_DRY_RUN", false),
+				Description: "Record the SSH and talosctl commands turingpi_k3s_cluster, turingpi_talos_cluster and turingpi_talos_cert_rotation would run on create and destroy, and fail with them as a reviewable script instead of running them.",
+			},
+			"default_metadata": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Fleet-wide identifiers (e.g. site, rack, owner) merged into the triggers of firmware and node resources and into the node labels of turingpi_k3s_cluster and turingpi_talos_cluster. Keys set on a resource win. Keys and values must be valid Kubernetes labels; changing them does not re-run resources on their triggers.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				ValidateFunc: validateDefaultMetadata,
+			},
+			"board_lock": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Lock file on the BMC, written over SSH before the first change a run makes to the board and renewed while it runs. A run that finds another run's lock fails instead of fighting over node power.",
+				Elem:        boardLockSchema(),
+			},
+			"metrics_listen": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_METRICS_LISTEN", ""),
+				Description: "Address to serve provider metrics on in the Prometheus text format while the provider runs (e.g., '127.0.0.1:9464'), at /metrics. Empty (default) serves nothing.",
+			},
+			"metrics_summary": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TURINGPI_METRICS_SUMMARY", false),
+				Description: "Log the provider metrics (BMC requests, retries, bytes uploaded, SSH commands, time per resource operation) at INFO level when Terraform stops the provider.",
+			},
+			"bmc_response_format": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("TURINGPI_BMC_RESPONSE_FORMAT", responseFormatAuto),
+				Description:      "BMC response format to parse: 'auto' (default) detects it per response, 'legacy' only accepts the array responses of firmware 1.x, 'v2' only accepts the object responses of firmware 2.x. Pin it for firmware forks that auto-detection misreads.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{responseFormatAuto, responseFormatLegacy, responseFormatV2}, false)),
+			},
+			"bmc_request_method": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("TURINGPI_BMC_REQUEST_METHOD", bmc.RequestMethodAuto),
+				Description:      "How mutating BMC calls (opt=set) are sent: 'auto' (default) uses POST bodies when the BMC reports API " + bmc.POSTAPIVersion + " or later and GET query parameters otherwise, 'get' and 'post' pin the method.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{bmc.RequestMethodAuto, bmc.RequestMethodGET, bmc.RequestMethodPOST}, false)),
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"turingpi_power":          resourcePower(),
-			"turingpi_flash":          resourceFlash(),
-			"turingpi_node":           resourceNode(),
-			"turingpi_usb":            resourceUSB(),
-			"turingpi_network_reset":  resourceNetworkReset(),
-			"turingpi_bmc_firmware":   resourceBMCFirmware(),
-			"turingpi_uart":           resourceUART(),
-			"turingpi_bmc_reboot":     resourceBMCReboot(),
-			"turingpi_usb_boot":       resourceUSBBoot(),
-			"turingpi_node_to_msd":    resourceNodeToMSD(),
-			"turingpi_clear_usb_boot": resourceClearUSBBoot(),
-			"turingpi_bmc_reload":     resourceBMCReload(),
-			"turingpi_k3s_cluster":    resourceK3sCluster(),
-			"turingpi_talos_cluster":  resourceTalosCluster(),
+			"turingpi_power":                resourcePower(),
+			"turingpi_flash":                resourceFlash(),
+			"turingpi_node":                 resourceNode(),
+			"turingpi_usb":                  resourceUSB(),
+			"turingpi_network_reset":        resourceNetworkReset(),
+			"turingpi_bmc_firmware":         resourceBMCFirmware(),
+			"turingpi_uart":                 resourceUART(),
+			"turingpi_bmc_reboot":           resourceBMCReboot(),
+			"turingpi_bmc_factory_reset":    resourceBMCFactoryReset(),
+			"turingpi_usb_boot":             resourceUSBBoot(),
+			"turingpi_node_to_msd":          resourceNodeToMSD(),
+			"turingpi_clear_usb_boot":       resourceClearUSBBoot(),
+			"turingpi_bmc_reload":           resourceBMCReload(),
+			"turingpi_k3s_cluster":          resourceK3sCluster(),
+			"turingpi_talos_cluster":        resourceTalosCluster(),
+			"turingpi_talos_cert_rotation":  resourceTalosCertRotation(),
+			"turingpi_eeprom":               resourceEEPROM(),
+			"turingpi_power_profile":        resourcePowerProfile(),
+			"turingpi_board":                resourceBoard(),
+			"turingpi_helm_release":         resourceHelmRelease(),
+			"turingpi_namespace":            resourceNamespace(),
+			"turingpi_cluster_registration": resourceClusterRegistration(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"turingpi_info":   dataSourceInfo(),
-			"turingpi_usb":    dataSourceUSB(),
-			"turingpi_power":  dataSourcePower(),
-			"turingpi_uart":   dataSourceUART(),
-			"turingpi_sdcard": dataSourceSDCard(),
-			"turingpi_about":  dataSourceAbout(),
+			"turingpi_info":                 dataSourceInfo(),
+			"turingpi_usb":                  dataSourceUSB(),
+			"turingpi_power":                dataSourcePower(),
+			"turingpi_uart":                 dataSourceUART(),
+			"turingpi_sdcard":               dataSourceSDCard(),
+			"turingpi_about":                dataSourceAbout(),
+			"turingpi_k3s_cluster_status":   dataSourceK3sClusterStatus(),
+			"turingpi_talos_cluster_health": dataSourceTalosClusterHealth(),
+			"turingpi_power_profile":        dataSourcePowerProfile(),
+			"turingpi_latest_k3s_version":   dataSourceLatestK3sVersion(),
+			"turingpi_latest_talos_version": dataSourceLatestTalosVersion(),
+			"turingpi_inventory":            dataSourceInventory(),
+			"turingpi_ssh_host_keys":        dataSourceSSHHostKeys(),
+			"turingpi_power_metrics":        dataSourcePowerMetrics(),
 		},
-		ConfigureFunc: configureProvider,
+		ConfigureContextFunc: configureProviderContext,
+	}
+	addRequiredFirmware(p.ResourcesMap)
+	instrumentOperations(p.ResourcesMap, "")
+	instrumentOperations(p.DataSourcesMap, "data.")
+	return p
+}
+
+// Shutdown releases what the provider process holds on the boards, such as
+// board locks, stops BMC session renewal and reports the provider metrics.
+// Called by main when Terraform stops the provider.
+func Shutdown() {
+	releaseBoardLocks()
+	stopBMCSessions()
+	stopMetrics()
+}
+
+// configureProviderContext configures the provider and warns when BMC
+// authentication is disabled
+func configureProviderContext(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	config, err := configureProvider(d)
+	if err != nil {
+		return nil, diagFromErr(err)
+	}
+	if d.Get("auth").(string) != authNone {
+		return config, nil
 	}
+	return config, diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "BMC authentication is disabled",
+		Detail: fmt.Sprintf("auth = \"none\": requests to %s are sent without credentials. Anyone who can reach the BMC can power, flash "+
+			"and reconfigure the nodes, so only use this on an isolated network.", config.(*ProviderConfig).Endpoint),
+	}}
 }
 
 func configureProvider(d *schema.ResourceData) (interface{}, error) {
 	username := d.Get("username").(string)
 	password := d.Get("password").(string)
-	endpoint := d.Get("endpoint").(string)
+	anonymous := d.Get("auth").(string) == authNone
+	if !anonymous && (username == "" || password == "") {
+		return nil, fmt.Errorf("username and password are required unless auth is \"none\"")
+	}
+	endpoint, err := normalizeEndpoint(d.Get("endpoint").(string))
+	if err != nil {
+		return nil, err
+	}
 	insecure := d.Get("insecure").(bool)
 
-	// Configure HTTP client with TLS settings
+	reauthInterval, err := time.ParseDuration(d.Get("reauth_interval").(string))
+	if err != nil || reauthInterval < 0 {
+		return nil, fmt.Errorf("invalid reauth_interval %q: must be a duration such as '30m', or '0' to disable", d.Get("reauth_interval").(string))
+	}
+
+	fleetParallelism := d.Get("fleet_parallelism").(int)
+	if fleetParallelism < 0 {
+		return nil, fmt.Errorf("invalid fleet_parallelism %d: must be 0 (no limit) or greater", fleetParallelism)
+	}
+
+	bmcResponseFormat = d.Get("bmc_response_format").(string)
+	startMetrics(d.Get("metrics_listen").(string), d.Get("metrics_summary").(bool))
+
+	requestID := d.Get("request_id").(string)
+	if requestID == "" {
+		var err error
+		if requestID, err = newRequestID(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Configure HTTP client with TLS settings and the correlation headers
+	var base http.RoundTripper
 	if insecure {
-		HTTPClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
+		base = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
 	}
+	transport := &bmcTransport{
+		base:      base,
+		userAgent: buildUserAgent(d.Get("user_agent_suffix").(string)),
+		requestID: requestID,
+		restart:   &bmcRestart{},
+	}
+	if transport.lock, err = extractBoardLock(d, endpoint, requestID); err != nil {
+		return nil, err
+	}
+	HTTPClient = &http.Client{Transport: transport}
+	log.Printf("[INFO] BMC requests use User-Agent %q and %s %s", transport.userAgent, requestIDHeader, requestID)
 
-	token, err := authenticate(endpoint, username, password)
-	if err != nil {
+	// Fail with the step that broke (DNS, TCP, TLS) before authenticating,
+	// and move to the endpoint the BMC redirects to
+	if endpoint, err = probeEndpoint(endpoint); err != nil {
 		return nil, err
 	}
 
+	var token string
+	var session *bmcSession
+	if anonymous {
+		log.Printf("[WARN] BMC authentication is disabled (auth = %q); requests to %s carry no credentials", authNone, endpoint)
+	} else {
+		if token, err = authenticate(endpoint, username, password); err != nil {
+			return nil, fmt.Errorf("authenticating with the BMC at %s: %w", endpoint, err)
+		}
+
+		// Renew the session in the background; the transport sends the latest token
+		session = newBMCSession(endpoint, username, password, token)
+		session.startKeepalive(reauthInterval)
+		transport.session = session
+	}
+	firmware, apiVersion := logBMCVersion(endpoint, token)
+	transport.postMutations = usePOSTMutations(d.Get("bmc_request_method").(string), apiVersion)
+
 	return &ProviderConfig{
-		Token:    token,
-		Endpoint: endpoint,
+		Token:           token,
+		Endpoint:        endpoint,
+		session:         session,
+		DryRun:          d.Get("dry_run").(bool),
+		DefaultMetadata: expandStringMap(d.Get("default_metadata").(map[string]interface{})),
+		fleet:           newFleetPool(fleetParallelism),
+		firmware: &bmcFirmware{
+			endpoint: endpoint,
+			token:    token,
+			restart:  transport.restart,
+			version:  firmware,
+			readAt:   time.Now(),
+		},
 	}, nil
 }
@@ -1,8 +1,13 @@
 package provider
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -56,12 +61,13 @@ func TestProvider_SchemaTypes(t *testing.T) {
 func TestProvider_RequiredFields(t *testing.T) {
 	p := Provider()
 
-	if !p.Schema["username"].Required {
-		t.Error("username should be required")
+	// Not required with auth = "none"; configureProvider checks them otherwise
+	if p.Schema["username"].Required {
+		t.Error("username should not be required")
 	}
 
-	if !p.Schema["password"].Required {
-		t.Error("password should be required")
+	if p.Schema["password"].Required {
+		t.Error("password should not be required")
 	}
 
 	if p.Schema["endpoint"].Required {
@@ -188,9 +194,67 @@ func TestProvider_HasResources(t *testing.T) {
 func TestProvider_HasConfigureFunc(t *testing.T) {
 	p := Provider()
 
-	//nolint:staticcheck // SA1019: intentionally testing deprecated ConfigureFunc field
-	if p.ConfigureFunc == nil {
-		t.Error("provider should have a ConfigureFunc")
+	if p.ConfigureContextFunc == nil {
+		t.Error("provider should have a ConfigureContextFunc")
+	}
+}
+
+func TestConfigureProvider_AuthNone(t *testing.T) {
+	var logins int
+	var authHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bmc/authenticate" {
+			logins++
+		}
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"response":[{"result":{"version":"2.1.0"}}]}`))
+	}))
+	defer server.Close()
+	oldClient := HTTPClient
+	t.Cleanup(func() { HTTPClient = oldClient })
+
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"endpoint": server.URL,
+		"auth":     "none",
+	})
+	meta, diags := configureProviderContext(context.Background(), d)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Summary, "authentication is disabled") {
+		t.Errorf("expected a warning, got %v", diags)
+	}
+	config := meta.(*ProviderConfig)
+	if config.Token != "" || config.session != nil {
+		t.Errorf("expected no token or session, got %+v", config)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/bmc?opt=get&type=power", nil)
+	req.Header.Set("Authorization", "Bearer "+config.Token)
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if logins != 0 {
+		t.Errorf("expected no login, got %d", logins)
+	}
+	for _, header := range authHeaders {
+		if header != "" {
+			t.Errorf("expected no Authorization header, got %q", header)
+		}
+	}
+}
+
+func TestConfigureProvider_PasswordRequired(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"endpoint": "https://10.10.88.70",
+		"username": "root",
+	})
+	_, diags := configureProviderContext(context.Background(), d)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "username and password are required") {
+		t.Errorf("expected a missing password error, got %v", diags)
 	}
 }
 
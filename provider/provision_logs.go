@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// provisionClusterLog is the log file of commands that target no single
+// node, e.g. talosctl gen config
+const provisionClusterLog = "cluster"
+
+// credentialOutputMarkers identify command output holding credentials, e.g.
+// a kubeconfig or the K3s node token read from a node, which is left out of
+// the logs
+var credentialOutputMarkers = []string{"client-key-data", "-----BEGIN", "::server:"}
+
+// k3sTokenEnv matches the join token passed to the K3s install script
+var k3sTokenEnv = regexp.MustCompile(`K3S_TOKEN=\S+`)
+
+// provisionLogs appends the commands a provisioner runs against each node,
+// and their output, to <dir>/<host>.log, so a failed cluster build leaves a
+// trail per node instead of one interleaved Terraform log. A nil
+// provisionLogs records nothing.
+type provisionLogs struct {
+	dir     string
+	secrets []string
+	mu      sync.Mutex
+}
+
+// newProvisionLogs creates dir and returns logs writing into it. secrets are
+// redacted from the logged commands and output.
+func newProvisionLogs(dir string, secrets ...string) (*provisionLogs, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create logs_dir %s: %w", dir, err)
+	}
+	l := &provisionLogs{dir: dir}
+	for _, s := range secrets {
+		if s != "" {
+			l.secrets = append(l.secrets, s)
+		}
+	}
+	return l, nil
+}
+
+// provisionLogsFor returns the provisioning logs of a resource with logs_dir
+// set, or nil
+func provisionLogsFor(d *schema.ResourceData, secrets ...string) (*provisionLogs, error) {
+	dir := d.Get("logs_dir").(string)
+	if dir == "" {
+		return nil, nil
+	}
+	return newProvisionLogs(dir, secrets...)
+}
+
+// redact adds secrets created after the logs were opened, such as a rotated
+// token, to the ones redacted from later records
+func (l *provisionLogs) redact(secrets ...string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range secrets {
+		if s != "" {
+			l.secrets = append(l.secrets, s)
+		}
+	}
+}
+
+// path returns the log file of host
+func (l *provisionLogs) path(host string) string {
+	if host == "" {
+		host = provisionClusterLog
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, host)
+	return filepath.Join(l.dir, name+".log")
+}
+
+// record appends a command run against host, its output and its error to
+// the log of host. Logging failures are ignored, they must not fail the build.
+func (l *provisionLogs) record(host, command, output string, err error) {
+	if l == nil {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s $ %s\n", time.Now().UTC().Format(time.RFC3339), k3sTokenEnv.ReplaceAllString(redactCommand(command, l.secrets), "K3S_TOKEN=<redacted>"))
+	if output = l.redactOutput(output); output != "" {
+		b.WriteString(output)
+		if !strings.HasSuffix(output, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "--- failed: %s\n", l.redactOutput(err.Error()))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, openErr := os.OpenFile(l.path(host), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if openErr != nil {
+		return
+	}
+	_, _ = file.WriteString(b.String())
+	_ = file.Close()
+}
+
+// redactOutput replaces the secrets in command output, and drops output
+// holding credentials entirely
+func (l *provisionLogs) redactOutput(output string) string {
+	for _, marker := range credentialOutputMarkers {
+		if strings.Contains(output, marker) {
+			return "<output redacted: contains credentials>"
+		}
+	}
+	for _, s := range l.secrets {
+		output = strings.ReplaceAll(output, s, "<redacted>")
+	}
+	return output
+}
+
+// setNodeLogs sets node_logs to the log file of each host, or clears it
+// without logs
+func setNodeLogs(d *schema.ResourceData, logs *provisionLogs, hosts []string) error {
+	paths := make(map[string]interface{}, len(hosts))
+	if logs != nil {
+		for _, host := range hosts {
+			paths[host] = logs.path(host)
+		}
+	}
+	return d.Set("node_logs", paths)
+}
+
+// clusterHosts returns the hosts of the control_plane and worker blocks
+func clusterHosts(d *schema.ResourceData) []string {
+	var hosts []string
+	for _, block := range []string{"control_plane", "worker"} {
+		for _, n := range d.Get(block).([]interface{}) {
+			if m, ok := n.(map[string]interface{}); ok && m["host"].(string) != "" {
+				hosts = append(hosts, m["host"].(string))
+			}
+		}
+	}
+	return hosts
+}
+
+// withProvisionLogs points at the node_logs files when fn fails, so the
+// per-node output is found even though the failed resource is not saved
+func withProvisionLogs(fn func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		diags := fn(ctx, d, meta)
+		if !diags.HasError() {
+			return diags
+		}
+
+		var files []string
+		for _, path := range d.Get("node_logs").(map[string]interface{}) {
+			if _, err := os.Stat(path.(string)); err == nil {
+				files = append(files, path.(string))
+			}
+		}
+		if len(files) == 0 {
+			return diags
+		}
+		sort.Strings(files)
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Per-node provisioning logs were written",
+			Detail:   fmt.Sprintf("The commands run on each node and their output are in:\n%s", strings.Join(files, "\n")),
+		})
+	}
+}
+
+// logsDirSchema is the logs_dir argument of the cluster resources
+func logsDirSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "Directory to write per-node provisioning logs to, one <host>.log file per node with every command run on it and its output. Secrets and credential output are redacted. Logs are appended across applies.",
+	}
+}
+
+// nodeLogsSchema is the node_logs attribute of the cluster resources
+func nodeLogsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Description: "Provisioning log file of each node, keyed by host. Empty unless logs_dir is set.",
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	}
+}
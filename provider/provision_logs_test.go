@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestProvisionLogs_Record(t *testing.T) {
+	logs, err := newProvisionLogs(filepath.Join(t.TempDir(), "logs"), "cluster-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs.record("10.10.88.73", "curl -sfL https://get.k3s.io | K3S_TOKEN=K10abc::server:def sh -", "[INFO] Installing k3s", nil)
+	logs.record("10.10.88.73", "echo 'c2VjcmV0LXJlZ2lzdHJ5LWNyZWRlbnRpYWxz' | base64 -d > /etc/rancher/k3s/registries.yaml", "", nil)
+	logs.record("10.10.88.73", "cat /etc/rancher/k3s/k3s.yaml", "users:\n- user:\n    client-key-data: abc\n", nil)
+	logs.record("10.10.88.73", "systemctl start k3s", "token cluster-secret rejected", errors.New("exit status 1"))
+
+	data, err := os.ReadFile(filepath.Join(logs.dir, "10.10.88.73.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+
+	for _, want := range []string{
+		"K3S_TOKEN=<redacted> sh -",
+		"[INFO] Installing k3s\n",
+		"echo '<redacted>' | base64 -d",
+		"<output redacted: contains credentials>",
+		"token <redacted> rejected",
+		"--- failed: exit status 1",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected log to contain %q, got:\n%s", want, log)
+		}
+	}
+	for _, leaked := range []string{"K10abc", "c2VjcmV0", "client-key-data", "cluster-secret"} {
+		if strings.Contains(log, leaked) {
+			t.Errorf("log leaks %q:\n%s", leaked, log)
+		}
+	}
+}
+
+func TestProvisionLogs_Path(t *testing.T) {
+	logs := &provisionLogs{dir: "/var/log/turingpi"}
+
+	tests := map[string]string{
+		"10.10.88.73":   "/var/log/turingpi/10.10.88.73.log",
+		"node1.local":   "/var/log/turingpi/node1.local.log",
+		"fd00::73":      "/var/log/turingpi/fd00__73.log",
+		"../etc/passwd": "/var/log/turingpi/.._etc_passwd.log",
+		"":              "/var/log/turingpi/cluster.log",
+	}
+	for host, want := range tests {
+		if got := logs.path(host); got != want {
+			t.Errorf("path(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestProvisionLogs_NilRecordsNothing(t *testing.T) {
+	var logs *provisionLogs
+	logs.record("10.10.88.73", "true", "", nil)
+}
+
+func TestTalosctlNode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"apply-config", "--nodes", "10.10.88.73", "--file", "cp.yaml"}, "10.10.88.73"},
+		{[]string{"-n", "10.10.88.74", "health"}, "10.10.88.74"},
+		{[]string{"services", "--nodes", "10.10.88.73,10.10.88.74"}, ""},
+		{[]string{"gen", "secrets", "-o", "secrets.yaml"}, ""},
+	}
+	for _, tt := range tests {
+		if got := talosctlNode(tt.args); got != tt.want {
+			t.Errorf("talosctlNode(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestK3sProvisioner_RecordsNodeLogs(t *testing.T) {
+	logs, err := newProvisionLogs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				return "active", nil
+			},
+		}
+	})
+	provisioner.Logs = logs
+
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+	if _, err := provisioner.runCommand(node, "systemctl is-active k3s-agent"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(logs.path(node.Host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "$ systemctl is-active k3s-agent\nactive\n") {
+		t.Errorf("unexpected log:\n%s", data)
+	}
+}
+
+func TestWithProvisionLogs_PointsAtLogsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name":     "test",
+		"logs_dir": dir,
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73",
+		}},
+		"worker": []interface{}{map[string]interface{}{
+			"host": "10.10.88.74",
+		}},
+	})
+
+	create := withProvisionLogs(func(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+		logs, err := provisionLogsFor(d)
+		if err != nil {
+			return diagFromErr(err)
+		}
+		if err := setNodeLogs(d, logs, clusterHosts(d)); err != nil {
+			return diagFromErr(err)
+		}
+		logs.record("10.10.88.73", "k3s kubectl get nodes", "", errors.New("exit status 1"))
+		return diag.Errorf("failed to install K3s server")
+	})
+
+	diags := create(context.Background(), d, nil)
+	if len(diags) != 2 || diags[1].Severity != diag.Warning {
+		t.Fatalf("expected the error and a logs warning, got %v", diags)
+	}
+	// Only the control plane ran a command, so only its log exists
+	if want := filepath.Join(dir, "10.10.88.73.log"); diags[1].Detail != "The commands run on each node and their output are in:\n"+want {
+		t.Errorf("unexpected detail: %s", diags[1].Detail)
+	}
+
+	nodeLogs := d.Get("node_logs").(map[string]interface{})
+	if nodeLogs["10.10.88.74"] != filepath.Join(dir, "10.10.88.74.log") {
+		t.Errorf("expected a log path for the worker, got %v", nodeLogs)
+	}
+}
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
+
+// k3sChannelsURL is the K3s release channel server
+// Replaced in tests to point at a local server
+var k3sChannelsURL = "https://update.k3s.io/v1-release/channels"
+
+// talosReleasesURL lists Talos releases on GitHub
+// Replaced in tests to point at a local server
+var talosReleasesURL = "https://api.github.com/repos/siderolabs/talos/releases?per_page=100"
+
+// bmcFirmwareReleasesURL lists Turing Pi BMC firmware releases on GitHub
+// Replaced in tests to point at a local server
+var bmcFirmwareReleasesURL = "https://api.github.com/repos/turing-machines/BMC-Firmware/releases?per_page=100"
+
+// releaseHTTPClient queries public release endpoints. It is separate from
+// HTTPClient so the BMC TLS settings do not apply to internet requests.
+var releaseHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchReleaseJSON GETs a release endpoint and decodes the JSON body into out
+func fetchReleaseJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := releaseHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// k3sChannel is one entry of the K3s channel server response
+type k3sChannel struct {
+	ID     string `json:"id"`
+	Latest string `json:"latest"`
+}
+
+// resolveK3sChannel returns the current version of a K3s release channel
+// (e.g., stable, latest, v1.30)
+func resolveK3sChannel(ctx context.Context, channel string) (string, error) {
+	var resp struct {
+		Data []k3sChannel `json:"data"`
+	}
+	if err := fetchReleaseJSON(ctx, k3sChannelsURL, &resp); err != nil {
+		return "", err
+	}
+
+	ids := make([]string, 0, len(resp.Data))
+	for _, c := range resp.Data {
+		if c.ID == channel {
+			if c.Latest == "" {
+				return "", fmt.Errorf("K3s channel %q has no release", channel)
+			}
+			return c.Latest, nil
+		}
+		ids = append(ids, c.ID)
+	}
+	return "", fmt.Errorf("unknown K3s channel %q (available: %s)", channel, strings.Join(ids, ", "))
+}
+
+// githubRelease is one entry of the GitHub releases response
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// resolveTalosChannel returns the newest Talos release of a channel: stable
+// (newest non-prerelease), latest (including prereleases), or a minor version
+// such as v1.7 (newest non-prerelease patch of that minor)
+func resolveTalosChannel(ctx context.Context, channel string) (string, error) {
+	var releases []githubRelease
+	if err := fetchReleaseJSON(ctx, talosReleasesURL, &releases); err != nil {
+		return "", err
+	}
+	return selectTalosRelease(releases, channel)
+}
+
+// selectTalosRelease picks the newest release matching channel
+func selectTalosRelease(releases []githubRelease, channel string) (string, error) {
+	var minor *version.Version
+	switch channel {
+	case "stable", "latest":
+	default:
+		v, err := version.NewVersion(channel)
+		if err != nil || len(strings.Split(strings.TrimPrefix(channel, "v"), ".")) != 2 {
+			return "", fmt.Errorf("invalid Talos channel %q, expected stable, latest or a minor version such as v1.7", channel)
+		}
+		minor = v
+	}
+
+	var best *version.Version
+	bestTag := ""
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && channel != "latest") {
+			continue
+		}
+		v, err := version.NewVersion(r.TagName)
+		if err != nil {
+			continue
+		}
+		if minor != nil {
+			segments, want := v.Segments(), minor.Segments()
+			if segments[0] != want[0] || segments[1] != want[1] {
+				continue
+			}
+		}
+		if best == nil || v.GreaterThan(best) {
+			best, bestTag = v, r.TagName
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("no Talos release found for channel %q", channel)
+	}
+	return bestTag, nil
+}
+
+// resolveLatestBMCFirmware returns the newest non-prerelease BMC firmware
+// release (e.g., v2.1.0)
+func resolveLatestBMCFirmware(ctx context.Context) (string, error) {
+	var releases []githubRelease
+	if err := fetchReleaseJSON(ctx, bmcFirmwareReleasesURL, &releases); err != nil {
+		return "", err
+	}
+
+	var best *version.Version
+	bestTag := ""
+	for _, r := range releases {
+		if r.Draft || r.Prerelease {
+			continue
+		}
+		v, err := version.NewVersion(r.TagName)
+		if err != nil {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best, bestTag = v, r.TagName
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no BMC firmware release found")
+	}
+	return bestTag, nil
+}
+
+// firmwareUpdateAvailable reports whether latest is newer than the current
+// firmware version. Versions that cannot be parsed never report an update.
+func firmwareUpdateAvailable(current, latest string) bool {
+	c, err := version.NewVersion(strings.TrimSpace(current))
+	if err != nil {
+		return false
+	}
+	l, err := version.NewVersion(latest)
+	if err != nil {
+		return false
+	}
+	return l.GreaterThan(c)
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// bmcFirmware caches the firmware version the BMC reports for
+// required_firmware. The version is read again after a restart sent by this
+// provider, such as a firmware upgrade, and when it could not be read before.
+type bmcFirmware struct {
+	endpoint string
+	token    string
+	restart  *bmcRestart
+
+	mu      sync.Mutex
+	version string
+	readAt  time.Time
+}
+
+// Version returns the current firmware version, or "" when the BMC does not
+// report it
+func (f *bmcFirmware) Version() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.version != "" && (f.restart == nil || !f.restart.since(f.readAt)) {
+		return f.version
+	}
+	about, err := fetchBMCAbout(f.endpoint, f.token)
+	if err != nil {
+		log.Printf("[WARN] Could not read the BMC firmware version from %s: %v", f.endpoint, err)
+		return f.version
+	}
+	f.version = extractFirmwareVersion(about)
+	f.readAt = time.Now()
+	return f.version
+}
+
+// FirmwareVersion returns the firmware version of the BMC, or "" when it is
+// unknown
+func (c *ProviderConfig) FirmwareVersion() string {
+	if c.firmware == nil {
+		return ""
+	}
+	return c.firmware.Version()
+}
+
+// addRequiredFirmware adds the required_firmware argument to every resource,
+// checked at plan time before any other CustomizeDiff
+func addRequiredFirmware(resources map[string]*schema.Resource) {
+	for _, r := range resources {
+		r.Schema["required_firmware"] = &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validateFirmwareConstraint,
+			Description: "Version constraint the BMC firmware must satisfy (e.g., \">= 2.0.5\"). " +
+				"Plans fail before any change is made when the BMC runs other firmware.",
+		}
+		next := r.CustomizeDiff
+		r.CustomizeDiff = func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+			if err := checkRequiredFirmware(d, meta); err != nil {
+				return err
+			}
+			if next == nil {
+				return nil
+			}
+			return next(ctx, d, meta)
+		}
+	}
+}
+
+func validateFirmwareConstraint(v interface{}, k string) ([]string, []error) {
+	if _, err := version.NewConstraint(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: invalid version constraint %q: %w", k, v, err)}
+	}
+	return nil, nil
+}
+
+// checkRequiredFirmware fails when the BMC firmware does not satisfy
+// required_firmware. Resources without it, and plans without a configured
+// provider, are not checked.
+func checkRequiredFirmware(d *schema.ResourceDiff, meta interface{}) error {
+	required := d.Get("required_firmware").(string)
+	config, ok := meta.(*ProviderConfig)
+	if required == "" || !ok || config == nil {
+		return nil
+	}
+	return firmwareSatisfies(config.FirmwareVersion(), required)
+}
+
+// firmwareSatisfies checks a reported firmware version against a
+// required_firmware constraint. Pre-release and build suffixes are ignored,
+// so 2.1.0-rc1 satisfies ">= 2.1.0".
+func firmwareSatisfies(current, required string) error {
+	constraint, err := version.NewConstraint(required)
+	if err != nil {
+		return fmt.Errorf("invalid required_firmware %q: %w", required, err)
+	}
+	if current == "" {
+		return fmt.Errorf("required_firmware %q cannot be checked: the BMC did not report its firmware version", required)
+	}
+	v, err := version.NewVersion(current)
+	if err != nil {
+		return fmt.Errorf("required_firmware %q cannot be checked: the BMC reports firmware %q, which is not a version", required, current)
+	}
+	if !constraint.Check(v.Core()) {
+		return fmt.Errorf("the BMC runs firmware %s, which does not satisfy required_firmware %q. "+
+			"Upgrade the BMC with turingpi_bmc_firmware before applying this resource", current, required)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestFirmwareSatisfies(t *testing.T) {
+	tests := []struct {
+		current  string
+		required string
+		wantErr  string
+	}{
+		{"2.0.5", ">= 2.0.5", ""},
+		{"v2.3.1", ">= 2.0.5, < 2.4.0", ""},
+		{"2.1.0-rc1", ">= 2.1.0", ""},
+		{"2.0.4", ">= 2.0.5", "does not satisfy"},
+		{"", ">= 2.0.5", "did not report"},
+		{"unknown", ">= 2.0.5", "not a version"},
+	}
+	for _, tt := range tests {
+		err := firmwareSatisfies(tt.current, tt.required)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s %s: unexpected error: %v", tt.current, tt.required, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s %s: expected %q, got %v", tt.current, tt.required, tt.wantErr, err)
+		}
+	}
+}
+
+func TestRequiredFirmware_Plan(t *testing.T) {
+	r := Provider().ResourcesMap["turingpi_power"]
+	if err := r.InternalValidate(nil, true); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	meta := &ProviderConfig{firmware: &bmcFirmware{version: "2.0.4", readAt: time.Now()}}
+	config := map[string]interface{}{"node": 1, "state": "on", "required_firmware": ">= 2.0.5"}
+
+	_, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta)
+	if err == nil || !strings.Contains(err.Error(), "firmware 2.0.4") {
+		t.Errorf("expected the plan to fail on old firmware, got %v", err)
+	}
+
+	meta.firmware.version = "2.1.0"
+	if _, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	config["required_firmware"] = "at least 2"
+	if diags := r.Validate(terraform.NewResourceConfigRaw(config)); !diags.HasError() {
+		t.Error("expected an invalid constraint to be rejected")
+	}
+}
+
+func TestBMCFirmware_ReadAgainAfterRestart(t *testing.T) {
+	restart := &bmcRestart{}
+	f := &bmcFirmware{version: "2.0.4", readAt: time.Now(), restart: restart}
+	if got := f.Version(); got != "2.0.4" {
+		t.Errorf("expected the cached version, got %q", got)
+	}
+	if restart.since(f.readAt) {
+		t.Error("expected no restart since the version was read")
+	}
+	restart.mark()
+	if !restart.since(f.readAt) {
+		t.Error("expected the restart to invalidate the cached version")
+	}
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// factoryResetConfirmation must be the value of confirm before a factory
+// reset is sent
+const factoryResetConfirmation = "RESET"
+
+// Replaced in tests to keep them fast
+var (
+	factoryResetStartDelay   = 5 * time.Second
+	factoryResetPollInterval = 5 * time.Second
+)
+
+func resourceBMCFactoryReset() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Resets the Turing Pi BMC to its factory settings, for rebuilding corrupted BMC state. The reset is sent when this resource is created or when the triggers change. Requires confirm = \"RESET\".",
+		CreateContext: resourceBMCFactoryResetCreate,
+		ReadContext:   resourceBMCFactoryResetRead,
+		UpdateContext: resourceBMCFactoryResetUpdate,
+		DeleteContext: resourceBMCFactoryResetDelete,
+		Schema: map[string]*schema.Schema{
+			"confirm": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Must be set to \"RESET\" to acknowledge that the BMC settings, including the web UI and SSH passwords, are erased.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{factoryResetConfirmation}, false)),
+			},
+			"retain_network": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Keep the BMC network configuration, so the BMC stays reachable at the same address (default: true).",
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "A map of values that, when changed, will trigger another factory reset.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"wait_for_ready": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Wait for the BMC to answer HTTP requests again after the reset (default: true).",
+			},
+			"ready_timeout": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     300,
+				Description: "Timeout in seconds to wait for the BMC after the reset (default: 300).",
+			},
+			// Computed attributes
+			"last_reset": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Timestamp of the last factory reset.",
+			},
+		},
+	}
+}
+
+func resourceBMCFactoryResetCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	if err := performFactoryReset(ctx, config, d); err != nil {
+		return diagFromErr(err)
+	}
+
+	d.SetId("bmc-factory-reset")
+	if err := d.Set("last_reset", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set last_reset: %w", err))
+	}
+
+	return nil
+}
+
+func resourceBMCFactoryResetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Factory reset is a trigger resource - nothing to read back
+	return nil
+}
+
+func resourceBMCFactoryResetUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	// Reset again only if triggers changed
+	if d.HasChange("triggers") {
+		if err := performFactoryReset(ctx, config, d); err != nil {
+			return diagFromErr(err)
+		}
+
+		if err := d.Set("last_reset", time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return diagFromErr(fmt.Errorf("failed to set last_reset: %w", err))
+		}
+	}
+
+	return nil
+}
+
+func resourceBMCFactoryResetDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Nothing to clean up for a trigger resource
+	d.SetId("")
+	return nil
+}
+
+// performFactoryReset sends the factory reset and waits for the BMC if configured
+func performFactoryReset(ctx context.Context, config *ProviderConfig, d *schema.ResourceData) error {
+	// Checked again here, since the schema validation is skipped for unknown values
+	if d.Get("confirm").(string) != factoryResetConfirmation {
+		return fmt.Errorf("confirm must be %q to reset the BMC", factoryResetConfirmation)
+	}
+
+	if err := factoryResetBMC(config.Endpoint, config.Token, d.Get("retain_network").(bool)); err != nil {
+		return fmt.Errorf("failed to factory reset BMC: %w", err)
+	}
+
+	if d.Get("wait_for_ready").(bool) {
+		if err := waitForBMCAnswering(ctx, config.Endpoint, d.Get("ready_timeout").(int)); err != nil {
+			return fmt.Errorf("BMC did not come back after factory reset: %w", err)
+		}
+	}
+	return nil
+}
+
+// factoryResetBMC triggers the BMC factory reset
+func factoryResetBMC(endpoint, token string, retainNetwork bool) error {
+	retain := 0
+	if retainNetwork {
+		retain = 1
+	}
+	url := fmt.Sprintf("%s/api/bmc?opt=set&type=factory_reset&retain_network=%d", endpoint, retain)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusNotFound:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s (the BMC firmware may not support factory reset; nothing was changed)", resp.StatusCode, string(body))
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// waitForBMCAnswering waits for the BMC API to answer again. The reset
+// restores the default credentials, so any response counts, including 401.
+func waitForBMCAnswering(ctx context.Context, endpoint string, timeoutSeconds int) error {
+	// The BMC answers until the reset takes it down, and nothing reports
+	// that, so wait a fixed delay before polling
+	if err := sleepContext(ctx, factoryResetStartDelay); err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: HTTPClient.Transport,
+	}
+
+	err := poll(ctx, factoryResetPollInterval, time.Duration(timeoutSeconds)*time.Second, "BMC", func(context.Context) (bool, error) {
+		resp, err := client.Get(fmt.Sprintf("%s/api/bmc?opt=get&type=about", endpoint))
+		if err != nil {
+			return false, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode < http.StatusInternalServerError, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for BMC: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestResourceBMCFactoryResetSchema(t *testing.T) {
+	resource := resourceBMCFactoryReset()
+
+	if resource.Schema["confirm"] == nil || !resource.Schema["confirm"].Required {
+		t.Error("confirm should be required")
+	}
+	if resource.Schema["retain_network"].Default != true {
+		t.Error("retain_network should default to true")
+	}
+	if resource.Schema["ready_timeout"].Default != 300 {
+		t.Error("ready_timeout should default to 300")
+	}
+	if !resource.Schema["last_reset"].Computed {
+		t.Error("last_reset should be computed")
+	}
+}
+
+func TestResourceBMCFactoryReset_ConfirmValidation(t *testing.T) {
+	resource := resourceBMCFactoryReset()
+
+	for _, value := range []string{"reset", "yes", ""} {
+		raw := map[string]interface{}{"confirm": value}
+		diags := resource.Validate(terraform.NewResourceConfigRaw(raw))
+		if !diags.HasError() {
+			t.Errorf("expected confirm = %q to be rejected", value)
+		}
+	}
+
+	raw := map[string]interface{}{"confirm": "RESET"}
+	if diags := resource.Validate(terraform.NewResourceConfigRaw(raw)); diags.HasError() {
+		t.Errorf("expected confirm = \"RESET\" to be accepted, got %v", diags)
+	}
+}
+
+func TestFactoryResetBMC(t *testing.T) {
+	tests := []struct {
+		name           string
+		retainNetwork  bool
+		serverResponse int
+		wantQuery      string
+		wantErr        string
+	}{
+		{
+			name:           "retain network",
+			retainNetwork:  true,
+			serverResponse: http.StatusOK,
+			wantQuery:      "retain_network=1",
+		},
+		{
+			name:           "full reset",
+			retainNetwork:  false,
+			serverResponse: http.StatusOK,
+			wantQuery:      "retain_network=0",
+		},
+		{
+			name:           "firmware without factory reset",
+			retainNetwork:  true,
+			serverResponse: http.StatusBadRequest,
+			wantQuery:      "retain_network=1",
+			wantErr:        "may not support factory reset",
+		},
+		{
+			name:           "server error",
+			retainNetwork:  true,
+			serverResponse: http.StatusInternalServerError,
+			wantQuery:      "retain_network=1",
+			wantErr:        "API returned status 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.URL.String(), "opt=set") || !strings.Contains(r.URL.String(), "type=factory_reset") {
+					t.Errorf("unexpected URL: %s", r.URL.String())
+				}
+				if !strings.Contains(r.URL.RawQuery, tt.wantQuery) {
+					t.Errorf("expected %s in URL: %s", tt.wantQuery, r.URL.String())
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer test-token" {
+					t.Errorf("expected Bearer test-token, got %s", auth)
+				}
+				w.WriteHeader(tt.serverResponse)
+			}))
+			defer server.Close()
+
+			originalClient := HTTPClient
+			HTTPClient = server.Client()
+			defer func() { HTTPClient = originalClient }()
+
+			err := factoryResetBMC(server.URL, "test-token", tt.retainNetwork)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestResourceBMCFactoryResetCRUD(t *testing.T) {
+	originalDelay, originalInterval := factoryResetStartDelay, factoryResetPollInterval
+	factoryResetStartDelay, factoryResetPollInterval = 0, 10*time.Millisecond
+	defer func() { factoryResetStartDelay, factoryResetPollInterval = originalDelay, originalInterval }()
+
+	resetCalled := 0
+	aboutCalled := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.String(), "type=factory_reset"):
+			resetCalled++
+			w.WriteHeader(http.StatusOK)
+		case strings.Contains(r.URL.String(), "type=about"):
+			aboutCalled++
+			// The reset restored the default credentials, so the old token is rejected
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	originalClient := HTTPClient
+	HTTPClient = server.Client()
+	defer func() { HTTPClient = originalClient }()
+
+	config := &ProviderConfig{
+		Endpoint: server.URL,
+		Token:    "test-token",
+	}
+
+	resource := resourceBMCFactoryReset()
+	d := resource.TestResourceData()
+	if err := d.Set("confirm", "RESET"); err != nil {
+		t.Fatalf("failed to set confirm: %v", err)
+	}
+	if err := d.Set("wait_for_ready", true); err != nil {
+		t.Fatalf("failed to set wait_for_ready: %v", err)
+	}
+	if err := d.Set("ready_timeout", 5); err != nil {
+		t.Fatalf("failed to set ready_timeout: %v", err)
+	}
+
+	diags := resourceBMCFactoryResetCreate(context.TODO(), d, config)
+	if diags.HasError() {
+		t.Fatalf("Create returned error: %v", diags)
+	}
+	if d.Id() != "bmc-factory-reset" {
+		t.Errorf("expected ID 'bmc-factory-reset', got '%s'", d.Id())
+	}
+	if resetCalled != 1 {
+		t.Errorf("expected factory reset to be called once, got %d", resetCalled)
+	}
+	if aboutCalled == 0 {
+		t.Error("expected about endpoint to be polled after the reset")
+	}
+	if d.Get("last_reset").(string) == "" {
+		t.Error("expected last_reset to be set")
+	}
+
+	// Update without trigger changes does not reset again
+	diags = resourceBMCFactoryResetUpdate(context.TODO(), d, config)
+	if diags.HasError() {
+		t.Errorf("Update returned error: %v", diags)
+	}
+	if resetCalled != 1 {
+		t.Errorf("expected factory reset count to remain 1, got %d", resetCalled)
+	}
+
+	diags = resourceBMCFactoryResetDelete(context.TODO(), d, config)
+	if diags.HasError() {
+		t.Errorf("Delete returned error: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected empty ID after delete, got '%s'", d.Id())
+	}
+}
+
+func TestResourceBMCFactoryResetCreate_RequiresConfirm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("no request expected, got %s", r.URL.String())
+	}))
+	defer server.Close()
+
+	config := &ProviderConfig{
+		Endpoint: server.URL,
+		Token:    "test-token",
+	}
+
+	d := resourceBMCFactoryReset().TestResourceData()
+	if err := d.Set("confirm", "yes"); err != nil {
+		t.Fatalf("failed to set confirm: %v", err)
+	}
+
+	diags := resourceBMCFactoryResetCreate(context.TODO(), d, config)
+	if !diags.HasError() {
+		t.Fatal("expected Create to fail without confirm = \"RESET\"")
+	}
+	if d.Id() != "" {
+		t.Errorf("expected no ID, got '%s'", d.Id())
+	}
+}
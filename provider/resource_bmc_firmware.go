@@ -3,17 +3,21 @@ package provider
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 )
 
 // BMC firmware response structures
@@ -32,6 +36,7 @@ func resourceBMCFirmware() *schema.Resource {
 		ReadContext:   resourceBMCFirmwareRead,
 		UpdateContext: resourceBMCFirmwareUpdate,
 		DeleteContext: resourceBMCFirmwareDelete,
+		CustomizeDiff: customizeDiffDefaultTriggers,
 		Schema: map[string]*schema.Schema{
 			"firmware_file": {
 				Type:        schema.TypeString,
@@ -47,7 +52,8 @@ func resourceBMCFirmware() *schema.Resource {
 			"triggers": {
 				Type:        schema.TypeMap,
 				Optional:    true,
-				Description: "A map of values that, when changed, will trigger a firmware upgrade. Use this to force an upgrade based on version changes.",
+				Computed:    true,
+				Description: "A map of values that, when changed, will trigger a firmware upgrade. Use this to force an upgrade based on version changes. The provider default_metadata is merged in.",
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -58,6 +64,52 @@ func resourceBMCFirmware() *schema.Resource {
 				Default:     300,
 				Description: "Timeout in seconds for the firmware upgrade operation (default: 300).",
 			},
+			"target_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Firmware version that firmware_file installs (e.g., 2.0.5). When the BMC already reports this version, create and update skip the upgrade, so the resource can stay in config as a version pin. If the BMC later reports another version, the next apply upgrades again.",
+			},
+			"board_model": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Board model that firmware_file is built for: \"Turing Pi 2\" or \"Turing Pi 2.5\". The upgrade is refused before anything is uploaded when the BMC " +
+					"reports a board revision of another model. BMC firmware that does not report its board revision is upgraded without the check.",
+				ValidateFunc: validation.StringInSlice([]string{"Turing Pi 2", "Turing Pi 2.5"}, false),
+			},
+			"force_cancel_existing": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Cancel a transfer left on the BMC by an interrupted flash or firmware upload before upgrading (default: false). Without it, a stale transfer blocks the upgrade until the BMC is rebooted.",
+			},
+			"allowed_hours": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Time of day the upgrade may start, as HH:MM-HH:MM in window_timezone (e.g. 01:00-05:00). A range that ends before it starts runs past midnight. Unset allows any time.",
+				ValidateFunc: validation.StringMatch(windowHoursPattern, "must be HH:MM-HH:MM, e.g. 01:00-05:00"),
+			},
+			"allowed_days": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Days the upgrade may start on (mon, tue, wed, thu, fri, sat, sun). For an allowed_hours range past midnight, the day the range starts. Unset allows every day.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice(windowDayNames, false),
+				},
+			},
+			"window_timezone": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "UTC",
+				Description:  "IANA time zone of allowed_hours and allowed_days (e.g. Europe/Berlin), or Local for the time zone of the Terraform host (default: UTC).",
+				ValidateFunc: validateWindowTimezone,
+			},
+			"wait_for_window": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Wait for the maintenance window to open instead of failing when the apply runs outside it (default: false).",
+			},
 			// Computed attributes
 			"last_upgrade": {
 				Type:        schema.TypeString,
@@ -69,6 +121,11 @@ func resourceBMCFirmware() *schema.Resource {
 				Computed:    true,
 				Description: "The firmware version before the upgrade.",
 			},
+			"current_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The firmware version currently reported by the BMC.",
+			},
 		},
 	}
 }
@@ -79,30 +136,82 @@ func resourceBMCFirmwareCreate(ctx context.Context, d *schema.ResourceData, meta
 	// Get current firmware version before upgrade
 	aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to get current firmware version: %w", err))
+		return diagFromErr(fmt.Errorf("failed to get current firmware version: %w", err))
 	}
 
 	previousVersion := extractFirmwareVersion(aboutData)
 	if err := d.Set("previous_version", previousVersion); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set previous_version: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set previous_version: %w", err))
+	}
+
+	d.SetId("bmc-firmware")
+
+	// Already at the pinned version - nothing to flash
+	if targetVersion := d.Get("target_version").(string); targetVersion != "" && firmwareVersionMatches(previousVersion, targetVersion) {
+		tflog.Info(ctx, "BMC firmware already at target version, skipping upgrade", map[string]interface{}{
+			"version": previousVersion,
+		})
+		if err := d.Set("current_version", previousVersion); err != nil {
+			return diagFromErr(fmt.Errorf("failed to set current_version: %w", err))
+		}
+		return nil
+	}
+
+	if err := checkFirmwareBoard(ctx, d, aboutData); err != nil {
+		d.SetId("")
+		return diagFromErr(err)
+	}
+	if err := enforceMaintenanceWindow(ctx, d); err != nil {
+		d.SetId("")
+		return diagFromErr(err)
 	}
 
 	// Perform the firmware upgrade
-	if err := performFirmwareUpgrade(config, d); err != nil {
-		return diag.FromErr(err)
+	if err := performFirmwareUpgrade(ctx, config, d); err != nil {
+		d.SetId("")
+		return diagFromErr(err)
 	}
 
-	d.SetId("bmc-firmware")
 	if err := d.Set("last_upgrade", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_upgrade: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_upgrade: %w", err))
 	}
-
-	return nil
+	diags := setUpgradedFirmwareVersion(ctx, config, d, previousVersion)
+	if diags.HasError() {
+		// The pinned version is not installed, so the next apply upgrades again
+		d.SetId("")
+	}
+	return diags
 }
 
 func resourceBMCFirmwareRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	// Firmware upgrade is a trigger resource - nothing to read back
-	// The firmware version can be read via turingpi_info data source
+	config := meta.(*ProviderConfig)
+
+	// Without target_version this is a trigger resource; the version is only
+	// recorded. The BMC may be rebooting after an upgrade, so failures are not fatal.
+	aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
+	if err != nil {
+		tflog.Warn(ctx, "Could not read BMC firmware version", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil
+	}
+
+	currentVersion := extractFirmwareVersion(aboutData)
+	if err := d.Set("current_version", currentVersion); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set current_version: %w", err))
+	}
+
+	// A pinned version that no longer matches (e.g., a manual downgrade) is
+	// drift: drop the resource from state so the next apply upgrades again
+	if targetVersion := d.Get("target_version").(string); targetVersion != "" && currentVersion != "" &&
+		!firmwareVersionMatches(currentVersion, targetVersion) {
+		tflog.Warn(ctx, "BMC firmware version differs from target_version, upgrade will be planned", map[string]interface{}{
+			"current_version": currentVersion,
+			"target_version":  targetVersion,
+		})
+		d.SetId("")
+	}
+
 	return nil
 }
 
@@ -110,42 +219,152 @@ func resourceBMCFirmwareUpdate(ctx context.Context, d *schema.ResourceData, meta
 	config := meta.(*ProviderConfig)
 
 	// Check if we should trigger an upgrade
-	if d.HasChange("firmware_file") || d.HasChange("triggers") || d.HasChange("bmc_local") {
+	if d.HasChange("firmware_file") || triggersChanged(d) || d.HasChange("bmc_local") || d.HasChange("target_version") {
 		// Get current firmware version before upgrade
 		aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
 		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to get current firmware version: %w", err))
+			return diagFromErr(fmt.Errorf("failed to get current firmware version: %w", err))
 		}
 
 		previousVersion := extractFirmwareVersion(aboutData)
+		if err := d.Set("current_version", previousVersion); err != nil {
+			return diagFromErr(fmt.Errorf("failed to set current_version: %w", err))
+		}
+
+		// Already at the pinned version - nothing to flash
+		if targetVersion := d.Get("target_version").(string); targetVersion != "" && firmwareVersionMatches(previousVersion, targetVersion) {
+			tflog.Info(ctx, "BMC firmware already at target version, skipping upgrade", map[string]interface{}{
+				"version": previousVersion,
+			})
+			return nil
+		}
+
 		if err := d.Set("previous_version", previousVersion); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set previous_version: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set previous_version: %w", err))
+		}
+
+		if err := checkFirmwareBoard(ctx, d, aboutData); err != nil {
+			d.Partial(true)
+			return diagFromErr(err)
+		}
+		if err := enforceMaintenanceWindow(ctx, d); err != nil {
+			d.Partial(true)
+			return diagFromErr(err)
 		}
 
 		// Perform the firmware upgrade
-		if err := performFirmwareUpgrade(config, d); err != nil {
-			return diag.FromErr(err)
+		if err := performFirmwareUpgrade(ctx, config, d); err != nil {
+			return diagFromErr(err)
 		}
 
 		if err := d.Set("last_upgrade", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_upgrade: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_upgrade: %w", err))
 		}
+		return setUpgradedFirmwareVersion(ctx, config, d, previousVersion)
 	}
 
 	return nil
 }
 
+// checkFirmwareBoard fails when board_model is set and the board revision in
+// the about data belongs to another model. The check is skipped when the BMC
+// does not report a revision, as older firmware releases do not.
+func checkFirmwareBoard(ctx context.Context, d *schema.ResourceData, aboutData *bmcAboutResponse) error {
+	model := d.Get("board_model").(string)
+	if model == "" {
+		return nil
+	}
+	revision, soc := parseBoardInfo(parseAboutResponse(aboutData))
+	if revision == "" {
+		tflog.Warn(ctx, "BMC does not report its board revision, board_model is not checked", map[string]interface{}{
+			"board_model": model,
+		})
+		return nil
+	}
+	caps := &boardCapabilities{Revision: revision, SoC: soc}
+	if caps.Model() != model {
+		board := fmt.Sprintf("board revision %s", revision)
+		if soc != "" {
+			board += fmt.Sprintf(" with BMC SoC %s", soc)
+		}
+		return fmt.Errorf("firmware_file is built for a %s, but the BMC reports %s, a %s; use the firmware for this board or correct board_model", model, board, caps.Model())
+	}
+	return nil
+}
+
+// firmwareVersionWait is how long the BMC has to report the new version after
+// an upgrade, which includes its reboot. Replaced in tests.
+var firmwareVersionWait = 3 * time.Minute
+
+// setUpgradedFirmwareVersion reads the firmware version back from the BMC
+// after an upgrade and sets current_version. It waits for target_version, or
+// without one for a version other than previousVersion. A BMC that reports
+// another version than target_version fails the apply; one that keeps
+// reporting previousVersion, or cannot be read, is a warning.
+func setUpgradedFirmwareVersion(ctx context.Context, config *ProviderConfig, d *schema.ResourceData, previousVersion string) diag.Diagnostics {
+	targetVersion := d.Get("target_version").(string)
+	var version string
+	err := poll(ctx, firmwareProgressInterval, firmwareVersionWait, "BMC firmware version", func(context.Context) (bool, error) {
+		aboutData, err := fetchBMCAbout(config.Endpoint, config.Token)
+		if err != nil {
+			// The BMC reboots to apply the firmware
+			return false, err
+		}
+		version = extractFirmwareVersion(aboutData)
+		if targetVersion != "" {
+			return firmwareVersionMatches(version, targetVersion), nil
+		}
+		return version != "" && version != previousVersion, nil
+	})
+	if err := d.Set("current_version", version); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set current_version: %w", err))
+	}
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return diagFromErr(err)
+	}
+
+	switch {
+	case version == "":
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "BMC firmware version not confirmed",
+			Detail:   fmt.Sprintf("The BMC did not report its firmware version within %s of the upgrade: %v", firmwareVersionWait, err),
+		}}
+	case targetVersion != "":
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "BMC firmware version does not match target_version",
+			Detail:   fmt.Sprintf("After the upgrade the BMC reports firmware %s, expected %s. Check that firmware_file installs target_version.", version, targetVersion),
+		}}
+	default:
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "BMC firmware version unchanged",
+			Detail:   fmt.Sprintf("After the upgrade the BMC still reports firmware %s. The image may be the installed version, or the upgrade may not have been applied.", version),
+		}}
+	}
+}
+
 func resourceBMCFirmwareDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	// Nothing to clean up for firmware - it's already flashed
 	d.SetId("")
 	return nil
 }
 
-func performFirmwareUpgrade(config *ProviderConfig, d *schema.ResourceData) error {
+func performFirmwareUpgrade(ctx context.Context, config *ProviderConfig, d *schema.ResourceData) error {
 	firmwareFile := d.Get("firmware_file").(string)
 	bmcLocal := d.Get("bmc_local").(bool)
 	timeout := d.Get("timeout").(int)
 
+	if d.Get("force_cancel_existing").(bool) {
+		if err := cancelStaleTransfer(ctx, config.Endpoint, config.Token); err != nil {
+			return err
+		}
+	}
+
 	var handle string
 	var err error
 
@@ -154,7 +373,11 @@ func performFirmwareUpgrade(config *ProviderConfig, d *schema.ResourceData) erro
 		handle, err = initBMCLocalFirmwareUpgrade(config.Endpoint, config.Token, firmwareFile)
 	} else {
 		// File needs to be uploaded from Terraform host
-		handle, err = uploadAndInitFirmwareUpgrade(config.Endpoint, config.Token, firmwareFile)
+		err = config.fleet.run(ctx, "BMC firmware upload", config.Endpoint, func() error {
+			var uploadErr error
+			handle, uploadErr = uploadAndInitFirmwareUpgrade(ctx, config.Endpoint, config.Token, firmwareFile)
+			return uploadErr
+		})
 	}
 
 	if err != nil {
@@ -162,7 +385,7 @@ func performFirmwareUpgrade(config *ProviderConfig, d *schema.ResourceData) erro
 	}
 
 	// Poll for completion
-	if err := waitForFirmwareUpgrade(config.Endpoint, config.Token, handle, timeout); err != nil {
+	if err := waitForFirmwareUpgrade(ctx, config.Endpoint, config.Token, handle, timeout); err != nil {
 		return fmt.Errorf("firmware upgrade failed: %w", err)
 	}
 
@@ -192,8 +415,8 @@ func initBMCLocalFirmwareUpgrade(endpoint, token, filePath string) (string, erro
 	}
 
 	var result firmwareInitResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return "", err
 	}
 
 	// Extract handle from response
@@ -205,8 +428,10 @@ func initBMCLocalFirmwareUpgrade(endpoint, token, filePath string) (string, erro
 	return handle, nil
 }
 
-// uploadAndInitFirmwareUpgrade uploads a firmware file and initiates the upgrade
-func uploadAndInitFirmwareUpgrade(endpoint, token, filePath string) (string, error) {
+// uploadAndInitFirmwareUpgrade uploads a firmware file and initiates the
+// upgrade. The upload handle is released if the upload fails or ctx is
+// cancelled, so it does not block the next upload.
+func uploadAndInitFirmwareUpgrade(ctx context.Context, endpoint, token, filePath string) (string, error) {
 	// Open and get file size
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -224,7 +449,7 @@ func uploadAndInitFirmwareUpgrade(endpoint, token, filePath string) (string, err
 	// Step 1: Initialize the firmware upload
 	initURL := fmt.Sprintf("%s/api/bmc?opt=set&type=firmware&length=%d", endpoint, fileSize)
 
-	initReq, err := http.NewRequest("GET", initURL, nil)
+	initReq, err := http.NewRequestWithContext(ctx, "GET", initURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create init request: %w", err)
 	}
@@ -238,12 +463,12 @@ func uploadAndInitFirmwareUpgrade(endpoint, token, filePath string) (string, err
 
 	if initResp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(initResp.Body)
-		return "", fmt.Errorf("init API returned status %d: %s", initResp.StatusCode, string(body))
+		return "", fmt.Errorf("init API returned status %d: %s%s", initResp.StatusCode, string(body), staleTransferHint(endpoint, token))
 	}
 
 	var initResult firmwareInitResponse
-	if err := json.NewDecoder(initResp.Body).Decode(&initResult); err != nil {
-		return "", fmt.Errorf("failed to decode init response: %w", err)
+	if err := decodeBMCResponse(initResp, endpoint, token, &initResult); err != nil {
+		return "", err
 	}
 
 	handle := extractHandle(initResult)
@@ -252,9 +477,8 @@ func uploadAndInitFirmwareUpgrade(endpoint, token, filePath string) (string, err
 	}
 
 	// Step 2: Upload the firmware file
-	if err := uploadFirmwareData(endpoint, token, handle, file, filePath); err != nil {
-		// Try to cancel on error
-		_ = cancelFirmwareUpload(endpoint, token, handle)
+	if err := uploadFirmwareData(ctx, endpoint, token, handle, file, filePath); err != nil {
+		releaseUploadHandle(endpoint, token, handle)
 		return "", fmt.Errorf("failed to upload firmware: %w", err)
 	}
 
@@ -262,7 +486,7 @@ func uploadAndInitFirmwareUpgrade(endpoint, token, filePath string) (string, err
 }
 
 // uploadFirmwareData uploads the firmware file data to the BMC
-func uploadFirmwareData(endpoint, token, handle string, file *os.File, filePath string) error {
+func uploadFirmwareData(ctx context.Context, endpoint, token, handle string, file *os.File, filePath string) error {
 	// Reset file position
 	if _, err := file.Seek(0, 0); err != nil {
 		return fmt.Errorf("failed to seek file: %w", err)
@@ -293,7 +517,7 @@ func uploadFirmwareData(endpoint, token, handle string, file *os.File, filePath
 
 	uploadURL := fmt.Sprintf("%s/api/bmc/upload/%s", endpoint, handle)
 
-	req, err := http.NewRequest("POST", uploadURL, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, body)
 	if err != nil {
 		return fmt.Errorf("failed to create upload request: %w", err)
 	}
@@ -314,53 +538,37 @@ func uploadFirmwareData(endpoint, token, handle string, file *os.File, filePath
 	return nil
 }
 
-// cancelFirmwareUpload cancels an in-progress firmware upload
-func cancelFirmwareUpload(endpoint, token, handle string) error {
-	url := fmt.Sprintf("%s/api/bmc/upload/%s/cancel", endpoint, handle)
+// firmwareProgressInterval is how often the flash progress of a firmware
+// upgrade is polled
+const firmwareProgressInterval = 3 * time.Second
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create cancel request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := HTTPClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("cancel request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	return nil
-}
-
-// waitForFirmwareUpgrade polls for firmware upgrade completion
-func waitForFirmwareUpgrade(endpoint, token, handle string, timeoutSeconds int) error {
-	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
-
-	for time.Now().Before(deadline) {
+// waitForFirmwareUpgrade polls for firmware upgrade completion. The upload is
+// complete at this point and the BMC is writing its flash, so an interruption
+// stops waiting without cancelling anything.
+func waitForFirmwareUpgrade(ctx context.Context, endpoint, token, handle string, timeoutSeconds int) error {
+	err := poll(ctx, firmwareProgressInterval, time.Duration(timeoutSeconds)*time.Second, "firmware upgrade", func(context.Context) (bool, error) {
 		progress, err := getFlashProgress(endpoint, token)
 		if err != nil {
-			// BMC might be rebooting, wait and retry
-			time.Sleep(5 * time.Second)
-			continue
+			// BMC might be rebooting, retry
+			return false, err
 		}
 
-		status := extractFlashStatus(progress)
-
-		switch status {
+		switch extractFlashStatus(progress) {
 		case "done", "complete", "success":
-			return nil
+			return true, nil
 		case "error", "failed":
-			return fmt.Errorf("firmware upgrade failed")
+			return false, waitutil.Permanent(fmt.Errorf("firmware upgrade failed"))
 		case "idle":
 			// Flash completed, BMC is idle
-			return nil
+			return true, nil
 		}
-
-		time.Sleep(3 * time.Second)
+		return false, nil
+	})
+	var timeoutErr *waitutil.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return fmt.Errorf("waiting for firmware upgrade to complete: %w", err)
 	}
-
-	return fmt.Errorf("timeout waiting for firmware upgrade to complete")
+	return err
 }
 
 // getFlashProgress retrieves the current flash progress
@@ -385,8 +593,8 @@ func getFlashProgress(endpoint, token string) (*flashProgressResponse, error) {
 	}
 
 	var result flashProgressResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -434,3 +642,12 @@ func extractFirmwareVersion(data *bmcAboutResponse) string {
 	}
 	return ""
 }
+
+// firmwareVersionMatches compares firmware versions, ignoring surrounding
+// whitespace and a leading "v" (e.g., "v2.0.5" matches "2.0.5")
+func firmwareVersionMatches(current, target string) bool {
+	normalize := func(v string) string {
+		return strings.TrimPrefix(strings.TrimSpace(v), "v")
+	}
+	return normalize(current) == normalize(target)
+}
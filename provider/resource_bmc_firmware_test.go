@@ -3,12 +3,16 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 func TestResourceBMCFirmwareSchema(t *testing.T) {
@@ -324,7 +328,7 @@ func TestCancelFirmwareUpload(t *testing.T) {
 	HTTPClient = server.Client()
 	defer func() { HTTPClient = originalClient }()
 
-	err := cancelFirmwareUpload(server.URL, "test-token", "test-handle")
+	err := cancelFirmwareUpload(context.Background(), server.URL, "test-token", "test-handle")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -370,7 +374,7 @@ func TestUploadFirmwareData(t *testing.T) {
 	}
 	defer func() { _ = file.Close() }()
 
-	err = uploadFirmwareData(server.URL, "test-token", "test-handle", file, tmpFile)
+	err = uploadFirmwareData(context.Background(), server.URL, "test-token", "test-handle", file, tmpFile)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -379,7 +383,16 @@ func TestUploadFirmwareData(t *testing.T) {
 	}
 }
 
+// fastFirmwareVersionWait shortens the wait for the upgraded firmware version
+func fastFirmwareVersionWait(t *testing.T) {
+	t.Helper()
+	orig := firmwareVersionWait
+	firmwareVersionWait = 50 * time.Millisecond
+	t.Cleanup(func() { firmwareVersionWait = orig })
+}
+
 func TestResourceBMCFirmwareCRUD(t *testing.T) {
+	fastFirmwareVersionWait(t)
 	requestCount := 0
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -433,11 +446,14 @@ func TestResourceBMCFirmwareCRUD(t *testing.T) {
 		t.Fatalf("failed to set timeout: %v", err)
 	}
 
-	// Test Create
+	// Test Create: the BMC keeps reporting the same version
 	diags := resourceBMCFirmwareCreate(context.TODO(), d, config)
 	if diags.HasError() {
 		t.Errorf("Create returned error: %v", diags)
 	}
+	if len(diags) != 1 || diags[0].Summary != "BMC firmware version unchanged" {
+		t.Errorf("expected a warning about the unchanged version, got %v", diags)
+	}
 	if d.Id() != "bmc-firmware" {
 		t.Errorf("expected ID 'bmc-firmware', got '%s'", d.Id())
 	}
@@ -467,3 +483,170 @@ func TestResourceBMCFirmwareCRUD(t *testing.T) {
 		t.Errorf("expected empty ID after delete, got '%s'", d.Id())
 	}
 }
+
+func TestFirmwareVersionMatches(t *testing.T) {
+	tests := []struct {
+		current, target string
+		want            bool
+	}{
+		{"2.0.5", "2.0.5", true},
+		{"v2.0.5", "2.0.5", true},
+		{"2.0.5 ", "v2.0.5", true},
+		{"2.0.4", "2.0.5", false},
+		{"", "2.0.5", false},
+	}
+	for _, tt := range tests {
+		if got := firmwareVersionMatches(tt.current, tt.target); got != tt.want {
+			t.Errorf("firmwareVersionMatches(%q, %q) = %v, want %v", tt.current, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestResourceBMCFirmware_TargetVersion(t *testing.T) {
+	fastFirmwareVersionWait(t)
+	flashRequests := 0
+	version := "2.0.5"
+	installs := "2.0.5"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.String(), "type=about"):
+			_, _ = fmt.Fprintf(w, `{"response":[["api","1.0"],["firmware","%s"]]}`, version)
+		case strings.Contains(r.URL.String(), "type=firmware"):
+			flashRequests++
+			version = installs
+			_, _ = w.Write([]byte(`{"response":[["handle","test-handle"]]}`))
+		case strings.Contains(r.URL.String(), "type=flash"):
+			_, _ = w.Write([]byte(`{"response":[["status","done"]]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	originalClient := HTTPClient
+	HTTPClient = server.Client()
+	defer func() { HTTPClient = originalClient }()
+
+	config := &ProviderConfig{Endpoint: server.URL, Token: "test-token"}
+
+	d := schema.TestResourceDataRaw(t, resourceBMCFirmware().Schema, map[string]interface{}{
+		"firmware_file":  "/tmp/test-firmware.bin",
+		"bmc_local":      true,
+		"timeout":        10,
+		"target_version": "v2.0.5",
+	})
+
+	// Matching version: no upgrade
+	if diags := resourceBMCFirmwareCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Create returned error: %v", diags)
+	}
+	if flashRequests != 0 {
+		t.Errorf("expected no upgrade when version matches, got %d firmware requests", flashRequests)
+	}
+	if d.Id() != "bmc-firmware" {
+		t.Errorf("expected ID 'bmc-firmware', got '%s'", d.Id())
+	}
+	if d.Get("last_upgrade").(string) != "" {
+		t.Error("expected last_upgrade to stay empty when upgrade is skipped")
+	}
+	if d.Get("current_version").(string) != "2.0.5" {
+		t.Errorf("expected current_version '2.0.5', got '%s'", d.Get("current_version"))
+	}
+
+	// Version still matches: stays in state
+	if diags := resourceBMCFirmwareRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Read returned error: %v", diags)
+	}
+	if d.Id() == "" {
+		t.Error("expected resource to stay in state while the version matches")
+	}
+
+	// Out-of-band downgrade: removed from state so the next apply upgrades
+	version = "2.0.3"
+	if diags := resourceBMCFirmwareRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Read returned error: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Error("expected resource to be removed from state on version drift")
+	}
+
+	// Non-matching version: upgrade runs
+	if diags := resourceBMCFirmwareCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Create returned error: %v", diags)
+	}
+	if flashRequests != 1 {
+		t.Errorf("expected one upgrade, got %d firmware requests", flashRequests)
+	}
+	if d.Get("previous_version").(string) != "2.0.3" {
+		t.Errorf("expected previous_version '2.0.3', got '%s'", d.Get("previous_version"))
+	}
+	if d.Get("current_version").(string) != "2.0.5" {
+		t.Errorf("expected current_version read back as '2.0.5', got '%s'", d.Get("current_version"))
+	}
+
+	// An image that installs another version fails the apply
+	version, installs = "2.0.3", "2.0.4"
+	diags := resourceBMCFirmwareCreate(context.Background(), d, config)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "reports firmware 2.0.4, expected v2.0.5") {
+		t.Fatalf("expected a version mismatch error, got %v", diags)
+	}
+	if d.Id() != "" {
+		t.Error("expected the resource not to be created with the wrong version")
+	}
+}
+
+func TestResourceBMCFirmwareCreate_BoardModel(t *testing.T) {
+	fastFirmwareVersionWait(t)
+	tests := []struct {
+		name      string
+		about     string
+		wantError bool
+	}{
+		{"matching board", `{"response":[["firmware","2.0.5"],["board_revision","2.4"]]}`, false},
+		{"other board", `{"response":[["firmware","2.0.5"],["board_revision","2.5.2"],["soc","T113-S3"]]}`, true},
+		{"revision not reported", `{"response":[["firmware","2.0.5"]]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploads int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.String(), "type=about"):
+					_, _ = w.Write([]byte(tt.about))
+				case strings.Contains(r.URL.String(), "type=firmware") && strings.Contains(r.URL.String(), "opt=set"):
+					uploads++
+					_, _ = w.Write([]byte(`{"response":[["handle","test-handle"]]}`))
+				case strings.Contains(r.URL.String(), "type=flash"):
+					_, _ = w.Write([]byte(`{"response":[["status","done"]]}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			originalClient := HTTPClient
+			HTTPClient = server.Client()
+			defer func() { HTTPClient = originalClient }()
+
+			d := schema.TestResourceDataRaw(t, resourceBMCFirmware().Schema, map[string]interface{}{
+				"firmware_file": "/tmp/test-firmware.bin",
+				"bmc_local":     true,
+				"board_model":   "Turing Pi 2",
+			})
+			diags := resourceBMCFirmwareCreate(context.Background(), d, &ProviderConfig{Endpoint: server.URL, Token: "test-token"})
+			if diags.HasError() != tt.wantError {
+				t.Fatalf("expected error %v, got %v", tt.wantError, diags)
+			}
+			if tt.wantError {
+				if !strings.Contains(diags[0].Summary, "board revision 2.5.2 with BMC SoC T113-S3, a Turing Pi 2.5") {
+					t.Errorf("expected the error to name the board, got %q", diags[0].Summary)
+				}
+				if uploads != 0 || d.Id() != "" {
+					t.Errorf("expected no upgrade and no resource, got %d uploads and ID %q", uploads, d.Id())
+				}
+			} else if uploads != 1 {
+				t.Errorf("expected one upgrade, got %d", uploads)
+			}
+		})
+	}
+}
@@ -56,18 +56,18 @@ func resourceBMCRebootCreate(ctx context.Context, d *schema.ResourceData, meta i
 	readyTimeout := d.Get("ready_timeout").(int)
 
 	if err := rebootBMC(config.Endpoint, config.Token); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to reboot BMC: %w", err))
+		return diagFromErr(fmt.Errorf("failed to reboot BMC: %w", err))
 	}
 
 	if waitForReady {
-		if err := waitForBMCReady(config.Endpoint, config.Token, readyTimeout); err != nil {
-			return diag.FromErr(fmt.Errorf("BMC did not become ready after reboot: %w", err))
+		if err := waitForBMCReady(ctx, config.Endpoint, config.Token, readyTimeout); err != nil {
+			return diagFromErr(fmt.Errorf("BMC did not become ready after reboot: %w", err))
 		}
 	}
 
 	d.SetId("bmc-reboot")
 	if err := d.Set("last_reboot", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_reboot: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_reboot: %w", err))
 	}
 
 	return nil
@@ -87,17 +87,17 @@ func resourceBMCRebootUpdate(ctx context.Context, d *schema.ResourceData, meta i
 		readyTimeout := d.Get("ready_timeout").(int)
 
 		if err := rebootBMC(config.Endpoint, config.Token); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to reboot BMC: %w", err))
+			return diagFromErr(fmt.Errorf("failed to reboot BMC: %w", err))
 		}
 
 		if waitForReady {
-			if err := waitForBMCReady(config.Endpoint, config.Token, readyTimeout); err != nil {
-				return diag.FromErr(fmt.Errorf("BMC did not become ready after reboot: %w", err))
+			if err := waitForBMCReady(ctx, config.Endpoint, config.Token, readyTimeout); err != nil {
+				return diagFromErr(fmt.Errorf("BMC did not become ready after reboot: %w", err))
 			}
 		}
 
 		if err := d.Set("last_reboot", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_reboot: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_reboot: %w", err))
 		}
 	}
 
@@ -135,20 +135,21 @@ func rebootBMC(endpoint, token string) error {
 }
 
 // waitForBMCReady waits for the BMC to become available after reboot
-func waitForBMCReady(endpoint, token string, timeoutSeconds int) error {
-	// Wait a few seconds for the reboot to initiate
-	time.Sleep(5 * time.Second)
-
-	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
-
-	for time.Now().Before(deadline) {
-		if checkBMCReady(endpoint, token) {
-			return nil
-		}
-		time.Sleep(5 * time.Second)
+func waitForBMCReady(ctx context.Context, endpoint, token string, timeoutSeconds int) error {
+	// The BMC answers until the reboot takes it down, so polling right
+	// away would report the old instance as ready. Nothing reports the
+	// shutdown, so this is a fixed delay.
+	if err := sleepContext(ctx, 5*time.Second); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("timeout after %d seconds", timeoutSeconds)
+	err := poll(ctx, pollInterval, time.Duration(timeoutSeconds)*time.Second, "BMC", func(context.Context) (bool, error) {
+		return checkBMCReady(endpoint, token), nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for BMC: %w", err)
+	}
+	return nil
 }
 
 // checkBMCReady checks if the BMC is responding to API requests
@@ -56,18 +56,18 @@ func resourceBMCReloadCreate(ctx context.Context, d *schema.ResourceData, meta i
 	readyTimeout := d.Get("ready_timeout").(int)
 
 	if err := reloadBMCDaemon(config.Endpoint, config.Token); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to reload BMC daemon: %w", err))
+		return diagFromErr(fmt.Errorf("failed to reload BMC daemon: %w", err))
 	}
 
 	if waitForReady {
-		if err := waitForBMCReady(config.Endpoint, config.Token, readyTimeout); err != nil {
-			return diag.FromErr(fmt.Errorf("BMC daemon did not become ready after reload: %w", err))
+		if err := waitForBMCReady(ctx, config.Endpoint, config.Token, readyTimeout); err != nil {
+			return diagFromErr(fmt.Errorf("BMC daemon did not become ready after reload: %w", err))
 		}
 	}
 
 	d.SetId("bmc-reload")
 	if err := d.Set("last_reload", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_reload: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_reload: %w", err))
 	}
 
 	return nil
@@ -87,17 +87,17 @@ func resourceBMCReloadUpdate(ctx context.Context, d *schema.ResourceData, meta i
 		readyTimeout := d.Get("ready_timeout").(int)
 
 		if err := reloadBMCDaemon(config.Endpoint, config.Token); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to reload BMC daemon: %w", err))
+			return diagFromErr(fmt.Errorf("failed to reload BMC daemon: %w", err))
 		}
 
 		if waitForReady {
-			if err := waitForBMCReady(config.Endpoint, config.Token, readyTimeout); err != nil {
-				return diag.FromErr(fmt.Errorf("BMC daemon did not become ready after reload: %w", err))
+			if err := waitForBMCReady(ctx, config.Endpoint, config.Token, readyTimeout); err != nil {
+				return diagFromErr(fmt.Errorf("BMC daemon did not become ready after reload: %w", err))
 			}
 		}
 
 		if err := d.Set("last_reload", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_reload: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_reload: %w", err))
 		}
 	}
 
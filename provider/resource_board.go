@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// boardImportID is the only import ID of turingpi_board; a provider manages one board
+const boardImportID = "default"
+
+func resourceBoard() *schema.Resource {
+	return &schema.Resource{
+		Description: "Manages the power state of every slot and the USB routing of the board in one resource. " +
+			"Importing it with the ID 'default' captures the live BMC state, to adopt Terraform on a running board.",
+		CreateContext: resourceBoardCreate,
+		ReadContext:   resourceBoardRead,
+		UpdateContext: resourceBoardUpdate,
+		DeleteContext: resourceBoardDelete,
+		Schema: map[string]*schema.Schema{
+			"slot": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    4,
+				Description: "Power state of a slot. Slots without a block are left untouched.",
+				Elem:        boardSlotSchema(),
+			},
+			"usb": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "USB routing. Left untouched when not set.",
+				Elem:        boardUSBSchema(),
+			},
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceBoardImport,
+		},
+	}
+}
+
+func boardSlotSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"node": {
+				Type:             schema.TypeInt,
+				Required:         true,
+				Description:      "Slot number (1-4)",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
+			},
+			"power": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Power state: 'on' or 'off'",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"on", "off"}, false)),
+			},
+		},
+	}
+}
+
+func boardUSBSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"node": {
+				Type:             schema.TypeInt,
+				Required:         true,
+				Description:      "Node ID to route USB to (1-4)",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
+			},
+			"mode": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "USB mode: 'host' (node acts as USB host) or 'device' (node acts as USB device)",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"host", "device"}, false)),
+			},
+			"route": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "usb-a",
+				Description:      "USB routing destination: 'usb-a' (external USB-A connector) or 'bmc' (route to BMC chip)",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"usb-a", "bmc"}, false)),
+			},
+		},
+	}
+}
+
+// expandBoardSlots converts slot blocks to desired power states (node1-node4 -> on)
+func expandBoardSlots(blocks []interface{}) (map[string]bool, error) {
+	desired := make(map[string]bool, len(blocks))
+	for _, b := range blocks {
+		block := b.(map[string]interface{})
+		name := fmt.Sprintf("node%d", block["node"].(int))
+		if _, ok := desired[name]; ok {
+			return nil, fmt.Errorf("slot %d is configured more than once", block["node"].(int))
+		}
+		desired[name] = block["power"].(string) == "on"
+	}
+	return desired, nil
+}
+
+// flattenBoardSlots renders power states as slot blocks in the order of nodes
+func flattenBoardSlots(nodes []int, power map[string]bool) []interface{} {
+	slots := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		state := "off"
+		if power[fmt.Sprintf("node%d", node)] {
+			state = "on"
+		}
+		slots = append(slots, map[string]interface{}{
+			"node":  node,
+			"power": state,
+		})
+	}
+	return slots
+}
+
+// applyBoard applies the slot power states and USB routing of the configuration
+func applyBoard(config *ProviderConfig, d *schema.ResourceData) error {
+	if d.IsNewResource() || d.HasChange("slot") {
+		desired, err := expandBoardSlots(d.Get("slot").([]interface{}))
+		if err != nil {
+			return err
+		}
+		if err := applyPowerProfile(config.Endpoint, config.Token, desired); err != nil {
+			return fmt.Errorf("failed to set slot power: %w", err)
+		}
+	}
+
+	if d.IsNewResource() || d.HasChange("usb") {
+		if usb := d.Get("usb").([]interface{}); len(usb) > 0 && usb[0] != nil {
+			block := usb[0].(map[string]interface{})
+			apiMode := getUSBAPIMode(block["mode"].(string), block["route"].(string))
+			if err := setUSBMode(config.Endpoint, config.Token, block["node"].(int), apiMode); err != nil {
+				return fmt.Errorf("failed to set USB mode: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func resourceBoardCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	if err := applyBoard(config, d); err != nil {
+		return diagFromErr(err)
+	}
+
+	d.SetId(boardImportID)
+
+	return resourceBoardRead(ctx, d, meta)
+}
+
+// resourceBoardRead refreshes the slots and USB routing managed by the
+// resource, so out-of-band changes show up as drift
+func resourceBoardRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	if slots := d.Get("slot").([]interface{}); len(slots) > 0 {
+		status, err := getPowerStatus(config.Endpoint, config.Token)
+		if err != nil {
+			return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
+		}
+		nodes := make([]int, 0, len(slots))
+		for _, s := range slots {
+			nodes = append(nodes, s.(map[string]interface{})["node"].(int))
+		}
+		if err := d.Set("slot", flattenBoardSlots(nodes, parsePowerStatus(status))); err != nil {
+			return diagFromErr(fmt.Errorf("failed to set slot: %w", err))
+		}
+	}
+
+	if usb := d.Get("usb").([]interface{}); len(usb) > 0 && usb[0] != nil {
+		if err := readBoardUSB(config, d); err != nil {
+			return diagFromErr(err)
+		}
+	}
+
+	return nil
+}
+
+// readBoardUSB sets the usb block from the live BMC USB configuration
+func readBoardUSB(config *ProviderConfig, d *schema.ResourceData) error {
+	status, err := getUSBStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return fmt.Errorf("failed to read USB status: %w", err)
+	}
+	mode, node, route := parseUSBStatus(status)
+	if err := d.Set("usb", []interface{}{map[string]interface{}{
+		"node":  node,
+		"mode":  mode,
+		"route": route,
+	}}); err != nil {
+		return fmt.Errorf("failed to set usb: %w", err)
+	}
+	return nil
+}
+
+func resourceBoardUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	if err := applyBoard(config, d); err != nil {
+		return diagFromErr(err)
+	}
+
+	return resourceBoardRead(ctx, d, meta)
+}
+
+func resourceBoardDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Leave slots and USB in their current state - only remove from state
+	d.SetId("")
+	return nil
+}
+
+// resourceBoardImport captures the power state of all four slots and the USB
+// routing from the BMC
+func resourceBoardImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if d.Id() != boardImportID {
+		return nil, fmt.Errorf("invalid import ID %q: expected %q", d.Id(), boardImportID)
+	}
+	config := meta.(*ProviderConfig)
+
+	status, err := getPowerStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read power status: %w", err)
+	}
+	if err := d.Set("slot", flattenBoardSlots([]int{1, 2, 3, 4}, parsePowerStatus(status))); err != nil {
+		return nil, fmt.Errorf("failed to set slot: %w", err)
+	}
+
+	if err := readBoardUSB(config, d); err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
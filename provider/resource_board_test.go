@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// newFakeBoardBMC serves power requests from fakePowerBMC and USB requests
+// against an in-memory mode and 0-indexed node
+func newFakeBoardBMC(t *testing.T, power map[string]int, usbMode, usbNode int) (*httptest.Server, *fakePowerBMC) {
+	t.Helper()
+	bmc := &fakePowerBMC{state: power}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("type") != "usb" {
+			bmc.ServeHTTP(w, r)
+			return
+		}
+		if q.Get("opt") == "set" {
+			_, _ = fmt.Sscanf(q.Get("mode"), "%d", &usbMode)
+			_, _ = fmt.Sscanf(q.Get("node"), "%d", &usbNode)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		mode, route := "host", "usb-a"
+		if usbMode == usbModeDeviceUSBA || usbMode == usbModeDeviceBMC {
+			mode = "device"
+		}
+		if usbMode >= usbModeHostBMC {
+			route = "bmc"
+		}
+		_, _ = fmt.Fprintf(w, `{"response":[{"result":[{"mode":"%s","node":"Node %d","route":"%s"}]}]}`, mode, usbNode+1, route)
+	}))
+	t.Cleanup(server.Close)
+	return server, bmc
+}
+
+func TestResourceBoard(t *testing.T) {
+	r := resourceBoard()
+	if err := r.InternalValidate(nil, true); err != nil {
+		t.Fatalf("resource internal validation failed: %s", err)
+	}
+}
+
+func TestResourceBoardImport(t *testing.T) {
+	server, _ := newFakeBoardBMC(t, map[string]int{"node1": 1, "node2": 0, "node3": 1, "node4": 0}, usbModeDeviceUSBA, 1)
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	d := resourceBoard().TestResourceData()
+	d.SetId("default")
+	states, err := resourceBoardImport(context.Background(), d, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(states) != 1 {
+		t.Fatalf("expected one imported state, got %d", len(states))
+	}
+
+	want := []string{"on", "off", "on", "off"}
+	for i, power := range want {
+		if got := d.Get(fmt.Sprintf("slot.%d.power", i)).(string); got != power {
+			t.Errorf("slot %d: expected power %q, got %q", i+1, power, got)
+		}
+		if got := d.Get(fmt.Sprintf("slot.%d.node", i)).(int); got != i+1 {
+			t.Errorf("slot %d: expected node %d, got %d", i+1, i+1, got)
+		}
+	}
+	if d.Get("usb.0.node").(int) != 2 || d.Get("usb.0.mode").(string) != "device" || d.Get("usb.0.route").(string) != "usb-a" {
+		t.Errorf("unexpected usb block: %v", d.Get("usb"))
+	}
+}
+
+func TestResourceBoardImport_InvalidID(t *testing.T) {
+	d := resourceBoard().TestResourceData()
+	d.SetId("board-1")
+	if _, err := resourceBoardImport(context.Background(), d, &ProviderConfig{}); err == nil {
+		t.Error("expected error for invalid import ID")
+	}
+}
+
+func TestResourceBoardCreate(t *testing.T) {
+	server, bmc := newFakeBoardBMC(t, map[string]int{"node1": 0, "node2": 1, "node3": 0, "node4": 1}, usbModeHostUSBA, 0)
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	d := schema.TestResourceDataRaw(t, resourceBoard().Schema, map[string]interface{}{
+		"slot": []interface{}{
+			map[string]interface{}{"node": 2, "power": "off"},
+			map[string]interface{}{"node": 1, "power": "on"},
+		},
+		"usb": []interface{}{map[string]interface{}{"node": 3, "mode": "device", "route": "bmc"}},
+	})
+
+	if diags := resourceBoardCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "default" {
+		t.Errorf("expected ID 'default', got %q", d.Id())
+	}
+	if len(bmc.writes) != 2 || bmc.writes[0] != "node2=0" || bmc.writes[1] != "node1=1" {
+		t.Errorf("expected node2 off then node1 on, got %v", bmc.writes)
+	}
+	if bmc.state["node4"] != 1 {
+		t.Error("expected unmanaged node4 to be left on")
+	}
+	if d.Get("usb.0.node").(int) != 3 || d.Get("usb.0.mode").(string) != "device" || d.Get("usb.0.route").(string) != "bmc" {
+		t.Errorf("unexpected usb block after create: %v", d.Get("usb"))
+	}
+	// Read keeps the configured slot order
+	if d.Get("slot.0.node").(int) != 2 || d.Get("slot.0.power").(string) != "off" || d.Get("slot.1.power").(string) != "on" {
+		t.Errorf("unexpected slots after create: %v", d.Get("slot"))
+	}
+}
+
+func TestResourceBoardRead_Drift(t *testing.T) {
+	server, _ := newFakeBoardBMC(t, map[string]int{"node1": 0, "node2": 0, "node3": 0, "node4": 0}, usbModeHostUSBA, 0)
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	d := schema.TestResourceDataRaw(t, resourceBoard().Schema, map[string]interface{}{
+		"slot": []interface{}{map[string]interface{}{"node": 3, "power": "on"}},
+	})
+	d.SetId("default")
+
+	if diags := resourceBoardRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := d.Get("slot.0.power").(string); got != "off" {
+		t.Errorf("expected drifted power 'off', got %q", got)
+	}
+	if len(d.Get("slot").([]interface{})) != 1 {
+		t.Error("expected only managed slots to be refreshed")
+	}
+	if len(d.Get("usb").([]interface{})) != 0 {
+		t.Error("expected unmanaged usb to stay unset")
+	}
+}
+
+func TestExpandBoardSlots_Duplicate(t *testing.T) {
+	_, err := expandBoardSlots([]interface{}{
+		map[string]interface{}{"node": 1, "power": "on"},
+		map[string]interface{}{"node": 1, "power": "off"},
+	})
+	if err == nil {
+		t.Error("expected error for duplicate slot")
+	}
+}
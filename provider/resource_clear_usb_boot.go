@@ -17,6 +17,7 @@ func resourceClearUSBBoot() *schema.Resource {
 		ReadContext:   resourceClearUSBBootRead,
 		UpdateContext: resourceClearUSBBootUpdate,
 		DeleteContext: resourceClearUSBBootDelete,
+		CustomizeDiff: customizeDiffDefaultTriggers,
 		Schema: map[string]*schema.Schema{
 			"node": {
 				Type:             schema.TypeInt,
@@ -27,7 +28,8 @@ func resourceClearUSBBoot() *schema.Resource {
 			"triggers": {
 				Type:        schema.TypeMap,
 				Optional:    true,
-				Description: "A map of values that, when changed, will re-clear USB boot status.",
+				Computed:    true,
+				Description: "A map of values that, when changed, will re-clear USB boot status. The provider default_metadata is merged in.",
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -47,12 +49,12 @@ func resourceClearUSBBootCreate(ctx context.Context, d *schema.ResourceData, met
 	node := d.Get("node").(int)
 
 	if err := clearUSBBoot(config.Endpoint, config.Token, node); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to clear USB boot for node %d: %w", node, err))
+		return diagFromErr(fmt.Errorf("failed to clear USB boot for node %d: %w", node, err))
 	}
 
 	d.SetId(fmt.Sprintf("clear-usb-boot-node-%d", node))
 	if err := d.Set("last_cleared", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_cleared: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_cleared: %w", err))
 	}
 
 	return nil
@@ -68,13 +70,13 @@ func resourceClearUSBBootUpdate(ctx context.Context, d *schema.ResourceData, met
 	node := d.Get("node").(int)
 
 	// Re-clear if node or triggers changed
-	if d.HasChange("node") || d.HasChange("triggers") {
+	if d.HasChange("node") || triggersChanged(d) {
 		if err := clearUSBBoot(config.Endpoint, config.Token, node); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to clear USB boot for node %d: %w", node, err))
+			return diagFromErr(fmt.Errorf("failed to clear USB boot for node %d: %w", node, err))
 		}
 
 		if err := d.Set("last_cleared", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_cleared: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_cleared: %w", err))
 		}
 	}
 
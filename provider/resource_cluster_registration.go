@@ -0,0 +1,247 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// registrationHTTPClient calls inventory APIs. It is separate from
+// HTTPClient so BMC credentials and TLS settings are never sent to them.
+// Replaced in tests.
+var registrationHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// clusterRegistration is the body sent to the inventory API
+type clusterRegistration struct {
+	Name              string            `json:"name"`
+	Endpoint          string            `json:"endpoint"`
+	Distribution      string            `json:"distribution,omitempty"`
+	KubernetesVersion string            `json:"kubernetes_version,omitempty"`
+	KubeconfigPath    string            `json:"kubeconfig_path,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	Source            string            `json:"source"`
+}
+
+func resourceClusterRegistration() *schema.Resource {
+	return &schema.Resource{
+		Description: "Registers a cluster with an external inventory API, such as a CMDB or a Rancher import webhook, " +
+			"and deletes the registration on destroy. The registration is not read back; state holds what was sent.",
+		CreateContext: resourceClusterRegistrationCreate,
+		ReadContext:   resourceClusterRegistrationRead,
+		UpdateContext: resourceClusterRegistrationUpdate,
+		DeleteContext: resourceClusterRegistrationDelete,
+		Schema: map[string]*schema.Schema{
+			"url": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				Description:  "Inventory API collection URL. The registration is created with a POST to this URL.",
+				ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+			},
+			"headers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "HTTP headers sent with every request, e.g. Authorization",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"id_field": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "id",
+				Description: "Field of the JSON create response holding the registration ID",
+			},
+			"cluster_name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Cluster name, e.g. turingpi_k3s_cluster.name",
+			},
+			"endpoint": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Kubernetes API endpoint, e.g. turingpi_k3s_cluster.api_endpoint",
+			},
+			"distribution": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Kubernetes distribution, e.g. k3s or talos",
+			},
+			"kubernetes_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Kubernetes version of the cluster",
+			},
+			"kubeconfig_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Where the kubeconfig can be found, e.g. turingpi_k3s_cluster.kubeconfig_path. Only the reference is sent, never the kubeconfig.",
+			},
+			"metadata": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Additional key/value pairs sent with the registration, e.g. site or owner",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"registration_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "ID returned by the inventory API, or cluster_name when the response has none",
+			},
+			"registration_url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "URL of the registration, updated with PUT and removed with DELETE: the Location header of the create response, or url/registration_id",
+			},
+			"payload": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "JSON body last sent to the inventory API",
+			},
+		},
+	}
+}
+
+func resourceClusterRegistrationCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	payload, err := clusterRegistrationPayload(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	url := d.Get("url").(string)
+	resp, body, err := sendRegistration(ctx, d, http.MethodPost, url, payload)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to register cluster: %w", err))
+	}
+
+	id := registrationID(body, d.Get("id_field").(string))
+	if id == "" {
+		id = d.Get("cluster_name").(string)
+	}
+	registrationURL := strings.TrimRight(url, "/") + "/" + id
+	if location := resp.Header.Get("Location"); location != "" {
+		if loc, err := resp.Request.URL.Parse(location); err == nil {
+			registrationURL = loc.String()
+		}
+	}
+
+	tflog.Info(ctx, "Registered cluster", map[string]interface{}{
+		"cluster":          d.Get("cluster_name").(string),
+		"registration_url": registrationURL,
+	})
+	d.SetId(id)
+	if err := d.Set("registration_id", id); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("registration_url", registrationURL); err != nil {
+		return diagFromErr(err)
+	}
+	return diagFromErr(d.Set("payload", string(payload)))
+}
+
+// resourceClusterRegistrationRead keeps the state as is: inventory APIs differ
+// too much to read registrations back
+func resourceClusterRegistrationRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceClusterRegistrationUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	payload, err := clusterRegistrationPayload(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	if string(payload) == d.Get("payload").(string) {
+		// Only headers or id_field changed
+		return nil
+	}
+
+	if _, _, err := sendRegistration(ctx, d, http.MethodPut, d.Get("registration_url").(string), payload); err != nil {
+		d.Partial(true)
+		return diagFromErr(fmt.Errorf("failed to update cluster registration: %w", err))
+	}
+	return diagFromErr(d.Set("payload", string(payload)))
+}
+
+func resourceClusterRegistrationDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	resp, _, err := sendRegistration(ctx, d, http.MethodDelete, d.Get("registration_url").(string), nil)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return diagFromErr(fmt.Errorf("failed to delete cluster registration: %w", err))
+	}
+	d.SetId("")
+	return nil
+}
+
+// clusterRegistrationPayload renders the registration body
+func clusterRegistrationPayload(d *schema.ResourceData) ([]byte, error) {
+	reg := clusterRegistration{
+		Name:              d.Get("cluster_name").(string),
+		Endpoint:          d.Get("endpoint").(string),
+		Distribution:      d.Get("distribution").(string),
+		KubernetesVersion: d.Get("kubernetes_version").(string),
+		KubeconfigPath:    d.Get("kubeconfig_path").(string),
+		Metadata:          expandStringMap(d.Get("metadata").(map[string]interface{})),
+		Source:            "terraform-provider-turingpi",
+	}
+	payload, err := json.Marshal(reg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render registration: %w", err)
+	}
+	return payload, nil
+}
+
+// sendRegistration sends a request to the inventory API and returns the
+// response and its body. Statuses other than 2xx are errors, returned with
+// the response.
+func sendRegistration(ctx context.Context, d *schema.ResourceData, method, url string, payload []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", buildUserAgent(""))
+	for name, value := range d.Get("headers").(map[string]interface{}) {
+		req.Header.Set(name, value.(string))
+	}
+
+	resp, err := registrationHTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s failed: %w", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("failed to read response of %s %s: %w", method, url, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, body, fmt.Errorf("%s %s returned HTTP %d: %s", method, url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, body, nil
+}
+
+// registrationID returns the string or number in field of a JSON object
+// response, or "" when there is none
+func registrationID(body []byte, field string) string {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return ""
+	}
+	switch v := obj[field].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
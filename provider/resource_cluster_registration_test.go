@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// fakeInventory records the requests of an inventory API
+type fakeInventory struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []clusterRegistration
+	status   int
+	response string
+}
+
+func (f *fakeInventory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		var reg clusterRegistration
+		_ = json.Unmarshal(body, &reg)
+		f.bodies = append(f.bodies, reg)
+	}
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+	}
+	_, _ = w.Write([]byte(f.response))
+}
+
+func testClusterRegistration(t *testing.T, url string) *schema.ResourceData {
+	t.Helper()
+	return schema.TestResourceDataRaw(t, resourceClusterRegistration().Schema, map[string]interface{}{
+		"url":                url + "/api/clusters/",
+		"headers":            map[string]interface{}{"Authorization": "Bearer inventory-token"},
+		"cluster_name":       "homelab",
+		"endpoint":           "https://10.10.88.73:6443",
+		"distribution":       "k3s",
+		"kubernetes_version": "v1.31.4",
+		"kubeconfig_path":    "/home/me/.kube/homelab",
+		"metadata":           map[string]interface{}{"site": "garage"},
+	})
+}
+
+func TestResourceClusterRegistration(t *testing.T) {
+	if err := resourceClusterRegistration().InternalValidate(nil, true); err != nil {
+		t.Fatalf("resource internal validation failed: %s", err)
+	}
+}
+
+func TestResourceClusterRegistration_Lifecycle(t *testing.T) {
+	inventory := &fakeInventory{response: `{"id": 42}`}
+	server := httptest.NewServer(inventory)
+	defer server.Close()
+
+	d := testClusterRegistration(t, server.URL)
+	if diags := resourceClusterRegistrationCreate(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "42" || d.Get("registration_url").(string) != server.URL+"/api/clusters/42" {
+		t.Errorf("unexpected registration %s at %s", d.Id(), d.Get("registration_url"))
+	}
+	reg := inventory.bodies[0]
+	if reg.Name != "homelab" || reg.Endpoint != "https://10.10.88.73:6443" || reg.KubeconfigPath != "/home/me/.kube/homelab" || reg.Metadata["site"] != "garage" {
+		t.Errorf("unexpected registration body: %+v", reg)
+	}
+
+	// Unchanged payloads are not sent again
+	if diags := resourceClusterRegistrationUpdate(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if err := d.Set("kubernetes_version", "v1.32.0"); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceClusterRegistrationUpdate(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	// A registration already removed on the inventory side is not an error
+	inventory.status = http.StatusNotFound
+	if diags := resourceClusterRegistrationDelete(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	want := []string{
+		"POST /api/clusters/ Bearer inventory-token",
+		"PUT /api/clusters/42 Bearer inventory-token",
+		"DELETE /api/clusters/42 Bearer inventory-token",
+	}
+	if strings.Join(inventory.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected requests:\n%s", strings.Join(inventory.requests, "\n"))
+	}
+	if inventory.bodies[1].KubernetesVersion != "v1.32.0" {
+		t.Errorf("expected the update to send the new version, got %+v", inventory.bodies[1])
+	}
+}
+
+func TestResourceClusterRegistration_LocationAndFallbackID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/api/clusters/by-name/homelab")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	d := testClusterRegistration(t, server.URL)
+	if diags := resourceClusterRegistrationCreate(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "homelab" {
+		t.Errorf("expected the cluster name as ID, got %q", d.Id())
+	}
+	if got := d.Get("registration_url").(string); got != server.URL+"/api/clusters/by-name/homelab" {
+		t.Errorf("expected the Location header as registration URL, got %s", got)
+	}
+}
+
+func TestResourceClusterRegistration_CreateFails(t *testing.T) {
+	inventory := &fakeInventory{status: http.StatusUnauthorized, response: "invalid token"}
+	server := httptest.NewServer(inventory)
+	defer server.Close()
+
+	d := testClusterRegistration(t, server.URL)
+	diags := resourceClusterRegistrationCreate(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "returned HTTP 401: invalid token") {
+		t.Errorf("expected the HTTP error, got %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected no ID after a failed registration, got %q", d.Id())
+	}
+}
@@ -0,0 +1,280 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// eepromBootModes maps boot_order entries to CM4 bootloader BOOT_ORDER nibbles
+// See: https://www.raspberrypi.com/documentation/computers/raspberry-pi.html#BOOT_ORDER
+var eepromBootModes = map[string]string{
+	"sd":      "1",
+	"network": "2",
+	"rpiboot": "3",
+	"usb":     "4",
+	"nvme":    "6",
+	"http":    "7",
+}
+
+// rpibootCommand creates the command used to run rpiboot and helper scripts
+// Replaced in tests to avoid executing real binaries
+var rpibootCommand = exec.CommandContext
+
+func resourceEEPROM() *schema.Resource {
+	return &schema.Resource{
+		Description: "Programs the CM4 bootloader EEPROM configuration (boot order) of a node. " +
+			"The node is placed in USB boot mode and routed to the USB-A port, where rpiboot running on the " +
+			"Terraform host writes the recovery image. The Terraform host must be connected to the Turing Pi USB-A port.",
+		CreateContext: resourceEEPROMCreate,
+		ReadContext:   resourceEEPROMRead,
+		UpdateContext: resourceEEPROMUpdate,
+		DeleteContext: resourceEEPROMDelete,
+		CustomizeDiff: customizeDiffDefaultTriggers,
+		Schema: map[string]*schema.Schema{
+			"node": {
+				Type:             schema.TypeInt,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
+				Description:      "The node number (1-4) holding the CM4 to program.",
+			},
+			"boot_order": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				MaxItems:    7,
+				Description: "Boot modes in the order they are tried: sd, usb, network, nvme, http, rpiboot.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice([]string{"sd", "usb", "network", "nvme", "http", "rpiboot"}, false),
+				},
+			},
+			"config": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Additional bootloader settings written to boot.conf (e.g., BOOT_UART = \"1\").",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"recovery_dir": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Local usbboot recovery directory (containing recovery.bin and pieeprom.original.bin). boot.conf is written here and update-pieeprom.sh is run if present.",
+			},
+			"rpiboot_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "rpiboot",
+				Description: "Path to the rpiboot binary on the Terraform host.",
+			},
+			"power_on_after": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Power the node back on after programming.",
+			},
+			"timeout": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          300,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(30)),
+				Description:      "Timeout in seconds for rpiboot to complete.",
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Computed:    true,
+				Description: "A map of values that, when changed, will reprogram the EEPROM. The provider default_metadata is merged in.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			// Computed attributes
+			"boot_order_value": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The BOOT_ORDER value written to the EEPROM (e.g., 0xf241).",
+			},
+			"boot_conf": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The rendered boot.conf content.",
+			},
+			"last_programmed": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Timestamp when the EEPROM was last programmed.",
+			},
+		},
+	}
+}
+
+func resourceEEPROMCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+	node := d.Get("node").(int)
+
+	// Check for rpiboot before the node is powered off
+	if _, diags := checkBinary(rpibootBinary, d.Get("rpiboot_path").(string)); diags.HasError() {
+		return diags
+	}
+
+	if err := programEEPROM(ctx, config, d); err != nil {
+		return diagFromErr(fmt.Errorf("failed to program EEPROM for node %d: %w", node, err))
+	}
+
+	d.SetId(fmt.Sprintf("eeprom-node-%d", node))
+	return nil
+}
+
+func resourceEEPROMRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// The EEPROM cannot be read back through the BMC - nothing to refresh
+	return nil
+}
+
+func resourceEEPROMUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+	node := d.Get("node").(int)
+
+	if d.HasChanges("boot_order", "config") || triggersChanged(d) {
+		if _, diags := checkBinary(rpibootBinary, d.Get("rpiboot_path").(string)); diags.HasError() {
+			return diags
+		}
+		if err := programEEPROM(ctx, config, d); err != nil {
+			return diagFromErr(fmt.Errorf("failed to program EEPROM for node %d: %w", node, err))
+		}
+	}
+
+	return nil
+}
+
+func resourceEEPROMDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// The EEPROM configuration stays on the module - only remove from state
+	d.SetId("")
+	return nil
+}
+
+// buildBootOrder converts boot mode names to a BOOT_ORDER value.
+// The bootloader reads nibbles right to left and 0xf (restart) is appended last.
+func buildBootOrder(modes []string) (string, error) {
+	var b strings.Builder
+	b.WriteString("f")
+	for i := len(modes) - 1; i >= 0; i-- {
+		nibble, ok := eepromBootModes[modes[i]]
+		if !ok {
+			return "", fmt.Errorf("unknown boot mode %q", modes[i])
+		}
+		b.WriteString(nibble)
+	}
+	return "0x" + b.String(), nil
+}
+
+// renderBootConf renders boot.conf content with BOOT_ORDER and any extra settings
+func renderBootConf(bootOrder string, extra map[string]string) string {
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		if k != "BOOT_ORDER" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("[all]\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, extra[k])
+	}
+	fmt.Fprintf(&b, "BOOT_ORDER=%s\n", bootOrder)
+	return b.String()
+}
+
+// programEEPROM runs the full EEPROM update sequence for a node:
+// power off, enable USB boot, route USB to the USB-A port, power on,
+// run rpiboot from the host, then restore normal boot
+func programEEPROM(ctx context.Context, config *ProviderConfig, d *schema.ResourceData) error {
+	node := d.Get("node").(int)
+	recoveryDir := d.Get("recovery_dir").(string)
+	timeout := time.Duration(d.Get("timeout").(int)) * time.Second
+
+	var modes []string
+	for _, m := range d.Get("boot_order").([]interface{}) {
+		modes = append(modes, m.(string))
+	}
+	bootOrder, err := buildBootOrder(modes)
+	if err != nil {
+		return err
+	}
+
+	extra := make(map[string]string)
+	for k, v := range d.Get("config").(map[string]interface{}) {
+		extra[k] = v.(string)
+	}
+	bootConf := renderBootConf(bootOrder, extra)
+
+	// Stage boot.conf and rebuild the EEPROM image before touching the node
+	if err := os.WriteFile(filepath.Join(recoveryDir, "boot.conf"), []byte(bootConf), 0644); err != nil {
+		return fmt.Errorf("failed to write boot.conf: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(recoveryDir, "update-pieeprom.sh")); err == nil {
+		cmd := rpibootCommand(ctx, "sh", "./update-pieeprom.sh")
+		cmd.Dir = recoveryDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("update-pieeprom.sh failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	tflog.Info(ctx, "Placing node in USB boot mode", map[string]interface{}{"node": node})
+	if err := setNodePower(config.Endpoint, config.Token, node, false); err != nil {
+		return fmt.Errorf("failed to power off node: %w", err)
+	}
+	if err := enableUSBBoot(config.Endpoint, config.Token, node); err != nil {
+		return fmt.Errorf("failed to enable USB boot: %w", err)
+	}
+	if err := setUSBMode(config.Endpoint, config.Token, node, usbModeDeviceUSBA); err != nil {
+		return fmt.Errorf("failed to route USB to USB-A port: %w", err)
+	}
+	if err := setNodePower(config.Endpoint, config.Token, node, true); err != nil {
+		return fmt.Errorf("failed to power on node: %w", err)
+	}
+
+	tflog.Info(ctx, "Running rpiboot", map[string]interface{}{"node": node, "boot_order": bootOrder})
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := rpibootCommand(runCtx, d.Get("rpiboot_path").(string), "-d", recoveryDir)
+	output, rpibootErr := cmd.CombinedOutput()
+
+	// Always restore normal boot, even when rpiboot failed
+	if err := setNodePower(config.Endpoint, config.Token, node, false); err != nil {
+		return fmt.Errorf("failed to power off node: %w", err)
+	}
+	if err := clearUSBBoot(config.Endpoint, config.Token, node); err != nil {
+		return fmt.Errorf("failed to clear USB boot: %w", err)
+	}
+	if rpibootErr != nil {
+		return fmt.Errorf("rpiboot failed: %w (output: %s)", rpibootErr, strings.TrimSpace(string(output)))
+	}
+	if d.Get("power_on_after").(bool) {
+		if err := setNodePower(config.Endpoint, config.Token, node, true); err != nil {
+			return fmt.Errorf("failed to power on node: %w", err)
+		}
+	}
+
+	if err := d.Set("boot_order_value", bootOrder); err != nil {
+		return err
+	}
+	if err := d.Set("boot_conf", bootConf); err != nil {
+		return err
+	}
+	return d.Set("last_programmed", time.Now().UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestResourceEEPROM(t *testing.T) {
+	r := resourceEEPROM()
+	if err := r.InternalValidate(nil, true); err != nil {
+		t.Fatalf("resource internal validation failed: %s", err)
+	}
+}
+
+func TestResourceEEPROM_SchemaTypes(t *testing.T) {
+	r := resourceEEPROM()
+
+	tests := []struct {
+		field    string
+		expected schema.ValueType
+	}{
+		{"node", schema.TypeInt},
+		{"boot_order", schema.TypeList},
+		{"config", schema.TypeMap},
+		{"recovery_dir", schema.TypeString},
+		{"rpiboot_path", schema.TypeString},
+		{"power_on_after", schema.TypeBool},
+		{"timeout", schema.TypeInt},
+		{"triggers", schema.TypeMap},
+		{"boot_order_value", schema.TypeString},
+		{"boot_conf", schema.TypeString},
+		{"last_programmed", schema.TypeString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			s, ok := r.Schema[tt.field]
+			if !ok {
+				t.Fatalf("schema missing '%s' field", tt.field)
+			}
+			if s.Type != tt.expected {
+				t.Errorf("expected %s to be type %v, got %v", tt.field, tt.expected, s.Type)
+			}
+		})
+	}
+}
+
+func TestResourceEEPROM_NodeForceNew(t *testing.T) {
+	r := resourceEEPROM()
+
+	if !r.Schema["node"].ForceNew {
+		t.Error("node field should be ForceNew")
+	}
+}
+
+func TestBuildBootOrder(t *testing.T) {
+	tests := []struct {
+		modes    []string
+		expected string
+		wantErr  bool
+	}{
+		{[]string{"sd"}, "0xf1", false},
+		{[]string{"sd", "usb", "network"}, "0xf241", false},
+		{[]string{"nvme", "sd"}, "0xf16", false},
+		{[]string{"floppy"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.modes, ","), func(t *testing.T) {
+			got, err := buildBootOrder(tt.modes)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderBootConf(t *testing.T) {
+	got := renderBootConf("0xf241", map[string]string{
+		"BOOT_UART":          "1",
+		"BOOT_ORDER":         "0xf1", // ignored, boot_order wins
+		"ENABLE_SELF_UPDATE": "1",
+	})
+	expected := "[all]\nBOOT_UART=1\nENABLE_SELF_UPDATE=1\nBOOT_ORDER=0xf241\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+// mockRpiboot replaces rpibootCommand for the duration of a test
+func mockRpiboot(t *testing.T, fail bool) *[]string {
+	t.Helper()
+	var calls []string
+	origLookPath := lookPath
+	lookPath = func(file string) (string, error) { return file, nil }
+	orig := rpibootCommand
+	rpibootCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		if fail {
+			return exec.CommandContext(ctx, "false")
+		}
+		return exec.CommandContext(ctx, "echo", "ok")
+	}
+	t.Cleanup(func() {
+		rpibootCommand = orig
+		lookPath = origLookPath
+	})
+	return &calls
+}
+
+func TestResourceEEPROMCreate_Sequence(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	calls := mockRpiboot(t, false)
+	dir := t.TempDir()
+
+	d := schema.TestResourceDataRaw(t, resourceEEPROM().Schema, map[string]interface{}{
+		"node":         2,
+		"boot_order":   []interface{}{"sd", "usb", "network"},
+		"recovery_dir": dir,
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := resourceEEPROMCreate(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if d.Id() != "eeprom-node-2" {
+		t.Errorf("expected ID 'eeprom-node-2', got '%s'", d.Id())
+	}
+	if v := d.Get("boot_order_value").(string); v != "0xf241" {
+		t.Errorf("expected boot_order_value '0xf241', got '%s'", v)
+	}
+
+	conf, err := os.ReadFile(filepath.Join(dir, "boot.conf"))
+	if err != nil {
+		t.Fatalf("boot.conf not written: %v", err)
+	}
+	if !strings.Contains(string(conf), "BOOT_ORDER=0xf241") {
+		t.Errorf("unexpected boot.conf content: %s", conf)
+	}
+
+	if len(*calls) != 1 || !strings.HasPrefix((*calls)[0], "rpiboot -d "+dir) {
+		t.Errorf("unexpected rpiboot calls: %v", *calls)
+	}
+
+	expected := []string{
+		"opt=set&type=power&node2=0",
+		"opt=set&type=usb_boot&node=2",
+		"opt=set&type=usb&mode=1&node=1",
+		"opt=set&type=power&node2=1",
+		"opt=set&type=power&node2=0",
+		"opt=set&type=clear_usb_boot&node=2",
+		"opt=set&type=power&node2=1",
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("expected %d requests, got %d: %v", len(expected), len(requests), requests)
+	}
+	for i, q := range expected {
+		if requests[i] != q {
+			t.Errorf("request %d: expected %q, got %q", i, q, requests[i])
+		}
+	}
+}
+
+func TestResourceEEPROMCreate_RpibootFailureClearsUSBBoot(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Query().Get("type"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mockRpiboot(t, true)
+
+	d := schema.TestResourceDataRaw(t, resourceEEPROM().Schema, map[string]interface{}{
+		"node":         1,
+		"boot_order":   []interface{}{"nvme"},
+		"recovery_dir": t.TempDir(),
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := resourceEEPROMCreate(context.Background(), d, config)
+	if !diags.HasError() {
+		t.Fatal("expected error when rpiboot fails")
+	}
+	if d.Id() != "" {
+		t.Errorf("expected empty ID, got '%s'", d.Id())
+	}
+	if requests[len(requests)-1] != "clear_usb_boot" {
+		t.Errorf("expected USB boot to be cleared last, got %v", requests)
+	}
+}
@@ -1,39 +1,151 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/waitutil"
 )
 
 func resourceFlash() *schema.Resource {
 	return &schema.Resource{
-		Description: "Flashes firmware to a Turing Pi compute node. The node must be powered off before flashing.",
-		Create:      resourceFlashCreate,
-		Read:        resourceFlashRead,
-		Delete:      resourceFlashDelete,
+		Description:   "Flashes firmware to one or more Turing Pi compute nodes. Nodes are powered off before flashing.",
+		CreateContext: resourceFlashCreate,
+		ReadContext:   resourceFlashRead,
+		UpdateContext: resourceFlashUpdate,
+		DeleteContext: resourceFlashDelete,
+		CustomizeDiff: resourceFlashCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"node": {
 				Type:             schema.TypeInt,
-				Required:         true,
-				Description:      "Node ID to flash firmware (1-4)",
+				Optional:         true,
+				Description:      "Node ID to flash firmware (1-4). Exactly one of node and nodes must be set.",
 				ForceNew:         true,
+				ExactlyOneOf:     []string{"node", "nodes"},
 				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
 			},
+			"nodes": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MinItems:    1,
+				MaxItems:    4,
+				Description: "Node IDs (1-4) to flash the same firmware to, one after another. Adding a node flashes only that node; failed nodes are retried on the next apply.",
+				Elem: &schema.Schema{
+					Type:             schema.TypeInt,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
+				},
+			},
 			"firmware_file": {
 				Type:        schema.TypeString,
 				Required:    true,
 				Description: "Path to the firmware file to flash",
 				ForceNew:    true,
 			},
+			"kernel_args": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Description: "Arguments appended to the kernel command line of the image before it is flashed, e.g. a config server URL or " +
+					"console settings. {node} is replaced with the slot number. The image must be uncompressed, with cmdline.txt, " +
+					"extlinux.conf or armbianEnv.txt on a FAT boot partition. The image file itself is not changed.",
+				Elem: &schema.Schema{Type: schema.TypeString},
+			},
+			"first_boot": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				MaxItems: 1,
+				Description: "Files written to the cloud-init NoCloud seed on the boot partition of the image before it is flashed, " +
+					"so a node can be reached over SSH on first boot. {node} is replaced with the slot number. The image must be " +
+					"uncompressed, with user-data (and network-config, for network_config) on a FAT partition, as in the Ubuntu images " +
+					"for the CM4 and RK1. The image file itself is not changed.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"hostname": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ForceNew:     true,
+							Description:  "Hostname of the node, e.g. turing-{node}",
+							AtLeastOneOf: firstBootArguments,
+						},
+						"ssh_authorized_keys": {
+							Type:         schema.TypeList,
+							Optional:     true,
+							ForceNew:     true,
+							Description:  "Public keys authorized for the default user of the image",
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							AtLeastOneOf: firstBootArguments,
+						},
+						"network_config": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ForceNew:     true,
+							Description:  "Netplan configuration (network config version 2) written to network-config, e.g. a static address",
+							AtLeastOneOf: firstBootArguments,
+						},
+					},
+				},
+			},
+			"verify": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Description: "Read each node back after it is flashed and compare the SHA256 of its storage with the image. The node is put in " +
+					"MSD mode and its storage hashed on the BMC over SSH, which takes about as long as the flash. A mismatch fails the node, " +
+					"so the next apply flashes it again.",
+				Elem: flashVerifySchema(),
+			},
+			"expand_rootfs": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Description: "Power each node on after it is flashed and, once it accepts SSH connections, grow its root partition and " +
+					"filesystem to fill the disk. A node whose expansion fails is marked failed, so the next apply flashes it again.",
+				Elem: flashExpandRootfsSchema(),
+			},
+			"force_cancel_existing": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Cancel a transfer left on the BMC by an interrupted flash or firmware upload before flashing (default: false). Without it, a stale transfer blocks the flash until the BMC is rebooted.",
+			},
+			"node_status": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Flash status of each node (node1-node4): pending, flashed or failed",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"node_progress": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Percentage of the image written to each node (node1-node4) by its last flash",
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+			},
+			"node_checksum": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "SHA256 read back from each node (node1-node4) by verify, matching the image as written",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"verified": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether every node was read back with verify and matched the image. False without verify.",
+			},
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(30 * time.Minute),
@@ -41,6 +153,26 @@ func resourceFlash() *schema.Resource {
 	}
 }
 
+// firstBootArguments are the arguments of the first_boot block, one of
+// which must be set
+var firstBootArguments = []string{"first_boot.0.hostname", "first_boot.0.ssh_authorized_keys", "first_boot.0.network_config"}
+
+// Status values of node_status
+const (
+	flashStatusPending = "pending"
+	flashStatusFlashed = "flashed"
+	flashStatusFailed  = "failed"
+)
+
+// Delays of the flash sequence. Replaced in tests to keep them fast.
+var (
+	flashPowerOffDelay = 2 * time.Second
+	flashPollInterval  = 5 * time.Second
+)
+
+// flashTimeout bounds the wait for the BMC to write an uploaded image
+const flashTimeout = 25 * time.Minute
+
 // flashResponse represents the BMC flash initiation response
 type flashResponse struct {
 	Handle interface{} `json:"handle"` // Can be string or number
@@ -77,13 +209,13 @@ func (f *flashStatusResponse) isTransferring() (inProgress bool, bytesWritten, t
 
 	// Try new object format first (BMC firmware 2.0.5+)
 	var newFormat transferringStatus
-	if err := json.Unmarshal(f.Transferring, &newFormat); err == nil {
+	if err := json.Unmarshal(f.Transferring, &newFormat); err == nil && parseV2Responses() {
 		return true, newFormat.BytesWritten, newFormat.Size
 	}
 
 	// Try old array format (legacy BMC firmware)
 	var oldFormat []int64
-	if err := json.Unmarshal(f.Transferring, &oldFormat); err == nil && len(oldFormat) >= 2 {
+	if err := json.Unmarshal(f.Transferring, &oldFormat); err == nil && len(oldFormat) >= 2 && parseLegacyResponses() {
 		return true, oldFormat[0], oldFormat[1]
 	}
 
@@ -91,11 +223,361 @@ func (f *flashStatusResponse) isTransferring() (inProgress bool, bytesWritten, t
 	return true, 0, 0
 }
 
-func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
+func resourceFlashCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	nodes, err := flashTargets(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	if _, ok := d.GetOk("nodes"); !ok {
+		if err := flashNodes(ctx, d, config, nodes); err != nil {
+			return diagFromErr(err)
+		}
+		d.SetId(fmt.Sprintf("flash-node-%d", nodes[0]))
+		return nil
+	}
+
+	// Set the ID first, so the status of each slot is kept in state when one
+	// fails and the next apply retries only the failed slots
+	d.SetId(flashNodesID(nodes))
+	err = flashNodes(ctx, d, config, nodes)
+	if err == nil || !anyFlashed(d, nodes) {
+		return diagFromErr(err)
+	}
+	// An error would taint the resource, and replacing it would flash the
+	// slots that succeeded again
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  notFlashedSummary(unflashedNodes(d, nodes)),
+		Detail: err.Error() + "\n\nThe resource was created so that the nodes that were flashed are not flashed again. " +
+			"node_status shows the result of each node. The next plan shows an update that flashes only the nodes that were not flashed, " +
+			"and that apply fails if they fail again.",
+	}}
+}
+
+// unflashedNodes returns the slots in nodes that were not flashed
+func unflashedNodes(d *schema.ResourceData, nodes []int) []int {
+	status := d.Get("node_status").(map[string]interface{})
+	var unflashed []int
+	for _, node := range nodes {
+		if status[flashNodeKey(node)] != flashStatusFlashed {
+			unflashed = append(unflashed, node)
+		}
+	}
+	return unflashed
+}
+
+// notFlashedSummary names the slots that were not flashed, e.g. "Nodes 2, 4
+// were not flashed"
+func notFlashedSummary(nodes []int) string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = strconv.Itoa(node)
+	}
+	if len(ids) == 1 {
+		return "Node " + ids[0] + " was not flashed"
+	}
+	return "Nodes " + strings.Join(ids, ", ") + " were not flashed"
+}
+
+// anyFlashed reports whether a slot in nodes was flashed
+func anyFlashed(d *schema.ResourceData, nodes []int) bool {
+	status := d.Get("node_status").(map[string]interface{})
+	for _, node := range nodes {
+		if status[flashNodeKey(node)] == flashStatusFlashed {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceFlashUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*ProviderConfig)
-	node := d.Get("node").(int)
+
+	nodes, err := flashTargets(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	// Forget slots removed from nodes; the firmware stays on them
+	status := d.Get("node_status").(map[string]interface{})
+	progress := d.Get("node_progress").(map[string]interface{})
+	checksums := d.Get("node_checksum").(map[string]interface{})
+	keep := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		keep[flashNodeKey(node)] = true
+	}
+	for key := range status {
+		if !keep[key] {
+			delete(status, key)
+			delete(progress, key)
+			delete(checksums, key)
+		}
+	}
+	if err := setFlashNodeState(d, status, progress); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("node_checksum", checksums); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set node_checksum: %w", err))
+	}
+
+	// As in create, the ID follows nodes even when a slot fails to flash
+	d.SetId(flashNodesID(nodes))
+	return diagFromErr(flashNodes(ctx, d, config, nodes))
+}
+
+// flashTargets returns the slots to flash from node or nodes
+func flashTargets(d *schema.ResourceData) ([]int, error) {
+	v, ok := d.GetOk("nodes")
+	if !ok {
+		return []int{d.Get("node").(int)}, nil
+	}
+
+	seen := make(map[int]bool)
+	var nodes []int
+	for _, n := range v.([]interface{}) {
+		node := n.(int)
+		if seen[node] {
+			return nil, fmt.Errorf("node %d is listed more than once in nodes", node)
+		}
+		seen[node] = true
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func flashNodesID(nodes []int) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		parts = append(parts, strconv.Itoa(node))
+	}
+	return "flash-nodes-" + strings.Join(parts, "-")
+}
+
+func flashNodeKey(node int) string {
+	return fmt.Sprintf("node%d", node)
+}
+
+// flashNodes flashes firmware_file to each slot in turn, skipping slots already
+// flashed. The BMC runs one flash at a time, so slots are never flashed in
+// parallel. node_status and node_progress are updated after every slot, and
+// a failed slot does not stop the others. An interruption stops the remaining
+// slots, which stay pending. With verify, a slot only counts as flashed once
+// its readback matches the image, and with expand_rootfs once its root
+// filesystem was grown.
+func flashNodes(ctx context.Context, d *schema.ResourceData, config *ProviderConfig, nodes []int) error {
 	firmwarePath := d.Get("firmware_file").(string)
+	forceCancel := d.Get("force_cancel_existing").(bool)
+	kernelArgs := expandStringList(d.Get("kernel_args").([]interface{}))
+	firstBoot := expandFirstBoot(d.Get("first_boot").([]interface{}))
+	verify, err := expandFlashVerify(d.Get("verify").([]interface{}), config.Endpoint)
+	if err != nil {
+		return err
+	}
+	expand, err := expandFlashExpandRootfs(d.Get("expand_rootfs").([]interface{}))
+	if err != nil {
+		return err
+	}
+	status := d.Get("node_status").(map[string]interface{})
+	progress := d.Get("node_progress").(map[string]interface{})
+	checksums := d.Get("node_checksum").(map[string]interface{})
+
+	for _, node := range nodes {
+		if _, ok := status[flashNodeKey(node)]; !ok {
+			status[flashNodeKey(node)] = flashStatusPending
+			progress[flashNodeKey(node)] = 0
+		}
+	}
+
+	var failed []string
+	for _, node := range nodes {
+		key := flashNodeKey(node)
+		if status[key] == flashStatusFlashed {
+			continue
+		}
+		if ctx.Err() != nil {
+			failed = append(failed, fmt.Sprintf("node %d: not flashed: %v", node, ctx.Err()))
+			continue
+		}
+
+		var pct float64
+		delete(checksums, key)
+		err := config.fleet.run(ctx, fmt.Sprintf("flash of node %d", node), config.Endpoint, func() error {
+			patches, err := flashKernelArgsPatches(ctx, firmwarePath, kernelArgs, node)
+			if err != nil {
+				return err
+			}
+			firstBootPatches, err := flashFirstBootPatches(ctx, firmwarePath, firstBoot, node)
+			if err != nil {
+				return err
+			}
+			patches = append(patches, firstBootPatches...)
+			if forceCancel {
+				if err := cancelStaleTransfer(ctx, config.Endpoint, config.Token); err != nil {
+					return err
+				}
+			}
+			if err := flashFirmware(ctx, config, node, firmwarePath, patches, &pct); err != nil || verify == nil {
+				return err
+			}
+			sum, err := verifyFlash(ctx, config, verify, node, firmwarePath, patches)
+			if err != nil {
+				return err
+			}
+			checksums[key] = sum
+			return nil
+		})
+		// The node boots on its own, so the BMC is not held while waiting for it
+		if err == nil && expand != nil {
+			err = expandFlashedRootfs(ctx, config, expand, node)
+		}
+		if err != nil {
+			status[key] = flashStatusFailed
+			progress[key] = int(pct)
+			failed = append(failed, fmt.Sprintf("node %d: %v", node, err))
+		} else {
+			status[key] = flashStatusFlashed
+			progress[key] = 100
+		}
+		if err := setFlashNodeState(d, status, progress); err != nil {
+			return err
+		}
+		if err := setFlashVerified(d, nodes, checksums, verify != nil); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to flash %d of %d nodes:\n%s", len(failed), len(nodes), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+func setFlashNodeState(d *schema.ResourceData, status, progress map[string]interface{}) error {
+	if err := d.Set("node_status", status); err != nil {
+		return fmt.Errorf("failed to set node_status: %w", err)
+	}
+	if err := d.Set("node_progress", progress); err != nil {
+		return fmt.Errorf("failed to set node_progress: %w", err)
+	}
+	return nil
+}
+
+// setFlashVerified sets node_checksum, and verified when every slot in nodes
+// was read back
+func setFlashVerified(d *schema.ResourceData, nodes []int, checksums map[string]interface{}, verify bool) error {
+	if err := d.Set("node_checksum", checksums); err != nil {
+		return fmt.Errorf("failed to set node_checksum: %w", err)
+	}
+	verified := verify
+	for _, node := range nodes {
+		if sum, _ := checksums[flashNodeKey(node)].(string); sum == "" {
+			verified = false
+		}
+	}
+	if err := d.Set("verified", verified); err != nil {
+		return fmt.Errorf("failed to set verified: %w", err)
+	}
+	return nil
+}
+
+// resourceFlashCustomizeDiff plans an update when a listed slot was not
+// flashed, so the next apply retries only those slots
+func resourceFlashCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	status := d.Get("node_status").(map[string]interface{})
+	for _, n := range d.Get("nodes").([]interface{}) {
+		if status[flashNodeKey(n.(int))] != flashStatusFlashed {
+			for _, key := range []string{"node_status", "node_progress", "node_checksum", "verified"} {
+				if err := d.SetNewComputed(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// flashKernelArgsPatches returns the patches adding kernel_args to the boot
+// partition of the image for node, or nil when kernel_args is empty
+func flashKernelArgsPatches(ctx context.Context, firmwarePath string, args []string, node int) ([]imagePatch, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	file, err := os.Open(firmwarePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open firmware file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
 
+	args = kernelArgsForNode(args, node)
+	bootFile, patches, err := kernelArgsPatches(file, args)
+	if err != nil {
+		return nil, fmt.Errorf("kernel_args: %w", err)
+	}
+	tflog.Info(ctx, "Appending kernel arguments to the image", map[string]interface{}{
+		"node":      node,
+		"boot_file": bootFile,
+		"args":      strings.Join(args, " "),
+	})
+	return patches, nil
+}
+
+// expandFirstBoot returns the first_boot block, or nil when it is not set
+func expandFirstBoot(v []interface{}) *firstBootConfig {
+	if len(v) == 0 || v[0] == nil {
+		return nil
+	}
+	m := v[0].(map[string]interface{})
+	return &firstBootConfig{
+		Hostname:          m["hostname"].(string),
+		SSHAuthorizedKeys: expandStringList(m["ssh_authorized_keys"].([]interface{})),
+		NetworkConfig:     m["network_config"].(string),
+	}
+}
+
+// flashFirstBootPatches returns the patches writing the first_boot files to
+// the image for node, or nil when first_boot is not set
+func flashFirstBootPatches(ctx context.Context, firmwarePath string, firstBoot *firstBootConfig, node int) ([]imagePatch, error) {
+	if firstBoot == nil {
+		return nil, nil
+	}
+	files, err := firstBoot.forNode(node).files()
+	if err != nil {
+		return nil, fmt.Errorf("first_boot: %w", err)
+	}
+	file, err := os.Open(firmwarePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open firmware file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	patches, err := firstBootPatches(file, files)
+	if err != nil {
+		return nil, fmt.Errorf("first_boot: %w", err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tflog.Info(ctx, "Writing first boot files to the image", map[string]interface{}{
+		"node":  node,
+		"files": strings.Join(names, ", "),
+	})
+	return patches, nil
+}
+
+// flashFirmware streams firmwarePath to the node, with patches applied, and
+// waits for the flash to finish. progress receives the last reported percentage. When the upload
+// fails or ctx is cancelled before the flash is done, the upload handle is
+// released so it does not block the next flash.
+func flashFirmware(ctx context.Context, config *ProviderConfig, node int, firmwarePath string, patches []imagePatch, progress *float64) (err error) {
 	// Open the firmware file
 	file, err := os.Open(firmwarePath)
 	if err != nil {
@@ -115,7 +597,10 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 	if err := setNodePower(config.Endpoint, config.Token, node, false); err != nil {
 		return fmt.Errorf("failed to power off node before flash: %w", err)
 	}
-	time.Sleep(2 * time.Second) // Wait for node to power off
+	// Wait for node to power off
+	if err := sleepContext(ctx, flashPowerOffDelay); err != nil {
+		return fmt.Errorf("flash of node %d interrupted: %w", node, err)
+	}
 
 	// Step 2: Initiate flash operation
 	// API uses 0-indexed nodes
@@ -123,7 +608,7 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 	apiNode := node - 1
 	url := fmt.Sprintf("%s/api/bmc?opt=set&type=flash&node=%d&file=stream&length=%d", config.Endpoint, apiNode, fileSize)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create flash request: %w", err)
 	}
@@ -137,12 +622,12 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("flash initiation failed with status %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("flash initiation failed with status %d: %s%s", resp.StatusCode, string(body), staleTransferHint(config.Endpoint, config.Token))
 	}
 
 	var flashResp flashResponse
-	if err := json.NewDecoder(resp.Body).Decode(&flashResp); err != nil {
-		return fmt.Errorf("failed to decode flash response: %w", err)
+	if err := decodeBMCResponse(resp, config.Endpoint, config.Token, &flashResp); err != nil {
+		return err
 	}
 
 	if flashResp.Handle == nil {
@@ -162,6 +647,13 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 
 	fmt.Printf("Got upload handle: %s\n", handleStr)
 
+	done := false
+	defer func() {
+		if err != nil && !done {
+			releaseUploadHandle(config.Endpoint, config.Token, handleStr)
+		}
+	}()
+
 	// Step 3: Upload the firmware file using multipart form
 	uploadURL := fmt.Sprintf("%s/api/bmc/upload/%s", config.Endpoint, handleStr)
 
@@ -189,7 +681,7 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 		}
 		defer func() { _ = uploadFile.Close() }()
 
-		if _, err := io.Copy(part, uploadFile); err != nil {
+		if _, err := io.Copy(part, newPatchedReader(uploadFile, patches)); err != nil {
 			errChan <- fmt.Errorf("failed to copy firmware data: %w", err)
 			return
 		}
@@ -197,7 +689,7 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 		errChan <- nil
 	}()
 
-	uploadReq, err := http.NewRequest("POST", uploadURL, pr)
+	uploadReq, err := http.NewRequestWithContext(ctx, "POST", uploadURL, pr)
 	if err != nil {
 		return fmt.Errorf("failed to create upload request: %w", err)
 	}
@@ -207,6 +699,11 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 	fmt.Printf("Uploading firmware to BMC (%d bytes)...\n", fileSize)
 	uploadResp, err := HTTPClient.Do(uploadReq)
 	if err != nil {
+		_ = pr.CloseWithError(err)
+		<-errChan
+		if ctx.Err() != nil {
+			return fmt.Errorf("flash of node %d interrupted during upload: %w", node, ctx.Err())
+		}
 		return fmt.Errorf("firmware upload failed: %w", err)
 	}
 	defer func() { _ = uploadResp.Body.Close() }()
@@ -224,46 +721,50 @@ func resourceFlashCreate(d *schema.ResourceData, meta interface{}) error {
 	fmt.Printf("Upload complete, waiting for flash to finish...\n")
 
 	// Step 4: Poll flash status until complete
-	timeout := time.After(25 * time.Minute)
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-timeout:
-			return fmt.Errorf("flash operation timed out")
-		case <-ticker.C:
-			status, err := getFlashStatus(config.Endpoint, config.Token)
-			if err != nil {
-				fmt.Printf("Warning: failed to get flash status: %v\n", err)
-				continue
-			}
+	err = poll(ctx, flashPollInterval, flashTimeout, "flash of node "+strconv.Itoa(node), func(context.Context) (bool, error) {
+		status, err := getFlashStatus(config.Endpoint, config.Token)
+		if err != nil {
+			fmt.Printf("Warning: failed to get flash status: %v\n", err)
+			return false, err
+		}
 
-			if status.Error != nil {
-				return fmt.Errorf("flash failed: %s", *status.Error)
-			}
+		if status.Error != nil {
+			return false, waitutil.Permanent(fmt.Errorf("flash failed: %s", *status.Error))
+		}
 
-			if status.Done != nil {
-				fmt.Printf("Flash completed successfully\n")
-				d.SetId(fmt.Sprintf("flash-node-%d", node))
-				return nil
-			}
+		if status.Done != nil {
+			done = true
+			fmt.Printf("Flash completed successfully\n")
+			*progress = 100
+			return true, nil
+		}
 
-			if status.Flashing != nil {
-				pct := float64(status.Flashing.BytesWritten) / float64(status.Flashing.TotalBytes) * 100
-				fmt.Printf("Flashing: %.1f%% (%d/%d bytes)\n", pct, status.Flashing.BytesWritten, status.Flashing.TotalBytes)
-			}
+		if status.Flashing != nil {
+			pct := float64(status.Flashing.BytesWritten) / float64(status.Flashing.TotalBytes) * 100
+			*progress = pct
+			fmt.Printf("Flashing: %.1f%% (%d/%d bytes)\n", pct, status.Flashing.BytesWritten, status.Flashing.TotalBytes)
+		}
 
-			if inProgress, bytesWritten, totalBytes := status.isTransferring(); inProgress {
-				if totalBytes > 0 {
-					pct := float64(bytesWritten) / float64(totalBytes) * 100
-					fmt.Printf("Transferring: %.1f%% (%d/%d bytes)\n", pct, bytesWritten, totalBytes)
-				} else {
-					fmt.Printf("Transferring...\n")
-				}
+		if inProgress, bytesWritten, totalBytes := status.isTransferring(); inProgress {
+			if totalBytes > 0 {
+				pct := float64(bytesWritten) / float64(totalBytes) * 100
+				fmt.Printf("Transferring: %.1f%% (%d/%d bytes)\n", pct, bytesWritten, totalBytes)
+			} else {
+				fmt.Printf("Transferring...\n")
 			}
 		}
+		return false, nil
+	})
+	var timeoutErr *waitutil.TimeoutError
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return fmt.Errorf("flash of node %d interrupted: %w", node, ctx.Err())
+	case errors.As(err, &timeoutErr):
+		return fmt.Errorf("flash operation timed out: %w", err)
 	}
+	return err
 }
 
 func getFlashStatus(endpoint, token string) (*flashStatusResponse, error) {
@@ -287,24 +788,39 @@ func getFlashStatus(endpoint, token string) (*flashStatusResponse, error) {
 	}
 
 	var status flashStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &status); err != nil {
+		return nil, err
 	}
 
 	return &status, nil
 }
 
-func resourceFlashRead(d *schema.ResourceData, meta interface{}) error {
+func resourceFlashRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	// Flash is a one-time operation - once completed, we just maintain state
 	// The resource exists if it was successfully flashed
 	id := d.Id()
-	if id == "" || !strings.HasPrefix(id, "flash-node-") {
+	if id == "" || !strings.HasPrefix(id, "flash-node") {
 		d.SetId("")
+		return nil
+	}
+
+	// Slots that failed on create are reported on every plan until an apply
+	// flashes them
+	var nodes []int
+	for _, n := range d.Get("nodes").([]interface{}) {
+		nodes = append(nodes, n.(int))
+	}
+	if unflashed := unflashedNodes(d, nodes); len(unflashed) > 0 {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  notFlashedSummary(unflashed),
+			Detail:   "node_status shows the result of each node. The next apply flashes only the nodes that were not flashed.",
+		}}
 	}
 	return nil
 }
 
-func resourceFlashDelete(d *schema.ResourceData, meta interface{}) error {
+func resourceFlashDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	// Flash cannot be "undone" - we just remove from state
 	// The node retains its flashed firmware
 	fmt.Printf("Removing flash resource from state (firmware remains on node)\n")
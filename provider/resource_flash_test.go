@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -38,7 +39,10 @@ func TestResourceFlash_SchemaTypes(t *testing.T) {
 		expected schema.ValueType
 	}{
 		{"node", schema.TypeInt},
+		{"nodes", schema.TypeList},
 		{"firmware_file", schema.TypeString},
+		{"node_status", schema.TypeMap},
+		{"node_progress", schema.TypeMap},
 	}
 
 	for _, tt := range tests {
@@ -53,8 +57,8 @@ func TestResourceFlash_SchemaTypes(t *testing.T) {
 func TestResourceFlash_RequiredFields(t *testing.T) {
 	r := resourceFlash()
 
-	if !r.Schema["node"].Required {
-		t.Error("node should be required")
+	if !r.Schema["node"].Optional || !r.Schema["nodes"].Optional {
+		t.Error("node and nodes should be optional")
 	}
 
 	if !r.Schema["firmware_file"].Required {
@@ -77,24 +81,20 @@ func TestResourceFlash_ForceNewFields(t *testing.T) {
 func TestResourceFlash_HasCRUDFunctions(t *testing.T) {
 	r := resourceFlash()
 
-	//nolint:staticcheck // SA1019: intentionally testing deprecated Create field
-	if r.Create == nil {
+	if r.CreateContext == nil {
 		t.Error("resource should have Create function")
 	}
 
-	//nolint:staticcheck // SA1019: intentionally testing deprecated Read field
-	if r.Read == nil {
+	if r.ReadContext == nil {
 		t.Error("resource should have Read function")
 	}
 
-	// Flash resource should NOT have Update (uses ForceNew instead)
-	//nolint:staticcheck // SA1019: intentionally testing deprecated Update field
-	if r.Update != nil {
-		t.Error("resource should NOT have Update function (uses ForceNew)")
+	// Update flashes nodes added to nodes and retries failed ones
+	if r.UpdateContext == nil {
+		t.Error("resource should have Update function")
 	}
 
-	//nolint:staticcheck // SA1019: intentionally testing deprecated Delete field
-	if r.Delete == nil {
+	if r.DeleteContext == nil {
 		t.Error("resource should have Delete function")
 	}
 }
@@ -111,12 +111,12 @@ func TestResourceFlashCreate_FileNotFound(t *testing.T) {
 		Token:    "test-token",
 	}
 
-	err := resourceFlashCreate(d, config)
-	if err == nil {
+	diags := resourceFlashCreate(context.Background(), d, config)
+	if !diags.HasError() {
 		t.Fatal("expected error for non-existent file")
 	}
-	if !strings.Contains(err.Error(), "failed to open firmware file") {
-		t.Errorf("expected file open error, got: %s", err)
+	if !strings.Contains(diags[0].Summary, "failed to open firmware file") {
+		t.Errorf("expected file open error, got: %v", diags)
 	}
 }
 
@@ -175,9 +175,8 @@ func TestResourceFlashRead_DoesNotError(t *testing.T) {
 	d := r.TestResourceData()
 	d.SetId("node-1")
 
-	err := resourceFlashRead(d, nil)
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+	if diags := resourceFlashRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
 	}
 }
 
@@ -186,9 +185,8 @@ func TestResourceFlashDelete_DoesNotError(t *testing.T) {
 	d := r.TestResourceData()
 	d.SetId("node-1")
 
-	err := resourceFlashDelete(d, nil)
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+	if diags := resourceFlashDelete(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
 	}
 }
 
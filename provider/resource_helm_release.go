@@ -0,0 +1,466 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/helm"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// newHelmReleaseClient creates the Helm client of turingpi_helm_release.
+// Replaced in tests.
+var newHelmReleaseClient = func(kubeconfig []byte, namespace string, opts HelmClientOptions) (HelmClient, error) {
+	return NewHelmClientFromBytesWithOptions(kubeconfig, namespace, opts)
+}
+
+// helmReleaseDiffer renders charts and compares them with live objects
+type helmReleaseDiffer interface {
+	AddRepository(name, url string) error
+	helm.Differ
+}
+
+// newHelmReleaseDiffer creates the client detect_drift compares releases
+// with. Replaced in tests.
+var newHelmReleaseDiffer = func(kubeconfig []byte, namespace string, opts HelmClientOptions) (helmReleaseDiffer, error) {
+	restConfig, err := helmRESTConfig(kubeconfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	client, err := helm.NewClientFromRESTConfig(restConfig, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return client.(*helm.RealClient), nil
+}
+
+func resourceHelmRelease() *schema.Resource {
+	return &schema.Resource{
+		Description: "Deploys a Helm chart to a cluster, e.g. an addon that the cluster resources do not install. " +
+			"The deployed values are stored normalized in state, so plans list the changed keys instead of an opaque values change.",
+		CreateContext: resourceHelmReleaseCreate,
+		ReadContext:   resourceHelmReleaseRead,
+		UpdateContext: resourceHelmReleaseUpdate,
+		DeleteContext: resourceHelmReleaseDelete,
+		CustomizeDiff: resourceHelmReleaseCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"kubeconfig": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Kubeconfig content of the target cluster, e.g. turingpi_k3s_cluster.kubeconfig",
+			},
+			"kube_context": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Kubeconfig context to use (default: the current context of kubeconfig)",
+			},
+			"api_server": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "API server URL to use instead of the one in kubeconfig, e.g. a load balancer in front of the control plane",
+			},
+			"insecure_skip_tls_verify": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Skip verification of the API server certificate, e.g. for a freshly bootstrapped cluster whose certificate does not yet cover api_server (default: false)",
+			},
+			"qps": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				Description:  "Client-side rate limit of Kubernetes API requests per second (default: the client-go default)",
+				ValidateFunc: validation.FloatAtLeast(0),
+			},
+			"burst": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Description:  "Client-side burst limit of Kubernetes API requests (default: the client-go default)",
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Release name",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "default",
+				ForceNew:    true,
+				Description: "Namespace of the release (default: default)",
+			},
+			"chart": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Chart reference, e.g. \"metallb/metallb\" with repository_url, an OCI reference or a local path",
+			},
+			"repository_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "URL of the chart repository, added under the name before the \"/\" in chart",
+			},
+			"version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Chart version (default: latest)",
+			},
+			"values": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Description:      "Chart values as a YAML document. Changes in formatting, key order or comments are ignored.",
+				ValidateFunc:     validateHelmValues,
+				DiffSuppressFunc: suppressEquivalentHelmValues,
+			},
+			"image_registry_mirror": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Registry host (and optional path) to pull the chart images from instead of their upstream registry, for air-gapped clusters " +
+					"(e.g., 'registry.lan:5000'). Supported for the cert-manager, metallb and ingress-nginx charts; images keep their repository path, " +
+					"e.g. quay.io/jetstack/cert-manager-controller becomes <mirror>/jetstack/cert-manager-controller. Image keys set in values take precedence.",
+				ValidateFunc: imageRegistryMirrorSchema().ValidateFunc,
+			},
+			"create_namespace": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Create the namespace if it does not exist (default: true)",
+			},
+			"wait": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Wait for the release resources to be ready (default: true)",
+			},
+			"atomic": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Roll back the release if the install or upgrade fails (default: false)",
+			},
+			"timeout": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     300,
+				Description: "Timeout in seconds for the install or upgrade (default: 300)",
+			},
+			"detect_drift": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Render the chart on every refresh and compare it with the live objects of the release, so objects " +
+					"edited or deleted outside Helm are reported in drift and restored by the next apply (default: false)",
+			},
+			// Computed attributes
+			"normalized_values": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The deployed values with sorted keys. Plans show changes to it line by line.",
+			},
+			"values_hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA-256 of normalized_values",
+			},
+			"values_diff": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The value keys changed by the last planned change, one per line, e.g. \"~ controller.replicaCount: 1 -> 2\"",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"revision": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Revision of the release",
+			},
+			"status": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Status of the release, e.g. deployed",
+			},
+			"drift": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Description: "With detect_drift, the release objects that differ from the rendered chart, one change per line, " +
+					"e.g. \"~ Deployment metallb-system/controller spec.replicas: 1 -> 3\". Only fields set by the chart are compared.",
+				Elem: &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func validateHelmValues(v interface{}, k string) ([]string, []error) {
+	if _, err := normalizeHelmValuesYAML(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+// suppressEquivalentHelmValues ignores values changes that normalize to the
+// same document
+func suppressEquivalentHelmValues(k, oldValue, newValue string, d *schema.ResourceData) bool {
+	oldNormalized, err := normalizeHelmValuesYAML(oldValue)
+	if err != nil {
+		return false
+	}
+	newNormalized, err := normalizeHelmValuesYAML(newValue)
+	if err != nil {
+		return false
+	}
+	return oldNormalized == newNormalized
+}
+
+// resourceHelmReleaseCustomizeDiff plans normalized_values, values_hash and
+// values_diff when the configured values differ from the deployed ones, which
+// also catches values changed outside Terraform. Drift found in the live
+// objects plans an upgrade, which restores them.
+func resourceHelmReleaseCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() != "" && d.Get("detect_drift").(bool) && len(d.Get("drift").([]interface{})) > 0 {
+		if err := d.SetNewComputed("drift"); err != nil {
+			return err
+		}
+	}
+
+	if !d.NewValueKnown("values") || !d.NewValueKnown("chart") || !d.NewValueKnown("image_registry_mirror") {
+		for _, key := range []string{"normalized_values", "values_hash", "values_diff"} {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	normalized, err := helmReleaseValues(d.Get("chart").(string), d.Get("image_registry_mirror").(string), d.Get("values").(string))
+	if err != nil {
+		return err
+	}
+	deployed := d.Get("normalized_values").(string)
+	if d.Id() != "" && normalized == deployed {
+		return nil
+	}
+
+	changes, err := diffHelmValues(deployed, normalized)
+	if err != nil {
+		return err
+	}
+	if err := d.SetNew("normalized_values", normalized); err != nil {
+		return err
+	}
+	if err := d.SetNew("values_hash", helmValuesHash(normalized)); err != nil {
+		return err
+	}
+	return d.SetNew("values_diff", changes)
+}
+
+func resourceHelmReleaseCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if err := installHelmRelease(ctx, d); err != nil {
+		return diagFromErr(err)
+	}
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("namespace").(string), d.Get("name").(string)))
+	return resourceHelmReleaseRead(ctx, d, meta)
+}
+
+func resourceHelmReleaseRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := helmReleaseClient(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	rel, err := client.GetRelease(d.Get("name").(string))
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		tflog.Warn(ctx, "Helm release not found, removing from state", map[string]interface{}{
+			"name": d.Get("name").(string),
+		})
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	normalized, err := normalizeHelmValues(rel.Config)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("normalized_values", normalized); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("values_hash", helmValuesHash(normalized)); err != nil {
+		return diagFromErr(err)
+	}
+	if err := d.Set("revision", rel.Version); err != nil {
+		return diagFromErr(err)
+	}
+	if rel.Info != nil {
+		if err := d.Set("status", rel.Info.Status.String()); err != nil {
+			return diagFromErr(err)
+		}
+	}
+	if !d.Get("detect_drift").(bool) {
+		return diagFromErr(d.Set("drift", nil))
+	}
+	return refreshHelmReleaseDrift(ctx, d)
+}
+
+// refreshHelmReleaseDrift sets drift to the differences between the rendered
+// chart and the live objects. The chart may be unreachable during a refresh,
+// so failures are warnings and keep the known drift.
+func refreshHelmReleaseDrift(ctx context.Context, d *schema.ResourceData) diag.Diagnostics {
+	diff, err := diffHelmRelease(ctx, d)
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Could not check Helm release " + d.Get("name").(string) + " for drift",
+			Detail:   err.Error(),
+		}}
+	}
+	if diff.HasDrift() {
+		tflog.Warn(ctx, "Helm release objects differ from the chart", map[string]interface{}{
+			"name":  d.Get("name").(string),
+			"drift": strings.Join(diff.Lines(), "; "),
+		})
+	}
+	lines := diff.Lines()
+	if lines == nil {
+		lines = []string{}
+	}
+	return diagFromErr(d.Set("drift", lines))
+}
+
+func diffHelmRelease(ctx context.Context, d *schema.ResourceData) (*helm.ReleaseDiff, error) {
+	client, err := newHelmReleaseDiffer([]byte(d.Get("kubeconfig").(string)), d.Get("namespace").(string), helmReleaseClientOptions(d))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Helm client: %w", err)
+	}
+	if err := addHelmReleaseRepository(client, d); err != nil {
+		return nil, err
+	}
+	values, err := helmReleaseValues(d.Get("chart").(string), d.Get("image_registry_mirror").(string), d.Get("values").(string))
+	if err != nil {
+		return nil, err
+	}
+	return client.DiffRelease(ctx, &helm.ChartSpec{
+		ReleaseName: d.Get("name").(string),
+		ChartName:   d.Get("chart").(string),
+		Namespace:   d.Get("namespace").(string),
+		Version:     d.Get("version").(string),
+		ValuesYaml:  values,
+	})
+}
+
+func resourceHelmReleaseUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if err := installHelmRelease(ctx, d); err != nil {
+		return diagFromErr(err)
+	}
+	return resourceHelmReleaseRead(ctx, d, meta)
+}
+
+func resourceHelmReleaseDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := helmReleaseClient(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	err = client.UninstallRelease(d.Get("name").(string))
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return diagFromErr(err)
+	}
+	d.SetId("")
+	return nil
+}
+
+// installHelmRelease installs or upgrades the release with the configured
+// chart and values
+func installHelmRelease(ctx context.Context, d *schema.ResourceData) error {
+	client, err := helmReleaseClient(d)
+	if err != nil {
+		return err
+	}
+
+	chart := d.Get("chart").(string)
+	if err := addHelmReleaseRepository(client, d); err != nil {
+		return err
+	}
+
+	values, err := helmReleaseValues(chart, d.Get("image_registry_mirror").(string), d.Get("values").(string))
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Installing Helm release", map[string]interface{}{
+		"name":  d.Get("name").(string),
+		"chart": chart,
+		"hash":  helmValuesHash(values),
+	})
+	_, err = client.InstallOrUpgradeChart(ctx, &ChartSpec{
+		ReleaseName:     d.Get("name").(string),
+		ChartName:       chart,
+		Namespace:       d.Get("namespace").(string),
+		Version:         d.Get("version").(string),
+		ValuesYaml:      values,
+		CreateNamespace: d.Get("create_namespace").(bool),
+		Wait:            d.Get("wait").(bool),
+		Timeout:         time.Duration(d.Get("timeout").(int)) * time.Second,
+		Atomic:          d.Get("atomic").(bool),
+	})
+	return err
+}
+
+// helmReleaseValues returns the normalized values a release is deployed with:
+// the configured values document over the image values of mirror, if set
+func helmReleaseValues(chart, mirror, doc string) (string, error) {
+	if mirror == "" {
+		return normalizeHelmValuesYAML(doc)
+	}
+	images, err := mirroredChartImageValues(chart, mirror)
+	if err != nil {
+		return "", err
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &values); err != nil {
+		return "", fmt.Errorf("failed to parse values: %w", err)
+	}
+	return normalizeHelmValues(mergeHelmValues(images, values))
+}
+
+// addHelmReleaseRepository adds repository_url, when set, under the name
+// before the "/" in chart
+func addHelmReleaseRepository(client interface{ AddRepository(name, url string) error }, d *schema.ResourceData) error {
+	url := d.Get("repository_url").(string)
+	if url == "" {
+		return nil
+	}
+	chart := d.Get("chart").(string)
+	repoName, _, ok := strings.Cut(chart, "/")
+	if !ok {
+		return fmt.Errorf("chart %q must be <repository>/<chart> when repository_url is set", chart)
+	}
+	return client.AddRepository(repoName, url)
+}
+
+// helmReleaseClientOptions returns the connection options of the release
+func helmReleaseClientOptions(d *schema.ResourceData) HelmClientOptions {
+	return HelmClientOptions{
+		KubeContext:           d.Get("kube_context").(string),
+		APIServer:             d.Get("api_server").(string),
+		QPS:                   float32(d.Get("qps").(float64)),
+		Burst:                 d.Get("burst").(int),
+		InsecureSkipTLSVerify: d.Get("insecure_skip_tls_verify").(bool),
+	}
+}
+
+func helmReleaseClient(d *schema.ResourceData) (HelmClient, error) {
+	client, err := newHelmReleaseClient([]byte(d.Get("kubeconfig").(string)), d.Get("namespace").(string), helmReleaseClientOptions(d))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Helm client: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,384 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfreed-dev/turingpi-terraform-provider/pkg/helm"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func useMockHelmReleaseClient(t *testing.T, mock *MockHelmClient) {
+	t.Helper()
+	orig := newHelmReleaseClient
+	newHelmReleaseClient = func(kubeconfig []byte, namespace string, opts HelmClientOptions) (HelmClient, error) {
+		return mock, nil
+	}
+	t.Cleanup(func() { newHelmReleaseClient = orig })
+}
+
+func TestResourceHelmRelease(t *testing.T) {
+	r := resourceHelmRelease()
+	if err := r.InternalValidate(nil, true); err != nil {
+		t.Fatalf("schema validation failed: %v", err)
+	}
+	for _, key := range []string{"normalized_values", "values_hash", "values_diff", "revision", "status"} {
+		if !r.Schema[key].Computed {
+			t.Errorf("%s should be computed", key)
+		}
+	}
+	if !r.Schema["kubeconfig"].Sensitive {
+		t.Error("kubeconfig should be sensitive")
+	}
+}
+
+func TestResourceHelmReleaseCreate(t *testing.T) {
+	mock := &MockHelmClient{
+		GetReleaseFunc: func(name string) (*release.Release, error) {
+			return &release.Release{
+				Name:    name,
+				Info:    &release.Info{Status: release.StatusDeployed},
+				Config:  map[string]interface{}{"controller": map[string]interface{}{"replicaCount": float64(2)}},
+				Version: 3,
+			}, nil
+		},
+	}
+	useMockHelmReleaseClient(t, mock)
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig":     "apiVersion: v1",
+		"name":           "ingress",
+		"namespace":      "ingress-nginx",
+		"chart":          "ingress-nginx/ingress-nginx",
+		"repository_url": "https://kubernetes.github.io/ingress-nginx",
+		"values":         "controller:\n    replicaCount:   2 # two\n",
+		"wait":           true,
+		"timeout":        60,
+	})
+
+	if diags := resourceHelmReleaseCreate(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if d.Id() != "ingress-nginx/ingress" {
+		t.Errorf("id = %q", d.Id())
+	}
+	if len(mock.AddRepositoryCalls) != 1 || mock.AddRepositoryCalls[0].Name != "ingress-nginx" {
+		t.Errorf("repository calls = %v", mock.AddRepositoryCalls)
+	}
+	if len(mock.InstallOrUpgradeCalls) != 1 {
+		t.Fatalf("expected 1 install, got %d", len(mock.InstallOrUpgradeCalls))
+	}
+	spec := mock.InstallOrUpgradeCalls[0]
+	if spec.ValuesYaml != "controller:\n    replicaCount: 2\n" {
+		t.Errorf("values = %q", spec.ValuesYaml)
+	}
+	if spec.Namespace != "ingress-nginx" || !spec.Wait {
+		t.Errorf("unexpected spec: %+v", spec)
+	}
+	if d.Get("normalized_values").(string) != spec.ValuesYaml {
+		t.Errorf("normalized_values = %q", d.Get("normalized_values"))
+	}
+	if d.Get("values_hash").(string) != helmValuesHash(spec.ValuesYaml) {
+		t.Errorf("values_hash = %q", d.Get("values_hash"))
+	}
+	if d.Get("revision").(int) != 3 || d.Get("status").(string) != "deployed" {
+		t.Errorf("revision = %v, status = %v", d.Get("revision"), d.Get("status"))
+	}
+}
+
+func TestResourceHelmReleaseCreate_ImageRegistryMirror(t *testing.T) {
+	mock := &MockHelmClient{}
+	useMockHelmReleaseClient(t, mock)
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig":            "apiVersion: v1",
+		"name":                  "cert-manager",
+		"namespace":             "cert-manager",
+		"chart":                 "jetstack/cert-manager",
+		"image_registry_mirror": "registry.lan:5000",
+		"values":                "webhook:\n  image:\n    repository: harbor.lan/webhook\n",
+	})
+	if err := installHelmRelease(context.Background(), d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := mock.InstallOrUpgradeCalls[0].ValuesYaml
+	if !strings.Contains(values, "repository: registry.lan:5000/jetstack/cert-manager-controller") {
+		t.Errorf("expected the controller image from the mirror, got:\n%s", values)
+	}
+	if !strings.Contains(values, "repository: harbor.lan/webhook") || strings.Contains(values, "cert-manager-webhook") {
+		t.Errorf("expected the configured webhook image to win, got:\n%s", values)
+	}
+
+	_ = d.Set("chart", "bitnami/redis")
+	if err := installHelmRelease(context.Background(), d); err == nil {
+		t.Error("expected an error for a chart without known image values")
+	}
+}
+
+func TestResourceHelmRelease_ClientOptions(t *testing.T) {
+	var got HelmClientOptions
+	orig := newHelmReleaseClient
+	newHelmReleaseClient = func(kubeconfig []byte, namespace string, opts HelmClientOptions) (HelmClient, error) {
+		got = opts
+		return &MockHelmClient{}, nil
+	}
+	t.Cleanup(func() { newHelmReleaseClient = orig })
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig":               "apiVersion: v1",
+		"name":                     "metallb",
+		"chart":                    "metallb/metallb",
+		"kube_context":             "lab",
+		"api_server":               "https://10.10.88.73:6443",
+		"insecure_skip_tls_verify": true,
+		"qps":                      20.0,
+		"burst":                    40,
+	})
+	if err := installHelmRelease(context.Background(), d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := HelmClientOptions{KubeContext: "lab", APIServer: "https://10.10.88.73:6443", QPS: 20, Burst: 40, InsecureSkipTLSVerify: true}
+	if got != want {
+		t.Errorf("expected client options %+v, got %+v", want, got)
+	}
+}
+
+func TestResourceHelmReleaseCreate_ChartWithoutRepository(t *testing.T) {
+	useMockHelmReleaseClient(t, &MockHelmClient{})
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig":     "apiVersion: v1",
+		"name":           "app",
+		"chart":          "app",
+		"repository_url": "https://example.com/charts",
+	})
+	if diags := resourceHelmReleaseCreate(context.Background(), d, nil); !diags.HasError() {
+		t.Fatal("expected an error for a chart without a repository prefix")
+	}
+}
+
+func TestResourceHelmReleaseRead_NotFound(t *testing.T) {
+	useMockHelmReleaseClient(t, &MockHelmClient{
+		GetReleaseFunc: func(name string) (*release.Release, error) {
+			return nil, fmt.Errorf("failed to get release %s: %w", name, driver.ErrReleaseNotFound)
+		},
+	})
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig": "apiVersion: v1",
+		"name":       "gone",
+		"chart":      "repo/gone",
+	})
+	d.SetId("default/gone")
+
+	if diags := resourceHelmReleaseRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Error("expected the release to be removed from state")
+	}
+}
+
+// helmReleaseState returns the state of a release deployed with values
+func helmReleaseState(t *testing.T, r *schema.Resource, values string) *terraform.InstanceState {
+	t.Helper()
+	cfg := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig": "apiVersion: v1",
+		"name":       "app",
+		"chart":      "repo/app",
+		"values":     values,
+	})
+	diff, err := r.Diff(context.Background(), nil, cfg, nil)
+	if err != nil {
+		t.Fatalf("create diff failed: %v", err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(nil, diff)
+	if err != nil {
+		t.Fatalf("failed to build state: %v", err)
+	}
+	d.SetId("default/app")
+	normalized, _ := normalizeHelmValuesYAML(values)
+	_ = d.Set("normalized_values", normalized)
+	_ = d.Set("values_hash", helmValuesHash(normalized))
+	_ = d.Set("drift", nil)
+	return d.State()
+}
+
+func TestResourceHelmReleaseCustomizeDiff(t *testing.T) {
+	r := resourceHelmRelease()
+	state := helmReleaseState(t, r, "controller:\n  replicaCount: 1\n")
+
+	planned := func(values string) *terraform.InstanceDiff {
+		t.Helper()
+		cfg := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"kubeconfig": "apiVersion: v1",
+			"name":       "app",
+			"chart":      "repo/app",
+			"values":     values,
+		})
+		diff, err := r.Diff(context.Background(), state, cfg, nil)
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+		return diff
+	}
+
+	t.Run("reformatted values", func(t *testing.T) {
+		if diff := planned("# same\ncontroller: {replicaCount: 1}\n"); diff != nil && len(diff.Attributes) > 0 {
+			t.Errorf("expected no changes, got %v", diff.Attributes)
+		}
+	})
+
+	t.Run("changed values", func(t *testing.T) {
+		diff := planned("controller:\n  replicaCount: 2\n")
+		if diff == nil {
+			t.Fatal("expected a diff")
+		}
+		if got := diff.Attributes["values_diff.0"]; got == nil || got.New != "~ controller.replicaCount: 1 -> 2" {
+			t.Errorf("values_diff.0 = %+v", got)
+		}
+		if got := diff.Attributes["normalized_values"]; got == nil || got.New != "controller:\n    replicaCount: 2\n" {
+			t.Errorf("normalized_values = %+v", got)
+		}
+		if got := diff.Attributes["values_hash"]; got == nil || got.New == got.Old {
+			t.Errorf("values_hash = %+v", got)
+		}
+	})
+}
+
+// fakeHelmDiffer returns a fixed release diff
+type fakeHelmDiffer struct {
+	diff  *helm.ReleaseDiff
+	err   error
+	repos []string
+	specs []*helm.ChartSpec
+}
+
+func (f *fakeHelmDiffer) AddRepository(name, url string) error {
+	f.repos = append(f.repos, name+"="+url)
+	return nil
+}
+
+func (f *fakeHelmDiffer) DiffRelease(ctx context.Context, spec *helm.ChartSpec) (*helm.ReleaseDiff, error) {
+	f.specs = append(f.specs, spec)
+	return f.diff, f.err
+}
+
+func useFakeHelmDiffer(t *testing.T, differ *fakeHelmDiffer) {
+	t.Helper()
+	orig := newHelmReleaseDiffer
+	newHelmReleaseDiffer = func(kubeconfig []byte, namespace string, opts HelmClientOptions) (helmReleaseDiffer, error) {
+		return differ, nil
+	}
+	t.Cleanup(func() { newHelmReleaseDiffer = orig })
+}
+
+func TestResourceHelmReleaseRead_Drift(t *testing.T) {
+	useMockHelmReleaseClient(t, &MockHelmClient{
+		GetReleaseFunc: func(name string) (*release.Release, error) {
+			return &release.Release{Name: name, Info: &release.Info{Status: release.StatusDeployed}, Version: 1}, nil
+		},
+	})
+	differ := &fakeHelmDiffer{diff: &helm.ReleaseDiff{Resources: []helm.ResourceDrift{{
+		Kind:      "Deployment",
+		Namespace: "metallb-system",
+		Name:      "controller",
+		Fields:    []helm.FieldDrift{{Path: "spec.replicas", Desired: "1", Live: "3"}},
+	}}}}
+	useFakeHelmDiffer(t, differ)
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig":     "apiVersion: v1",
+		"name":           "metallb",
+		"namespace":      "metallb-system",
+		"chart":          "metallb/metallb",
+		"repository_url": "https://metallb.github.io/metallb",
+		"values":         "speaker:\n  enabled: true\n",
+		"detect_drift":   true,
+	})
+	d.SetId("metallb-system/metallb")
+
+	if diags := resourceHelmReleaseRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	drift := d.Get("drift").([]interface{})
+	if len(drift) != 1 || drift[0] != "~ Deployment metallb-system/controller spec.replicas: 1 -> 3" {
+		t.Errorf("drift = %v", drift)
+	}
+	if len(differ.repos) != 1 || differ.repos[0] != "metallb=https://metallb.github.io/metallb" {
+		t.Errorf("repositories = %v", differ.repos)
+	}
+	if spec := differ.specs[0]; spec.ReleaseName != "metallb" || spec.ValuesYaml != "speaker:\n    enabled: true\n" {
+		t.Errorf("unexpected spec: %+v", spec)
+	}
+
+	// Failures keep the known drift and only warn
+	differ.err = fmt.Errorf("chart repository unreachable")
+	diags := resourceHelmReleaseRead(context.Background(), d, nil)
+	if diags.HasError() || len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Fatalf("expected a warning, got %v", diags)
+	}
+	if len(d.Get("drift").([]interface{})) != 1 {
+		t.Errorf("expected the known drift to be kept, got %v", d.Get("drift"))
+	}
+}
+
+func TestResourceHelmReleaseCustomizeDiff_Drift(t *testing.T) {
+	r := resourceHelmRelease()
+	state := helmReleaseState(t, r, "controller:\n  replicaCount: 1\n")
+	state.Attributes["detect_drift"] = "true"
+	state.Attributes["drift.#"] = "1"
+	state.Attributes["drift.0"] = "- ClusterRole app-reader: missing"
+
+	cfg := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig":   "apiVersion: v1",
+		"name":         "app",
+		"chart":        "repo/app",
+		"values":       "controller:\n  replicaCount: 1\n",
+		"detect_drift": true,
+	})
+	diff, err := r.Diff(context.Background(), state, cfg, nil)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if diff == nil || diff.Attributes["drift.#"] == nil || !diff.Attributes["drift.#"].NewComputed {
+		t.Errorf("expected drift to plan an upgrade, got %v", diff)
+	}
+
+	state.Attributes["drift.#"] = "0"
+	delete(state.Attributes, "drift.0")
+	if diff, err := r.Diff(context.Background(), state, cfg, nil); err != nil || (diff != nil && len(diff.Attributes) > 0) {
+		t.Errorf("expected no changes without drift, got %v, %v", diff, err)
+	}
+}
+
+func TestResourceHelmReleaseDelete(t *testing.T) {
+	mock := &MockHelmClient{}
+	useMockHelmReleaseClient(t, mock)
+
+	d := schema.TestResourceDataRaw(t, resourceHelmRelease().Schema, map[string]interface{}{
+		"kubeconfig": "apiVersion: v1",
+		"name":       "app",
+		"chart":      "repo/app",
+	})
+	d.SetId("default/app")
+
+	if diags := resourceHelmReleaseDelete(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(mock.UninstallReleaseCalls) != 1 || mock.UninstallReleaseCalls[0] != "app" {
+		t.Errorf("uninstall calls = %v", mock.UninstallReleaseCalls)
+	}
+	if d.Id() != "" {
+		t.Error("expected id to be cleared")
+	}
+}
@@ -2,11 +2,15 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
 // Test resource schema validation
@@ -23,7 +27,8 @@ func TestResourceK3sCluster_Schema(t *testing.T) {
 	expectedFields := []string{
 		"name", "k3s_version", "cluster_token", "control_plane", "worker",
 		"pod_cidr", "service_cidr", "metallb", "ingress", "install_timeout",
-		"kubeconfig_path", "kubeconfig", "api_endpoint", "node_token", "cluster_status",
+		"kubeconfig_path", "store_sensitive_outputs", "kubeconfig", "api_endpoint", "node_token", "cluster_status",
+		"host", "cluster_ca_certificate", "client_certificate", "client_key",
 	}
 	for _, field := range expectedFields {
 		if _, ok := r.Schema[field]; !ok {
@@ -69,7 +74,7 @@ func TestResourceK3sCluster_SchemaTypes(t *testing.T) {
 // Test required fields
 func TestResourceK3sCluster_RequiredFields(t *testing.T) {
 	r := resourceK3sCluster()
-	requiredFields := []string{"name", "control_plane"}
+	requiredFields := []string{"name"}
 	for _, field := range requiredFields {
 		if !r.Schema[field].Required {
 			t.Errorf("field '%s' should be required", field)
@@ -80,7 +85,7 @@ func TestResourceK3sCluster_RequiredFields(t *testing.T) {
 // Test optional fields
 func TestResourceK3sCluster_OptionalFields(t *testing.T) {
 	r := resourceK3sCluster()
-	optionalFields := []string{"k3s_version", "cluster_token", "worker", "metallb", "ingress", "kubeconfig_path"}
+	optionalFields := []string{"k3s_version", "cluster_token", "control_plane", "worker", "metallb", "ingress", "kubeconfig_path", "mode", "server_url"}
 	for _, field := range optionalFields {
 		if r.Schema[field].Required {
 			t.Errorf("field '%s' should be optional", field)
@@ -102,7 +107,7 @@ func TestResourceK3sCluster_SensitiveFields(t *testing.T) {
 // Test computed fields
 func TestResourceK3sCluster_ComputedFields(t *testing.T) {
 	r := resourceK3sCluster()
-	computedFields := []string{"kubeconfig", "api_endpoint", "node_token", "cluster_status"}
+	computedFields := []string{"kubeconfig", "api_endpoint", "node_token", "cluster_status", "dns_service_ip", "ingress_ip"}
 	for _, field := range computedFields {
 		if !r.Schema[field].Computed {
 			t.Errorf("field '%s' should be computed", field)
@@ -121,6 +126,8 @@ func TestResourceK3sCluster_Defaults(t *testing.T) {
 		{"pod_cidr", "10.244.0.0/16"},
 		{"service_cidr", "10.96.0.0/12"},
 		{"install_timeout", 600},
+		{"store_sensitive_outputs", true},
+		{"mode", "full"},
 	}
 
 	for _, tt := range tests {
@@ -148,8 +155,9 @@ func TestK3sNodeSchema(t *testing.T) {
 	if !s.Schema["host"].Required {
 		t.Error("'host' should be required")
 	}
-	if !s.Schema["ssh_user"].Required {
-		t.Error("'ssh_user' should be required")
+	// ssh_user may be inherited from ssh_defaults
+	if !s.Schema["ssh_user"].Optional {
+		t.Error("'ssh_user' should be optional")
 	}
 
 	// Check sensitive fields
@@ -160,9 +168,9 @@ func TestK3sNodeSchema(t *testing.T) {
 		t.Error("'ssh_password' should be sensitive")
 	}
 
-	// Check default port
-	if s.Schema["ssh_port"].Default != 22 {
-		t.Errorf("expected default ssh_port 22, got %v", s.Schema["ssh_port"].Default)
+	// No schema default, so an unset port can be inherited from ssh_defaults
+	if s.Schema["ssh_port"].Default != nil {
+		t.Errorf("expected no default ssh_port, got %v", s.Schema["ssh_port"].Default)
 	}
 }
 
@@ -246,6 +254,155 @@ func TestExtractNodeConfig(t *testing.T) {
 	}
 }
 
+func TestExtractNodeConfig_NetworkSettings(t *testing.T) {
+	config := extractNodeConfigWithDefaults(map[string]interface{}{
+		"host":             "10.10.88.74",
+		"ssh_user":         "root",
+		"ssh_port":         22,
+		"flannel_iface":    "end0",
+		"node_ip":          "10.10.88.74",
+		"node_external_ip": "192.168.1.50",
+	}, map[string]interface{}{"flannel_iface": "wlan0"})
+
+	// Network settings are taken from the node only
+	if config.FlannelIface != "end0" || config.NodeIP != "10.10.88.74" || config.NodeExternalIP != "192.168.1.50" {
+		t.Errorf("unexpected network settings: %+v", config)
+	}
+}
+
+func TestK3sNodeFlags(t *testing.T) {
+	if flags := k3sNodeFlags(NodeConfig{Host: "10.10.88.73"}); len(flags) != 0 {
+		t.Errorf("expected no flags, got %v", flags)
+	}
+
+	flags := k3sNodeFlags(NodeConfig{
+		Host:           "10.10.88.73",
+		FlannelIface:   "end0",
+		NodeIP:         "10.10.88.73",
+		NodeExternalIP: "192.168.1.50",
+	})
+	expected := []string{"--flannel-iface=end0", "--node-ip=10.10.88.73", "--node-external-ip=192.168.1.50"}
+	if strings.Join(flags, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, flags)
+	}
+}
+
+// Test that per-node network flags reach the agent install command
+func TestK3sProvisioner_InstallK3sAgent_NodeFlags(t *testing.T) {
+	var installCmd string
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s ") {
+					return "not_installed", nil
+				}
+				if strings.Contains(cmd, "/tmp/k3s-install.sh agent") {
+					installCmd = cmd
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22, FlannelIface: "end0", NodeIP: "10.10.88.74"}
+
+	if _, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(installCmd, "/tmp/k3s-install.sh agent --flannel-iface=end0 --node-ip=10.10.88.74") {
+		t.Errorf("expected node flags in install command, got %q", installCmd)
+	}
+}
+
+func TestExtractNodeConfigWithDefaults(t *testing.T) {
+	defaults := map[string]interface{}{
+		"ssh_user":     "admin",
+		"ssh_key":      "shared-key",
+		"ssh_password": "",
+		"ssh_port":     2222,
+	}
+
+	// Unset node fields inherit from defaults
+	config := extractNodeConfigWithDefaults(map[string]interface{}{
+		"host":         "10.10.88.74",
+		"ssh_user":     "",
+		"ssh_key":      "",
+		"ssh_password": "",
+		"ssh_port":     0,
+	}, defaults)
+	if config.SSHUser != "admin" || string(config.SSHKey) != "shared-key" || config.SSHPort != 2222 {
+		t.Errorf("expected inherited settings, got %+v", config)
+	}
+
+	// Node fields override defaults
+	config = extractNodeConfigWithDefaults(map[string]interface{}{
+		"host":         "10.10.88.75",
+		"ssh_user":     "root",
+		"ssh_key":      "node-key",
+		"ssh_password": "",
+		"ssh_port":     22,
+	}, defaults)
+	if config.SSHUser != "root" || string(config.SSHKey) != "node-key" || config.SSHPort != 22 {
+		t.Errorf("expected node overrides, got %+v", config)
+	}
+
+	// Port falls back to 22 without defaults
+	config = extractNodeConfigWithDefaults(map[string]interface{}{
+		"host":     "10.10.88.76",
+		"ssh_user": "root",
+		"ssh_port": 0,
+	}, nil)
+	if config.SSHPort != 22 {
+		t.Errorf("expected ssh_port 22, got %d", config.SSHPort)
+	}
+}
+
+func TestExtractClusterConfig_SSHDefaults(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name": "test-cluster",
+		"ssh_defaults": []interface{}{
+			map[string]interface{}{
+				"ssh_user": "admin",
+				"ssh_key":  "shared-key",
+			},
+		},
+		"control_plane": []interface{}{
+			map[string]interface{}{"host": "10.10.88.73"},
+		},
+		"worker": []interface{}{
+			map[string]interface{}{"host": "10.10.88.74"},
+			map[string]interface{}{"host": "10.10.88.75", "ssh_user": "root", "ssh_port": 2222},
+		},
+	})
+
+	cfg := extractClusterConfig(d)
+	if cfg.ControlPlane.SSHUser != "admin" || string(cfg.ControlPlane.SSHKey) != "shared-key" || cfg.ControlPlane.SSHPort != 22 {
+		t.Errorf("control plane did not inherit defaults: %+v", cfg.ControlPlane)
+	}
+	if cfg.Workers[0].SSHUser != "admin" {
+		t.Errorf("worker 1 did not inherit ssh_user: %+v", cfg.Workers[0])
+	}
+	if cfg.Workers[1].SSHUser != "root" || cfg.Workers[1].SSHPort != 2222 || string(cfg.Workers[1].SSHKey) != "shared-key" {
+		t.Errorf("worker 2 overrides not applied: %+v", cfg.Workers[1])
+	}
+	if err := validateNodeSSH(append([]NodeConfig{cfg.ControlPlane}, cfg.Workers...)...); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestValidateNodeSSH(t *testing.T) {
+	if err := validateNodeSSH(NodeConfig{Host: "a", SSHKey: []byte("k")}); err == nil {
+		t.Error("expected error for missing ssh_user")
+	}
+	if err := validateNodeSSH(NodeConfig{Host: "a", SSHUser: "root"}); err == nil {
+		t.Error("expected error for missing credentials")
+	}
+	if err := validateNodeSSH(NodeConfig{Host: "a", SSHUser: "root", SSHPassword: "pw"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 // Test splitIPRange
 func TestSplitIPRange(t *testing.T) {
 	tests := []struct {
@@ -281,7 +438,7 @@ func TestK3sProvisioner_InstallK3sServer(t *testing.T) {
 		"swapoff -a",
 		"mkdir -p /etc/rancher/k3s",
 		"test -f /usr/local/bin/k3s && echo 'installed' || echo 'not_installed'",
-		"curl -sfL https://get.k3s.io -o /tmp/k3s-install.sh && chmod +x /tmp/k3s-install.sh",
+		k3sDownloadScriptCmd,
 	}
 
 	mockFactory := func() SSHClient {
@@ -321,7 +478,7 @@ func TestK3sProvisioner_InstallK3sServer(t *testing.T) {
 	// This will fail because mock doesn't fully implement all commands,
 	// but we can verify the flow starts correctly
 	ctx := context.Background()
-	_ = provisioner.InstallK3sServer(ctx, node, cfg, 5*time.Second)
+	_, _ = provisioner.InstallK3sServer(ctx, node, cfg, 5*time.Second)
 	// We just verify no panic occurs
 }
 
@@ -526,3 +683,530 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// Test that install script failures carry installer output and the service journal
+func TestK3sProvisioner_InstallK3sAgent_FailureDiagnostics(t *testing.T) {
+	var installerOutput strings.Builder
+	for i := 1; i <= 50; i++ {
+		fmt.Fprintf(&installerOutput, "line %d\n", i)
+	}
+
+	journalRequested := false
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				switch {
+				case strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s "):
+					return "not_installed", nil
+				case strings.Contains(cmd, "/tmp/k3s-install.sh agent"):
+					return installerOutput.String(), fmt.Errorf("Process exited with status 1")
+				case strings.Contains(cmd, "journalctl -u k3s-agent"):
+					journalRequested = true
+					return "k3s-agent: failed to contact server\n", nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	node := NodeConfig{Host: "10.10.88.74", SSHUser: "root", SSHPort: 22}
+
+	_, err := provisioner.InstallK3sAgent(context.Background(), node, "https://10.10.88.73:6443", "token", "", time.Second)
+	if err == nil {
+		t.Fatal("expected error from failed install script")
+	}
+
+	var installErr *InstallError
+	if !errors.As(err, &installErr) {
+		t.Fatalf("expected InstallError, got %T: %v", err, err)
+	}
+	if !journalRequested {
+		t.Error("expected journalctl to be queried on failure")
+	}
+	if strings.Contains(installErr.Output, "line 10\n") || !strings.HasSuffix(installErr.Output, "line 50") {
+		t.Errorf("expected only the last %d lines of output, got:\n%s", installOutputTailLines, installErr.Output)
+	}
+	if !strings.Contains(err.Error(), "failed to contact server") {
+		t.Errorf("expected journal in error message, got: %v", err)
+	}
+}
+
+// Test that the journal is not fetched when disabled
+func TestK3sProvisioner_InstallFailure_JournalDisabled(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.Contains(cmd, "journalctl") {
+					t.Errorf("journalctl should not be run when disabled")
+				}
+				if strings.Contains(cmd, "/tmp/k3s-install.sh server") {
+					return "[ERROR] Download failed\n", fmt.Errorf("Process exited with status 1")
+				}
+				if strings.HasPrefix(cmd, "test -f /usr/local/bin/k3s ") {
+					return "not_installed", nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	provisioner.CollectJournalOnFailure = false
+	node := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPort: 22}
+
+	_, err := provisioner.InstallK3sServer(context.Background(), node, ClusterConfig{Name: "test"}, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "[ERROR] Download failed") {
+		t.Errorf("expected installer output in error, got: %v", err)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	if got := tailLines("a\nb\nc\n", 2); got != "b\nc" {
+		t.Errorf("expected 'b\\nc', got %q", got)
+	}
+	if got := tailLines("a\n", 5); got != "a" {
+		t.Errorf("expected 'a', got %q", got)
+	}
+	if got := tailLines("", 5); got != "" {
+		t.Errorf("expected empty string, got %q", got)
+	}
+}
+
+func TestRenderServerConfig(t *testing.T) {
+	cfg := ClusterConfig{
+		PodCIDR:     "10.244.0.0/16",
+		ServiceCIDR: "10.96.0.0/12",
+		ServerConfig: map[string]string{
+			"disable":      "traefik",
+			"cluster-cidr": "ignored",
+		},
+	}
+
+	expected := "cluster-cidr: \"10.244.0.0/16\"\ndisable: \"traefik\"\nservice-cidr: \"10.96.0.0/12\"\n"
+	if got := RenderServerConfig(cfg); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if ConfigChecksum(RenderServerConfig(cfg)) != ConfigChecksum(expected) {
+		t.Error("expected checksum to be stable for identical config")
+	}
+}
+
+func TestK3sProvisioner_GetServerConfigChecksum(t *testing.T) {
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.Contains(cmd, "sha256sum "+k3sConfigPath) {
+					return "abc123\n", nil
+				}
+				return "", fmt.Errorf("unexpected command: %s", cmd)
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	checksum, err := provisioner.GetServerConfigChecksum(NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPort: 22})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checksum != "abc123" {
+		t.Errorf("expected checksum 'abc123', got %q", checksum)
+	}
+}
+
+func TestK3sProvisioner_ApplyServerConfig(t *testing.T) {
+	var commands []string
+	mockFactory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				if cmd == "k3s kubectl get nodes 2>/dev/null" {
+					return "node1 Ready", nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	cfg := ClusterConfig{PodCIDR: "10.244.0.0/16"}
+	err := provisioner.ApplyServerConfig(context.Background(), NodeConfig{Host: "10.10.88.73", SSHPort: 22}, cfg, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(commands) < 2 || !strings.Contains(commands[0], "base64 -d > "+k3sConfigPath) || commands[1] != serviceCommand("restart", "k3s") {
+		t.Errorf("unexpected command sequence: %v", commands)
+	}
+}
+
+func TestResourceK3sClusterUpdate_ConfigChangeRequiresAllowRestart(t *testing.T) {
+	// TestResourceDataRaw diffs against empty state, so the server config fields show as changed
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host":     "10.10.88.73",
+			"ssh_user": "root",
+		}},
+		"server_config": map[string]interface{}{"disable": "traefik"},
+	})
+	d.SetId("test")
+
+	diags := resourceK3sClusterUpdate(context.Background(), d, nil)
+	if !diags.HasError() {
+		t.Fatal("expected error when allow_restart is false")
+	}
+	if !strings.Contains(diags[0].Summary, "requires a restart") {
+		t.Errorf("unexpected diagnostic: %s", diags[0].Summary)
+	}
+}
+
+func TestValidateK3sMode(t *testing.T) {
+	controlPlane := []interface{}{map[string]interface{}{"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "pw"}}
+	worker := []interface{}{map[string]interface{}{"host": "10.10.88.74", "ssh_user": "root", "ssh_password": "pw"}}
+
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr string
+	}{
+		{"full", map[string]interface{}{"control_plane": controlPlane, "worker": worker}, ""},
+		{"full without control plane", map[string]interface{}{"worker": worker}, "control_plane is required"},
+		{"server_only", map[string]interface{}{"mode": "server_only", "control_plane": controlPlane}, ""},
+		{"server_only with workers", map[string]interface{}{"mode": "server_only", "control_plane": controlPlane, "worker": worker}, "worker blocks are not allowed"},
+		{"agents_only", map[string]interface{}{"mode": "agents_only", "server_url": "https://10.10.88.10:6443", "cluster_token": "K10abc", "worker": worker}, ""},
+		{"agents_only with control plane", map[string]interface{}{"mode": "agents_only", "server_url": "https://10.10.88.10:6443", "cluster_token": "K10abc", "control_plane": controlPlane, "worker": worker}, "must not be set"},
+		{"agents_only without token", map[string]interface{}{"mode": "agents_only", "server_url": "https://10.10.88.10:6443", "worker": worker}, "server_url and cluster_token are required"},
+		{"agents_only without workers", map[string]interface{}{"mode": "agents_only", "server_url": "https://10.10.88.10:6443", "cluster_token": "K10abc"}, "at least one worker"},
+		{"agents_only with metallb", map[string]interface{}{"mode": "agents_only", "server_url": "https://10.10.88.10:6443", "cluster_token": "K10abc", "worker": worker,
+			"metallb": []interface{}{map[string]interface{}{"ip_range": "10.10.88.80-10.10.88.89"}}}, "metallb is not supported"},
+		{"agents_only with auto_upgrade", map[string]interface{}{"mode": "agents_only", "server_url": "https://10.10.88.10:6443", "cluster_token": "K10abc", "worker": worker,
+			"auto_upgrade": []interface{}{map[string]interface{}{"channel": "stable"}}}, "auto_upgrade is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{"name": "test"}
+			for k, v := range tt.raw {
+				raw[k] = v
+			}
+			d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, raw)
+
+			err := validateK3sMode(d, extractClusterConfig(d))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestResourceK3sCluster_LegacyStateMode(t *testing.T) {
+	config := map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+		}},
+	}
+	diff := legacyStateDiff(t, resourceK3sCluster(), config, "mode")
+	if diff != nil && diff.Attributes["mode"] != nil {
+		t.Errorf("expected no mode change for state without mode, got %+v", diff.Attributes["mode"])
+	}
+	if diff.RequiresNew() {
+		t.Error("expected state without mode not to be replaced")
+	}
+}
+
+func TestResourceK3sCluster_LegacyStateSSHPort(t *testing.T) {
+	r := resourceK3sCluster()
+	node := map[string]interface{}{"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret", "ssh_port": 22}
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name": "test", "control_plane": []interface{}{node},
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.SetId("legacy")
+
+	// State written when ssh_port defaulted to 22, planned with the port unset
+	delete(node, "ssh_port")
+	config := map[string]interface{}{"name": "test", "control_plane": []interface{}{node}}
+	diff, err := r.Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != nil && diff.Attributes["control_plane.0.ssh_port"] != nil {
+		t.Errorf("expected no ssh_port change, got %+v", diff.Attributes["control_plane.0.ssh_port"])
+	}
+
+	config["ssh_defaults"] = []interface{}{map[string]interface{}{"ssh_port": 2222}}
+	diff, err = r.Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || diff.Attributes["control_plane.0.ssh_port"] == nil {
+		t.Error("expected the port from ssh_defaults to replace 22")
+	}
+}
+
+func TestResourceK3sCluster_CIDRChangeForcesNew(t *testing.T) {
+	r := resourceK3sCluster()
+	config := map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host": "10.10.88.73", "ssh_user": "root", "ssh_password": "secret",
+		}},
+	}
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.SetId("test")
+	checksum := ConfigChecksum(RenderServerConfig(ClusterConfig{PodCIDR: "10.244.0.0/16", ServiceCIDR: "10.96.0.0/12"}))
+	if err := data.Set("config_checksum", checksum); err != nil {
+		t.Fatal(err)
+	}
+
+	config["pod_cidr"] = "10.244.0.0/16,fd00:10:244::/56"
+	config["service_cidr"] = "10.96.0.0/12,fd00:10:96::/112"
+	diff, err := r.Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || !diff.RequiresNew() {
+		t.Fatal("expected a CIDR change to replace the cluster")
+	}
+	if attr := diff.Attributes["config_checksum"]; attr != nil && !attr.NewComputed && attr.New != checksum {
+		t.Errorf("expected config_checksum to ignore the CIDR change, got %+v", attr)
+	}
+}
+
+func TestK3sProvisioner_WaitForAgentActive(t *testing.T) {
+	calls := 0
+	mock := &MockSSHClient{
+		RunCommandFunc: func(cmd string) (string, error) {
+			if !strings.Contains(cmd, "systemctl is-active --quiet k3s-agent") {
+				t.Errorf("unexpected command: %s", cmd)
+			}
+			calls++
+			return "active\n", nil
+		},
+	}
+	p := &K3sProvisioner{clientFactory: func() SSHClient { return mock }}
+
+	if err := p.WaitForAgentActive(NodeConfig{Host: "10.10.88.74", SSHPort: 22}, time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 status check, got %d", calls)
+	}
+}
+
+func TestK3sProvisioner_AgentActive_Inactive(t *testing.T) {
+	mock := &MockSSHClient{
+		RunCommandFunc: func(cmd string) (string, error) {
+			return "", &ExitError{Command: cmd, Code: 3}
+		},
+	}
+	p := &K3sProvisioner{clientFactory: func() SSHClient { return mock }}
+
+	if p.AgentActive(NodeConfig{Host: "10.10.88.74", SSHPort: 22}) {
+		t.Error("expected inactive agent")
+	}
+}
+
+// fastSSHDialRetry removes the delay between SSH connection retries for the duration of a test
+func fastSSHDialRetry(t *testing.T) {
+	t.Helper()
+	delay := sshDialRetryDelay
+	sshDialRetryDelay = 0
+	t.Cleanup(func() { sshDialRetryDelay = delay })
+}
+
+func TestK3sProvisioner_RunCommand_RetriesDialErrors(t *testing.T) {
+	fastSSHDialRetry(t)
+
+	connects := 0
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			ConnectFunc: func(host string, port int, config *SSHConfig) error {
+				connects++
+				if connects < 3 {
+					return &DialError{Addr: host + ":22", Err: errors.New("connection refused")}
+				}
+				return nil
+			},
+			RunCommandFunc: func(cmd string) (string, error) {
+				return "ok", nil
+			},
+		}
+	})
+
+	output, err := provisioner.runCommand(NodeConfig{Host: "10.10.88.73", SSHPort: 22}, "true")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "ok" || connects != 3 {
+		t.Errorf("expected success on third connect, got output %q after %d connects", output, connects)
+	}
+}
+
+func TestK3sProvisioner_RunCommand_ExitErrorNotRetried(t *testing.T) {
+	fastSSHDialRetry(t)
+
+	runs := 0
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				runs++
+				return "", &ExitError{Command: cmd, Code: 2, Stderr: "no such file"}
+			},
+		}
+	})
+
+	_, err := provisioner.runCommand(NodeConfig{Host: "10.10.88.73", SSHPort: 22}, "cat /missing")
+	if runs != 1 {
+		t.Errorf("expected command to run once, ran %d times", runs)
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Errorf("expected exit code 2 through the wrapped error, got %v", err)
+	}
+}
+
+func TestK3sProvisioner_RunCommand_SessionLostNotRetried(t *testing.T) {
+	fastSSHDialRetry(t)
+
+	runs := 0
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				runs++
+				return "", &SessionLostError{Addr: "10.10.88.73:22", Err: io.EOF}
+			},
+		}
+	})
+
+	_, err := provisioner.runCommand(NodeConfig{Host: "10.10.88.73", SSHPort: 22}, "k3s token rotate")
+	if runs != 1 {
+		t.Errorf("expected a command that may have run not to be retried, ran %d times", runs)
+	}
+	if !isSSHConnectionError(err) || isSSHDialError(err) {
+		t.Errorf("expected a SessionLostError, got %v", err)
+	}
+}
+
+func TestK3sProvisioner_CheckK3sInstalled_Unreachable(t *testing.T) {
+	fastSSHDialRetry(t)
+
+	provisioner := NewK3sProvisionerWithClientFactory(func() SSHClient {
+		return &MockSSHClient{
+			ConnectFunc: func(host string, port int, config *SSHConfig) error {
+				return &DialError{Addr: host + ":22", Err: errors.New("i/o timeout")}
+			},
+		}
+	})
+
+	installed, err := provisioner.CheckK3sInstalled(NodeConfig{Host: "10.10.88.73", SSHPort: 22})
+	if err == nil || !isSSHDialError(err) {
+		t.Fatalf("expected DialError for unreachable node, got installed=%v err=%v", installed, err)
+	}
+}
+
+func TestK3sIngressService(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want *k3sServiceRef
+	}{
+		{"traefik", map[string]interface{}{}, &k3sServiceRef{Namespace: "kube-system", Name: "traefik"}},
+		{"nginx ingress", map[string]interface{}{"ingress": []interface{}{map[string]interface{}{"enabled": true}}},
+			&k3sServiceRef{Namespace: "ingress-nginx", Name: "ingress-nginx-controller"}},
+		{"traefik disabled", map[string]interface{}{"server_config": map[string]interface{}{"disable": "servicelb, traefik"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{"name": "test"}
+			for k, v := range tt.raw {
+				raw[k] = v
+			}
+			d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, raw)
+
+			got := k3sIngressService(d)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshRegistriesChecksum(t *testing.T) {
+	nodeChecksums := map[string]string{"10.10.88.73": "aaa", "10.10.88.74": "bbb"}
+	mockFactory := func() SSHClient {
+		var host string
+		return &MockSSHClient{
+			ConnectFunc: func(h string, port int, config *SSHConfig) error {
+				host = h
+				return nil
+			},
+			RunCommandFunc: func(cmd string) (string, error) {
+				return nodeChecksums[host] + "\n", nil
+			},
+		}
+	}
+	provisioner := NewK3sProvisionerWithClientFactory(mockFactory)
+	nodes := []NodeConfig{{Host: "10.10.88.73"}, {Host: "10.10.88.74"}}
+
+	d := resourceK3sCluster().TestResourceData()
+	_ = d.Set("registries_checksum", "aaa")
+	if err := refreshRegistriesChecksum(d, provisioner, nodes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.Get("registries_checksum").(string); got != "bbb" {
+		t.Errorf("expected drifted worker checksum 'bbb', got %q", got)
+	}
+
+	// Unmanaged registries are not checked
+	d = resourceK3sCluster().TestResourceData()
+	if err := refreshRegistriesChecksum(d, provisioner, nodes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.Get("registries_checksum").(string); got != "" {
+		t.Errorf("expected empty checksum, got %q", got)
+	}
+}
+
+func TestResourceK3sClusterCreate_RequiresKubeconfigPathWithoutSensitiveOutputs(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceK3sCluster().Schema, map[string]interface{}{
+		"name": "test",
+		"control_plane": []interface{}{map[string]interface{}{
+			"host":     "10.10.88.73",
+			"ssh_user": "root",
+			"ssh_key":  "key",
+		}},
+		"store_sensitive_outputs": false,
+	})
+
+	diags := resourceK3sClusterCreate(context.Background(), d, dryRunConfig)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "kubeconfig_path must be set") {
+		t.Fatalf("expected an error when kubeconfig_path is unset, got %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected nothing to be provisioned, got ID %q", d.Id())
+	}
+}
@@ -0,0 +1,342 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// newNamespaceK8sClient creates the Kubernetes client of turingpi_namespace.
+// Replaced in tests.
+var newNamespaceK8sClient = NewK8sClient
+
+// k8sNameRegexp matches Kubernetes DNS label names, e.g. namespace names
+var k8sNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func resourceNamespace() *schema.Resource {
+	return &schema.Resource{
+		Description: "Creates a namespace with bootstrap secrets, e.g. an image pull secret, on a freshly provisioned cluster, " +
+			"so objects that must exist before GitOps takes over do not need the kubernetes provider.",
+		CreateContext: resourceNamespaceCreate,
+		ReadContext:   resourceNamespaceRead,
+		UpdateContext: resourceNamespaceUpdate,
+		DeleteContext: resourceNamespaceDelete,
+		Schema: map[string]*schema.Schema{
+			"kubeconfig": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Kubeconfig content of the target cluster, e.g. turingpi_k3s_cluster.kubeconfig",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				Description:  "Namespace name",
+				ValidateFunc: validation.StringMatch(k8sNameRegexp, "must be a lowercase DNS label, e.g. \"apps\""),
+			},
+			"labels": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Labels of the namespace",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"annotations": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Annotations of the namespace",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"image_pull_secret": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "kubernetes.io/dockerconfigjson secrets for pulling images from private registries",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:         schema.TypeString,
+							Required:     true,
+							Description:  "Secret name, referenced from imagePullSecrets",
+							ValidateFunc: validation.StringIsNotEmpty,
+						},
+						"registry": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Registry host, e.g. ghcr.io",
+						},
+						"username": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Registry username",
+						},
+						"password": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Sensitive:   true,
+							Description: "Registry password or token",
+						},
+						"email": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Registry account email",
+						},
+					},
+				},
+			},
+			"secret": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Other bootstrap secrets, e.g. the credentials a GitOps controller needs to reach its repository",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:         schema.TypeString,
+							Required:     true,
+							Description:  "Secret name",
+							ValidateFunc: validation.StringIsNotEmpty,
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "Opaque",
+							Description: "Secret type (default: Opaque)",
+						},
+						"data": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Secret data as plain strings; they are base64 encoded by the provider",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"retain_on_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Leave the namespace and its secrets in the cluster when this resource is destroyed, e.g. after GitOps has taken them over (default: false)",
+			},
+		},
+	}
+}
+
+func resourceNamespaceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if err := applyNamespace(ctx, d, nil); err != nil {
+		return diagFromErr(err)
+	}
+	d.SetId(d.Get("name").(string))
+	return resourceNamespaceRead(ctx, d, meta)
+}
+
+func resourceNamespaceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := newNamespaceK8sClient([]byte(d.Get("kubeconfig").(string)))
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to create Kubernetes client: %w", err))
+	}
+	exists, err := client.NamespaceExists(ctx, d.Id())
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to read namespace %s: %w", d.Id(), err))
+	}
+	if !exists {
+		tflog.Warn(ctx, "Namespace not found, removing from state", map[string]interface{}{"name": d.Id()})
+		d.SetId("")
+	}
+	return nil
+}
+
+func resourceNamespaceUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Secrets dropped from the configuration are deleted
+	var removed []string
+	if d.HasChanges("image_pull_secret", "secret") {
+		oldPull, newPull := d.GetChange("image_pull_secret")
+		oldSecrets, newSecrets := d.GetChange("secret")
+		kept := make(map[string]bool)
+		for _, s := range append(newPull.([]interface{}), newSecrets.([]interface{})...) {
+			kept[s.(map[string]interface{})["name"].(string)] = true
+		}
+		for _, s := range append(oldPull.([]interface{}), oldSecrets.([]interface{})...) {
+			if name := s.(map[string]interface{})["name"].(string); !kept[name] {
+				removed = append(removed, name)
+			}
+		}
+	}
+
+	if err := applyNamespace(ctx, d, removed); err != nil {
+		return diagFromErr(err)
+	}
+	return resourceNamespaceRead(ctx, d, meta)
+}
+
+func resourceNamespaceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if d.Get("retain_on_destroy").(bool) {
+		tflog.Info(ctx, "retain_on_destroy is set, leaving namespace in the cluster", map[string]interface{}{"name": d.Id()})
+		d.SetId("")
+		return nil
+	}
+
+	client, err := newNamespaceK8sClient([]byte(d.Get("kubeconfig").(string)))
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to create Kubernetes client: %w", err))
+	}
+	// Deleting the namespace deletes its secrets
+	manifest, err := renderK8sObjects([]map[string]interface{}{namespaceObject(d)})
+	if err != nil {
+		return diagFromErr(err)
+	}
+	if err := client.DeleteManifest(ctx, manifest); err != nil {
+		return diagFromErr(err)
+	}
+	d.SetId("")
+	return nil
+}
+
+// applyNamespace server-side applies the namespace and its secrets, and
+// deletes the removed secrets
+func applyNamespace(ctx context.Context, d *schema.ResourceData, removed []string) error {
+	client, err := newNamespaceK8sClient([]byte(d.Get("kubeconfig").(string)))
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	objects, err := namespaceObjects(d)
+	if err != nil {
+		return err
+	}
+	manifest, err := renderK8sObjects(objects)
+	if err != nil {
+		return err
+	}
+	tflog.Info(ctx, "Applying namespace", map[string]interface{}{
+		"name":    d.Get("name").(string),
+		"objects": len(objects),
+	})
+	if err := client.ApplyManifest(ctx, manifest); err != nil {
+		return err
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+	var stale []map[string]interface{}
+	for _, name := range removed {
+		stale = append(stale, secretObject(d.Get("name").(string), name, "Opaque", nil))
+	}
+	manifest, err = renderK8sObjects(stale)
+	if err != nil {
+		return err
+	}
+	return client.DeleteManifest(ctx, manifest)
+}
+
+// namespaceObjects returns the namespace followed by its secrets
+func namespaceObjects(d *schema.ResourceData) ([]map[string]interface{}, error) {
+	namespace := d.Get("name").(string)
+	objects := []map[string]interface{}{namespaceObject(d)}
+	seen := make(map[string]bool)
+
+	for _, raw := range d.Get("image_pull_secret").([]interface{}) {
+		s := raw.(map[string]interface{})
+		name := s["name"].(string)
+		if seen[name] {
+			return nil, fmt.Errorf("secret %q is defined more than once", name)
+		}
+		seen[name] = true
+
+		dockerConfig, err := dockerConfigJSON(s["registry"].(string), s["username"].(string), s["password"].(string), s["email"].(string))
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, secretObject(namespace, name, "kubernetes.io/dockerconfigjson", map[string]string{
+			".dockerconfigjson": dockerConfig,
+		}))
+	}
+
+	for _, raw := range d.Get("secret").([]interface{}) {
+		s := raw.(map[string]interface{})
+		name := s["name"].(string)
+		if seen[name] {
+			return nil, fmt.Errorf("secret %q is defined more than once", name)
+		}
+		seen[name] = true
+
+		data := make(map[string]string)
+		if m, ok := s["data"].(map[string]interface{}); ok {
+			data = expandStringMap(m)
+		}
+		objects = append(objects, secretObject(namespace, name, s["type"].(string), data))
+	}
+
+	return objects, nil
+}
+
+func namespaceObject(d *schema.ResourceData) map[string]interface{} {
+	metadata := map[string]interface{}{"name": d.Get("name").(string)}
+	if labels := d.Get("labels").(map[string]interface{}); len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if annotations := d.Get("annotations").(map[string]interface{}); len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   metadata,
+	}
+}
+
+// secretObject returns a Secret with data base64 encoded
+func secretObject(namespace, name, secretType string, data map[string]string) map[string]interface{} {
+	encoded := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		encoded[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"type":       secretType,
+		"data":       encoded,
+	}
+}
+
+// dockerConfigJSON renders the .dockerconfigjson of an image pull secret
+func dockerConfigJSON(registry, username, password, email string) (string, error) {
+	entry := map[string]string{
+		"username": username,
+		"password": password,
+		"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if email != "" {
+		entry["email"] = email
+	}
+	out, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{registry: entry},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render image pull secret: %w", err)
+	}
+	return string(out), nil
+}
+
+// renderK8sObjects renders objects as a multi-document YAML manifest
+func renderK8sObjects(objects []map[string]interface{}) (string, error) {
+	docs := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("failed to render manifest: %w", err)
+		}
+		docs = append(docs, string(out))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
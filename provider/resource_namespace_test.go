@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// namespaceTestCluster fakes the cluster of turingpi_namespace and records
+// applied objects and deletions
+type namespaceTestCluster struct {
+	applied map[string]map[string]interface{}
+	deleted []string
+}
+
+func useNamespaceTestCluster(t *testing.T, namespaces ...string) *namespaceTestCluster {
+	t.Helper()
+	cluster := &namespaceTestCluster{applied: make(map[string]map[string]interface{})}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(k8sschema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(k8sschema.GroupVersionKind{Version: "v1", Kind: "Secret"}, meta.RESTScopeNamespace)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(patch.GetPatch(), &obj); err != nil {
+			t.Fatalf("invalid apply patch: %v", err)
+		}
+		cluster.applied[patch.GetResource().Resource+"/"+patch.GetName()] = obj
+		return true, &unstructured.Unstructured{}, nil
+	})
+	dynamicClient.PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteAction)
+		cluster.deleted = append(cluster.deleted, del.GetResource().Resource+"/"+del.GetName())
+		return true, nil, nil
+	})
+
+	var objects []runtime.Object
+	for _, name := range namespaces {
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	client := &K8sClient{
+		clientset: kubefake.NewClientset(objects...),
+		dynamic:   dynamicClient,
+		mapper:    mapper,
+	}
+
+	orig := newNamespaceK8sClient
+	newNamespaceK8sClient = func(kubeconfig []byte) (*K8sClient, error) { return client, nil }
+	t.Cleanup(func() { newNamespaceK8sClient = orig })
+	return cluster
+}
+
+func TestResourceNamespace(t *testing.T) {
+	r := resourceNamespace()
+	if err := r.InternalValidate(nil, true); err != nil {
+		t.Fatalf("schema validation failed: %v", err)
+	}
+	if !r.Schema["kubeconfig"].Sensitive {
+		t.Error("kubeconfig should be sensitive")
+	}
+	if !r.Schema["name"].ForceNew {
+		t.Error("name should force a new resource")
+	}
+
+	validate := r.Schema["name"].ValidateFunc
+	for _, name := range []string{"apps", "flux-system", "a1"} {
+		if _, errs := validate(name, "name"); len(errs) > 0 {
+			t.Errorf("%q should be valid: %v", name, errs)
+		}
+	}
+	for _, name := range []string{"Apps", "-apps", "apps_1", ""} {
+		if _, errs := validate(name, "name"); len(errs) == 0 {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestResourceNamespaceCreate(t *testing.T) {
+	cluster := useNamespaceTestCluster(t, "flux-system")
+
+	d := schema.TestResourceDataRaw(t, resourceNamespace().Schema, map[string]interface{}{
+		"kubeconfig": "apiVersion: v1",
+		"name":       "flux-system",
+		"labels":     map[string]interface{}{"team": "platform"},
+		"image_pull_secret": []interface{}{map[string]interface{}{
+			"name":     "ghcr",
+			"registry": "ghcr.io",
+			"username": "bot",
+			"password": "s3cret",
+		}},
+		"secret": []interface{}{map[string]interface{}{
+			"name": "git-credentials",
+			"data": map[string]interface{}{"token": "abc"},
+		}},
+	})
+
+	if diags := resourceNamespaceCreate(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "flux-system" {
+		t.Errorf("id = %q", d.Id())
+	}
+
+	ns := cluster.applied["namespaces/flux-system"]
+	if ns == nil {
+		t.Fatalf("namespace not applied, got %v", cluster.applied)
+	}
+	if labels := ns["metadata"].(map[string]interface{})["labels"].(map[string]interface{}); labels["team"] != "platform" {
+		t.Errorf("labels = %v", labels)
+	}
+
+	pull := cluster.applied["secrets/ghcr"]
+	if pull == nil || pull["type"] != "kubernetes.io/dockerconfigjson" {
+		t.Fatalf("image pull secret = %v", pull)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(pull["data"].(map[string]interface{})[".dockerconfigjson"].(string))
+	var dockerConfig struct {
+		Auths map[string]map[string]string `json:"auths"`
+	}
+	if err := json.Unmarshal(raw, &dockerConfig); err != nil {
+		t.Fatalf("invalid .dockerconfigjson %q: %v", raw, err)
+	}
+	auth := dockerConfig.Auths["ghcr.io"]
+	if auth["username"] != "bot" || auth["password"] != "s3cret" || auth["auth"] != base64.StdEncoding.EncodeToString([]byte("bot:s3cret")) {
+		t.Errorf("ghcr.io auth = %v", auth)
+	}
+
+	opaque := cluster.applied["secrets/git-credentials"]
+	if opaque == nil || opaque["type"] != "Opaque" {
+		t.Fatalf("secret = %v", opaque)
+	}
+	if token := opaque["data"].(map[string]interface{})["token"]; token != base64.StdEncoding.EncodeToString([]byte("abc")) {
+		t.Errorf("token = %v", token)
+	}
+}
+
+func TestResourceNamespaceCreate_DuplicateSecret(t *testing.T) {
+	useNamespaceTestCluster(t)
+
+	d := schema.TestResourceDataRaw(t, resourceNamespace().Schema, map[string]interface{}{
+		"kubeconfig": "apiVersion: v1",
+		"name":       "apps",
+		"image_pull_secret": []interface{}{map[string]interface{}{
+			"name": "regcred", "registry": "ghcr.io", "username": "bot", "password": "x",
+		}},
+		"secret": []interface{}{map[string]interface{}{"name": "regcred"}},
+	})
+
+	diags := resourceNamespaceCreate(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "more than once") {
+		t.Errorf("expected a duplicate secret error, got %v", diags)
+	}
+}
+
+func TestResourceNamespaceRead_NotFound(t *testing.T) {
+	useNamespaceTestCluster(t)
+
+	d := schema.TestResourceDataRaw(t, resourceNamespace().Schema, map[string]interface{}{
+		"kubeconfig": "apiVersion: v1",
+		"name":       "apps",
+	})
+	d.SetId("apps")
+
+	if diags := resourceNamespaceRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Error("expected the namespace to be removed from state")
+	}
+}
+
+func TestResourceNamespaceDelete(t *testing.T) {
+	for _, retain := range []bool{false, true} {
+		cluster := useNamespaceTestCluster(t, "apps")
+
+		d := schema.TestResourceDataRaw(t, resourceNamespace().Schema, map[string]interface{}{
+			"kubeconfig":        "apiVersion: v1",
+			"name":              "apps",
+			"retain_on_destroy": retain,
+		})
+		d.SetId("apps")
+
+		if diags := resourceNamespaceDelete(context.Background(), d, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if d.Id() != "" {
+			t.Error("expected id to be cleared")
+		}
+		if retain && len(cluster.deleted) != 0 {
+			t.Errorf("retain_on_destroy: expected no deletes, got %v", cluster.deleted)
+		}
+		if !retain && (len(cluster.deleted) != 1 || cluster.deleted[0] != "namespaces/apps") {
+			t.Errorf("deleted = %v", cluster.deleted)
+		}
+	}
+}
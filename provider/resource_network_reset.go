@@ -40,12 +40,12 @@ func resourceNetworkResetCreate(ctx context.Context, d *schema.ResourceData, met
 	config := meta.(*ProviderConfig)
 
 	if err := resetNetwork(config.Endpoint, config.Token); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to reset network: %w", err))
+		return diagFromErr(fmt.Errorf("failed to reset network: %w", err))
 	}
 
 	d.SetId("network-reset")
 	if err := d.Set("last_reset", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_reset: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_reset: %w", err))
 	}
 
 	return nil
@@ -63,11 +63,11 @@ func resourceNetworkResetUpdate(ctx context.Context, d *schema.ResourceData, met
 	// If triggers changed, perform a reset
 	if d.HasChange("triggers") {
 		if err := resetNetwork(config.Endpoint, config.Token); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to reset network: %w", err))
+			return diagFromErr(fmt.Errorf("failed to reset network: %w", err))
 		}
 
 		if err := d.Set("last_reset", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_reset: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_reset: %w", err))
 		}
 	}
 
@@ -1,7 +1,12 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -47,6 +52,38 @@ func resourceNode() *schema.Resource {
 				Default:     "login:",
 				Description: "Pattern to search for in UART output to confirm successful boot (e.g., 'login:' for standard Linux, 'machine is running and ready' for Talos)",
 			},
+			"module": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Compute module in the slot as reported by the BMC (e.g., RK1, CM4). Empty if the firmware does not report it.",
+			},
+			"board_model": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Board model detected from the BMC (Turing Pi 2 or Turing Pi 2.5). Empty if the firmware does not report a revision.",
+			},
+			"boot_time": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Seconds from power-on until boot_check_pattern appeared on the last boot check. Exact with UART streaming, otherwise accurate to the polling interval.",
+			},
+			"boot_check_method": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "How the last boot check read UART output: stream (BMC WebSocket endpoint), poll (older firmware) or console_capture (console_log_path is set)",
+			},
+			"console_log_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Local file the node's UART output is appended to while it is flashed and booted, for debugging nodes that hang during bring-up",
+			},
+			"on_power_change": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Commands run over SSH on the BMC or another host before and after the node changes power state, including on destroy.",
+				Elem:        powerHooksSchema(),
+			},
 		},
 	}
 }
@@ -60,11 +97,48 @@ func resourceNodeProvision(d *schema.ResourceData, meta interface{}) error {
 	timeout := d.Get("login_prompt_timeout").(int)
 	bootCheckPattern := d.Get("boot_check_pattern").(string)
 
+	// Capture the console for the whole provisioning run
+	var capture *consoleCapture
+	if path := d.Get("console_log_path").(string); path != "" {
+		var err error
+		if capture, err = startConsoleCapture(config.Endpoint, config.Token, node, path); err != nil {
+			return err
+		}
+		defer func() {
+			if err := capture.Stop(); err != nil {
+				log.Printf("[WARN] Failed to close console log for node %d: %v", node, err)
+			}
+		}()
+	}
+
+	// Open the UART stream before powering on, so no boot output is missed.
+	// Older firmware has no streaming endpoint and is polled instead.
+	var stream *uartStream
+	if bootCheck && capture == nil {
+		var err error
+		stream, err = openUARTStream(config.Endpoint, config.Token, node)
+		if err != nil {
+			if !errors.Is(err, errUARTStreamUnsupported) {
+				log.Printf("[WARN] UART streaming of node %d failed, falling back to polling: %v", node, err)
+			}
+			stream = nil
+		} else {
+			defer func() { _ = stream.Close() }()
+		}
+	}
+	poweredOnAt := time.Now()
+
 	// Step 1: Turn on the node
-	if powerState == "on" {
-		turnOnNode(node)
-	} else {
-		turnOffNode(node)
+	err := withPowerHooks(context.Background(), config, d, node, powerState, func() error {
+		if powerState == "on" {
+			turnOnNode(node)
+		} else {
+			turnOffNode(node)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set power state for node %d: %w", node, err)
 	}
 
 	// Step 2: Flash firmware if provided
@@ -75,13 +149,37 @@ func resourceNodeProvision(d *schema.ResourceData, meta interface{}) error {
 	// Step 3: Boot check
 	if bootCheck {
 		fmt.Printf("Checking boot status for node %d (pattern: %q)...\n", node, bootCheckPattern)
-		success, err := checkBootStatus(config.Endpoint, node, timeout, config.Token, bootCheckPattern)
-		if err != nil {
-			return fmt.Errorf("boot status check failed for node %d: %v", node, err)
+		var success bool
+		method := "poll"
+		remaining := time.Duration(timeout)*time.Second - time.Since(poweredOnAt)
+		switch {
+		case capture != nil:
+			// The capture drains the UART buffer, so match against its output
+			method = "console_capture"
+			success = capture.WaitFor(bootCheckPattern, remaining)
+		case stream != nil:
+			method = "stream"
+			success, err = stream.WaitFor(bootCheckPattern, remaining)
+			if err != nil {
+				return fmt.Errorf("boot status check failed for node %d: %v", node, err)
+			}
+		default:
+			success, err = checkBootStatus(config.Endpoint, node, timeout, config.Token, bootCheckPattern)
+			if err != nil {
+				return fmt.Errorf("boot status check failed for node %d: %v", node, err)
+			}
 		}
 		if !success {
 			return fmt.Errorf("node %d did not boot successfully", node)
 		}
+		bootTime := time.Since(poweredOnAt).Seconds()
+		log.Printf("[INFO] Node %d booted in %.1fs (%s)", node, bootTime, method)
+		if err := d.Set("boot_time", math.Round(bootTime*10)/10); err != nil {
+			return fmt.Errorf("failed to set boot_time: %v", err)
+		}
+		if err := d.Set("boot_check_method", method); err != nil {
+			return fmt.Errorf("failed to set boot_check_method: %v", err)
+		}
 	}
 
 	d.SetId(fmt.Sprintf("node-%d", node))
@@ -95,11 +193,31 @@ func resourceNodeStatus(d *schema.ResourceData, meta interface{}) error {
 	if err := d.Set("power_state", currentPower); err != nil {
 		return fmt.Errorf("failed to set power_state: %v", err)
 	}
+
+	// Hardware detection is informational, so BMC errors are not fatal
+	if config, ok := meta.(*ProviderConfig); ok {
+		if caps, err := detectBoardCapabilities(config.Endpoint, config.Token); err == nil {
+			if err := d.Set("module", caps.Modules[node]); err != nil {
+				return fmt.Errorf("failed to set module: %v", err)
+			}
+			if err := d.Set("board_model", caps.Model()); err != nil {
+				return fmt.Errorf("failed to set board_model: %v", err)
+			}
+		}
+	}
 	return nil
 }
 
 func resourceNodeDelete(d *schema.ResourceData, meta interface{}) error {
 	node := d.Get("node").(int)
-	turnOffNode(node)
-	return nil
+	if _, ok := d.GetOk("on_power_change"); !ok {
+		turnOffNode(node)
+		return nil
+	}
+
+	config := meta.(*ProviderConfig)
+	return withPowerHooks(context.Background(), config, d, node, "off", func() error {
+		turnOffNode(node)
+		return nil
+	})
 }
@@ -19,6 +19,7 @@ func resourceNodeToMSD() *schema.Resource {
 		ReadContext:   resourceNodeToMSDRead,
 		UpdateContext: resourceNodeToMSDUpdate,
 		DeleteContext: resourceNodeToMSDDelete,
+		CustomizeDiff: customizeDiffDefaultTriggers,
 		Schema: map[string]*schema.Schema{
 			"node": {
 				Type:             schema.TypeInt,
@@ -29,7 +30,8 @@ func resourceNodeToMSD() *schema.Resource {
 			"triggers": {
 				Type:        schema.TypeMap,
 				Optional:    true,
-				Description: "A map of values that, when changed, will trigger a reboot into MSD mode.",
+				Computed:    true,
+				Description: "A map of values that, when changed, will trigger a reboot into MSD mode. The provider default_metadata is merged in.",
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -49,12 +51,12 @@ func resourceNodeToMSDCreate(ctx context.Context, d *schema.ResourceData, meta i
 	node := d.Get("node").(int)
 
 	if err := nodeToMSD(config.Endpoint, config.Token, node); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to reboot node %d into MSD mode: %w", node, err))
+		return diagFromErr(fmt.Errorf("failed to reboot node %d into MSD mode: %w", node, err))
 	}
 
 	d.SetId(fmt.Sprintf("node-to-msd-%d", node))
 	if err := d.Set("last_triggered", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_triggered: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_triggered: %w", err))
 	}
 
 	return nil
@@ -70,13 +72,13 @@ func resourceNodeToMSDUpdate(ctx context.Context, d *schema.ResourceData, meta i
 	node := d.Get("node").(int)
 
 	// Re-trigger if node or triggers changed
-	if d.HasChange("node") || d.HasChange("triggers") {
+	if d.HasChange("node") || triggersChanged(d) {
 		if err := nodeToMSD(config.Endpoint, config.Token, node); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to reboot node %d into MSD mode: %w", node, err))
+			return diagFromErr(fmt.Errorf("failed to reboot node %d into MSD mode: %w", node, err))
 		}
 
 		if err := d.Set("last_triggered", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_triggered: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_triggered: %w", err))
 		}
 	}
 
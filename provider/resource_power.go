@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
@@ -13,7 +14,7 @@ import (
 
 func resourcePower() *schema.Resource {
 	return &schema.Resource{
-		Description:   "Manages power state of a Turing Pi compute node, including power on, power off, and reset operations.",
+		Description:   "Manages power state of a Turing Pi compute node, including power on, power off, reset and power cycle operations.",
 		CreateContext: resourcePowerCreate,
 		ReadContext:   resourcePowerRead,
 		UpdateContext: resourcePowerUpdate,
@@ -28,8 +29,35 @@ func resourcePower() *schema.Resource {
 			"state": {
 				Type:             schema.TypeString,
 				Required:         true,
-				Description:      "Power state: 'on', 'off', or 'reset'. Reset triggers a reboot and the state returns to 'on' after.",
-				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"on", "off", "reset"}, false)),
+				Description:      "Power state: 'on', 'off', 'reset' or 'cycle'. Reset triggers a reboot and the state returns to 'on' after. Cycle powers the node off, waits cycle_delay_seconds, powers it back on and verifies it is on.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"on", "off", "reset", "cycle"}, false)),
+			},
+			"cycle_delay_seconds": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          5,
+				Description:      "Seconds the node stays powered off when the state is 'cycle'",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(0, 600)),
+			},
+			"graceful": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Shut the node down gracefully (Kubernetes drain and/or OS shutdown over SSH) before cutting power when the state is set to 'off' or the resource is destroyed.",
+				Elem:        gracefulShutdownSchema(),
+			},
+			"on_power_change": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Commands run over SSH on the BMC or another host before and after the node changes power state, including on destroy.",
+				Elem:        powerHooksSchema(),
+			},
+			"keep_state_on_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Leave the node as it is when the resource is destroyed, only removing it from state. By default the node is powered off on destroy.",
 			},
 			// Computed attribute showing actual power state
 			"current_state": {
@@ -50,8 +78,11 @@ func resourcePowerCreate(ctx context.Context, d *schema.ResourceData, meta inter
 	node := d.Get("node").(int)
 	state := d.Get("state").(string)
 
-	if err := setPowerState(config.Endpoint, config.Token, node, state); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set power state: %w", err))
+	err := withPowerHooks(ctx, config, d, node, state, func() error {
+		return applyPowerState(ctx, config, d, node, state)
+	})
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to set power state: %w", err))
 	}
 
 	d.SetId(fmt.Sprintf("power-node-%d", node))
@@ -69,7 +100,7 @@ func resourcePowerRead(ctx context.Context, d *schema.ResourceData, meta interfa
 	// Fetch power status
 	status, err := getPowerStatus(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to read power status: %w", err))
+		return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
 	}
 
 	nodeStatus := parsePowerStatus(status)
@@ -82,7 +113,7 @@ func resourcePowerRead(ctx context.Context, d *schema.ResourceData, meta interfa
 	}
 
 	if err := d.Set("current_state", powered); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set current_state: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set current_state: %w", err))
 	}
 
 	return diags
@@ -91,11 +122,22 @@ func resourcePowerRead(ctx context.Context, d *schema.ResourceData, meta interfa
 func resourcePowerUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*ProviderConfig)
 
+	// Only settings such as graceful, on_power_change or the destroy behavior
+	// changed; do not touch the node (reset and cycle would reboot it, and off
+	// would run the hooks and graceful shutdown again). current_state was read
+	// by the refresh before this apply.
+	if !d.HasChanges("state", "node") {
+		return nil
+	}
+
 	node := d.Get("node").(int)
 	state := d.Get("state").(string)
 
-	if err := setPowerState(config.Endpoint, config.Token, node, state); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to update power state: %w", err))
+	err := withPowerHooks(ctx, config, d, node, state, func() error {
+		return applyPowerState(ctx, config, d, node, state)
+	})
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to update power state: %w", err))
 	}
 
 	// Update ID if node changed
@@ -110,9 +152,20 @@ func resourcePowerDelete(ctx context.Context, d *schema.ResourceData, meta inter
 
 	node := d.Get("node").(int)
 
+	if d.Get("keep_state_on_destroy").(bool) {
+		tflog.Info(ctx, "keep_state_on_destroy is set, leaving the node powered as it is", map[string]interface{}{
+			"node": node,
+		})
+		d.SetId("")
+		return nil
+	}
+
 	// On delete, power off the node
-	if err := setPowerState(config.Endpoint, config.Token, node, "off"); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to power off node on delete: %w", err))
+	err := withPowerHooks(ctx, config, d, node, "off", func() error {
+		return powerOffGracefully(ctx, config, d, node)
+	})
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to power off node on delete: %w", err))
 	}
 
 	d.SetId("")
@@ -141,12 +194,30 @@ func resourcePowerImport(ctx context.Context, d *schema.ResourceData, meta inter
 	if err := d.Set("state", "on"); err != nil {
 		return nil, fmt.Errorf("failed to set state: %w", err)
 	}
+	if err := d.Set("keep_state_on_destroy", false); err != nil {
+		return nil, fmt.Errorf("failed to set keep_state_on_destroy: %w", err)
+	}
+	if err := d.Set("cycle_delay_seconds", 5); err != nil {
+		return nil, fmt.Errorf("failed to set cycle_delay_seconds: %w", err)
+	}
 
 	d.SetId(fmt.Sprintf("power-node-%d", node))
 
 	return []*schema.ResourceData{d}, nil
 }
 
+// applyPowerState moves the node to state, shutting it down gracefully for
+// 'off' and power cycling it for 'cycle'
+func applyPowerState(ctx context.Context, config *ProviderConfig, d *schema.ResourceData, node int, state string) error {
+	switch state {
+	case "off":
+		return powerOffGracefully(ctx, config, d, node)
+	case "cycle":
+		return cyclePower(ctx, config, d, node)
+	}
+	return setPowerState(config.Endpoint, config.Token, node, state)
+}
+
 // setPowerState sets the power state for a node
 func setPowerState(endpoint, token string, node int, state string) error {
 	switch state {
@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// builtinPowerProfiles are available without a profile block
+var builtinPowerProfiles = map[string]map[string]bool{
+	"all-on":  {"node1": true, "node2": true, "node3": true, "node4": true},
+	"all-off": {"node1": false, "node2": false, "node3": false, "node4": false},
+}
+
+func resourcePowerProfile() *schema.Resource {
+	return &schema.Resource{
+		Description: "Applies a named power profile (a set of node on/off states) to the whole board. " +
+			"Built-in profiles are all-on and all-off; custom profiles are defined with profile blocks.",
+		CreateContext: resourcePowerProfileCreate,
+		ReadContext:   resourcePowerProfileRead,
+		UpdateContext: resourcePowerProfileUpdate,
+		DeleteContext: resourcePowerProfileDelete,
+		CustomizeDiff: resourcePowerProfileCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"active": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Name of the profile to apply: all-on, all-off, or the name of a profile block.",
+			},
+			"profile": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Custom power profiles. Nodes not listed are left untouched.",
+				Elem:        powerProfileSchema(),
+			},
+			// Computed attributes
+			"current_state": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Power status of all nodes as reported by the BMC (node1-node4 -> bool)",
+				Elem: &schema.Schema{
+					Type: schema.TypeBool,
+				},
+			},
+			"in_sync": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the board power state matches the active profile. A drifted board is corrected on the next apply.",
+			},
+		},
+	}
+}
+
+func powerProfileSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Profile name (e.g., compute-only).",
+			},
+			"nodes": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Description: "Desired power state per node (node1-node4 -> true for on, false for off).",
+				Elem: &schema.Schema{
+					Type: schema.TypeBool,
+				},
+			},
+		},
+	}
+}
+
+func resourcePowerProfileCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	desired, err := resolvePowerProfile(d.Get("active").(string), d.Get("profile").([]interface{}))
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	if err := applyPowerProfile(config.Endpoint, config.Token, desired); err != nil {
+		return diagFromErr(fmt.Errorf("failed to apply power profile: %w", err))
+	}
+
+	d.SetId("power-profile")
+
+	return resourcePowerProfileRead(ctx, d, meta)
+}
+
+func resourcePowerProfileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	status, err := getPowerStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to read power status: %w", err))
+	}
+	nodeStatus := parsePowerStatus(status)
+
+	if err := d.Set("current_state", nodeStatus); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set current_state: %w", err))
+	}
+
+	inSync := false
+	if desired, err := resolvePowerProfile(d.Get("active").(string), d.Get("profile").([]interface{})); err == nil {
+		inSync = powerProfileMatches(desired, nodeStatus)
+	}
+	if err := d.Set("in_sync", inSync); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set in_sync: %w", err))
+	}
+
+	return nil
+}
+
+func resourcePowerProfileUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*ProviderConfig)
+
+	desired, err := resolvePowerProfile(d.Get("active").(string), d.Get("profile").([]interface{}))
+	if err != nil {
+		return diagFromErr(err)
+	}
+
+	if err := applyPowerProfile(config.Endpoint, config.Token, desired); err != nil {
+		return diagFromErr(fmt.Errorf("failed to apply power profile: %w", err))
+	}
+
+	return resourcePowerProfileRead(ctx, d, meta)
+}
+
+func resourcePowerProfileDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Leave nodes in their current power state - only remove from state
+	d.SetId("")
+	return nil
+}
+
+// resourcePowerProfileCustomizeDiff plans a reapply when the board drifted from the active profile
+func resourcePowerProfileCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	if inSync, ok := d.Get("in_sync").(bool); ok && !inSync {
+		return d.SetNew("in_sync", true)
+	}
+	return nil
+}
+
+// expandPowerProfiles converts profile blocks into a name -> node states map, including built-ins
+func expandPowerProfiles(blocks []interface{}) (map[string]map[string]bool, error) {
+	profiles := make(map[string]map[string]bool, len(builtinPowerProfiles)+len(blocks))
+	for name, nodes := range builtinPowerProfiles {
+		profiles[name] = nodes
+	}
+
+	for _, b := range blocks {
+		block := b.(map[string]interface{})
+		name := block["name"].(string)
+		if _, ok := builtinPowerProfiles[name]; ok {
+			return nil, fmt.Errorf("profile name %q is reserved for a built-in profile", name)
+		}
+		nodes := make(map[string]bool)
+		for k, v := range block["nodes"].(map[string]interface{}) {
+			if _, ok := builtinPowerProfiles["all-on"][k]; !ok {
+				return nil, fmt.Errorf("profile %q: invalid node %q, expected node1-node4", name, k)
+			}
+			nodes[k] = v.(bool)
+		}
+		profiles[name] = nodes
+	}
+
+	return profiles, nil
+}
+
+// resolvePowerProfile returns the node states of the named profile
+func resolvePowerProfile(name string, blocks []interface{}) (map[string]bool, error) {
+	profiles, err := expandPowerProfiles(blocks)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown power profile %q (available: %v)", name, names)
+	}
+	return nodes, nil
+}
+
+// powerProfileMatches reports whether every node in the profile has the desired state
+func powerProfileMatches(desired, current map[string]bool) bool {
+	for node, on := range desired {
+		if current[node] != on {
+			return false
+		}
+	}
+	return true
+}
+
+// applyPowerProfile powers nodes off before powering others on, keeping peak draw low while switching
+func applyPowerProfile(endpoint, token string, desired map[string]bool) error {
+	for _, on := range []bool{false, true} {
+		for i := 1; i <= 4; i++ {
+			state, ok := desired[fmt.Sprintf("node%d", i)]
+			if !ok || state != on {
+				continue
+			}
+			if err := setNodePower(endpoint, token, i, on); err != nil {
+				return fmt.Errorf("node %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
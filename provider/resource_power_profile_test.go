@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// fakePowerBMC serves power get/set requests against an in-memory node state
+type fakePowerBMC struct {
+	mu     sync.Mutex
+	state  map[string]int
+	writes []string
+}
+
+func newFakePowerBMC(t *testing.T, initial map[string]int) *httptest.Server {
+	t.Helper()
+	bmc := &fakePowerBMC{state: initial}
+	server := httptest.NewServer(bmc)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (b *fakePowerBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := r.URL.Query()
+	if q.Get("opt") == "set" {
+		for k, v := range q {
+			if strings.HasPrefix(k, "node") {
+				b.state[k] = map[string]int{"0": 0, "1": 1}[v[0]]
+				b.writes = append(b.writes, k+"="+v[0])
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var response [][]interface{}
+	for _, n := range []string{"node1", "node2", "node3", "node4"} {
+		response = append(response, []interface{}{n, float64(b.state[n])})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"response": response})
+}
+
+func TestResourcePowerProfile(t *testing.T) {
+	r := resourcePowerProfile()
+	if err := r.InternalValidate(nil, true); err != nil {
+		t.Fatalf("resource internal validation failed: %s", err)
+	}
+}
+
+func TestResourcePowerProfile_SchemaTypes(t *testing.T) {
+	r := resourcePowerProfile()
+
+	tests := []struct {
+		field    string
+		expected schema.ValueType
+	}{
+		{"active", schema.TypeString},
+		{"profile", schema.TypeList},
+		{"current_state", schema.TypeMap},
+		{"in_sync", schema.TypeBool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			s, ok := r.Schema[tt.field]
+			if !ok {
+				t.Fatalf("schema missing '%s' field", tt.field)
+			}
+			if s.Type != tt.expected {
+				t.Errorf("expected %s to be type %v, got %v", tt.field, tt.expected, s.Type)
+			}
+		})
+	}
+}
+
+func TestResolvePowerProfile(t *testing.T) {
+	blocks := []interface{}{
+		map[string]interface{}{
+			"name":  "compute-only",
+			"nodes": map[string]interface{}{"node1": true, "node2": true, "node3": true, "node4": false},
+		},
+	}
+
+	nodes, err := resolvePowerProfile("compute-only", blocks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !nodes["node1"] || nodes["node4"] {
+		t.Errorf("unexpected profile nodes: %v", nodes)
+	}
+
+	nodes, err = resolvePowerProfile("all-off", nil)
+	if err != nil || len(nodes) != 4 || nodes["node2"] {
+		t.Errorf("unexpected all-off profile: %v, %v", nodes, err)
+	}
+
+	if _, err := resolvePowerProfile("missing", blocks); err == nil || !strings.Contains(err.Error(), "compute-only") {
+		t.Errorf("expected unknown profile error listing available profiles, got %v", err)
+	}
+
+	reserved := []interface{}{map[string]interface{}{"name": "all-on", "nodes": map[string]interface{}{"node1": false}}}
+	if _, err := resolvePowerProfile("all-on", reserved); err == nil {
+		t.Error("expected error for reserved profile name")
+	}
+
+	invalid := []interface{}{map[string]interface{}{"name": "bad", "nodes": map[string]interface{}{"node5": true}}}
+	if _, err := resolvePowerProfile("bad", invalid); err == nil {
+		t.Error("expected error for invalid node key")
+	}
+}
+
+func TestPowerProfileMatches(t *testing.T) {
+	current := map[string]bool{"node1": true, "node2": true, "node3": false, "node4": false}
+
+	if !powerProfileMatches(map[string]bool{"node1": true, "node3": false}, current) {
+		t.Error("expected partial profile to match")
+	}
+	if powerProfileMatches(builtinPowerProfiles["all-on"], current) {
+		t.Error("expected all-on not to match")
+	}
+}
+
+func TestResourcePowerProfileCreate_PowersOffFirst(t *testing.T) {
+	bmc := &fakePowerBMC{state: map[string]int{"node1": 0, "node2": 1, "node3": 1, "node4": 1}}
+	server := httptest.NewServer(bmc)
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourcePowerProfile().Schema, map[string]interface{}{
+		"active": "compute-only",
+		"profile": []interface{}{
+			map[string]interface{}{
+				"name":  "compute-only",
+				"nodes": map[string]interface{}{"node1": true, "node2": true, "node3": true, "node4": false},
+			},
+		},
+	})
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := resourcePowerProfileCreate(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if d.Id() != "power-profile" {
+		t.Errorf("expected ID 'power-profile', got '%s'", d.Id())
+	}
+	if len(bmc.writes) == 0 || bmc.writes[0] != "node4=0" {
+		t.Errorf("expected node4 to be powered off first, got %v", bmc.writes)
+	}
+	if !d.Get("in_sync").(bool) {
+		t.Error("expected in_sync after apply")
+	}
+	if d.Get("current_state.node4").(bool) {
+		t.Error("expected node4 to be off")
+	}
+}
+
+func TestResourcePowerProfileRead_Drift(t *testing.T) {
+	server := newFakePowerBMC(t, map[string]int{"node1": 1, "node2": 0, "node3": 1, "node4": 1})
+
+	d := schema.TestResourceDataRaw(t, resourcePowerProfile().Schema, map[string]interface{}{
+		"active": "all-on",
+	})
+	d.SetId("power-profile")
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+
+	diags := resourcePowerProfileRead(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Get("in_sync").(bool) {
+		t.Error("expected in_sync to be false when node2 is off")
+	}
+}
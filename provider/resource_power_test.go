@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
 func TestResourcePower(t *testing.T) {
@@ -590,3 +591,110 @@ func TestSetPowerState_InvalidState(t *testing.T) {
 		t.Error("expected error for invalid state")
 	}
 }
+
+func TestResourcePowerDelete_KeepStateOnDestroy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected no BMC request, got %s", r.URL.String())
+	}))
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourcePower().Schema, map[string]interface{}{
+		"node":                  1,
+		"state":                 "on",
+		"keep_state_on_destroy": true,
+	})
+	d.SetId("power-node-1")
+
+	config := &ProviderConfig{
+		Token:    "test-token",
+		Endpoint: server.URL,
+	}
+
+	diags := resourcePowerDelete(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Error("expected ID to be cleared after delete")
+	}
+}
+
+func TestResourcePowerUpdate_KeepStateOnDestroyOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("opt") == "set" {
+			t.Errorf("expected no power change, got %s", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"response":[{"result":{"node1":1,"node2":0,"node3":0,"node4":0}}]}`))
+	}))
+	defer server.Close()
+
+	r := resourcePower()
+	state := r.Data(nil)
+	state.SetId("power-node-1")
+	_ = state.Set("node", 1)
+	_ = state.Set("state", "reset")
+	_ = state.Set("keep_state_on_destroy", false)
+
+	diff, err := r.Diff(context.Background(), state.State(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"node":                  1,
+		"state":                 "reset",
+		"keep_state_on_destroy": true,
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state.State(), diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ProviderConfig{
+		Token:    "test-token",
+		Endpoint: server.URL,
+	}
+	if diags := resourcePowerUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+}
+
+func TestResourcePowerUpdate_HooksOnlyWithReset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected no BMC request, got %s", r.URL.String())
+	}))
+	defer server.Close()
+
+	r := resourcePower()
+	state := r.Data(nil)
+	state.SetId("power-node-1")
+	_ = state.Set("node", 1)
+	_ = state.Set("state", "reset")
+
+	diff, err := r.Diff(context.Background(), state.State(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"node":  1,
+		"state": "reset",
+		"on_power_change": []interface{}{map[string]interface{}{
+			"pre_command":  "logger pre",
+			"ssh_password": "turing",
+		}},
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state.State(), diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ProviderConfig{
+		Token:    "test-token",
+		Endpoint: server.URL,
+		sshClientFactory: func() SSHClient {
+			t.Error("expected no hook to run")
+			return &MockSSHClient{}
+		},
+	}
+	if diags := resourcePowerUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+}
@@ -0,0 +1,275 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceTalosCertRotation() *schema.Resource {
+	return &schema.Resource{
+		Description: "Rotates the client certificates of a Talos cluster, and optionally its CAs, without rebuilding the cluster. " +
+			"Rotation runs when this resource is created or when the triggers change. Requires talosctl in PATH.",
+		CreateContext: resourceTalosCertRotationCreate,
+		ReadContext:   resourceTalosCertRotationRead,
+		UpdateContext: resourceTalosCertRotationUpdate,
+		DeleteContext: resourceTalosCertRotationDelete,
+		Schema: map[string]*schema.Schema{
+			"talosconfig": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Current talosconfig content, e.g. turingpi_talos_cluster.talosconfig",
+			},
+			"control_plane_nodes": {
+				Type:        schema.TypeList,
+				Required:    true,
+				ForceNew:    true,
+				MinItems:    1,
+				Description: "IP addresses of the control plane nodes. The first one is used to generate the new credentials. Changing it rotates the certificates again.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"worker_nodes": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "IP addresses of the worker nodes, which receive the new CAs when rotate_ca is true. Changing it rotates the certificates again.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"rotate_ca": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Also replace the Talos API and Kubernetes CAs, applying the new machine configs to each node in turn (default: false). Without it, new client certificates are signed by the existing CAs. Changing it rotates the certificates again.",
+			},
+			"crt_ttl": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "8760h",
+				ForceNew:         true,
+				Description:      "Validity of the new talosconfig client certificate (default: 8760h, one year). Changing it rotates the certificates again.",
+				ValidateDiagFunc: validateDuration,
+			},
+			"health_timeout": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     600,
+				Description: "Timeout in seconds to wait for the cluster to be healthy with the new credentials (default: 600)",
+			},
+			"talosconfig_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path to write the new talosconfig to",
+			},
+			"kubeconfig_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path to write the new kubeconfig to",
+			},
+			"file_permission": filePermissionSchema(),
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "A map of values that, when changed, will trigger another rotation, e.g. a date ahead of certificate expiry.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			// Computed attributes
+			"new_talosconfig": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Talosconfig with the rotated client certificate",
+			},
+			"kubeconfig": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Kubeconfig with a new admin client certificate",
+			},
+			"last_rotation": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Timestamp of the last certificate rotation",
+			},
+		},
+	}
+}
+
+// validateDuration accepts Go durations such as 8760h
+var validateDuration = validation.ToDiagFunc(func(v interface{}, k string) ([]string, []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s must be a duration such as '8760h': %v", k, err)}
+	}
+	return nil, nil
+})
+
+func resourceTalosCertRotationCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	diags := rotateTalosCertificatesWithMeta(ctx, d, meta)
+	// After a CA rotation, the talosconfig signed by the new CA must reach
+	// state even when a later step failed, since the old one no longer works
+	if diags.HasError() && d.Get("new_talosconfig").(string) == "" {
+		return diags
+	}
+	d.SetId(fmt.Sprintf("talos-cert-rotation-%s", d.Get("control_plane_nodes.0").(string)))
+	return diags
+}
+
+func resourceTalosCertRotationRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Rotation is a trigger resource - nothing to read back
+	return nil
+}
+
+func resourceTalosCertRotationUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Rotate again only if triggers changed; the other settings are ForceNew
+	if !d.HasChange("triggers") {
+		return nil
+	}
+	return rotateTalosCertificatesWithMeta(ctx, d, meta)
+}
+
+func resourceTalosCertRotationDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Rotated certificates stay in place; nothing to undo
+	d.SetId("")
+	return nil
+}
+
+// rotateTalosCertificatesWithMeta sets up the talosctl (or dry run) provisioner
+// and rotates the certificates
+func rotateTalosCertificatesWithMeta(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	rec := newDryRunRecorder(meta)
+	if rec == nil {
+		if _, diags := checkBinary(talosctlBinary, ""); diags.HasError() {
+			return diags
+		}
+	}
+	provisioner, err := newTalosClusterProvisioner(rec)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	defer func() { _ = provisioner.Cleanup() }()
+
+	diags := rotateTalosCertificates(d, provisioner)
+	if rec != nil && !diags.HasError() {
+		return rec.diagnostics(ctx, "Talos certificate rotation")
+	}
+	return diags
+}
+
+// rotateTalosCertificates rotates the CAs if requested, generates a talosconfig
+// and kubeconfig with new client certificates, checks the cluster with them
+// and stores them. A failure marks d partial, so the previous credentials are
+// kept, unless the CAs were already replaced: the talosconfig signed by the
+// new CA is then stored, and written to talosconfig_path, as soon as it
+// exists, and kept when a later step fails.
+func rotateTalosCertificates(d *schema.ResourceData, provisioner *TalosProvisioner) diag.Diagnostics {
+	var diags diag.Diagnostics
+	caRotated := false
+	fail := func(err error) diag.Diagnostics {
+		if !caRotated {
+			d.Partial(true)
+		}
+		return append(diags, diagFromErr(err)...)
+	}
+	controlPlanes := expandStringList(d.Get("control_plane_nodes").([]interface{}))
+	workers := expandStringList(d.Get("worker_nodes").([]interface{}))
+	node := controlPlanes[0]
+
+	talosconfigPath := filepath.Join(provisioner.WorkDir(), "talosconfig")
+	if err := os.WriteFile(talosconfigPath, []byte(d.Get("talosconfig").(string)), 0600); err != nil {
+		return fail(fmt.Errorf("failed to write talosconfig: %w", err))
+	}
+
+	// 1. Replace the CAs; the talosconfig signed by the old CA stops working
+	if d.Get("rotate_ca").(bool) {
+		rotatedPath := filepath.Join(provisioner.WorkDir(), "talosconfig-rotated-ca")
+		if err := provisioner.RotateCA(talosconfigPath, controlPlanes, workers, rotatedPath); err != nil {
+			return fail(err)
+		}
+		rotated, err := provisioner.ReadTalosconfig(rotatedPath)
+		if err != nil {
+			return fail(fmt.Errorf("the CAs were rotated, but the new talosconfig could not be read: %w", err))
+		}
+		caRotated = true
+		if err := d.Set("new_talosconfig", rotated); err != nil {
+			return fail(err)
+		}
+		diags = append(diags, writeRotatedCredential(d, "talosconfig", "talosconfig_path", rotated)...)
+		talosconfigPath = rotatedPath
+	}
+
+	// 2. New client certificate for talosctl
+	newTalosconfigPath := filepath.Join(provisioner.WorkDir(), "talosconfig-new")
+	if err := provisioner.NewTalosconfig(talosconfigPath, node, d.Get("crt_ttl").(string), newTalosconfigPath); err != nil {
+		return fail(err)
+	}
+
+	// 3. Check the cluster accepts the new credentials before replacing the old ones
+	timeout := time.Duration(d.Get("health_timeout").(int)) * time.Second
+	if err := provisioner.WaitForHealth(newTalosconfigPath, node, timeout); err != nil {
+		return fail(fmt.Errorf("cluster is not healthy with the rotated talosconfig: %w", err))
+	}
+
+	// 4. New admin kubeconfig
+	kubeconfigPath := filepath.Join(provisioner.WorkDir(), "kubeconfig")
+	if err := provisioner.GetKubeconfig(newTalosconfigPath, node, kubeconfigPath); err != nil {
+		return fail(err)
+	}
+
+	newTalosconfig, err := provisioner.ReadTalosconfig(newTalosconfigPath)
+	if err != nil {
+		return fail(err)
+	}
+	kubeconfig, err := os.ReadFile(kubeconfigPath)
+	if err != nil && provisioner.DryRun == nil {
+		return fail(fmt.Errorf("failed to read kubeconfig: %w", err))
+	}
+
+	if err := d.Set("new_talosconfig", newTalosconfig); err != nil {
+		return fail(err)
+	}
+	if err := d.Set("kubeconfig", string(kubeconfig)); err != nil {
+		return fail(err)
+	}
+	if err := d.Set("last_rotation", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return fail(fmt.Errorf("failed to set last_rotation: %w", err))
+	}
+
+	// Write the new credentials to files if paths specified
+	diags = append(diags, writeRotatedCredential(d, "talosconfig", "talosconfig_path", newTalosconfig)...)
+	diags = append(diags, writeRotatedCredential(d, "kubeconfig", "kubeconfig_path", string(kubeconfig))...)
+	return diags
+}
+
+// writeRotatedCredential writes content to the file at pathKey, if set. A
+// failure is a warning, the credential is in state.
+func writeRotatedCredential(d *schema.ResourceData, name, pathKey, content string) diag.Diagnostics {
+	path := d.Get(pathKey).(string)
+	if path == "" || content == "" {
+		return nil
+	}
+	if err := writeCredentialFile(path, []byte(content), filePermission(d)); err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Failed to write %s file", name),
+			Detail:   fmt.Sprintf("Could not write %s to %s: %v", name, path, err),
+		}}
+	}
+	return nil
+}
+
+func expandStringList(list []interface{}) []string {
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		result = append(result, v.(string))
+	}
+	return result
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// certRotationExec fakes talosctl: commands that write a file write a marker
+// naming the command, so tests can tell which talosconfig was used
+func certRotationExec(calls *[]string) func(string, ...string) *exec.Cmd {
+	return func(name string, args ...string) *exec.Cmd {
+		joined := strings.Join(args, " ")
+		*calls = append(*calls, joined)
+		out := args[len(args)-1]
+		switch {
+		case strings.Contains(joined, "rotate-ca"):
+			for i, a := range args {
+				if a == "--output" {
+					out = args[i+1]
+				}
+			}
+			return exec.Command("sh", "-c", `printf 'context: rotated-ca' > "$0"`, out)
+		case strings.Contains(joined, "config new"):
+			return exec.Command("sh", "-c", `printf 'context: new-client' > "$0"`, out)
+		case strings.Contains(joined, " kubeconfig "):
+			return exec.Command("sh", "-c", `printf 'apiVersion: v1\nkind: Config' > "$0"`, out)
+		case strings.Contains(joined, "health"):
+			return exec.Command("true")
+		}
+		return exec.Command("false")
+	}
+}
+
+func TestRotateTalosCertificates(t *testing.T) {
+	var calls []string
+	provisioner := NewTalosProvisionerWithExec(certRotationExec(&calls))
+	defer func() { _ = provisioner.Cleanup() }()
+
+	dir := t.TempDir()
+	d := schema.TestResourceDataRaw(t, resourceTalosCertRotation().Schema, map[string]interface{}{
+		"talosconfig":         "context: old",
+		"control_plane_nodes": []interface{}{"10.10.88.73"},
+		"crt_ttl":             "720h",
+		"health_timeout":      10,
+		"talosconfig_path":    filepath.Join(dir, "talosconfig"),
+		"kubeconfig_path":     filepath.Join(dir, "kubeconfig"),
+	})
+
+	if diags := rotateTalosCertificates(d, provisioner); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	for _, c := range calls {
+		if strings.Contains(c, "rotate-ca") {
+			t.Errorf("expected no CA rotation without rotate_ca, got %q", c)
+		}
+	}
+	if !strings.Contains(calls[0], "config new --nodes 10.10.88.73 --roles os:admin --crt-ttl 720h") {
+		t.Errorf("expected a new client certificate first, got %v", calls)
+	}
+	if got := d.Get("new_talosconfig").(string); got != "context: new-client" {
+		t.Errorf("unexpected new_talosconfig %q", got)
+	}
+	if got := d.Get("kubeconfig").(string); !strings.Contains(got, "kind: Config") {
+		t.Errorf("unexpected kubeconfig %q", got)
+	}
+	if d.Get("last_rotation").(string) == "" {
+		t.Error("expected last_rotation to be set")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "talosconfig"))
+	if err != nil || string(data) != "context: new-client" {
+		t.Errorf("expected the new talosconfig at talosconfig_path, got %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "kubeconfig"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected kubeconfig written with mode 0600, got %v, %v", info, err)
+	}
+}
+
+func TestRotateTalosCertificates_RotateCA(t *testing.T) {
+	var calls []string
+	provisioner := NewTalosProvisionerWithExec(certRotationExec(&calls))
+	defer func() { _ = provisioner.Cleanup() }()
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCertRotation().Schema, map[string]interface{}{
+		"talosconfig":         "context: old",
+		"control_plane_nodes": []interface{}{"10.10.88.73", "10.10.88.74"},
+		"worker_nodes":        []interface{}{"10.10.88.75"},
+		"rotate_ca":           true,
+		"crt_ttl":             "8760h",
+		"health_timeout":      10,
+	})
+
+	if diags := rotateTalosCertificates(d, provisioner); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if !strings.Contains(calls[0], "rotate-ca --nodes 10.10.88.73 --control-plane-nodes 10.10.88.73,10.10.88.74") ||
+		!strings.Contains(calls[0], "--dry-run=false") || !strings.Contains(calls[0], "--worker-nodes 10.10.88.75") {
+		t.Errorf("expected rotate-ca on all nodes first, got %v", calls)
+	}
+	// Later commands authenticate with the talosconfig signed by the new CA
+	rotated := filepath.Join(provisioner.WorkDir(), "talosconfig-rotated-ca")
+	if !strings.HasPrefix(calls[1], "--talosconfig "+rotated+" config new") {
+		t.Errorf("expected the new client certificate to be requested with the rotated talosconfig, got %q", calls[1])
+	}
+}
+
+func TestRotateTalosCertificates_Unhealthy(t *testing.T) {
+	var calls []string
+	fake := certRotationExec(&calls)
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "health") {
+			return exec.Command("false")
+		}
+		return fake(name, args...)
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCertRotation().Schema, map[string]interface{}{
+		"talosconfig":         "context: old",
+		"control_plane_nodes": []interface{}{"10.10.88.73"},
+		"crt_ttl":             "8760h",
+		"health_timeout":      0,
+	})
+
+	diags := rotateTalosCertificates(d, provisioner)
+	if !diags.HasError() {
+		t.Fatal("expected an error when the cluster rejects the new credentials")
+	}
+	if d.Get("new_talosconfig").(string) != "" {
+		t.Error("expected new_talosconfig to stay empty")
+	}
+}
+
+func TestRotateTalosCertificates_RotateCAThenUnhealthy(t *testing.T) {
+	var calls []string
+	fake := certRotationExec(&calls)
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "health") {
+			return exec.Command("false")
+		}
+		return fake(name, args...)
+	})
+
+	path := filepath.Join(t.TempDir(), "talosconfig")
+	d := schema.TestResourceDataRaw(t, resourceTalosCertRotation().Schema, map[string]interface{}{
+		"talosconfig":         "context: old",
+		"control_plane_nodes": []interface{}{"10.10.88.73"},
+		"rotate_ca":           true,
+		"crt_ttl":             "8760h",
+		"health_timeout":      0,
+		"talosconfig_path":    path,
+	})
+	d.SetId("talos-cert-rotation-10.10.88.73")
+
+	diags := rotateTalosCertificates(d, provisioner)
+	_ = provisioner.Cleanup()
+	if !diags.HasError() {
+		t.Fatal("expected an error when the cluster rejects the new credentials")
+	}
+	// The old talosconfig no longer works, so the one signed by the new CA is kept
+	if got := d.State().Attributes["new_talosconfig"]; got != "context: rotated-ca" {
+		t.Errorf("expected the rotated talosconfig in state, got %q", got)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "context: rotated-ca" {
+		t.Errorf("expected the rotated talosconfig at talosconfig_path, got %q, %v", data, err)
+	}
+}
+
+func TestResourceTalosCertRotation_DryRun(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCertRotation().Schema, map[string]interface{}{
+		"talosconfig":         "context: old",
+		"control_plane_nodes": []interface{}{"10.10.88.73"},
+		"rotate_ca":           true,
+		"crt_ttl":             "8760h",
+		"health_timeout":      10,
+	})
+
+	diags := resourceTalosCertRotationCreate(context.Background(), d, &ProviderConfig{DryRun: true})
+	if !diags.HasError() {
+		t.Fatal("expected dry_run to fail the apply with the script")
+	}
+	if !strings.Contains(diags[0].Detail, "talosctl --talosconfig $WORKDIR/talosconfig rotate-ca") {
+		t.Errorf("expected rotate-ca in the script, got:\n%s", diags[0].Detail)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected no ID under dry_run, got %q", d.Id())
+	}
+}
@@ -4,10 +4,25 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Talos cluster provisioning modes
+const (
+	talosModeFull        = "full"
+	talosModeWorkersOnly = "workers_only"
+)
+
+// Values of replace_strategy
+const (
+	talosReplaceRecreate   = "recreate"
+	talosReplaceReuseNodes = "reuse_nodes"
 )
 
 func resourceTalosCluster() *schema.Resource {
@@ -16,10 +31,11 @@ func resourceTalosCluster() *schema.Resource {
 		DeprecationMessage: "turingpi_talos_cluster is deprecated and will be removed in v2.0.0. " +
 			"Use the terraform-turingpi-modules/talos-cluster module instead. " +
 			"See: https://github.com/jfreed-dev/terraform-turingpi-modules",
-		CreateContext: resourceTalosClusterCreate,
-		ReadContext:   resourceTalosClusterRead,
-		UpdateContext: resourceTalosClusterUpdate,
+		CreateContext: withClusterSummary(withProvisionLogs(resourceTalosClusterCreate), talosClusterSummary),
+		ReadContext:   withClusterSummary(resourceTalosClusterRead, talosClusterSummary),
+		UpdateContext: withClusterSummary(resourceTalosClusterUpdate, talosClusterSummary),
 		DeleteContext: resourceTalosClusterDelete,
+		CustomizeDiff: resourceTalosClusterCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:        schema.TypeString,
@@ -48,16 +64,38 @@ func resourceTalosCluster() *schema.Resource {
 			"install_disk": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Default:     "/dev/mmcblk0",
+				Default:     emmcInstallDisk,
+				ForceNew:    true,
+				Description: "Install disk for Talos (default: /dev/mmcblk0 for eMMC). When not set, RK1 nodes with a slot install to NVMe if present.",
+			},
+			"mode": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          talosModeFull,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressDefaultFill(talosModeFull),
+				Description:      "Provisioning mode: 'full' (control planes and workers) or 'workers_only' (apply worker configs to join a cluster whose control plane is managed elsewhere, using existing_secrets_yaml).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{talosModeFull, talosModeWorkersOnly}, false)),
+			},
+			"existing_secrets_yaml": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
 				ForceNew:    true,
-				Description: "Install disk for Talos (default: /dev/mmcblk0 for eMMC).",
+				Description: "Secrets (talosctl gen secrets output) of the existing cluster. Required in workers_only mode.",
+			},
+			"existing_talosconfig": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				ForceNew:    true,
+				Description: "Talosconfig of the existing cluster, used instead of the one generated from existing_secrets_yaml in workers_only mode.",
 			},
 			"control_plane": {
 				Type:        schema.TypeList,
-				Required:    true,
-				MinItems:    1,
+				Optional:    true,
 				ForceNew:    true,
-				Description: "Control plane node configuration.",
+				Description: "Control plane node configuration. Required unless mode is workers_only.",
 				Elem:        talosNodeSchema(),
 			},
 			"worker": {
@@ -71,9 +109,74 @@ func resourceTalosCluster() *schema.Resource {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     true,
-				ForceNew:    true,
 				Description: "Allow scheduling workloads on control plane nodes.",
 			},
+			"host_dns": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Host DNS resolver settings (machine.features.hostDNS) for every node. Talos defaults apply when not set.",
+				Elem:        talosHostDNSSchema(),
+			},
+			"time_sync": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Time synchronization settings (machine.time) for every node, e.g. LAN NTP servers behind NAT. Talos defaults apply when not set.",
+				Elem:        talosTimeSyncSchema(),
+			},
+			"kubespan": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Enable KubeSpan (machine.network.kubespan) on every node, a WireGuard mesh between nodes found through cluster discovery, so nodes on different L2 segments or sites form one cluster.",
+			},
+			"cni": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          talosCNIFlannel,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressDefaultFill(talosCNIFlannel),
+				Description:      "CNI Talos installs (cluster.network.cni): flannel (default), or none for a CNI installed separately, such as with the cilium block",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{talosCNIFlannel, talosCNINone}, false)),
+			},
+			"cilium": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Install Cilium with the Helm client after bootstrap, before the cluster health check, with settings for Talos. Requires cni = \"none\".",
+				Elem:        talosCiliumSchema(),
+			},
+			"pod_cidrs":     talosCIDRListSchema("Pod subnets (cluster.network.podSubnets): one CIDR, or an IPv4 and an IPv6 CIDR for a dual-stack cluster. Talos defaults to " + talosDefaultPodSubnet + "."),
+			"service_cidrs": talosCIDRListSchema("Service subnets (cluster.network.serviceSubnets), covering the same address families as pod_cidrs. Talos defaults to " + talosDefaultServiceSubnet + "."),
+			"pod_security": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Default Pod Security Standard levels and exemptions of the PodSecurity admission plugin, patched into the control plane configs. Talos defaults apply when not set.",
+				Elem:        talosPodSecuritySchema(),
+			},
+			"admission_control": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "API server admission plugin configurations (cluster.apiServer.admissionControl) patched into the control plane configs. Plugins replace the Talos entry of the same name.",
+				Elem:        talosAdmissionPluginSchema(),
+			},
+			"apply_mode": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          talosApplyAuto,
+				Description:      "How Update applies changed machine settings (allow_scheduling_on_control_plane, host_dns, time_sync, kubespan, pod_security, admission_control) to the nodes: 'auto' (default) reboots nodes only when a change requires it, 'no-reboot' fails instead of rebooting, 'staged' applies the config at the next reboot (see reboot_nodes).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{talosApplyAuto, talosApplyNoReboot, talosApplyStaged}, false)),
+			},
+			"reboot_nodes": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Hosts of cluster nodes to reboot, one at a time, when they are added to this list, e.g. to roll staged configs out. Nodes stay in the list; remove and re-add a host to reboot it again. Ignored on create.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 			"metallb": {
 				Type:        schema.TypeList,
 				Optional:    true,
@@ -88,6 +191,7 @@ func resourceTalosCluster() *schema.Resource {
 				Description: "NGINX Ingress controller configuration.",
 				Elem:        ingressSchema(),
 			},
+			"image_registry_mirror": imageRegistryMirrorSchema(),
 			"bootstrap_timeout": {
 				Type:        schema.TypeInt,
 				Optional:    true,
@@ -109,6 +213,47 @@ func resourceTalosCluster() *schema.Resource {
 				Optional:    true,
 				Description: "Path to write the cluster secrets file (for backup).",
 			},
+			"file_permission": filePermissionSchema(),
+			"logs_dir":        logsDirSchema(),
+			"node_logs":       nodeLogsSchema(),
+			"replace_strategy": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          talosReplaceRecreate,
+				Description:      "What destroying the cluster, including for a replacement, does to the nodes: 'recreate' (default) resets them, 'reuse_nodes' leaves them installed so the replacement re-adopts them with the secrets in secrets_path instead of reinstalling. Requires secrets_path outside workers_only mode.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{talosReplaceRecreate, talosReplaceReuseNodes}, false)),
+			},
+			"reset_wipe_mode": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          talosWipeAll,
+				Description:      "Which disks destroying the cluster wipes when it resets the nodes: 'all' (default) wipes the system disk and the user disks of the machine config, 'system-disk' only the system disk, keeping data disks such as Longhorn NVMe volumes, 'user-disks' only the disks in reset_user_disks.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{talosWipeAll, talosWipeSystemDisk, talosWipeUserDisks}, false)),
+			},
+			"reset_system_labels": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "System disk partitions to wipe on reset, by label (e.g. STATE, EPHEMERAL). When set, only these partitions are wiped instead of the whole system disk.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+			},
+			"reset_user_disks": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "User disks to wipe on reset (e.g. /dev/nvme0n1). Requires reset_wipe_mode 'user-disks'.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+			},
+			"store_sensitive_outputs": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Store kubeconfig and its certificates, talosconfig and secrets_yaml in Terraform state. When false, they are left empty and only written to their *_path files; talosconfig_path is then required.",
+			},
 			// Computed outputs
 			"kubeconfig": {
 				Type:        schema.TypeString,
@@ -116,6 +261,29 @@ func resourceTalosCluster() *schema.Resource {
 				Sensitive:   true,
 				Description: "Kubeconfig content for accessing the cluster.",
 			},
+			"host": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Kubernetes API server URL from the kubeconfig.",
+			},
+			"cluster_ca_certificate": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "PEM-encoded cluster CA certificate from the kubeconfig.",
+			},
+			"client_certificate": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "PEM-encoded client certificate from the kubeconfig.",
+			},
+			"client_key": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "PEM-encoded client key from the kubeconfig.",
+			},
 			"talosconfig": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -138,6 +306,7 @@ func resourceTalosCluster() *schema.Resource {
 				Computed:    true,
 				Description: "Current status of the cluster (bootstrapping, ready, degraded).",
 			},
+			"summary": clusterSummarySchema(),
 		},
 	}
 }
@@ -155,10 +324,127 @@ func talosNodeSchema() *schema.Resource {
 				Optional:    true,
 				Description: "Hostname to assign to the node (defaults to turing-cp-N or turing-w-N).",
 			},
+			"slot": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Description:      "Turing Pi slot (1-4) of the node. When set and the BMC reports an RK1 module in the slot, the install disk defaults to NVMe if the node has one.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
+			},
+			"install_disk": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Install disk for this node, overriding the cluster install_disk.",
+			},
 		},
 	}
 }
 
+// resolveTalosInstallDisks sets RK1-aware install disks on nodes with a slot
+// and no install_disk of their own, unless install_disk was set for the
+// cluster. RK1 nodes install to NVMe when the disk is present, and to eMMC
+// otherwise. Detection is best-effort: any failure keeps the cluster default.
+func resolveTalosInstallDisks(ctx context.Context, d *schema.ResourceData, meta interface{}, provisioner *TalosProvisioner, cfg *TalosClusterConfig) {
+	if d.Get("install_disk").(string) != emmcInstallDisk {
+		return
+	}
+	if raw := d.GetRawConfig(); !raw.IsNull() && !raw.GetAttr("install_disk").IsNull() {
+		return
+	}
+	config, ok := meta.(*ProviderConfig)
+	if !ok {
+		return
+	}
+
+	var caps *boardCapabilities
+	for _, nodes := range [][]TalosNodeConfig{cfg.ControlPlanes, cfg.Workers} {
+		for i := range nodes {
+			node := &nodes[i]
+			if node.InstallDisk != "" || node.Slot == 0 {
+				continue
+			}
+			if caps == nil {
+				var err error
+				if caps, err = detectBoardCapabilities(config.Endpoint, config.Token); err != nil {
+					tflog.Warn(ctx, "Board detection failed, using the cluster install disk", map[string]interface{}{"error": err.Error()})
+					return
+				}
+			}
+			if !caps.IsRK1(node.Slot) {
+				continue
+			}
+
+			disks, err := provisioner.ListDisks(node.Host)
+			if err != nil {
+				tflog.Warn(ctx, "Could not list disks of RK1 node, using the cluster install disk", map[string]interface{}{
+					"host":  node.Host,
+					"error": err.Error(),
+				})
+				continue
+			}
+			node.InstallDisk = selectRK1InstallDisk(disks)
+			tflog.Info(ctx, "Selected install disk for RK1 node", map[string]interface{}{
+				"host":         node.Host,
+				"slot":         node.Slot,
+				"install_disk": node.InstallDisk,
+			})
+		}
+	}
+}
+
+// validateTalosMode checks the node blocks and join settings required by the cluster mode
+func validateTalosMode(d *schema.ResourceData, cfg TalosClusterConfig) error {
+	if !cfg.WorkersOnly {
+		if len(cfg.ControlPlanes) == 0 {
+			return fmt.Errorf("at least one control_plane block is required unless mode is %s", talosModeWorkersOnly)
+		}
+		return nil
+	}
+
+	if len(cfg.ControlPlanes) > 0 {
+		return fmt.Errorf("control_plane must not be set in %s mode, the control plane is managed elsewhere", talosModeWorkersOnly)
+	}
+	if d.Get("existing_secrets_yaml").(string) == "" {
+		return fmt.Errorf("existing_secrets_yaml is required in %s mode", talosModeWorkersOnly)
+	}
+	if len(cfg.Workers) == 0 {
+		return fmt.Errorf("at least one worker block is required in %s mode", talosModeWorkersOnly)
+	}
+	for _, addon := range []string{"metallb", "ingress", "cilium"} {
+		if v, ok := d.GetOk(addon); ok && len(v.([]interface{})) > 0 {
+			return fmt.Errorf("%s is not supported in %s mode, deploy it with the existing cluster", addon, talosModeWorkersOnly)
+		}
+	}
+	return nil
+}
+
+// resourceTalosClusterCustomizeDiff checks the reset wipe options and
+// reboot_nodes, recomputes node_logs when logs_dir changes, and checks that a
+// reuse_nodes replacement can re-adopt the nodes: configured nodes only
+// accept configs signed with the secrets they were installed with
+func resourceTalosClusterCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if err := validateTalosResetOptions(extractTalosResetOptions(d.Get)); err != nil {
+		return err
+	}
+	if err := validateTalosRebootNodes(d.Get); err != nil {
+		return err
+	}
+	if err := validateTalosCNI(d.Get); err != nil {
+		return err
+	}
+	if d.Id() != "" && d.HasChange("logs_dir") {
+		if err := d.SetNewComputed("node_logs"); err != nil {
+			return err
+		}
+	}
+	if d.Get("replace_strategy").(string) != talosReplaceReuseNodes || d.Get("mode").(string) == talosModeWorkersOnly {
+		return nil
+	}
+	if d.Get("secrets_path").(string) == "" {
+		return fmt.Errorf("replace_strategy %q requires secrets_path, so the replacement cluster can reuse the secrets of the installed nodes", talosReplaceReuseNodes)
+	}
+	return nil
+}
+
 func extractTalosNodeConfig(data map[string]interface{}) TalosNodeConfig {
 	config := TalosNodeConfig{}
 
@@ -168,6 +454,12 @@ func extractTalosNodeConfig(data map[string]interface{}) TalosNodeConfig {
 	if v, ok := data["hostname"].(string); ok {
 		config.Hostname = v
 	}
+	if v, ok := data["slot"].(int); ok {
+		config.Slot = v
+	}
+	if v, ok := data["install_disk"].(string); ok {
+		config.InstallDisk = v
+	}
 
 	return config
 }
@@ -180,8 +472,26 @@ func extractTalosClusterConfig(d *schema.ResourceData) TalosClusterConfig {
 		InstallDisk:         d.Get("install_disk").(string),
 		AllowSchedulingOnCP: d.Get("allow_scheduling_on_control_plane").(bool),
 		BootstrapTimeout:    time.Duration(d.Get("bootstrap_timeout").(int)) * time.Second,
+		WorkersOnly:         d.Get("mode").(string) == talosModeWorkersOnly,
+		CNI:                 d.Get("cni").(string),
+		Cilium:              extractTalosCilium(d.Get),
+	}
+
+	if cfg.WorkersOnly {
+		// Join the existing cluster with its secrets and, if given, its talosconfig
+		cfg.SecretsYAML = d.Get("existing_secrets_yaml").(string)
+		cfg.Talosconfig = d.Get("existing_talosconfig").(string)
+	} else if secretsPath := d.Get("secrets_path").(string); secretsPath != "" {
+		// Reuse secrets from a previous partial run so already-configured nodes accept the new config
+		if data, err := os.ReadFile(secretsPath); err == nil {
+			cfg.SecretsYAML = string(data)
+		}
 	}
 
+	extractTalosMachineSettings(d, &cfg)
+	extractTalosNetwork(d, &cfg)
+	extractTalosAdmission(d, &cfg)
+
 	// Extract control plane nodes
 	if v, ok := d.GetOk("control_plane"); ok {
 		for _, cp := range v.([]interface{}) {
@@ -203,45 +513,111 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 	var diags diag.Diagnostics
 
 	cfg := extractTalosClusterConfig(d)
+	if err := validateTalosMode(d, cfg); err != nil {
+		return diagFromErr(err)
+	}
+	if err := validateTalosAdmission(cfg); err != nil {
+		return diagFromErr(err)
+	}
+	if err := validateTalosNetwork(cfg); err != nil {
+		return diagFromErr(err)
+	}
+	cfg.NodeLabels = defaultMetadata(meta)
+
+	// Without a stored talosconfig, Read and Delete rely on the talosconfig file
+	if !storeSensitiveOutputs(d) && d.Get("talosconfig_path").(string) == "" {
+		return diag.Errorf("talosconfig_path must be set when store_sensitive_outputs is false")
+	}
+
+	rec := newDryRunRecorder(meta)
+	if rec == nil {
+		// Fail early with an actionable message when talosctl is missing (e.g., on Terraform Cloud)
+		if _, diags := checkBinary(talosctlBinary, ""); diags.HasError() {
+			return diags
+		}
+	}
 
 	// Create provisioner
-	provisioner, err := NewTalosProvisioner()
+	provisioner, err := newTalosClusterProvisioner(rec)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to create Talos provisioner: %w", err))
+		return diagFromErr(fmt.Errorf("failed to create Talos provisioner: %w", err))
 	}
 	defer func() { _ = provisioner.Cleanup() }()
+	if cfg.Cilium != nil {
+		provisioner.InstallCNI = ciliumInstaller(*cfg.Cilium)
+	}
+
+	logs, err := provisionLogsFor(d)
+	if err != nil {
+		return diagFromErr(err)
+	}
+	provisioner.Logs = logs
+	if err := setNodeLogs(d, logs, clusterHosts(d)); err != nil {
+		return diagFromErr(err)
+	}
+
+	// Persist secrets before touching any node, so a re-run after a partial
+	// failure reuses them and can securely reapply to configured nodes
+	if secretsPath := d.Get("secrets_path").(string); secretsPath != "" && cfg.SecretsYAML == "" {
+		// talosctl runs in the provisioner work dir, so resolve relative paths first
+		absPath, err := filepath.Abs(secretsPath)
+		if err != nil {
+			return diagFromErr(err)
+		}
+		// talosctl writes the file itself, so check the path before it does
+		if err := checkCredentialPath(absPath); err != nil {
+			return diagFromErr(err)
+		}
+		if err := provisioner.GenerateSecrets(absPath); err != nil {
+			return diagFromErr(err)
+		}
+		secrets, err := provisioner.ReadSecrets(absPath)
+		if err != nil {
+			return diagFromErr(err)
+		}
+		cfg.SecretsYAML = secrets
+	}
+
+	resolveTalosInstallDisks(ctx, d, meta, provisioner, &cfg)
 
 	// Set initial status
 	if err := d.Set("cluster_status", "bootstrapping"); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
 	}
 
 	// Provision the cluster
 	state, err := provisioner.ProvisionCluster(ctx, cfg)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to provision cluster: %w", err))
+		return diagFromErr(fmt.Errorf("failed to provision cluster: %w", err))
+	}
+	if rec != nil {
+		rec.comment("then deploy the enabled add-ons (MetalLB, NGINX Ingress) through the Kubernetes API")
+		return rec.diagnostics(ctx, fmt.Sprintf("create of Talos cluster %q", cfg.Name))
 	}
 
 	// Set computed values
-	if err := d.Set("kubeconfig", state.Kubeconfig); err != nil {
-		return diag.FromErr(err)
+	if err := setSensitiveOutput(d, "kubeconfig", state.Kubeconfig); err != nil {
+		return diagFromErr(err)
+	}
+	if err := setKubeconfigCredentials(d, state.Kubeconfig); err != nil {
+		return diagFromErr(err)
 	}
-	if err := d.Set("talosconfig", state.Talosconfig); err != nil {
-		return diag.FromErr(err)
+	if err := setSensitiveOutput(d, "talosconfig", state.Talosconfig); err != nil {
+		return diagFromErr(err)
 	}
-	if err := d.Set("secrets_yaml", state.SecretsYAML); err != nil {
-		return diag.FromErr(err)
+	if err := setSensitiveOutput(d, "secrets_yaml", state.SecretsYAML); err != nil {
+		return diagFromErr(err)
 	}
 	if err := d.Set("api_endpoint", state.APIEndpoint); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
 	}
 	if err := d.Set("cluster_status", state.ClusterStatus); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
 	}
 
 	// Write kubeconfig to file if path specified
 	if kubeconfigPath := d.Get("kubeconfig_path").(string); kubeconfigPath != "" && state.Kubeconfig != "" {
-		if err := os.WriteFile(kubeconfigPath, []byte(state.Kubeconfig), 0600); err != nil {
+		if err := writeCredentialFile(kubeconfigPath, []byte(state.Kubeconfig), filePermission(d)); err != nil {
 			diags = append(diags, diag.Diagnostic{
 				Severity: diag.Warning,
 				Summary:  "Failed to write kubeconfig file",
@@ -252,7 +628,7 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 
 	// Write talosconfig to file if path specified
 	if talosconfigPath := d.Get("talosconfig_path").(string); talosconfigPath != "" && state.Talosconfig != "" {
-		if err := os.WriteFile(talosconfigPath, []byte(state.Talosconfig), 0600); err != nil {
+		if err := writeCredentialFile(talosconfigPath, []byte(state.Talosconfig), filePermission(d)); err != nil {
 			diags = append(diags, diag.Diagnostic{
 				Severity: diag.Warning,
 				Summary:  "Failed to write talosconfig file",
@@ -263,7 +639,7 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 
 	// Write secrets to file if path specified
 	if secretsPath := d.Get("secrets_path").(string); secretsPath != "" && state.SecretsYAML != "" {
-		if err := os.WriteFile(secretsPath, []byte(state.SecretsYAML), 0600); err != nil {
+		if err := writeCredentialFile(secretsPath, []byte(state.SecretsYAML), filePermission(d)); err != nil {
 			diags = append(diags, diag.Diagnostic{
 				Severity: diag.Warning,
 				Summary:  "Failed to write secrets file",
@@ -277,15 +653,15 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 		// Create temp kubeconfig file for addon deployment
 		kubeconfigFile, err := os.CreateTemp("", "kubeconfig-*")
 		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to create temp kubeconfig: %w", err))
+			return diagFromErr(fmt.Errorf("failed to create temp kubeconfig: %w", err))
 		}
 		defer func() { _ = os.Remove(kubeconfigFile.Name()) }()
 
 		if _, err := kubeconfigFile.WriteString(state.Kubeconfig); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to write temp kubeconfig: %w", err))
+			return diagFromErr(fmt.Errorf("failed to write temp kubeconfig: %w", err))
 		}
 		if err := kubeconfigFile.Close(); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to close temp kubeconfig: %w", err))
+			return diagFromErr(fmt.Errorf("failed to close temp kubeconfig: %w", err))
 		}
 
 		// Deploy MetalLB if enabled
@@ -293,7 +669,7 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 			metallbConfig := metallbList[0].(map[string]interface{})
 			if enabled, ok := metallbConfig["enabled"].(bool); ok && enabled {
 				ipRange := metallbConfig["ip_range"].(string)
-				if err := deployMetalLB(ctx, kubeconfigFile.Name(), ipRange); err != nil {
+				if err := deployMetalLB(ctx, kubeconfigFile.Name(), ipRange, d.Get("image_registry_mirror").(string)); err != nil {
 					diags = append(diags, diag.Diagnostic{
 						Severity: diag.Warning,
 						Summary:  "Failed to deploy MetalLB",
@@ -322,7 +698,7 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 				}
 
 				if ingressIP != "" {
-					if err := deployNginxIngress(ctx, kubeconfigFile.Name(), ingressIP); err != nil {
+					if err := deployNginxIngress(ctx, kubeconfigFile.Name(), ingressIP, d.Get("image_registry_mirror").(string)); err != nil {
 						diags = append(diags, diag.Diagnostic{
 							Severity: diag.Warning,
 							Summary:  "Failed to deploy NGINX Ingress",
@@ -342,79 +718,147 @@ func resourceTalosClusterCreate(ctx context.Context, d *schema.ResourceData, met
 func resourceTalosClusterRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 
-	// Get stored talosconfig
-	talosconfig := d.Get("talosconfig").(string)
+	// Get stored talosconfig, falling back to talosconfig_path
+	talosconfig := readSensitiveOutput(d, "talosconfig", "talosconfig_path")
 	if talosconfig == "" {
+		if !storeSensitiveOutputs(d) {
+			// talosconfig file is missing, so health cannot be checked
+			if err := d.Set("cluster_status", "unknown"); err != nil {
+				return diagFromErr(err)
+			}
+			return diags
+		}
 		// No talosconfig means cluster doesn't exist
 		d.SetId("")
 		return diags
 	}
 
-	// Get control plane IP
-	controlPlanes := d.Get("control_plane").([]interface{})
-	if len(controlPlanes) == 0 {
+	cfg := extractTalosClusterConfig(d)
+	if len(cfg.ControlPlanes) == 0 && !cfg.WorkersOnly {
 		d.SetId("")
 		return diags
 	}
 
-	cpConfig := controlPlanes[0].(map[string]interface{})
-	cpHost := cpConfig["host"].(string)
+	// Fill in the kubeconfig attributes of clusters created before they existed
+	if d.Get("host").(string) == "" {
+		if kubeconfig := readSensitiveOutput(d, "kubeconfig", "kubeconfig_path"); kubeconfig != "" {
+			if err := setKubeconfigCredentials(d, kubeconfig); err != nil {
+				tflog.Warn(ctx, "Failed to parse kubeconfig", map[string]interface{}{"error": err.Error()})
+			}
+		}
+	}
 
-	// Create provisioner to check health
+	// Health is checked with talosctl. Without it, refresh keeps the rest of
+	// the state and reports what is missing, so destroy can still be planned.
+	if _, missing := checkBinary(talosctlBinary, ""); missing.HasError() {
+		missing[0].Severity = diag.Warning
+		if err := d.Set("cluster_status", "unknown"); err != nil {
+			return diagFromErr(err)
+		}
+		return append(diags, missing...)
+	}
 	provisioner, err := NewTalosProvisioner()
 	if err != nil {
-		// If talosctl not available, just return current state
-		return diags
+		return diagFromErr(fmt.Errorf("failed to create Talos provisioner: %w", err))
 	}
 	defer func() { _ = provisioner.Cleanup() }()
 
-	// Check cluster health
-	status, err := provisioner.CheckClusterHealth(talosconfig, cpHost)
+	// Check cluster health, or only the workers when the control plane is managed elsewhere
+	var status string
+	if cfg.WorkersOnly {
+		var workerIPs []string
+		for _, w := range cfg.Workers {
+			workerIPs = append(workerIPs, w.Host)
+		}
+		status, err = provisioner.CheckWorkersHealth(talosconfig, workerIPs)
+	} else {
+		status, err = provisioner.CheckClusterHealth(talosconfig, cfg.ControlPlanes[0].Host)
+	}
 	if err != nil {
 		status = "unknown"
 	}
 
 	if err := d.Set("cluster_status", status); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err)
 	}
 
 	return diags
 }
 
 func resourceTalosClusterUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	// Most changes require ForceNew, so this is mostly a no-op
-	// Only addon changes can be applied without recreation
+	// Most changes require ForceNew. Addons and the in-place machine
+	// settings are applied without recreation.
+
+	if newDryRunRecorder(meta) != nil {
+		// Keep the prior state, so the change is still planned without dry_run
+		d.Partial(true)
+		return diag.Errorf("dry_run: updates of turingpi_talos_cluster are not previewed, and this one was not applied")
+	}
 
 	var diags diag.Diagnostics
 
+	if d.HasChange("logs_dir") {
+		logs, err := provisionLogsFor(d)
+		if err != nil {
+			return diagFromErr(err)
+		}
+		if err := setNodeLogs(d, logs, clusterHosts(d)); err != nil {
+			return diagFromErr(err)
+		}
+	}
+
+	if talosMachineConfigChanged(d) || len(talosRebootsAdded(d)) > 0 {
+		if _, diags := checkBinary(talosctlBinary, ""); diags.HasError() {
+			d.Partial(true)
+			return diags
+		}
+		provisioner, err := NewTalosProvisioner()
+		if err != nil {
+			return diagFromErr(fmt.Errorf("failed to create Talos provisioner: %w", err))
+		}
+		defer func() { _ = provisioner.Cleanup() }()
+		if err := updateTalosMachineConfig(ctx, d, meta, provisioner); err != nil {
+			// Keep the prior state, so the change is planned again
+			d.Partial(true)
+			return diagFromErr(err)
+		}
+	}
+
+	if cilium := extractTalosCilium(d.Get); cilium != nil && d.HasChange("cilium") {
+		if err := updateTalosCilium(ctx, d, *cilium); err != nil {
+			d.Partial(true)
+			return diagFromErr(err)
+		}
+	}
+
 	// Check if addon configuration changed
-	if d.HasChange("metallb") || d.HasChange("ingress") {
-		kubeconfig := d.Get("kubeconfig").(string)
+	if d.HasChanges("metallb", "ingress", "image_registry_mirror") {
+		kubeconfig := readSensitiveOutput(d, "kubeconfig", "kubeconfig_path")
 		if kubeconfig == "" {
-			return diag.Errorf("no kubeconfig available for addon updates")
+			return diag.Errorf("no kubeconfig available for addon updates (not in state and kubeconfig_path is unset or unreadable)")
 		}
 
 		// Create temp kubeconfig file
 		kubeconfigFile, err := os.CreateTemp("", "kubeconfig-*")
 		if err != nil {
-			return diag.FromErr(err)
+			return diagFromErr(err)
 		}
 		defer func() { _ = os.Remove(kubeconfigFile.Name()) }()
 
 		if _, err := kubeconfigFile.WriteString(kubeconfig); err != nil {
-			return diag.FromErr(err)
+			return diagFromErr(err)
 		}
 		if err := kubeconfigFile.Close(); err != nil {
-			return diag.FromErr(err)
+			return diagFromErr(err)
 		}
 
-		// Deploy/update MetalLB if changed
-		if d.HasChange("metallb") {
+		// Deploy/update MetalLB if changed; a new mirror upgrades both addons
+		if d.HasChanges("metallb", "image_registry_mirror") {
 			if metallbList := d.Get("metallb").([]interface{}); len(metallbList) > 0 {
 				metallbConfig := metallbList[0].(map[string]interface{})
 				if enabled, ok := metallbConfig["enabled"].(bool); ok && enabled {
 					ipRange := metallbConfig["ip_range"].(string)
-					if err := deployMetalLB(ctx, kubeconfigFile.Name(), ipRange); err != nil {
+					if err := deployMetalLB(ctx, kubeconfigFile.Name(), ipRange, d.Get("image_registry_mirror").(string)); err != nil {
 						diags = append(diags, diag.Diagnostic{
 							Severity: diag.Warning,
 							Summary:  "Failed to update MetalLB",
@@ -426,7 +870,7 @@ func resourceTalosClusterUpdate(ctx context.Context, d *schema.ResourceData, met
 		}
 
 		// Deploy/update Ingress if changed
-		if d.HasChange("ingress") {
+		if d.HasChanges("ingress", "image_registry_mirror") {
 			if ingressList := d.Get("ingress").([]interface{}); len(ingressList) > 0 {
 				ingressConfig := ingressList[0].(map[string]interface{})
 				if enabled, ok := ingressConfig["enabled"].(bool); ok && enabled {
@@ -435,7 +879,7 @@ func resourceTalosClusterUpdate(ctx context.Context, d *schema.ResourceData, met
 						ingressIP = ip
 					}
 					if ingressIP != "" {
-						if err := deployNginxIngress(ctx, kubeconfigFile.Name(), ingressIP); err != nil {
+						if err := deployNginxIngress(ctx, kubeconfigFile.Name(), ingressIP, d.Get("image_registry_mirror").(string)); err != nil {
 							diags = append(diags, diag.Diagnostic{
 								Severity: diag.Warning,
 								Summary:  "Failed to update NGINX Ingress",
@@ -454,9 +898,33 @@ func resourceTalosClusterUpdate(ctx context.Context, d *schema.ResourceData, met
 func resourceTalosClusterDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 
-	// Get stored talosconfig
-	talosconfig := d.Get("talosconfig").(string)
+	if d.Get("replace_strategy").(string) == talosReplaceReuseNodes {
+		// The nodes keep running the cluster, and a replacement re-adopts them
+		// with the secrets (and talosconfig) left in place
+		tflog.Info(ctx, "replace_strategy is reuse_nodes, leaving the Talos nodes installed", map[string]interface{}{
+			"cluster_name": d.Get("name").(string),
+		})
+		if kubeconfigPath := d.Get("kubeconfig_path").(string); kubeconfigPath != "" {
+			_ = os.Remove(kubeconfigPath)
+		}
+		d.SetId("")
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Talos nodes were not reset",
+			Detail:   fmt.Sprintf("replace_strategy is %q, so the nodes still run cluster %q. Set replace_strategy to %q and apply before destroying to wipe them.", talosReplaceReuseNodes, d.Get("name").(string), talosReplaceRecreate),
+		}}
+	}
+
+	// Get stored talosconfig, falling back to talosconfig_path
+	talosconfig := readSensitiveOutput(d, "talosconfig", "talosconfig_path")
 	if talosconfig == "" {
+		if !storeSensitiveOutputs(d) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Talos nodes were not reset",
+				Detail:   fmt.Sprintf("talosconfig is not stored in state and could not be read from %q", d.Get("talosconfig_path").(string)),
+			})
+		}
 		// No talosconfig, nothing to delete
 		d.SetId("")
 		return diags
@@ -479,13 +947,21 @@ func resourceTalosClusterDelete(ctx context.Context, d *schema.ResourceData, met
 		}
 	}
 
+	// Nodes are reset with talosctl; keep the cluster in state until they can be
+	rec := newDryRunRecorder(meta)
+	if rec == nil {
+		if _, missing := checkBinary(talosctlBinary, ""); missing.HasError() {
+			return append(diags, missing...)
+		}
+	}
+
 	// Create provisioner
-	provisioner, err := NewTalosProvisioner()
+	provisioner, err := newTalosClusterProvisioner(rec)
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Warning,
 			Summary:  "Could not create Talos provisioner",
-			Detail:   fmt.Sprintf("talosctl may not be available: %v", err),
+			Detail:   err.Error(),
 		})
 		d.SetId("")
 		return diags
@@ -493,13 +969,16 @@ func resourceTalosClusterDelete(ctx context.Context, d *schema.ResourceData, met
 	defer func() { _ = provisioner.Cleanup() }()
 
 	// Destroy the cluster
-	if err := provisioner.DestroyCluster(talosconfig, controlPlaneIPs, workerIPs); err != nil {
+	if err := provisioner.DestroyCluster(talosconfig, controlPlaneIPs, workerIPs, extractTalosResetOptions(d.Get)); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Warning,
 			Summary:  "Cluster destruction may be incomplete",
 			Detail:   fmt.Sprintf("Some nodes may not have been reset: %v", err),
 		})
 	}
+	if rec != nil {
+		return append(diags, rec.diagnostics(ctx, fmt.Sprintf("destroy of Talos cluster %q", d.Id()))...)
+	}
 
 	// Clean up local files
 	if kubeconfigPath := d.Get("kubeconfig_path").(string); kubeconfigPath != "" {
@@ -508,7 +987,8 @@ func resourceTalosClusterDelete(ctx context.Context, d *schema.ResourceData, met
 	if talosconfigPath := d.Get("talosconfig_path").(string); talosconfigPath != "" {
 		_ = os.Remove(talosconfigPath)
 	}
-	if secretsPath := d.Get("secrets_path").(string); secretsPath != "" {
+	// In workers_only mode the secrets belong to the existing cluster, so they are kept
+	if secretsPath := d.Get("secrets_path").(string); secretsPath != "" && d.Get("mode").(string) != talosModeWorkersOnly {
 		_ = os.Remove(secretsPath)
 	}
 
@@ -1,11 +1,17 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
 func TestResourceTalosCluster(t *testing.T) {
@@ -19,7 +25,7 @@ func TestResourceTalosCluster_Schema(t *testing.T) {
 	resource := resourceTalosCluster()
 	schema := resource.Schema
 
-	requiredFields := []string{"name", "cluster_endpoint", "control_plane"}
+	requiredFields := []string{"name", "cluster_endpoint"}
 	for _, field := range requiredFields {
 		if _, ok := schema[field]; !ok {
 			t.Errorf("Schema missing required field: %s", field)
@@ -31,6 +37,7 @@ func TestResourceTalosCluster_Schema(t *testing.T) {
 		"worker", "allow_scheduling_on_control_plane",
 		"metallb", "ingress", "bootstrap_timeout",
 		"kubeconfig_path", "talosconfig_path", "secrets_path",
+		"control_plane", "mode", "existing_secrets_yaml", "existing_talosconfig",
 	}
 	for _, field := range optionalFields {
 		if _, ok := schema[field]; !ok {
@@ -41,6 +48,7 @@ func TestResourceTalosCluster_Schema(t *testing.T) {
 	computedFields := []string{
 		"kubeconfig", "talosconfig", "secrets_yaml",
 		"api_endpoint", "cluster_status",
+		"host", "cluster_ca_certificate", "client_certificate", "client_key",
 	}
 	for _, field := range computedFields {
 		if _, ok := schema[field]; !ok {
@@ -107,10 +115,9 @@ func TestResourceTalosCluster_RequiredFields(t *testing.T) {
 		}
 	}
 
-	// control_plane is required with MinItems
-	cpSchema := schema["control_plane"]
-	if cpSchema.MinItems != 1 {
-		t.Errorf("control_plane should have MinItems=1, got %d", cpSchema.MinItems)
+	// control_plane is optional in the schema, since workers_only mode must not set it
+	if schema["control_plane"].Required {
+		t.Error("control_plane should be optional")
 	}
 }
 
@@ -120,7 +127,8 @@ func TestResourceTalosCluster_ForceNewFields(t *testing.T) {
 
 	forceNewFields := []string{
 		"name", "cluster_endpoint", "install_disk",
-		"control_plane", "worker", "allow_scheduling_on_control_plane",
+		"control_plane", "worker",
+		"mode", "existing_secrets_yaml", "existing_talosconfig",
 	}
 	for _, field := range forceNewFields {
 		s, ok := schema[field]
@@ -132,13 +140,35 @@ func TestResourceTalosCluster_ForceNewFields(t *testing.T) {
 			t.Errorf("Field %s should have ForceNew=true", field)
 		}
 	}
+
+	// Applied to the running nodes with apply_mode
+	for _, field := range talosInPlaceSettings {
+		if schema[field].ForceNew {
+			t.Errorf("Field %s should be updated in place", field)
+		}
+	}
+}
+
+func TestResourceTalosCluster_LegacyStateMode(t *testing.T) {
+	config := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+	}
+	diff := legacyStateDiff(t, resourceTalosCluster(), config, "mode")
+	if diff != nil && diff.Attributes["mode"] != nil {
+		t.Errorf("expected no mode change for state without mode, got %+v", diff.Attributes["mode"])
+	}
+	if diff.RequiresNew() {
+		t.Error("expected state without mode not to be replaced")
+	}
 }
 
 func TestResourceTalosCluster_SensitiveFields(t *testing.T) {
 	resource := resourceTalosCluster()
 	schema := resource.Schema
 
-	sensitiveFields := []string{"kubeconfig", "talosconfig", "secrets_yaml"}
+	sensitiveFields := []string{"kubeconfig", "talosconfig", "secrets_yaml", "existing_secrets_yaml", "existing_talosconfig"}
 	for _, field := range sensitiveFields {
 		s, ok := schema[field]
 		if !ok {
@@ -162,6 +192,8 @@ func TestResourceTalosCluster_DefaultValues(t *testing.T) {
 		{"install_disk", "/dev/mmcblk0"},
 		{"allow_scheduling_on_control_plane", true},
 		{"bootstrap_timeout", 600},
+		{"store_sensitive_outputs", true},
+		{"mode", "full"},
 	}
 
 	for _, tc := range tests {
@@ -233,7 +265,7 @@ func TestExtractTalosNodeConfig_MinimalData(t *testing.T) {
 	}
 }
 
-func TestGeneratePatchYAML(t *testing.T) {
+func TestGenerateNodePatchYAML(t *testing.T) {
 	tests := []struct {
 		name           string
 		hostname       string
@@ -269,9 +301,9 @@ func TestGeneratePatchYAML(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			patch, err := generatePatchYAML(tc.hostname, tc.allowSchedule, tc.isControlPlane)
+			patch, err := generateNodePatchYAML(tc.hostname, tc.allowSchedule, tc.isControlPlane, "")
 			if err != nil {
-				t.Fatalf("generatePatchYAML failed: %v", err)
+				t.Fatalf("generateNodePatchYAML failed: %v", err)
 			}
 
 			for _, want := range tc.wantContains {
@@ -507,7 +539,7 @@ func TestTalosProvisioner_Reset_ConnectionRefused(t *testing.T) {
 	}
 
 	// Reset should not fail on connection refused (expected during reboot)
-	err := provisioner.Reset(talosconfigPath, "10.10.88.73", false)
+	err := provisioner.Reset(talosconfigPath, "10.10.88.73", false, TalosResetOptions{})
 	// The mock will fail, but real implementation handles connection refused
 	// This test verifies the method exists and can be called
 	_ = err
@@ -541,3 +573,323 @@ func TestResourceTalosCluster_Description(t *testing.T) {
 		t.Error("Description should mention Talos")
 	}
 }
+
+func TestResourceTalosClusterCreate_RequiresTalosconfigPathWithoutSensitiveOutputs(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":                    "test",
+		"cluster_endpoint":        "https://10.10.88.73:6443",
+		"control_plane":           []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"store_sensitive_outputs": false,
+	})
+
+	diags := resourceTalosClusterCreate(context.Background(), d, nil)
+	if !diags.HasError() {
+		t.Fatal("expected error when talosconfig_path is unset")
+	}
+	if !strings.Contains(diags[0].Summary, "talosconfig_path") {
+		t.Errorf("unexpected error: %s", diags[0].Summary)
+	}
+}
+
+func TestResourceTalosClusterRead_FillsKubeconfigCredentials(t *testing.T) {
+	// Without talosctl the health check is skipped
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+	})
+	d.SetId("test")
+	if err := d.Set("talosconfig", "context: test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("kubeconfig", certKubeconfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := resourceTalosClusterRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := d.Get("host").(string); got != "https://10.10.88.73:6443" {
+		t.Errorf("expected host from the kubeconfig, got %q", got)
+	}
+	if got := d.Get("client_key").(string); got != "key-pem" {
+		t.Errorf("expected client_key from the kubeconfig, got %q", got)
+	}
+}
+
+func TestResourceTalosClusterRead_MissingTalosctl(t *testing.T) {
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+	lookPath = func(file string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+	})
+	d.SetId("test")
+	if err := d.Set("talosconfig", "context: test"); err != nil {
+		t.Fatal(err)
+	}
+
+	diags := resourceTalosClusterRead(context.Background(), d, nil)
+	if diags.HasError() {
+		t.Fatalf("expected refresh to keep working without talosctl, got %v", diags)
+	}
+	if len(diags) != 1 || !strings.Contains(diags[0].Summary, "talosctl") {
+		t.Errorf("expected a warning naming talosctl, got %v", diags)
+	}
+	if got := d.Get("cluster_status").(string); got != "unknown" {
+		t.Errorf("expected unknown cluster_status, got %q", got)
+	}
+}
+
+func TestTalosProvisioner_IsMaintenanceMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		expected    bool
+		expectError bool
+	}{
+		{"maintenance", "echo 'NODE NAMESPACE TYPE'", true, false},
+		{"configured", "echo 'rpc error: tls: certificate required' >&2; exit 1", false, false},
+		{"unreachable", "echo 'connection refused' >&2; exit 1", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExec := func(name string, args ...string) *exec.Cmd {
+				return exec.Command("sh", "-c", tt.script)
+			}
+			provisioner := NewTalosProvisionerWithExec(mockExec)
+			defer func() { _ = provisioner.Cleanup() }()
+
+			got, err := provisioner.IsMaintenanceMode("10.10.88.73")
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected maintenance=%v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTalosProvisioner_ApplyConfigAuto_ConfiguredNodeUsesTalosconfig(t *testing.T) {
+	var applyArgs []string
+	mockExec := func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "get" {
+			return exec.Command("sh", "-c", "echo 'tls: certificate required' >&2; exit 1")
+		}
+		applyArgs = args
+		return exec.Command("echo", "applied")
+	}
+
+	provisioner := NewTalosProvisionerWithExec(mockExec)
+	defer func() { _ = provisioner.Cleanup() }()
+
+	insecure, err := provisioner.ApplyConfigAuto("/tmp/talosconfig", "10.10.88.73", "/tmp/config.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if insecure {
+		t.Error("expected secure apply for configured node")
+	}
+
+	joined := strings.Join(applyArgs, " ")
+	if strings.Contains(joined, "--insecure") || !strings.Contains(joined, "--talosconfig /tmp/talosconfig") {
+		t.Errorf("unexpected apply arguments: %s", joined)
+	}
+}
+
+func TestTalosProvisioner_ApplyConfigAuto_MaintenanceNodeUsesInsecure(t *testing.T) {
+	var applyArgs []string
+	mockExec := func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "apply-config" {
+			applyArgs = args
+		}
+		return exec.Command("echo", "ok")
+	}
+
+	provisioner := NewTalosProvisionerWithExec(mockExec)
+	defer func() { _ = provisioner.Cleanup() }()
+
+	insecure, err := provisioner.ApplyConfigAuto("/tmp/talosconfig", "10.10.88.73", "/tmp/config.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !insecure || !strings.Contains(strings.Join(applyArgs, " "), "--insecure") {
+		t.Errorf("expected insecure apply, got args: %v", applyArgs)
+	}
+}
+
+func TestValidateTalosMode(t *testing.T) {
+	controlPlane := []interface{}{map[string]interface{}{"host": "10.10.88.73"}}
+	worker := []interface{}{map[string]interface{}{"host": "10.10.88.74"}}
+
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr string
+	}{
+		{"full", map[string]interface{}{"control_plane": controlPlane, "worker": worker}, ""},
+		{"full without control plane", map[string]interface{}{"worker": worker}, "control_plane block is required"},
+		{"workers_only", map[string]interface{}{"mode": "workers_only", "existing_secrets_yaml": "cluster: {}", "worker": worker}, ""},
+		{"workers_only with control plane", map[string]interface{}{"mode": "workers_only", "existing_secrets_yaml": "cluster: {}", "control_plane": controlPlane, "worker": worker}, "must not be set"},
+		{"workers_only without secrets", map[string]interface{}{"mode": "workers_only", "worker": worker}, "existing_secrets_yaml is required"},
+		{"workers_only without workers", map[string]interface{}{"mode": "workers_only", "existing_secrets_yaml": "cluster: {}"}, "at least one worker"},
+		{"workers_only with ingress", map[string]interface{}{"mode": "workers_only", "existing_secrets_yaml": "cluster: {}", "worker": worker,
+			"ingress": []interface{}{map[string]interface{}{"enabled": true}}}, "ingress is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{"name": "test", "cluster_endpoint": "https://10.10.88.10:6443"}
+			for k, v := range tt.raw {
+				raw[k] = v
+			}
+			d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, raw)
+
+			err := validateTalosMode(d, extractTalosClusterConfig(d))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtractTalosClusterConfig_WorkersOnly(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":                  "test",
+		"cluster_endpoint":      "https://10.10.88.10:6443",
+		"mode":                  "workers_only",
+		"existing_secrets_yaml": "cluster: {}",
+		"existing_talosconfig":  "context: test",
+		"worker":                []interface{}{map[string]interface{}{"host": "10.10.88.74"}},
+	})
+
+	cfg := extractTalosClusterConfig(d)
+	if !cfg.WorkersOnly {
+		t.Error("expected WorkersOnly")
+	}
+	if cfg.SecretsYAML != "cluster: {}" || cfg.Talosconfig != "context: test" {
+		t.Errorf("existing secrets and talosconfig not used: %q, %q", cfg.SecretsYAML, cfg.Talosconfig)
+	}
+}
+
+func TestKubeletsHealthy(t *testing.T) {
+	services := parseTalosServices(`NODE          SERVICE   STATE     HEALTH   LAST CHANGE   LAST EVENT
+10.10.88.74   kubelet   Running   OK       2m1s ago      Health check successful
+10.10.88.75   kubelet   Running   Fail     2m1s ago      Health check failed: connection refused
+10.10.88.75   apid      Running   OK       2m1s ago      Health check successful
+`)
+
+	if !kubeletsHealthy(services, []string{"10.10.88.74"}) {
+		t.Error("expected 10.10.88.74 to be healthy")
+	}
+	if kubeletsHealthy(services, []string{"10.10.88.74", "10.10.88.75"}) {
+		t.Error("expected failing kubelet on 10.10.88.75 to be unhealthy")
+	}
+	if kubeletsHealthy(services, []string{"10.10.88.76"}) {
+		t.Error("expected node without kubelet to be unhealthy")
+	}
+}
+
+func TestResourceTalosClusterDelete_MissingTalosctl(t *testing.T) {
+	oldLookPath := lookPath
+	lookPath = func(string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+	defer func() { lookPath = oldLookPath }()
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+	})
+	d.SetId("test")
+	if err := d.Set("talosconfig", "context: test"); err != nil {
+		t.Fatal(err)
+	}
+
+	diags := resourceTalosClusterDelete(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "Terraform Cloud") {
+		t.Fatalf("expected the missing binary diagnostic, got %v", diags)
+	}
+	if d.Id() == "" {
+		t.Error("expected the cluster to stay in state")
+	}
+}
+
+func TestResourceTalosClusterDelete_ReuseNodes(t *testing.T) {
+	// talosctl must not be needed, since no node is reset
+	oldLookPath := lookPath
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	defer func() { lookPath = oldLookPath }()
+
+	dir := t.TempDir()
+	secretsPath := filepath.Join(dir, "secrets.yaml")
+	if err := os.WriteFile(secretsPath, []byte("cluster: secrets"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"secrets_path":     secretsPath,
+		"replace_strategy": talosReplaceReuseNodes,
+	})
+	d.SetId("test")
+	if err := d.Set("talosconfig", "context: test"); err != nil {
+		t.Fatal(err)
+	}
+
+	diags := resourceTalosClusterDelete(context.Background(), d, nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Summary != "Talos nodes were not reset" {
+		t.Errorf("expected a warning that the nodes were kept, got %v", diags)
+	}
+	if d.Id() != "" {
+		t.Error("expected ID to be cleared")
+	}
+	if _, err := os.Stat(secretsPath); err != nil {
+		t.Errorf("expected secrets_path to be kept for the replacement: %v", err)
+	}
+}
+
+func TestResourceTalosClusterCustomizeDiff_ReuseNodesRequiresSecretsPath(t *testing.T) {
+	r := resourceTalosCluster()
+	raw := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"replace_strategy": talosReplaceReuseNodes,
+	}
+
+	_, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(raw), nil)
+	if err == nil || !strings.Contains(err.Error(), "requires secrets_path") {
+		t.Fatalf("expected secrets_path error, got %v", err)
+	}
+
+	raw["secrets_path"] = "secrets.yaml"
+	if _, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(raw), nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -20,6 +20,7 @@ func resourceUART() *schema.Resource {
 		ReadContext:   resourceUARTRead,
 		UpdateContext: resourceUARTUpdate,
 		DeleteContext: resourceUARTDelete,
+		CustomizeDiff: customizeDiffDefaultTriggers,
 		Schema: map[string]*schema.Schema{
 			"node": {
 				Type:             schema.TypeInt,
@@ -35,7 +36,8 @@ func resourceUART() *schema.Resource {
 			"triggers": {
 				Type:        schema.TypeMap,
 				Optional:    true,
-				Description: "A map of values that, when changed, will trigger resending the command.",
+				Computed:    true,
+				Description: "A map of values that, when changed, will trigger resending the command. The provider default_metadata is merged in.",
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -57,12 +59,12 @@ func resourceUARTCreate(ctx context.Context, d *schema.ResourceData, meta interf
 	command := d.Get("command").(string)
 
 	if err := writeUART(config.Endpoint, config.Token, node, command); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to write UART: %w", err))
+		return diagFromErr(fmt.Errorf("failed to write UART: %w", err))
 	}
 
 	d.SetId(fmt.Sprintf("uart-write-node-%d", node))
 	if err := d.Set("last_sent", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_sent: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_sent: %w", err))
 	}
 
 	return nil
@@ -78,18 +80,18 @@ func resourceUARTUpdate(ctx context.Context, d *schema.ResourceData, meta interf
 	config := meta.(*ProviderConfig)
 
 	// Resend command if it changed or triggers changed
-	if d.HasChange("command") || d.HasChange("triggers") || d.HasChange("node") {
+	if d.HasChange("command") || triggersChanged(d) || d.HasChange("node") {
 		node := d.Get("node").(int)
 		command := d.Get("command").(string)
 
 		if err := writeUART(config.Endpoint, config.Token, node, command); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to write UART: %w", err))
+			return diagFromErr(fmt.Errorf("failed to write UART: %w", err))
 		}
 
 		// Update ID if node changed
 		d.SetId(fmt.Sprintf("uart-write-node-%d", node))
 		if err := d.Set("last_sent", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_sent: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_sent: %w", err))
 		}
 	}
 
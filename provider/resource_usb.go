@@ -48,8 +48,8 @@ func resourceUSB() *schema.Resource {
 		Schema: map[string]*schema.Schema{
 			"node": {
 				Type:             schema.TypeInt,
-				Required:         true,
-				Description:      "Node ID to route USB to (1-4)",
+				Optional:         true,
+				Description:      "Node ID to route USB to (1-4). When omitted, mode and route are changed for whichever node USB is currently routed to.",
 				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 4)),
 			},
 			"mode": {
@@ -65,6 +65,28 @@ func resourceUSB() *schema.Resource {
 				Description:      "USB routing destination: 'usb-a' (external USB-A connector) or 'bmc' (route to BMC chip)",
 				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"usb-a", "bmc"}, false)),
 			},
+			"restore_on_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Restore the USB configuration found before this resource was created when it is destroyed. When false, the USB configuration is left as-is.",
+			},
+			// Configuration recorded before create, restored on destroy
+			"previous_mode": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "USB mode before this resource was created: host, device or flash",
+			},
+			"previous_node": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Node USB was routed to before this resource was created",
+			},
+			"previous_route": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "USB routing destination before this resource was created",
+			},
 			// Computed attributes from reading current state
 			"current_mode": {
 				Type:        schema.TypeString,
@@ -91,16 +113,40 @@ func resourceUSB() *schema.Resource {
 func resourceUSBCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*ProviderConfig)
 
-	node := d.Get("node").(int)
 	mode := d.Get("mode").(string)
 	route := d.Get("route").(string)
 
+	// Record the current configuration so it can be restored on destroy
+	status, err := getUSBStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return diagFromErr(fmt.Errorf("failed to read USB status: %w", err))
+	}
+	previousMode, previousNode, previousRoute := parseUSBStatus(status)
+	if usbFlashModeReported(status) {
+		// Recorded as reported, so destroy does not restore it as host mode
+		previousMode = "flash"
+	}
+	node := d.Get("node").(int)
+	if node == 0 {
+		node = previousNode
+	}
+
 	// Convert to API mode integer
 	apiMode := getUSBAPIMode(mode, route)
 
 	// Set USB configuration
 	if err := setUSBMode(config.Endpoint, config.Token, node, apiMode); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set USB mode: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set USB mode: %w", err))
+	}
+
+	if err := d.Set("previous_mode", previousMode); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set previous_mode: %w", err))
+	}
+	if err := d.Set("previous_node", previousNode); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set previous_node: %w", err))
+	}
+	if err := d.Set("previous_route", previousRoute); err != nil {
+		return diagFromErr(fmt.Errorf("failed to set previous_route: %w", err))
 	}
 
 	d.SetId(fmt.Sprintf("usb-node-%d", node))
@@ -116,20 +162,20 @@ func resourceUSBRead(ctx context.Context, d *schema.ResourceData, meta interface
 	// Fetch current USB status
 	status, err := getUSBStatus(config.Endpoint, config.Token)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to read USB status: %w", err))
+		return diagFromErr(fmt.Errorf("failed to read USB status: %w", err))
 	}
 
 	// Parse the response
 	currentMode, currentNode, currentRoute := parseUSBStatus(status)
 
 	if err := d.Set("current_mode", currentMode); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set current_mode: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set current_mode: %w", err))
 	}
 	if err := d.Set("current_node", currentNode); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set current_node: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set current_node: %w", err))
 	}
 	if err := d.Set("current_route", currentRoute); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set current_route: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set current_route: %w", err))
 	}
 
 	return diags
@@ -138,7 +184,10 @@ func resourceUSBRead(ctx context.Context, d *schema.ResourceData, meta interface
 func resourceUSBUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*ProviderConfig)
 
-	node := d.Get("node").(int)
+	node, err := usbTargetNode(d, config)
+	if err != nil {
+		return diagFromErr(err)
+	}
 	mode := d.Get("mode").(string)
 	route := d.Get("route").(string)
 
@@ -147,7 +196,7 @@ func resourceUSBUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 
 	// Set USB configuration
 	if err := setUSBMode(config.Endpoint, config.Token, node, apiMode); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to update USB mode: %w", err))
+		return diagFromErr(fmt.Errorf("failed to update USB mode: %w", err))
 	}
 
 	// Update the ID if node changed
@@ -158,10 +207,47 @@ func resourceUSBUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 }
 
 func resourceUSBDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	// USB routing cannot be truly "deleted" - it's always routed somewhere
-	// On delete, we just remove from state. The USB configuration remains on the BMC.
+	// USB routing cannot be truly "deleted" - it's always routed somewhere.
+	// Restore the configuration recorded at create time, if any. Imported
+	// resources have no recorded configuration and are only removed from state.
+	var diags diag.Diagnostics
+	previousMode := d.Get("previous_mode").(string)
+	previousNode := d.Get("previous_node").(int)
+	switch {
+	case !d.Get("restore_on_destroy").(bool) || previousMode == "":
+		// Opted out, or nothing recorded to restore
+	case previousMode == "flash":
+		// getUSBAPIMode has no flash mode, so restoring would leave host mode
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Previous USB mode not restored",
+			Detail: fmt.Sprintf("USB was in flash mode on node %d before this resource was created. "+
+				"turingpi_usb can only set host and device mode, so the USB configuration was left as-is.", previousNode),
+		})
+	default:
+		config := meta.(*ProviderConfig)
+		apiMode := getUSBAPIMode(previousMode, d.Get("previous_route").(string))
+		if err := setUSBMode(config.Endpoint, config.Token, previousNode, apiMode); err != nil {
+			return diagFromErr(fmt.Errorf("failed to restore previous USB mode: %w", err))
+		}
+	}
+
 	d.SetId("")
-	return nil
+	return diags
+}
+
+// usbTargetNode returns the node argument, or when it is omitted the node
+// USB is currently routed to, since the BMC sets mode and node together
+func usbTargetNode(d *schema.ResourceData, config *ProviderConfig) (int, error) {
+	if node := d.Get("node").(int); node != 0 {
+		return node, nil
+	}
+	status, err := getUSBStatus(config.Endpoint, config.Token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the current USB node: %w", err)
+	}
+	_, node, _ := parseUSBStatus(status)
+	return node, nil
 }
 
 // getUSBAPIMode converts human-readable mode and route to API mode integer
@@ -228,8 +314,8 @@ func getUSBStatus(endpoint, token string) (*usbStatusResponse, error) {
 	}
 
 	var result usbStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeBMCResponse(resp, endpoint, token, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -243,38 +329,7 @@ func parseUSBStatus(status *usbStatusResponse) (mode string, node int, route str
 	node = 1
 	route = "usb-a"
 
-	statusMap := make(map[string]interface{})
-
-	// Try parsing as new format first: [{"result": [{key: value, ...}]}]
-	var newFormat []map[string]interface{}
-	if err := json.Unmarshal(status.Response, &newFormat); err == nil {
-		for _, item := range newFormat {
-			if result, ok := item["result"].([]interface{}); ok {
-				for _, r := range result {
-					if resultMap, ok := r.(map[string]interface{}); ok {
-						for k, v := range resultMap {
-							statusMap[k] = v
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// If new format didn't work, try legacy format: [[key, value], [key, value], ...]
-	if len(statusMap) == 0 {
-		var legacyFormat [][]interface{}
-		if err := json.Unmarshal(status.Response, &legacyFormat); err == nil {
-			for _, item := range legacyFormat {
-				if len(item) >= 2 {
-					key, keyOk := item[0].(string)
-					if keyOk {
-						statusMap[key] = item[1]
-					}
-				}
-			}
-		}
-	}
+	statusMap := usbStatusFields(status)
 
 	// Parse mode
 	if m, ok := statusMap["mode"].(string); ok {
@@ -323,3 +378,49 @@ func parseUSBStatus(status *usbStatusResponse) (mode string, node int, route str
 
 	return mode, node, route
 }
+
+// usbFlashModeReported reports whether the BMC reports USB in flash mode,
+// which parseUSBStatus maps to host
+func usbFlashModeReported(status *usbStatusResponse) bool {
+	m, _ := usbStatusFields(status)["mode"].(string)
+	return m == "Flash" || m == "flash"
+}
+
+// usbStatusFields flattens a USB status response into key/value pairs.
+// Handles both legacy format and new BMC firmware format (2.3.4+)
+func usbStatusFields(status *usbStatusResponse) map[string]interface{} {
+	statusMap := make(map[string]interface{})
+
+	// Try parsing as new format first: [{"result": [{key: value, ...}]}]
+	var newFormat []map[string]interface{}
+	if err := json.Unmarshal(status.Response, &newFormat); err == nil && parseV2Responses() {
+		for _, item := range newFormat {
+			if result, ok := item["result"].([]interface{}); ok {
+				for _, r := range result {
+					if resultMap, ok := r.(map[string]interface{}); ok {
+						for k, v := range resultMap {
+							statusMap[k] = v
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// If new format didn't work, try legacy format: [[key, value], [key, value], ...]
+	if len(statusMap) == 0 {
+		var legacyFormat [][]interface{}
+		if err := json.Unmarshal(status.Response, &legacyFormat); err == nil && parseLegacyResponses() {
+			for _, item := range legacyFormat {
+				if len(item) >= 2 {
+					key, keyOk := item[0].(string)
+					if keyOk {
+						statusMap[key] = item[1]
+					}
+				}
+			}
+		}
+	}
+
+	return statusMap
+}
@@ -19,6 +19,7 @@ func resourceUSBBoot() *schema.Resource {
 		ReadContext:   resourceUSBBootRead,
 		UpdateContext: resourceUSBBootUpdate,
 		DeleteContext: resourceUSBBootDelete,
+		CustomizeDiff: customizeDiffDefaultTriggers,
 		Schema: map[string]*schema.Schema{
 			"node": {
 				Type:             schema.TypeInt,
@@ -29,7 +30,8 @@ func resourceUSBBoot() *schema.Resource {
 			"triggers": {
 				Type:        schema.TypeMap,
 				Optional:    true,
-				Description: "A map of values that, when changed, will re-enable USB boot mode.",
+				Computed:    true,
+				Description: "A map of values that, when changed, will re-enable USB boot mode. The provider default_metadata is merged in.",
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -49,12 +51,12 @@ func resourceUSBBootCreate(ctx context.Context, d *schema.ResourceData, meta int
 	node := d.Get("node").(int)
 
 	if err := enableUSBBoot(config.Endpoint, config.Token, node); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to enable USB boot for node %d: %w", node, err))
+		return diagFromErr(fmt.Errorf("failed to enable USB boot for node %d: %w", node, err))
 	}
 
 	d.SetId(fmt.Sprintf("usb-boot-node-%d", node))
 	if err := d.Set("last_enabled", time.Now().UTC().Format(time.RFC3339)); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to set last_enabled: %w", err))
+		return diagFromErr(fmt.Errorf("failed to set last_enabled: %w", err))
 	}
 
 	return nil
@@ -70,13 +72,13 @@ func resourceUSBBootUpdate(ctx context.Context, d *schema.ResourceData, meta int
 	node := d.Get("node").(int)
 
 	// Re-enable if node or triggers changed
-	if d.HasChange("node") || d.HasChange("triggers") {
+	if d.HasChange("node") || triggersChanged(d) {
 		if err := enableUSBBoot(config.Endpoint, config.Token, node); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to enable USB boot for node %d: %w", node, err))
+			return diagFromErr(fmt.Errorf("failed to enable USB boot for node %d: %w", node, err))
 		}
 
 		if err := d.Set("last_enabled", time.Now().UTC().Format(time.RFC3339)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set last_enabled: %w", err))
+			return diagFromErr(fmt.Errorf("failed to set last_enabled: %w", err))
 		}
 	}
 
@@ -89,7 +91,7 @@ func resourceUSBBootDelete(ctx context.Context, d *schema.ResourceData, meta int
 	node := d.Get("node").(int)
 
 	if err := clearUSBBoot(config.Endpoint, config.Token, node); err != nil {
-		return diag.FromErr(fmt.Errorf("failed to clear USB boot for node %d: %w", node, err))
+		return diagFromErr(fmt.Errorf("failed to clear USB boot for node %d: %w", node, err))
 	}
 
 	d.SetId("")
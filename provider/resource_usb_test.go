@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -25,6 +26,10 @@ func TestResourceUSB_Schema(t *testing.T) {
 		"node",
 		"mode",
 		"route",
+		"restore_on_destroy",
+		"previous_mode",
+		"previous_node",
+		"previous_route",
 		"current_mode",
 		"current_node",
 		"current_route",
@@ -64,9 +69,6 @@ func TestResourceUSB_SchemaTypes(t *testing.T) {
 func TestResourceUSB_RequiredFields(t *testing.T) {
 	r := resourceUSB()
 
-	if !r.Schema["node"].Required {
-		t.Error("node should be required")
-	}
 	if !r.Schema["mode"].Required {
 		t.Error("mode should be required")
 	}
@@ -78,12 +80,15 @@ func TestResourceUSB_OptionalFields(t *testing.T) {
 	if !r.Schema["route"].Optional {
 		t.Error("route should be optional")
 	}
+	if !r.Schema["node"].Optional {
+		t.Error("node should be optional")
+	}
 }
 
 func TestResourceUSB_ComputedFields(t *testing.T) {
 	r := resourceUSB()
 
-	computedFields := []string{"current_mode", "current_node", "current_route"}
+	computedFields := []string{"previous_mode", "previous_node", "previous_route", "current_mode", "current_node", "current_route"}
 	for _, field := range computedFields {
 		if !r.Schema[field].Computed {
 			t.Errorf("%s should be computed", field)
@@ -97,6 +102,9 @@ func TestResourceUSB_DefaultValues(t *testing.T) {
 	if r.Schema["route"].Default != "usb-a" {
 		t.Errorf("route should default to 'usb-a', got %v", r.Schema["route"].Default)
 	}
+	if r.Schema["restore_on_destroy"].Default != true {
+		t.Errorf("restore_on_destroy should default to true, got %v", r.Schema["restore_on_destroy"].Default)
+	}
 }
 
 func TestResourceUSB_HasCRUDFunctions(t *testing.T) {
@@ -564,6 +572,116 @@ func TestResourceUSBDelete_ClearsId(t *testing.T) {
 	}
 }
 
+func TestResourceUSB_RestoresPreviousConfigOnDestroy(t *testing.T) {
+	var setCalls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.String(), "opt=set") {
+			setCalls = append(setCalls, r.URL.RawQuery)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// Before create: device mode on node 3, routed to the BMC
+		response := map[string]interface{}{
+			"response": [][]interface{}{
+				{"mode", "Device"},
+				{"node", float64(2)},
+				{"route", "BMC"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+	d := resourceUSB().TestResourceData()
+	_ = d.Set("node", 1)
+	_ = d.Set("mode", "host")
+	_ = d.Set("route", "usb-a")
+	_ = d.Set("restore_on_destroy", true)
+
+	if diags := resourceUSBCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Get("previous_mode") != "device" || d.Get("previous_node") != 3 || d.Get("previous_route") != "bmc" {
+		t.Errorf("unexpected previous config: %v/%v/%v", d.Get("previous_mode"), d.Get("previous_node"), d.Get("previous_route"))
+	}
+
+	if diags := resourceUSBDelete(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(setCalls) != 2 || !strings.Contains(setCalls[1], "mode=5&node=2") {
+		t.Errorf("expected previous config (mode 5, node index 2) to be restored, got %v", setCalls)
+	}
+}
+
+func TestResourceUSB_SkipsFlashModeRestoreOnDestroy(t *testing.T) {
+	var setCalls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.String(), "opt=set") {
+			setCalls = append(setCalls, r.URL.RawQuery)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// Before create: flash mode on node 2, routed to the BMC
+		response := map[string]interface{}{
+			"response": [][]interface{}{
+				{"mode", "Flash"},
+				{"node", float64(1)},
+				{"route", "BMC"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+	d := resourceUSB().TestResourceData()
+	_ = d.Set("node", 1)
+	_ = d.Set("mode", "host")
+	_ = d.Set("route", "usb-a")
+	_ = d.Set("restore_on_destroy", true)
+
+	if diags := resourceUSBCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Get("previous_mode") != "flash" || d.Get("previous_node") != 2 {
+		t.Errorf("expected flash mode on node 2 to be recorded, got %v/%v", d.Get("previous_mode"), d.Get("previous_node"))
+	}
+
+	diags := resourceUSBDelete(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Errorf("expected one warning about the skipped restore, got %v", diags)
+	}
+	if len(setCalls) != 1 {
+		t.Errorf("expected no restore call after the create, got %v", setCalls)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected empty ID after delete, got '%s'", d.Id())
+	}
+}
+
+func TestResourceUSBDelete_RestoreDisabled(t *testing.T) {
+	d := resourceUSB().TestResourceData()
+	d.SetId("usb-node-1")
+	_ = d.Set("restore_on_destroy", false)
+	_ = d.Set("previous_mode", "device")
+	_ = d.Set("previous_node", 3)
+	_ = d.Set("previous_route", "bmc")
+
+	// No provider config: any BMC call would panic
+	if diags := resourceUSBDelete(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected empty ID after delete, got '%s'", d.Id())
+	}
+}
+
 func TestResourceUSBUpdate_ChangesMode(t *testing.T) {
 	var capturedMode string
 
@@ -612,3 +730,50 @@ func TestResourceUSBUpdate_ChangesMode(t *testing.T) {
 		t.Errorf("expected mode 'device' to be set, got '%s'", capturedMode)
 	}
 }
+
+func TestResourceUSB_KeepsCurrentNode(t *testing.T) {
+	var setCalls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.String(), "opt=set") {
+			setCalls = append(setCalls, r.URL.RawQuery)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// USB is routed to node 3
+		response := map[string]interface{}{
+			"response": [][]interface{}{
+				{"mode", "Host"},
+				{"node", float64(2)},
+				{"route", "USB-A"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := &ProviderConfig{Token: "test-token", Endpoint: server.URL}
+	d := resourceUSB().TestResourceData()
+	_ = d.Set("mode", "device")
+	_ = d.Set("route", "bmc")
+
+	if diags := resourceUSBCreate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "usb-node-3" {
+		t.Errorf("expected the current node in the ID, got %s", d.Id())
+	}
+
+	_ = d.Set("mode", "host")
+	if diags := resourceUSBUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	want := []string{"opt=set&type=usb&mode=5&node=2", "opt=set&type=usb&mode=4&node=2"}
+	if strings.Join(setCalls, " ") != strings.Join(want, " ") {
+		t.Errorf("expected the mode to change on the current node, got %v", setCalls)
+	}
+	if d.Get("node").(int) != 0 {
+		t.Errorf("expected node to stay unset, got %d", d.Get("node").(int))
+	}
+}
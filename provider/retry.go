@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Backoff settings for transient API server errors. Fresh K3s and Talos API
+// servers fail intermittently for about a minute after bootstrap.
+// Replaced in tests to keep them fast.
+var (
+	transientRetryInitial  = 2 * time.Second
+	transientRetryMax      = 20 * time.Second
+	transientRetryAttempts = 6
+)
+
+// transientErrorMessages are error fragments of transient failures that reach
+// us as plain strings (e.g., wrapped by Helm)
+var transientErrorMessages = []string{
+	"connection refused",
+	"connection reset by peer",
+	"unexpected EOF",
+	"etcdserver: leader changed",
+	"etcdserver: request timed out",
+	"etcdserver: no leader",
+	"the server is currently unable to handle the request",
+	"Internal error occurred",
+}
+
+// isTransientAPIError reports whether err is worth retrying: connection
+// refused, HTTP 500 or 503, EOF, or an etcd leader election in progress
+func isTransientAPIError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) {
+		return true
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		switch status.Status().Code {
+		case 500, 503:
+			return true
+		}
+	}
+
+	msg := err.Error()
+	if strings.HasSuffix(msg, ": EOF") || msg == "EOF" {
+		return true
+	}
+	for _, fragment := range transientErrorMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryTransient runs fn, retrying with exponential backoff while it fails with
+// a transient API server error. It stops at the first permanent error, after
+// transientRetryAttempts attempts, or when ctx is done.
+func retryTransient(ctx context.Context, operation string, fn func() error) error {
+	delay := transientRetryInitial
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isTransientAPIError(err) || attempt >= transientRetryAttempts {
+			return err
+		}
+
+		metrics.countRetry(retryKindKubernetesAPI)
+		tflog.Warn(ctx, "Transient API server error, retrying", map[string]interface{}{
+			"operation": operation,
+			"attempt":   attempt,
+			"delay":     delay.String(),
+			"error":     err.Error(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > transientRetryMax {
+			delay = transientRetryMax
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fastTransientRetry shortens the backoff for the duration of a test
+func fastTransientRetry(t *testing.T) {
+	t.Helper()
+	origInitial, origMax := transientRetryInitial, transientRetryMax
+	transientRetryInitial, transientRetryMax = time.Millisecond, 2*time.Millisecond
+	t.Cleanup(func() { transientRetryInitial, transientRetryMax = origInitial, origMax })
+}
+
+func TestIsTransientAPIError(t *testing.T) {
+	gr := schema.GroupResource{Group: "metallb.io", Resource: "ipaddresspools"}
+
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"nil", nil, false},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"connection refused string", errors.New("dial tcp 10.10.88.73:6443: connect: connection refused"), true},
+		{"EOF", fmt.Errorf("request failed: %w", io.EOF), true},
+		{"EOF string", errors.New(`Get "https://10.10.88.73:6443/api": EOF`), true},
+		{"internal error", apierrors.NewInternalError(errors.New("etcd")), true},
+		{"service unavailable", apierrors.NewServiceUnavailable("starting"), true},
+		{"generic 500", apierrors.NewGenericServerResponse(500, "patch", gr, "pool", "", 0, false), true},
+		{"etcd leader", errors.New("etcdserver: leader changed"), true},
+		{"not found", apierrors.NewNotFound(gr, "pool"), false},
+		{"forbidden", apierrors.NewForbidden(gr, "pool", errors.New("rbac")), false},
+		{"invalid chart", errors.New("chart requires kubeVersion: >=1.30"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientAPIError(tt.err); got != tt.transient {
+				t.Errorf("expected %v, got %v for %v", tt.transient, got, tt.err)
+			}
+		})
+	}
+}
+
+func TestRetryTransient_RecoversFromTransientErrors(t *testing.T) {
+	fastTransientRetry(t)
+
+	calls := 0
+	err := retryTransient(context.Background(), "test", func() error {
+		calls++
+		if calls < 3 {
+			return apierrors.NewServiceUnavailable("starting")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryTransient_PermanentError(t *testing.T) {
+	fastTransientRetry(t)
+
+	calls := 0
+	err := retryTransient(context.Background(), "test", func() error {
+		calls++
+		return errors.New("invalid manifest")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected one call and an error, got %d calls and %v", calls, err)
+	}
+}
+
+func TestRetryTransient_GivesUp(t *testing.T) {
+	fastTransientRetry(t)
+
+	calls := 0
+	err := retryTransient(context.Background(), "test", func() error {
+		calls++
+		return errors.New("connection refused")
+	})
+	if err == nil || calls != transientRetryAttempts {
+		t.Errorf("expected %d calls and an error, got %d calls and %v", transientRetryAttempts, calls, err)
+	}
+}
+
+func TestRetryTransient_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := retryTransient(ctx, "test", func() error {
+		calls++
+		return errors.New("connection refused")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected one call and an error, got %d calls and %v", calls, err)
+	}
+}
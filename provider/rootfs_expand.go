@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// expandRootfsScript grows the partition holding / to the end of its disk, then
+// the filesystem. growpart (cloud-guest-utils) is preferred, with sfdisk as a
+// fallback; growpart exits 1 when the partition is already at full size.
+const expandRootfsScript = `set -e
+ROOT=$(findmnt -n -o SOURCE /)
+FSTYPE=$(findmnt -n -o FSTYPE /)
+DISK=/dev/$(lsblk -no PKNAME "$ROOT")
+PART=$(cat /sys/class/block/$(basename "$ROOT")/partition)
+echo "Expanding $ROOT (partition $PART of $DISK, $FSTYPE)"
+if command -v growpart >/dev/null 2>&1; then
+  growpart "$DISK" "$PART" || [ $? -eq 1 ]
+else
+  echo ", +" | sfdisk --no-reread -N "$PART" "$DISK"
+  partx -u "$DISK" || true
+fi
+case "$FSTYPE" in
+  ext2|ext3|ext4) resize2fs "$ROOT" ;;
+  xfs) xfs_growfs / ;;
+  btrfs) btrfs filesystem resize max / ;;
+  *) echo "unsupported root filesystem: $FSTYPE" >&2; exit 1 ;;
+esac
+df -h /`
+
+// flashExpandRootfsSchema is the expand_rootfs block of turingpi_flash
+func flashExpandRootfsSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "IP address or hostname of the flashed node OS, e.g. 10.10.88.7{node}. {node} is replaced with the slot number.",
+			},
+			"ssh_user": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "root",
+				Description: "SSH username",
+			},
+			"ssh_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH private key content",
+			},
+			"ssh_password": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH password (ssh_key is preferred)",
+			},
+			"ssh_port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     22,
+				Description: "SSH port number",
+			},
+			"timeout": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     300,
+				Description: "Timeout in seconds to wait for SSH on the flashed node before expanding the root filesystem",
+			},
+		},
+	}
+}
+
+// flashExpandRootfs holds the expand_rootfs block of turingpi_flash
+type flashExpandRootfs struct {
+	node    NodeConfig
+	timeout time.Duration
+}
+
+// expandFlashExpandRootfs returns the expand_rootfs block, or nil when it is
+// not set
+func expandFlashExpandRootfs(v []interface{}) (*flashExpandRootfs, error) {
+	if len(v) == 0 || v[0] == nil {
+		return nil, nil
+	}
+	data := v[0].(map[string]interface{})
+	node := extractNodeConfig(data)
+	if err := validateNodeSSH(node); err != nil {
+		return nil, fmt.Errorf("expand_rootfs: %w", err)
+	}
+	return &flashExpandRootfs{node: node, timeout: time.Duration(data["timeout"].(int)) * time.Second}, nil
+}
+
+// expandFlashedRootfs powers on a node a flash left off, then grows its root
+// filesystem once it accepts SSH connections
+func expandFlashedRootfs(ctx context.Context, config *ProviderConfig, e *flashExpandRootfs, node int) error {
+	if err := setNodePower(config.Endpoint, config.Token, node, true); err != nil {
+		return fmt.Errorf("failed to power on node %d to expand its root filesystem: %w", node, err)
+	}
+	target := e.node
+	target.Host = strings.ReplaceAll(target.Host, "{node}", strconv.Itoa(node))
+	return expandRootfs(ctx, target, e.timeout, config.newSSHClient)
+}
+
+// expandRootfs waits for the freshly flashed node to accept SSH connections,
+// then grows its root partition and filesystem to fill the disk
+func expandRootfs(ctx context.Context, node NodeConfig, timeout time.Duration, clientFactory func() SSHClient) error {
+	sshConfig := node.getSSHConfig()
+	if err := WaitForSSHWithClient(node.Host, node.SSHPort, sshConfig, timeout, clientFactory); err != nil {
+		return err
+	}
+
+	client := clientFactory()
+	if err := client.Connect(node.Host, node.SSHPort, sshConfig); err != nil {
+		return fmt.Errorf("SSH connection to %s failed: %w", node.Host, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	output, err := client.RunCommand(expandRootfsScript)
+	tflog.Info(ctx, "Root filesystem expansion output", map[string]interface{}{
+		"host":   node.Host,
+		"output": strings.TrimSpace(output),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to expand root filesystem on %s: %w", node.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// mockRootfsSSH returns an SSH client factory recording each command run.
+// Commands fail with exitErr when it is set.
+func mockRootfsSSH(exitErr error) (func() SSHClient, *[]string) {
+	var commands []string
+	factory := func() SSHClient {
+		return &MockSSHClient{
+			RunCommandFunc: func(cmd string) (string, error) {
+				commands = append(commands, cmd)
+				return "/dev/mmcblk0p2   29G  1.2G   27G   5% /", exitErr
+			},
+		}
+	}
+	return factory, &commands
+}
+
+func TestExpandRootfs(t *testing.T) {
+	factory, commands := mockRootfsSSH(nil)
+
+	node := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPassword: "turing", SSHPort: 22}
+	if err := expandRootfs(context.Background(), node, time.Second, factory); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*commands) != 1 || !strings.Contains((*commands)[0], "growpart") || !strings.Contains((*commands)[0], "resize2fs") {
+		t.Errorf("expected the expansion script to run once, got %v", *commands)
+	}
+}
+
+func TestExpandRootfs_CommandFailure(t *testing.T) {
+	factory, _ := mockRootfsSSH(&ExitError{Command: "sh", Code: 1, Stderr: "unsupported root filesystem: squashfs"})
+
+	node := NodeConfig{Host: "10.10.88.73", SSHUser: "root", SSHPassword: "turing", SSHPort: 22}
+	err := expandRootfs(context.Background(), node, time.Second, factory)
+	if err == nil || !strings.Contains(err.Error(), "unsupported root filesystem") {
+		t.Errorf("expected expansion error, got %v", err)
+	}
+}
+
+func TestResourceFlashCreate_ExpandRootfs(t *testing.T) {
+	var hosts []string
+	_, config, image := setupFlashNodesTest(t)
+	config.sshClientFactory = func() SSHClient {
+		return &MockSSHClient{
+			ConnectFunc: func(host string, port int, config *SSHConfig) error {
+				hosts = append(hosts, host)
+				return nil
+			},
+			RunCommandFunc: func(cmd string) (string, error) {
+				if strings.Contains(cmd, "growpart") && hosts[len(hosts)-1] == "10.10.88.73" {
+					return "", &ExitError{Command: "sh", Code: 1, Stderr: "unsupported root filesystem: squashfs"}
+				}
+				return "", nil
+			},
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceFlash().Schema, map[string]interface{}{
+		"nodes":         []interface{}{1, 3},
+		"firmware_file": image,
+		"expand_rootfs": []interface{}{map[string]interface{}{
+			"host":         "10.10.88.7{node}",
+			"ssh_password": "turing",
+			"timeout":      1,
+		}},
+	})
+	diags := resourceFlashCreate(context.Background(), d, config)
+	if diags.HasError() || len(diags) != 1 || !strings.Contains(diags[0].Detail, "unsupported root filesystem") {
+		t.Fatalf("expected a warning about the expansion of node 3, got %v", diags)
+	}
+
+	if got := strings.Join(hosts, " "); !strings.Contains(got, "10.10.88.71") || !strings.Contains(got, "10.10.88.73") {
+		t.Errorf("expected {node} to be replaced in host, got %s", got)
+	}
+	status := d.Get("node_status").(map[string]interface{})
+	if status["node1"] != flashStatusFlashed || status["node3"] != flashStatusFailed {
+		t.Errorf("expected node1 flashed and node3 failed, got %v", status)
+	}
+}
+
+func TestExpandFlashExpandRootfs_Validation(t *testing.T) {
+	_, err := expandFlashExpandRootfs([]interface{}{map[string]interface{}{
+		"host": "10.10.88.7{node}", "ssh_user": "root", "ssh_key": "", "ssh_password": "", "ssh_port": 22, "timeout": 300,
+	}})
+	if err == nil || !strings.Contains(err.Error(), "expand_rootfs") {
+		t.Errorf("expected an error without SSH credentials, got %v", err)
+	}
+}
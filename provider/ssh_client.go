@@ -1,8 +1,14 @@
 package provider
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"sync"
+	"syscall"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -16,6 +22,7 @@ type SSHConfig struct {
 	PrivateKeyPath string        // Path to private key file
 	Timeout        time.Duration // Connection timeout (default 30s)
 	HostKeyCheck   bool          // Verify host keys (default false for cluster provisioning)
+	HostKeys       []string      // Accepted host keys in authorized_keys format, required with HostKeyCheck
 }
 
 // SSHClient interface for SSH operations - allows mocking in tests
@@ -25,6 +32,94 @@ type SSHClient interface {
 	Close() error
 }
 
+// DialError is returned when the SSH connection cannot be established or is
+// lost before a command runs. These failures are often transient (e.g., a node
+// still booting) and worth retrying.
+type DialError struct {
+	Addr string
+	Err  error
+}
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("failed to connect to %s: %v", e.Addr, e.Err)
+}
+
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// SessionLostError is returned when the connection dropped while a command
+// ran, before it reported an exit status. The command may have run in part,
+// so it is not retried.
+type SessionLostError struct {
+	Addr string
+	Err  error
+}
+
+func (e *SessionLostError) Error() string {
+	return fmt.Sprintf("connection to %s lost while the command ran: %v", e.Addr, e.Err)
+}
+
+func (e *SessionLostError) Unwrap() error {
+	return e.Err
+}
+
+// ExitError is returned when a remote command ran and exited with a non-zero
+// status. Retrying the connection will not help.
+type ExitError struct {
+	Command string
+	Code    int
+	Stderr  string
+}
+
+func (e *ExitError) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("command exited with status %d: %s", e.Code, e.Stderr)
+	}
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// isSSHDialError reports whether err is (or wraps) a DialError
+func isSSHDialError(err error) bool {
+	var dialErr *DialError
+	return errors.As(err, &dialErr)
+}
+
+// isSSHConnectionError reports whether err is (or wraps) a DialError or a
+// SessionLostError, i.e. the node, not the command, failed
+func isSSHConnectionError(err error) bool {
+	var lostErr *SessionLostError
+	return isSSHDialError(err) || errors.As(err, &lostErr)
+}
+
+// isSSHUnreachableError reports whether err is a DialError of a node that
+// could not be reached at the network level: the connection timed out, was
+// refused or had no route. Authentication and host key failures are not, the
+// node answered them.
+func isSSHUnreachableError(err error) bool {
+	if !isSSHDialError(err) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+// lockedWriter serializes writes from the stdout and stderr copy goroutines
+// of an SSH session into one buffer
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
 // RealSSHClient implements SSHClient using golang.org/x/crypto/ssh
 type RealSSHClient struct {
 	client *ssh.Client
@@ -79,50 +174,68 @@ func (c *RealSSHClient) Connect(host string, port int, config *SSHConfig) error
 	}
 
 	// Build SSH client config
-	var hostKeyCallback ssh.HostKeyCallback
-	if config.HostKeyCheck {
-		// In production, you'd use ssh.FixedHostKey or a known_hosts file
-		// For now, we don't support strict host key checking
-		return fmt.Errorf("host key checking not yet implemented - set HostKeyCheck to false")
-	}
-	hostKeyCallback = ssh.InsecureIgnoreHostKey()
-
 	sshConfig := &ssh.ClientConfig{
 		User:            config.User,
 		Auth:            authMethods,
-		HostKeyCallback: hostKeyCallback,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 		Timeout:         timeout,
 	}
+	if config.HostKeyCheck {
+		callback, algorithms, err := fixedHostKeysCallback(config.HostKeys)
+		if err != nil {
+			return err
+		}
+		sshConfig.HostKeyCallback = callback
+		sshConfig.HostKeyAlgorithms = algorithms
+	}
 
 	// Connect to SSH server
 	addr := fmt.Sprintf("%s:%d", host, port)
 	client, err := ssh.Dial("tcp", addr, sshConfig)
 	if err != nil {
-		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+		return &DialError{Addr: addr, Err: err}
 	}
 
 	c.client = client
 	return nil
 }
 
-// RunCommand executes a command on the remote host and returns combined output
+// RunCommand executes a command on the remote host and returns combined output.
+// A non-zero exit returns an ExitError. A connection that breaks before the
+// command starts returns a DialError, one that breaks while it runs a
+// SessionLostError.
 func (c *RealSSHClient) RunCommand(cmd string) (string, error) {
 	if c.client == nil {
 		return "", fmt.Errorf("not connected")
 	}
+	metrics.sshCommands.Add(1)
 
 	session, err := c.client.NewSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", &DialError{Addr: c.client.RemoteAddr().String(), Err: fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
 
-	output, err := session.CombinedOutput(cmd)
+	var combined, stderr bytes.Buffer
+	out := &lockedWriter{mu: &sync.Mutex{}, w: &combined}
+	session.Stdout = out
+	session.Stderr = io.MultiWriter(out, &stderr)
+
+	err = session.Run(cmd)
+	output := combined.String()
 	if err != nil {
-		return string(output), fmt.Errorf("command failed: %w", err)
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return output, &ExitError{Command: cmd, Code: exitErr.ExitStatus(), Stderr: string(bytes.TrimSpace(stderr.Bytes()))}
+		}
+		var missingErr *ssh.ExitMissingError
+		if errors.As(err, &missingErr) || errors.Is(err, io.EOF) {
+			return output, &SessionLostError{Addr: c.client.RemoteAddr().String(), Err: err}
+		}
+		return output, fmt.Errorf("command failed: %w", err)
 	}
 
-	return string(output), nil
+	return output, nil
 }
 
 // Close closes the SSH connection
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyAlgorithms are offered one at a time when scanning a node, so each
+// key type it has is collected. RSA keys are requested with SHA-256
+// signatures, which OpenSSH servers accept by default.
+var hostKeyAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoECDSA384,
+	ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA256,
+}
+
+// errHostKeyCaptured aborts a scan handshake once the host key is received
+var errHostKeyCaptured = errors.New("host key captured")
+
+// scanSSHHostKeys returns the host keys of the SSH server at host:port, one
+// per key type. The connection is closed right after key exchange, so no
+// credentials are needed.
+func scanSSHHostKeys(host string, port int, timeout time.Duration) ([]ssh.PublicKey, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	var keys []ssh.PublicKey
+	var lastErr error
+	for _, algorithm := range hostKeyAlgorithms {
+		var captured ssh.PublicKey
+		config := &ssh.ClientConfig{
+			User:              "turingpi",
+			HostKeyAlgorithms: []string{algorithm},
+			HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+				captured = key
+				return errHostKeyCaptured
+			},
+			Timeout: timeout,
+		}
+
+		client, err := ssh.Dial("tcp", addr, config)
+		if client != nil {
+			_ = client.Close()
+		}
+		if captured == nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) || errors.Is(err, net.ErrClosed) {
+				// The server is unreachable, so the other algorithms will fail too
+				return nil, &DialError{Addr: addr, Err: err}
+			}
+			// The server has no key of this type
+			lastErr = err
+			continue
+		}
+		if !containsHostKey(keys, captured) {
+			keys = append(keys, captured)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no host keys received from %s: %w", addr, lastErr)
+	}
+	return keys, nil
+}
+
+// containsHostKey reports whether keys includes key
+func containsHostKey(keys []ssh.PublicKey, key ssh.PublicKey) bool {
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatHostKey renders a host key in authorized_keys format
+// ("ssh-ed25519 AAAA..."), as accepted by ssh_host_keys
+func formatHostKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+// knownHostsLine renders a host key as an OpenSSH known_hosts line
+func knownHostsLine(host string, port int, key ssh.PublicKey) string {
+	if port != 22 {
+		host = fmt.Sprintf("[%s]:%d", host, port)
+	}
+	return host + " " + formatHostKey(key)
+}
+
+// parseHostKeys parses host keys in authorized_keys format. known_hosts lines
+// are accepted too, the host name field is ignored.
+func parseHostKeys(lines []string) ([]ssh.PublicKey, error) {
+	var keys []ssh.PublicKey
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			_, _, key, _, _, err = ssh.ParseKnownHosts([]byte(line))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid host key %q: %w", line, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// fixedHostKeysCallback accepts a server only if it presents one of the
+// given host keys. It also returns the host key algorithms to negotiate, so
+// a server with several keys presents one of the pinned types.
+func fixedHostKeysCallback(lines []string) (ssh.HostKeyCallback, []string, error) {
+	keys, err := parseHostKeys(lines)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil, fmt.Errorf("host key checking requires at least one host key")
+	}
+
+	var algorithms []string
+	for _, key := range keys {
+		keyAlgorithms := []string{key.Type()}
+		if key.Type() == ssh.KeyAlgoRSA {
+			keyAlgorithms = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, algorithm := range keyAlgorithms {
+			if !slices.Contains(algorithms, algorithm) {
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+
+	callback := func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		if containsHostKey(keys, key) {
+			return nil
+		}
+		return fmt.Errorf("host key mismatch for %s: got %s %s, which is not in ssh_host_keys", hostname, key.Type(), ssh.FingerprintSHA256(key))
+	}
+	return callback, algorithms, nil
+}
@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+)
+
+// startHostKeyServer starts an SSH server with an ed25519 and an RSA host
+// key that accepts any password, and returns its address and public keys
+func startHostKeyServer(t *testing.T) (string, int, []ssh.PublicKey) {
+	t.Helper()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	var public []ssh.PublicKey
+	for _, key := range []interface{}{edKey, rsaKey} {
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.AddHostKey(signer)
+		public = append(public, signer.PublicKey())
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					_ = newChannel.Reject(ssh.Prohibited, "no sessions")
+				}
+			}()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, public
+}
+
+func TestScanSSHHostKeys(t *testing.T) {
+	host, port, want := startHostKeyServer(t)
+
+	keys, err := scanSSHHostKeys(host, port, 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 host keys, got %d", len(keys))
+	}
+	for _, key := range want {
+		if !containsHostKey(keys, key) {
+			t.Errorf("missing %s host key", key.Type())
+		}
+	}
+}
+
+func TestScanSSHHostKeys_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	_, err = scanSSHHostKeys("127.0.0.1", port, time.Second)
+	if !isSSHDialError(err) {
+		t.Errorf("expected a DialError, got %v", err)
+	}
+}
+
+func TestRealSSHClient_HostKeyCheck(t *testing.T) {
+	host, port, keys := startHostKeyServer(t)
+
+	pinned := &SSHConfig{User: "root", Password: "secret", HostKeyCheck: true, HostKeys: []string{formatHostKey(keys[0])}}
+	client := NewSSHClient()
+	if err := client.Connect(host, port, pinned); err != nil {
+		t.Fatalf("expected pinned host key to be accepted: %v", err)
+	}
+	_ = client.Close()
+
+	// Only the RSA key is pinned, so the server must present it instead of its ed25519 key
+	pinned.HostKeys = []string{formatHostKey(keys[1])}
+	client = NewSSHClient()
+	if err := client.Connect(host, port, pinned); err != nil {
+		t.Fatalf("expected pinned RSA host key to be accepted: %v", err)
+	}
+	_ = client.Close()
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherKey)
+	mismatch := &SSHConfig{User: "root", Password: "secret", HostKeyCheck: true, HostKeys: []string{formatHostKey(otherSigner.PublicKey())}}
+	err := NewSSHClient().Connect(host, port, mismatch)
+	if err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+		t.Errorf("expected host key mismatch, got %v", err)
+	}
+
+	if err := NewSSHClient().Connect(host, port, &SSHConfig{User: "root", Password: "secret", HostKeyCheck: true}); err == nil {
+		t.Error("expected an error for host key checking without keys")
+	}
+}
+
+func TestParseHostKeys(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(edKey)
+	line := formatHostKey(signer.PublicKey())
+
+	keys, err := parseHostKeys([]string{line, "10.10.88.73 " + line, ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("expected 2 keys from authorized_keys and known_hosts lines, got %d", len(keys))
+	}
+
+	if _, err := parseHostKeys([]string{"not a key"}); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestSetSSHHostKeys(t *testing.T) {
+	_, _, keys := startHostKeyServer(t)
+
+	d := schema.TestResourceDataRaw(t, dataSourceSSHHostKeys().Schema, map[string]interface{}{
+		"hosts": []interface{}{"10.10.88.74", "10.10.88.73"},
+		"port":  2222,
+	})
+	diags := setSSHHostKeys(d, []string{"10.10.88.74", "10.10.88.73"}, 2222, map[string][]ssh.PublicKey{
+		"10.10.88.73": keys,
+		"10.10.88.74": keys[:1],
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if n := d.Get("keys.#").(int); n != 3 {
+		t.Errorf("expected 3 keys, got %d", n)
+	}
+	if fp := d.Get("keys.0.fingerprint").(string); fp != ssh.FingerprintSHA256(keys[0]) {
+		t.Errorf("unexpected fingerprint %q", fp)
+	}
+	hostKeys := d.Get("host_keys").(map[string]interface{})
+	if lines := strings.Split(hostKeys["10.10.88.73"].(string), "\n"); len(lines) != 2 {
+		t.Errorf("expected 2 host keys for 10.10.88.73, got %v", lines)
+	}
+	if !strings.HasPrefix(d.Get("known_hosts").(string), "[10.10.88.74]:2222 ssh-ed25519 ") {
+		t.Errorf("unexpected known_hosts:\n%s", d.Get("known_hosts"))
+	}
+	if d.Id() != "10.10.88.73,10.10.88.74:2222" {
+		t.Errorf("unexpected id %q", d.Id())
+	}
+}
@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// Pod Security Standard levels
+const (
+	podSecurityPrivileged = "privileged"
+	podSecurityBaseline   = "baseline"
+	podSecurityRestricted = "restricted"
+)
+
+// podSecurityPlugin is the name of the Pod Security admission plugin in
+// cluster.apiServer.admissionControl
+const podSecurityPlugin = "PodSecurity"
+
+// TalosPodSecurity configures the cluster-wide Pod Security Standard
+// defaults enforced by the PodSecurity admission plugin
+type TalosPodSecurity struct {
+	Enforce          string
+	Audit            string
+	Warn             string
+	ExemptNamespaces []string
+}
+
+// TalosAdmissionPlugin is an entry of cluster.apiServer.admissionControl
+type TalosAdmissionPlugin struct {
+	Name          string
+	Configuration map[string]interface{}
+}
+
+func talosPodSecuritySchema() *schema.Resource {
+	levels := []string{podSecurityPrivileged, podSecurityBaseline, podSecurityRestricted}
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"enforce": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          podSecurityBaseline,
+				Description:      "Pod Security Standard enforced in namespaces without a pod-security.kubernetes.io/enforce label: privileged, baseline or restricted.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(levels, false)),
+			},
+			"audit": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          podSecurityRestricted,
+				Description:      "Pod Security Standard whose violations are recorded in the audit log.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(levels, false)),
+			},
+			"warn": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          podSecurityRestricted,
+				Description:      "Pod Security Standard whose violations are returned to clients as warnings.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(levels, false)),
+			},
+			"exempt_namespaces": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Namespaces exempt from Pod Security admission. Defaults to kube-system.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func talosAdmissionPluginSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Name of the admission plugin (e.g., EventRateLimit).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotEmpty),
+			},
+			"configuration": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Plugin configuration as a YAML or JSON object, e.g. from yamlencode().",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := parseAdmissionConfiguration(v.(string)); err != nil {
+						return nil, []error{fmt.Errorf("%s: %w", k, err)}
+					}
+					return nil, nil
+				},
+			},
+		},
+	}
+}
+
+// parseAdmissionConfiguration parses an admission plugin configuration,
+// which must be a YAML (or JSON) object
+func parseAdmissionConfiguration(s string) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal([]byte(s), &config); err != nil {
+		return nil, fmt.Errorf("configuration must be a YAML or JSON object: %w", err)
+	}
+	if config == nil {
+		return nil, fmt.Errorf("configuration must be a YAML or JSON object")
+	}
+	return config, nil
+}
+
+// extractTalosAdmission reads the pod_security and admission_control blocks into cfg
+func extractTalosAdmission(d *schema.ResourceData, cfg *TalosClusterConfig) {
+	if v, ok := d.GetOk("pod_security"); ok {
+		if list := v.([]interface{}); len(list) > 0 && list[0] != nil {
+			data := list[0].(map[string]interface{})
+			cfg.PodSecurity = &TalosPodSecurity{
+				Enforce: data["enforce"].(string),
+				Audit:   data["audit"].(string),
+				Warn:    data["warn"].(string),
+			}
+			for _, ns := range data["exempt_namespaces"].([]interface{}) {
+				if s, ok := ns.(string); ok && s != "" {
+					cfg.PodSecurity.ExemptNamespaces = append(cfg.PodSecurity.ExemptNamespaces, s)
+				}
+			}
+		}
+	}
+
+	if v, ok := d.GetOk("admission_control"); ok {
+		for _, raw := range v.([]interface{}) {
+			data, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// Invalid configurations are rejected by the schema validation
+			config, _ := parseAdmissionConfiguration(data["configuration"].(string))
+			cfg.AdmissionControl = append(cfg.AdmissionControl, TalosAdmissionPlugin{
+				Name:          data["name"].(string),
+				Configuration: config,
+			})
+		}
+	}
+}
+
+// validateTalosAdmission rejects admission_control entries that clash with
+// each other or with pod_security, and admission settings without control planes
+func validateTalosAdmission(cfg TalosClusterConfig) error {
+	if cfg.WorkersOnly && (cfg.PodSecurity != nil || len(cfg.AdmissionControl) > 0) {
+		return fmt.Errorf("pod_security and admission_control are not supported in %s mode, they only apply to control planes", talosModeWorkersOnly)
+	}
+	seen := make(map[string]bool)
+	for _, plugin := range cfg.AdmissionControl {
+		if seen[plugin.Name] {
+			return fmt.Errorf("admission_control plugin %q is set more than once", plugin.Name)
+		}
+		seen[plugin.Name] = true
+	}
+	if cfg.PodSecurity != nil && seen[podSecurityPlugin] {
+		return fmt.Errorf("admission_control must not configure %s when pod_security is set", podSecurityPlugin)
+	}
+	return nil
+}
+
+// podSecurityConfiguration renders the PodSecurityConfiguration of the
+// PodSecurity admission plugin
+func podSecurityConfiguration(ps TalosPodSecurity) map[string]interface{} {
+	exempt := ps.ExemptNamespaces
+	if len(exempt) == 0 {
+		exempt = []string{"kube-system"}
+	}
+	return map[string]interface{}{
+		"apiVersion": "pod-security.admission.config.k8s.io/v1alpha1",
+		"kind":       "PodSecurityConfiguration",
+		"defaults": map[string]interface{}{
+			"enforce":         ps.Enforce,
+			"enforce-version": "latest",
+			"audit":           ps.Audit,
+			"audit-version":   "latest",
+			"warn":            ps.Warn,
+			"warn-version":    "latest",
+		},
+		"exemptions": map[string]interface{}{
+			"usernames":      []string{},
+			"runtimeClasses": []string{},
+			"namespaces":     exempt,
+		},
+	}
+}
+
+// generateAdmissionPatchYAML renders pod_security and admission_control as a
+// config patch for the control planes. Talos replaces admissionControl
+// entries by name, so only the plugins given are changed. Returns an empty
+// string when neither is set.
+func generateAdmissionPatchYAML(cfg TalosClusterConfig) (string, error) {
+	var plugins []map[string]interface{}
+	if cfg.PodSecurity != nil {
+		plugins = append(plugins, map[string]interface{}{
+			"name":          podSecurityPlugin,
+			"configuration": podSecurityConfiguration(*cfg.PodSecurity),
+		})
+	}
+	for _, plugin := range cfg.AdmissionControl {
+		plugins = append(plugins, map[string]interface{}{
+			"name":          plugin.Name,
+			"configuration": plugin.Configuration,
+		})
+	}
+	if len(plugins) == 0 {
+		return "", nil
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		"cluster": map[string]interface{}{
+			"apiServer": map[string]interface{}{
+				"admissionControl": plugins,
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal admission control patch: %w", err)
+	}
+	return string(data), nil
+}
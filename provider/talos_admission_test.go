@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateAdmissionPatchYAML(t *testing.T) {
+	raw := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"pod_security": []interface{}{map[string]interface{}{
+			"enforce":           "restricted",
+			"exempt_namespaces": []interface{}{"kube-system", "metallb-system"},
+		}},
+		"admission_control": []interface{}{map[string]interface{}{
+			"name":          "EventRateLimit",
+			"configuration": `{"kind":"Configuration","limits":[{"type":"Server","qps":50}]}`,
+		}},
+	}
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, raw)
+	cfg := extractTalosClusterConfig(d)
+	if err := validateTalosAdmission(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	patch, err := generateAdmissionPatchYAML(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Cluster struct {
+			APIServer struct {
+				AdmissionControl []struct {
+					Name          string                 `yaml:"name"`
+					Configuration map[string]interface{} `yaml:"configuration"`
+				} `yaml:"admissionControl"`
+			} `yaml:"apiServer"`
+		} `yaml:"cluster"`
+	}
+	if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+		t.Fatalf("invalid patch YAML: %v\n%s", err, patch)
+	}
+
+	plugins := parsed.Cluster.APIServer.AdmissionControl
+	if len(plugins) != 2 || plugins[0].Name != "PodSecurity" || plugins[1].Name != "EventRateLimit" {
+		t.Fatalf("unexpected plugins: %+v", plugins)
+	}
+	defaults := plugins[0].Configuration["defaults"].(map[string]interface{})
+	if defaults["enforce"] != "restricted" || defaults["audit"] != "restricted" || defaults["warn"] != "restricted" {
+		t.Errorf("unexpected pod security defaults: %v", defaults)
+	}
+	exemptions := plugins[0].Configuration["exemptions"].(map[string]interface{})
+	if namespaces := exemptions["namespaces"].([]interface{}); len(namespaces) != 2 || namespaces[1] != "metallb-system" {
+		t.Errorf("unexpected exempt namespaces: %v", namespaces)
+	}
+	if plugins[1].Configuration["kind"] != "Configuration" {
+		t.Errorf("unexpected EventRateLimit configuration: %v", plugins[1].Configuration)
+	}
+}
+
+func TestGenerateAdmissionPatchYAML_Defaults(t *testing.T) {
+	patch, err := generateAdmissionPatchYAML(TalosClusterConfig{})
+	if err != nil || patch != "" {
+		t.Errorf("expected no patch without settings, got %q, %v", patch, err)
+	}
+
+	patch, _ = generateAdmissionPatchYAML(TalosClusterConfig{
+		PodSecurity: &TalosPodSecurity{Enforce: "baseline", Audit: "restricted", Warn: "restricted"},
+	})
+	if !strings.Contains(patch, "- kube-system") {
+		t.Errorf("expected kube-system to be exempt by default, got:\n%s", patch)
+	}
+}
+
+func TestValidateTalosAdmission(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     TalosClusterConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg: TalosClusterConfig{
+				PodSecurity:      &TalosPodSecurity{Enforce: "baseline"},
+				AdmissionControl: []TalosAdmissionPlugin{{Name: "EventRateLimit"}},
+			},
+		},
+		{
+			name:    "duplicate plugin",
+			cfg:     TalosClusterConfig{AdmissionControl: []TalosAdmissionPlugin{{Name: "EventRateLimit"}, {Name: "EventRateLimit"}}},
+			wantErr: "more than once",
+		},
+		{
+			name: "PodSecurity with pod_security",
+			cfg: TalosClusterConfig{
+				PodSecurity:      &TalosPodSecurity{Enforce: "baseline"},
+				AdmissionControl: []TalosAdmissionPlugin{{Name: "PodSecurity"}},
+			},
+			wantErr: "when pod_security is set",
+		},
+		{
+			name:    "workers only",
+			cfg:     TalosClusterConfig{WorkersOnly: true, PodSecurity: &TalosPodSecurity{Enforce: "baseline"}},
+			wantErr: "workers_only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTalosAdmission(tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTalosAdmissionPluginSchema_ConfigurationValidation(t *testing.T) {
+	validate := talosAdmissionPluginSchema().Schema["configuration"].ValidateFunc
+	if _, errs := validate("kind: Configuration\n", "configuration"); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	for _, invalid := range []string{"", "- a\n- b\n", "{"} {
+		if _, errs := validate(invalid, "configuration"); len(errs) == 0 {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestTalosProvisioner_GenerateConfigWithPatches_ControlPlane(t *testing.T) {
+	var gotArgs []string
+	var patchContent string
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		for i, arg := range args {
+			if arg == "--config-patch-control-plane" && i+1 < len(args) {
+				data, _ := os.ReadFile(strings.TrimPrefix(args[i+1], "@"))
+				patchContent = string(data)
+			}
+		}
+		return exec.Command("true")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	err := provisioner.GenerateConfigWithPatches("secrets.yaml", "test", "https://10.10.88.73:6443", emmcInstallDisk, "configs", nil, []string{"cluster:\n  apiServer: {}\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(strings.Join(gotArgs, " "), "--config-patch @") {
+		t.Errorf("expected no patch for all machine types, got %v", gotArgs)
+	}
+	if !strings.Contains(patchContent, "apiServer") {
+		t.Errorf("expected control plane patch file to be passed to talosctl, got %q", patchContent)
+	}
+}
@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Values of apply_mode, passed to talosctl apply-config --mode
+const (
+	talosApplyAuto     = "auto"
+	talosApplyNoReboot = "no-reboot"
+	talosApplyStaged   = "staged"
+)
+
+// talosInPlaceSettings are the arguments whose changes Update applies to
+// the running nodes. Other machine config inputs force a new cluster.
+var talosInPlaceSettings = []string{
+	"allow_scheduling_on_control_plane",
+	"host_dns",
+	"time_sync",
+	"kubespan",
+	"pod_security",
+	"admission_control",
+}
+
+// talosMachineConfigChanged reports whether Update must apply regenerated
+// machine configs: an in-place setting changed, or the cilium block turned
+// kube-proxy replacement on or off
+func talosMachineConfigChanged(d *schema.ResourceData) bool {
+	return d.HasChanges(talosInPlaceSettings...) || d.HasChange("cilium.0.kube_proxy_replacement")
+}
+
+// ApplyConfigWithMode applies config to a configured node with a talosctl
+// apply mode
+func (p *TalosProvisioner) ApplyConfigWithMode(talosconfig, nodeIP, configPath, mode string) error {
+	args := []string{
+		"apply-config",
+		"--nodes", nodeIP,
+		"--file", configPath,
+		"--mode", mode,
+	}
+
+	_, err := p.runTalosctlWithConfig(talosconfig, args...)
+	if err != nil {
+		return fmt.Errorf("failed to apply config to %s in %s mode: %w", nodeIP, mode, err)
+	}
+	return nil
+}
+
+// RebootNode reboots a node. talosctl waits for it to come back.
+func (p *TalosProvisioner) RebootNode(talosconfig, nodeIP string) error {
+	if _, err := p.runTalosctlWithConfig(talosconfig, "reboot", "--nodes", nodeIP); err != nil {
+		return fmt.Errorf("failed to reboot node %s: %w", nodeIP, err)
+	}
+	return nil
+}
+
+// writeTalosconfig writes talosconfig content to the work dir and returns
+// its path
+func (p *TalosProvisioner) writeTalosconfig(talosconfig string) (string, error) {
+	path := filepath.Join(p.workDir, "talosconfig")
+	if err := os.WriteFile(path, []byte(talosconfig), 0600); err != nil {
+		return "", fmt.Errorf("failed to write talosconfig: %w", err)
+	}
+	return path, nil
+}
+
+// ApplyClusterConfig regenerates the machine configs of a running cluster
+// from its secrets and applies them to every node with a talosctl apply
+// mode, control planes first
+func (p *TalosProvisioner) ApplyClusterConfig(cfg TalosClusterConfig, talosconfig, mode string) error {
+	secretsPath := filepath.Join(p.workDir, "secrets.yaml")
+	if err := os.WriteFile(secretsPath, []byte(cfg.SecretsYAML), 0600); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	configDir := filepath.Join(p.workDir, "configs")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := p.generateClusterConfigs(cfg, secretsPath, configDir); err != nil {
+		return err
+	}
+	talosconfigPath, err := p.writeTalosconfig(talosconfig)
+	if err != nil {
+		return err
+	}
+
+	for i, cp := range cfg.ControlPlanes {
+		patchedConfig, err := p.patchNodeConfig(configDir, cfg, cp, i, true)
+		if err != nil {
+			return err
+		}
+		if err := p.ApplyConfigWithMode(talosconfigPath, cp.Host, patchedConfig, mode); err != nil {
+			return err
+		}
+	}
+	for i, worker := range cfg.Workers {
+		patchedConfig, err := p.patchNodeConfig(configDir, cfg, worker, i, false)
+		if err != nil {
+			return err
+		}
+		if err := p.ApplyConfigWithMode(talosconfigPath, worker.Host, patchedConfig, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebootNodes reboots nodes one at a time. Before the next one, the cluster
+// must be healthy as seen from healthNode, or with no control plane (in
+// workers_only mode) the rebooted worker's kubelet.
+func (p *TalosProvisioner) RebootNodes(talosconfig string, nodes []string, healthNode string, timeout time.Duration) error {
+	talosconfigPath, err := p.writeTalosconfig(talosconfig)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := p.RebootNode(talosconfigPath, node); err != nil {
+			return err
+		}
+		if healthNode != "" {
+			err = p.WaitForHealth(talosconfigPath, healthNode, timeout)
+		} else {
+			err = p.WaitForWorkers(talosconfigPath, []string{node}, timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("after rebooting %s: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// talosRebootsAdded returns the reboot_nodes entries the plan adds, in
+// list order
+func talosRebootsAdded(d *schema.ResourceData) []string {
+	old, new := d.GetChange("reboot_nodes")
+	seen := map[string]bool{}
+	for _, host := range old.([]interface{}) {
+		seen[host.(string)] = true
+	}
+	var added []string
+	for _, host := range new.([]interface{}) {
+		if h := host.(string); !seen[h] {
+			seen[h] = true
+			added = append(added, h)
+		}
+	}
+	return added
+}
+
+// validateTalosRebootNodes checks that reboot_nodes only lists hosts of the
+// cluster's node blocks
+func validateTalosRebootNodes(get func(string) interface{}) error {
+	hosts := map[string]bool{}
+	for _, block := range []string{"control_plane", "worker"} {
+		for _, n := range get(block).([]interface{}) {
+			if m, ok := n.(map[string]interface{}); ok {
+				hosts[m["host"].(string)] = true
+			}
+		}
+	}
+	for _, host := range get("reboot_nodes").([]interface{}) {
+		if h, _ := host.(string); h != "" && !hosts[h] {
+			return fmt.Errorf("reboot_nodes lists %q, which is not the host of a control_plane or worker block", h)
+		}
+	}
+	return nil
+}
+
+// updateTalosMachineConfig applies changed in-place settings to the nodes
+// with apply_mode, then reboots the nodes added to reboot_nodes
+func updateTalosMachineConfig(ctx context.Context, d *schema.ResourceData, meta interface{}, provisioner *TalosProvisioner) error {
+	applyConfig := talosMachineConfigChanged(d)
+	reboots := talosRebootsAdded(d)
+
+	talosconfig := readSensitiveOutput(d, "talosconfig", "talosconfig_path")
+	if talosconfig == "" {
+		return fmt.Errorf("no talosconfig available to update the nodes (not in state and talosconfig_path is unset or unreadable)")
+	}
+	logs, err := provisionLogsFor(d)
+	if err != nil {
+		return err
+	}
+	provisioner.Logs = logs
+
+	cfg := extractTalosClusterConfig(d)
+	if applyConfig {
+		if err := validateTalosAdmission(cfg); err != nil {
+			return err
+		}
+		if cfg.SecretsYAML == "" {
+			cfg.SecretsYAML = readSensitiveOutput(d, "secrets_yaml", "secrets_path")
+		}
+		if cfg.SecretsYAML == "" {
+			return fmt.Errorf("no cluster secrets available to regenerate the machine configs (secrets_yaml is not in state and secrets_path is unset or unreadable)")
+		}
+		cfg.NodeLabels = defaultMetadata(meta)
+		resolveTalosInstallDisks(ctx, d, meta, provisioner, &cfg)
+		if err := provisioner.ApplyClusterConfig(cfg, talosconfig, d.Get("apply_mode").(string)); err != nil {
+			return err
+		}
+	}
+
+	if len(reboots) > 0 {
+		healthNode := ""
+		if len(cfg.ControlPlanes) > 0 {
+			healthNode = cfg.ControlPlanes[0].Host
+		}
+		if err := provisioner.RebootNodes(talosconfig, reboots, healthNode, cfg.BootstrapTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
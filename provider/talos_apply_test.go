@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func talosApplyConfig(extra map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"worker":           []interface{}{map[string]interface{}{"host": "10.10.88.74"}},
+		"reboot_nodes":     []interface{}{"10.10.88.73"},
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+	return config
+}
+
+// talosApplyUpdate plans config against a created cluster and returns the
+// resource data Update would get, and the plan
+func talosApplyUpdate(t *testing.T, config map[string]interface{}) (*schema.ResourceData, *terraform.InstanceDiff) {
+	t.Helper()
+	r := resourceTalosCluster()
+	create, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(talosApplyConfig(nil)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prior, err := schema.InternalMap(r.Schema).Data(nil, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prior.SetId("test")
+	_ = prior.Set("talosconfig", "context: test")
+	_ = prior.Set("secrets_yaml", "cluster:\n  id: test\n")
+
+	diff, err := r.Diff(context.Background(), prior.State(), terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(prior.State(), diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, diff
+}
+
+func TestUpdateTalosMachineConfig_Staged(t *testing.T) {
+	d, diff := talosApplyUpdate(t, talosApplyConfig(map[string]interface{}{
+		"kubespan":     true,
+		"apply_mode":   talosApplyStaged,
+		"reboot_nodes": []interface{}{"10.10.88.73", "10.10.88.74"},
+	}))
+	if diff.RequiresNew() {
+		t.Fatalf("expected an in-place update, got %+v", diff)
+	}
+
+	var calls []string
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, strings.Join(args, " "))
+		return exec.Command("true")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	if err := updateTalosMachineConfig(context.Background(), d, nil, provisioner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	applyCP := commandIndex(calls, "apply-config --nodes 10.10.88.73")
+	applyWorker := commandIndex(calls, "apply-config --nodes 10.10.88.74")
+	reboot := commandIndex(calls, "reboot --nodes 10.10.88.74")
+	if applyCP == -1 || applyWorker < applyCP || reboot < applyWorker {
+		t.Fatalf("expected control plane and worker applies before the reboot, got %v", calls)
+	}
+	if !strings.HasSuffix(calls[applyCP], "--mode staged") {
+		t.Errorf("expected a staged apply, got %q", calls[applyCP])
+	}
+	if commandIndex(calls, "reboot --nodes 10.10.88.73") != -1 {
+		t.Error("expected nodes already in reboot_nodes not to be rebooted again")
+	}
+	if commandIndex(calls[reboot:], "health --nodes 10.10.88.73") == -1 {
+		t.Errorf("expected a health check after the reboot, got %v", calls)
+	}
+}
+
+func TestUpdateTalosMachineConfig_RebootOnly(t *testing.T) {
+	d, _ := talosApplyUpdate(t, talosApplyConfig(map[string]interface{}{
+		"reboot_nodes": []interface{}{"10.10.88.73", "10.10.88.74"},
+	}))
+
+	var calls []string
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, strings.Join(args, " "))
+		return exec.Command("true")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	if err := updateTalosMachineConfig(context.Background(), d, nil, provisioner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if commandIndex(calls, "apply-config") != -1 {
+		t.Errorf("expected no config apply without setting changes, got %v", calls)
+	}
+	if commandIndex(calls, "reboot --nodes 10.10.88.74") == -1 {
+		t.Errorf("expected the added node to be rebooted, got %v", calls)
+	}
+}
+
+func TestValidateTalosRebootNodes(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, talosApplyConfig(map[string]interface{}{
+		"reboot_nodes": []interface{}{"10.10.88.74", "10.10.88.99"},
+	}))
+	err := validateTalosRebootNodes(d.Get)
+	if err == nil || !strings.Contains(err.Error(), `"10.10.88.99"`) {
+		t.Errorf("expected an unknown host to be rejected, got %v", err)
+	}
+
+	d = schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, talosApplyConfig(nil))
+	if err := validateTalosRebootNodes(d.Get); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
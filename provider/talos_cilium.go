@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Values of cni
+const (
+	talosCNIFlannel = "flannel"
+	talosCNINone    = "none"
+)
+
+// Cilium chart location
+const (
+	ciliumRepoName  = "cilium"
+	ciliumRepoURL   = "https://helm.cilium.io/"
+	ciliumNamespace = "kube-system"
+)
+
+// talosKubePrismPort is the port of KubePrism, the API server load balancer
+// Talos runs on every node, which Cilium uses in place of the kube-proxy
+// Service address it replaces
+const talosKubePrismPort = 7445
+
+// talosCilium is the cilium block of turingpi_talos_cluster
+type talosCilium struct {
+	Version              string
+	Values               string
+	KubeProxyReplacement bool
+}
+
+func talosCiliumSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Cilium chart version (e.g., 1.16.5). Empty installs the latest release.",
+			},
+			"kube_proxy_replacement": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Disable kube-proxy in the machine config and let Cilium replace it, reaching the API server through KubePrism (default: true). Changes are applied to the nodes with apply_mode.",
+			},
+			"values": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Extra chart values as YAML, merged over the Talos defaults",
+			},
+		},
+	}
+}
+
+// extractTalosCilium returns the cilium block, or nil when it is not set
+func extractTalosCilium(get func(string) interface{}) *talosCilium {
+	v := get("cilium").([]interface{})
+	if len(v) == 0 || v[0] == nil {
+		return nil
+	}
+	m := v[0].(map[string]interface{})
+	return &talosCilium{
+		Version:              m["version"].(string),
+		Values:               m["values"].(string),
+		KubeProxyReplacement: m["kube_proxy_replacement"].(bool),
+	}
+}
+
+// validateTalosCNI checks that cilium is only set without the default CNI
+func validateTalosCNI(get func(string) interface{}) error {
+	if extractTalosCilium(get) != nil && get("cni").(string) != talosCNINone {
+		return fmt.Errorf("cilium requires cni = %q, so Talos does not install Flannel", talosCNINone)
+	}
+	return nil
+}
+
+// ciliumValues returns the chart values for Talos: Kubernetes IPAM, the
+// capabilities Talos allows instead of SYS_MODULE, and the cgroup v2 mount
+// Talos already provides, then the values of the cilium block on top
+func ciliumValues(c talosCilium) (map[string]interface{}, error) {
+	values := map[string]interface{}{
+		"ipam": map[string]interface{}{"mode": "kubernetes"},
+		"securityContext": map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"ciliumAgent": []interface{}{
+					"CHOWN", "KILL", "NET_ADMIN", "NET_RAW", "IPC_LOCK", "SYS_ADMIN",
+					"SYS_RESOURCE", "DAC_OVERRIDE", "FOWNER", "SETGID", "SETUID",
+				},
+				"cleanCiliumState": []interface{}{"NET_ADMIN", "SYS_ADMIN", "SYS_RESOURCE"},
+			},
+		},
+		"cgroup": map[string]interface{}{
+			"autoMount": map[string]interface{}{"enabled": false},
+			"hostRoot":  "/sys/fs/cgroup",
+		},
+		"kubeProxyReplacement": c.KubeProxyReplacement,
+	}
+	if c.KubeProxyReplacement {
+		values["k8sServiceHost"] = "localhost"
+		values["k8sServicePort"] = talosKubePrismPort
+	}
+	if c.Values != "" {
+		var extra map[string]interface{}
+		if err := yaml.Unmarshal([]byte(c.Values), &extra); err != nil {
+			return nil, fmt.Errorf("cilium values are not valid YAML: %w", err)
+		}
+		values = mergeHelmValues(values, extra)
+	}
+	return values, nil
+}
+
+// generateCNIPatchYAML renders cni and, with kube-proxy replacement, the
+// disabled kube-proxy as a cluster patch for every node. Returns an empty
+// string for the default Flannel.
+func generateCNIPatchYAML(cfg TalosClusterConfig) (string, error) {
+	if cfg.CNI != talosCNINone {
+		return "", nil
+	}
+	cluster := map[string]interface{}{
+		"network": map[string]interface{}{
+			"cni": map[string]interface{}{"name": talosCNINone},
+		},
+	}
+	if cfg.Cilium != nil && cfg.Cilium.KubeProxyReplacement {
+		cluster["proxy"] = map[string]interface{}{"disabled": true}
+	}
+	data, err := yaml.Marshal(map[string]interface{}{"cluster": cluster})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal CNI patch: %w", err)
+	}
+	return string(data), nil
+}
+
+// installCilium installs or upgrades the Cilium chart. It does not wait for
+// the pods: nodes only become ready once Cilium runs, which the cluster
+// health check that follows waits for.
+func installCilium(ctx context.Context, client HelmClient, c talosCilium) error {
+	values, err := ciliumValues(c)
+	if err != nil {
+		return err
+	}
+	valuesYaml, err := renderHelmValues(values)
+	if err != nil {
+		return err
+	}
+	if err := client.AddRepository(ciliumRepoName, ciliumRepoURL); err != nil {
+		return fmt.Errorf("failed to add Cilium repo: %w", err)
+	}
+	tflog.Info(ctx, "Installing Cilium", map[string]interface{}{
+		"version":                c.Version,
+		"kube_proxy_replacement": c.KubeProxyReplacement,
+	})
+	spec := &ChartSpec{
+		ReleaseName: "cilium",
+		ChartName:   ciliumRepoName + "/cilium",
+		Namespace:   ciliumNamespace,
+		Version:     c.Version,
+		ValuesYaml:  valuesYaml,
+		Timeout:     5 * time.Minute,
+	}
+	if _, err := client.InstallOrUpgradeChart(ctx, spec); err != nil {
+		return fmt.Errorf("failed to install Cilium chart: %w", err)
+	}
+	return nil
+}
+
+// ciliumInstaller returns the InstallCNI hook of TalosProvisioner for a
+// cilium block
+func ciliumInstaller(c talosCilium) func(context.Context, string) error {
+	return func(ctx context.Context, kubeconfigPath string) error {
+		client, err := NewHelmClient(kubeconfigPath, ciliumNamespace)
+		if err != nil {
+			return fmt.Errorf("failed to create Helm client: %w", err)
+		}
+		return installCilium(ctx, client, c)
+	}
+}
+
+// updateTalosCilium upgrades Cilium when the cilium block changes. Removing
+// the block leaves Cilium installed, since the cluster has no other CNI.
+func updateTalosCilium(ctx context.Context, d *schema.ResourceData, c talosCilium) error {
+	kubeconfig := readSensitiveOutput(d, "kubeconfig", "kubeconfig_path")
+	if kubeconfig == "" {
+		return fmt.Errorf("cilium changes need the kubeconfig, which is neither in state nor at kubeconfig_path")
+	}
+	client, err := NewHelmClientFromBytes([]byte(kubeconfig), ciliumNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to create Helm client: %w", err)
+	}
+	return installCilium(ctx, client, c)
+}
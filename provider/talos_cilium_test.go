@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCiliumValues(t *testing.T) {
+	values, err := ciliumValues(talosCilium{
+		KubeProxyReplacement: true,
+		Values:               "hubble:\n  relay:\n    enabled: true\nipam:\n  mode: cluster-pool\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["kubeProxyReplacement"] != true || values["k8sServiceHost"] != "localhost" || values["k8sServicePort"] != talosKubePrismPort {
+		t.Errorf("expected kube-proxy replacement through KubePrism, got %v", values)
+	}
+	if values["ipam"].(map[string]interface{})["mode"] != "cluster-pool" {
+		t.Errorf("expected the block values to win, got %v", values["ipam"])
+	}
+	if values["cgroup"].(map[string]interface{})["hostRoot"] != "/sys/fs/cgroup" {
+		t.Errorf("expected the Talos cgroup mount, got %v", values["cgroup"])
+	}
+	if _, ok := values["hubble"]; !ok {
+		t.Error("expected the extra values to be merged")
+	}
+
+	values, _ = ciliumValues(talosCilium{})
+	if _, ok := values["k8sServiceHost"]; ok || values["kubeProxyReplacement"] != false {
+		t.Errorf("expected kube-proxy to be kept, got %v", values)
+	}
+
+	if _, err := ciliumValues(talosCilium{Values: "hubble: ["}); err == nil {
+		t.Error("expected invalid values to be rejected")
+	}
+}
+
+func TestGenerateCNIPatchYAML(t *testing.T) {
+	if patch, _ := generateCNIPatchYAML(TalosClusterConfig{CNI: talosCNIFlannel}); patch != "" {
+		t.Errorf("expected no patch with Flannel, got %q", patch)
+	}
+
+	patch, err := generateCNIPatchYAML(TalosClusterConfig{CNI: talosCNINone, Cilium: &talosCilium{KubeProxyReplacement: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Cluster struct {
+			Network struct {
+				CNI struct {
+					Name string `yaml:"name"`
+				} `yaml:"cni"`
+			} `yaml:"network"`
+			Proxy struct {
+				Disabled bool `yaml:"disabled"`
+			} `yaml:"proxy"`
+		} `yaml:"cluster"`
+	}
+	if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Cluster.Network.CNI.Name != "none" || !parsed.Cluster.Proxy.Disabled {
+		t.Errorf("unexpected patch:\n%s", patch)
+	}
+
+	patch, _ = generateCNIPatchYAML(TalosClusterConfig{CNI: talosCNINone})
+	if strings.Contains(patch, "proxy") {
+		t.Errorf("expected kube-proxy to be kept without cilium, got:\n%s", patch)
+	}
+}
+
+func TestValidateTalosCNI(t *testing.T) {
+	config := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+		"cilium":           []interface{}{map[string]interface{}{"version": "1.16.5"}},
+	}
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, config)
+	if err := validateTalosCNI(d.Get); err == nil || !strings.Contains(err.Error(), `cni = "none"`) {
+		t.Errorf("expected cilium to require cni = none, got %v", err)
+	}
+
+	config["cni"] = talosCNINone
+	d = schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, config)
+	if err := validateTalosCNI(d.Get); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c := extractTalosCilium(d.Get); c == nil || !c.KubeProxyReplacement || c.Version != "1.16.5" {
+		t.Errorf("unexpected cilium block: %+v", c)
+	}
+}
+
+func TestInstallCilium(t *testing.T) {
+	client := &MockHelmClient{}
+	if err := installCilium(context.Background(), client, talosCilium{Version: "1.16.5", KubeProxyReplacement: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.AddRepositoryCalls) != 1 || client.AddRepositoryCalls[0].URL != ciliumRepoURL {
+		t.Errorf("expected the Cilium repo to be added, got %v", client.AddRepositoryCalls)
+	}
+	if len(client.InstallOrUpgradeCalls) != 1 {
+		t.Fatalf("expected one install, got %d", len(client.InstallOrUpgradeCalls))
+	}
+	spec := client.InstallOrUpgradeCalls[0]
+	if spec.ChartName != "cilium/cilium" || spec.Namespace != "kube-system" || spec.Version != "1.16.5" {
+		t.Errorf("unexpected chart spec: %+v", spec)
+	}
+	if spec.Wait {
+		t.Error("expected the install not to wait for pods, which need the nodes to become ready")
+	}
+	if !strings.Contains(spec.ValuesYaml, "k8sServicePort: 7445") {
+		t.Errorf("expected the KubePrism port in the values:\n%s", spec.ValuesYaml)
+	}
+}
+
+func TestResourceTalosCluster_LegacyStateCNI(t *testing.T) {
+	config := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"control_plane":    []interface{}{map[string]interface{}{"host": "10.10.88.73"}},
+	}
+	diff := legacyStateDiff(t, resourceTalosCluster(), config, "cni")
+	if diff != nil && diff.Attributes["cni"] != nil {
+		t.Errorf("expected no cni change for state without cni, got %+v", diff.Attributes["cni"])
+	}
+	if diff.RequiresNew() {
+		t.Error("expected state without cni not to be replaced")
+	}
+}
@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// TalosHostDNS configures the Talos host DNS cache (machine.features.hostDNS)
+type TalosHostDNS struct {
+	Enabled              bool
+	ForwardKubeDNSToHost bool
+	ResolveMemberNames   bool
+}
+
+// TalosTimeSync configures Talos time synchronization (machine.time)
+type TalosTimeSync struct {
+	Enabled     bool
+	Servers     []string
+	BootTimeout string
+}
+
+func talosHostDNSSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Enable the host DNS caching resolver on each node.",
+			},
+			"forward_kube_dns_to_host": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Forward CoreDNS upstream queries to the host DNS resolver instead of the node's nameservers.",
+			},
+			"resolve_member_names": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Resolve cluster member hostnames through the host DNS resolver.",
+			},
+		},
+	}
+}
+
+func talosTimeSyncSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Enable time synchronization. Disable when the node clock is managed otherwise.",
+			},
+			"servers": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "NTP servers to synchronize with (e.g., a router on the LAN when outbound NTP is blocked). Defaults to time.cloudflare.com.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"boot_timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "How long to wait for the first time sync during boot (e.g., 2m). Uses the Talos default when empty.",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if s := v.(string); s != "" {
+						if _, err := time.ParseDuration(s); err != nil {
+							return nil, []error{fmt.Errorf("%s must be a duration such as 2m: %w", k, err)}
+						}
+					}
+					return nil, nil
+				},
+			},
+		},
+	}
+}
+
+// extractTalosMachineSettings reads the host_dns and time_sync blocks and
+// the kubespan flag into cfg
+func extractTalosMachineSettings(d *schema.ResourceData, cfg *TalosClusterConfig) {
+	cfg.KubeSpan = d.Get("kubespan").(bool)
+
+	if v, ok := d.GetOk("host_dns"); ok {
+		if list := v.([]interface{}); len(list) > 0 && list[0] != nil {
+			data := list[0].(map[string]interface{})
+			cfg.HostDNS = &TalosHostDNS{
+				Enabled:              data["enabled"].(bool),
+				ForwardKubeDNSToHost: data["forward_kube_dns_to_host"].(bool),
+				ResolveMemberNames:   data["resolve_member_names"].(bool),
+			}
+		}
+	}
+
+	if v, ok := d.GetOk("time_sync"); ok {
+		if list := v.([]interface{}); len(list) > 0 && list[0] != nil {
+			data := list[0].(map[string]interface{})
+			cfg.TimeSync = &TalosTimeSync{
+				Enabled:     data["enabled"].(bool),
+				BootTimeout: data["boot_timeout"].(string),
+			}
+			for _, server := range data["servers"].([]interface{}) {
+				if s, ok := server.(string); ok && s != "" {
+					cfg.TimeSync.Servers = append(cfg.TimeSync.Servers, s)
+				}
+			}
+		}
+	}
+}
+
+// generateMachineSettingsPatchYAML renders the cluster-wide machine settings
+// as a config patch for every node. Returns an empty string when none are set.
+func generateMachineSettingsPatchYAML(cfg TalosClusterConfig) (string, error) {
+	machine := map[string]interface{}{}
+
+	if cfg.HostDNS != nil {
+		hostDNS := map[string]interface{}{
+			"enabled": cfg.HostDNS.Enabled,
+		}
+		if cfg.HostDNS.Enabled {
+			hostDNS["forwardKubeDNSToHost"] = cfg.HostDNS.ForwardKubeDNSToHost
+			hostDNS["resolveMemberNames"] = cfg.HostDNS.ResolveMemberNames
+		}
+		machine["features"] = map[string]interface{}{
+			"hostDNS": hostDNS,
+		}
+	}
+
+	if cfg.TimeSync != nil {
+		timeConfig := map[string]interface{}{
+			"disabled": !cfg.TimeSync.Enabled,
+		}
+		if len(cfg.TimeSync.Servers) > 0 {
+			timeConfig["servers"] = cfg.TimeSync.Servers
+		}
+		if cfg.TimeSync.BootTimeout != "" {
+			timeConfig["bootTimeout"] = cfg.TimeSync.BootTimeout
+		}
+		machine["time"] = timeConfig
+	}
+
+	if len(cfg.NodeLabels) > 0 {
+		machine["nodeLabels"] = cfg.NodeLabels
+	}
+
+	patch := map[string]interface{}{}
+	if cfg.KubeSpan {
+		// KubeSpan finds its peers through cluster discovery, so discovery is
+		// enabled explicitly in case an earlier patch turned it off
+		machine["network"] = map[string]interface{}{
+			"kubespan": map[string]interface{}{"enabled": true},
+		}
+		patch["cluster"] = map[string]interface{}{
+			"discovery": map[string]interface{}{"enabled": true},
+		}
+	}
+
+	if len(machine) == 0 {
+		return "", nil
+	}
+	patch["machine"] = machine
+
+	data, err := yaml.Marshal(patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal machine settings patch: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateMachineSettingsPatchYAML(t *testing.T) {
+	raw := map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"host_dns":         []interface{}{map[string]interface{}{"forward_kube_dns_to_host": true}},
+		"time_sync": []interface{}{map[string]interface{}{
+			"servers":      []interface{}{"10.10.88.1", "pool.ntp.org"},
+			"boot_timeout": "2m",
+		}},
+	}
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, raw)
+	cfg := extractTalosClusterConfig(d)
+
+	patch, err := generateMachineSettingsPatchYAML(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Machine struct {
+			Features struct {
+				HostDNS map[string]bool `yaml:"hostDNS"`
+			} `yaml:"features"`
+			Time struct {
+				Disabled    bool     `yaml:"disabled"`
+				Servers     []string `yaml:"servers"`
+				BootTimeout string   `yaml:"bootTimeout"`
+			} `yaml:"time"`
+		} `yaml:"machine"`
+	}
+	if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+		t.Fatalf("invalid patch YAML: %v\n%s", err, patch)
+	}
+
+	hostDNS := parsed.Machine.Features.HostDNS
+	if !hostDNS["enabled"] || !hostDNS["forwardKubeDNSToHost"] || hostDNS["resolveMemberNames"] {
+		t.Errorf("unexpected hostDNS settings: %v", hostDNS)
+	}
+	timeConfig := parsed.Machine.Time
+	if timeConfig.Disabled || strings.Join(timeConfig.Servers, ",") != "10.10.88.1,pool.ntp.org" || timeConfig.BootTimeout != "2m" {
+		t.Errorf("unexpected time settings: %+v", timeConfig)
+	}
+}
+
+func TestGenerateMachineSettingsPatchYAML_Defaults(t *testing.T) {
+	patch, err := generateMachineSettingsPatchYAML(TalosClusterConfig{})
+	if err != nil || patch != "" {
+		t.Errorf("expected no patch without settings, got %q, %v", patch, err)
+	}
+
+	patch, _ = generateMachineSettingsPatchYAML(TalosClusterConfig{
+		HostDNS:  &TalosHostDNS{Enabled: false, ForwardKubeDNSToHost: true},
+		TimeSync: &TalosTimeSync{Enabled: false},
+	})
+	if strings.Contains(patch, "forwardKubeDNSToHost") {
+		t.Errorf("expected no host DNS options when disabled, got:\n%s", patch)
+	}
+	if !strings.Contains(patch, "disabled: true") {
+		t.Errorf("expected time sync to be disabled, got:\n%s", patch)
+	}
+}
+
+func TestGenerateMachineSettingsPatchYAML_KubeSpan(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"kubespan":         true,
+	})
+	patch, err := generateMachineSettingsPatchYAML(extractTalosClusterConfig(d))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Machine struct {
+			Network struct {
+				KubeSpan map[string]bool `yaml:"kubespan"`
+			} `yaml:"network"`
+		} `yaml:"machine"`
+		Cluster struct {
+			Discovery map[string]bool `yaml:"discovery"`
+		} `yaml:"cluster"`
+	}
+	if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+		t.Fatalf("invalid patch YAML: %v\n%s", err, patch)
+	}
+	if !parsed.Machine.Network.KubeSpan["enabled"] {
+		t.Errorf("expected KubeSpan to be enabled, got:\n%s", patch)
+	}
+	if !parsed.Cluster.Discovery["enabled"] {
+		t.Errorf("expected cluster discovery to be enabled, got:\n%s", patch)
+	}
+}
+
+func TestTalosTimeSyncSchema_BootTimeoutValidation(t *testing.T) {
+	validate := talosTimeSyncSchema().Schema["boot_timeout"].ValidateFunc
+	if _, errs := validate("2m30s", "boot_timeout"); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if _, errs := validate("two minutes", "boot_timeout"); len(errs) == 0 {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestTalosProvisioner_GenerateConfig_Patches(t *testing.T) {
+	var gotArgs []string
+	var patchContent string
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		for i, arg := range args {
+			if arg == "--config-patch" && i+1 < len(args) {
+				data, _ := os.ReadFile(strings.TrimPrefix(args[i+1], "@"))
+				patchContent = string(data)
+			}
+		}
+		return exec.Command("true")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	if err := provisioner.GenerateConfig("secrets.yaml", "test", "https://10.10.88.73:6443", emmcInstallDisk, "configs", "machine:\n  time:\n    disabled: true\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(strings.Join(gotArgs, " "), "--config-patch @") {
+		t.Errorf("expected --config-patch argument, got %v", gotArgs)
+	}
+	if !strings.Contains(patchContent, "disabled: true") {
+		t.Errorf("expected patch file to be passed to talosctl, got %q", patchContent)
+	}
+}
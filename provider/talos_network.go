@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// Subnets Talos uses when cluster.network is not patched
+const (
+	talosDefaultPodSubnet     = "10.244.0.0/16"
+	talosDefaultServiceSubnet = "10.96.0.0/12"
+)
+
+func talosCIDRListSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		ForceNew:    true,
+		MaxItems:    2,
+		Description: description,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validation.IsCIDR,
+		},
+	}
+}
+
+// extractTalosNetwork reads pod_cidrs and service_cidrs into cfg
+func extractTalosNetwork(d *schema.ResourceData, cfg *TalosClusterConfig) {
+	cfg.PodCIDRs = expandStringList(d.Get("pod_cidrs").([]interface{}))
+	cfg.ServiceCIDRs = expandStringList(d.Get("service_cidrs").([]interface{}))
+}
+
+// validateTalosNetwork checks that each list has at most one CIDR per
+// address family, and that pods and services cover the same families. An
+// unset list stands for the Talos default subnet.
+func validateTalosNetwork(cfg TalosClusterConfig) error {
+	if len(cfg.PodCIDRs) == 0 && len(cfg.ServiceCIDRs) == 0 {
+		return nil
+	}
+	pods, services := strings.Join(cfg.PodCIDRs, ","), strings.Join(cfg.ServiceCIDRs, ",")
+	if pods == "" {
+		pods = talosDefaultPodSubnet
+	}
+	if services == "" {
+		services = talosDefaultServiceSubnet
+	}
+	_, err := validateCIDRFamilies(pods, services, "pod_cidrs", "service_cidrs")
+	return err
+}
+
+// generateNetworkPatchYAML renders pod_cidrs and service_cidrs as a
+// cluster.network patch for every node. Returns an empty string when
+// neither is set.
+func generateNetworkPatchYAML(cfg TalosClusterConfig) (string, error) {
+	network := map[string]interface{}{}
+	if len(cfg.PodCIDRs) > 0 {
+		network["podSubnets"] = cfg.PodCIDRs
+	}
+	if len(cfg.ServiceCIDRs) > 0 {
+		network["serviceSubnets"] = cfg.ServiceCIDRs
+	}
+	if len(network) == 0 {
+		return "", nil
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		"cluster": map[string]interface{}{"network": network},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal network patch: %w", err)
+	}
+	return string(data), nil
+}
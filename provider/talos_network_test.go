@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateNetworkPatchYAML_DualStack(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":             "test",
+		"cluster_endpoint": "https://10.10.88.73:6443",
+		"pod_cidrs":        []interface{}{"10.244.0.0/16", "fd00:10:244::/56"},
+		"service_cidrs":    []interface{}{"10.96.0.0/12", "fd00:10:96::/112"},
+	})
+	cfg := extractTalosClusterConfig(d)
+	if err := validateTalosNetwork(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	patch, err := generateNetworkPatchYAML(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parsed struct {
+		Cluster struct {
+			Network struct {
+				PodSubnets     []string `yaml:"podSubnets"`
+				ServiceSubnets []string `yaml:"serviceSubnets"`
+			} `yaml:"network"`
+		} `yaml:"cluster"`
+	}
+	if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+		t.Fatalf("invalid patch YAML: %v\n%s", err, patch)
+	}
+	network := parsed.Cluster.Network
+	if strings.Join(network.PodSubnets, ",") != "10.244.0.0/16,fd00:10:244::/56" || strings.Join(network.ServiceSubnets, ",") != "10.96.0.0/12,fd00:10:96::/112" {
+		t.Errorf("unexpected network patch:\n%s", patch)
+	}
+}
+
+func TestGenerateNetworkPatchYAML_Defaults(t *testing.T) {
+	patch, err := generateNetworkPatchYAML(TalosClusterConfig{})
+	if err != nil || patch != "" {
+		t.Errorf("expected no patch without CIDRs, got %q, %v", patch, err)
+	}
+
+	patch, err = generateNetworkPatchYAML(TalosClusterConfig{PodCIDRs: []string{"10.42.0.0/16"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(patch, "podSubnets") || strings.Contains(patch, "serviceSubnets") {
+		t.Errorf("expected only podSubnets, got:\n%s", patch)
+	}
+}
+
+func TestValidateTalosNetwork(t *testing.T) {
+	tests := []struct {
+		name     string
+		pods     []string
+		services []string
+		wantErr  string
+	}{
+		{"defaults", nil, nil, ""},
+		{"custom IPv4 pods", []string{"10.42.0.0/16"}, nil, ""},
+		{"IPv6 only", []string{"fd00:10:244::/56"}, []string{"fd00:10:96::/112"}, ""},
+		{"dual-stack pods with default services", []string{"10.244.0.0/16", "fd00:10:244::/56"}, nil, "pod_cidrs is dual-stack but service_cidrs is IPv4"},
+		{"IPv6 services with default pods", nil, []string{"fd00:10:96::/112"}, "pod_cidrs is IPv4 but service_cidrs is IPv6"},
+		{"two IPv4 pod CIDRs", []string{"10.42.0.0/16", "10.43.0.0/16"}, []string{"10.96.0.0/12"}, "more than one CIDR of the same address family"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTalosNetwork(TalosClusterConfig{PodCIDRs: tt.pods, ServiceCIDRs: tt.services})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
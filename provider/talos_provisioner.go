@@ -16,6 +16,10 @@ import (
 type TalosNodeConfig struct {
 	Host     string
 	Hostname string
+	// Slot is the Turing Pi slot (1-4) of the node, 0 if unknown
+	Slot int
+	// InstallDisk overrides the cluster install disk for this node
+	InstallDisk string
 }
 
 // TalosClusterConfig holds the Talos cluster configuration
@@ -28,6 +32,36 @@ type TalosClusterConfig struct {
 	Workers             []TalosNodeConfig
 	AllowSchedulingOnCP bool
 	BootstrapTimeout    time.Duration
+	// SecretsYAML reuses existing cluster secrets instead of generating new ones,
+	// so a re-run after a partial failure produces configs the nodes already trust
+	SecretsYAML string
+	// WorkersOnly joins the workers to a cluster whose control plane is managed
+	// elsewhere: control plane steps and kubeconfig retrieval are skipped
+	WorkersOnly bool
+	// Talosconfig replaces the generated talosconfig (e.g., the existing cluster's)
+	Talosconfig string
+	// HostDNS and TimeSync are cluster-wide machine settings; nil keeps the Talos defaults
+	HostDNS  *TalosHostDNS
+	TimeSync *TalosTimeSync
+	// KubeSpan enables the WireGuard mesh between nodes and the cluster
+	// discovery it relies on
+	KubeSpan bool
+	// NodeLabels are set as machine.nodeLabels on every node, from the
+	// provider default_metadata
+	NodeLabels map[string]string
+	// PodSecurity and AdmissionControl are patched into
+	// cluster.apiServer.admissionControl of the control planes; nil and
+	// empty keep the Talos defaults
+	PodSecurity      *TalosPodSecurity
+	AdmissionControl []TalosAdmissionPlugin
+	// PodCIDRs and ServiceCIDRs replace the Talos default subnets in
+	// cluster.network, one per address family; empty keeps the defaults
+	PodCIDRs     []string
+	ServiceCIDRs []string
+	// CNI is flannel or none; Cilium, when set, is installed on a cluster
+	// without a CNI
+	CNI    string
+	Cilium *talosCilium
 }
 
 // TalosProvisioner handles Talos cluster operations via talosctl
@@ -35,12 +69,20 @@ type TalosProvisioner struct {
 	talosctlPath string
 	workDir      string
 	execCommand  func(name string, arg ...string) *exec.Cmd
+	// DryRun records talosctl commands instead of running them; nil runs them
+	DryRun *dryRunRecorder
+	// Logs records each talosctl command and its output per node; nil
+	// records nothing
+	Logs *provisionLogs
+	// InstallCNI installs the CNI of a cluster created without one, with
+	// the path of its kubeconfig; nil installs nothing
+	InstallCNI func(ctx context.Context, kubeconfigPath string) error
 }
 
 // NewTalosProvisioner creates a new Talos provisioner
 func NewTalosProvisioner() (*TalosProvisioner, error) {
 	// Find talosctl in PATH
-	talosctlPath, err := exec.LookPath("talosctl")
+	talosctlPath, err := lookPath("talosctl")
 	if err != nil {
 		return nil, fmt.Errorf("talosctl not found in PATH: %w", err)
 	}
@@ -83,16 +125,36 @@ func (p *TalosProvisioner) WorkDir() string {
 
 // runTalosctl executes a talosctl command and returns the output
 func (p *TalosProvisioner) runTalosctl(args ...string) (string, error) {
+	if p.DryRun != nil {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		p.DryRun.command(p.talosctlPath + " " + strings.Join(quoted, " "))
+		return "", nil
+	}
 	cmd := p.execCommand(p.talosctlPath, args...)
 	cmd.Dir = p.workDir
 
 	output, err := cmd.CombinedOutput()
+	p.Logs.record(talosctlNode(args), "talosctl "+strings.Join(args, " "), string(output), err)
 	if err != nil {
 		return string(output), fmt.Errorf("talosctl %s failed: %w\nOutput: %s", strings.Join(args, " "), err, string(output))
 	}
 	return string(output), nil
 }
 
+// talosctlNode returns the node a talosctl command targets with --nodes, or
+// "" for commands without one or with several
+func talosctlNode(args []string) string {
+	for i, arg := range args {
+		if (arg == "--nodes" || arg == "-n") && i+1 < len(args) && !strings.Contains(args[i+1], ",") {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
 // runTalosctlWithConfig executes a talosctl command with a specific talosconfig
 func (p *TalosProvisioner) runTalosctlWithConfig(talosconfig string, args ...string) (string, error) {
 	fullArgs := append([]string{"--talosconfig", talosconfig}, args...)
@@ -108,8 +170,16 @@ func (p *TalosProvisioner) GenerateSecrets(outputPath string) error {
 	return nil
 }
 
-// GenerateConfig generates machine configs for the cluster
-func (p *TalosProvisioner) GenerateConfig(secretsPath, clusterName, endpoint, installDisk, outputDir string) error {
+// GenerateConfig generates machine configs for the cluster. Each patch is
+// applied to the configs of all machine types.
+func (p *TalosProvisioner) GenerateConfig(secretsPath, clusterName, endpoint, installDisk, outputDir string, patches ...string) error {
+	return p.GenerateConfigWithPatches(secretsPath, clusterName, endpoint, installDisk, outputDir, patches, nil)
+}
+
+// GenerateConfigWithPatches generates machine configs for the cluster,
+// applying patches to all machine types and controlPlanePatches to the
+// control plane config only
+func (p *TalosProvisioner) GenerateConfigWithPatches(secretsPath, clusterName, endpoint, installDisk, outputDir string, patches, controlPlanePatches []string) error {
 	args := []string{
 		"gen", "config",
 		"--with-secrets", secretsPath,
@@ -118,6 +188,22 @@ func (p *TalosProvisioner) GenerateConfig(secretsPath, clusterName, endpoint, in
 		"--install-disk", installDisk,
 		"--output-dir", outputDir,
 	}
+	for i, patch := range patches {
+		patchFile := filepath.Join(p.workDir, fmt.Sprintf("gen-patch-%d.yaml", i+1))
+		if err := os.WriteFile(patchFile, []byte(patch), 0600); err != nil {
+			return fmt.Errorf("failed to write config patch: %w", err)
+		}
+		defer func() { _ = os.Remove(patchFile) }()
+		args = append(args, "--config-patch", "@"+patchFile)
+	}
+	for i, patch := range controlPlanePatches {
+		patchFile := filepath.Join(p.workDir, fmt.Sprintf("gen-patch-controlplane-%d.yaml", i+1))
+		if err := os.WriteFile(patchFile, []byte(patch), 0600); err != nil {
+			return fmt.Errorf("failed to write control plane config patch: %w", err)
+		}
+		defer func() { _ = os.Remove(patchFile) }()
+		args = append(args, "--config-patch-control-plane", "@"+patchFile)
+	}
 
 	_, err := p.runTalosctl(args...)
 	if err != nil {
@@ -126,15 +212,22 @@ func (p *TalosProvisioner) GenerateConfig(secretsPath, clusterName, endpoint, in
 	return nil
 }
 
-// generatePatchYAML creates a YAML patch for node configuration
-func generatePatchYAML(hostname string, allowSchedulingOnCP bool, isControlPlane bool) (string, error) {
-	patch := map[string]interface{}{
-		"machine": map[string]interface{}{
-			"network": map[string]interface{}{
-				"hostname": hostname,
-			},
+// generateNodePatchYAML creates a YAML patch for node configuration,
+// overriding the install disk when installDisk is set
+func generateNodePatchYAML(hostname string, allowSchedulingOnCP bool, isControlPlane bool, installDisk string) (string, error) {
+	machine := map[string]interface{}{
+		"network": map[string]interface{}{
+			"hostname": hostname,
 		},
 	}
+	if installDisk != "" {
+		machine["install"] = map[string]interface{}{
+			"disk": installDisk,
+		}
+	}
+	patch := map[string]interface{}{
+		"machine": machine,
+	}
 
 	if isControlPlane && allowSchedulingOnCP {
 		patch["cluster"] = map[string]interface{}{
@@ -205,6 +298,46 @@ func (p *TalosProvisioner) ApplyConfigWithTalosconfig(talosconfig, nodeIP, confi
 	return nil
 }
 
+// IsMaintenanceMode reports whether a node is in maintenance mode (no machine config applied).
+// Maintenance mode accepts insecure connections, while configured nodes require a client certificate.
+func (p *TalosProvisioner) IsMaintenanceMode(nodeIP string) (bool, error) {
+	output, err := p.runTalosctl("get", "disks", "--insecure", "--nodes", nodeIP)
+	if err == nil {
+		return true, nil
+	}
+
+	lower := strings.ToLower(output + err.Error())
+	for _, marker := range []string{"certificate required", "authentication handshake failed", "tls: "} {
+		if strings.Contains(lower, marker) {
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("failed to determine mode of node %s: %w", nodeIP, err)
+}
+
+// ListDisks returns the block devices of a node in maintenance mode
+func (p *TalosProvisioner) ListDisks(nodeIP string) ([]string, error) {
+	output, err := p.runTalosctl("get", "disks", "--insecure", "--nodes", nodeIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disks of %s: %w", nodeIP, err)
+	}
+	return parseTalosDisks(output), nil
+}
+
+// ApplyConfigAuto applies config insecurely to nodes in maintenance mode and
+// through talosconfig to nodes that are already configured.
+// Returns whether the insecure mode was used.
+func (p *TalosProvisioner) ApplyConfigAuto(talosconfig, nodeIP, configPath string) (bool, error) {
+	maintenance, err := p.IsMaintenanceMode(nodeIP)
+	if err != nil {
+		return false, err
+	}
+	if maintenance {
+		return true, p.ApplyConfig(nodeIP, configPath, true)
+	}
+	return false, p.ApplyConfigWithTalosconfig(talosconfig, nodeIP, configPath)
+}
+
 // IsBootstrapped checks if the cluster is already bootstrapped
 func (p *TalosProvisioner) IsBootstrapped(talosconfig, nodeIP string) (bool, error) {
 	args := []string{
@@ -262,9 +395,54 @@ func (p *TalosProvisioner) GetKubeconfig(talosconfig, nodeIP, outputPath string)
 	return nil
 }
 
+// NewTalosconfig writes a talosconfig with a new os:admin client certificate,
+// signed by the cluster's existing Talos CA, to outputPath
+func (p *TalosProvisioner) NewTalosconfig(talosconfig, nodeIP, crtTTL, outputPath string) error {
+	args := []string{
+		"config", "new",
+		"--nodes", nodeIP,
+		"--roles", "os:admin",
+		"--crt-ttl", crtTTL,
+		outputPath,
+	}
+
+	_, err := p.runTalosctlWithConfig(talosconfig, args...)
+	if err != nil {
+		return fmt.Errorf("failed to generate talosconfig: %w", err)
+	}
+	return nil
+}
+
+// RotateCA replaces the Talos API and Kubernetes CAs, applying the new
+// machine configs to the nodes one by one, and writes the talosconfig signed
+// by the new CA to outputPath
+func (p *TalosProvisioner) RotateCA(talosconfig string, controlPlaneIPs, workerIPs []string, outputPath string) error {
+	args := []string{
+		"rotate-ca",
+		"--nodes", controlPlaneIPs[0],
+		"--control-plane-nodes", strings.Join(controlPlaneIPs, ","),
+		"--talos=true",
+		"--kubernetes=true",
+		"--dry-run=false",
+		"--output", outputPath,
+	}
+	if len(workerIPs) > 0 {
+		args = append(args, "--worker-nodes", strings.Join(workerIPs, ","))
+	}
+
+	_, err := p.runTalosctlWithConfig(talosconfig, args...)
+	if err != nil {
+		return fmt.Errorf("failed to rotate CAs: %w", err)
+	}
+	return nil
+}
+
 // ReadTalosconfig reads the talosconfig file content
 func (p *TalosProvisioner) ReadTalosconfig(path string) (string, error) {
 	data, err := os.ReadFile(path)
+	if err != nil && p.DryRun != nil {
+		return "", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to read talosconfig: %w", err)
 	}
@@ -274,6 +452,9 @@ func (p *TalosProvisioner) ReadTalosconfig(path string) (string, error) {
 // ReadSecrets reads the secrets file content
 func (p *TalosProvisioner) ReadSecrets(path string) (string, error) {
 	data, err := os.ReadFile(path)
+	if err != nil && p.DryRun != nil {
+		return "", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to read secrets: %w", err)
 	}
@@ -282,49 +463,75 @@ func (p *TalosProvisioner) ReadSecrets(path string) (string, error) {
 
 // WaitForHealth waits for the node to be healthy
 func (p *TalosProvisioner) WaitForHealth(talosconfig, nodeIP string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		args := []string{
-			"health",
-			"--nodes", nodeIP,
-			"--wait-timeout", "10s",
-		}
-
+	args := []string{
+		"health",
+		"--nodes", nodeIP,
+		"--wait-timeout", "10s",
+	}
+	err := poll(context.Background(), pollInterval, timeout, "Talos node health", func(context.Context) (bool, error) {
 		_, err := p.runTalosctlWithConfig(talosconfig, args...)
-		if err == nil {
-			return nil
-		}
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for node %s to be healthy: %w", nodeIP, err)
+	}
+	return nil
+}
 
-		time.Sleep(5 * time.Second)
+// WaitForWorkers waits for the kubelet to be running and healthy on every worker
+func (p *TalosProvisioner) WaitForWorkers(talosconfig string, workerIPs []string, timeout time.Duration) error {
+	if p.DryRun != nil {
+		p.DryRun.comment("wait up to %v for the kubelet on %s to be healthy", timeout, strings.Join(workerIPs, ", "))
+		return nil
 	}
+	err := poll(context.Background(), pollInterval, timeout, "kubelet on workers", func(context.Context) (bool, error) {
+		services, err := p.GetServices(talosconfig, workerIPs)
+		return err == nil && kubeletsHealthy(services, workerIPs), err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for kubelet on workers %s: %w", strings.Join(workerIPs, ", "), err)
+	}
+	return nil
+}
 
-	return fmt.Errorf("timeout waiting for node %s to be healthy after %v", nodeIP, timeout)
+// kubeletsHealthy reports whether the kubelet is Running and OK on every node
+func kubeletsHealthy(services []TalosServiceStatus, nodeIPs []string) bool {
+	healthy := make(map[string]bool)
+	for _, s := range services {
+		if s.ID == "kubelet" && s.State == "Running" && s.Health == "OK" {
+			healthy[s.Node] = true
+		}
+	}
+	for _, ip := range nodeIPs {
+		if !healthy[ip] {
+			return false
+		}
+	}
+	return true
 }
 
 // WaitForAPIServer waits for the Kubernetes API server to be ready
 func (p *TalosProvisioner) WaitForAPIServer(talosconfig, nodeIP string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		args := []string{
-			"service", "kube-apiserver",
-			"--nodes", nodeIP,
-		}
-
+	if p.DryRun != nil {
+		p.DryRun.comment("wait up to %v for kube-apiserver on %s to be running", timeout, nodeIP)
+		return nil
+	}
+	args := []string{
+		"service", "kube-apiserver",
+		"--nodes", nodeIP,
+	}
+	err := poll(context.Background(), pollInterval, timeout, "kube-apiserver", func(context.Context) (bool, error) {
 		output, err := p.runTalosctlWithConfig(talosconfig, args...)
-		if err == nil && strings.Contains(output, "Running") {
-			return nil
-		}
-
-		time.Sleep(5 * time.Second)
+		return err == nil && strings.Contains(output, "Running"), err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for API server on %s: %w", nodeIP, err)
 	}
-
-	return fmt.Errorf("timeout waiting for API server on %s after %v", nodeIP, timeout)
+	return nil
 }
 
-// Reset resets a node (wipes it)
-func (p *TalosProvisioner) Reset(talosconfig, nodeIP string, graceful bool) error {
+// Reset resets a node, wiping the disks selected by opts
+func (p *TalosProvisioner) Reset(talosconfig, nodeIP string, graceful bool, opts TalosResetOptions) error {
 	args := []string{
 		"reset",
 		"--nodes", nodeIP,
@@ -333,6 +540,7 @@ func (p *TalosProvisioner) Reset(talosconfig, nodeIP string, graceful bool) erro
 	if !graceful {
 		args = append(args, "--graceful=false")
 	}
+	args = append(args, opts.args()...)
 
 	_, err := p.runTalosctlWithConfig(talosconfig, args...)
 	if err != nil {
@@ -376,15 +584,80 @@ func (p *TalosProvisioner) GetClusterMembers(talosconfig, nodeIP string) ([]stri
 	return members, nil
 }
 
+// generateClusterConfigs generates the base machine configs of a cluster,
+// with the cluster-wide patches, into configDir
+func (p *TalosProvisioner) generateClusterConfigs(cfg TalosClusterConfig, secretsPath, configDir string) error {
+	var patches []string
+	machineSettings, err := generateMachineSettingsPatchYAML(cfg)
+	if err != nil {
+		return err
+	}
+	if machineSettings != "" {
+		patches = append(patches, machineSettings)
+	}
+	network, err := generateNetworkPatchYAML(cfg)
+	if err != nil {
+		return err
+	}
+	if network != "" {
+		patches = append(patches, network)
+	}
+	cni, err := generateCNIPatchYAML(cfg)
+	if err != nil {
+		return err
+	}
+	if cni != "" {
+		patches = append(patches, cni)
+	}
+	var controlPlanePatches []string
+	admission, err := generateAdmissionPatchYAML(cfg)
+	if err != nil {
+		return err
+	}
+	if admission != "" {
+		controlPlanePatches = append(controlPlanePatches, admission)
+	}
+	return p.GenerateConfigWithPatches(secretsPath, cfg.Name, cfg.ClusterEndpoint, cfg.InstallDisk, configDir, patches, controlPlanePatches)
+}
+
+// patchNodeConfig writes the config of the i-th control plane or worker,
+// with its hostname and install disk, and returns its path
+func (p *TalosProvisioner) patchNodeConfig(configDir string, cfg TalosClusterConfig, node TalosNodeConfig, i int, controlPlane bool) (string, error) {
+	base, name, prefix := filepath.Join(configDir, "worker.yaml"), "worker", "turing-w"
+	allowScheduling := false
+	if controlPlane {
+		base, name, prefix = filepath.Join(configDir, "controlplane.yaml"), "controlplane", "turing-cp"
+		allowScheduling = cfg.AllowSchedulingOnCP
+	}
+	hostname := node.Hostname
+	if hostname == "" {
+		hostname = fmt.Sprintf("%s-%d", prefix, i+1)
+	}
+
+	patchContent, err := generateNodePatchYAML(hostname, allowScheduling, controlPlane, node.InstallDisk)
+	if err != nil {
+		return "", err
+	}
+	patchedConfig := filepath.Join(p.workDir, fmt.Sprintf("%s-%d.yaml", name, i+1))
+	if err := p.PatchConfig(base, patchContent, patchedConfig); err != nil {
+		return "", err
+	}
+	return patchedConfig, nil
+}
+
 // ProvisionCluster provisions a complete Talos cluster
 func (p *TalosProvisioner) ProvisionCluster(ctx context.Context, cfg TalosClusterConfig) (*TalosClusterState, error) {
 	state := &TalosClusterState{
 		ClusterStatus: "bootstrapping",
 	}
 
-	// 1. Generate secrets
+	// 1. Generate secrets, or reuse the provided ones
 	secretsPath := filepath.Join(p.workDir, "secrets.yaml")
-	if err := p.GenerateSecrets(secretsPath); err != nil {
+	if cfg.SecretsYAML != "" {
+		if err := os.WriteFile(secretsPath, []byte(cfg.SecretsYAML), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write secrets: %w", err)
+		}
+	} else if err := p.GenerateSecrets(secretsPath); err != nil {
 		return nil, err
 	}
 
@@ -401,12 +674,17 @@ func (p *TalosProvisioner) ProvisionCluster(ctx context.Context, cfg TalosCluste
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := p.GenerateConfig(secretsPath, cfg.Name, cfg.ClusterEndpoint, cfg.InstallDisk, configDir); err != nil {
+	if err := p.generateClusterConfigs(cfg, secretsPath, configDir); err != nil {
 		return nil, err
 	}
 
-	// Read talosconfig
+	// Read talosconfig, preferring the provided one
 	talosconfigPath := filepath.Join(configDir, "talosconfig")
+	if cfg.Talosconfig != "" {
+		if err := os.WriteFile(talosconfigPath, []byte(cfg.Talosconfig), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write talosconfig: %w", err)
+		}
+	}
 	talosconfigContent, err := p.ReadTalosconfig(talosconfigPath)
 	if err != nil {
 		return nil, err
@@ -414,27 +692,14 @@ func (p *TalosProvisioner) ProvisionCluster(ctx context.Context, cfg TalosCluste
 	state.Talosconfig = talosconfigContent
 
 	// 3. Apply configs to control planes
-	controlplaneConfig := filepath.Join(configDir, "controlplane.yaml")
 	for i, cp := range cfg.ControlPlanes {
-		// Generate hostname patch
-		hostname := cp.Hostname
-		if hostname == "" {
-			hostname = fmt.Sprintf("turing-cp-%d", i+1)
-		}
-
-		patchContent, err := generatePatchYAML(hostname, cfg.AllowSchedulingOnCP, true)
+		patchedConfig, err := p.patchNodeConfig(configDir, cfg, cp, i, true)
 		if err != nil {
 			return nil, err
 		}
 
-		// Patch config
-		patchedConfig := filepath.Join(p.workDir, fmt.Sprintf("controlplane-%d.yaml", i+1))
-		if err := p.PatchConfig(controlplaneConfig, patchContent, patchedConfig); err != nil {
-			return nil, err
-		}
-
-		// Apply config (insecure for initial setup)
-		if err := p.ApplyConfig(cp.Host, patchedConfig, true); err != nil {
+		// Apply config (insecure in maintenance mode, secure if already configured)
+		if _, err := p.ApplyConfigAuto(talosconfigPath, cp.Host, patchedConfig); err != nil {
 			return nil, err
 		}
 
@@ -445,11 +710,14 @@ func (p *TalosProvisioner) ProvisionCluster(ctx context.Context, cfg TalosCluste
 	if len(cfg.ControlPlanes) > 0 {
 		firstCP := cfg.ControlPlanes[0].Host
 
-		// Wait a bit for the node to be ready for bootstrap
-		time.Sleep(10 * time.Second)
-
-		if err := p.Bootstrap(talosconfigPath, firstCP); err != nil {
-			return nil, err
+		// The node only accepts the bootstrap once it runs the applied
+		// config. Bootstrap checks etcd first, so retrying it is safe.
+		err := poll(ctx, pollInterval, cfg.BootstrapTimeout, "bootstrap of "+firstCP, func(context.Context) (bool, error) {
+			err := p.Bootstrap(talosconfigPath, firstCP)
+			return err == nil, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("bootstrapping %s: %w", firstCP, err)
 		}
 
 		// Wait for API server
@@ -459,35 +727,42 @@ func (p *TalosProvisioner) ProvisionCluster(ctx context.Context, cfg TalosCluste
 	}
 
 	// 5. Apply configs to workers
-	workerConfig := filepath.Join(configDir, "worker.yaml")
 	for i, worker := range cfg.Workers {
-		// Generate hostname patch
-		hostname := worker.Hostname
-		if hostname == "" {
-			hostname = fmt.Sprintf("turing-w-%d", i+1)
-		}
-
-		patchContent, err := generatePatchYAML(hostname, false, false)
+		patchedConfig, err := p.patchNodeConfig(configDir, cfg, worker, i, false)
 		if err != nil {
 			return nil, err
 		}
 
-		// Patch config
-		patchedConfig := filepath.Join(p.workDir, fmt.Sprintf("worker-%d.yaml", i+1))
-		if err := p.PatchConfig(workerConfig, patchContent, patchedConfig); err != nil {
+		// Apply config (insecure in maintenance mode, secure if already configured)
+		if _, err := p.ApplyConfigAuto(talosconfigPath, worker.Host, patchedConfig); err != nil {
 			return nil, err
 		}
 
-		// Apply config (insecure for initial setup)
-		if err := p.ApplyConfig(worker.Host, patchedConfig, true); err != nil {
+		state.WorkerIPs = append(state.WorkerIPs, worker.Host)
+	}
+
+	// 6. Install the CNI, which the nodes need to become ready
+	kubeconfigPath := filepath.Join(p.workDir, "kubeconfig")
+	if p.InstallCNI != nil && len(cfg.ControlPlanes) > 0 {
+		if err := p.GetKubeconfig(talosconfigPath, cfg.ControlPlanes[0].Host, kubeconfigPath); err != nil {
+			return nil, err
+		}
+		if p.DryRun != nil {
+			p.DryRun.comment("then install Cilium through the Kubernetes API")
+		} else if err := p.InstallCNI(ctx, kubeconfigPath); err != nil {
 			return nil, err
 		}
-
-		state.WorkerIPs = append(state.WorkerIPs, worker.Host)
 	}
 
-	// 6. Wait for cluster health
-	if len(cfg.ControlPlanes) > 0 {
+	// 7. Wait for cluster health
+	if cfg.WorkersOnly {
+		// The control plane is not ours to query, so only the kubelets are checked
+		if err := p.WaitForWorkers(talosconfigPath, state.WorkerIPs, cfg.BootstrapTimeout); err != nil {
+			state.ClusterStatus = "degraded"
+		} else {
+			state.ClusterStatus = "ready"
+		}
+	} else if len(cfg.ControlPlanes) > 0 {
 		if err := p.WaitForHealth(talosconfigPath, cfg.ControlPlanes[0].Host, cfg.BootstrapTimeout); err != nil {
 			state.ClusterStatus = "degraded"
 			// Continue anyway to get kubeconfig if possible
@@ -496,15 +771,14 @@ func (p *TalosProvisioner) ProvisionCluster(ctx context.Context, cfg TalosCluste
 		}
 	}
 
-	// 7. Get kubeconfig
-	kubeconfigPath := filepath.Join(p.workDir, "kubeconfig")
+	// 8. Get kubeconfig
 	if len(cfg.ControlPlanes) > 0 {
 		if err := p.GetKubeconfig(talosconfigPath, cfg.ControlPlanes[0].Host, kubeconfigPath); err != nil {
 			return nil, err
 		}
 
 		kubeconfigContent, err := os.ReadFile(kubeconfigPath)
-		if err != nil {
+		if err != nil && p.DryRun == nil {
 			return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
 		}
 		state.Kubeconfig = string(kubeconfigContent)
@@ -528,7 +802,7 @@ type TalosClusterState struct {
 }
 
 // DestroyCluster destroys a Talos cluster by resetting all nodes
-func (p *TalosProvisioner) DestroyCluster(talosconfig string, controlPlaneIPs, workerIPs []string) error {
+func (p *TalosProvisioner) DestroyCluster(talosconfig string, controlPlaneIPs, workerIPs []string, opts TalosResetOptions) error {
 	// Write talosconfig to temp file
 	talosconfigPath := filepath.Join(p.workDir, "talosconfig")
 	if err := os.WriteFile(talosconfigPath, []byte(talosconfig), 0600); err != nil {
@@ -537,7 +811,7 @@ func (p *TalosProvisioner) DestroyCluster(talosconfig string, controlPlaneIPs, w
 
 	// Reset workers first
 	for _, ip := range workerIPs {
-		if err := p.Reset(talosconfigPath, ip, false); err != nil {
+		if err := p.Reset(talosconfigPath, ip, false, opts); err != nil {
 			// Log but continue - node might already be reset
 			fmt.Printf("Warning: failed to reset worker %s: %v\n", ip, err)
 		}
@@ -545,7 +819,7 @@ func (p *TalosProvisioner) DestroyCluster(talosconfig string, controlPlaneIPs, w
 
 	// Then reset control planes
 	for _, ip := range controlPlaneIPs {
-		if err := p.Reset(talosconfigPath, ip, false); err != nil {
+		if err := p.Reset(talosconfigPath, ip, false, opts); err != nil {
 			// Log but continue
 			fmt.Printf("Warning: failed to reset control plane %s: %v\n", ip, err)
 		}
@@ -575,3 +849,118 @@ func (p *TalosProvisioner) CheckClusterHealth(talosconfig string, controlPlaneIP
 
 	return "ready", nil
 }
+
+// CheckWorkersHealth checks the kubelet on each worker of a worker-only cluster
+func (p *TalosProvisioner) CheckWorkersHealth(talosconfig string, workerIPs []string) (string, error) {
+	// Write talosconfig to temp file
+	talosconfigPath := filepath.Join(p.workDir, "talosconfig")
+	if err := os.WriteFile(talosconfigPath, []byte(talosconfig), 0600); err != nil {
+		return "unknown", fmt.Errorf("failed to write talosconfig: %w", err)
+	}
+
+	services, err := p.GetServices(talosconfigPath, workerIPs)
+	if err != nil || !kubeletsHealthy(services, workerIPs) {
+		return "degraded", nil
+	}
+
+	return "ready", nil
+}
+
+// TalosEtcdMember describes a member of the etcd cluster
+type TalosEtcdMember struct {
+	ID         string
+	Hostname   string
+	PeerURLs   []string
+	ClientURLs []string
+	Learner    bool
+}
+
+// GetEtcdMembers returns the etcd members reported by a control plane node
+func (p *TalosProvisioner) GetEtcdMembers(talosconfig, nodeIP string) ([]TalosEtcdMember, error) {
+	output, err := p.runTalosctlWithConfig(talosconfig, "etcd", "members", "--nodes", nodeIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get etcd members: %w", err)
+	}
+	return parseEtcdMembers(output), nil
+}
+
+// parseEtcdMembers parses `talosctl etcd members` table output:
+// NODE ID HOSTNAME PEER URLS CLIENT URLS LEARNER
+func parseEtcdMembers(output string) []TalosEtcdMember {
+	var members []TalosEtcdMember
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[0] == "NODE" {
+			continue
+		}
+		members = append(members, TalosEtcdMember{
+			ID:         fields[1],
+			Hostname:   fields[2],
+			PeerURLs:   strings.Split(fields[3], ","),
+			ClientURLs: strings.Split(fields[4], ","),
+			Learner:    fields[5] == "true",
+		})
+	}
+	return members
+}
+
+// TalosServiceStatus describes a Talos system service on a node
+type TalosServiceStatus struct {
+	Node   string
+	ID     string
+	State  string
+	Health string
+}
+
+// GetServices returns the state and health of Talos services on the given nodes
+func (p *TalosProvisioner) GetServices(talosconfig string, nodeIPs []string) ([]TalosServiceStatus, error) {
+	output, err := p.runTalosctlWithConfig(talosconfig, "service", "--nodes", strings.Join(nodeIPs, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+	return parseTalosServices(output), nil
+}
+
+// parseTalosServices parses `talosctl service` table output:
+// NODE SERVICE STATE HEALTH LAST CHANGE LAST EVENT
+func parseTalosServices(output string) []TalosServiceStatus {
+	var services []TalosServiceStatus
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "NODE" {
+			continue
+		}
+		services = append(services, TalosServiceStatus{
+			Node:   fields[0],
+			ID:     fields[1],
+			State:  fields[2],
+			Health: fields[3],
+		})
+	}
+	return services
+}
+
+// GetKubernetesVersion returns the Kubernetes version run by the kubelet on a node
+func (p *TalosProvisioner) GetKubernetesVersion(talosconfig, nodeIP string) (string, error) {
+	output, err := p.runTalosctlWithConfig(talosconfig, "get", "kubeletspec", "--nodes", nodeIP, "-o", "yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubelet spec: %w", err)
+	}
+	return parseKubeletVersion(output), nil
+}
+
+// parseKubeletVersion extracts the image tag from KubeletSpec YAML
+// (e.g., "image: ghcr.io/siderolabs/kubelet:v1.31.4" -> "v1.31.4")
+func parseKubeletVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		image, ok := strings.CutPrefix(strings.TrimSpace(line), "image:")
+		if !ok {
+			continue
+		}
+		image, _, _ = strings.Cut(strings.TrimSpace(image), "@")
+		if i := strings.LastIndex(image, ":"); i != -1 && !strings.Contains(image[i:], "/") {
+			return image[i+1:]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of reset_wipe_mode, passed to talosctl reset --wipe-mode
+const (
+	talosWipeAll        = "all"
+	talosWipeSystemDisk = "system-disk"
+	talosWipeUserDisks  = "user-disks"
+)
+
+// TalosResetOptions selects which disks a reset wipes. The zero value wipes
+// everything, like talosctl reset without wipe flags.
+type TalosResetOptions struct {
+	WipeMode     string
+	SystemLabels []string
+	UserDisks    []string
+}
+
+// extractTalosResetOptions reads the reset_* arguments. get is the Get method
+// of schema.ResourceData or schema.ResourceDiff.
+func extractTalosResetOptions(get func(string) interface{}) TalosResetOptions {
+	opts := TalosResetOptions{WipeMode: get("reset_wipe_mode").(string)}
+	for _, v := range get("reset_system_labels").([]interface{}) {
+		opts.SystemLabels = append(opts.SystemLabels, v.(string))
+	}
+	for _, v := range get("reset_user_disks").([]interface{}) {
+		opts.UserDisks = append(opts.UserDisks, v.(string))
+	}
+	return opts
+}
+
+// args returns the talosctl reset flags of the options
+func (o TalosResetOptions) args() []string {
+	var args []string
+	if o.WipeMode != "" && o.WipeMode != talosWipeAll {
+		args = append(args, "--wipe-mode", o.WipeMode)
+	}
+	if len(o.SystemLabels) > 0 {
+		args = append(args, "--system-labels-to-wipe", strings.Join(o.SystemLabels, ","))
+	}
+	if len(o.UserDisks) > 0 {
+		args = append(args, "--user-disks-to-wipe", strings.Join(o.UserDisks, ","))
+	}
+	return args
+}
+
+// validateTalosResetOptions rejects wipe lists the wipe mode ignores, so a
+// reset never wipes more than the configuration suggests
+func validateTalosResetOptions(opts TalosResetOptions) error {
+	if len(opts.SystemLabels) > 0 && opts.WipeMode == talosWipeUserDisks {
+		return fmt.Errorf("reset_system_labels cannot be combined with reset_wipe_mode %q, which leaves the system disk alone", talosWipeUserDisks)
+	}
+	if len(opts.UserDisks) > 0 && opts.WipeMode != talosWipeUserDisks {
+		return fmt.Errorf("reset_user_disks requires reset_wipe_mode %q", talosWipeUserDisks)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestTalosResetOptions_Args(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TalosResetOptions
+		want []string
+	}{
+		{name: "zero value", opts: TalosResetOptions{}},
+		{name: "all", opts: TalosResetOptions{WipeMode: talosWipeAll}},
+		{
+			name: "system disk partitions",
+			opts: TalosResetOptions{WipeMode: talosWipeSystemDisk, SystemLabels: []string{"STATE", "EPHEMERAL"}},
+			want: []string{"--wipe-mode", "system-disk", "--system-labels-to-wipe", "STATE,EPHEMERAL"},
+		},
+		{
+			name: "user disks",
+			opts: TalosResetOptions{WipeMode: talosWipeUserDisks, UserDisks: []string{"/dev/nvme0n1"}},
+			want: []string{"--wipe-mode", "user-disks", "--user-disks-to-wipe", "/dev/nvme0n1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateTalosResetOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    TalosResetOptions
+		wantErr string
+	}{
+		{name: "default", opts: TalosResetOptions{WipeMode: talosWipeAll}},
+		{name: "system labels", opts: TalosResetOptions{WipeMode: talosWipeSystemDisk, SystemLabels: []string{"EPHEMERAL"}}},
+		{name: "user disks", opts: TalosResetOptions{WipeMode: talosWipeUserDisks, UserDisks: []string{"/dev/sda"}}},
+		{name: "system labels in user-disks mode", opts: TalosResetOptions{WipeMode: talosWipeUserDisks, SystemLabels: []string{"STATE"}}, wantErr: "leaves the system disk alone"},
+		{name: "user disks in system-disk mode", opts: TalosResetOptions{WipeMode: talosWipeSystemDisk, UserDisks: []string{"/dev/sda"}}, wantErr: "requires reset_wipe_mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTalosResetOptions(tt.opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtractTalosResetOptions(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceTalosCluster().Schema, map[string]interface{}{
+		"name":                "test",
+		"cluster_endpoint":    "https://10.10.88.73:6443",
+		"reset_wipe_mode":     talosWipeSystemDisk,
+		"reset_system_labels": []interface{}{"STATE", "EPHEMERAL"},
+	})
+
+	want := TalosResetOptions{WipeMode: talosWipeSystemDisk, SystemLabels: []string{"STATE", "EPHEMERAL"}}
+	if got := extractTalosResetOptions(d.Get); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestTalosProvisioner_DestroyCluster_WipeOptions(t *testing.T) {
+	var resets [][]string
+	provisioner := NewTalosProvisionerWithExec(func(name string, args ...string) *exec.Cmd {
+		resets = append(resets, args)
+		return exec.Command("echo", "reset")
+	})
+	defer func() { _ = provisioner.Cleanup() }()
+
+	opts := TalosResetOptions{WipeMode: talosWipeSystemDisk}
+	if err := provisioner.DestroyCluster("talosconfig", []string{"10.10.88.73"}, []string{"10.10.88.74"}, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resets) != 2 {
+		t.Fatalf("expected two resets, got %v", resets)
+	}
+	for _, args := range resets {
+		if !strings.Contains(strings.Join(args, " "), "--wipe-mode system-disk") {
+			t.Errorf("expected --wipe-mode system-disk, got %v", args)
+		}
+	}
+}
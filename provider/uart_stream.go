@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// uartStreamPath is the WebSocket endpoint of BMC firmware that streams UART
+// output as it arrives. Older firmware answers it with 404.
+const uartStreamPath = "/api/bmc/ws/uart"
+
+// uartStreamMaxMessage caps the size of one WebSocket message
+const uartStreamMaxMessage = 1 << 20
+
+// errUARTStreamUnsupported is returned when the BMC firmware has no streaming
+// UART endpoint, so callers fall back to polling
+var errUARTStreamUnsupported = errors.New("BMC firmware does not support UART streaming")
+
+// uartStream is a WebSocket connection streaming the UART output of a node.
+// Output arrives as the node writes it, so boot patterns cannot fall between
+// two polls and their arrival time is exact.
+type uartStream struct {
+	node int
+	conn *websocket.Conn
+}
+
+// openUARTStream connects to the streaming UART endpoint of node. It returns
+// errUARTStreamUnsupported when the firmware does not provide it. The
+// connection goes through HTTPClient, so it shares the BMC TLS settings,
+// correlation headers and session token of other requests.
+func openUARTStream(endpoint, token string, node int) (*uartStream, error) {
+	// API uses 0-indexed nodes
+	url := fmt.Sprintf("%s%s?node=%d", endpoint, uartStreamPath, node-1)
+	conn, resp, err := websocket.Dial(context.Background(), url, &websocket.DialOptions{
+		HTTPClient: HTTPClient,
+		HTTPHeader: http.Header{"Authorization": []string{"Bearer " + token}},
+	})
+	if err != nil {
+		if resp != nil {
+			switch resp.StatusCode {
+			case http.StatusNotFound, http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+				return nil, errUARTStreamUnsupported
+			}
+		}
+		return nil, fmt.Errorf("UART stream of node %d: %w", node, err)
+	}
+	conn.SetReadLimit(uartStreamMaxMessage)
+
+	return &uartStream{node: node, conn: conn}, nil
+}
+
+// WaitFor reports whether pattern appears in the stream before the timeout
+// expires. Output split across messages is matched too. The connection is
+// closed when the timeout expires.
+func (s *uartStream) WaitFor(pattern string, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var tail string
+	for {
+		// Pings are answered while reading
+		_, data, err := s.conn.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, nil
+			}
+			return false, fmt.Errorf("UART stream of node %d closed: %w", s.node, err)
+		}
+		window := tail + string(data)
+		if strings.Contains(window, pattern) {
+			return true, nil
+		}
+		// Keep enough output to match a pattern split across messages
+		if keep := len(pattern) - 1; len(window) > keep {
+			tail = window[len(window)-keep:]
+		} else {
+			tail = window
+		}
+	}
+}
+
+// Close performs the WebSocket close handshake and closes the connection
+func (s *uartStream) Close() error {
+	return s.conn.Close(websocket.StatusNormalClosure, "")
+}
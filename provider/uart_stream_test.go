@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// newFakeUARTStreamBMC serves the streaming UART endpoint. It pings the client
+// after the handshake, then sends each message as a text frame and waits
+// until the client closes.
+func newFakeUARTStreamBMC(t *testing.T, messages []string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != uartStreamPath || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("node") != "0" || r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Errorf("accept failed: %v", err)
+			return
+		}
+		defer func() { _ = conn.CloseNow() }()
+
+		// Reads in the background, receiving the pong and the close
+		ctx := conn.CloseRead(r.Context())
+		if err := conn.Ping(ctx); err != nil {
+			t.Errorf("expected the client to answer pings, got %v", err)
+			return
+		}
+		for _, m := range messages {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(m)); err != nil {
+				return
+			}
+		}
+		<-ctx.Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUARTStream_WaitFor(t *testing.T) {
+	server := newFakeUARTStreamBMC(t, []string{"U-Boot 2017.09\r\n", "Ubuntu 22.04 turing-1 ttyS2\r\n\r\nturing-1 log", "in: "})
+
+	stream, err := openUARTStream(server.URL, "test-token", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	found, err := stream.WaitFor("login:", 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !found {
+		t.Error("expected pattern split across messages to be found")
+	}
+}
+
+func TestUARTStream_Timeout(t *testing.T) {
+	server := newFakeUARTStreamBMC(t, []string{"Starting kernel ...\r\n"})
+
+	stream, err := openUARTStream(server.URL, "test-token", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found, err := stream.WaitFor("login:", 200*time.Millisecond)
+	if found || err != nil {
+		t.Errorf("expected timeout without error, got %v, %v", found, err)
+	}
+}
+
+func TestOpenUARTStream_Unsupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	if _, err := openUARTStream(server.URL, "test-token", 1); !errors.Is(err, errUARTStreamUnsupported) {
+		t.Errorf("expected errUARTStreamUnsupported, got %v", err)
+	}
+
+	// Other failures are reported, so the fallback to polling is logged
+	server = newFakeUARTStreamBMC(t, nil)
+	if _, err := openUARTStream(server.URL, "wrong-token", 1); err == nil || errors.Is(err, errUARTStreamUnsupported) {
+		t.Errorf("expected a handshake error, got %v", err)
+	}
+}
+
+func TestUARTStream_ServerClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = conn.Close(websocket.StatusGoingAway, "node powered off")
+	}))
+	t.Cleanup(server.Close)
+
+	stream, err := openUARTStream(server.URL, "test-token", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.WaitFor("login:", 5*time.Second); err == nil || websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Errorf("expected the close to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Timeouts for clearing upload handles. The release after an interruption
+// runs on its own context, since the operation's context is already done.
+// Replaced in tests to keep them fast.
+var (
+	uploadCancelTimeout = 10 * time.Second
+	staleTransferWait   = 30 * time.Second
+)
+
+// transferHandle returns the handle of the transfer in progress. Only BMC
+// firmware 2.0.5+ reports it.
+func (f *flashStatusResponse) transferHandle() (string, bool) {
+	if len(f.Transferring) == 0 || !parseV2Responses() {
+		return "", false
+	}
+	var status transferringStatus
+	if err := json.Unmarshal(f.Transferring, &status); err != nil || status.ID == 0 {
+		return "", false
+	}
+	return strconv.FormatInt(status.ID, 10), true
+}
+
+// cancelFirmwareUpload cancels an in-progress upload, releasing its handle
+func cancelFirmwareUpload(ctx context.Context, endpoint, token, handle string) error {
+	url := fmt.Sprintf("%s/api/bmc/upload/%s/cancel", endpoint, handle)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cancel request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cancel request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return nil
+}
+
+// releaseUploadHandle cancels the upload of an interrupted or failed
+// operation, so the BMC does not keep the handle and block the next flash
+// until it is rebooted
+func releaseUploadHandle(endpoint, token, handle string) {
+	ctx, cancel := context.WithTimeout(context.Background(), uploadCancelTimeout)
+	defer cancel()
+	if err := cancelFirmwareUpload(ctx, endpoint, token, handle); err != nil {
+		log.Printf("[WARN] Failed to release upload handle %s: %v", handle, err)
+		return
+	}
+	log.Printf("[INFO] Released upload handle %s", handle)
+}
+
+// cancelStaleTransfer cancels a transfer left over from an interrupted run,
+// for force_cancel_existing, and waits for the BMC to release it. A node
+// flash that is already writing is not interrupted.
+func cancelStaleTransfer(ctx context.Context, endpoint, token string) error {
+	status, err := getFlashStatus(endpoint, token)
+	if err != nil {
+		return fmt.Errorf("failed to check for a stale transfer: %w", err)
+	}
+	if status.Flashing != nil {
+		return categorize(errorCategoryNodeBusy, fmt.Errorf("a flash is already writing to a node; wait for it to finish instead of cancelling it"))
+	}
+	if inProgress, _, _ := status.isTransferring(); !inProgress {
+		return nil
+	}
+
+	handle, ok := status.transferHandle()
+	if !ok {
+		return categorize(errorCategoryNodeBusy, fmt.Errorf("a transfer is in progress but the BMC does not report its handle; reboot the BMC to clear it"))
+	}
+	log.Printf("[INFO] Cancelling stale transfer with handle %s", handle)
+	if err := cancelFirmwareUpload(ctx, endpoint, token, handle); err != nil {
+		return fmt.Errorf("failed to cancel stale transfer %s: %w", handle, err)
+	}
+
+	err = poll(ctx, flashPollInterval, staleTransferWait, "stale transfer "+handle+" to be released", func(context.Context) (bool, error) {
+		status, err := getFlashStatus(endpoint, token)
+		if err != nil {
+			return false, err
+		}
+		inProgress, _, _ := status.isTransferring()
+		return !inProgress, nil
+	})
+	if err != nil {
+		return fmt.Errorf("stale transfer %s was not released: %w", handle, err)
+	}
+	return nil
+}
+
+// staleTransferHint explains a failed flash or firmware init when a transfer
+// is still in progress on the BMC, e.g. after an interrupted apply
+func staleTransferHint(endpoint, token string) string {
+	status, err := getFlashStatus(endpoint, token)
+	if err != nil {
+		return ""
+	}
+	if inProgress, _, _ := status.isTransferring(); !inProgress {
+		return ""
+	}
+	return "\nA transfer is still in progress on the BMC, possibly left over from an interrupted run. " +
+		"Set force_cancel_existing = true to cancel it before starting."
+}
+
+// sleepContext waits for d, returning early with the context error when ctx
+// is cancelled, e.g. when Terraform is interrupted
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
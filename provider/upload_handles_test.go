@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// transferBMC serves a flash status that reports a transfer in progress
+// until its handle is cancelled, and records the cancelled handles
+type transferBMC struct {
+	mu        sync.Mutex
+	status    string
+	cancelled []string
+}
+
+func (b *transferBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/cancel"):
+		handle := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/bmc/upload/"), "/cancel")
+		b.cancelled = append(b.cancelled, handle)
+		b.status = `{}`
+		_, _ = w.Write([]byte(`{}`))
+	case strings.HasPrefix(r.URL.Path, "/api/bmc/upload/"):
+		_, _ = w.Write([]byte(`{}`))
+	case q.Get("type") == "power":
+		_, _ = w.Write([]byte(`{}`))
+	case q.Get("opt") == "set" && q.Get("type") == "flash":
+		_, _ = w.Write([]byte(`{"handle":42}`))
+	case q.Get("opt") == "get" && q.Get("type") == "flash":
+		_, _ = w.Write([]byte(b.status))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *transferBMC) cancels() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.cancelled...)
+}
+
+func newTransferBMC(t *testing.T, status string) (*transferBMC, string) {
+	t.Helper()
+	oldPoll, oldWait := flashPollInterval, staleTransferWait
+	flashPollInterval, staleTransferWait = time.Millisecond, time.Second
+	t.Cleanup(func() { flashPollInterval, staleTransferWait = oldPoll, oldWait })
+
+	bmc := &transferBMC{status: status}
+	server := httptest.NewServer(bmc)
+	t.Cleanup(server.Close)
+	return bmc, server.URL
+}
+
+const staleTransferStatus = `{"Transferring":{"id":7,"process_name":"flashing","size":100,"cancelled":false,"bytes_written":10}}`
+
+func TestCancelStaleTransfer(t *testing.T) {
+	bmc, endpoint := newTransferBMC(t, staleTransferStatus)
+
+	if err := cancelStaleTransfer(context.Background(), endpoint, "test-token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := bmc.cancels(); len(got) != 1 || got[0] != "7" {
+		t.Errorf("expected handle 7 cancelled, got %v", got)
+	}
+}
+
+func TestCancelStaleTransfer_Idle(t *testing.T) {
+	bmc, endpoint := newTransferBMC(t, `{}`)
+
+	if err := cancelStaleTransfer(context.Background(), endpoint, "test-token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := bmc.cancels(); len(got) != 0 {
+		t.Errorf("expected nothing cancelled, got %v", got)
+	}
+}
+
+func TestCancelStaleTransfer_Refused(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   string
+	}{
+		{"legacy format without handle", `{"Transferring":[10,100]}`, "reboot the BMC"},
+		{"node flash writing", `{"Flashing":{"bytes_written":10,"total_bytes":100}}`, "wait for it to finish"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc, endpoint := newTransferBMC(t, tt.status)
+
+			err := cancelStaleTransfer(context.Background(), endpoint, "test-token")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if got := bmc.cancels(); len(got) != 0 {
+				t.Errorf("expected nothing cancelled, got %v", got)
+			}
+		})
+	}
+}
+
+func TestStaleTransferHint(t *testing.T) {
+	_, endpoint := newTransferBMC(t, staleTransferStatus)
+	if hint := staleTransferHint(endpoint, "test-token"); !strings.Contains(hint, "force_cancel_existing") {
+		t.Errorf("expected a force_cancel_existing hint, got %q", hint)
+	}
+
+	_, endpoint = newTransferBMC(t, `{}`)
+	if hint := staleTransferHint(endpoint, "test-token"); hint != "" {
+		t.Errorf("expected no hint when idle, got %q", hint)
+	}
+}
+
+func TestFlashFirmware_InterruptedReleasesHandle(t *testing.T) {
+	// The transfer never finishes, so only the interruption ends the flash
+	bmc, endpoint := newTransferBMC(t, `{"Transferring":{"id":42,"size":100,"bytes_written":10}}`)
+	oldDelay := flashPowerOffDelay
+	flashPowerOffDelay = 0
+	t.Cleanup(func() { flashPowerOffDelay = oldDelay })
+
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("firmware"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var pct float64
+	err := flashFirmware(ctx, &ProviderConfig{Endpoint: endpoint, Token: "test-token"}, 1, image, nil, &pct)
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("expected an interruption error, got %v", err)
+	}
+	if got := bmc.cancels(); len(got) != 1 || got[0] != "42" {
+		t.Errorf("expected upload handle 42 released, got %v", got)
+	}
+}
+
+func TestWaitForFirmwareUpgrade_Interrupted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := waitForFirmwareUpgrade(ctx, server.URL, "test-token", "1", 60)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the wait to stop at once, took %s", time.Since(start))
+	}
+}